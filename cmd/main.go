@@ -1,17 +1,34 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"time"
+	_ "time/tzdata" // база часовых поясов для образов без tzdata
 
 	"github.com/gofiber/fiber/v2"
 	"go.dataflow.ru/service-sales/pkg/logger"
+	"go.dataflow.ru/service-sales/pkg/ratelimit"
 
+	"go.dataflow.ru/service-sales/internal/adapters/alerts"
+	"go.dataflow.ru/service-sales/internal/adapters/anomaly"
+	"go.dataflow.ru/service-sales/internal/adapters/audit"
+	"go.dataflow.ru/service-sales/internal/adapters/catalog"
+	"go.dataflow.ru/service-sales/internal/adapters/cluster"
 	salesHttp "go.dataflow.ru/service-sales/internal/adapters/http"
+	"go.dataflow.ru/service-sales/internal/adapters/rates"
+	"go.dataflow.ru/service-sales/internal/adapters/replication"
+	"go.dataflow.ru/service-sales/internal/adapters/reports"
+	"go.dataflow.ru/service-sales/internal/adapters/rules"
 	"go.dataflow.ru/service-sales/internal/adapters/storage"
+	"go.dataflow.ru/service-sales/internal/adapters/targets"
+	"go.dataflow.ru/service-sales/internal/adapters/webhook"
+	"go.dataflow.ru/service-sales/internal/app/domain"
+	"go.dataflow.ru/service-sales/internal/app/ports"
 	"go.dataflow.ru/service-sales/internal/app/services"
 	"go.dataflow.ru/service-sales/internal/config"
 )
@@ -19,6 +36,9 @@ import (
 const (
 	readTimeout  = 1 * time.Second
 	writeTimeout = 1 * time.Second
+
+	// таймаут запроса справочных данных первого узла кластера
+	referenceTimeout = 10 * time.Second
 )
 
 func main() {
@@ -29,10 +49,200 @@ func main() {
 		logger.Panicf("cant read config: %v", err)
 	}
 
-	saleRepo := storage.New(logger)
-	saleService := services.NewSaleService(saleRepo, logger)
-	saleHandler := salesHttp.New(saleService)
-	srv := NewServer(saleHandler)
+	defaultLocation, err := time.LoadLocation(cfg.Catalog.DefaultTimeZone)
+	if err != nil {
+		logger.Panicf("cant load default time zone: %v", err)
+	}
+
+	catalogRepo, err := catalog.New(cfg.Catalog.File)
+	if err != nil {
+		logger.Panicf("cant load catalog: %v", err)
+	}
+
+	catalogService := services.NewCatalogService(catalogRepo, defaultLocation, logger)
+
+	saleRepo := storage.New(logger,
+		storage.WithRetention(cfg.Storage.RetentionHorizon, cfg.Storage.SegmentsDir),
+		storage.WithSnapshots(cfg.Storage.SnapshotTTL, cfg.Storage.MaxSnapshots),
+	)
+	if cfg.Storage.RetentionHorizon > 0 {
+		go evictSales(saleRepo, cfg.Storage.EvictionInterval, logger)
+	}
+
+	exchangeRates := rates.New(cfg.Currency.Base)
+	if cfg.Currency.RatesFile != "" {
+		exchangeRates, err = rates.Load(cfg.Currency.RatesFile, cfg.Currency.Base)
+		if err != nil {
+			logger.Panicf("cant load exchange rates: %v", err)
+		}
+	}
+
+	saleOpts := []services.Option{
+		services.WithDefaultCurrency(cfg.Currency.Default),
+		services.WithExchangeRates(exchangeRates),
+		services.WithStoreCalendar(catalogService),
+		services.WithCatalog(catalogService),
+	}
+
+	if cfg.Catalog.Validation {
+		saleOpts = append(saleOpts, services.WithCatalogValidation())
+	}
+
+	saleRules, err := rules.Load(cfg.Rules.File)
+	if err != nil {
+		logger.Panicf("cant load rules: %v", err)
+	}
+
+	ruleEngine, err := services.NewRuleEngine(saleRules)
+	if err != nil {
+		logger.Panicf("invalid rules: %v", err)
+	}
+
+	saleOpts = append(saleOpts, services.WithRules(ruleEngine))
+
+	// ведомый читает у ведущего журнал продаж и справочные данные
+	var leader *replication.Client
+
+	var replicationOpts []services.ReplicationOption
+	if cfg.Replication.Role == domain.RoleFollower {
+		leader = replication.New(cfg.Replication.LeaderURL, replication.WithToken(cfg.Replication.Token))
+		replicationOpts = append(replicationOpts,
+			services.FollowLeader(leader, cfg.Replication.LeaderURL),
+			services.WithReplicationBatchSize(cfg.Replication.BatchSize),
+		)
+	}
+
+	replicationService := services.NewReplicationService(saleRepo, logger, replicationOpts...)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if replicationService.IsFollower() {
+		go replicationService.Run(ctx, cfg.Replication.PollInterval)
+	}
+
+	// в кластере продажи магазина хранятся на узле-владельце, локальное хранилище содержит только магазины узла
+	var salesStorage ports.SalesStorage = saleRepo
+	if len(cfg.Cluster.Nodes) > 0 {
+		salesStorage, err = cluster.New(cfg.Cluster.Self, cfg.Cluster.Nodes, saleRepo,
+			cluster.WithVirtualNodes(cfg.Cluster.VirtualNodes),
+			cluster.WithToken(cfg.Cluster.Token),
+		)
+		if err != nil {
+			logger.Panicf("cant create cluster storage: %v", err)
+		}
+	}
+
+	anomalyQueue, err := anomaly.NewQueue(cfg.Anomalies.QueueFile)
+	if err != nil {
+		logger.Panicf("cant load anomaly queue: %v", err)
+	}
+
+	detector := anomaly.New(
+		anomaly.WithWindow(cfg.Anomalies.Window),
+		anomaly.WithMinObservations(cfg.Anomalies.MinObservations),
+	)
+	if cfg.Anomalies.Enabled {
+		saleOpts = append(saleOpts,
+			services.WithAnomalyDetection(detector, anomalyQueue, cfg.Anomalies.Threshold, cfg.Anomalies.Action),
+		)
+	}
+
+	targetRepo, err := targets.New(cfg.Targets.File)
+	if err != nil {
+		logger.Panicf("cant load targets: %v", err)
+	}
+
+	// справочник и планы изменяются на ведущем или первом узле кластера, остальные экземпляры периодически
+	// заменяют ими свою копию
+	var referenceOpts []services.ReferenceOption
+	switch source := referenceSource(cfg); {
+	case leader != nil:
+		referenceOpts = append(referenceOpts, services.FollowReference(leader, source))
+	case source != "":
+		node := cluster.NewClient(source, cfg.Cluster.Token, &http.Client{Timeout: referenceTimeout})
+		referenceOpts = append(referenceOpts, services.FollowReference(node, source))
+	}
+
+	referenceService := services.NewReferenceService(catalogRepo, targetRepo, logger, referenceOpts...)
+	if len(referenceOpts) > 0 {
+		go referenceService.Run(ctx, cfg.Catalog.SyncInterval)
+	}
+
+	alertRepo, err := alerts.New(cfg.Alerts.File)
+	if err != nil {
+		logger.Panicf("cant load alerts: %v", err)
+	}
+
+	reportRepo, err := reports.New(cfg.Reports.Dir, reports.WithKeepRuns(cfg.Reports.KeepRuns))
+	if err != nil {
+		logger.Panicf("cant load reports: %v", err)
+	}
+
+	// оповещения и отчеты по расписанию проверяет один экземпляр, иначе каждое событие отправлялось бы несколько раз
+	notifier := webhook.New(cfg.Alerts.Timeout)
+	scheduler := isScheduler(cfg)
+
+	alertService := services.NewAlertService(alertRepo, salesStorage, notifier, logger,
+		services.WithAlertCalendar(catalogService),
+		services.WithAlertCatalog(catalogService),
+		services.WithAlertTargets(targetRepo),
+		services.WithAlertRetries(cfg.Alerts.Attempts, cfg.Alerts.Backoff, cfg.Alerts.MaxBackoff),
+	)
+	if scheduler {
+		// выручка проверяется после продаж, принятых этим узлом, и периодически - по всем продажам
+		go alertService.Run(ctx, cfg.Alerts.CheckInterval)
+
+		saleOpts = append(saleOpts, services.WithSalesObserver(alertService))
+	}
+
+	saleService := services.NewSaleService(salesStorage, logger, saleOpts...)
+	anomalyService := services.NewAnomalyService(anomalyQueue, detector, saleService, logger)
+
+	targetService := services.NewTargetService(targetRepo, saleService, logger)
+	forecastService := services.NewForecastService(saleService, logger)
+	sqlService := services.NewSQLService(saleService, logger,
+		services.WithSQLTimeout(cfg.SQL.QueryTimeout),
+		services.WithSQLMaxRows(cfg.SQL.MaxRows),
+	)
+
+	auditLog, err := audit.New(cfg.Purge.AuditFile)
+	if err != nil {
+		logger.Panicf("cant load audit log: %v", err)
+	}
+
+	// удаление по запросу выполняется на всех узлах кластера, а по сроку хранения каждый узел удаляет
+	// продажи своих магазинов сам. Ведомый экземпляр продажи не удаляет: удаление на ведущем начинает журнал
+	// магазина в новом поколении, и ведомый перечитывает магазин при синхронизации
+	retentionService := services.NewRetentionService(salesStorage, auditLog, logger)
+	if cfg.Purge.Horizon > 0 && !replicationService.IsFollower() {
+		expiry := services.NewRetentionService(saleRepo, auditLog, logger, services.WithRetentionHorizon(cfg.Purge.Horizon))
+		go expiry.Run(ctx, cfg.Purge.Interval)
+	}
+
+	reportService := services.NewReportService(reportRepo, saleService, reports.NewRenderer(), notifier, logger,
+		services.WithReportRetries(cfg.Alerts.Attempts, cfg.Alerts.Backoff, cfg.Alerts.MaxBackoff),
+	)
+	if scheduler {
+		go reportService.Run(ctx, cfg.Reports.CheckInterval)
+	} else {
+		logger.Info("alerts and reports are not checked on this instance, configure them on the leader or the first cluster node")
+	}
+
+	saleHandler := salesHttp.New(saleService, cfg.Currency.Default)
+	targetHandler := salesHttp.NewTargetHandler(targetService)
+	forecastHandler := salesHttp.NewForecastHandler(forecastService)
+	anomalyHandler := salesHttp.NewAnomalyHandler(anomalyService)
+	ruleHandler := salesHttp.NewRuleHandler(ruleEngine)
+	sqlHandler := salesHttp.NewSQLHandler(sqlService)
+	alertHandler := salesHttp.NewAlertHandler(alertService)
+	reportHandler := salesHttp.NewReportHandler(reportService)
+	retentionHandler := salesHttp.NewRetentionHandler(retentionService)
+	catalogHandler := salesHttp.NewCatalogHandler(catalogService)
+	replicationHandler := salesHttp.NewReplicationHandler(replicationService)
+	referenceHandler := salesHttp.NewReferenceHandler(referenceService)
+	nodeHandler := salesHttp.NewNodeHandler(saleRepo)
+	srv := NewServer(cfg, saleHandler, catalogHandler, replicationHandler, referenceHandler, nodeHandler, targetHandler, forecastHandler, anomalyHandler, ruleHandler, sqlHandler, alertHandler, reportHandler, retentionHandler)
 
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt)
@@ -40,6 +250,8 @@ func main() {
 	go func() {
 		<-c
 
+		cancel()
+
 		logger.Info("Gracefully shutting down...")
 
 		_ = srv.Shutdown()
@@ -50,15 +262,188 @@ func main() {
 	}
 }
 
-func NewServer(h *salesHttp.SalesHandler) *fiber.App {
+// isScheduler сообщает, что экземпляр проверяет оповещения и выполняет отчеты по расписанию: ведущий экземпляр
+// без кластера или первый узел CLUSTER_NODES. Остальные экземпляры читают те же продажи, и их проверки
+// дублировали бы события.
+func isScheduler(cfg config.Config) bool {
+	if cfg.Replication.Role == domain.RoleFollower {
+		return false
+	}
+
+	return len(cfg.Cluster.Nodes) == 0 || cfg.Cluster.Self == cfg.Cluster.Nodes[0]
+}
+
+// referenceSource возвращает адрес экземпляра, справочник и планы которого копирует экземпляр: ведущего
+// для ведомого и первого узла CLUSTER_NODES для остальных узлов кластера. Пустой адрес - экземпляр изменяет
+// справочник и планы сам.
+func referenceSource(cfg config.Config) string {
+	if cfg.Replication.Role == domain.RoleFollower {
+		return cfg.Replication.LeaderURL
+	}
+
+	if len(cfg.Cluster.Nodes) == 0 || cfg.Cluster.Self == cfg.Cluster.Nodes[0] {
+		return ""
+	}
+
+	return cfg.Cluster.Nodes[0]
+}
+
+// evictSales периодически вытесняет старые продажи из памяти на диск.
+func evictSales(saleRepo *storage.SalesStorage, interval time.Duration, logger *logger.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		if err := saleRepo.Evict(now); err != nil {
+			logger.Errorf("cant evict sales: %v", err)
+		}
+	}
+}
+
+func NewServer(
+	cfg config.Config,
+	h *salesHttp.SalesHandler,
+	ch *salesHttp.CatalogHandler,
+	rh *salesHttp.ReplicationHandler,
+	rfh *salesHttp.ReferenceHandler,
+	nh *salesHttp.NodeHandler,
+	th *salesHttp.TargetHandler,
+	fh *salesHttp.ForecastHandler,
+	ah *salesHttp.AnomalyHandler,
+	ruh *salesHttp.RuleHandler,
+	sh *salesHttp.SQLHandler,
+	alh *salesHttp.AlertHandler,
+	reh *salesHttp.ReportHandler,
+	peh *salesHttp.RetentionHandler,
+) *fiber.App {
 	server := fiber.New(fiber.Config{
 		ReadTimeout:  readTimeout,
 		WriteTimeout: writeTimeout,
+		BodyLimit:    cfg.Limits.MaxBodySize,
 	})
 
-	server.Post("/data", h.AddSale)
-	server.Get("/data", h.GetSales)
-	server.Post("/calculate", h.CalculateTotalSum)
+	// ведомый экземпляр хранит копию данных ведущего, поэтому любая запись на нем перенаправляется на ведущий
+	write := func(handler fiber.Handler) fiber.Handler {
+		return handler
+	}
+
+	// оповещения, отчеты, правила и аномалии не реплицируются, поэтому и чтение их на ведомом перенаправляется
+	leaderOnly := func(handler fiber.Handler) fiber.Handler {
+		return handler
+	}
+	if cfg.Replication.Role == domain.RoleFollower {
+		readOnly := salesHttp.ReadOnly(cfg.Replication.LeaderURL)
+		write = func(fiber.Handler) fiber.Handler {
+			return readOnly
+		}
+
+		redirect := salesHttp.LeaderOnly(cfg.Replication.LeaderURL)
+		leaderOnly = func(fiber.Handler) fiber.Handler {
+			return redirect
+		}
+	}
+
+	// справочник и планы узел кластера, кроме первого, копирует с первого узла, поэтому их изменение
+	// перенаправляется на первый узел
+	reference := write
+	if source := referenceSource(cfg); source != "" && cfg.Replication.Role != domain.RoleFollower {
+		referenceCopy := salesHttp.ReferenceCopy(source)
+		reference = func(fiber.Handler) fiber.Handler {
+			return referenceCopy
+		}
+	}
+
+	// внутренний API узлов регистрируется до лимита клиентов: координатор пересылает на узел запросы
+	// многих клиентов, поэтому узлы подтверждают запросы секретом кластера, а не ограничиваются по IP-адресу
+	node := server.Group("/cluster", salesHttp.RequireToken(cluster.TokenHeader, cfg.Cluster.Token))
+	node.Post("/sales", write(nh.AddSale))
+	node.Post("/sales/insert", write(nh.InsertSale))
+	node.Get("/sales", nh.GetSales)
+	node.Post("/totals", nh.GetTotalSums)
+	node.Post("/totals_by_product", nh.GetTotalSumByProduct)
+	node.Post("/totals_by_dimension", nh.GetTotalSumByDimension)
+	node.Post("/receipts", write(nh.AddReceipt))
+	node.Post("/receipt_totals", nh.GetReceiptTotals)
+	node.Post("/sketches", nh.GetSketches)
+	node.Post("/query", nh.Query)
+	node.Post("/snapshots", nh.OpenSnapshot)
+	node.Delete("/snapshots/:snapshot", nh.CloseSnapshot)
+	node.Post("/purge", write(nh.Purge))
+	node.Get("/reference", rfh.Reference)
+
+	// журнал продаж ведомые читают так же, как узлы внутренний API: с секретом репликации и без лимита клиентов.
+	// Маршруты регистрируются по одному: группа /replication закрыла бы секретом и состояние репликации
+	replicationToken := salesHttp.RequireToken(replication.TokenHeader, cfg.Replication.Token)
+	server.Get("/replication/versions", replicationToken, rh.Versions)
+	server.Get("/replication/sales", replicationToken, rh.Sales)
+	server.Get("/replication/reference", replicationToken, rfh.Reference)
+
+	server.Use(salesHttp.RateLimit(
+		cfg.Limits.APIKeyHeader,
+		cfg.Limits.APIKeys,
+		ratelimit.New(cfg.Limits.APIKeyRate, cfg.Limits.APIKeyBurst),
+		ratelimit.New(cfg.Limits.IPRate, cfg.Limits.IPBurst),
+	))
+
+	heavy := salesHttp.ConcurrencyLimit(cfg.Limits.MaxConcurrentQueries, cfg.Limits.QueryRetryAfter)
+
+	server.Post("/data", write(h.AddSale))
+	server.Post("/receipts", write(h.AddReceipt))
+	server.Get("/data", heavy, h.GetSales)
+	server.Post("/calculate", heavy, h.CalculateTotalSum)
+	server.Post("/query", heavy, h.Query)
+	server.Post("/sql", heavy, sh.Query)
+	server.Post("/snapshots", h.OpenSnapshot)
+	server.Delete("/snapshots/:snapshot", h.CloseSnapshot)
+
+	server.Get("/targets", th.GetTargets)
+	server.Put("/targets", reference(th.SaveTargets))
+	server.Post("/targets/import", reference(th.ImportTargets))
+	server.Delete("/targets/:store_id/:start_date", reference(th.DeleteTarget))
+	server.Get("/targets/:store_id/attainment", heavy, th.GetAttainment)
+
+	server.Get("/forecast/:store_id", heavy, fh.Forecast)
+
+	server.Get("/rules", leaderOnly(ruh.GetRules))
+
+	server.Get("/alerts", leaderOnly(alh.GetAlerts))
+	server.Post("/alerts", write(alh.CreateAlert))
+	server.Get("/alerts/:id", leaderOnly(alh.GetAlert))
+	server.Put("/alerts/:id", write(alh.UpdateAlert))
+	server.Delete("/alerts/:id", write(alh.DeleteAlert))
+	server.Post("/alerts/:id/test", leaderOnly(alh.TestAlert))
+
+	server.Get("/reports", leaderOnly(reh.GetReports))
+	server.Post("/reports", write(reh.CreateReport))
+	server.Get("/reports/:id", leaderOnly(reh.GetReport))
+	server.Put("/reports/:id", write(reh.UpdateReport))
+	server.Delete("/reports/:id", write(reh.DeleteReport))
+	server.Post("/reports/:id/run", heavy, write(reh.RunReport))
+	server.Get("/reports/:id/runs", leaderOnly(reh.GetRuns))
+	server.Get("/reports/:id/runs/:run_id", leaderOnly(reh.GetRunContent))
+
+	server.Get("/anomalies", leaderOnly(ah.GetAnomalies))
+	server.Post("/anomalies/:id/accept", write(ah.AcceptAnomaly))
+	server.Post("/anomalies/:id/reject", write(ah.RejectAnomaly))
+
+	// удаление продаж необратимо, поэтому административный API доступен только с токеном администратора
+	admin := server.Group("/admin", salesHttp.RequireToken(salesHttp.AdminTokenHeader, cfg.Admin.Token))
+	admin.Post("/purge", write(peh.Purge))
+	admin.Get("/audit", peh.GetAuditLog)
+
+	server.Get("/replication/status", rh.Status)
+
+	server.Get("/stores", ch.GetStores)
+	server.Post("/stores/import", reference(ch.ImportStores))
+	server.Get("/stores/:store_id", ch.GetStore)
+	server.Put("/stores/:store_id", reference(ch.SaveStore))
+	server.Delete("/stores/:store_id", reference(ch.DeleteStore))
+
+	server.Get("/products", ch.GetProducts)
+	server.Post("/products/import", reference(ch.ImportProducts))
+	server.Get("/products/:product_id", ch.GetProduct)
+	server.Put("/products/:product_id", reference(ch.SaveProduct))
+	server.Delete("/products/:product_id", reference(ch.DeleteProduct))
 
 	return server
 }
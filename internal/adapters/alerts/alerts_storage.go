@@ -0,0 +1,146 @@
+package alerts
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+)
+
+// Storage описания оповещений. Оповещений немного, поэтому они хранятся в памяти в порядке создания,
+// а при каждом изменении целиком сохраняются в JSON-файл (если путь к файлу задан), как и планы выручки.
+type Storage struct {
+	alerts []*domain.Alert
+
+	path string
+
+	mu sync.RWMutex
+}
+
+// New возвращает оповещения, загруженные из файла path. Если путь пустой, оповещения не сохраняются на диск.
+func New(path string) (*Storage, error) {
+	s := &Storage{path: path}
+
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("read alerts: %w", err)
+	}
+
+	if err = json.Unmarshal(data, &s.alerts); err != nil {
+		return nil, fmt.Errorf("decode alerts: %w", err)
+	}
+
+	return s, nil
+}
+
+// SaveAlert создает оповещение или заменяет оповещение с тем же идентификатором.
+// Изменение применяется, только если его удалось сохранить.
+func (s *Storage) SaveAlert(alert *domain.Alert) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prev := s.alerts
+
+	alerts := append([]*domain.Alert(nil), s.alerts...)
+	if i := s.index(alert.ID); i >= 0 {
+		alerts[i] = alert
+	} else {
+		alerts = append(alerts, alert)
+	}
+
+	s.alerts = alerts
+
+	if err := s.persist(); err != nil {
+		s.alerts = prev
+
+		return err
+	}
+
+	return nil
+}
+
+func (s *Storage) DeleteAlert(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.index(id)
+	if i < 0 {
+		return domain.ErrAlertNotFound
+	}
+
+	prev := s.alerts
+	s.alerts = append(s.alerts[:i:i], s.alerts[i+1:]...)
+
+	if err := s.persist(); err != nil {
+		s.alerts = prev
+
+		return err
+	}
+
+	return nil
+}
+
+func (s *Storage) GetAlert(id string) (*domain.Alert, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	i := s.index(id)
+	if i < 0 {
+		return nil, domain.ErrAlertNotFound
+	}
+
+	return s.alerts[i], nil
+}
+
+// GetAlerts возвращает оповещения в порядке создания.
+func (s *Storage) GetAlerts() []*domain.Alert {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]*domain.Alert(nil), s.alerts...)
+}
+
+// index возвращает индекс оповещения с идентификатором id или -1. Вызывается под блокировкой.
+func (s *Storage) index(id string) int {
+	for i, alert := range s.alerts {
+		if alert.ID == id {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// persist сохраняет оповещения в файл через временный файл. Вызывается под блокировкой.
+func (s *Storage) persist() error {
+	if s.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(s.alerts, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode alerts: %w", err)
+	}
+
+	tmp := s.path + ".tmp"
+	if err = os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("write alerts: %w", err)
+	}
+
+	if err = os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("write alerts: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package alerts
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+)
+
+func TestStorage_Persistence(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "alerts.json")
+
+	s, err := New(path)
+	require.NoError(t, err)
+
+	revenue := &domain.Alert{
+		ID:         "a1",
+		Kind:       domain.AlertDailyRevenue,
+		StoreID:    "store_1",
+		Threshold:  decimal.NewFromInt(1000),
+		Currency:   "RUB",
+		Metric:     domain.MetricGross,
+		WebhookURL: "http://localhost/hook",
+		Secret:     "secret",
+	}
+	noSales := &domain.Alert{
+		ID:         "a2",
+		Kind:       domain.AlertNoSales,
+		Window:     domain.Duration(time.Hour),
+		WebhookURL: "http://localhost/hook",
+	}
+
+	require.NoError(t, s.SaveAlert(revenue))
+	require.NoError(t, s.SaveAlert(noSales))
+
+	// оповещение с тем же идентификатором заменяется на своем месте
+	updated := *revenue
+	updated.Threshold = decimal.NewFromInt(1500)
+	require.NoError(t, s.SaveAlert(&updated))
+
+	require.NoError(t, s.SaveAlert(&domain.Alert{ID: "a3", Kind: domain.AlertNoSales}))
+	require.NoError(t, s.DeleteAlert("a3"))
+	assert.ErrorIs(t, s.DeleteAlert("a3"), domain.ErrAlertNotFound)
+
+	// оповещения восстанавливаются из файла
+	restored, err := New(path)
+	require.NoError(t, err)
+
+	alerts := restored.GetAlerts()
+	require.Len(t, alerts, 2)
+	assert.Equal(t, "a1", alerts[0].ID)
+	assert.Equal(t, "1500", alerts[0].Threshold.String())
+	assert.Equal(t, "secret", alerts[0].Secret)
+	assert.Equal(t, domain.Duration(time.Hour), alerts[1].Window)
+
+	_, err = restored.GetAlert("a3")
+	assert.ErrorIs(t, err, domain.ErrAlertNotFound)
+}
+
+func TestStorage_PersistFailure(t *testing.T) {
+	t.Parallel()
+
+	// каталог для файла оповещений не существует, поэтому сохранение невозможно
+	s, err := New(filepath.Join(t.TempDir(), "missing", "alerts.json"))
+	require.NoError(t, err)
+
+	assert.Error(t, s.SaveAlert(&domain.Alert{ID: "a1"}))
+
+	// неудачное изменение не применяется
+	assert.Empty(t, s.GetAlerts())
+}
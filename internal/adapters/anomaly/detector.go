@@ -0,0 +1,220 @@
+package anomaly
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/shopspring/decimal"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+)
+
+const (
+	defaultWindow          = 100
+	defaultMinObservations = 20
+
+	// minReasonScore оценка признака, начиная с которой признак попадает в причины оценки
+	minReasonScore = 1
+)
+
+// признаки продажи. Значения признаков логарифмируются: цены и количества распределены скорее логнормально,
+// а ошибка ввода в порядке цены (199000 вместо 1990) дает одинаковое отклонение при любой цене.
+// Для признака задан минимальный разброс, чтобы товар с неизменной ценой не давал бесконечной оценки
+// при любом изменении цены.
+var (
+	priceFeature    = feature{name: "unit price", minSpread: 0.1}
+	quantityFeature = feature{name: "quantity", minSpread: 0.5}
+	amountFeature   = feature{name: "sale amount", minSpread: 0.5}
+)
+
+type feature struct {
+	name      string
+	minSpread float64
+}
+
+type Option func(d *Detector)
+
+// WithWindow задает окно скользящей статистики: вес продажи в статистике уменьшается вдвое примерно
+// через window/3 продаж.
+func WithWindow(window int) Option {
+	return func(d *Detector) {
+		if window > 0 {
+			d.alpha = 2 / (float64(window) + 1)
+		}
+	}
+}
+
+// WithMinObservations задает количество продаж, после которого статистика используется для оценки.
+func WithMinObservations(n int) Option {
+	return func(d *Detector) {
+		d.minObservations = n
+	}
+}
+
+// Detector оценивает продажи по скользящей статистике цены и количества товара в магазине и суммы строки
+// продажи в магазине. Сумма строки позволяет оценить продажи товаров, по которым статистики еще нет.
+// Статистика хранится в памяти и накапливается заново после перезапуска.
+type Detector struct {
+	alpha           float64
+	minObservations int
+
+	products map[productKey]*productStats
+	stores   map[storeKey]*ewm
+
+	mu sync.Mutex
+}
+
+type productKey struct {
+	storeID, productID, currency string
+}
+
+type storeKey struct {
+	storeID, currency string
+}
+
+type productStats struct {
+	price    ewm
+	quantity ewm
+}
+
+func New(opts ...Option) *Detector {
+	d := &Detector{
+		alpha:           2 / (float64(defaultWindow) + 1),
+		minObservations: defaultMinObservations,
+		products:        make(map[productKey]*productStats),
+		stores:          make(map[storeKey]*ewm),
+	}
+
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	return d
+}
+
+// Score возвращает наибольшее отклонение признаков продажи от статистики в стандартных отклонениях.
+// Признаки без достаточной статистики не оцениваются.
+func (d *Detector) Score(sale *domain.Sale) domain.AnomalyScore {
+	price, quantity, amount := values(sale)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var scores []featureScore
+
+	if p, ok := d.products[productKeyOf(sale)]; ok {
+		scores = append(scores,
+			d.score(priceFeature, &p.price, price),
+			d.score(quantityFeature, &p.quantity, quantity),
+		)
+	}
+
+	if s, ok := d.stores[storeKeyOf(sale)]; ok {
+		scores = append(scores, d.score(amountFeature, s, amount))
+	}
+
+	sort.Slice(scores, func(i, j int) bool { return scores[i].score > scores[j].score })
+
+	var res domain.AnomalyScore
+
+	for _, s := range scores {
+		if s.score < minReasonScore {
+			break
+		}
+
+		res.Score = math.Max(res.Score, s.score)
+		res.Reasons = append(res.Reasons, s.reason)
+	}
+
+	return res
+}
+
+// Observe учитывает продажу в статистике магазина и товара.
+func (d *Detector) Observe(sale *domain.Sale) {
+	price, quantity, amount := values(sale)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	p, ok := d.products[productKeyOf(sale)]
+	if !ok {
+		p = &productStats{}
+		d.products[productKeyOf(sale)] = p
+	}
+
+	p.price.add(price, d.alpha)
+	p.quantity.add(quantity, d.alpha)
+
+	s, ok := d.stores[storeKeyOf(sale)]
+	if !ok {
+		s = &ewm{}
+		d.stores[storeKeyOf(sale)] = s
+	}
+
+	s.add(amount, d.alpha)
+}
+
+type featureScore struct {
+	score  float64
+	reason string
+}
+
+// score возвращает отклонение значения признака от статистики. Вызывается под блокировкой.
+func (d *Detector) score(f feature, stats *ewm, value float64) featureScore {
+	if stats.n < d.minObservations {
+		return featureScore{}
+	}
+
+	score := math.Abs(value-stats.mean) / math.Max(math.Sqrt(stats.variance), f.minSpread)
+
+	return featureScore{
+		score: score,
+		reason: fmt.Sprintf("%s %s differs from typical %s (score %.1f)",
+			f.name, format(value), format(stats.mean), score),
+	}
+}
+
+// ewm экспоненциально взвешенные среднее и дисперсия.
+type ewm struct {
+	n        int
+	mean     float64
+	variance float64
+}
+
+func (e *ewm) add(x, alpha float64) {
+	e.n++
+
+	if e.n == 1 {
+		e.mean = x
+		return
+	}
+
+	diff := x - e.mean
+	incr := alpha * diff
+	e.mean += incr
+	e.variance = (1 - alpha) * (e.variance + diff*incr)
+}
+
+// values возвращает логарифмы цены, количества и валовой суммы строки продажи.
+func values(sale *domain.Sale) (float64, float64, float64) {
+	amount := decimal.NewFromInt(sale.QuantitySold).Mul(sale.SalePrice)
+
+	return math.Log1p(sale.SalePrice.InexactFloat64()),
+		math.Log1p(float64(sale.QuantitySold)),
+		math.Log1p(amount.InexactFloat64())
+}
+
+// format возвращает значение признака в исходном масштабе.
+func format(v float64) string {
+	return decimal.NewFromFloat(math.Expm1(v)).Round(domain.AmountPrecision).String()
+}
+
+func productKeyOf(sale *domain.Sale) productKey {
+	return productKey{storeID: sale.StoreID, productID: sale.ProductID, currency: sale.Currency}
+}
+
+func storeKeyOf(sale *domain.Sale) storeKey {
+	return storeKey{storeID: sale.StoreID, currency: sale.Currency}
+}
@@ -0,0 +1,76 @@
+package anomaly
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+)
+
+func TestDetector_Score(t *testing.T) {
+	t.Parallel()
+
+	sale := func(productID string, quantity int64, price string) *domain.Sale {
+		return &domain.Sale{
+			StoreID:      "store_1",
+			ProductID:    productID,
+			QuantitySold: quantity,
+			SalePrice:    decimal.RequireFromString(price),
+			Currency:     "RUB",
+			SaleDate:     time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+		}
+	}
+
+	d := New(WithWindow(50), WithMinObservations(10))
+
+	// до накопления статистики продажи не оцениваются
+	assert.Zero(t, d.Score(sale("product_1", 1, "199000")).Score)
+
+	for i := 0; i < 30; i++ {
+		d.Observe(sale("product_1", int64(1+i%3), fmt.Sprintf("%d", 1950+i*3)))
+	}
+
+	testCases := []struct {
+		name    string
+		sale    *domain.Sale
+		anomaly bool
+		reason  string
+	}{
+		{name: "обычная продажа", sale: sale("product_1", 2, "1990")},
+		{name: "цена по акции", sale: sale("product_1", 1, "1590")},
+		{name: "ошибка ввода цены", sale: sale("product_1", 1, "199000"), anomaly: true, reason: "unit price 199000"},
+		{name: "большое количество", sale: sale("product_1", 300, "1990"), anomaly: true, reason: "quantity 300"},
+		{name: "новый товар с обычной суммой", sale: sale("product_2", 1, "3500")},
+		{name: "новый товар с необычной суммой", sale: sale("product_2", 1, "500000"), anomaly: true, reason: "sale amount 500000"},
+	}
+
+	for _, tt := range testCases {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			score := d.Score(tt.sale)
+			if !tt.anomaly {
+				assert.Less(t, score.Score, 6.0, score.Reasons)
+				return
+			}
+
+			assert.GreaterOrEqual(t, score.Score, 6.0)
+			assert.Condition(t, func() bool {
+				for _, reason := range score.Reasons {
+					if strings.HasPrefix(reason, tt.reason) {
+						return true
+					}
+				}
+
+				return false
+			}, score.Reasons)
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package anomaly
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+)
+
+// Queue очередь проверки аномальных продаж. Аномалии хранятся в памяти в порядке обнаружения,
+// а при каждом изменении целиком сохраняются в JSON-файл (если путь к файлу задан), как и планы выручки.
+type Queue struct {
+	anomalies []*domain.Anomaly
+	index     map[string]int // индекс аномалии в anomalies по идентификатору
+
+	path string
+
+	mu sync.RWMutex
+}
+
+// NewQueue возвращает очередь, загруженную из файла path. Если путь пустой, очередь не сохраняется на диск.
+func NewQueue(path string) (*Queue, error) {
+	q := &Queue{
+		index: make(map[string]int),
+		path:  path,
+	}
+
+	if path == "" {
+		return q, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return q, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("read anomalies: %w", err)
+	}
+
+	var anomalies []*domain.Anomaly
+	if err = json.Unmarshal(data, &anomalies); err != nil {
+		return nil, fmt.Errorf("decode anomalies: %w", err)
+	}
+
+	for _, anomaly := range anomalies {
+		q.put(anomaly)
+	}
+
+	return q, nil
+}
+
+// SaveAnomaly добавляет аномалию в очередь или заменяет аномалию с тем же идентификатором.
+// Изменение применяется, только если его удалось сохранить.
+func (q *Queue) SaveAnomaly(anomaly *domain.Anomaly) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	i, exists := q.index[anomaly.ID]
+
+	var prev *domain.Anomaly
+	if exists {
+		prev = q.anomalies[i]
+	}
+
+	q.put(anomaly)
+
+	if err := q.persist(); err != nil {
+		if exists {
+			q.anomalies[i] = prev
+		} else {
+			q.anomalies = q.anomalies[:len(q.anomalies)-1]
+			delete(q.index, anomaly.ID)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func (q *Queue) GetAnomaly(id string) (*domain.Anomaly, error) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	i, ok := q.index[id]
+	if !ok {
+		return nil, domain.ErrAnomalyNotFound
+	}
+
+	return q.anomalies[i], nil
+}
+
+// GetAnomalies возвращает аномалии со статусом status (все, если status пустой) в порядке обнаружения.
+func (q *Queue) GetAnomalies(status string) []*domain.Anomaly {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	res := make([]*domain.Anomaly, 0, len(q.anomalies))
+
+	for _, anomaly := range q.anomalies {
+		if status == "" || anomaly.Status == status {
+			res = append(res, anomaly)
+		}
+	}
+
+	return res
+}
+
+// put добавляет аномалию или заменяет аномалию с тем же идентификатором. Вызывается под блокировкой.
+func (q *Queue) put(anomaly *domain.Anomaly) {
+	if i, ok := q.index[anomaly.ID]; ok {
+		q.anomalies[i] = anomaly
+		return
+	}
+
+	q.index[anomaly.ID] = len(q.anomalies)
+	q.anomalies = append(q.anomalies, anomaly)
+}
+
+// persist сохраняет очередь в файл через временный файл. Вызывается под блокировкой.
+func (q *Queue) persist() error {
+	if q.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(q.anomalies, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode anomalies: %w", err)
+	}
+
+	tmp := q.path + ".tmp"
+	if err = os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("write anomalies: %w", err)
+	}
+
+	if err = os.Rename(tmp, q.path); err != nil {
+		return fmt.Errorf("write anomalies: %w", err)
+	}
+
+	return nil
+}
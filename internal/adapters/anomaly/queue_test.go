@@ -0,0 +1,66 @@
+package anomaly
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+)
+
+func TestQueue(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "anomalies.json")
+
+	q, err := NewQueue(path)
+	require.NoError(t, err)
+
+	detectedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	first := &domain.Anomaly{
+		ID: "a1",
+		Sale: &domain.Sale{
+			StoreID:      "store_1",
+			ProductID:    "product_1",
+			QuantitySold: 1,
+			SalePrice:    decimal.NewFromInt(199000),
+			Currency:     "RUB",
+			SaleDate:     detectedAt,
+		},
+		Action:       domain.AnomalyActionQuarantine,
+		Status:       domain.AnomalyPending,
+		DetectedAt:   detectedAt,
+		AnomalyScore: domain.AnomalyScore{Score: 46, Reasons: []string{"unit price"}},
+	}
+
+	second := &domain.Anomaly{ID: "a2", Sale: first.Sale, Action: domain.AnomalyActionFlag, Status: domain.AnomalyPending}
+
+	require.NoError(t, q.SaveAnomaly(first))
+	require.NoError(t, q.SaveAnomaly(second))
+
+	resolved := *second
+	resolved.Status = domain.AnomalyRejected
+	require.NoError(t, q.SaveAnomaly(&resolved))
+
+	assert.Equal(t, []*domain.Anomaly{first}, q.GetAnomalies(domain.AnomalyPending))
+	assert.Equal(t, []*domain.Anomaly{first, &resolved}, q.GetAnomalies(""))
+
+	_, err = q.GetAnomaly("a3")
+	assert.ErrorIs(t, err, domain.ErrAnomalyNotFound)
+
+	// очередь восстанавливается из файла
+	loaded, err := NewQueue(path)
+	require.NoError(t, err)
+
+	a, err := loaded.GetAnomaly("a1")
+	require.NoError(t, err)
+	assert.Equal(t, first.AnomalyScore, a.AnomalyScore)
+	assert.Equal(t, "199000", a.Sale.SalePrice.String())
+	assert.True(t, detectedAt.Equal(a.DetectedAt))
+	assert.Len(t, loaded.GetAnomalies(domain.AnomalyRejected), 1)
+}
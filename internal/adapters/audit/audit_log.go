@@ -0,0 +1,118 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+)
+
+// Log журнал аудита административных операций. Записи хранятся в памяти и дописываются в файл по одной
+// JSON-записи на строку: в отличие от других хранилищ, файл журнала не перезаписывается, поэтому прежние
+// записи не теряются при сбое во время записи новой. Без файла записи хранятся только в памяти.
+type Log struct {
+	entries []*domain.AuditEntry
+
+	path string
+
+	mu sync.RWMutex
+}
+
+// New возвращает журнал, загруженный из файла path. Если путь пустой, журнал не сохраняется на диск.
+func New(path string) (*Log, error) {
+	l := &Log{path: path}
+
+	if path == "" {
+		return l, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return l, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("read audit log: %w", err)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, len(data)+1)
+
+	for n := 1; scanner.Scan(); n++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		var entry domain.AuditEntry
+		if err = json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("decode audit log line %d: %w", n, err)
+		}
+
+		l.entries = append(l.entries, &entry)
+	}
+
+	return l, nil
+}
+
+// Append добавляет запись в журнал. Запись добавляется, только если ее удалось сохранить.
+func (l *Log) Append(entry *domain.AuditEntry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.path != "" {
+		if err := l.write(entry); err != nil {
+			return err
+		}
+	}
+
+	l.entries = append(l.entries, entry)
+
+	return nil
+}
+
+// GetEntries возвращает записи журнала, последняя - первой.
+func (l *Log) GetEntries() []*domain.AuditEntry {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	res := make([]*domain.AuditEntry, len(l.entries))
+	for i, entry := range l.entries {
+		res[len(l.entries)-1-i] = entry
+	}
+
+	return res
+}
+
+// write дописывает запись в файл журнала и сбрасывает файл на диск. Вызывается под блокировкой.
+func (l *Log) write(entry *domain.AuditEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("encode audit entry: %w", err)
+	}
+
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("write audit log: %w", err)
+	}
+
+	if _, err = f.Write(append(data, '\n')); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("write audit log: %w", err)
+	}
+
+	if err = f.Sync(); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("write audit log: %w", err)
+	}
+
+	if err = f.Close(); err != nil {
+		return fmt.Errorf("write audit log: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+)
+
+func TestLog_Persistence(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	l, err := New(path)
+	require.NoError(t, err)
+
+	before := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	require.NoError(t, l.Append(&domain.AuditEntry{
+		ID:      "e1",
+		Time:    time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC),
+		Action:  domain.AuditPurge,
+		Actor:   domain.AuditRetention,
+		Purge:   &domain.Purge{Before: &before},
+		Deleted: 120,
+	}))
+	require.NoError(t, l.Append(&domain.AuditEntry{
+		ID:      "e2",
+		Action:  domain.AuditPurge,
+		Actor:   "10.0.0.1",
+		Purge:   &domain.Purge{StoreID: "store_1", Reason: "store closed"},
+		Deleted: 30,
+	}))
+
+	// записи дописываются в файл по одной на строку
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 2, countLines(data))
+
+	restored, err := New(path)
+	require.NoError(t, err)
+
+	entries := restored.GetEntries()
+	require.Len(t, entries, 2)
+	assert.Equal(t, "e2", entries[0].ID)
+	assert.Equal(t, "store closed", entries[0].Purge.Reason)
+	assert.Equal(t, before, *entries[1].Purge.Before)
+	assert.Equal(t, 120, entries[1].Deleted)
+
+	require.NoError(t, restored.Append(&domain.AuditEntry{ID: "e3", Action: domain.AuditPurge}))
+
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 3, countLines(data))
+}
+
+func TestLog_AppendFailure(t *testing.T) {
+	t.Parallel()
+
+	// каталог для файла журнала не существует, поэтому запись невозможна
+	l, err := New(filepath.Join(t.TempDir(), "missing", "audit.log"))
+	require.NoError(t, err)
+
+	assert.Error(t, l.Append(&domain.AuditEntry{ID: "e1"}))
+
+	// несохраненная запись не добавляется
+	assert.Empty(t, l.GetEntries())
+}
+
+func countLines(data []byte) int {
+	n := 0
+	for _, b := range data {
+		if b == '\n' {
+			n++
+		}
+	}
+
+	return n
+}
@@ -0,0 +1,255 @@
+package catalog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+)
+
+// snapshot содержимое файла справочника.
+type snapshot struct {
+	Stores   []*domain.Store   `json:"stores"`
+	Products []*domain.Product `json:"products"`
+}
+
+// Storage справочник магазинов и товаров. Справочник небольшой, поэтому хранится в памяти,
+// а при каждом изменении целиком сохраняется в JSON-файл (если путь к файлу задан).
+type Storage struct {
+	stores   map[string]*domain.Store
+	products map[string]*domain.Product
+
+	path string
+
+	mu sync.RWMutex
+}
+
+// New возвращает справочник, загруженный из файла path. Если путь пустой, справочник не сохраняется на диск.
+func New(path string) (*Storage, error) {
+	s := &Storage{
+		stores:   make(map[string]*domain.Store),
+		products: make(map[string]*domain.Product),
+		path:     path,
+	}
+
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("read catalog: %w", err)
+	}
+
+	var snap snapshot
+	if err = json.Unmarshal(data, &snap); err != nil {
+		return nil, fmt.Errorf("decode catalog: %w", err)
+	}
+
+	for _, store := range snap.Stores {
+		s.stores[store.ID] = store
+	}
+
+	for _, product := range snap.Products {
+		s.products[product.ID] = product
+	}
+
+	return s, nil
+}
+
+// SaveStores создает или обновляет магазины. Изменения применяются, только если их удалось сохранить.
+func (s *Storage) SaveStores(stores ...*domain.Store) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prev := make(map[string]*domain.Store, len(stores))
+	for _, store := range stores {
+		if _, ok := prev[store.ID]; !ok {
+			prev[store.ID] = s.stores[store.ID]
+		}
+
+		s.stores[store.ID] = store
+	}
+
+	if err := s.persist(); err != nil {
+		restore(s.stores, prev)
+
+		return err
+	}
+
+	return nil
+}
+
+// DeleteStore удаляет магазин.
+func (s *Storage) DeleteStore(storeID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	store, ok := s.stores[storeID]
+	if !ok {
+		return domain.ErrStoreNotFound
+	}
+
+	delete(s.stores, storeID)
+
+	if err := s.persist(); err != nil {
+		s.stores[storeID] = store
+
+		return err
+	}
+
+	return nil
+}
+
+// GetStore возвращает магазин по идентификатору.
+func (s *Storage) GetStore(storeID string) (*domain.Store, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	store, ok := s.stores[storeID]
+
+	return store, ok
+}
+
+// GetStores возвращает все магазины, упорядоченные по идентификатору.
+func (s *Storage) GetStores() []*domain.Store {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stores := make([]*domain.Store, 0, len(s.stores))
+	for _, store := range s.stores {
+		stores = append(stores, store)
+	}
+
+	sort.Slice(stores, func(i, j int) bool { return stores[i].ID < stores[j].ID })
+
+	return stores
+}
+
+// SaveProducts создает или обновляет товары. Изменения применяются, только если их удалось сохранить.
+func (s *Storage) SaveProducts(products ...*domain.Product) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prev := make(map[string]*domain.Product, len(products))
+	for _, product := range products {
+		if _, ok := prev[product.ID]; !ok {
+			prev[product.ID] = s.products[product.ID]
+		}
+
+		s.products[product.ID] = product
+	}
+
+	if err := s.persist(); err != nil {
+		restore(s.products, prev)
+
+		return err
+	}
+
+	return nil
+}
+
+// DeleteProduct удаляет товар.
+func (s *Storage) DeleteProduct(productID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	product, ok := s.products[productID]
+	if !ok {
+		return domain.ErrProductNotFound
+	}
+
+	delete(s.products, productID)
+
+	if err := s.persist(); err != nil {
+		s.products[productID] = product
+
+		return err
+	}
+
+	return nil
+}
+
+// GetProduct возвращает товар по идентификатору.
+func (s *Storage) GetProduct(productID string) (*domain.Product, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	product, ok := s.products[productID]
+
+	return product, ok
+}
+
+// GetProducts возвращает все товары, упорядоченные по идентификатору.
+func (s *Storage) GetProducts() []*domain.Product {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	products := make([]*domain.Product, 0, len(s.products))
+	for _, product := range s.products {
+		products = append(products, product)
+	}
+
+	sort.Slice(products, func(i, j int) bool { return products[i].ID < products[j].ID })
+
+	return products
+}
+
+// persist сохраняет справочник в файл. Файл записывается во временный и затем переименовывается,
+// чтобы при сбое на диске не остался частично записанный справочник. Вызывается под блокировкой.
+func (s *Storage) persist() error {
+	if s.path == "" {
+		return nil
+	}
+
+	snap := snapshot{
+		Stores:   make([]*domain.Store, 0, len(s.stores)),
+		Products: make([]*domain.Product, 0, len(s.products)),
+	}
+
+	for _, store := range s.stores {
+		snap.Stores = append(snap.Stores, store)
+	}
+
+	for _, product := range s.products {
+		snap.Products = append(snap.Products, product)
+	}
+
+	sort.Slice(snap.Stores, func(i, j int) bool { return snap.Stores[i].ID < snap.Stores[j].ID })
+	sort.Slice(snap.Products, func(i, j int) bool { return snap.Products[i].ID < snap.Products[j].ID })
+
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode catalog: %w", err)
+	}
+
+	tmp := s.path + ".tmp"
+	if err = os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("write catalog: %w", err)
+	}
+
+	if err = os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("write catalog: %w", err)
+	}
+
+	return nil
+}
+
+// restore возвращает записи справочника к предыдущим значениям (nil - записи не было).
+func restore[T any](items map[string]*T, prev map[string]*T) {
+	for id, item := range prev {
+		if item == nil {
+			delete(items, id)
+		} else {
+			items[id] = item
+		}
+	}
+}
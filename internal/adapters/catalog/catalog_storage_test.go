@@ -0,0 +1,53 @@
+package catalog
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+)
+
+func TestStorage_Persistence(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "catalog.json")
+
+	s, err := New(path)
+	require.NoError(t, err)
+
+	store := &domain.Store{
+		ID:         "store_1",
+		Name:       "Тверская",
+		TimeZone:   "Europe/Moscow",
+		Attributes: map[string]string{"region": "center"},
+	}
+
+	require.NoError(t, s.SaveStores(store, &domain.Store{ID: "store_2"}))
+	require.NoError(t, s.SaveProducts(&domain.Product{ID: "product_1", Category: "dairy"}))
+	require.NoError(t, s.DeleteStore("store_2"))
+	assert.ErrorIs(t, s.DeleteStore("store_2"), domain.ErrStoreNotFound)
+
+	// справочник восстанавливается из файла
+	restored, err := New(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, []*domain.Store{store}, restored.GetStores())
+	assert.Equal(t, []*domain.Product{{ID: "product_1", Category: "dairy"}}, restored.GetProducts())
+}
+
+func TestStorage_PersistFailure(t *testing.T) {
+	t.Parallel()
+
+	// каталог для файла справочника не существует, поэтому сохранение невозможно
+	s, err := New(filepath.Join(t.TempDir(), "missing", "catalog.json"))
+	require.NoError(t, err)
+
+	assert.Error(t, s.SaveStores(&domain.Store{ID: "store_1"}))
+
+	// неудачное изменение не применяется
+	_, ok := s.GetStore("store_1")
+	assert.False(t, ok)
+}
@@ -0,0 +1,356 @@
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+	"go.dataflow.ru/service-sales/internal/app/ports"
+)
+
+// TokenHeader заголовок с общим секретом узлов кластера, которым узел подтверждает запросы к внутреннему API.
+const TokenHeader = "X-Cluster-Token"
+
+// ErrNodeRateLimited узел отклонил запрос из-за превышения лимита запросов.
+var ErrNodeRateLimited = errors.New("cluster node rate limit exceeded")
+
+// statusErrors ошибки хранилища узла, которые эндпоинт внутреннего API возвращает с соответствующим кодом ответа.
+type statusErrors map[int]error
+
+var (
+	addSaleErrors    = statusErrors{http.StatusBadRequest: domain.ErrSaleOutOfRange}
+	addReceiptErrors = statusErrors{
+		http.StatusBadRequest: domain.ErrSaleOutOfRange,
+		http.StatusConflict:   domain.ErrReceiptExists,
+	}
+	readErrors = statusErrors{
+		http.StatusNotFound:              domain.ErrSnapshotNotFound,
+		http.StatusRequestEntityTooLarge: domain.ErrQueryTooLarge,
+	}
+	openSnapshotErrors  = statusErrors{http.StatusTooManyRequests: domain.ErrTooManySnapshots}
+	closeSnapshotErrors = statusErrors{http.StatusNotFound: domain.ErrSnapshotNotFound}
+	purgeErrors         = statusErrors{http.StatusBadRequest: domain.ErrInvalidPurge}
+)
+
+// Client хранилище продаж другого узла кластера: запросы выполняются через внутренний API узла /cluster/*,
+// который работает только с локальным хранилищем узла.
+type Client struct {
+	baseURL  string
+	token    string // общий секрет узлов кластера
+	http     *http.Client
+	snapshot string // токен снимка узла, в котором выполняется чтение
+}
+
+// NewClient возвращает клиент хранилища узла с адресом baseURL. Запросы подписываются секретом кластера token.
+func NewClient(baseURL, token string, client *http.Client) *Client {
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		token:   token,
+		http:    client,
+	}
+}
+
+type totalsRequest struct {
+	Snapshot string               `json:"snapshot"`
+	Queries  []domain.StorePeriod `json:"queries"`
+}
+
+// storePeriodRequest запрос по магазину узла за период.
+type storePeriodRequest struct {
+	Snapshot string `json:"snapshot"`
+	domain.StorePeriod
+}
+
+type totalsByDimensionRequest struct {
+	storePeriodRequest
+	Dimension string `json:"dimension"`
+}
+
+// queryRequest запрос агрегатов продаж узла. Пояс разбивки передается именем из базы часовых поясов.
+type queryRequest struct {
+	Snapshot  string              `json:"snapshot"`
+	StartDate time.Time           `json:"start_date"`
+	EndDate   time.Time           `json:"end_date"`
+	Filters   map[string][]string `json:"filters,omitempty"`
+	GroupBy   []string            `json:"group_by,omitempty"`
+	Metrics   []string            `json:"metrics"`
+	Bucket    string              `json:"bucket,omitempty"`
+	TimeZone  string              `json:"time_zone,omitempty"`
+}
+
+// AddSale сохраняет продажу в хранилище узла.
+func (c *Client) AddSale(sale *domain.Sale) error {
+	return c.do(http.MethodPost, "/cluster/sales", sale, nil, addSaleErrors)
+}
+
+// InsertSale сохраняет продажу, которая может быть раньше последней продажи магазина, в хранилище узла.
+func (c *Client) InsertSale(sale *domain.Sale) error {
+	return c.do(http.MethodPost, "/cluster/sales/insert", sale, nil, addSaleErrors)
+}
+
+// AddReceipt сохраняет чек в хранилище узла.
+func (c *Client) AddReceipt(receipt *domain.Receipt) error {
+	return c.do(http.MethodPost, "/cluster/receipts", receipt, nil, addReceiptErrors)
+}
+
+// GetSales возвращает продажи узла.
+func (c *Client) GetSales() ([]*domain.Sale, error) {
+	path := "/cluster/sales"
+	if c.snapshot != "" {
+		path += "?snapshot=" + url.QueryEscape(c.snapshot)
+	}
+
+	var sales []*domain.Sale
+	if err := c.do(http.MethodGet, path, nil, &sales, readErrors); err != nil {
+		return nil, err
+	}
+
+	return sales, nil
+}
+
+// ScanSales передает fn продажи узла. Продажи узла загружаются одним запросом, поэтому отмена ctx проверяется
+// перед запросом; во время передачи ее проверяет fn.
+func (c *Client) ScanSales(ctx context.Context, fn func(sale *domain.Sale) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	sales, err := c.GetSales()
+	if err != nil {
+		return err
+	}
+
+	for _, sale := range sales {
+		if err = fn(sale); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetTotalSum возвращает суммы продаж магазина узла за период.
+func (c *Client) GetTotalSum(storeID string, startDate, endDate time.Time) (domain.Totals, error) {
+	totals, err := c.GetTotalSums([]domain.StorePeriod{{StoreID: storeID, StartDate: startDate, EndDate: endDate}})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(totals) != 1 {
+		return nil, fmt.Errorf("node %s returned %d totals, expected 1", c.baseURL, len(totals))
+	}
+
+	return totals[0], nil
+}
+
+// GetTotalSumByProduct возвращает суммы продаж магазина узла за период в разрезе товаров.
+func (c *Client) GetTotalSumByProduct(storeID string, startDate, endDate time.Time) (map[string]domain.Totals, error) {
+	req := storePeriodRequest{
+		Snapshot:    c.snapshot,
+		StorePeriod: domain.StorePeriod{StoreID: storeID, StartDate: startDate, EndDate: endDate},
+	}
+
+	var res map[string]domain.Totals
+	if err := c.do(http.MethodPost, "/cluster/totals_by_product", req, &res, readErrors); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// GetTotalSumByDimension возвращает суммы продаж магазина узла за период в разрезе значений измерения.
+func (c *Client) GetTotalSumByDimension(storeID, dimension string, startDate, endDate time.Time) (map[string]domain.Totals, error) {
+	req := totalsByDimensionRequest{
+		storePeriodRequest: storePeriodRequest{
+			Snapshot:    c.snapshot,
+			StorePeriod: domain.StorePeriod{StoreID: storeID, StartDate: startDate, EndDate: endDate},
+		},
+		Dimension: dimension,
+	}
+
+	var res map[string]domain.Totals
+	if err := c.do(http.MethodPost, "/cluster/totals_by_dimension", req, &res, readErrors); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// GetReceiptTotals возвращает показатели чеков магазина узла за период.
+func (c *Client) GetReceiptTotals(storeID string, startDate, endDate time.Time) (domain.ReceiptTotals, error) {
+	req := storePeriodRequest{
+		Snapshot:    c.snapshot,
+		StorePeriod: domain.StorePeriod{StoreID: storeID, StartDate: startDate, EndDate: endDate},
+	}
+
+	var res domain.ReceiptTotals
+	if err := c.do(http.MethodPost, "/cluster/receipt_totals", req, &res, readErrors); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// GetSketches возвращает приближенную статистику продаж магазина узла за период.
+func (c *Client) GetSketches(storeID string, startDate, endDate time.Time) (*domain.SalesSketches, error) {
+	req := storePeriodRequest{
+		Snapshot:    c.snapshot,
+		StorePeriod: domain.StorePeriod{StoreID: storeID, StartDate: startDate, EndDate: endDate},
+	}
+
+	res := domain.NewSalesSketches()
+	if err := c.do(http.MethodPost, "/cluster/sketches", req, res, readErrors); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// Query выполняет запрос агрегатов продаж магазинов узла.
+func (c *Client) Query(q domain.Query) (*domain.QueryResult, error) {
+	req := queryRequest{
+		Snapshot:  c.snapshot,
+		StartDate: q.StartDate,
+		EndDate:   q.EndDate,
+		Filters:   q.Filters,
+		GroupBy:   q.GroupBy,
+		Metrics:   q.Metrics,
+		Bucket:    q.Bucket,
+	}
+
+	if q.Location != nil {
+		req.TimeZone = q.Location.String()
+	}
+
+	var res domain.QueryResult
+	if err := c.do(http.MethodPost, "/cluster/query", req, &res, readErrors); err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+// GetTotalSums возвращает суммы продаж магазинов узла, каждого за свой период.
+func (c *Client) GetTotalSums(queries []domain.StorePeriod) ([]domain.Totals, error) {
+	var res []domain.Totals
+	if err := c.do(http.MethodPost, "/cluster/totals", totalsRequest{Snapshot: c.snapshot, Queries: queries}, &res, readErrors); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// OpenSnapshot открывает снимок продаж узла.
+func (c *Client) OpenSnapshot() (domain.Snapshot, error) {
+	var snapshot domain.Snapshot
+	if err := c.do(http.MethodPost, "/cluster/snapshots", nil, &snapshot, openSnapshotErrors); err != nil {
+		return domain.Snapshot{}, err
+	}
+
+	return snapshot, nil
+}
+
+// Snapshot возвращает чтение продаж узла в снимке token. Снимок проверяется узлом при чтении.
+func (c *Client) Snapshot(token string) (ports.SalesReader, error) {
+	s := *c
+	s.snapshot = token
+
+	return &s, nil
+}
+
+// CloseSnapshot закрывает снимок продаж узла.
+func (c *Client) CloseSnapshot(token string) error {
+	return c.do(http.MethodDelete, "/cluster/snapshots/"+url.PathEscape(token), nil, nil, closeSnapshotErrors)
+}
+
+// purgeResponse результат удаления продаж узла.
+type purgeResponse struct {
+	Deleted int `json:"deleted"`
+}
+
+// Purge удаляет продажи в хранилище узла.
+func (c *Client) Purge(purge domain.Purge) (int, error) {
+	var res purgeResponse
+	if err := c.do(http.MethodPost, "/cluster/purge", purge, &res, purgeErrors); err != nil {
+		return 0, err
+	}
+
+	return res.Deleted, nil
+}
+
+// Reference возвращает справочник магазинов и товаров и планы узла.
+func (c *Client) Reference() (domain.ReferenceData, error) {
+	var ref domain.ReferenceData
+	if err := c.do(http.MethodGet, "/cluster/reference", nil, &ref, nil); err != nil {
+		return domain.ReferenceData{}, err
+	}
+
+	return ref, nil
+}
+
+// do выполняет запрос к внутреннему API узла. Ответ с ошибкой восстанавливается в ошибку хранилища по errs.
+func (c *Client) do(method, path string, body, res interface{}, errs statusErrors) error {
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("encode request to node %s: %w", c.baseURL, err)
+		}
+
+		reqBody = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, c.baseURL+path, reqBody)
+	if err != nil {
+		return fmt.Errorf("request node %s: %w", c.baseURL, err)
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	req.Header.Set(TokenHeader, c.token)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("request node %s: %w", c.baseURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return c.statusError(resp.StatusCode, string(msg), errs)
+	}
+
+	if res == nil {
+		return nil
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(res); err != nil {
+		return fmt.Errorf("decode response of node %s: %w", c.baseURL, err)
+	}
+
+	return nil
+}
+
+// statusError восстанавливает ошибку хранилища узла по коду ответа эндпоинта внутреннего API. Коды, которые
+// эндпоинт не использует для ошибок хранилища, возвращаются общей ошибкой узла.
+func (c *Client) statusError(status int, msg string, errs statusErrors) error {
+	if err, ok := errs[status]; ok {
+		return fmt.Errorf("%s: %w", msg, err)
+	}
+
+	if status == http.StatusTooManyRequests {
+		return fmt.Errorf("node %s: %w", c.baseURL, ErrNodeRateLimited)
+	}
+
+	return fmt.Errorf("node %s error: status %d: %s", c.baseURL, status, msg)
+}
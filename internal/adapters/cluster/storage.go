@@ -0,0 +1,416 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+	"go.dataflow.ru/service-sales/internal/app/ports"
+	"go.dataflow.ru/service-sales/pkg/hashring"
+)
+
+const (
+	defaultVirtualNodes = 128
+	defaultTimeout      = 10 * time.Second
+
+	// разделитель токенов снимков узлов в токене снимка кластера
+	tokenSeparator = "."
+)
+
+// Storage хранилище продаж кластера. Магазины распределены между узлами кольцом консистентного хеширования
+// по идентификатору магазина: продажа сохраняется, а суммы магазина считаются на узле-владельце магазина.
+// Запросы по всем магазинам (выгрузка продаж, суммы по группам магазинов) рассылаются узлам параллельно,
+// и их результаты объединяются. Состав кластера задается статически и одинаков на всех узлах.
+type Storage struct {
+	ring  *hashring.Ring
+	nodes map[string]ports.SalesStorage // хранилища узлов по адресу, для текущего узла - локальное хранилище
+
+	router
+}
+
+type Option func(s *options)
+
+type options struct {
+	virtualNodes int
+	token        string
+	http         *http.Client
+}
+
+// WithVirtualNodes задает количество точек каждого узла на кольце.
+func WithVirtualNodes(n int) Option {
+	return func(o *options) {
+		o.virtualNodes = n
+	}
+}
+
+// WithToken задает общий секрет узлов кластера для запросов к другим узлам.
+func WithToken(token string) Option {
+	return func(o *options) {
+		o.token = token
+	}
+}
+
+// WithHTTPClient задает HTTP-клиент для запросов к другим узлам.
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *options) {
+		o.http = client
+	}
+}
+
+// New возвращает хранилище кластера из узлов nodes, текущий узел self работает с хранилищем local.
+func New(self string, nodes []string, local ports.SalesStorage, opts ...Option) (*Storage, error) {
+	o := options{
+		virtualNodes: defaultVirtualNodes,
+		http:         &http.Client{Timeout: defaultTimeout},
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	s := &Storage{
+		ring:  hashring.New(nodes, o.virtualNodes),
+		nodes: make(map[string]ports.SalesStorage, len(nodes)),
+	}
+
+	for _, node := range nodes {
+		if _, ok := s.nodes[node]; ok {
+			return nil, fmt.Errorf("duplicate cluster node %s", node)
+		}
+
+		if node == self {
+			s.nodes[node] = local
+		} else {
+			s.nodes[node] = NewClient(node, o.token, o.http)
+		}
+	}
+
+	if _, ok := s.nodes[self]; !ok {
+		return nil, fmt.Errorf("node %s is not a cluster member", self)
+	}
+
+	s.router = router{ring: s.ring, readers: make(map[string]ports.SalesReader, len(nodes))}
+	for node, storage := range s.nodes {
+		s.readers[node] = storage
+	}
+
+	return s, nil
+}
+
+// AddSale сохраняет продажу на узле-владельце магазина.
+func (s *Storage) AddSale(sale *domain.Sale) error {
+	return s.nodes[s.ring.Owner(sale.StoreID)].AddSale(sale)
+}
+
+// InsertSale сохраняет продажу, которая может быть раньше последней продажи магазина, на узле-владельце магазина.
+func (s *Storage) InsertSale(sale *domain.Sale) error {
+	return s.nodes[s.ring.Owner(sale.StoreID)].InsertSale(sale)
+}
+
+// AddReceipt сохраняет чек на узле-владельце магазина.
+func (s *Storage) AddReceipt(receipt *domain.Receipt) error {
+	return s.nodes[s.ring.Owner(receipt.StoreID)].AddReceipt(receipt)
+}
+
+// OpenSnapshot открывает снимок на всех узлах. Токен снимка кластера состоит из токенов снимков узлов.
+func (s *Storage) OpenSnapshot() (domain.Snapshot, error) {
+	nodes := s.ring.Nodes()
+	snapshots := make([]domain.Snapshot, len(nodes))
+
+	err := scatter(nodes, func(i int, node string) error {
+		snapshot, err := s.nodes[node].OpenSnapshot()
+		snapshots[i] = snapshot
+
+		return err
+	})
+	if err != nil {
+		// снимки, открытые на остальных узлах, закрываются, чтобы не занимать лимит до истечения срока
+		for i, node := range nodes {
+			if snapshots[i].Token != "" {
+				_ = s.nodes[node].CloseSnapshot(snapshots[i].Token)
+			}
+		}
+
+		return domain.Snapshot{}, err
+	}
+
+	res := domain.Snapshot{Versions: make(map[string]int)}
+	tokens := make([]string, 0, len(nodes))
+
+	for _, snapshot := range snapshots {
+		tokens = append(tokens, snapshot.Token)
+
+		if res.ExpiresAt.IsZero() || snapshot.ExpiresAt.Before(res.ExpiresAt) {
+			res.ExpiresAt = snapshot.ExpiresAt
+		}
+
+		for storeID, version := range snapshot.Versions {
+			res.Versions[storeID] = version
+		}
+	}
+
+	res.Token = strings.Join(tokens, tokenSeparator)
+
+	return res, nil
+}
+
+// Snapshot возвращает чтение продаж кластера в снимке token.
+func (s *Storage) Snapshot(token string) (ports.SalesReader, error) {
+	tokens, err := s.splitToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &router{ring: s.ring, readers: make(map[string]ports.SalesReader, len(tokens))}
+
+	for node, token := range tokens {
+		reader, err := s.nodes[node].Snapshot(token)
+		if err != nil {
+			return nil, err
+		}
+
+		r.readers[node] = reader
+	}
+
+	return r, nil
+}
+
+// CloseSnapshot закрывает снимок на всех узлах.
+func (s *Storage) CloseSnapshot(token string) error {
+	tokens, err := s.splitToken(token)
+	if err != nil {
+		return err
+	}
+
+	return scatter(s.ring.Nodes(), func(_ int, node string) error {
+		return s.nodes[node].CloseSnapshot(tokens[node])
+	})
+}
+
+// Purge удаляет продажи магазина на узле-владельце, продажи всех магазинов - на всех узлах.
+func (s *Storage) Purge(purge domain.Purge) (int, error) {
+	if purge.StoreID != "" {
+		return s.nodes[s.ring.Owner(purge.StoreID)].Purge(purge)
+	}
+
+	nodes := s.ring.Nodes()
+	deleted := make([]int, len(nodes))
+
+	err := scatter(nodes, func(i int, node string) error {
+		n, err := s.nodes[node].Purge(purge)
+		deleted[i] = n
+
+		return err
+	})
+
+	total := 0
+	for _, n := range deleted {
+		total += n
+	}
+
+	return total, err
+}
+
+// splitToken возвращает токены снимков узлов из токена снимка кластера.
+func (s *Storage) splitToken(token string) (map[string]string, error) {
+	nodes := s.ring.Nodes()
+
+	parts := strings.Split(token, tokenSeparator)
+	if len(parts) != len(nodes) {
+		return nil, domain.ErrSnapshotNotFound
+	}
+
+	tokens := make(map[string]string, len(nodes))
+	for i, node := range nodes {
+		tokens[node] = parts[i]
+	}
+
+	return tokens, nil
+}
+
+// router направляет чтение продаж магазина на узел-владелец, а чтение по всем магазинам - на все узлы.
+type router struct {
+	ring    *hashring.Ring
+	readers map[string]ports.SalesReader
+}
+
+// GetSales возвращает продажи всех узлов.
+func (r *router) GetSales() ([]*domain.Sale, error) {
+	nodes := r.ring.Nodes()
+	sales := make([][]*domain.Sale, len(nodes))
+
+	err := scatter(nodes, func(i int, node string) error {
+		nodeSales, err := r.readers[node].GetSales()
+		sales[i] = nodeSales
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var res []*domain.Sale
+	for _, nodeSales := range sales {
+		res = append(res, nodeSales...)
+	}
+
+	return res, nil
+}
+
+// ScanSales передает fn продажи узлов по очереди: fn не вызывается параллельно.
+func (r *router) ScanSales(ctx context.Context, fn func(sale *domain.Sale) error) error {
+	for _, node := range r.ring.Nodes() {
+		if err := r.readers[node].ScanSales(ctx, fn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetTotalSum возвращает суммы продаж магазина за период с узла-владельца магазина.
+func (r *router) GetTotalSum(storeID string, startDate, endDate time.Time) (domain.Totals, error) {
+	return r.readers[r.ring.Owner(storeID)].GetTotalSum(storeID, startDate, endDate)
+}
+
+// GetTotalSumByProduct возвращает суммы продаж магазина за период в разрезе товаров с узла-владельца магазина.
+func (r *router) GetTotalSumByProduct(storeID string, startDate, endDate time.Time) (map[string]domain.Totals, error) {
+	return r.readers[r.ring.Owner(storeID)].GetTotalSumByProduct(storeID, startDate, endDate)
+}
+
+// GetTotalSumByDimension возвращает суммы продаж магазина за период в разрезе значений измерения
+// с узла-владельца магазина.
+func (r *router) GetTotalSumByDimension(storeID, dimension string, startDate, endDate time.Time) (map[string]domain.Totals, error) {
+	return r.readers[r.ring.Owner(storeID)].GetTotalSumByDimension(storeID, dimension, startDate, endDate)
+}
+
+// GetReceiptTotals возвращает показатели чеков магазина за период с узла-владельца магазина.
+func (r *router) GetReceiptTotals(storeID string, startDate, endDate time.Time) (domain.ReceiptTotals, error) {
+	return r.readers[r.ring.Owner(storeID)].GetReceiptTotals(storeID, startDate, endDate)
+}
+
+// GetSketches возвращает приближенную статистику продаж магазина за период с узла-владельца магазина.
+func (r *router) GetSketches(storeID string, startDate, endDate time.Time) (*domain.SalesSketches, error) {
+	return r.readers[r.ring.Owner(storeID)].GetSketches(storeID, startDate, endDate)
+}
+
+// Query выполняет запрос агрегатов продаж на узлах-владельцах магазинов фильтра (без фильтра по магазинам -
+// на всех узлах) и объединяет строки результатов узлов.
+func (r *router) Query(q domain.Query) (*domain.QueryResult, error) {
+	nodes := r.ring.Nodes()
+
+	if stores, ok := q.Filters[domain.FieldStore]; ok {
+		owners := make(map[string]bool, len(stores))
+		nodes = nodes[:0:0]
+
+		for _, storeID := range stores {
+			if node := r.ring.Owner(storeID); !owners[node] {
+				owners[node] = true
+				nodes = append(nodes, node)
+			}
+		}
+	}
+
+	results := make([]*domain.QueryResult, len(nodes))
+
+	err := scatter(nodes, func(i int, node string) error {
+		res, err := r.readers[node].Query(q)
+		results[i] = res
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := &domain.QueryResult{}
+	rows := make([][]domain.QueryRow, 0, len(results))
+
+	for _, nodeRes := range results {
+		res.Plan = nodeRes.Plan
+		rows = append(rows, nodeRes.Rows)
+	}
+
+	res.Rows = domain.MergeQueryRows(rows...)
+
+	return res, nil
+}
+
+// GetTotalSums возвращает суммы продаж магазинов: запросы группируются по узлам-владельцам магазинов,
+// и каждый узел получает один запрос.
+func (r *router) GetTotalSums(queries []domain.StorePeriod) ([]domain.Totals, error) {
+	var (
+		nodes   []string
+		byNode  = make(map[string][]int) // индексы запросов по узлам
+		results = make([]domain.Totals, len(queries))
+	)
+
+	for i, q := range queries {
+		node := r.ring.Owner(q.StoreID)
+		if _, ok := byNode[node]; !ok {
+			nodes = append(nodes, node)
+		}
+
+		byNode[node] = append(byNode[node], i)
+	}
+
+	err := scatter(nodes, func(_ int, node string) error {
+		nodeQueries := make([]domain.StorePeriod, 0, len(byNode[node]))
+		for _, i := range byNode[node] {
+			nodeQueries = append(nodeQueries, queries[i])
+		}
+
+		totals, err := r.readers[node].GetTotalSums(nodeQueries)
+		if err != nil {
+			return err
+		}
+
+		if len(totals) != len(nodeQueries) {
+			return fmt.Errorf("node %s returned %d totals, expected %d", node, len(totals), len(nodeQueries))
+		}
+
+		for j, i := range byNode[node] {
+			results[i] = totals[j]
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// scatter параллельно вызывает fn для каждого узла и возвращает первую ошибку.
+func scatter(nodes []string, fn func(i int, node string) error) error {
+	errs := make([]error, len(nodes))
+
+	var wg sync.WaitGroup
+
+	for i, node := range nodes {
+		wg.Add(1)
+
+		go func(i int, node string) {
+			defer wg.Done()
+
+			if err := fn(i, node); err != nil {
+				errs[i] = fmt.Errorf("node %s: %w", node, err)
+			}
+		}(i, node)
+	}
+
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
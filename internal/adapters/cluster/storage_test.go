@@ -0,0 +1,330 @@
+package cluster
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.dataflow.ru/service-sales/internal/adapters/catalog"
+	salesHttp "go.dataflow.ru/service-sales/internal/adapters/http"
+	"go.dataflow.ru/service-sales/internal/adapters/storage"
+	"go.dataflow.ru/service-sales/internal/adapters/targets"
+	"go.dataflow.ru/service-sales/internal/app/domain"
+	"go.dataflow.ru/service-sales/internal/app/services"
+	"go.dataflow.ru/service-sales/pkg/logger"
+)
+
+const testToken = "secret"
+
+// nodeApp возвращает приложение fiber с внутренним API узла над хранилищем local.
+func nodeApp(local *storage.SalesStorage) *fiber.App {
+	nh := salesHttp.NewNodeHandler(local)
+	app := fiber.New()
+
+	node := app.Group("/cluster", salesHttp.RequireToken(TokenHeader, testToken))
+	node.Post("/sales", nh.AddSale)
+	node.Post("/sales/insert", nh.InsertSale)
+	node.Get("/sales", nh.GetSales)
+	node.Post("/totals", nh.GetTotalSums)
+	node.Post("/totals_by_product", nh.GetTotalSumByProduct)
+	node.Post("/totals_by_dimension", nh.GetTotalSumByDimension)
+	node.Post("/receipts", nh.AddReceipt)
+	node.Post("/receipt_totals", nh.GetReceiptTotals)
+	node.Post("/sketches", nh.GetSketches)
+	node.Post("/query", nh.Query)
+	node.Post("/snapshots", nh.OpenSnapshot)
+	node.Delete("/snapshots/:snapshot", nh.CloseSnapshot)
+	node.Post("/purge", nh.Purge)
+
+	return app
+}
+
+// clusterTransport передает запросы HTTP-клиента приложению fiber узла в том же процессе.
+type clusterTransport map[string]*fiber.App
+
+func (t clusterTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t["http://"+req.URL.Host].Test(req, -1)
+}
+
+func TestStorage_Cluster(t *testing.T) {
+	t.Parallel()
+
+	nodes := []string{"http://node_1", "http://node_2", "http://node_3"}
+	transport := make(clusterTransport)
+
+	locals := make(map[string]*storage.SalesStorage)
+	clusters := make([]*Storage, 0, len(nodes))
+
+	for _, node := range nodes {
+		local := storage.New(logger.NoOpLogger())
+		locals[node] = local
+
+		transport[node] = nodeApp(local)
+
+		c, err := New(node, nodes, local, WithToken(testToken), WithHTTPClient(&http.Client{Transport: transport}))
+		require.NoError(t, err)
+
+		clusters = append(clusters, c)
+	}
+
+	dt := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	// продажи принимает любой узел
+	addSales := func(from, to int) {
+		for i := from; i < to; i++ {
+			require.NoError(t, clusters[i%len(clusters)].AddSale(&domain.Sale{
+				StoreID:      fmt.Sprintf("store_%d", i%10),
+				ProductID:    fmt.Sprintf("product_%d", i%3),
+				QuantitySold: 1,
+				SalePrice:    decimal.NewFromInt(int64(i + 1)),
+				Currency:     "RUB",
+				SaleDate:     dt.Add(time.Duration(i) * time.Hour),
+			}))
+		}
+	}
+
+	addSales(0, 100)
+
+	// продажи магазина хранятся только на узле-владельце
+	owners := make(map[string]bool)
+
+	for node, local := range locals {
+		sales, err := local.GetSales()
+		require.NoError(t, err)
+
+		for _, sale := range sales {
+			owner := clusters[0].ring.Owner(sale.StoreID)
+			assert.Equal(t, owner, node)
+			owners[owner] = true
+		}
+	}
+
+	assert.Len(t, owners, len(nodes), "магазины распределены по всем узлам")
+
+	startDate, endDate := dt, dt.AddDate(1, 0, 0)
+
+	// суммы store_i - сумма цен продаж i, i+10, ..., i+90: 10*(i+1) + 450
+	queries := make([]domain.StorePeriod, 0, 10)
+	for i := 0; i < 10; i++ {
+		queries = append(queries, domain.StorePeriod{StoreID: fmt.Sprintf("store_%d", i), StartDate: startDate, EndDate: endDate})
+	}
+
+	for _, c := range clusters {
+		sales, err := c.GetSales()
+		require.NoError(t, err)
+		assert.Len(t, sales, 100)
+
+		for i := 0; i < 10; i++ {
+			totals, err := c.GetTotalSum(fmt.Sprintf("store_%d", i), startDate, endDate)
+			require.NoError(t, err)
+			assert.Equal(t, fmt.Sprint(10*(i+1)+450), totals["RUB"].Gross.String())
+		}
+
+		sums, err := c.GetTotalSums(queries)
+		require.NoError(t, err)
+		require.Len(t, sums, 10)
+
+		for i, totals := range sums {
+			assert.Equal(t, fmt.Sprint(10*(i+1)+450), totals["RUB"].Gross.String())
+		}
+
+		byProduct, err := c.GetTotalSumByProduct("store_0", startDate, endDate)
+		require.NoError(t, err)
+		assert.Equal(t, "184", byProduct["product_0"]["RUB"].Gross.String()) // 1 + 31 + 61 + 91
+	}
+
+	// снимок кластера видит продажи всех узлов на момент открытия
+	snapshot, err := clusters[0].OpenSnapshot()
+	require.NoError(t, err)
+	assert.Len(t, snapshot.Versions, 10)
+
+	addSales(100, 130)
+
+	r, err := clusters[1].Snapshot(snapshot.Token)
+	require.NoError(t, err)
+
+	sales, err := r.GetSales()
+	require.NoError(t, err)
+	assert.Len(t, sales, 100)
+
+	sums, err := r.GetTotalSums(queries)
+	require.NoError(t, err)
+	assert.Equal(t, "460", sums[0]["RUB"].Gross.String())
+
+	require.NoError(t, clusters[2].CloseSnapshot(snapshot.Token))
+	assert.ErrorIs(t, clusters[2].CloseSnapshot(snapshot.Token), domain.ErrSnapshotNotFound)
+
+	// снимок локального узла проверяется сразу, снимки других узлов - при чтении
+	r, err = clusters[1].Snapshot(snapshot.Token)
+	if err == nil {
+		_, err = r.GetTotalSums(queries)
+	}
+	assert.ErrorIs(t, err, domain.ErrSnapshotNotFound)
+
+	_, err = clusters[0].Snapshot("unknown")
+	assert.ErrorIs(t, err, domain.ErrSnapshotNotFound)
+
+	// чек сохраняется на узле-владельце магазина, повторный чек отклоняется любым узлом
+	for _, c := range clusters {
+		err = c.AddReceipt(&domain.Receipt{
+			ID:            "receipt_1",
+			StoreID:       "store_0",
+			PaymentMethod: domain.PaymentCard,
+			Currency:      "RUB",
+			SaleDate:      dt.Add(130 * time.Hour), // после последней продажи store_0
+			Lines: []domain.ReceiptLine{
+				{ProductID: "product_0", QuantitySold: 2, SalePrice: decimal.NewFromInt(10)},
+				{ProductID: "product_1", QuantitySold: 1, SalePrice: decimal.NewFromInt(5)},
+			},
+		})
+		if c == clusters[0] {
+			require.NoError(t, err)
+		} else {
+			assert.ErrorIs(t, err, domain.ErrReceiptExists)
+		}
+	}
+
+	for _, c := range clusters {
+		receipts, err := c.GetReceiptTotals("store_0", startDate, endDate)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), receipts["RUB"].Receipts)
+		assert.Equal(t, int64(3), receipts["RUB"].Items)
+		assert.Equal(t, "25", receipts["RUB"].Amounts.Gross.String())
+
+		payments, err := c.GetTotalSumByDimension("store_0", domain.DimensionPaymentMethod, startDate, endDate)
+		require.NoError(t, err)
+		assert.Equal(t, "25", payments[domain.PaymentCard]["RUB"].Gross.String())
+		assert.Equal(t, "793", payments[domain.Unspecified]["RUB"].Gross.String()) // 460 + 101 + 111 + 121
+
+		// 13 продаж вне чеков и чек из двух строк
+		sketches, err := c.GetSketches("store_0", startDate, endDate)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(3), sketches.Products.Estimate())
+		assert.Equal(t, int64(14), sketches.Tickets["RUB"].Count())
+		assert.Equal(t, 121.0, sketches.Tickets["RUB"].Quantile(1))
+	}
+
+	// запрос выполняется на узлах-владельцах магазинов фильтра, строки узлов объединяются
+	for _, c := range clusters {
+		res, err := c.Query(domain.Query{
+			StartDate: startDate,
+			EndDate:   endDate,
+			Filters:   map[string][]string{domain.FieldStore: {"store_0", "store_1"}},
+			GroupBy:   []string{domain.FieldStore},
+			Metrics:   []string{domain.MetricGross},
+		})
+		require.NoError(t, err)
+		require.Len(t, res.Rows, 2)
+		assert.Equal(t, "818", res.Rows[0].Metrics[domain.MetricGross].String()) // 793 + чек 25
+		assert.Equal(t, "806", res.Rows[1].Metrics[domain.MetricGross].String()) // 2 + 12 + ... + 122
+
+		res, err = c.Query(domain.Query{
+			StartDate: startDate,
+			EndDate:   endDate,
+			Metrics:   []string{domain.MetricSales, domain.MetricQuantity},
+		})
+		require.NoError(t, err)
+		require.Len(t, res.Rows, 1)
+		assert.Equal(t, "132", res.Rows[0].Metrics[domain.MetricSales].String())
+		assert.Equal(t, "133", res.Rows[0].Metrics[domain.MetricQuantity].String())
+	}
+
+	// продажа раньше последней продажи магазина вставляется на узле-владельце
+	backdated := &domain.Sale{
+		StoreID:      "store_0",
+		ProductID:    "product_0",
+		QuantitySold: 1,
+		SalePrice:    decimal.NewFromInt(1000),
+		Currency:     "RUB",
+		SaleDate:     dt.Add(time.Hour),
+	}
+
+	assert.ErrorIs(t, clusters[1].AddSale(backdated), domain.ErrSaleOutOfRange)
+	require.NoError(t, clusters[1].InsertSale(backdated))
+
+	for _, c := range clusters {
+		totals, err := c.GetTotalSum("store_0", startDate, endDate)
+		require.NoError(t, err)
+		assert.Equal(t, "1818", totals["RUB"].Gross.String())
+	}
+
+	_, err = New("http://node_4", nodes, locals[nodes[0]])
+	assert.Error(t, err)
+}
+
+func TestClient_StatusErrors(t *testing.T) {
+	t.Parallel()
+
+	local := storage.New(logger.NoOpLogger(), storage.WithSnapshots(time.Minute, 1))
+	app := nodeApp(local)
+	app.Get("/cluster/limited", func(c *fiber.Ctx) error {
+		return fiber.ErrTooManyRequests
+	})
+
+	transport := clusterTransport{"http://node_1": app}
+	c := NewClient("http://node_1", testToken, &http.Client{Transport: transport})
+
+	// код 400 означает разные ошибки на разных эндпоинтах
+	_, err := c.Purge(domain.Purge{})
+	assert.ErrorIs(t, err, domain.ErrInvalidPurge)
+	assert.NotErrorIs(t, err, domain.ErrSaleOutOfRange)
+
+	err = c.AddSale(&domain.Sale{StoreID: "store_1", SaleDate: time.Date(1000, 1, 1, 0, 0, 0, 0, time.UTC)})
+	assert.ErrorIs(t, err, domain.ErrSaleOutOfRange)
+
+	_, err = c.Query(domain.Query{Metrics: []string{domain.MetricGross}, Location: time.FixedZone("unknown", 0)})
+	assert.ErrorContains(t, err, "status 400")
+	assert.NotErrorIs(t, err, domain.ErrSaleOutOfRange)
+
+	// 429 - ошибка лимита снимков только при открытии снимка
+	_, err = c.OpenSnapshot()
+	require.NoError(t, err)
+
+	_, err = c.OpenSnapshot()
+	assert.ErrorIs(t, err, domain.ErrTooManySnapshots)
+
+	err = c.do(http.MethodGet, "/cluster/limited", nil, nil, readErrors)
+	assert.ErrorIs(t, err, ErrNodeRateLimited)
+	assert.NotErrorIs(t, err, domain.ErrTooManySnapshots)
+
+	// запросы без секрета кластера отклоняются
+	_, err = NewClient("http://node_1", "wrong", &http.Client{Transport: transport}).GetSales()
+	assert.ErrorContains(t, err, "status 401")
+}
+
+func TestClient_Reference(t *testing.T) {
+	t.Parallel()
+
+	newReference := func(opts ...services.ReferenceOption) (*catalog.Storage, *services.ReferenceService) {
+		catalogRepo, err := catalog.New("")
+		require.NoError(t, err)
+
+		targetRepo, err := targets.New("")
+		require.NoError(t, err)
+
+		return catalogRepo, services.NewReferenceService(catalogRepo, targetRepo, logger.NoOpLogger(), opts...)
+	}
+
+	// справочник изменяется на первом узле, второй узел заменяет им свою копию
+	firstCatalog, first := newReference()
+	require.NoError(t, firstCatalog.SaveStores(&domain.Store{ID: "store_1", TimeZone: "Asia/Almaty"}))
+	require.NoError(t, firstCatalog.SaveProducts(&domain.Product{ID: "product_1", Category: "dairy"}))
+
+	app := nodeApp(storage.New(logger.NoOpLogger()))
+	app.Get("/cluster/reference", salesHttp.NewReferenceHandler(first).Reference)
+
+	c := NewClient("http://node_1", testToken, &http.Client{Transport: clusterTransport{"http://node_1": app}})
+	secondCatalog, second := newReference(services.FollowReference(c, "http://node_1"))
+	require.NoError(t, secondCatalog.SaveStores(&domain.Store{ID: "store_1"}, &domain.Store{ID: "store_2"}))
+
+	require.NoError(t, second.Sync())
+
+	assert.Equal(t, firstCatalog.GetStores(), secondCatalog.GetStores())
+	assert.Equal(t, firstCatalog.GetProducts(), secondCatalog.GetProducts())
+}
@@ -0,0 +1,118 @@
+package http
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+	"go.dataflow.ru/service-sales/internal/app/ports"
+)
+
+// AlertHandler обработчик оповещений.
+type AlertHandler struct {
+	alertService ports.AlertService
+}
+
+// NewAlertHandler возвращает новый экземпляр обработчика.
+func NewAlertHandler(service ports.AlertService) *AlertHandler {
+	return &AlertHandler{alertService: service}
+}
+
+// GetAlerts обрабатывает запрос получения оповещений.
+func (h *AlertHandler) GetAlerts(c *fiber.Ctx) error {
+	alerts := h.alertService.GetAlerts()
+
+	res := make([]AlertResponse, 0, len(alerts))
+	for _, alert := range alerts {
+		res = append(res, alertResponse(alert))
+	}
+
+	return c.JSON(res)
+}
+
+// GetAlert обрабатывает запрос получения оповещения.
+func (h *AlertHandler) GetAlert(c *fiber.Ctx) error {
+	alert, err := h.alertService.GetAlert(c.Params("id"))
+	if err != nil {
+		return alertError(err)
+	}
+
+	return c.JSON(alertResponse(alert))
+}
+
+// CreateAlert обрабатывает запрос создания оповещения.
+func (h *AlertHandler) CreateAlert(c *fiber.Ctx) error {
+	var alert domain.Alert
+
+	if err := c.BodyParser(&alert); err != nil {
+		return fiber.ErrUnprocessableEntity
+	}
+
+	created, err := h.alertService.CreateAlert(&alert)
+	if err != nil {
+		return alertError(err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(alertResponse(created))
+}
+
+// UpdateAlert обрабатывает запрос замены оповещения.
+func (h *AlertHandler) UpdateAlert(c *fiber.Ctx) error {
+	var alert domain.Alert
+
+	if err := c.BodyParser(&alert); err != nil {
+		return fiber.ErrUnprocessableEntity
+	}
+
+	alert.ID = c.Params("id")
+
+	updated, err := h.alertService.UpdateAlert(&alert)
+	if err != nil {
+		return alertError(err)
+	}
+
+	return c.JSON(alertResponse(updated))
+}
+
+// DeleteAlert обрабатывает запрос удаления оповещения.
+func (h *AlertHandler) DeleteAlert(c *fiber.Ctx) error {
+	if err := h.alertService.DeleteAlert(c.Params("id")); err != nil {
+		return alertError(err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// TestAlert обрабатывает запрос отправки пробного события оповещения. Ошибка доставки - 502 с ее описанием.
+func (h *AlertHandler) TestAlert(c *fiber.Ctx) error {
+	event, err := h.alertService.TestAlert(c.UserContext(), c.Params("id"))
+	if errors.Is(err, domain.ErrAlertNotFound) {
+		return alertError(err)
+	}
+
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadGateway, err.Error())
+	}
+
+	return c.JSON(event)
+}
+
+func alertResponse(alert *domain.Alert) AlertResponse {
+	res := AlertResponse{Alert: *alert, Signed: alert.Secret != ""}
+	res.Secret = ""
+
+	return res
+}
+
+func alertError(err error) error {
+	if errors.Is(err, domain.ErrAlertNotFound) {
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+
+	if errors.Is(err, domain.ErrInvalidAlert) {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+}
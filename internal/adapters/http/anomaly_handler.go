@@ -0,0 +1,61 @@
+package http
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+	"go.dataflow.ru/service-sales/internal/app/ports"
+)
+
+// AnomalyHandler обработчик очереди проверки аномальных продаж.
+type AnomalyHandler struct {
+	anomalyService ports.AnomalyService
+}
+
+// NewAnomalyHandler возвращает новый экземпляр обработчика.
+func NewAnomalyHandler(service ports.AnomalyService) *AnomalyHandler {
+	return &AnomalyHandler{anomalyService: service}
+}
+
+// GetAnomalies обрабатывает запрос получения аномалий (всех или со статусом status).
+func (h *AnomalyHandler) GetAnomalies(c *fiber.Ctx) error {
+	return c.JSON(h.anomalyService.GetAnomalies(c.Query("status")))
+}
+
+// AcceptAnomaly обрабатывает запрос подтверждения аномальной продажи.
+func (h *AnomalyHandler) AcceptAnomaly(c *fiber.Ctx) error {
+	anomaly, err := h.anomalyService.AcceptAnomaly(c.Params("id"))
+	if err != nil {
+		return anomalyError(err)
+	}
+
+	return c.JSON(anomaly)
+}
+
+// RejectAnomaly обрабатывает запрос отклонения аномальной продажи.
+func (h *AnomalyHandler) RejectAnomaly(c *fiber.Ctx) error {
+	anomaly, err := h.anomalyService.RejectAnomaly(c.Params("id"))
+	if err != nil {
+		return anomalyError(err)
+	}
+
+	return c.JSON(anomaly)
+}
+
+func anomalyError(err error) error {
+	if errors.Is(err, domain.ErrAnomalyNotFound) {
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+
+	if errors.Is(err, domain.ErrAnomalyResolved) {
+		return fiber.NewError(fiber.StatusConflict, err.Error())
+	}
+
+	if errors.Is(err, domain.ErrSaleOutOfRange) {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+}
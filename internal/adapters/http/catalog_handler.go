@@ -0,0 +1,148 @@
+package http
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+	"go.dataflow.ru/service-sales/internal/app/ports"
+)
+
+// CatalogHandler обработчик справочника магазинов и товаров.
+type CatalogHandler struct {
+	catalogService ports.CatalogService
+}
+
+// NewCatalogHandler возвращает новый экземпляр обработчика.
+func NewCatalogHandler(service ports.CatalogService) *CatalogHandler {
+	return &CatalogHandler{catalogService: service}
+}
+
+// SaveStore обрабатывает запрос на создание или изменение магазина.
+func (h *CatalogHandler) SaveStore(c *fiber.Ctx) error {
+	var req StoreDto
+
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.ErrUnprocessableEntity
+	}
+
+	store := &domain.Store{
+		ID:         c.Params("store_id"),
+		Name:       req.Name,
+		TimeZone:   req.TimeZone,
+		Attributes: req.Attributes,
+	}
+
+	if err := h.catalogService.SaveStore(store); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(store)
+}
+
+// ImportStores обрабатывает запрос на загрузку магазинов из CSV.
+func (h *CatalogHandler) ImportStores(c *fiber.Ctx) error {
+	stores, err := parseStoresCSV(bytes.NewReader(c.Body()))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	if err = h.catalogService.ImportStores(stores); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(ImportResponse{Imported: len(stores)})
+}
+
+// DeleteStore обрабатывает запрос на удаление магазина.
+func (h *CatalogHandler) DeleteStore(c *fiber.Ctx) error {
+	if err := h.catalogService.DeleteStore(c.Params("store_id")); err != nil {
+		return catalogError(err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetStore обрабатывает запрос получения магазина.
+func (h *CatalogHandler) GetStore(c *fiber.Ctx) error {
+	store, err := h.catalogService.GetStore(c.Params("store_id"))
+	if err != nil {
+		return catalogError(err)
+	}
+
+	return c.JSON(store)
+}
+
+// GetStores обрабатывает запрос получения списка магазинов.
+func (h *CatalogHandler) GetStores(c *fiber.Ctx) error {
+	return c.JSON(h.catalogService.GetStores())
+}
+
+// SaveProduct обрабатывает запрос на создание или изменение товара.
+func (h *CatalogHandler) SaveProduct(c *fiber.Ctx) error {
+	var req ProductDto
+
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.ErrUnprocessableEntity
+	}
+
+	product := &domain.Product{
+		ID:       c.Params("product_id"),
+		Name:     req.Name,
+		Category: req.Category,
+	}
+
+	if err := h.catalogService.SaveProduct(product); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(product)
+}
+
+// ImportProducts обрабатывает запрос на загрузку товаров из CSV.
+func (h *CatalogHandler) ImportProducts(c *fiber.Ctx) error {
+	products, err := parseProductsCSV(bytes.NewReader(c.Body()))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	if err = h.catalogService.ImportProducts(products); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(ImportResponse{Imported: len(products)})
+}
+
+// DeleteProduct обрабатывает запрос на удаление товара.
+func (h *CatalogHandler) DeleteProduct(c *fiber.Ctx) error {
+	if err := h.catalogService.DeleteProduct(c.Params("product_id")); err != nil {
+		return catalogError(err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetProduct обрабатывает запрос получения товара.
+func (h *CatalogHandler) GetProduct(c *fiber.Ctx) error {
+	product, err := h.catalogService.GetProduct(c.Params("product_id"))
+	if err != nil {
+		return catalogError(err)
+	}
+
+	return c.JSON(product)
+}
+
+// GetProducts обрабатывает запрос получения списка товаров.
+func (h *CatalogHandler) GetProducts(c *fiber.Ctx) error {
+	return c.JSON(h.catalogService.GetProducts())
+}
+
+func catalogError(err error) error {
+	if errors.Is(err, domain.ErrStoreNotFound) || errors.Is(err, domain.ErrProductNotFound) {
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+
+	return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+}
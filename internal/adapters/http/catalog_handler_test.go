@@ -0,0 +1,219 @@
+package http
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.dataflow.ru/service-sales/pkg/logger"
+
+	"go.dataflow.ru/service-sales/internal/adapters/catalog"
+	"go.dataflow.ru/service-sales/internal/app/domain"
+	"go.dataflow.ru/service-sales/internal/app/services"
+)
+
+func TestParseStoresCSV(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name  string
+		csv   string
+		exp   []*domain.Store
+		error string
+	}{
+		{
+			name: "поля и атрибуты",
+			csv:  "store_id,name,time_zone,region,format\nstore_1,Тверская,Europe/Moscow,center,\n",
+			exp: []*domain.Store{{
+				ID:         "store_1",
+				Name:       "Тверская",
+				TimeZone:   "Europe/Moscow",
+				Attributes: map[string]string{"region": "center"},
+			}},
+		},
+		{
+			name:  "пустой файл",
+			csv:   "",
+			error: "empty csv",
+		},
+		{
+			name:  "нет колонки store_id",
+			csv:   "name,time_zone\nТверская,Europe/Moscow\n",
+			error: "csv header must contain store_id column",
+		},
+		{
+			name:  "строка с лишней колонкой",
+			csv:   "store_id,name\nstore_1,Тверская,center\n",
+			error: "read csv",
+		},
+	}
+
+	for _, tt := range testCases {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			stores, err := parseStoresCSV(strings.NewReader(tt.csv))
+			if tt.error != "" {
+				assert.ErrorContains(t, err, tt.error)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.exp, stores)
+		})
+	}
+}
+
+func TestParseProductsCSV(t *testing.T) {
+	t.Parallel()
+
+	products, err := parseProductsCSV(strings.NewReader("product_id,category\nproduct_1,dairy\nproduct_2,\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []*domain.Product{{ID: "product_1", Category: "dairy"}, {ID: "product_2"}}, products)
+
+	_, err = parseProductsCSV(strings.NewReader("store_id\nstore_1\n"))
+	assert.EqualError(t, err, "csv header must contain product_id column")
+}
+
+func TestParseTargetsCSV(t *testing.T) {
+	t.Parallel()
+
+	header := "store_id,start_date,end_date,amount,currency\n"
+
+	testCases := []struct {
+		name  string
+		csv   string
+		error string
+	}{
+		{
+			name: "корректный план",
+			csv:  header + "store_1,2024-04-01,2024-04-30,3000,RUB\n",
+		},
+		{
+			name:  "некорректная дата",
+			csv:   header + "store_1,2024-04-01,2024-04-31,3000,RUB\n",
+			error: "line 2, column end_date",
+		},
+		{
+			name:  "некорректная сумма",
+			csv:   header + "store_1,2024-04-01,2024-04-30,3000,RUB\nstore_2,2024-04-01,2024-04-30,много,RUB\n",
+			error: "line 3, column amount",
+		},
+	}
+
+	for _, tt := range testCases {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := parseTargetsCSV(strings.NewReader(tt.csv))
+			if tt.error != "" {
+				assert.ErrorContains(t, err, tt.error)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestCatalogHandler(t *testing.T) {
+	t.Parallel()
+
+	storage, err := catalog.New("")
+	require.NoError(t, err)
+
+	handler := NewCatalogHandler(services.NewCatalogService(storage, time.UTC, logger.NoOpLogger()))
+
+	app := fiber.New()
+	app.Post("/stores/import", handler.ImportStores)
+	app.Put("/stores/:store_id", handler.SaveStore)
+	app.Get("/stores/:store_id", handler.GetStore)
+	app.Delete("/stores/:store_id", handler.DeleteStore)
+
+	testCases := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+		exp    string
+	}{
+		{
+			name:   "некорректный CSV",
+			method: fiber.MethodPost,
+			path:   "/stores/import",
+			body:   "name\nТверская\n",
+			status: fiber.StatusBadRequest,
+			exp:    "csv header must contain store_id column",
+		},
+		{
+			name:   "импорт с некорректным магазином не сохраняет ни одного",
+			method: fiber.MethodPost,
+			path:   "/stores/import",
+			body:   "store_id,time_zone\nstore_1,Europe/Moscow\nstore_2,Local\n",
+			status: fiber.StatusBadRequest,
+			exp:    `store "store_2": invalid time zone: unknown time zone Local`,
+		},
+		{
+			name:   "магазин из неудачного импорта не найден",
+			method: fiber.MethodGet,
+			path:   "/stores/store_1",
+			status: fiber.StatusNotFound,
+			exp:    domain.ErrStoreNotFound.Error(),
+		},
+		{
+			name:   "импорт",
+			method: fiber.MethodPost,
+			path:   "/stores/import",
+			body:   "store_id,time_zone\nstore_1,Europe/Moscow\nstore_2,\n",
+			status: fiber.StatusOK,
+			exp:    `{"imported":2}`,
+		},
+		{
+			name:   "некорректный магазин",
+			method: fiber.MethodPut,
+			path:   "/stores/store_3",
+			body:   `{"time_zone":"Europe/Unknown"}`,
+			status: fiber.StatusBadRequest,
+			exp:    `store "store_3": invalid time zone`,
+		},
+		{
+			name:   "удаление",
+			method: fiber.MethodDelete,
+			path:   "/stores/store_2",
+			status: fiber.StatusNoContent,
+		},
+		{
+			name:   "удаление отсутствующего магазина",
+			method: fiber.MethodDelete,
+			path:   "/stores/store_2",
+			status: fiber.StatusNotFound,
+			exp:    domain.ErrStoreNotFound.Error(),
+		},
+	}
+
+	// шаги выполняются последовательно над одним справочником
+	for _, tt := range testCases {
+		req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+		if strings.HasPrefix(tt.body, "{") {
+			req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		}
+
+		resp, err := app.Test(req)
+		require.NoError(t, err, tt.name)
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err, tt.name)
+
+		assert.Equal(t, tt.status, resp.StatusCode, tt.name)
+		assert.Contains(t, string(body), tt.exp, tt.name)
+	}
+}
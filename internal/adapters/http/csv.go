@@ -0,0 +1,149 @@
+package http
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/shopspring/decimal"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+)
+
+// parseStoresCSV разбирает магазины из CSV с заголовком. Обязательна колонка store_id, колонки name и time_zone
+// заполняют одноименные поля, все остальные колонки становятся атрибутами магазина.
+func parseStoresCSV(r io.Reader) ([]*domain.Store, error) {
+	header, records, err := readCSV(r, "store_id")
+	if err != nil {
+		return nil, err
+	}
+
+	stores := make([]*domain.Store, 0, len(records))
+
+	for _, record := range records {
+		store := &domain.Store{Attributes: make(map[string]string)}
+
+		for i, column := range header {
+			switch column {
+			case "store_id":
+				store.ID = record[i]
+			case "name":
+				store.Name = record[i]
+			case "time_zone":
+				store.TimeZone = record[i]
+			default:
+				if record[i] != "" {
+					store.Attributes[column] = record[i]
+				}
+			}
+		}
+
+		stores = append(stores, store)
+	}
+
+	return stores, nil
+}
+
+// parseProductsCSV разбирает товары из CSV с заголовком product_id,name,category (name и category необязательны).
+func parseProductsCSV(r io.Reader) ([]*domain.Product, error) {
+	header, records, err := readCSV(r, "product_id")
+	if err != nil {
+		return nil, err
+	}
+
+	products := make([]*domain.Product, 0, len(records))
+
+	for _, record := range records {
+		product := &domain.Product{}
+
+		for i, column := range header {
+			switch column {
+			case "product_id":
+				product.ID = record[i]
+			case "name":
+				product.Name = record[i]
+			case "category":
+				product.Category = record[i]
+			}
+		}
+
+		products = append(products, product)
+	}
+
+	return products, nil
+}
+
+// readCSV читает CSV с заголовком, в котором должна быть колонка required.
+func readCSV(r io.Reader, required string) ([]string, [][]string, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil, fmt.Errorf("empty csv")
+	}
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("read csv header: %w", err)
+	}
+
+	found := false
+
+	for _, column := range header {
+		if column == required {
+			found = true
+		}
+	}
+
+	if !found {
+		return nil, nil, fmt.Errorf("csv header must contain %s column", required)
+	}
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, nil, fmt.Errorf("read csv: %w", err)
+	}
+
+	return header, records, nil
+}
+
+// parseTargetsCSV разбирает планы из CSV с заголовком store_id,start_date,end_date,amount,currency,metric
+// (metric необязательна).
+func parseTargetsCSV(r io.Reader) ([]*domain.Target, error) {
+	header, records, err := readCSV(r, "store_id")
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make([]*domain.Target, 0, len(records))
+
+	for n, record := range records {
+		target := &domain.Target{}
+
+		for i, column := range header {
+			switch column {
+			case "store_id":
+				target.StoreID = record[i]
+			case "start_date":
+				target.Start, err = domain.ParseDate(record[i])
+			case "end_date":
+				target.End, err = domain.ParseDate(record[i])
+			case "amount":
+				target.Amount, err = decimal.NewFromString(record[i])
+			case "currency":
+				target.Currency = record[i]
+			case "metric":
+				target.Metric = record[i]
+			}
+
+			if err != nil {
+				return nil, fmt.Errorf("line %d, column %s: %w", n+2, column, err)
+			}
+		}
+
+		targets = append(targets, target)
+	}
+
+	return targets, nil
+}
@@ -2,9 +2,12 @@ package http
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/shopspring/decimal"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
 )
 
 type SaleDto struct {
@@ -12,9 +15,16 @@ type SaleDto struct {
 	StoreID      string          `json:"store_id"`
 	QuantitySold int64           `json:"quantity_sold"`
 	SalePrice    decimal.Decimal `json:"sale_price"`
+	Discount     decimal.Decimal `json:"discount"`
+	VATRate      decimal.Decimal `json:"vat_rate"`
+	Currency     string          `json:"currency"`
 	SaleDate     string          `json:"sale_date"`
+
+	PaymentMethod string `json:"payment_method"`
+	Channel       string `json:"channel"`
 }
 
+// Validate проверяет формат запроса. Значения полей продажи и бизнес-правила проверяет сервис продаж.
 func (r *SaleDto) Validate() error {
 	if r.ProductID == "" {
 		return fmt.Errorf("productID not defined")
@@ -24,12 +34,53 @@ func (r *SaleDto) Validate() error {
 		return fmt.Errorf("storeID not defined")
 	}
 
-	if r.QuantitySold <= 0 {
-		return fmt.Errorf("quantity must be positive")
+	if _, err := time.Parse(time.RFC3339, r.SaleDate); err != nil {
+		return fmt.Errorf("date must be in RFC3339 format")
+	}
+
+	return nil
+}
+
+type AddSaleResponse struct {
+	Status string `json:"status"`
+}
+
+type ReceiptDto struct {
+	ReceiptID     string           `json:"receipt_id"`
+	StoreID       string           `json:"store_id"`
+	PaymentMethod string           `json:"payment_method"`
+	Channel       string           `json:"channel"`
+	Currency      string           `json:"currency"`
+	SaleDate      string           `json:"sale_date"`
+	Lines         []ReceiptLineDto `json:"lines"`
+}
+
+type ReceiptLineDto struct {
+	ProductID    string          `json:"product_id"`
+	QuantitySold int64           `json:"quantity_sold"`
+	SalePrice    decimal.Decimal `json:"sale_price"`
+	Discount     decimal.Decimal `json:"discount"`
+	VATRate      decimal.Decimal `json:"vat_rate"`
+}
+
+// Validate проверяет формат запроса. Значения полей строк и бизнес-правила проверяет сервис продаж.
+func (r *ReceiptDto) Validate() error {
+	if r.ReceiptID == "" {
+		return fmt.Errorf("receiptID not defined")
+	}
+
+	if r.StoreID == "" {
+		return fmt.Errorf("storeID not defined")
+	}
+
+	if len(r.Lines) == 0 {
+		return fmt.Errorf("receipt has no lines")
 	}
 
-	if r.SalePrice.IsNegative() {
-		return fmt.Errorf("price must be positive")
+	for i, line := range r.Lines {
+		if line.ProductID == "" {
+			return fmt.Errorf("line %d: productID not defined", i+1)
+		}
 	}
 
 	if _, err := time.Parse(time.RFC3339, r.SaleDate); err != nil {
@@ -39,8 +90,10 @@ func (r *SaleDto) Validate() error {
 	return nil
 }
 
-type AddSaleResponse struct {
-	Status string `json:"status"`
+type AddReceiptResponse struct {
+	Status    string         `json:"status"`
+	ReceiptID string         `json:"receipt_id"`
+	Amounts   domain.Amounts `json:"amounts"` // суммы чека
 }
 
 type CalculateTotalSumRequest struct {
@@ -48,19 +101,229 @@ type CalculateTotalSumRequest struct {
 	StoreID   string `json:"store_id"`
 	StartDate string `json:"start_date"`
 	EndDate   string `json:"end_date"`
+	Currency  string `json:"currency"` // если задана, суммы пересчитываются в эту валюту
+
+	StoreFilter map[string]string `json:"store_filter"` // атрибуты магазинов для store_group_sales
+	GroupBy     string            `json:"group_by"`     // атрибут магазина для группировки в store_group_sales
+
+	// магазины и правило сдвига периода (domain.Offset*) для compare_periods; без store_id и store_ids
+	// сравниваются магазины с атрибутами store_filter (так же выбираются магазины distinct_products
+	// и ticket_quantiles)
+	StoreIDs []string `json:"store_ids"`
+	Offset   string   `json:"offset"`
+
+	Dimension string `json:"dimension"` // измерение продаж (domain.Dimension*) для dimension_sales
+
+	// разбивка периода (day, week или month) для distinct_products и ticket_quantiles и квантили размера
+	// чека для ticket_quantiles (по умолчанию domain.DefaultQuantiles)
+	Bucket    string    `json:"bucket"`
+	Quantiles []float64 `json:"quantiles"`
+
+	Snapshot string `json:"snapshot"` // токен снимка продаж, если задан, расчет выполняется в состоянии снимка
 }
 
+const (
+	operationTotalSales       = "total_sales"
+	operationDailySales       = "daily_sales"
+	operationCategorySales    = "category_sales"
+	operationStoreGroupSales  = "store_group_sales"
+	operationComparePeriods   = "compare_periods"
+	operationReceiptStats     = "receipt_stats"
+	operationDimensionSales   = "dimension_sales"
+	operationDistinctProducts = "distinct_products"
+	operationTicketQuantiles  = "ticket_quantiles"
+)
+
 func (r *CalculateTotalSumRequest) Validate() error {
-	if r.Operation != "total_sales" {
+	switch r.Operation {
+	case operationTotalSales, operationDailySales, operationCategorySales, operationStoreGroupSales,
+		operationReceiptStats:
+	case operationComparePeriods:
+		if r.Offset == "" {
+			return fmt.Errorf("offset not defined")
+		}
+	case operationDimensionSales:
+		if !domain.IsDimension(r.Dimension) {
+			return fmt.Errorf("dimension must be one of %s", strings.Join(domain.Dimensions(), ", "))
+		}
+	case operationDistinctProducts, operationTicketQuantiles:
+		if err := domain.ValidateSketchBucket(r.Bucket); err != nil {
+			return err
+		}
+
+		if err := domain.ValidateQuantiles(r.Quantiles); err != nil {
+			return err
+		}
+	default:
 		return fmt.Errorf("unknown operation")
 	}
 
+	if r.Currency != "" && !domain.IsCurrencyCode(r.Currency) {
+		return fmt.Errorf("currency must be ISO 4217 code")
+	}
+
 	return nil
 }
 
 type CalculateTotalSumResponse struct {
-	StoreID    string          `json:"store_id"`
-	TotalSales decimal.Decimal `json:"total_sales"`
-	StartDate  string          `json:"start_date"`
-	EndDate    string          `json:"end_date"`
+	StoreID    string           `json:"store_id"`
+	TotalSales *decimal.Decimal `json:"total_sales,omitempty"` // валовая выручка в currency
+	Currency   string           `json:"currency,omitempty"`
+	Converted  *domain.Amounts  `json:"converted,omitempty"`
+	Totals     domain.Totals    `json:"totals"`
+	StartDate  string           `json:"start_date"`
+	EndDate    string           `json:"end_date"`
+}
+
+type DailySalesResponse struct {
+	StoreID   string               `json:"store_id"`
+	StartDate string               `json:"start_date"`
+	EndDate   string               `json:"end_date"`
+	Days      []domain.DailyTotals `json:"days"`
+}
+
+type GroupedSalesResponse struct {
+	StoreID   string                   `json:"store_id,omitempty"`
+	StartDate string                   `json:"start_date"`
+	EndDate   string                   `json:"end_date"`
+	Groups    map[string]domain.Totals `json:"groups"`
+}
+
+type ComparisonResponse struct {
+	StartDate string                    `json:"start_date"`
+	EndDate   string                    `json:"end_date"`
+	Offset    string                    `json:"offset"`
+	Stores    []domain.PeriodComparison `json:"stores"`
+	Total     map[string]domain.Change  `json:"total"` // изменение сумм всех магазинов
+}
+
+type ReceiptStatsResponse struct {
+	StoreID    string                     `json:"store_id"`
+	StartDate  string                     `json:"start_date"`
+	EndDate    string                     `json:"end_date"`
+	Currencies map[string]ReceiptStatsDto `json:"currencies"`
+}
+
+// ReceiptStatsDto показатели чеков в одной валюте со средним чеком и средним количеством товаров в чеке.
+type ReceiptStatsDto struct {
+	domain.ReceiptStats
+	AverageBasket  decimal.Decimal `json:"average_basket"`
+	ItemsPerBasket decimal.Decimal `json:"items_per_basket"`
+}
+
+// DistinctProductsResponse приближенное количество различных проданных товаров за период и по интервалам разбивки.
+type DistinctProductsResponse struct {
+	StoreIDs         []string                 `json:"store_ids,omitempty"`
+	StartDate        string                   `json:"start_date"`
+	EndDate          string                   `json:"end_date"`
+	DistinctProducts uint64                   `json:"distinct_products"`
+	Buckets          []DistinctProductsBucket `json:"buckets,omitempty"`
+}
+
+type DistinctProductsBucket struct {
+	Start            domain.Date `json:"start"`
+	DistinctProducts uint64      `json:"distinct_products"`
+}
+
+// TicketQuantilesResponse приближенные квантили размера чека по валютам за период и по интервалам разбивки.
+type TicketQuantilesResponse struct {
+	StoreIDs   []string                      `json:"store_ids,omitempty"`
+	StartDate  string                        `json:"start_date"`
+	EndDate    string                        `json:"end_date"`
+	Currencies map[string]TicketQuantilesDto `json:"currencies"`
+	Buckets    []TicketQuantilesBucket       `json:"buckets,omitempty"`
+}
+
+type TicketQuantilesBucket struct {
+	Start      domain.Date                   `json:"start"`
+	Currencies map[string]TicketQuantilesDto `json:"currencies"`
+}
+
+// TicketQuantilesDto количество чеков в валюте и квантили их размера (квантиль -> валовая сумма чека).
+type TicketQuantilesDto struct {
+	Tickets   int64                      `json:"tickets"`
+	Quantiles map[string]decimal.Decimal `json:"quantiles"`
+}
+
+// QueryRequest запрос агрегатов продаж. Даты периода - моменты RFC3339 или даты YYYY-MM-DD в часовом поясе
+// time_zone (по умолчанию UTC), в котором также выполняется временная разбивка bucket.
+type QueryRequest struct {
+	StartDate string              `json:"start_date"`
+	EndDate   string              `json:"end_date"`
+	TimeZone  string              `json:"time_zone"`
+	Filters   map[string][]string `json:"filters"`  // поле -> допустимые значения
+	GroupBy   []string            `json:"group_by"` // поля группировки, кроме всегда добавляемой валюты
+	Metrics   []string            `json:"metrics"`
+	Bucket    string              `json:"bucket"` // hour, day, week или month; пустой - без разбивки
+
+	Snapshot string `json:"snapshot"` // токен снимка продаж, если задан, запрос выполняется в состоянии снимка
+}
+
+// SQLRequest SQL-запрос к продажам.
+type SQLRequest struct {
+	Query    string `json:"query"`
+	Snapshot string `json:"snapshot"` // токен снимка продаж, если задан, запрос выполняется в состоянии снимка
+}
+
+type StoreDto struct {
+	Name       string            `json:"name"`
+	TimeZone   string            `json:"time_zone"`
+	Attributes map[string]string `json:"attributes"`
+}
+
+type ProductDto struct {
+	Name     string `json:"name"`
+	Category string `json:"category"`
+}
+
+// AlertResponse оповещение в ответе API. Секрет подписи не возвращается, signed - запросы оповещения подписываются.
+type AlertResponse struct {
+	domain.Alert
+	Signed bool `json:"signed"`
+}
+
+// ReportResponse отчет по расписанию в ответе API. Секрет подписи не возвращается, signed - запросы доставки
+// отчета подписываются.
+type ReportResponse struct {
+	domain.Report
+	Signed bool `json:"signed"`
+}
+
+// PurgeResponse результат удаления продаж узла кластера.
+type PurgeResponse struct {
+	Deleted int `json:"deleted"`
+}
+
+type ImportResponse struct {
+	Imported int `json:"imported"`
+}
+
+// NodeTotalsRequest запрос сумм продаж магазинов узла кластера.
+type NodeTotalsRequest struct {
+	Snapshot string               `json:"snapshot"`
+	Queries  []domain.StorePeriod `json:"queries"`
+}
+
+// NodeStorePeriodRequest запрос по магазину узла кластера за период.
+type NodeStorePeriodRequest struct {
+	Snapshot string `json:"snapshot"`
+	domain.StorePeriod
+}
+
+// NodeTotalsByDimensionRequest запрос сумм продаж магазина узла кластера в разрезе значений измерения.
+type NodeTotalsByDimensionRequest struct {
+	NodeStorePeriodRequest
+	Dimension string `json:"dimension"`
+}
+
+// NodeQueryRequest запрос агрегатов продаж магазинов узла кластера.
+type NodeQueryRequest struct {
+	Snapshot  string              `json:"snapshot"`
+	StartDate time.Time           `json:"start_date"`
+	EndDate   time.Time           `json:"end_date"`
+	Filters   map[string][]string `json:"filters"`
+	GroupBy   []string            `json:"group_by"`
+	Metrics   []string            `json:"metrics"`
+	Bucket    string              `json:"bucket"`
+	TimeZone  string              `json:"time_zone"`
 }
@@ -0,0 +1,48 @@
+package http
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+	"go.dataflow.ru/service-sales/internal/app/ports"
+)
+
+// ForecastHandler обработчик прогнозов выручки магазинов.
+type ForecastHandler struct {
+	forecastService ports.ForecastService
+}
+
+// NewForecastHandler возвращает новый экземпляр обработчика.
+func NewForecastHandler(service ports.ForecastService) *ForecastHandler {
+	return &ForecastHandler{forecastService: service}
+}
+
+// Forecast обрабатывает запрос прогноза дневной выручки магазина. Параметры запроса: horizon и history (дней),
+// currency, metric, models (через запятую), level (вероятность интервала прогноза).
+func (h *ForecastHandler) Forecast(c *fiber.Ctx) error {
+	params := domain.ForecastParams{
+		Horizon:  c.QueryInt("horizon"),
+		History:  c.QueryInt("history"),
+		Currency: c.Query("currency"),
+		Metric:   c.Query("metric"),
+		Level:    c.QueryFloat("level"),
+	}
+
+	if models := c.Query("models"); models != "" {
+		params.Models = strings.Split(models, ",")
+	}
+
+	forecast, err := h.forecastService.Forecast(c.Params("store_id"), params)
+	if errors.Is(err, domain.ErrInsufficientHistory) {
+		return fiber.NewError(fiber.StatusUnprocessableEntity, err.Error())
+	}
+
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(forecast)
+}
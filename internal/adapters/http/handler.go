@@ -1,9 +1,12 @@
 package http
 
 import (
+	"errors"
+	"strconv"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/shopspring/decimal"
 
 	"go.dataflow.ru/service-sales/internal/app/domain"
 	"go.dataflow.ru/service-sales/internal/app/ports"
@@ -12,11 +15,14 @@ import (
 // SalesHandler обработчик продаж.
 type SalesHandler struct {
 	salesService ports.SalesService
+
+	// валюта total_sales суммы магазина, если валюта не задана в запросе, а продажи магазина в нескольких валютах
+	defaultCurrency string
 }
 
 // New возвращает новый экземпляр обработчика.
-func New(service ports.SalesService) *SalesHandler {
-	return &SalesHandler{salesService: service}
+func New(service ports.SalesService, defaultCurrency string) *SalesHandler {
+	return &SalesHandler{salesService: service, defaultCurrency: defaultCurrency}
 }
 
 // AddSale обрабатывает запрос на добавление новой продажи.
@@ -33,6 +39,19 @@ func (h *SalesHandler) AddSale(c *fiber.Ctx) error {
 	}
 
 	err = h.salesService.AddSale(convertFromDto(req))
+	if errors.Is(err, domain.ErrSaleQuarantined) {
+		return c.Status(fiber.StatusAccepted).JSON(AddSaleResponse{Status: "quarantined"})
+	}
+
+	if errors.Is(err, domain.ErrStoreNotFound) || errors.Is(err, domain.ErrProductNotFound) {
+		return fiber.NewError(fiber.StatusUnprocessableEntity, err.Error())
+	}
+
+	if errors.Is(err, domain.ErrSaleOutOfRange) || errors.Is(err, domain.ErrInvalidSale) ||
+		errors.Is(err, domain.ErrRuleViolation) {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
 	if err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
 	}
@@ -40,9 +59,53 @@ func (h *SalesHandler) AddSale(c *fiber.Ctx) error {
 	return c.JSON(AddSaleResponse{Status: "success"})
 }
 
+// AddReceipt обрабатывает запрос на добавление чека. Строки чека сохраняются атомарно.
+func (h *SalesHandler) AddReceipt(c *fiber.Ctx) error {
+	var req ReceiptDto
+
+	err := c.BodyParser(&req)
+	if err != nil {
+		return fiber.ErrUnprocessableEntity
+	}
+
+	if err = req.Validate(); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	receipt := convertReceiptFromDto(req)
+
+	err = h.salesService.AddReceipt(receipt)
+	if errors.Is(err, domain.ErrReceiptExists) {
+		return fiber.NewError(fiber.StatusConflict, err.Error())
+	}
+
+	if errors.Is(err, domain.ErrStoreNotFound) || errors.Is(err, domain.ErrProductNotFound) {
+		return fiber.NewError(fiber.StatusUnprocessableEntity, err.Error())
+	}
+
+	if errors.Is(err, domain.ErrSaleOutOfRange) || errors.Is(err, domain.ErrInvalidSale) ||
+		errors.Is(err, domain.ErrRuleViolation) {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(AddReceiptResponse{Status: "success", ReceiptID: receipt.ID, Amounts: receipt.Amounts()})
+}
+
 // GetSales обрабатывает запрос получения списка всех продаж.
 func (h *SalesHandler) GetSales(c *fiber.Ctx) error {
-	sales := h.salesService.GetSales()
+	svc, err := h.service(c.Query("snapshot"))
+	if err != nil {
+		return err
+	}
+
+	sales, err := svc.GetSales()
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
 
 	return c.JSON(sales)
 }
@@ -60,24 +123,385 @@ func (h *SalesHandler) CalculateTotalSum(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, err.Error())
 	}
 
-	startDate, err := time.Parse(time.RFC3339, req.StartDate)
+	period, err := domain.ParsePeriod(req.StartDate, req.EndDate)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	svc, err := h.service(req.Snapshot)
+	if err != nil {
+		return err
+	}
+
+	switch req.Operation {
+	case operationDailySales:
+		return dailySales(c, svc, req, period)
+	case operationCategorySales:
+		return categorySales(c, svc, req, period)
+	case operationStoreGroupSales:
+		return storeGroupSales(c, svc, req, period)
+	case operationComparePeriods:
+		return comparePeriods(c, svc, req, period)
+	case operationReceiptStats:
+		return receiptStats(c, svc, req, period)
+	case operationDimensionSales:
+		return dimensionSales(c, svc, req, period)
+	case operationDistinctProducts:
+		return distinctProducts(c, svc, req, period)
+	case operationTicketQuantiles:
+		return ticketQuantiles(c, svc, req, period)
+	default:
+		return totalSales(c, svc, req, period, h.defaultCurrency)
+	}
+}
+
+// Query обрабатывает запрос агрегатов продаж с фильтрами, группировкой и временной разбивкой.
+func (h *SalesHandler) Query(c *fiber.Ctx) error {
+	var req QueryRequest
+
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.ErrUnprocessableEntity
+	}
+
+	period, err := domain.ParsePeriod(req.StartDate, req.EndDate)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	loc, err := time.LoadLocation(req.TimeZone)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	svc, err := h.service(req.Snapshot)
+	if err != nil {
+		return err
+	}
+
+	startDate, endDate := period.Resolve(loc)
+
+	res, err := svc.Query(domain.Query{
+		StartDate: startDate,
+		EndDate:   endDate,
+		Filters:   req.Filters,
+		GroupBy:   req.GroupBy,
+		Metrics:   req.Metrics,
+		Bucket:    req.Bucket,
+		Location:  loc,
+	})
+	if errors.Is(err, domain.ErrInvalidQuery) || errors.Is(err, domain.ErrQueryTooLarge) {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(res)
+}
+
+// OpenSnapshot обрабатывает запрос открытия снимка продаж.
+func (h *SalesHandler) OpenSnapshot(c *fiber.Ctx) error {
+	snapshot, err := h.salesService.OpenSnapshot()
+	if errors.Is(err, domain.ErrTooManySnapshots) {
+		return fiber.NewError(fiber.StatusTooManyRequests, err.Error())
+	}
+
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(snapshot)
+}
+
+// CloseSnapshot обрабатывает запрос закрытия снимка продаж.
+func (h *SalesHandler) CloseSnapshot(c *fiber.Ctx) error {
+	err := h.salesService.CloseSnapshot(c.Params("snapshot"))
+	if errors.Is(err, domain.ErrSnapshotNotFound) {
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// service возвращает сервис, читающий продажи в снимке token, или основной сервис, если снимок не задан.
+func (h *SalesHandler) service(token string) (ports.SalesService, error) {
+	if token == "" {
+		return h.salesService, nil
+	}
+
+	svc, err := h.salesService.WithSnapshot(token)
+	if errors.Is(err, domain.ErrSnapshotNotFound) {
+		return nil, fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	return svc, nil
+}
+
+// totalSales обрабатывает запрос суммы продаж магазина за период. Без currency в запросе total_sales
+// возвращается в единственной валюте продаж магазина, а продажи в нескольких валютах пересчитываются
+// в defaultCurrency, если заданы курсы.
+func totalSales(c *fiber.Ctx, svc ports.SalesService, req CalculateTotalSumRequest, period domain.Period, defaultCurrency string) error {
+	loc, err := svc.StoreLocation(req.StoreID)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	startDate, endDate := period.Resolve(loc)
+
+	totals, err := svc.GetTotalSum(req.StoreID, startDate, endDate)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	resp := CalculateTotalSumResponse{
+		StoreID:   req.StoreID,
+		Totals:    totals,
+		StartDate: req.StartDate,
+		EndDate:   req.EndDate,
+	}
+
+	currencies := totals.Currencies()
+
+	switch {
+	case req.Currency != "":
+		converted, err := svc.GetConvertedTotalSum(req.StoreID, startDate, endDate, req.Currency)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+
+		resp.TotalSales = &converted.Gross
+		resp.Currency = req.Currency
+		resp.Converted = &converted
+	case len(currencies) <= 1:
+		currency := defaultCurrency
+		if len(currencies) == 1 {
+			currency = currencies[0]
+		}
+
+		gross := totals[currency].Gross
+		resp.TotalSales = &gross
+		resp.Currency = currency
+	default:
+		// без курсов сумма в нескольких валютах не имеет смысла, остаются только суммы по валютам
+		converted, err := svc.GetConvertedTotalSum(req.StoreID, startDate, endDate, defaultCurrency)
+		if err == nil {
+			resp.TotalSales = &converted.Gross
+			resp.Currency = defaultCurrency
+			resp.Converted = &converted
+		}
+	}
+
+	return c.JSON(resp)
+}
+
+// dailySales обрабатывает запрос ряда дневных продаж магазина.
+func dailySales(c *fiber.Ctx, svc ports.SalesService, req CalculateTotalSumRequest, period domain.Period) error {
+	loc, err := svc.StoreLocation(req.StoreID)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	days, err := svc.GetDailyTotals(req.StoreID, period.Start.Day(loc), period.End.Day(loc))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(DailySalesResponse{
+		StoreID:   req.StoreID,
+		StartDate: req.StartDate,
+		EndDate:   req.EndDate,
+		Days:      days,
+	})
+}
+
+// categorySales обрабатывает запрос сумм продаж магазина по категориям товаров.
+func categorySales(c *fiber.Ctx, svc ports.SalesService, req CalculateTotalSumRequest, period domain.Period) error {
+	groups, err := svc.GetCategoryTotals(req.StoreID, period)
 	if err != nil {
 		return fiber.NewError(fiber.StatusBadRequest, err.Error())
 	}
 
-	endDate, err := time.Parse(time.RFC3339, req.EndDate)
+	return c.JSON(GroupedSalesResponse{
+		StoreID:   req.StoreID,
+		StartDate: req.StartDate,
+		EndDate:   req.EndDate,
+		Groups:    groups,
+	})
+}
+
+// storeGroupSales обрабатывает запрос сумм продаж по магазинам с заданными атрибутами.
+func storeGroupSales(c *fiber.Ctx, svc ports.SalesService, req CalculateTotalSumRequest, period domain.Period) error {
+	groups, err := svc.GetStoreGroupTotals(req.StoreFilter, req.GroupBy, period)
 	if err != nil {
 		return fiber.NewError(fiber.StatusBadRequest, err.Error())
 	}
 
-	totalSales := h.salesService.GetTotalSum(req.StoreID, startDate, endDate)
+	return c.JSON(GroupedSalesResponse{
+		StartDate: req.StartDate,
+		EndDate:   req.EndDate,
+		Groups:    groups,
+	})
+}
+
+// comparePeriods обрабатывает запрос сравнения продаж магазинов за период с предыдущим периодом.
+func comparePeriods(c *fiber.Ctx, svc ports.SalesService, req CalculateTotalSumRequest, period domain.Period) error {
+	stores, err := svc.ComparePeriods(requestStores(req), req.StoreFilter, period, req.Offset)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	current, previous := make(domain.Totals), make(domain.Totals)
+
+	for _, store := range stores {
+		for currency, change := range store.Currencies {
+			current[currency] = current[currency].Add(change.Current)
+			previous[currency] = previous[currency].Add(change.Previous)
+		}
+	}
+
+	return c.JSON(ComparisonResponse{
+		StartDate: req.StartDate,
+		EndDate:   req.EndDate,
+		Offset:    req.Offset,
+		Stores:    stores,
+		Total:     domain.Compare(current, previous),
+	})
+}
+
+// dimensionSales обрабатывает запрос сумм продаж магазина в разрезе значений измерения.
+func dimensionSales(c *fiber.Ctx, svc ports.SalesService, req CalculateTotalSumRequest, period domain.Period) error {
+	groups, err := svc.GetDimensionTotals(req.StoreID, req.Dimension, period)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(GroupedSalesResponse{
+		StoreID:   req.StoreID,
+		StartDate: req.StartDate,
+		EndDate:   req.EndDate,
+		Groups:    groups,
+	})
+}
+
+// receiptStats обрабатывает запрос показателей чеков магазина за период.
+func receiptStats(c *fiber.Ctx, svc ports.SalesService, req CalculateTotalSumRequest, period domain.Period) error {
+	totals, err := svc.GetReceiptTotals(req.StoreID, period)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
 
-	return c.JSON(CalculateTotalSumResponse{
+	resp := ReceiptStatsResponse{
 		StoreID:    req.StoreID,
-		TotalSales: totalSales,
 		StartDate:  req.StartDate,
 		EndDate:    req.EndDate,
-	})
+		Currencies: make(map[string]ReceiptStatsDto, len(totals)),
+	}
+
+	for currency, stats := range totals {
+		resp.Currencies[currency] = ReceiptStatsDto{
+			ReceiptStats:   stats,
+			AverageBasket:  stats.AverageBasket(),
+			ItemsPerBasket: stats.ItemsPerBasket(),
+		}
+	}
+
+	return c.JSON(resp)
+}
+
+// distinctProducts обрабатывает запрос приближенного количества различных товаров, проданных магазинами.
+func distinctProducts(c *fiber.Ctx, svc ports.SalesService, req CalculateTotalSumRequest, period domain.Period) error {
+	storeIDs := requestStores(req)
+
+	series, err := svc.GetSalesSketches(storeIDs, req.StoreFilter, period, req.Bucket)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	resp := DistinctProductsResponse{
+		StoreIDs:         storeIDs,
+		StartDate:        req.StartDate,
+		EndDate:          req.EndDate,
+		DistinctProducts: series.Total.Products.Estimate(),
+	}
+
+	for _, b := range series.Buckets {
+		resp.Buckets = append(resp.Buckets, DistinctProductsBucket{
+			Start:            b.Start,
+			DistinctProducts: b.Sketches.Products.Estimate(),
+		})
+	}
+
+	return c.JSON(resp)
+}
+
+// ticketQuantiles обрабатывает запрос приближенных квантилей размера чека магазинов.
+func ticketQuantiles(c *fiber.Ctx, svc ports.SalesService, req CalculateTotalSumRequest, period domain.Period) error {
+	storeIDs := requestStores(req)
+
+	series, err := svc.GetSalesSketches(storeIDs, req.StoreFilter, period, req.Bucket)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	quantiles := req.Quantiles
+	if len(quantiles) == 0 {
+		quantiles = domain.DefaultQuantiles
+	}
+
+	resp := TicketQuantilesResponse{
+		StoreIDs:   storeIDs,
+		StartDate:  req.StartDate,
+		EndDate:    req.EndDate,
+		Currencies: ticketQuantilesOf(series.Total, quantiles),
+	}
+
+	for _, b := range series.Buckets {
+		resp.Buckets = append(resp.Buckets, TicketQuantilesBucket{
+			Start:      b.Start,
+			Currencies: ticketQuantilesOf(b.Sketches, quantiles),
+		})
+	}
+
+	return c.JSON(resp)
+}
+
+// ticketQuantilesOf возвращает квантили размера чека по валютам статистики.
+func ticketQuantilesOf(sketches *domain.SalesSketches, quantiles []float64) map[string]TicketQuantilesDto {
+	res := make(map[string]TicketQuantilesDto, len(sketches.Tickets))
+
+	for currency, tickets := range sketches.Tickets {
+		dto := TicketQuantilesDto{
+			Tickets:   tickets.Count(),
+			Quantiles: make(map[string]decimal.Decimal, len(quantiles)),
+		}
+
+		for _, q := range quantiles {
+			size := decimal.NewFromFloat(tickets.Quantile(q)).Round(domain.AmountPrecision)
+			dto.Quantiles[strconv.FormatFloat(q, 'f', -1, 64)] = size
+		}
+
+		res[currency] = dto
+	}
+
+	return res
+}
+
+// requestStores возвращает магазины запроса: store_id и store_ids.
+func requestStores(req CalculateTotalSumRequest) []string {
+	if req.StoreID == "" {
+		return req.StoreIDs
+	}
+
+	return append([]string{req.StoreID}, req.StoreIDs...)
 }
 
 func convertFromDto(s SaleDto) *domain.Sale {
@@ -88,6 +512,38 @@ func convertFromDto(s SaleDto) *domain.Sale {
 		StoreID:      s.StoreID,
 		QuantitySold: s.QuantitySold,
 		SalePrice:    s.SalePrice,
+		Discount:     s.Discount,
+		VATRate:      s.VATRate,
+		Currency:     s.Currency,
 		SaleDate:     dt,
+
+		PaymentMethod: s.PaymentMethod,
+		Channel:       s.Channel,
+	}
+}
+
+func convertReceiptFromDto(r ReceiptDto) *domain.Receipt {
+	dt, _ := time.Parse(time.RFC3339, r.SaleDate)
+
+	receipt := &domain.Receipt{
+		ID:            r.ReceiptID,
+		StoreID:       r.StoreID,
+		PaymentMethod: r.PaymentMethod,
+		Channel:       r.Channel,
+		Currency:      r.Currency,
+		SaleDate:      dt,
+		Lines:         make([]domain.ReceiptLine, 0, len(r.Lines)),
 	}
+
+	for _, line := range r.Lines {
+		receipt.Lines = append(receipt.Lines, domain.ReceiptLine{
+			ProductID:    line.ProductID,
+			QuantitySold: line.QuantitySold,
+			SalePrice:    line.SalePrice,
+			Discount:     line.Discount,
+			VATRate:      line.VATRate,
+		})
+	}
+
+	return receipt
 }
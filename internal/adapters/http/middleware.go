@@ -0,0 +1,114 @@
+package http
+
+import (
+	"crypto/subtle"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+	"go.dataflow.ru/service-sales/pkg/ratelimit"
+)
+
+// RateLimit ограничивает частоту запросов клиента. Клиенты, передавшие в заголовке apiKeyHeader один из ключей
+// apiKeys, лимитируются по ключу, остальные - по IP-адресу: неизвестный ключ не дает отдельной корзины.
+func RateLimit(apiKeyHeader string, apiKeys []string, byAPIKey, byIP *ratelimit.Limiter) fiber.Handler {
+	known := make(map[string]struct{}, len(apiKeys))
+	for _, key := range apiKeys {
+		known[key] = struct{}{}
+	}
+
+	return func(c *fiber.Ctx) error {
+		limiter, key := byIP, "ip:"+c.IP()
+		if apiKey := c.Get(apiKeyHeader); apiKey != "" {
+			if _, ok := known[apiKey]; ok {
+				limiter, key = byAPIKey, "key:"+apiKey
+			}
+		}
+
+		if ok, wait := limiter.Allow(key); !ok {
+			return tooManyRequests(c, wait)
+		}
+
+		return c.Next()
+	}
+}
+
+// ConcurrencyLimit ограничивает число одновременно выполняемых запросов.
+// Запросы сверх лимита не ждут в очереди, а сразу получают 429.
+func ConcurrencyLimit(limit int, retryAfter time.Duration) fiber.Handler {
+	sem := make(chan struct{}, limit)
+
+	return func(c *fiber.Ctx) error {
+		select {
+		case sem <- struct{}{}:
+			defer func() { <-sem }()
+
+			return c.Next()
+		default:
+			return tooManyRequests(c, retryAfter)
+		}
+	}
+}
+
+func tooManyRequests(c *fiber.Ctx, wait time.Duration) error {
+	seconds := int64(math.Ceil(wait.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	c.Set(fiber.HeaderRetryAfter, strconv.FormatInt(seconds, 10))
+
+	return fiber.ErrTooManyRequests
+}
+
+// AdminTokenHeader заголовок с токеном администратора для административного API /admin/*.
+const AdminTokenHeader = "X-Admin-Token"
+
+// RequireToken пропускает только запросы с секретом token в заголовке header. Если секрет не задан,
+// отклоняются все запросы.
+func RequireToken(header, token string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if token == "" {
+			return fiber.ErrForbidden
+		}
+
+		if subtle.ConstantTimeCompare([]byte(c.Get(header)), []byte(token)) != 1 {
+			return fiber.ErrUnauthorized
+		}
+
+		return c.Next()
+	}
+}
+
+// ReadOnly отклоняет запросы записи на ведомом экземпляре. Ответ 307 содержит в Location адрес того же запроса
+// на ведущем экземпляре leaderURL, поэтому клиент может повторить запрос на ведущем.
+func ReadOnly(leaderURL string) fiber.Handler {
+	return redirect(leaderURL, domain.ErrReadOnlyReplica)
+}
+
+// LeaderOnly перенаправляет на ведущий экземпляр leaderURL чтение данных, которые не реплицируются: оповещений,
+// отчетов, правил и аномалий. Как и в ReadOnly, ответ 307 содержит в Location адрес того же запроса на ведущем.
+func LeaderOnly(leaderURL string) fiber.Handler {
+	return redirect(leaderURL, domain.ErrLeaderOnly)
+}
+
+// ReferenceCopy перенаправляет на первый узел кластера sourceURL изменение справочника и планов на остальных
+// узлах, которые поддерживают их копию (см. services.ReferenceService).
+func ReferenceCopy(sourceURL string) fiber.Handler {
+	return redirect(sourceURL, domain.ErrReferenceCopy)
+}
+
+// redirect отклоняет запрос с кодом 307 и ошибкой err, указывая в Location адрес того же запроса на baseURL.
+func redirect(baseURL string, err error) fiber.Handler {
+	baseURL = strings.TrimRight(baseURL, "/")
+
+	return func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderLocation, baseURL+c.OriginalURL())
+
+		return fiber.NewError(fiber.StatusTemporaryRedirect, err.Error())
+	}
+}
@@ -0,0 +1,297 @@
+package http
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+	"go.dataflow.ru/service-sales/internal/app/ports"
+)
+
+// NodeHandler внутренний API узла кластера (/cluster/*): операции с локальным хранилищем узла,
+// которые другие узлы выполняют для магазинов, принадлежащих этому узлу (см. cluster.Client).
+// Продажи приходят уже проверенными на узле, принявшем запрос.
+type NodeHandler struct {
+	storage ports.SalesStorage
+}
+
+// NewNodeHandler возвращает новый экземпляр обработчика.
+func NewNodeHandler(storage ports.SalesStorage) *NodeHandler {
+	return &NodeHandler{storage: storage}
+}
+
+// AddSale обрабатывает запрос на сохранение продажи в хранилище узла.
+func (h *NodeHandler) AddSale(c *fiber.Ctx) error {
+	return h.saveSale(c, h.storage.AddSale)
+}
+
+// InsertSale обрабатывает запрос на сохранение продажи, которая может быть раньше последней продажи магазина.
+func (h *NodeHandler) InsertSale(c *fiber.Ctx) error {
+	return h.saveSale(c, h.storage.InsertSale)
+}
+
+// saveSale сохраняет продажу из тела запроса функцией хранилища save.
+func (h *NodeHandler) saveSale(c *fiber.Ctx, save func(sale *domain.Sale) error) error {
+	var sale domain.Sale
+
+	if err := c.BodyParser(&sale); err != nil {
+		return fiber.ErrUnprocessableEntity
+	}
+
+	err := save(&sale)
+	if errors.Is(err, domain.ErrSaleOutOfRange) {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// AddReceipt обрабатывает запрос на сохранение чека в хранилище узла.
+func (h *NodeHandler) AddReceipt(c *fiber.Ctx) error {
+	var receipt domain.Receipt
+
+	if err := c.BodyParser(&receipt); err != nil {
+		return fiber.ErrUnprocessableEntity
+	}
+
+	err := h.storage.AddReceipt(&receipt)
+	if errors.Is(err, domain.ErrSaleOutOfRange) {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	if errors.Is(err, domain.ErrReceiptExists) {
+		return fiber.NewError(fiber.StatusConflict, err.Error())
+	}
+
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetSales обрабатывает запрос продаж узла.
+func (h *NodeHandler) GetSales(c *fiber.Ctx) error {
+	reader, err := h.reader(c.Query("snapshot"))
+	if err != nil {
+		return err
+	}
+
+	sales, err := reader.GetSales()
+	if err != nil {
+		return nodeError(err)
+	}
+
+	return c.JSON(sales)
+}
+
+// GetTotalSums обрабатывает запрос сумм продаж магазинов узла.
+func (h *NodeHandler) GetTotalSums(c *fiber.Ctx) error {
+	var req NodeTotalsRequest
+
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.ErrUnprocessableEntity
+	}
+
+	reader, err := h.reader(req.Snapshot)
+	if err != nil {
+		return err
+	}
+
+	totals, err := reader.GetTotalSums(req.Queries)
+	if err != nil {
+		return nodeError(err)
+	}
+
+	return c.JSON(totals)
+}
+
+// GetTotalSumByProduct обрабатывает запрос сумм продаж магазина узла в разрезе товаров.
+func (h *NodeHandler) GetTotalSumByProduct(c *fiber.Ctx) error {
+	var req NodeStorePeriodRequest
+
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.ErrUnprocessableEntity
+	}
+
+	reader, err := h.reader(req.Snapshot)
+	if err != nil {
+		return err
+	}
+
+	totals, err := reader.GetTotalSumByProduct(req.StoreID, req.StartDate, req.EndDate)
+	if err != nil {
+		return nodeError(err)
+	}
+
+	return c.JSON(totals)
+}
+
+// GetTotalSumByDimension обрабатывает запрос сумм продаж магазина узла в разрезе значений измерения.
+func (h *NodeHandler) GetTotalSumByDimension(c *fiber.Ctx) error {
+	var req NodeTotalsByDimensionRequest
+
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.ErrUnprocessableEntity
+	}
+
+	reader, err := h.reader(req.Snapshot)
+	if err != nil {
+		return err
+	}
+
+	totals, err := reader.GetTotalSumByDimension(req.StoreID, req.Dimension, req.StartDate, req.EndDate)
+	if err != nil {
+		return nodeError(err)
+	}
+
+	return c.JSON(totals)
+}
+
+// GetReceiptTotals обрабатывает запрос показателей чеков магазина узла.
+func (h *NodeHandler) GetReceiptTotals(c *fiber.Ctx) error {
+	var req NodeStorePeriodRequest
+
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.ErrUnprocessableEntity
+	}
+
+	reader, err := h.reader(req.Snapshot)
+	if err != nil {
+		return err
+	}
+
+	totals, err := reader.GetReceiptTotals(req.StoreID, req.StartDate, req.EndDate)
+	if err != nil {
+		return nodeError(err)
+	}
+
+	return c.JSON(totals)
+}
+
+// GetSketches обрабатывает запрос приближенной статистики продаж магазина узла.
+func (h *NodeHandler) GetSketches(c *fiber.Ctx) error {
+	var req NodeStorePeriodRequest
+
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.ErrUnprocessableEntity
+	}
+
+	reader, err := h.reader(req.Snapshot)
+	if err != nil {
+		return err
+	}
+
+	sketches, err := reader.GetSketches(req.StoreID, req.StartDate, req.EndDate)
+	if err != nil {
+		return nodeError(err)
+	}
+
+	return c.JSON(sketches)
+}
+
+// Query обрабатывает запрос агрегатов продаж магазинов узла.
+func (h *NodeHandler) Query(c *fiber.Ctx) error {
+	var req NodeQueryRequest
+
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.ErrUnprocessableEntity
+	}
+
+	loc, err := time.LoadLocation(req.TimeZone)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	reader, err := h.reader(req.Snapshot)
+	if err != nil {
+		return err
+	}
+
+	res, err := reader.Query(domain.Query{
+		StartDate: req.StartDate,
+		EndDate:   req.EndDate,
+		Filters:   req.Filters,
+		GroupBy:   req.GroupBy,
+		Metrics:   req.Metrics,
+		Bucket:    req.Bucket,
+		Location:  loc,
+	})
+	if err != nil {
+		return nodeError(err)
+	}
+
+	return c.JSON(res)
+}
+
+// OpenSnapshot обрабатывает запрос открытия снимка продаж узла.
+func (h *NodeHandler) OpenSnapshot(c *fiber.Ctx) error {
+	snapshot, err := h.storage.OpenSnapshot()
+	if err != nil {
+		return nodeError(err)
+	}
+
+	return c.JSON(snapshot)
+}
+
+// CloseSnapshot обрабатывает запрос закрытия снимка продаж узла.
+func (h *NodeHandler) CloseSnapshot(c *fiber.Ctx) error {
+	if err := h.storage.CloseSnapshot(c.Params("snapshot")); err != nil {
+		return nodeError(err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// Purge обрабатывает запрос удаления продаж в хранилище узла.
+func (h *NodeHandler) Purge(c *fiber.Ctx) error {
+	var purge domain.Purge
+
+	if err := c.BodyParser(&purge); err != nil {
+		return fiber.ErrUnprocessableEntity
+	}
+
+	deleted, err := h.storage.Purge(purge)
+	if errors.Is(err, domain.ErrInvalidPurge) {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(PurgeResponse{Deleted: deleted})
+}
+
+func (h *NodeHandler) reader(token string) (ports.SalesReader, error) {
+	if token == "" {
+		return h.storage, nil
+	}
+
+	reader, err := h.storage.Snapshot(token)
+	if err != nil {
+		return nil, nodeError(err)
+	}
+
+	return reader, nil
+}
+
+// nodeError возвращает ответ внутреннего API для ошибки хранилища узла.
+func nodeError(err error) error {
+	switch {
+	case errors.Is(err, domain.ErrSnapshotNotFound):
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	case errors.Is(err, domain.ErrTooManySnapshots):
+		return fiber.NewError(fiber.StatusTooManyRequests, err.Error())
+	case errors.Is(err, domain.ErrQueryTooLarge):
+		return fiber.NewError(fiber.StatusRequestEntityTooLarge, err.Error())
+	default:
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,27 @@
+package http
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"go.dataflow.ru/service-sales/internal/app/ports"
+)
+
+// ReferenceHandler обработчик справочных данных для экземпляров, поддерживающих их копию.
+type ReferenceHandler struct {
+	referenceService ports.ReferenceService
+}
+
+// NewReferenceHandler возвращает новый экземпляр обработчика.
+func NewReferenceHandler(service ports.ReferenceService) *ReferenceHandler {
+	return &ReferenceHandler{referenceService: service}
+}
+
+// Reference обрабатывает запрос справочника магазинов и товаров и планов экземпляра.
+func (h *ReferenceHandler) Reference(c *fiber.Ctx) error {
+	ref, err := h.referenceService.Reference()
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(ref)
+}
@@ -0,0 +1,74 @@
+package http
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+	"go.dataflow.ru/service-sales/internal/app/ports"
+)
+
+// maxReplicationBatch максимальное количество продаж журнала в одном ответе.
+const maxReplicationBatch = 10000
+
+// ReplicationHandler обработчик репликации: журнал продаж для ведомых экземпляров и состояние репликации.
+type ReplicationHandler struct {
+	replicationService ports.ReplicationService
+}
+
+// NewReplicationHandler возвращает новый экземпляр обработчика.
+func NewReplicationHandler(service ports.ReplicationService) *ReplicationHandler {
+	return &ReplicationHandler{replicationService: service}
+}
+
+// Versions обрабатывает запрос эпохи экземпляра и версий журналов магазинов (поколения и количества принятых
+// в нем продаж).
+func (h *ReplicationHandler) Versions(c *fiber.Ctx) error {
+	versions, err := h.replicationService.Versions()
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(versions)
+}
+
+// Sales обрабатывает запрос продаж магазина store_id из журнала эпохи epoch и поколения generation, начиная
+// с версии from, не более limit. Если эпоха экземпляра или поколение журнала магазина другие, возвращает 409.
+func (h *ReplicationHandler) Sales(c *fiber.Ctx) error {
+	storeID := c.Query("store_id")
+	if storeID == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "store_id not defined")
+	}
+
+	from := c.QueryInt("from", 0)
+	if from < 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "from must not be negative")
+	}
+
+	limit := c.QueryInt("limit", maxReplicationBatch)
+	if limit <= 0 || limit > maxReplicationBatch {
+		limit = maxReplicationBatch
+	}
+
+	sales, err := h.replicationService.SalesSince(c.Query("epoch"), storeID, c.QueryInt("generation", 0), from, limit)
+	if errors.Is(err, domain.ErrLogGenerationChanged) {
+		return fiber.NewError(fiber.StatusConflict, err.Error())
+	}
+
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(sales)
+}
+
+// Status обрабатывает запрос состояния репликации.
+func (h *ReplicationHandler) Status(c *fiber.Ctx) error {
+	status, err := h.replicationService.Status()
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(status)
+}
@@ -0,0 +1,144 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+	"go.dataflow.ru/service-sales/internal/app/ports"
+)
+
+// ReportHandler обработчик отчетов по расписанию.
+type ReportHandler struct {
+	reportService ports.ReportService
+}
+
+// NewReportHandler возвращает новый экземпляр обработчика.
+func NewReportHandler(service ports.ReportService) *ReportHandler {
+	return &ReportHandler{reportService: service}
+}
+
+// GetReports обрабатывает запрос получения отчетов.
+func (h *ReportHandler) GetReports(c *fiber.Ctx) error {
+	reports := h.reportService.GetReports()
+
+	res := make([]ReportResponse, 0, len(reports))
+	for _, report := range reports {
+		res = append(res, reportResponse(report))
+	}
+
+	return c.JSON(res)
+}
+
+// GetReport обрабатывает запрос получения отчета.
+func (h *ReportHandler) GetReport(c *fiber.Ctx) error {
+	report, err := h.reportService.GetReport(c.Params("id"))
+	if err != nil {
+		return reportError(err)
+	}
+
+	return c.JSON(reportResponse(report))
+}
+
+// CreateReport обрабатывает запрос создания отчета.
+func (h *ReportHandler) CreateReport(c *fiber.Ctx) error {
+	var report domain.Report
+
+	if err := c.BodyParser(&report); err != nil {
+		return fiber.ErrUnprocessableEntity
+	}
+
+	created, err := h.reportService.CreateReport(&report)
+	if err != nil {
+		return reportError(err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(reportResponse(created))
+}
+
+// UpdateReport обрабатывает запрос замены отчета.
+func (h *ReportHandler) UpdateReport(c *fiber.Ctx) error {
+	var report domain.Report
+
+	if err := c.BodyParser(&report); err != nil {
+		return fiber.ErrUnprocessableEntity
+	}
+
+	report.ID = c.Params("id")
+
+	updated, err := h.reportService.UpdateReport(&report)
+	if err != nil {
+		return reportError(err)
+	}
+
+	return c.JSON(reportResponse(updated))
+}
+
+// DeleteReport обрабатывает запрос удаления отчета вместе с результатами запусков.
+func (h *ReportHandler) DeleteReport(c *fiber.Ctx) error {
+	if err := h.reportService.DeleteReport(c.Params("id")); err != nil {
+		return reportError(err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// RunReport обрабатывает запрос запуска отчета вне расписания. Ошибки запроса и доставки возвращаются в запуске.
+func (h *ReportHandler) RunReport(c *fiber.Ctx) error {
+	run, err := h.reportService.RunReport(c.UserContext(), c.Params("id"))
+	if err != nil {
+		return reportError(err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(run)
+}
+
+// GetRuns обрабатывает запрос получения запусков отчета, последний - первым.
+func (h *ReportHandler) GetRuns(c *fiber.Ctx) error {
+	runs, err := h.reportService.GetRuns(c.Params("id"))
+	if err != nil {
+		return reportError(err)
+	}
+
+	return c.JSON(runs)
+}
+
+// GetRunContent обрабатывает запрос скачивания результата запуска отчета. Для неудачного запуска - 404
+// с ошибкой запуска.
+func (h *ReportHandler) GetRunContent(c *fiber.Ctx) error {
+	run, content, err := h.reportService.GetRunContent(c.Params("id"), c.Params("run_id"))
+	if err != nil {
+		return reportError(err)
+	}
+
+	if run.Error != "" {
+		return fiber.NewError(fiber.StatusNotFound, fmt.Sprintf("report run failed: %s", run.Error))
+	}
+
+	c.Set(fiber.HeaderContentType, domain.ReportContentType(run.Format))
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="report-%s-%s.%s"`,
+		run.ReportID, run.StartDate.Format("2006-01-02"), run.Format))
+
+	return c.Send(content)
+}
+
+func reportResponse(report *domain.Report) ReportResponse {
+	res := ReportResponse{Report: *report, Signed: report.Secret != ""}
+	res.Secret = ""
+
+	return res
+}
+
+func reportError(err error) error {
+	if errors.Is(err, domain.ErrReportNotFound) || errors.Is(err, domain.ErrReportRunNotFound) {
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+
+	if errors.Is(err, domain.ErrInvalidReport) {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+}
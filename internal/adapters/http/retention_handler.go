@@ -0,0 +1,45 @@
+package http
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+	"go.dataflow.ru/service-sales/internal/app/ports"
+)
+
+// RetentionHandler обработчик удаления продаж и журнала аудита.
+type RetentionHandler struct {
+	retentionService ports.RetentionService
+}
+
+// NewRetentionHandler возвращает новый экземпляр обработчика.
+func NewRetentionHandler(service ports.RetentionService) *RetentionHandler {
+	return &RetentionHandler{retentionService: service}
+}
+
+// Purge обрабатывает запрос удаления продаж. Инициатор удаления в журнале аудита - адрес клиента.
+func (h *RetentionHandler) Purge(c *fiber.Ctx) error {
+	var purge domain.Purge
+
+	if err := c.BodyParser(&purge); err != nil {
+		return fiber.ErrUnprocessableEntity
+	}
+
+	entry, err := h.retentionService.Purge(&purge, c.IP())
+	if errors.Is(err, domain.ErrInvalidPurge) {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(entry)
+}
+
+// GetAuditLog обрабатывает запрос журнала аудита.
+func (h *RetentionHandler) GetAuditLog(c *fiber.Ctx) error {
+	return c.JSON(h.retentionService.GetAuditLog())
+}
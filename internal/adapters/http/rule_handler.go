@@ -0,0 +1,22 @@
+package http
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"go.dataflow.ru/service-sales/internal/app/ports"
+)
+
+// RuleHandler обработчик бизнес-правил проверки продаж.
+type RuleHandler struct {
+	rules ports.RuleEngine
+}
+
+// NewRuleHandler возвращает новый экземпляр обработчика.
+func NewRuleHandler(rules ports.RuleEngine) *RuleHandler {
+	return &RuleHandler{rules: rules}
+}
+
+// GetRules обрабатывает запрос правил со статистикой проверок и нарушений.
+func (h *RuleHandler) GetRules(c *fiber.Ctx) error {
+	return c.JSON(h.rules.Stats())
+}
@@ -0,0 +1,44 @@
+package http
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+	"go.dataflow.ru/service-sales/internal/app/ports"
+)
+
+// SQLHandler обработчик SQL-запросов к продажам.
+type SQLHandler struct {
+	sqlService ports.SQLService
+}
+
+// NewSQLHandler возвращает новый экземпляр обработчика.
+func NewSQLHandler(service ports.SQLService) *SQLHandler {
+	return &SQLHandler{sqlService: service}
+}
+
+// Query обрабатывает SQL-запрос SELECT к таблице продаж sales.
+func (h *SQLHandler) Query(c *fiber.Ctx) error {
+	var req SQLRequest
+
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.ErrUnprocessableEntity
+	}
+
+	res, err := h.sqlService.Query(c.UserContext(), req.Query, req.Snapshot)
+
+	switch {
+	case errors.Is(err, domain.ErrInvalidQuery):
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	case errors.Is(err, domain.ErrQueryTimeout):
+		return fiber.NewError(fiber.StatusRequestTimeout, err.Error())
+	case errors.Is(err, domain.ErrSnapshotNotFound):
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	case err != nil:
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(res)
+}
@@ -0,0 +1,103 @@
+package http
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+	"go.dataflow.ru/service-sales/internal/app/ports"
+)
+
+// TargetHandler обработчик планов выручки магазинов.
+type TargetHandler struct {
+	targetService ports.TargetService
+}
+
+// NewTargetHandler возвращает новый экземпляр обработчика.
+func NewTargetHandler(service ports.TargetService) *TargetHandler {
+	return &TargetHandler{targetService: service}
+}
+
+// SaveTargets обрабатывает запрос на создание или замену планов (JSON-массив планов).
+func (h *TargetHandler) SaveTargets(c *fiber.Ctx) error {
+	var targets []*domain.Target
+
+	if err := c.BodyParser(&targets); err != nil {
+		return fiber.ErrUnprocessableEntity
+	}
+
+	if err := h.targetService.ImportTargets(targets); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(ImportResponse{Imported: len(targets)})
+}
+
+// ImportTargets обрабатывает запрос на загрузку планов из CSV.
+func (h *TargetHandler) ImportTargets(c *fiber.Ctx) error {
+	targets, err := parseTargetsCSV(bytes.NewReader(c.Body()))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	if err = h.targetService.ImportTargets(targets); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(ImportResponse{Imported: len(targets)})
+}
+
+// GetTargets обрабатывает запрос получения планов (всех или магазина store_id).
+func (h *TargetHandler) GetTargets(c *fiber.Ctx) error {
+	targets := h.targetService.GetTargets(c.Query("store_id"))
+	if targets == nil {
+		targets = []*domain.Target{}
+	}
+
+	return c.JSON(targets)
+}
+
+// DeleteTarget обрабатывает запрос на удаление плана магазина с датой начала start_date.
+func (h *TargetHandler) DeleteTarget(c *fiber.Ctx) error {
+	start, err := domain.ParseDate(c.Params("start_date"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "start date must be in YYYY-MM-DD format")
+	}
+
+	if err = h.targetService.DeleteTarget(c.Params("store_id"), start); err != nil {
+		return targetError(err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetAttainment обрабатывает запрос выполнения плана магазина на дату date (по умолчанию - на текущий момент).
+func (h *TargetHandler) GetAttainment(c *fiber.Ctx) error {
+	var day *domain.Date
+
+	if date := c.Query("date"); date != "" {
+		d, err := domain.ParseDate(date)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "date must be in YYYY-MM-DD format")
+		}
+
+		day = &d
+	}
+
+	attainment, err := h.targetService.GetAttainment(c.Params("store_id"), day)
+	if err != nil {
+		return targetError(err)
+	}
+
+	return c.JSON(attainment)
+}
+
+func targetError(err error) error {
+	if errors.Is(err, domain.ErrTargetNotFound) {
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+
+	return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+}
@@ -0,0 +1,176 @@
+package rates
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const dateLayout = "2006-01-02"
+
+// rate курс валюты к базовой, действующий начиная с effectiveFrom.
+type rate struct {
+	effectiveFrom time.Time
+	value         decimal.Decimal
+}
+
+// Table таблица курсов валют к базовой валюте с датами вступления в силу.
+type Table struct {
+	base  string
+	rates map[string][]rate // по валюте, упорядочены по effectiveFrom
+}
+
+// New возвращает пустую таблицу курсов. Без курсов возможен только пересчет валюты в саму себя.
+func New(base string) *Table {
+	return &Table{
+		base:  base,
+		rates: make(map[string][]rate),
+	}
+}
+
+// Load загружает таблицу курсов из CSV-файла.
+func Load(path, base string) (*Table, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open rates file: %w", err)
+	}
+	defer f.Close()
+
+	t := New(base)
+	if err = t.Read(f); err != nil {
+		return nil, fmt.Errorf("read rates file %s: %w", path, err)
+	}
+
+	return t, nil
+}
+
+// Read читает курсы в формате CSV с заголовком:
+//
+//	effective_date,currency,rate
+//	2024-01-01,KZT,0.2
+//
+// где rate - стоимость одной единицы валюты в базовой валюте, effective_date - дата (в UTC)
+// или момент в формате RFC3339, с которого курс действует.
+func (t *Table) Read(r io.Reader) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 3
+	reader.TrimLeadingSpace = true
+
+	line := 0
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return err
+		}
+
+		line++
+		if line == 1 && record[0] == "effective_date" {
+			continue
+		}
+
+		effectiveFrom, err := parseDate(record[0])
+		if err != nil {
+			return fmt.Errorf("line %d: invalid effective date %q", line, record[0])
+		}
+
+		value, err := decimal.NewFromString(record[2])
+		if err != nil || !value.IsPositive() {
+			return fmt.Errorf("line %d: invalid rate %q", line, record[2])
+		}
+
+		t.Add(strings.ToUpper(record[1]), effectiveFrom, value)
+	}
+
+	return nil
+}
+
+// Add добавляет курс валюты к базовой, действующий с момента effectiveFrom.
+func (t *Table) Add(currency string, effectiveFrom time.Time, value decimal.Decimal) {
+	rates := append(t.rates[currency], rate{effectiveFrom: effectiveFrom, value: value})
+
+	sort.SliceStable(rates, func(i, j int) bool {
+		return rates[i].effectiveFrom.Before(rates[j].effectiveFrom)
+	})
+
+	t.rates[currency] = rates
+}
+
+// Rate возвращает курс пересчета валюты from в валюту to, действующий на момент at.
+func (t *Table) Rate(from, to string, at time.Time) (decimal.Decimal, error) {
+	if from == to {
+		return decimal.NewFromInt(1), nil
+	}
+
+	fromRate, err := t.toBase(from, at)
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+
+	toRate, err := t.toBase(to, at)
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+
+	return fromRate.Div(toRate), nil
+}
+
+// Changes возвращает упорядоченные моменты вступления в силу новых курсов внутри интервала (startDate, endDate].
+func (t *Table) Changes(startDate, endDate time.Time) []time.Time {
+	seen := make(map[time.Time]struct{})
+
+	var changes []time.Time
+
+	for _, rates := range t.rates {
+		for _, r := range rates {
+			if !r.effectiveFrom.After(startDate) || r.effectiveFrom.After(endDate) {
+				continue
+			}
+
+			if _, ok := seen[r.effectiveFrom]; !ok {
+				seen[r.effectiveFrom] = struct{}{}
+				changes = append(changes, r.effectiveFrom)
+			}
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Before(changes[j]) })
+
+	return changes
+}
+
+// toBase возвращает курс валюты к базовой на момент at.
+func (t *Table) toBase(currency string, at time.Time) (decimal.Decimal, error) {
+	if currency == t.base {
+		return decimal.NewFromInt(1), nil
+	}
+
+	rates := t.rates[currency]
+
+	// последний курс, вступивший в силу не позже at
+	i := sort.Search(len(rates), func(i int) bool { return rates[i].effectiveFrom.After(at) })
+	if i == 0 {
+		return decimal.Decimal{}, fmt.Errorf("no %s/%s rate effective at %s", currency, t.base, at.Format(time.RFC3339))
+	}
+
+	return rates[i-1].value, nil
+}
+
+func parseDate(s string) (time.Time, error) {
+	if dt, err := time.Parse(dateLayout, s); err == nil {
+		return dt, nil
+	}
+
+	return time.Parse(time.RFC3339, s)
+}
@@ -0,0 +1,118 @@
+package rates
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testRates = `effective_date,currency,rate
+2024-01-01,KZT,0.2
+2024-06-01,KZT,0.25
+2024-01-01,BYN,28
+2024-03-15T12:00:00Z,BYN,30
+`
+
+func TestTable_Rate(t *testing.T) {
+	t.Parallel()
+
+	table := New("RUB")
+	require.NoError(t, table.Read(strings.NewReader(testRates)))
+
+	testCases := []struct {
+		name    string
+		from    string
+		to      string
+		at      time.Time
+		expRate decimal.Decimal
+		err     string
+	}{
+		{
+			name:    "одинаковые валюты",
+			from:    "USD",
+			to:      "USD",
+			at:      time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+			expRate: decimal.NewFromInt(1),
+		},
+		{
+			name:    "в базовую валюту",
+			from:    "KZT",
+			to:      "RUB",
+			at:      time.Date(2024, 5, 31, 23, 59, 59, 0, time.UTC),
+			expRate: decimal.RequireFromString("0.2"),
+		},
+		{
+			name:    "в базовую валюту после смены курса",
+			from:    "KZT",
+			to:      "RUB",
+			at:      time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+			expRate: decimal.RequireFromString("0.25"),
+		},
+		{
+			name:    "из базовой валюты",
+			from:    "RUB",
+			to:      "KZT",
+			at:      time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+			expRate: decimal.NewFromInt(5),
+		},
+		{
+			name:    "кросс-курс",
+			from:    "BYN",
+			to:      "KZT",
+			at:      time.Date(2024, 3, 20, 0, 0, 0, 0, time.UTC),
+			expRate: decimal.NewFromInt(150),
+		},
+		{
+			name: "курс еще не действует",
+			from: "KZT",
+			to:   "RUB",
+			at:   time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC),
+			err:  "no KZT/RUB rate effective at 2023-12-31T00:00:00Z",
+		},
+		{
+			name: "неизвестная валюта",
+			from: "USD",
+			to:   "RUB",
+			at:   time.Date(2024, 3, 20, 0, 0, 0, 0, time.UTC),
+			err:  "no USD/RUB rate effective at 2024-03-20T00:00:00Z",
+		},
+	}
+
+	for _, tt := range testCases {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			rate, err := table.Rate(tt.from, tt.to, tt.at)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.True(t, tt.expRate.Equal(rate), "expected %s, got %s", tt.expRate, rate)
+		})
+	}
+}
+
+func TestTable_Changes(t *testing.T) {
+	t.Parallel()
+
+	table := New("RUB")
+	require.NoError(t, table.Read(strings.NewReader(testRates)))
+
+	changes := table.Changes(
+		time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+	)
+
+	assert.Equal(t, []time.Time{
+		time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC),
+		time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+	}, changes)
+}
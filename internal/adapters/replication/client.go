@@ -0,0 +1,129 @@
+package replication
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+)
+
+const defaultTimeout = 10 * time.Second
+
+// TokenHeader заголовок с общим секретом ведущего и ведомых, которым ведомый подтверждает запросы журнала.
+const TokenHeader = "X-Replication-Token"
+
+// Client читает журнал продаж и справочные данные ведущего экземпляра по HTTP (GET /replication/versions,
+// GET /replication/sales, GET /replication/reference).
+type Client struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+type Option func(c *Client)
+
+// WithToken задает общий секрет ведущего и ведомых для запросов журнала.
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// WithHTTPClient задает HTTP-клиент для запросов к ведущему.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Client) {
+		c.http = client
+	}
+}
+
+// New возвращает клиент журнала ведущего экземпляра с адресом baseURL.
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		http:    &http.Client{Timeout: defaultTimeout},
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// Versions возвращает эпоху ведущего и версии журналов его магазинов.
+func (c *Client) Versions() (domain.LogVersions, error) {
+	var versions domain.LogVersions
+	if err := c.get("/replication/versions", nil, &versions); err != nil {
+		return domain.LogVersions{}, err
+	}
+
+	return versions, nil
+}
+
+// SalesSince возвращает не более limit продаж магазина из журнала ведущего эпохи epoch и поколения generation,
+// начиная с версии version.
+func (c *Client) SalesSince(epoch, storeID string, generation, version, limit int) ([]*domain.Sale, error) {
+	query := url.Values{
+		"epoch":      {epoch},
+		"store_id":   {storeID},
+		"generation": {strconv.Itoa(generation)},
+		"from":       {strconv.Itoa(version)},
+		"limit":      {strconv.Itoa(limit)},
+	}
+
+	var sales []*domain.Sale
+	if err := c.get("/replication/sales", query, &sales); err != nil {
+		return nil, err
+	}
+
+	return sales, nil
+}
+
+// Reference возвращает справочник магазинов и товаров и планы ведущего.
+func (c *Client) Reference() (domain.ReferenceData, error) {
+	var ref domain.ReferenceData
+	if err := c.get("/replication/reference", nil, &ref); err != nil {
+		return domain.ReferenceData{}, err
+	}
+
+	return ref, nil
+}
+
+func (c *Client) get(path string, query url.Values, res interface{}) error {
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return fmt.Errorf("create request %s: %w", path, err)
+	}
+
+	req.Header.Set(TokenHeader, c.token)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("request leader: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusConflict {
+		return fmt.Errorf("request leader %s: %w", path, domain.ErrLogGenerationChanged)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("request leader %s: status %d", path, resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(res); err != nil {
+		return fmt.Errorf("decode leader response %s: %w", path, err)
+	}
+
+	return nil
+}
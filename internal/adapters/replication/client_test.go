@@ -0,0 +1,221 @@
+package replication
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.dataflow.ru/service-sales/internal/adapters/catalog"
+	salesHttp "go.dataflow.ru/service-sales/internal/adapters/http"
+	"go.dataflow.ru/service-sales/internal/adapters/storage"
+	"go.dataflow.ru/service-sales/internal/adapters/targets"
+	"go.dataflow.ru/service-sales/internal/app/domain"
+	"go.dataflow.ru/service-sales/internal/app/services"
+	"go.dataflow.ru/service-sales/pkg/logger"
+)
+
+// appTransport передает запросы HTTP-клиента приложению fiber в том же процессе.
+type appTransport struct {
+	app *fiber.App
+}
+
+func (t appTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.app.Test(req, -1)
+}
+
+func TestReplication_LeaderFollower(t *testing.T) {
+	t.Parallel()
+
+	// ведущий: хранилище и приложение с журналом; при перезапуске создаются заново
+	var leaderStorage *storage.SalesStorage
+
+	transport := &appTransport{}
+	startLeader := func() {
+		leaderStorage = storage.New(logger.NoOpLogger(), storage.WithIndexGranularity(3))
+
+		rh := salesHttp.NewReplicationHandler(services.NewReplicationService(leaderStorage, logger.NoOpLogger()))
+		token := salesHttp.RequireToken(TokenHeader, "secret")
+		transport.app = fiber.New()
+		transport.app.Get("/replication/versions", token, rh.Versions)
+		transport.app.Get("/replication/sales", token, rh.Sales)
+	}
+
+	startLeader()
+
+	followerStorage := storage.New(logger.NoOpLogger())
+	follower := services.NewReplicationService(followerStorage, logger.NoOpLogger(),
+		services.FollowLeader(New("http://leader", WithToken("secret"), WithHTTPClient(&http.Client{Transport: transport})), "http://leader"),
+		services.WithReplicationBatchSize(4),
+	)
+
+	dt := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	addSales := func(from, to int) {
+		for i := from; i < to; i++ {
+			require.NoError(t, leaderStorage.AddSale(&domain.Sale{
+				StoreID:      fmt.Sprintf("store_%d", i%3),
+				ProductID:    fmt.Sprintf("product_%d", i%4),
+				QuantitySold: int64(i%2 + 1),
+				SalePrice:    decimal.New(int64(1000+i), -2),
+				Discount:     decimal.NewFromInt(int64(i % 2)),
+				VATRate:      decimal.NewFromInt(20),
+				Currency:     []string{"RUB", "KZT"}[i%2],
+				SaleDate:     dt.Add(time.Duration(i) * time.Hour),
+			}))
+		}
+	}
+
+	assertReplicated := func() {
+		leaderSales, err := leaderStorage.GetSales()
+		require.NoError(t, err)
+
+		followerSales, err := followerStorage.GetSales()
+		require.NoError(t, err)
+
+		assert.ElementsMatch(t, saleKeys(leaderSales), saleKeys(followerSales))
+
+		for i := 0; i < 3; i++ {
+			storeID := fmt.Sprintf("store_%d", i)
+
+			expected, err := leaderStorage.GetTotalSum(storeID, dt, dt.AddDate(1, 0, 0))
+			require.NoError(t, err)
+
+			actual, err := followerStorage.GetTotalSum(storeID, dt, dt.AddDate(1, 0, 0))
+			require.NoError(t, err)
+
+			assert.Equal(t, totalKeys(expected), totalKeys(actual))
+		}
+
+		status, err := follower.Status()
+		require.NoError(t, err)
+		assert.Equal(t, 0, status.Lag)
+		assert.Empty(t, status.Error)
+
+		versions, err := leaderStorage.Versions()
+		require.NoError(t, err)
+		require.Len(t, status.Versions, len(versions))
+
+		for storeID, version := range versions {
+			assert.Equal(t, version.Version, status.Versions[storeID], storeID)
+		}
+	}
+
+	addSales(0, 20)
+	require.NoError(t, follower.Sync())
+	assertReplicated()
+
+	// ведомый дочитывает только новые продажи
+	addSales(20, 35)
+	require.NoError(t, follower.Sync())
+	assertReplicated()
+
+	// после удаления продаж на ведущем журнал магазина начинается заново, и ведомый перечитывает магазин,
+	// даже если новая версия журнала уже больше прежней
+	before := dt.Add(10 * time.Hour)
+	deleted, err := leaderStorage.Purge(domain.Purge{StoreID: "store_0", Before: &before})
+	require.NoError(t, err)
+	assert.Equal(t, 4, deleted)
+
+	addSales(35, 50)
+	require.NoError(t, follower.Sync())
+	assertReplicated()
+
+	// после перезапуска ведущего журналы начинаются заново в новой эпохе: ведомый перечитывает все магазины,
+	// даже если новые версии журналов уже не меньше прежних
+	startLeader()
+	addSales(100, 160)
+	require.NoError(t, follower.Sync())
+	assertReplicated()
+
+	// журнал не отдается без секрета репликации
+	_, err = New("http://leader", WithToken("wrong"), WithHTTPClient(&http.Client{Transport: transport})).Versions()
+	assert.ErrorContains(t, err, "status 401")
+
+	// ведомый не принимает продажи и указывает адрес ведущего
+	app := fiber.New()
+	app.Post("/data", salesHttp.ReadOnly("http://leader:8005/"))
+
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodPost, "/data?source=pos", nil), -1)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusTemporaryRedirect, resp.StatusCode)
+	assert.Equal(t, "http://leader:8005/data?source=pos", resp.Header.Get(fiber.HeaderLocation))
+
+	// оповещения не реплицируются, и их чтение тоже перенаправляется на ведущий
+	app.Get("/alerts", salesHttp.LeaderOnly("http://leader:8005/"))
+
+	resp, err = app.Test(httptest.NewRequest(fiber.MethodGet, "/alerts", nil), -1)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusTemporaryRedirect, resp.StatusCode)
+	assert.Equal(t, "http://leader:8005/alerts", resp.Header.Get(fiber.HeaderLocation))
+}
+
+func TestReplication_Reference(t *testing.T) {
+	t.Parallel()
+
+	newReference := func(opts ...services.ReferenceOption) (*catalog.Storage, *targets.Storage, *services.ReferenceService) {
+		catalogRepo, err := catalog.New("")
+		require.NoError(t, err)
+
+		targetRepo, err := targets.New("")
+		require.NoError(t, err)
+
+		return catalogRepo, targetRepo, services.NewReferenceService(catalogRepo, targetRepo, logger.NoOpLogger(), opts...)
+	}
+
+	leaderCatalog, leaderTargets, leaderReference := newReference()
+
+	app := fiber.New()
+	app.Get("/replication/reference", salesHttp.RequireToken(TokenHeader, "secret"),
+		salesHttp.NewReferenceHandler(leaderReference).Reference)
+
+	client := New("http://leader", WithToken("secret"), WithHTTPClient(&http.Client{Transport: appTransport{app: app}}))
+	followerCatalog, _, follower := newReference(services.FollowReference(client, "http://leader"))
+
+	require.NoError(t, followerCatalog.SaveStores(&domain.Store{ID: "store_9"}))
+	require.NoError(t, leaderCatalog.SaveStores(&domain.Store{ID: "store_1", TimeZone: "Europe/Moscow"}))
+	require.NoError(t, leaderCatalog.SaveProducts(&domain.Product{ID: "product_1", Category: "bread"}))
+
+	start := domain.Date{Year: 2024, Month: 5, Day: 1}
+	require.NoError(t, leaderTargets.SaveTargets(&domain.Target{
+		StoreID: "store_1", Start: start, End: start.AddDays(30), Amount: decimal.RequireFromString("3000.50"), Currency: "RUB",
+	}))
+
+	require.NoError(t, follower.Sync())
+
+	expected, err := leaderReference.Reference()
+	require.NoError(t, err)
+
+	actual, err := follower.Reference()
+	require.NoError(t, err)
+
+	assert.Equal(t, expected.Stores, actual.Stores)
+	assert.Equal(t, expected.Products, actual.Products)
+	require.Len(t, actual.Targets, 1)
+	assert.Equal(t, "3000.5", actual.Targets[0].Amount.String())
+}
+
+func saleKeys(sales []*domain.Sale) []string {
+	keys := make([]string, 0, len(sales))
+	for _, sale := range sales {
+		keys = append(keys, fmt.Sprintf("%s/%s/%d/%s/%s/%s/%s/%d", sale.StoreID, sale.ProductID, sale.QuantitySold,
+			sale.SalePrice, sale.Discount, sale.VATRate, sale.Currency, sale.SaleDate.UnixNano()))
+	}
+
+	return keys
+}
+
+func totalKeys(totals domain.Totals) map[string]string {
+	keys := make(map[string]string, len(totals))
+	for currency, a := range totals {
+		keys[currency] = fmt.Sprintf("%s/%s/%s/%s", a.Gross, a.Discount, a.Tax, a.Net)
+	}
+
+	return keys
+}
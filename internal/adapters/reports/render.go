@@ -0,0 +1,180 @@
+package reports
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"time"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+)
+
+// Renderer представляет результат запроса отчета таблицей: по столбцу на интервал разбивки (если задана),
+// поля группировки, валюту и показатели в порядке запроса.
+type Renderer struct{}
+
+// NewRenderer возвращает новый экземпляр.
+func NewRenderer() *Renderer {
+	return &Renderer{}
+}
+
+// jsonReport содержимое отчета в формате JSON.
+type jsonReport struct {
+	Report    string            `json:"report"`
+	Name      string            `json:"name"`
+	StartDate time.Time         `json:"start_date"`
+	EndDate   time.Time         `json:"end_date"`
+	CreatedAt time.Time         `json:"created_at"`
+	Rows      []domain.QueryRow `json:"rows"`
+}
+
+// Render представляет результат запроса отчета в формате отчета.
+func (r *Renderer) Render(report *domain.Report, run *domain.ReportRun, result *domain.QueryResult) ([]byte, error) {
+	switch run.Format {
+	case domain.ReportJSON:
+		rows := result.Rows
+		if rows == nil {
+			rows = []domain.QueryRow{}
+		}
+
+		return json.MarshalIndent(jsonReport{
+			Report:    report.ID,
+			Name:      report.Name,
+			StartDate: run.StartDate,
+			EndDate:   run.EndDate,
+			CreatedAt: run.StartedAt,
+			Rows:      rows,
+		}, "", "  ")
+	case domain.ReportCSV:
+		return renderCSV(report, result)
+	case domain.ReportHTML:
+		return renderHTML(report, run, result)
+	default:
+		return nil, fmt.Errorf("unknown report format %q", run.Format)
+	}
+}
+
+// table возвращает заголовок и строки таблицы результата.
+func table(report *domain.Report, result *domain.QueryResult) ([]string, [][]string) {
+	fields := append([]string(nil), report.GroupBy...)
+	if !containsString(fields, domain.FieldCurrency) {
+		fields = append(fields, domain.FieldCurrency)
+	}
+
+	var header []string
+	if report.Bucket != "" {
+		header = append(header, "bucket")
+	}
+
+	header = append(append(header, fields...), report.Metrics...)
+
+	rows := make([][]string, 0, len(result.Rows))
+
+	for _, row := range result.Rows {
+		line := make([]string, 0, len(header))
+
+		if report.Bucket != "" {
+			bucket := ""
+			if row.Bucket != nil {
+				bucket = row.Bucket.Format(time.RFC3339)
+			}
+
+			line = append(line, bucket)
+		}
+
+		for _, field := range fields {
+			line = append(line, row.Group[field])
+		}
+
+		for _, metric := range report.Metrics {
+			line = append(line, row.Metrics[metric].String())
+		}
+
+		rows = append(rows, line)
+	}
+
+	return header, rows
+}
+
+func renderCSV(report *domain.Report, result *domain.QueryResult) ([]byte, error) {
+	header, rows := table(report, result)
+
+	var buf bytes.Buffer
+
+	w := csv.NewWriter(&buf)
+	_ = w.Write(header)
+	_ = w.WriteAll(rows)
+
+	if err := w.Error(); err != nil {
+		return nil, fmt.Errorf("render csv: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+var htmlTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Name}}</title>
+<style>
+table { border-collapse: collapse; font-family: sans-serif; font-size: 14px; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; }
+td.number { text-align: right; }
+</style>
+</head>
+<body>
+<h1>{{.Name}}</h1>
+<p>{{.StartDate}} - {{.EndDate}}</p>
+<table>
+<tr>{{range .Header}}<th>{{.}}</th>{{end}}</tr>
+{{range .Rows}}<tr>{{range $i, $v := .}}<td{{if index $.Numeric $i}} class="number"{{end}}>{{$v}}</td>{{end}}</tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+func renderHTML(report *domain.Report, run *domain.ReportRun, result *domain.QueryResult) ([]byte, error) {
+	header, rows := table(report, result)
+
+	// показатели - последние столбцы таблицы
+	numeric := make([]bool, len(header))
+	for i := len(header) - len(report.Metrics); i < len(header); i++ {
+		numeric[i] = true
+	}
+
+	var buf bytes.Buffer
+
+	err := htmlTemplate.Execute(&buf, struct {
+		Name      string
+		StartDate string
+		EndDate   string
+		Header    []string
+		Rows      [][]string
+		Numeric   []bool
+	}{
+		Name:      report.Name,
+		StartDate: run.StartDate.Format(time.RFC3339),
+		EndDate:   run.EndDate.Format(time.RFC3339),
+		Header:    header,
+		Rows:      rows,
+		Numeric:   numeric,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("render html: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,93 @@
+package reports
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+)
+
+func TestRenderer_Render(t *testing.T) {
+	t.Parallel()
+
+	day := time.Date(2024, 4, 8, 0, 0, 0, 0, time.UTC)
+
+	report := &domain.Report{
+		ID:      "r1",
+		Name:    "Выручка <по магазинам>",
+		GroupBy: []string{domain.FieldStore},
+		Metrics: []string{domain.MetricGross, domain.MetricSales},
+		Bucket:  domain.BucketDay,
+	}
+
+	result := &domain.QueryResult{Rows: []domain.QueryRow{
+		{
+			Bucket: &day,
+			Group:  map[string]string{domain.FieldStore: "store_1", domain.FieldCurrency: "RUB"},
+			Metrics: map[string]decimal.Decimal{
+				domain.MetricGross: decimal.RequireFromString("1000.50"),
+				domain.MetricSales: decimal.NewFromInt(3),
+			},
+		},
+	}}
+
+	run := func(format string) *domain.ReportRun {
+		return &domain.ReportRun{
+			ID:        "run1",
+			ReportID:  "r1",
+			Format:    format,
+			StartedAt: day.AddDate(0, 0, 7),
+			StartDate: day,
+			EndDate:   day.AddDate(0, 0, 7).Add(-time.Nanosecond),
+		}
+	}
+
+	r := NewRenderer()
+
+	t.Run("csv", func(t *testing.T) {
+		t.Parallel()
+
+		content, err := r.Render(report, run(domain.ReportCSV), result)
+		require.NoError(t, err)
+		assert.Equal(t, "bucket,store,currency,gross,sales\n2024-04-08T00:00:00Z,store_1,RUB,1000.5,3\n", string(content))
+	})
+
+	t.Run("json", func(t *testing.T) {
+		t.Parallel()
+
+		content, err := r.Render(report, run(domain.ReportJSON), result)
+		require.NoError(t, err)
+
+		var doc struct {
+			Report string            `json:"report"`
+			Rows   []domain.QueryRow `json:"rows"`
+		}
+		require.NoError(t, json.Unmarshal(content, &doc))
+		assert.Equal(t, "r1", doc.Report)
+		require.Len(t, doc.Rows, 1)
+		assert.Equal(t, "store_1", doc.Rows[0].Group[domain.FieldStore])
+	})
+
+	t.Run("html", func(t *testing.T) {
+		t.Parallel()
+
+		content, err := r.Render(report, run(domain.ReportHTML), result)
+		require.NoError(t, err)
+
+		// название экранируется, показатели выравниваются по правому краю
+		assert.Contains(t, string(content), "Выручка &lt;по магазинам&gt;")
+		assert.Contains(t, string(content), `<td>store_1</td><td>RUB</td><td class="number">1000.5</td><td class="number">3</td>`)
+	})
+
+	t.Run("неизвестный формат", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := r.Render(report, run("xlsx"), result)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,319 @@
+package reports
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+)
+
+// defaultKeepRuns количество хранимых запусков каждого отчета по умолчанию.
+const defaultKeepRuns = 20
+
+// Storage отчеты по расписанию и результаты их запусков. Описания отчетов и запусков хранятся в памяти
+// и при каждом изменении целиком сохраняются в файл reports.json каталога хранилища, содержимое запусков -
+// в отдельных файлах runs/<id>.<формат>. Без каталога все хранится только в памяти. У каждого отчета хранятся
+// только последние запуски, содержимое более старых удаляется.
+type Storage struct {
+	reports []*domain.Report
+	runs    map[string][]*domain.ReportRun // по отчету, в порядке запуска
+	content map[string][]byte              // содержимое запусков, если каталог не задан
+
+	dir      string
+	keepRuns int
+
+	mu sync.RWMutex
+}
+
+// index содержимое файла reports.json.
+type index struct {
+	Reports []*domain.Report    `json:"reports"`
+	Runs    []*domain.ReportRun `json:"runs"`
+}
+
+type Option func(s *Storage)
+
+// WithKeepRuns задает количество хранимых запусков каждого отчета.
+func WithKeepRuns(n int) Option {
+	return func(s *Storage) {
+		if n > 0 {
+			s.keepRuns = n
+		}
+	}
+}
+
+// New возвращает хранилище, загруженное из каталога dir. Если каталог пустой, отчеты не сохраняются на диск.
+func New(dir string, opts ...Option) (*Storage, error) {
+	s := &Storage{
+		runs:     make(map[string][]*domain.ReportRun),
+		content:  make(map[string][]byte),
+		dir:      dir,
+		keepRuns: defaultKeepRuns,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if dir == "" {
+		return s, nil
+	}
+
+	if err := os.MkdirAll(filepath.Join(dir, "runs"), 0o755); err != nil {
+		return nil, fmt.Errorf("create reports dir: %w", err)
+	}
+
+	data, err := os.ReadFile(s.indexPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("read reports: %w", err)
+	}
+
+	var idx index
+	if err = json.Unmarshal(data, &idx); err != nil {
+		return nil, fmt.Errorf("decode reports: %w", err)
+	}
+
+	s.reports = idx.Reports
+
+	for _, run := range idx.Runs {
+		s.runs[run.ReportID] = append(s.runs[run.ReportID], run)
+	}
+
+	return s, nil
+}
+
+// SaveReport создает отчет или заменяет отчет с тем же идентификатором.
+// Изменение применяется, только если его удалось сохранить.
+func (s *Storage) SaveReport(report *domain.Report) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prev := s.reports
+
+	reports := append([]*domain.Report(nil), s.reports...)
+	if i := s.index(report.ID); i >= 0 {
+		reports[i] = report
+	} else {
+		reports = append(reports, report)
+	}
+
+	s.reports = reports
+
+	if err := s.persist(); err != nil {
+		s.reports = prev
+
+		return err
+	}
+
+	return nil
+}
+
+// DeleteReport удаляет отчет вместе с результатами запусков.
+func (s *Storage) DeleteReport(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.index(id)
+	if i < 0 {
+		return domain.ErrReportNotFound
+	}
+
+	prev, runs := s.reports, s.runs[id]
+
+	s.reports = append(s.reports[:i:i], s.reports[i+1:]...)
+	delete(s.runs, id)
+
+	if err := s.persist(); err != nil {
+		s.reports, s.runs[id] = prev, runs
+
+		return err
+	}
+
+	s.removeContent(runs)
+
+	return nil
+}
+
+func (s *Storage) GetReport(id string) (*domain.Report, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	i := s.index(id)
+	if i < 0 {
+		return nil, domain.ErrReportNotFound
+	}
+
+	return s.reports[i], nil
+}
+
+// GetReports возвращает отчеты в порядке создания.
+func (s *Storage) GetReports() []*domain.Report {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]*domain.Report(nil), s.reports...)
+}
+
+// SaveRun сохраняет запуск отчета и его содержимое (nil для неудачного запуска). Запуски сверх
+// хранимого количества удаляются.
+func (s *Storage) SaveRun(run *domain.ReportRun, content []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.index(run.ReportID) < 0 {
+		return domain.ErrReportNotFound
+	}
+
+	if content != nil {
+		if err := s.writeContent(run, content); err != nil {
+			return err
+		}
+	}
+
+	prev := s.runs[run.ReportID]
+
+	runs := append(append([]*domain.ReportRun(nil), prev...), run)
+
+	var evicted []*domain.ReportRun
+	if len(runs) > s.keepRuns {
+		evicted, runs = runs[:len(runs)-s.keepRuns], runs[len(runs)-s.keepRuns:]
+	}
+
+	s.runs[run.ReportID] = runs
+
+	if err := s.persist(); err != nil {
+		s.runs[run.ReportID] = prev
+		s.removeContent([]*domain.ReportRun{run})
+
+		return err
+	}
+
+	s.removeContent(evicted)
+
+	return nil
+}
+
+// GetRuns возвращает запуски отчета, последний - первым.
+func (s *Storage) GetRuns(reportID string) []*domain.ReportRun {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	runs := s.runs[reportID]
+
+	res := make([]*domain.ReportRun, len(runs))
+	for i, run := range runs {
+		res[len(runs)-1-i] = run
+	}
+
+	return res
+}
+
+// GetRunContent возвращает запуск отчета и его содержимое.
+func (s *Storage) GetRunContent(reportID, runID string) (*domain.ReportRun, []byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, run := range s.runs[reportID] {
+		if run.ID != runID {
+			continue
+		}
+
+		if run.Error != "" {
+			return run, nil, nil
+		}
+
+		if s.dir == "" {
+			return run, s.content[run.ID], nil
+		}
+
+		content, err := os.ReadFile(s.contentPath(run))
+		if err != nil {
+			return nil, nil, fmt.Errorf("read report run %s: %w", run.ID, err)
+		}
+
+		return run, content, nil
+	}
+
+	return nil, nil, domain.ErrReportRunNotFound
+}
+
+// index возвращает индекс отчета с идентификатором id или -1. Вызывается под блокировкой.
+func (s *Storage) index(id string) int {
+	for i, report := range s.reports {
+		if report.ID == id {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func (s *Storage) writeContent(run *domain.ReportRun, content []byte) error {
+	if s.dir == "" {
+		s.content[run.ID] = content
+		return nil
+	}
+
+	if err := os.WriteFile(s.contentPath(run), content, 0o644); err != nil {
+		return fmt.Errorf("write report run %s: %w", run.ID, err)
+	}
+
+	return nil
+}
+
+// removeContent удаляет содержимое запусков. Ошибка удаления файла не мешает работе хранилища:
+// файл запуска, которого нет в описании, больше не читается.
+func (s *Storage) removeContent(runs []*domain.ReportRun) {
+	for _, run := range runs {
+		if s.dir == "" {
+			delete(s.content, run.ID)
+			continue
+		}
+
+		_ = os.Remove(s.contentPath(run))
+	}
+}
+
+func (s *Storage) indexPath() string {
+	return filepath.Join(s.dir, "reports.json")
+}
+
+func (s *Storage) contentPath(run *domain.ReportRun) string {
+	return filepath.Join(s.dir, "runs", run.ID+"."+run.Format)
+}
+
+// persist сохраняет описания отчетов и запусков через временный файл. Вызывается под блокировкой.
+func (s *Storage) persist() error {
+	if s.dir == "" {
+		return nil
+	}
+
+	idx := index{Reports: s.reports, Runs: []*domain.ReportRun{}}
+	for _, report := range s.reports {
+		idx.Runs = append(idx.Runs, s.runs[report.ID]...)
+	}
+
+	data, err := json.MarshalIndent(idx, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode reports: %w", err)
+	}
+
+	tmp := s.indexPath() + ".tmp"
+	if err = os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("write reports: %w", err)
+	}
+
+	if err = os.Rename(tmp, s.indexPath()); err != nil {
+		return fmt.Errorf("write reports: %w", err)
+	}
+
+	return nil
+}
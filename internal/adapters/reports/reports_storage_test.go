@@ -0,0 +1,120 @@
+package reports
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+)
+
+func TestStorage_Persistence(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	s, err := New(dir)
+	require.NoError(t, err)
+
+	report := &domain.Report{ID: "r1", Name: "Выручка за неделю", Format: domain.ReportCSV, Secret: "secret"}
+	require.NoError(t, s.SaveReport(report))
+
+	ok := &domain.ReportRun{ID: "run1", ReportID: "r1", Format: domain.ReportCSV, Rows: 1}
+	failed := &domain.ReportRun{ID: "run2", ReportID: "r1", Format: domain.ReportCSV, Error: "query failed"}
+
+	require.NoError(t, s.SaveRun(ok, []byte("currency,gross\nRUB,1000\n")))
+	require.NoError(t, s.SaveRun(failed, nil))
+
+	assert.ErrorIs(t, s.SaveRun(&domain.ReportRun{ID: "run3", ReportID: "r2"}, nil), domain.ErrReportNotFound)
+
+	// отчет и запуски восстанавливаются из каталога
+	restored, err := New(dir)
+	require.NoError(t, err)
+
+	reports := restored.GetReports()
+	require.Len(t, reports, 1)
+	assert.Equal(t, "secret", reports[0].Secret)
+
+	runs := restored.GetRuns("r1")
+	require.Len(t, runs, 2)
+	assert.Equal(t, "run2", runs[0].ID)
+	assert.Equal(t, "run1", runs[1].ID)
+
+	run, content, err := restored.GetRunContent("r1", "run1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, run.Rows)
+	assert.Equal(t, "currency,gross\nRUB,1000\n", string(content))
+
+	run, content, err = restored.GetRunContent("r1", "run2")
+	require.NoError(t, err)
+	assert.Equal(t, "query failed", run.Error)
+	assert.Nil(t, content)
+
+	_, _, err = restored.GetRunContent("r1", "run3")
+	assert.ErrorIs(t, err, domain.ErrReportRunNotFound)
+
+	// удаление отчета удаляет содержимое запусков
+	require.NoError(t, restored.DeleteReport("r1"))
+	assert.ErrorIs(t, restored.DeleteReport("r1"), domain.ErrReportNotFound)
+	assert.Empty(t, restored.GetRuns("r1"))
+
+	_, err = os.Stat(filepath.Join(dir, "runs", "run1.csv"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestStorage_KeepRuns(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name string
+		dir  string
+	}{
+		{
+			name: "в каталоге",
+			dir:  t.TempDir(),
+		},
+		{
+			name: "в памяти",
+		},
+	}
+
+	for _, tt := range testCases {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			s, err := New(tt.dir, WithKeepRuns(2))
+			require.NoError(t, err)
+
+			require.NoError(t, s.SaveReport(&domain.Report{ID: "r1"}))
+
+			for _, id := range []string{"run1", "run2", "run3"} {
+				require.NoError(t, s.SaveRun(&domain.ReportRun{ID: id, ReportID: "r1", Format: domain.ReportJSON}, []byte(id)))
+			}
+
+			runs := s.GetRuns("r1")
+			require.Len(t, runs, 2)
+			assert.Equal(t, "run3", runs[0].ID)
+			assert.Equal(t, "run2", runs[1].ID)
+
+			// содержимое вытесненного запуска удалено
+			_, _, err = s.GetRunContent("r1", "run1")
+			assert.ErrorIs(t, err, domain.ErrReportRunNotFound)
+
+			if tt.dir != "" {
+				_, err = os.Stat(filepath.Join(tt.dir, "runs", "run1.json"))
+				assert.ErrorIs(t, err, os.ErrNotExist)
+			} else {
+				assert.NotContains(t, s.content, "run1")
+			}
+
+			_, content, err := s.GetRunContent("r1", "run3")
+			require.NoError(t, err)
+			assert.Equal(t, "run3", string(content))
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+)
+
+// Load загружает бизнес-правила проверки продаж из JSON-файла path (массив правил).
+// Если путь пустой, правил нет.
+func Load(path string) ([]domain.Rule, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read rules: %w", err)
+	}
+
+	var rules []domain.Rule
+	if err = json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("decode rules: %w", err)
+	}
+
+	return rules, nil
+}
@@ -0,0 +1,461 @@
+package storage
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+)
+
+// maxStoreCurrencies максимальное количество валют продаж одного магазина.
+const maxStoreCurrencies = math.MaxUint8 + 1
+
+var (
+	minSaleDate = time.Unix(0, math.MinInt64)
+	maxSaleDate = time.Unix(0, math.MaxInt64)
+)
+
+// storeSales продажи одного магазина в порядке поступления (временные метки не убывают).
+//
+// Продажи в памяти хранятся по колонкам: i-й элемент каждой колонки относится к i-й продаже. Строки хранятся
+// в виде идентификаторов, деньги - целыми числами с фиксированной точкой, поэтому продажа в памяти не содержит
+// указателей, и сборщик мусора не обходит миллионы объектов.
+//
+// Старые продажи вытесняются на диск целыми гранулами: продажи с индексами [0, len(evicted)*granularity)
+// хранятся в сегментах, остальные - в памяти.
+//
+// Колонки только дописываются, а при вытеснении создаются заново, поэтому копия storeSales (snapshot) видит
+// неизменные продажи, пока запись продолжается в оригинал: новые элементы добавляются за пределами длины
+// колонок копии. Изменяемые на месте поля (wide, cumulativeSums, dimensionSums) при записи копируются.
+type storeSales struct {
+	id string
+
+	// версия - количество продаж, когда-либо добавленных в магазин (включая вытесненные). Продажи только
+	// дописываются, поэтому состояние магазина в версии v - первые v продаж (см. Snapshot)
+	version int
+
+	// поколение продаж магазина: увеличивается, когда магазин пересобирается после удаления продаж
+	// (см. SalesStorage.Purge), и входит в имена файлов сегментов
+	generation int
+
+	evicted []granuleRef // гранулы, вытесненные на диск
+
+	sparseIndex []int64 // разреженный индекс временных меток продаж в unix-наносекундах (включая вытесненные)
+
+	// время последней продажи в unix-наносекундах (продажа может быть вытеснена), действительно при version > 0
+	lastTimestamp int64
+
+	timestamps []int64  // время продажи в unix-наносекундах
+	products   []uint32 // идентификатор товара в productIDs
+	quantities []int64
+	prices     []int64  // цена с priceScale знаками после запятой
+	discounts  []int64  // скидка с priceScale знаками после запятой, nil пока все скидки нулевые
+	vatRates   []int64  // ставка НДС с priceScale знаками после запятой, nil пока весь НДС нулевой
+	currencies []uint8  // индекс валюты в currencyCodes
+	receipts   []uint32 // идентификатор чека в receiptIDs плюс 1 (0 - продажа вне чека), nil пока нет продаж в чеках
+	payments   []uint32 // идентификатор способа оплаты в paymentMethods плюс 1 (0 - не задан), nil пока не задан ни один
+	channels   []uint32 // идентификатор канала продаж в salesChannels плюс 1 (0 - не задан), nil пока не задан ни один
+
+	// продажи, цена, скидка или ставка НДС которых не представимы с фиксированной точкой,
+	// по индексу продажи в памяти
+	wide map[int]*domain.Sale
+
+	productIDs     interner
+	receiptIDs     interner // чеки магазина, в том числе вытесненные на диск
+	paymentMethods interner
+	salesChannels  interner
+
+	// валюты продаж магазина, версии, в которых они появились, и кумулятивные суммы продаж по ним
+	currencyCodes  []string
+	currencySince  []int
+	cumulativeSums []cumulativeSums
+
+	// кумулятивные суммы продаж по значениям измерений (способ оплаты, канал) в каждой валюте
+	dimensionSums []dimensionSums
+
+	// сводки продаж по дням в UTC: по валютам (с приближенной статистикой) и по товарам в валютах
+	// (см. SalesStorage.addRollups)
+	dailyTotals   rollup
+	productTotals rollup
+}
+
+// dimensionSums кумулятивные суммы продаж в валюте currency со значением value измерения dimension.
+// Суммы ведутся только для заданных значений: суммы продаж без значения - разность сумм валюты
+// и сумм всех значений.
+type dimensionSums struct {
+	dimension string
+	value     string
+	currency  uint8 // индекс валюты в currencyCodes
+	since     int   // версия, в которой появились суммы
+
+	sums cumulativeSums
+}
+
+func newStoreSales(id string) *storeSales {
+	return &storeSales{
+		id:             id,
+		productIDs:     newInterner(),
+		receiptIDs:     newInterner(),
+		paymentMethods: newInterner(),
+		salesChannels:  newInterner(),
+		dailyTotals:    newRollup(rollupDay, false, true),
+		productTotals:  newRollup(rollupDay, true, false),
+	}
+}
+
+// snapshot возвращает копию продаж магазина для чтения, не меняющуюся при дальнейшей записи в оригинал.
+func (s *storeSales) snapshot() *storeSales {
+	c := *s
+	c.cumulativeSums = append([]cumulativeSums(nil), s.cumulativeSums...)
+	c.dimensionSums = append([]dimensionSums(nil), s.dimensionSums...)
+
+	return &c
+}
+
+// rollback возвращает продажи к копии prev, сделанной snapshot перед добавлением продаж (см. SalesStorage.appendSales).
+// Копия не публиковалась, а интернеры разделяют с ней map, поэтому строки, добавленные после копии, из map удаляются.
+func (s *storeSales) rollback(prev *storeSales) {
+	s.productIDs.truncate(len(prev.productIDs.values))
+	s.receiptIDs.truncate(len(prev.receiptIDs.values))
+	s.paymentMethods.truncate(len(prev.paymentMethods.values))
+	s.salesChannels.truncate(len(prev.salesChannels.values))
+
+	*s = *prev
+}
+
+// addRollups учитывает добавленную продажу с индексом i в сводках магазина и возвращает ее строку и показатели.
+func (s *storeSales) addRollups(i int) (row, queryTotals) {
+	row := s.row(i - (s.version - s.len()))
+	totals := queryTotals{amounts: row.amounts, quantity: row.quantity, sales: 1}
+
+	s.dailyTotals.add(i, &row, totals)
+	s.productTotals.add(i, &row, totals)
+
+	return row, totals
+}
+
+// len возвращает количество продаж в памяти.
+func (s *storeSales) len() int {
+	return len(s.timestamps)
+}
+
+// append добавляет продажу в колонки. Продажа не добавляется, если ее суммы не представимы с фиксированной точкой
+// или она раньше последней продажи магазина: разреженный индекс, поиск по времени и сводки рассчитаны
+// на неубывающие временные метки.
+func (s *storeSales) append(sale *domain.Sale) error {
+	if sale.SaleDate.Before(minSaleDate) || sale.SaleDate.After(maxSaleDate) {
+		return fmt.Errorf("sale date %s: %w", sale.SaleDate, domain.ErrSaleOutOfRange)
+	}
+
+	if s.version > 0 && sale.SaleDate.UnixNano() < s.lastTimestamp {
+		return fmt.Errorf("sale date %s before last sale %s: %w",
+			sale.SaleDate, time.Unix(0, s.lastTimestamp).UTC(), domain.ErrSaleOutOfRange)
+	}
+
+	amounts, ok := fixedAmountsOf(sale.Amounts())
+	if !ok {
+		return fmt.Errorf("sale amounts: %w", domain.ErrSaleOutOfRange)
+	}
+
+	currency := s.currencyIndex(sale.Currency)
+	if currency < 0 && len(s.currencyCodes) == maxStoreCurrencies {
+		return fmt.Errorf("too many currencies: %w", domain.ErrSaleOutOfRange)
+	}
+
+	if currency < 0 {
+		// первая продажа в новой валюте: до нее кумулятивная сумма в этой валюте нулевая
+		s.currencyCodes = append(s.currencyCodes, sale.Currency)
+		s.currencySince = append(s.currencySince, s.version)
+		s.cumulativeSums = append(s.cumulativeSums, newCumulativeSums(s.len()))
+		currency = len(s.currencyCodes) - 1
+	}
+
+	sums, ok := s.cumulativeSums[currency].next(amounts)
+	if !ok {
+		return fmt.Errorf("store total: %w", domain.ErrSaleOutOfRange)
+	}
+
+	// считаем кумулятивные суммы продаж для текущей продажи: в валюте продажи сумма растет,
+	// в остальных валютах переносится с предыдущей продажи
+	for i := range s.cumulativeSums {
+		c := &s.cumulativeSums[i]
+		if i == currency {
+			c.append(sums)
+		} else {
+			c.append(c.at(s.len() - 1))
+		}
+	}
+
+	// суммы измерений ведутся так же: сумма значения измерения продажи растет, остальные переносятся.
+	// Составляющие сумм не отрицательные, поэтому сумма значения не больше суммы валюты и не переполняется
+	s.addDimensionSums(sale, uint8(currency))
+
+	for i := range s.dimensionSums {
+		d := &s.dimensionSums[i]
+		if d.currency == uint8(currency) && sale.Dimension(d.dimension) == d.value {
+			sums, _ := d.sums.next(amounts)
+			d.sums.append(sums)
+		} else {
+			d.sums.append(d.sums.at(s.len() - 1))
+		}
+	}
+
+	price, ok1 := toFixed(sale.SalePrice, priceScale)
+	discount, ok2 := toFixed(sale.Discount, priceScale)
+	vatRate, ok3 := toFixed(sale.VATRate, priceScale)
+
+	if !ok1 || !ok2 || !ok3 {
+		// map разделяется со снимками, поэтому дополняется копированием (такие продажи редки)
+		wide := make(map[int]*domain.Sale, len(s.wide)+1)
+		for i, sale := range s.wide {
+			wide[i] = sale
+		}
+
+		wide[s.len()] = sale
+		s.wide = wide
+	}
+
+	s.discounts = appendSparse(s.discounts, discount, s.len())
+	s.vatRates = appendSparse(s.vatRates, vatRate, s.len())
+	s.receipts = appendSparse(s.receipts, internOptional(&s.receiptIDs, sale.ReceiptID), s.len())
+	s.payments = appendSparse(s.payments, internOptional(&s.paymentMethods, sale.PaymentMethod), s.len())
+	s.channels = appendSparse(s.channels, internOptional(&s.salesChannels, sale.Channel), s.len())
+	s.timestamps = append(s.timestamps, sale.SaleDate.UnixNano())
+	s.products = append(s.products, s.productIDs.id(sale.ProductID))
+	s.quantities = append(s.quantities, sale.QuantitySold)
+	s.prices = append(s.prices, price)
+	s.currencies = append(s.currencies, uint8(currency))
+	s.lastTimestamp = sale.SaleDate.UnixNano()
+	s.version++
+
+	return nil
+}
+
+// addDimensionSums добавляет суммы для значений измерений продажи в валюте currency, которых еще нет:
+// до продажи эти суммы нулевые.
+func (s *storeSales) addDimensionSums(sale *domain.Sale, currency uint8) {
+	for _, dimension := range domain.Dimensions() {
+		value := sale.Dimension(dimension)
+		if value == "" || s.dimensionIndex(dimension, value, currency) >= 0 {
+			continue
+		}
+
+		s.dimensionSums = append(s.dimensionSums, dimensionSums{
+			dimension: dimension,
+			value:     value,
+			currency:  currency,
+			since:     s.version,
+			sums:      newCumulativeSums(s.len()),
+		})
+	}
+}
+
+// dimensionIndex возвращает индекс сумм значения измерения в валюте в dimensionSums или -1.
+func (s *storeSales) dimensionIndex(dimension, value string, currency uint8) int {
+	for i := range s.dimensionSums {
+		d := &s.dimensionSums[i]
+		if d.dimension == dimension && d.value == value && d.currency == currency {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// currencyIndex возвращает индекс валюты в currencyCodes или -1.
+func (s *storeSales) currencyIndex(currency string) int {
+	for i, code := range s.currencyCodes {
+		if code == currency {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// sale собирает продажу в памяти с индексом i. Время продажи возвращается в UTC.
+func (s *storeSales) sale(i int) *domain.Sale {
+	if sale, ok := s.wide[i]; ok {
+		return sale
+	}
+
+	sale := &domain.Sale{
+		ProductID:    s.productIDs.value(s.products[i]),
+		StoreID:      s.id,
+		QuantitySold: s.quantities[i],
+		SalePrice:    fromFixed(s.prices[i], priceScale),
+		Discount:     decimal.Zero,
+		VATRate:      decimal.Zero,
+		Currency:     s.currencyCodes[s.currencies[i]],
+		SaleDate:     time.Unix(0, s.timestamps[i]).UTC(),
+	}
+
+	if s.discounts != nil {
+		sale.Discount = fromFixed(s.discounts[i], priceScale)
+	}
+
+	if s.vatRates != nil {
+		sale.VATRate = fromFixed(s.vatRates[i], priceScale)
+	}
+
+	sale.ReceiptID = optionalValue(&s.receiptIDs, s.receipts, i)
+	sale.PaymentMethod = optionalValue(&s.paymentMethods, s.payments, i)
+	sale.Channel = optionalValue(&s.salesChannels, s.channels, i)
+
+	return sale
+}
+
+// row возвращает строку продажи в памяти с индексом i. Суммы продажи - разность соседних кумулятивных сумм.
+func (s *storeSales) row(i int) row {
+	currency := s.currencies[i]
+	sums := &s.cumulativeSums[currency]
+
+	return row{
+		timestamp: s.timestamps[i],
+		product:   s.productIDs.value(s.products[i]),
+		currency:  s.currencyCodes[currency],
+		quantity:  s.quantities[i],
+		receipt:   optionalValue(&s.receiptIDs, s.receipts, i),
+		payment:   optionalValue(&s.paymentMethods, s.payments, i),
+		channel:   optionalValue(&s.salesChannels, s.channels, i),
+		amounts:   sums.at(i).sub(sums.at(i - 1)),
+	}
+}
+
+// trim отбрасывает первые n продаж в памяти (после их вытеснения на диск), освобождая память.
+func (s *storeSales) trim(n int) {
+	s.timestamps = append([]int64(nil), s.timestamps[n:]...)
+	s.products = append([]uint32(nil), s.products[n:]...)
+	s.quantities = append([]int64(nil), s.quantities[n:]...)
+	s.prices = append([]int64(nil), s.prices[n:]...)
+	s.currencies = append([]uint8(nil), s.currencies[n:]...)
+
+	if s.discounts != nil {
+		s.discounts = append([]int64(nil), s.discounts[n:]...)
+	}
+
+	if s.vatRates != nil {
+		s.vatRates = append([]int64(nil), s.vatRates[n:]...)
+	}
+
+	if s.receipts != nil {
+		s.receipts = append([]uint32(nil), s.receipts[n:]...)
+	}
+
+	if s.payments != nil {
+		s.payments = append([]uint32(nil), s.payments[n:]...)
+	}
+
+	if s.channels != nil {
+		s.channels = append([]uint32(nil), s.channels[n:]...)
+	}
+
+	if s.wide != nil {
+		wide := make(map[int]*domain.Sale)
+		for i, sale := range s.wide {
+			if i >= n {
+				wide[i-n] = sale
+			}
+		}
+
+		s.wide = wide
+	}
+
+	for i := range s.cumulativeSums {
+		s.cumulativeSums[i].trim(n)
+	}
+
+	for i := range s.dimensionSums {
+		s.dimensionSums[i].sums.trim(n)
+	}
+}
+
+// row строка продажи, достаточная для агрегации.
+type row struct {
+	timestamp int64
+	product   string
+	currency  string
+	quantity  int64
+	receipt   string // идентификатор чека, пустой у продажи вне чека
+	payment   string
+	channel   string
+	amounts   fixedAmounts
+}
+
+// dimension возвращает значение измерения продажи.
+func (r *row) dimension(dimension string) string {
+	switch dimension {
+	case domain.DimensionPaymentMethod:
+		return r.payment
+	case domain.DimensionChannel:
+		return r.channel
+	default:
+		return ""
+	}
+}
+
+// rowOf возвращает строку продажи, прочитанной с диска.
+func rowOf(sale *domain.Sale) (row, error) {
+	amounts, ok := fixedAmountsOf(sale.Amounts())
+	if !ok {
+		return row{}, errCorruptedSegment
+	}
+
+	return row{
+		timestamp: sale.SaleDate.UnixNano(),
+		product:   sale.ProductID,
+		currency:  sale.Currency,
+		quantity:  sale.QuantitySold,
+		receipt:   sale.ReceiptID,
+		payment:   sale.PaymentMethod,
+		channel:   sale.Channel,
+		amounts:   amounts,
+	}, nil
+}
+
+// appendSparse добавляет значение в колонку, которая создается при первом ненулевом значении.
+func appendSparse[T int64 | uint32](column []T, v T, n int) []T {
+	if column == nil && v == 0 {
+		return nil
+	}
+
+	if column == nil {
+		column = make([]T, n, n+1)
+	}
+
+	return append(column, v)
+}
+
+// internOptional возвращает идентификатор необязательной строки для разреженной колонки: 0 - пустая строка,
+// иначе идентификатор в интернере плюс 1.
+func internOptional(in *interner, s string) uint32 {
+	if s == "" {
+		return 0
+	}
+
+	return in.id(s) + 1
+}
+
+// optionalValue возвращает необязательную строку i-й продажи из разреженной колонки, заполненной internOptional.
+func optionalValue(in *interner, column []uint32, i int) string {
+	if column == nil || column[i] == 0 {
+		return ""
+	}
+
+	return in.value(column[i] - 1)
+}
+
+// unixNano возвращает время в unix-наносекундах, ограничивая его диапазоном, представимым в int64.
+func unixNano(t time.Time) int64 {
+	switch {
+	case t.Before(minSaleDate):
+		return math.MinInt64
+	case t.After(maxSaleDate):
+		return math.MaxInt64
+	default:
+		return t.UnixNano()
+	}
+}
@@ -0,0 +1,171 @@
+package storage
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+	"go.dataflow.ru/service-sales/pkg/logger"
+)
+
+// TestSalesStorage_Concurrency стресс-тест для запуска с детектором гонок (go test -race):
+// параллельная запись в несколько магазинов, чтение, запросы агрегатов по сводкам и вытеснение на диск.
+func TestSalesStorage_Concurrency(t *testing.T) {
+	t.Parallel()
+
+	const (
+		stores         = 4
+		salesPerWriter = 2000
+		readers        = 4
+	)
+
+	s := New(logger.NoOpLogger(), WithIndexGranularity(16), WithRetention(time.Hour, t.TempDir()))
+
+	dt := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	end := dt.Add(salesPerWriter * time.Minute)
+
+	var writers sync.WaitGroup
+
+	for w := 0; w < stores; w++ {
+		writers.Add(1)
+
+		go func(storeID string) {
+			defer writers.Done()
+
+			for i := 0; i < salesPerWriter; i++ {
+				assert.NoError(t, s.AddSale(&domain.Sale{
+					StoreID:      storeID,
+					ProductID:    fmt.Sprintf("product_%d", i%7),
+					QuantitySold: 1,
+					SalePrice:    decimal.NewFromInt(1),
+					Currency:     "RUB",
+					SaleDate:     dt.Add(time.Duration(i) * time.Minute),
+				}))
+			}
+		}(fmt.Sprintf("store_%d", w))
+	}
+
+	done := make(chan struct{})
+
+	var others sync.WaitGroup
+
+	for r := 0; r < readers; r++ {
+		others.Add(1)
+
+		go func(storeID string) {
+			defer others.Done()
+
+			// снимки только растут: сумма продаж магазина не убывает от запроса к запросу
+			prev := decimal.Zero
+
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+
+				totals, err := s.GetTotalSum(storeID, dt, end)
+				assert.NoError(t, err)
+
+				gross := totals["RUB"].Gross
+				assert.True(t, gross.GreaterThanOrEqual(prev), "%s < %s", gross, prev)
+				prev = gross
+
+				_, err = s.GetTotalSumByProduct(storeID, dt.Add(time.Hour), dt.Add(2*time.Hour))
+				assert.NoError(t, err)
+
+				_, err = s.Query(domain.Query{
+					StartDate: dt,
+					EndDate:   end,
+					GroupBy:   []string{domain.FieldProduct},
+					Metrics:   []string{domain.MetricSales},
+					Bucket:    domain.BucketDay,
+				})
+				assert.NoError(t, err)
+
+				_, err = s.GetSketches(storeID, dt, end)
+				assert.NoError(t, err)
+			}
+		}(fmt.Sprintf("store_%d", r%stores))
+	}
+
+	others.Add(1)
+
+	go func() {
+		defer others.Done()
+
+		for now := dt.Add(2 * time.Hour); ; now = now.Add(time.Hour) {
+			select {
+			case <-done:
+				return
+			default:
+			}
+
+			assert.NoError(t, s.Evict(now))
+
+			_, err := s.GetSales()
+			assert.NoError(t, err)
+		}
+	}()
+
+	writers.Wait()
+	close(done)
+	others.Wait()
+
+	for w := 0; w < stores; w++ {
+		totals, err := s.GetTotalSum(fmt.Sprintf("store_%d", w), dt, end)
+		assert.NoError(t, err)
+		assert.Equal(t, fmt.Sprint(salesPerWriter), totals["RUB"].Gross.String())
+	}
+
+	sales, err := s.GetSales()
+	assert.NoError(t, err)
+	assert.Len(t, sales, stores*salesPerWriter)
+
+	queryRes, err := s.Query(domain.Query{StartDate: dt, EndDate: end, Metrics: []string{domain.MetricSales}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{fmt.Sprintf("map[currency:RUB] map[sales:%d]", stores*salesPerWriter)}, queryRows(queryRes.Rows))
+}
+
+// TestSalesStorage_ReadersDoNotBlock проверяет, что чтение и запись в другие магазины
+// не ждут блокировки магазина, в который идет запись.
+func TestSalesStorage_ReadersDoNotBlock(t *testing.T) {
+	t.Parallel()
+
+	s := New(logger.NoOpLogger())
+
+	dt := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	sale := func(storeID string) *domain.Sale {
+		return &domain.Sale{
+			StoreID:      storeID,
+			ProductID:    "product_1",
+			QuantitySold: 1,
+			SalePrice:    decimal.NewFromInt(10),
+			Currency:     "RUB",
+			SaleDate:     dt,
+		}
+	}
+
+	assert.NoError(t, s.AddSale(sale("store_1")))
+
+	// запись в store_1 "зависла" с захваченной блокировкой магазина
+	st := s.store("store_1")
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	totals, err := s.GetTotalSum("store_1", dt, dt)
+	assert.NoError(t, err)
+	assert.Equal(t, "10", totals["RUB"].Gross.String())
+
+	assert.NoError(t, s.AddSale(sale("store_2")))
+
+	totals, err = s.GetTotalSum("store_2", dt, dt)
+	assert.NoError(t, err)
+	assert.Equal(t, "10", totals["RUB"].Gross.String())
+}
@@ -0,0 +1,96 @@
+package storage
+
+// cumulativeSums кумулятивные составляющие продаж в одной валюте в копейках. Все массивы выровнены по продажам
+// магазина, хранящимся в памяти: i-й элемент - сумма со всех продаж магазина (в том числе вытесненных на диск)
+// по i-ю продажу в памяти включительно.
+// Скидки и НДС у многих магазинов не используются, поэтому их массивы создаются при первой ненулевой сумме.
+// Чистая выручка не хранится, а вычисляется при чтении.
+type cumulativeSums struct {
+	gross    []int64
+	discount []int64 // nil, пока все скидки нулевые
+	tax      []int64 // nil, пока весь НДС нулевой
+
+	base fixedAmounts // сумма вытесненных продаж, предшествующих продажам в памяти
+}
+
+// newCumulativeSums возвращает кумулятивные суммы для валюты, впервые встретившейся на продаже с индексом n.
+func newCumulativeSums(n int) cumulativeSums {
+	return cumulativeSums{gross: make([]int64, n, n+1)}
+}
+
+// next возвращает кумулятивные суммы после продажи с суммами amounts; false - при переполнении.
+func (c *cumulativeSums) next(amounts fixedAmounts) (fixedAmounts, bool) {
+	last := c.at(len(c.gross) - 1)
+
+	gross, ok1 := addChecked(last.gross, amounts.gross)
+	discount, ok2 := addChecked(last.discount, amounts.discount)
+	tax, ok3 := addChecked(last.tax, amounts.tax)
+
+	return fixedAmounts{gross: gross, discount: discount, tax: tax}, ok1 && ok2 && ok3
+}
+
+// append добавляет кумулятивные суммы для следующей продажи, вычисленные next.
+func (c *cumulativeSums) append(sums fixedAmounts) {
+	c.gross = append(c.gross, sums.gross)
+
+	if c.discount != nil || sums.discount != c.base.discount {
+		if c.discount == nil {
+			c.discount = fill(len(c.gross)-1, c.base.discount)
+		}
+
+		c.discount = append(c.discount, sums.discount)
+	}
+
+	if c.tax != nil || sums.tax != c.base.tax {
+		if c.tax == nil {
+			c.tax = fill(len(c.gross)-1, c.base.tax)
+		}
+
+		c.tax = append(c.tax, sums.tax)
+	}
+}
+
+// at возвращает кумулятивные суммы на продажу в памяти с индексом i (i = -1 - до первой продажи в памяти).
+func (c *cumulativeSums) at(i int) fixedAmounts {
+	if i < 0 {
+		return c.base
+	}
+
+	a := fixedAmounts{gross: c.gross[i], discount: c.base.discount, tax: c.base.tax}
+
+	if c.discount != nil {
+		a.discount = c.discount[i]
+	}
+
+	if c.tax != nil {
+		a.tax = c.tax[i]
+	}
+
+	return a
+}
+
+// trim отбрасывает кумулятивные суммы первых n продаж, освобождая память.
+func (c *cumulativeSums) trim(n int) {
+	c.base = c.at(n - 1)
+
+	c.gross = append([]int64(nil), c.gross[n:]...)
+
+	if c.discount != nil {
+		c.discount = append([]int64(nil), c.discount[n:]...)
+	}
+
+	if c.tax != nil {
+		c.tax = append([]int64(nil), c.tax[n:]...)
+	}
+}
+
+func fill(n int, v int64) []int64 {
+	res := make([]int64, n, n+1)
+	if v != 0 {
+		for i := range res {
+			res[i] = v
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"math"
+	"math/bits"
+
+	"github.com/shopspring/decimal"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+)
+
+const (
+	// priceScale количество знаков после запятой в цене, скидке и ставке НДС продажи в памяти.
+	// Продажи с более точными значениями хранятся целиком (см. storeSales.wide).
+	priceScale = 4
+
+	// amountScale количество знаков после запятой в денежных суммах (суммы строк уже округлены до этой точности).
+	amountScale = domain.AmountPrecision
+)
+
+// toFixed переводит десятичное число в число с фиксированной точкой с scale знаками после запятой.
+// Возвращает false, если число не представимо точно.
+func toFixed(d decimal.Decimal, scale int32) (int64, bool) {
+	if d.Exponent() >= -scale && d.Exponent() <= 0 && d.Coefficient().IsInt64() {
+		// частый случай: коэффициент помещается в int64, остается домножить на степень 10
+		return mulPow10(d.Coefficient().Int64(), scale+d.Exponent())
+	}
+
+	shifted := d.Shift(scale)
+	if !shifted.IsInteger() {
+		return 0, false
+	}
+
+	v := shifted.BigInt()
+	if !v.IsInt64() {
+		return 0, false
+	}
+
+	return v.Int64(), true
+}
+
+// fromFixed переводит число с фиксированной точкой в десятичное.
+func fromFixed(v int64, scale int32) decimal.Decimal {
+	if v == 0 {
+		return decimal.Zero
+	}
+
+	return decimal.New(v, -scale)
+}
+
+func mulPow10(v int64, n int32) (int64, bool) {
+	for ; n > 0; n-- {
+		hi, lo := bits.Mul64(uint64(abs(v)), 10)
+		if hi != 0 || lo > math.MaxInt64 {
+			return 0, false
+		}
+
+		if v < 0 {
+			v = -int64(lo)
+		} else {
+			v = int64(lo)
+		}
+	}
+
+	return v, true
+}
+
+func abs(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+
+	return v
+}
+
+// addChecked складывает числа, сообщая о переполнении.
+func addChecked(a, b int64) (int64, bool) {
+	c := a + b
+	if (c > a) != (b > 0) {
+		return 0, false
+	}
+
+	return c, true
+}
+
+// fixedAmounts денежные составляющие продажи в копейках (единицах amountScale). Чистая выручка вычисляется.
+type fixedAmounts struct {
+	gross    int64
+	discount int64
+	tax      int64
+}
+
+// fixedAmountsOf переводит денежные составляющие продажи в копейки.
+func fixedAmountsOf(a domain.Amounts) (fixedAmounts, bool) {
+	gross, ok1 := toFixed(a.Gross, amountScale)
+	discount, ok2 := toFixed(a.Discount, amountScale)
+	tax, ok3 := toFixed(a.Tax, amountScale)
+
+	return fixedAmounts{gross: gross, discount: discount, tax: tax}, ok1 && ok2 && ok3
+}
+
+func (a fixedAmounts) add(b fixedAmounts) fixedAmounts {
+	return fixedAmounts{gross: a.gross + b.gross, discount: a.discount + b.discount, tax: a.tax + b.tax}
+}
+
+func (a fixedAmounts) sub(b fixedAmounts) fixedAmounts {
+	return fixedAmounts{gross: a.gross - b.gross, discount: a.discount - b.discount, tax: a.tax - b.tax}
+}
+
+// amounts переводит суммы в десятичные.
+func (a fixedAmounts) amounts() domain.Amounts {
+	return domain.Amounts{
+		Gross:    fromFixed(a.gross, amountScale),
+		Discount: fromFixed(a.discount, amountScale),
+		Tax:      fromFixed(a.tax, amountScale),
+		Net:      fromFixed(a.gross-a.discount-a.tax, amountScale),
+	}
+}
+
+// fixedTotals суммы продаж в копейках в разрезе валют.
+type fixedTotals map[string]fixedAmounts
+
+func (t fixedTotals) add(currency string, a fixedAmounts) {
+	t[currency] = t[currency].add(a)
+}
+
+// totals переводит суммы в десятичные.
+func (t fixedTotals) totals() domain.Totals {
+	res := make(domain.Totals, len(t))
+	for currency, a := range t {
+		res[currency] = a.amounts()
+	}
+
+	return res
+}
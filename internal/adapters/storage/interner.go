@@ -0,0 +1,48 @@
+package storage
+
+// interner присваивает строкам (идентификаторам товаров) компактные числовые идентификаторы,
+// чтобы каждая строка хранилась в памяти один раз. Не потокобезопасен, но строки только дописываются,
+// поэтому копия интернера позволяет читать уже выданные идентификаторы, пока в оригинал добавляются новые.
+type interner struct {
+	ids    map[string]uint32
+	values []string
+}
+
+func newInterner() interner {
+	return interner{ids: make(map[string]uint32)}
+}
+
+// id возвращает идентификатор строки, добавляя ее при первом обращении.
+func (in *interner) id(s string) uint32 {
+	if id, ok := in.ids[s]; ok {
+		return id
+	}
+
+	id := uint32(len(in.values))
+	in.ids[s] = id
+	in.values = append(in.values, s)
+
+	return id
+}
+
+// value возвращает строку по идентификатору.
+func (in *interner) value(id uint32) string {
+	return in.values[id]
+}
+
+// contains сообщает, что строка уже добавлена.
+func (in *interner) contains(s string) bool {
+	_, ok := in.ids[s]
+
+	return ok
+}
+
+// truncate оставляет первые n строк. Используется только для отката строк, идентификаторы которых
+// еще не опубликованы для чтения.
+func (in *interner) truncate(n int) {
+	for _, s := range in.values[n:] {
+		delete(in.ids, s)
+	}
+
+	in.values = in.values[:n]
+}
@@ -1,5 +1,7 @@
 package storage
 
+import "time"
+
 type Option func(s *SalesStorage)
 
 func WithIndexGranularity(size int64) Option {
@@ -7,3 +9,19 @@ func WithIndexGranularity(size int64) Option {
 		s.indexGranularity = size
 	}
 }
+
+// WithRetention включает вытеснение продаж старше horizon в сегменты на диске в каталоге dir.
+func WithRetention(horizon time.Duration, dir string) Option {
+	return func(s *SalesStorage) {
+		s.retention = horizon
+		s.segmentsDir = dir
+	}
+}
+
+// WithSnapshots задает срок действия снимков продаж и максимальное количество одновременно открытых снимков.
+func WithSnapshots(ttl time.Duration, limit int) Option {
+	return func(s *SalesStorage) {
+		s.snapshotTTL = ttl
+		s.maxSnapshots = limit
+	}
+}
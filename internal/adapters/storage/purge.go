@@ -0,0 +1,169 @@
+package storage
+
+import (
+	"fmt"
+	"math"
+	"os"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+)
+
+// Purge удаляет продажи магазина purge.StoreID (всех магазинов, если он не задан), совершенные раньше
+// purge.Before (все продажи магазина, если время не задано), и возвращает количество удаленных продаж.
+//
+// Продажи, кумулятивные суммы, разреженный индекс и сводки магазина строятся заново по оставшимся продажам,
+// как если бы магазин принял только их, а удаленные продажи вычитаются из сводки сети. Поэтому версии
+// магазина начинаются заново в новом поколении журнала: открытые снимки закрываются, а ведомые экземпляры,
+// увидев новое поколение, перечитывают журнал магазина с начала (см. services.ReplicationService).
+// Файлы сегментов с удаленными продажами удаляются,
+// оставшиеся продажи старше горизонта хранения снова вытесняются на диск.
+func (s *SalesStorage) Purge(purge domain.Purge) (int, error) {
+	if err := purge.Validate(); err != nil {
+		return 0, err
+	}
+
+	before := int64(math.MaxInt64)
+	if purge.Before != nil {
+		before = unixNano(*purge.Before)
+	}
+
+	// вытеснение не выполняется одновременно с пересборкой: оно читает снимок магазина без блокировки
+	s.evictMu.Lock()
+	defer s.evictMu.Unlock()
+
+	stores := *s.stores.Load()
+	if purge.StoreID != "" {
+		st, ok := stores[purge.StoreID]
+		if !ok {
+			return 0, nil
+		}
+
+		stores = map[string]*store{purge.StoreID: st}
+	}
+
+	deleted := 0
+
+	for id, st := range stores {
+		n, err := s.purgeStore(st, before)
+		if n > 0 {
+			deleted += n
+
+			// версии снимков относятся к продажам до пересборки
+			s.closeSnapshots()
+		}
+
+		if err != nil {
+			return deleted, fmt.Errorf("purge sales of store %s: %w", id, err)
+		}
+	}
+
+	return deleted, nil
+}
+
+// purgeStore пересобирает магазин без продаж раньше before и возвращает количество удаленных продаж.
+// Вызывается под блокировкой вытеснения. Если магазин пересобран, ошибка означает, что не удалось удалить
+// файлы старых сегментов.
+func (s *SalesStorage) purgeStore(st *store, before int64) (int, error) {
+	st.mu.Lock()
+
+	old := st.sales
+	r := s.reader(old, -1)
+
+	// временные метки продаж магазина не убывают, поэтому удаляемые продажи - первые keep
+	keep, err := r.lowerBound(before)
+	if err != nil || keep == 0 {
+		st.mu.Unlock()
+		return 0, err
+	}
+
+	rebuilt, purged, err := s.rebuild(r, keep)
+	if err != nil {
+		st.mu.Unlock()
+		return 0, err
+	}
+
+	st.sales = rebuilt
+	st.publish()
+	s.chain.subtract(purged)
+
+	st.mu.Unlock()
+
+	s.logger.Infof("purged %d sales of store %s", keep, old.id)
+
+	return keep, s.replaceSegments(st, old)
+}
+
+// replaceSegments удаляет файлы сегментов продаж old, замененных пересобранными продажами магазина st,
+// и снова вытесняет на диск продажи старше горизонта хранения. Вызывается под блокировкой вытеснения.
+func (s *SalesStorage) replaceSegments(st *store, old *storeSales) error {
+	// чтение, начатое до пересборки, может не найти удаленный файл и вернуть ошибку
+	removed := make(map[string]bool)
+	for _, ref := range old.evicted {
+		if removed[ref.path] {
+			continue
+		}
+
+		removed[ref.path] = true
+
+		if err := os.Remove(ref.path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("remove segment: %w", err)
+		}
+	}
+
+	if s.retention > 0 {
+		if err := s.evictStore(st, s.now().Add(-s.retention)); err != nil {
+			s.logger.Errorf("cant evict sales of store %s after rebuild: %v", old.id, err)
+		}
+	}
+
+	return nil
+}
+
+// rebuild возвращает продажи магазина, построенные заново из продаж reader начиная с индекса keep,
+// и сводку сети по продажам до keep. Вызывается под блокировкой магазина.
+func (s *SalesStorage) rebuild(r *reader, keep int) (*storeSales, *chainRollup, error) {
+	purged := &chainRollup{}
+
+	err := r.scan(0, keep, func(row row) {
+		purged.add(row.timestamp, row.currency, queryTotals{amounts: row.amounts, quantity: row.quantity, sales: 1})
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sales, err := r.salesRange(keep, r.count)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rebuilt, err := s.rebuildSales(r.store, sales)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return rebuilt, purged, nil
+}
+
+// rebuildSales возвращает продажи магазина old следующего поколения, построенные заново из продаж sales.
+func (s *SalesStorage) rebuildSales(old *storeSales, sales []*domain.Sale) (*storeSales, error) {
+	rebuilt := newStoreSales(old.id)
+	rebuilt.generation = old.generation + 1
+
+	for i, sale := range sales {
+		if err := s.appendSale(rebuilt, sale); err != nil {
+			return nil, fmt.Errorf("rebuild sale %d: %w", i, err)
+		}
+
+		rebuilt.addRollups(i)
+	}
+
+	return rebuilt, nil
+}
+
+// closeSnapshots закрывает все открытые снимки.
+func (s *SalesStorage) closeSnapshots() {
+	s.snapshotsMu.Lock()
+	defer s.snapshotsMu.Unlock()
+
+	s.snapshots = make(map[string]*Snapshot)
+}
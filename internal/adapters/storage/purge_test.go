@@ -0,0 +1,323 @@
+package storage
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+	"go.dataflow.ru/service-sales/pkg/logger"
+)
+
+func TestSalesStorage_Purge(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	s := New(logger.NoOpLogger(), WithIndexGranularity(3), WithRetention(10*24*time.Hour, dir))
+	s.now = func() time.Time { return now }
+
+	dt := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	addSales := func(storeID string, from, to int) {
+		for i := from; i < to; i++ {
+			currency := "RUB"
+			if i%4 == 0 {
+				currency = "KZT"
+			}
+
+			require.NoError(t, s.AddSale(&domain.Sale{
+				StoreID:       storeID,
+				ProductID:     fmt.Sprintf("product_%d", i%3),
+				QuantitySold:  1,
+				SalePrice:     decimal.NewFromInt(int64(i + 1)),
+				Discount:      decimal.NewFromInt(int64(i % 2)),
+				Currency:      currency,
+				PaymentMethod: []string{"card", "cash"}[i%2],
+				SaleDate:      dt.AddDate(0, 0, i),
+			}))
+		}
+	}
+
+	addSales("store_1", 0, 20)
+	addSales("store_2", 0, 10)
+	require.NoError(t, s.Evict(now))
+	require.NotEmpty(t, s.view("store_1").evicted)
+
+	snapshot, err := s.OpenSnapshot()
+	require.NoError(t, err)
+
+	// удаление без магазина и времени не выполняется
+	_, err = s.Purge(domain.Purge{})
+	assert.ErrorIs(t, err, domain.ErrInvalidPurge)
+
+	// продажи store_1 раньше 13 мая: первые 12
+	before := dt.AddDate(0, 0, 12)
+
+	deleted, err := s.Purge(domain.Purge{StoreID: "store_1", Before: &before})
+	require.NoError(t, err)
+	assert.Equal(t, 12, deleted)
+
+	versions, err := s.Versions()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]domain.LogVersion{
+		"store_1": {Generation: 1, Version: 8},
+		"store_2": {Generation: 0, Version: 10},
+	}, versions)
+
+	// журнал прежнего поколения больше не читается
+	_, err = s.SalesSince("store_1", 0, 0, 100)
+	assert.ErrorIs(t, err, domain.ErrLogGenerationChanged)
+
+	// снимки закрыты: их версии относятся к продажам до удаления
+	_, err = s.Snapshot(snapshot.Token)
+	assert.ErrorIs(t, err, domain.ErrSnapshotNotFound)
+
+	// магазин продолжает принимать продажи
+	addSales("store_1", 20, 25)
+
+	sales, err := s.SalesSince("store_1", 1, 0, 100)
+	require.NoError(t, err)
+	require.Len(t, sales, 13)
+	assert.Equal(t, before, sales[0].SaleDate)
+
+	// суммы по кумулятивным суммам, индексу и сводкам совпадают с перебором
+	periods := [][2]time.Time{
+		{dt, dt.AddDate(0, 1, 0)},
+		{dt, before},
+		{before.Add(time.Hour), dt.AddDate(0, 0, 17)},
+		{dt.AddDate(0, 0, 14), dt.AddDate(0, 0, 23)},
+	}
+
+	for _, p := range periods {
+		totals, err := s.GetTotalSum("store_1", p[0], p[1])
+		require.NoError(t, err)
+
+		simple, err := s.GetTotalSumSimple("store_1", p[0], p[1])
+		require.NoError(t, err)
+
+		assert.Equal(t, simple, totals, "period %s - %s", p[0], p[1])
+	}
+
+	byDimension, err := s.GetTotalSumByDimension("store_1", domain.DimensionPaymentMethod, dt, dt.AddDate(0, 1, 0))
+	require.NoError(t, err)
+	// оплата картой - четные продажи 12..24
+	assert.Equal(t, map[string]string{"KZT": "76", "RUB": "57"}, gross(byDimension["card"]))
+
+	// сводка сети не содержит удаленных продаж: сумма продаж сети совпадает с суммой по магазинам
+	res, err := s.Query(domain.Query{
+		StartDate: dt,
+		EndDate:   dt.AddDate(0, 1, 0).Add(-time.Nanosecond),
+		Metrics:   []string{domain.MetricGross, domain.MetricSales},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, planRollup, res.Plan)
+
+	chain := make(map[string]string)
+	for _, row := range res.Rows {
+		chain[row.Group[domain.FieldCurrency]] = row.Metrics[domain.MetricGross].String() + "/" +
+			row.Metrics[domain.MetricSales].String()
+	}
+
+	// store_1: продажи 12..24 на 13..25, store_2: продажи 0..9 на 1..10; в KZT - каждая четвертая продажа
+	assert.Equal(t, map[string]string{"RUB": "211/16", "KZT": "91/7"}, chain)
+
+	// сегменты прежнего поколения удалены, оставшиеся старые продажи снова вытеснены
+	files, err := filepath.Glob(filepath.Join(dir, "store_1", "*.seg"))
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+
+	for _, file := range files {
+		assert.Contains(t, filepath.Base(file), "1-")
+	}
+
+	// все продажи магазина
+	deleted, err = s.Purge(domain.Purge{StoreID: "store_2"})
+	require.NoError(t, err)
+	assert.Equal(t, 10, deleted)
+
+	all, err := s.GetSales()
+	require.NoError(t, err)
+	assert.Len(t, all, 13)
+
+	files, err = filepath.Glob(filepath.Join(dir, "store_2", "*"))
+	require.NoError(t, err)
+	assert.Empty(t, files)
+
+	// магазина нет - удалять нечего
+	deleted, err = s.Purge(domain.Purge{StoreID: "store_3"})
+	require.NoError(t, err)
+	assert.Zero(t, deleted)
+}
+
+func TestSalesStorage_PurgeBefore(t *testing.T) {
+	t.Parallel()
+
+	s := New(logger.NoOpLogger(), WithIndexGranularity(2))
+
+	dt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	for _, storeID := range []string{"store_1", "store_2", "store_3"} {
+		for i := 0; i < 5; i++ {
+			require.NoError(t, s.AddSale(&domain.Sale{
+				StoreID:      storeID,
+				ProductID:    "product_1",
+				QuantitySold: 1,
+				SalePrice:    decimal.NewFromInt(10),
+				Currency:     "RUB",
+				SaleDate:     dt.AddDate(0, 0, i),
+			}))
+		}
+	}
+
+	// все магазины: продажи раньше 3 мая, граница не включается
+	before := dt.AddDate(0, 0, 2)
+
+	deleted, err := s.Purge(domain.Purge{Before: &before})
+	require.NoError(t, err)
+	assert.Equal(t, 6, deleted)
+
+	sales, err := s.GetSales()
+	require.NoError(t, err)
+	require.Len(t, sales, 9)
+
+	dates := make([]string, 0, len(sales))
+	for _, sale := range sales {
+		assert.False(t, sale.SaleDate.Before(before))
+		dates = append(dates, sale.StoreID+" "+sale.SaleDate.Format("01-02"))
+	}
+
+	sort.Strings(dates)
+	assert.Equal(t, "store_1 05-03", dates[0])
+
+	// повторное удаление ничего не меняет
+	deleted, err = s.Purge(domain.Purge{Before: &before})
+	require.NoError(t, err)
+	assert.Zero(t, deleted)
+}
+
+func TestSalesStorage_InsertSale(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	s := New(logger.NoOpLogger(), WithIndexGranularity(3), WithRetention(10*24*time.Hour, dir))
+	s.now = func() time.Time { return now }
+
+	dt := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 20; i++ {
+		require.NoError(t, s.AddSale(&domain.Sale{
+			StoreID:      "store_1",
+			ProductID:    fmt.Sprintf("product_%d", i%3),
+			QuantitySold: 1,
+			SalePrice:    decimal.NewFromInt(int64(i + 1)),
+			Currency:     "RUB",
+			SaleDate:     dt.AddDate(0, 0, i),
+		}))
+	}
+
+	require.NoError(t, s.Evict(now))
+	require.NotEmpty(t, s.view("store_1").evicted)
+
+	snapshot, err := s.OpenSnapshot()
+	require.NoError(t, err)
+
+	// продажа 6 мая из карантина раньше последней продажи: AddSale ее отклоняет, InsertSale пересобирает магазин
+	backdated := &domain.Sale{
+		StoreID:       "store_1",
+		ProductID:     "product_9",
+		QuantitySold:  1,
+		SalePrice:     decimal.NewFromInt(1000),
+		Currency:      "RUB",
+		PaymentMethod: domain.PaymentCard,
+		SaleDate:      dt.AddDate(0, 0, 5).Add(time.Hour),
+	}
+
+	assert.ErrorIs(t, s.AddSale(backdated), domain.ErrSaleOutOfRange)
+	require.NoError(t, s.InsertSale(backdated))
+
+	versions, err := s.Versions()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]domain.LogVersion{"store_1": {Generation: 1, Version: 21}}, versions)
+
+	_, err = s.Snapshot(snapshot.Token)
+	assert.ErrorIs(t, err, domain.ErrSnapshotNotFound)
+
+	sales, err := s.GetSales()
+	require.NoError(t, err)
+	require.Len(t, sales, 21)
+	assert.Equal(t, "product_9", sales[6].ProductID)
+	assert.True(t, sort.SliceIsSorted(sales, func(i, j int) bool { return sales[i].SaleDate.Before(sales[j].SaleDate) }))
+
+	// продажа не раньше последней дописывается без пересборки
+	require.NoError(t, s.InsertSale(&domain.Sale{
+		StoreID:      "store_1",
+		ProductID:    "product_0",
+		QuantitySold: 1,
+		SalePrice:    decimal.NewFromInt(100),
+		Currency:     "RUB",
+		SaleDate:     dt.AddDate(0, 0, 20),
+	}))
+
+	versions, err = s.Versions()
+	require.NoError(t, err)
+	assert.Equal(t, domain.LogVersion{Generation: 1, Version: 22}, versions["store_1"])
+
+	// суммы по кумулятивным суммам, индексу и сводкам учитывают вставленную продажу и совпадают с перебором
+	periods := [][2]time.Time{
+		{dt, dt.AddDate(0, 1, 0)},
+		{dt.AddDate(0, 0, 5), dt.AddDate(0, 0, 6).Add(-time.Nanosecond)},
+		{dt.AddDate(0, 0, 3), dt.AddDate(0, 0, 17)},
+	}
+
+	for _, p := range periods {
+		totals, err := s.GetTotalSum("store_1", p[0], p[1])
+		require.NoError(t, err)
+
+		simple, err := s.GetTotalSumSimple("store_1", p[0], p[1])
+		require.NoError(t, err)
+
+		assert.Equal(t, simple, totals, "period %s - %s", p[0], p[1])
+	}
+
+	totals, err := s.GetTotalSum("store_1", dt, dt.AddDate(0, 1, 0))
+	require.NoError(t, err)
+	assert.Equal(t, "1310", totals["RUB"].Gross.String()) // 1 + ... + 20, 1000 и 100
+
+	byDimension, err := s.GetTotalSumByDimension("store_1", domain.DimensionPaymentMethod, dt, dt.AddDate(0, 1, 0))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"RUB": "1000"}, gross(byDimension[domain.PaymentCard]))
+
+	// сводки магазина по дням и сводка сети по часам учитывают вставленную продажу
+	for _, filters := range []map[string][]string{nil, {domain.FieldStore: {"store_1"}}} {
+		res, err := s.Query(domain.Query{
+			StartDate: dt,
+			EndDate:   dt.AddDate(0, 1, 0).Add(-time.Nanosecond),
+			Filters:   filters,
+			Metrics:   []string{domain.MetricGross, domain.MetricSales},
+		})
+		require.NoError(t, err)
+		require.Len(t, res.Rows, 1)
+		assert.Equal(t, planRollup, res.Plan)
+		assert.Equal(t, "1310", res.Rows[0].Metrics[domain.MetricGross].String())
+		assert.Equal(t, "22", res.Rows[0].Metrics[domain.MetricSales].String())
+	}
+
+	// сегменты прежнего поколения удалены
+	files, err := filepath.Glob(filepath.Join(dir, "store_1", "*.seg"))
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+
+	for _, file := range files {
+		assert.Contains(t, filepath.Base(file), "1-")
+	}
+}
@@ -0,0 +1,526 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+)
+
+// Способы выполнения запроса агрегатов.
+const (
+	planRollup     = "rollup"     // сводки продаж за целые интервалы, остальные продажи - как в edges
+	planCumulative = "cumulative" // разности кумулятивных сумм валют и измерений на границах интервалов
+	planScan       = "scan"       // перебор продаж периода
+)
+
+// Сводки продаж, которыми выполняется план rollup.
+const (
+	rollupStoreDaily   = "store_day"   // дневные суммы магазина (storeSales.dailyTotals)
+	rollupProductDaily = "product_day" // дневные суммы товаров магазина (storeSales.productTotals)
+	rollupChainHourly  = "chain_hour"  // часовые суммы сети (SalesStorage.chain)
+)
+
+// maxQueryRows максимальное количество строк результата запроса.
+const maxQueryRows = 100000
+
+// queryPlan план выполнения запроса.
+type queryPlan struct {
+	method string
+
+	// измерение, суммы которого используются планом cumulative (пустое - только суммы валют)
+	dimension string
+
+	// сводка плана rollup и способ выполнения для продаж, не покрытых целыми интервалами сводки
+	// (cumulative без измерения или scan)
+	rollup string
+	edges  string
+}
+
+// planQuery выбирает способ выполнения запроса. chain - можно использовать сводку сети (запрос не в снимке).
+//
+// Сводки хранят все показатели по магазину, товару и валюте, поэтому запросы без измерений выполняются
+// сводками: часовой сводкой сети, если не нужны магазины и товары, иначе дневной сводкой магазинов или товаров.
+// Продажи на краях периода, не покрытых целыми интервалами сводки, считаются по кумулятивным суммам, если
+// нужны только денежные показатели без товаров, иначе перебором.
+//
+// Кумулятивные суммы хранятся по валютам и по значениям каждого измерения в валюте, поэтому по ним считаются
+// денежные показатели с группировкой и фильтрами по магазину, валюте и не более чем одному измерению.
+// Остальные запросы выполняются перебором продаж.
+func planQuery(q *domain.Query, chain bool) queryPlan {
+	money := true
+
+	for _, metric := range q.Metrics {
+		if metric == domain.MetricQuantity || metric == domain.MetricSales {
+			money = false
+		}
+	}
+
+	fields := append([]string(nil), q.GroupBy...)
+	for field := range q.Filters {
+		fields = append(fields, field)
+	}
+
+	var store, product bool
+
+	plan := queryPlan{method: planCumulative}
+
+	for _, field := range fields {
+		switch field {
+		case domain.FieldStore:
+			store = true
+		case domain.FieldProduct:
+			product = true
+		case domain.FieldCurrency:
+		default:
+			if plan.dimension != "" && plan.dimension != field {
+				return queryPlan{method: planScan}
+			}
+
+			plan.dimension = field
+		}
+	}
+
+	if plan.dimension == "" {
+		plan := queryPlan{method: planRollup, rollup: rollupStoreDaily, edges: planCumulative}
+
+		switch {
+		case product:
+			plan.rollup = rollupProductDaily
+		case !store && chain:
+			plan.rollup = rollupChainHourly
+		}
+
+		if product || !money {
+			plan.edges = planScan
+		}
+
+		return plan
+	}
+
+	if product || !money {
+		return queryPlan{method: planScan}
+	}
+
+	return plan
+}
+
+// queryTotals накопленные показатели группы запроса.
+type queryTotals struct {
+	amounts  fixedAmounts
+	quantity int64
+	sales    int64
+}
+
+// queryResult накапливает строки результата запроса по магазинам.
+type queryResult struct {
+	q       *domain.Query
+	filters map[string]map[string]bool
+
+	rows  map[string]*queryTotals
+	group map[string]domain.QueryRow // интервал и группа строки по ключу
+}
+
+func newQueryResult(q *domain.Query) *queryResult {
+	res := &queryResult{
+		q:       q,
+		filters: make(map[string]map[string]bool, len(q.Filters)),
+		rows:    make(map[string]*queryTotals),
+		group:   make(map[string]domain.QueryRow),
+	}
+
+	for field, values := range q.Filters {
+		res.filters[field] = make(map[string]bool, len(values))
+		for _, v := range values {
+			res.filters[field][v] = true
+		}
+	}
+
+	return res
+}
+
+// matches сообщает, что значение поля проходит фильтр запроса.
+func (res *queryResult) matches(field, value string) bool {
+	allowed, ok := res.filters[field]
+	if !ok {
+		return true
+	}
+
+	if value == "" {
+		value = domain.Unspecified
+	}
+
+	return allowed[value]
+}
+
+// add добавляет показатели в строку интервала bucket (nil - без разбивки) с полями продажи values.
+func (res *queryResult) add(bucket *time.Time, values func(field string) string, totals queryTotals) error {
+	var b strings.Builder
+
+	if bucket != nil {
+		b.WriteString(bucket.Format(time.RFC3339Nano))
+	}
+
+	b.WriteString("\x00" + values(domain.FieldCurrency))
+
+	for _, field := range res.q.GroupBy {
+		b.WriteString("\x00" + values(field))
+	}
+
+	key := b.String()
+
+	row, ok := res.rows[key]
+	if !ok {
+		if len(res.rows) == maxQueryRows {
+			return fmt.Errorf("more than %d rows: %w", maxQueryRows, domain.ErrQueryTooLarge)
+		}
+
+		group := map[string]string{domain.FieldCurrency: values(domain.FieldCurrency)}
+		for _, field := range res.q.GroupBy {
+			value := values(field)
+			if value == "" {
+				value = domain.Unspecified
+			}
+
+			group[field] = value
+		}
+
+		row = &queryTotals{}
+		res.rows[key] = row
+		res.group[key] = domain.QueryRow{Bucket: bucket, Group: group}
+	}
+
+	row.amounts = row.amounts.add(totals.amounts)
+	row.quantity += totals.quantity
+	row.sales += totals.sales
+
+	return nil
+}
+
+// result возвращает строки результата с показателями запроса. Строки без продаж (с нулевыми показателями)
+// не возвращаются, чтобы результат не зависел от плана.
+func (res *queryResult) result(plan queryPlan) *domain.QueryResult {
+	rows := make([]domain.QueryRow, 0, len(res.rows))
+
+	for key, totals := range res.rows {
+		if *totals == (queryTotals{}) {
+			continue
+		}
+
+		row := res.group[key]
+		row.Metrics = make(map[string]decimal.Decimal, len(res.q.Metrics))
+
+		amounts := totals.amounts.amounts()
+
+		for _, metric := range res.q.Metrics {
+			switch metric {
+			case domain.MetricGross:
+				row.Metrics[metric] = amounts.Gross
+			case domain.MetricDiscount:
+				row.Metrics[metric] = amounts.Discount
+			case domain.MetricTax:
+				row.Metrics[metric] = amounts.Tax
+			case domain.MetricNet:
+				row.Metrics[metric] = amounts.Net
+			case domain.MetricQuantity:
+				row.Metrics[metric] = decimal.NewFromInt(totals.quantity)
+			case domain.MetricSales:
+				row.Metrics[metric] = decimal.NewFromInt(totals.sales)
+			}
+		}
+
+		rows = append(rows, row)
+	}
+
+	return &domain.QueryResult{Plan: plan.method, Rows: domain.MergeQueryRows(rows)}
+}
+
+// intervals вызывает fn для каждого интервала разбивки запроса (без разбивки - для всего периода)
+// с началом интервала bucket (nil - без разбивки).
+func (res *queryResult) intervals(fn func(startDate, endDate time.Time, bucket *time.Time) error) error {
+	for _, b := range res.q.Buckets() {
+		var bucket *time.Time
+		if res.q.Bucket != "" {
+			start := res.q.BucketStart(b.StartDate)
+			bucket = &start
+		}
+
+		if err := fn(b.StartDate, b.EndDate, bucket); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// queryChain добавляет в res показатели продаж целых часов интервалов запроса по сводке сети. Продажи
+// остальных частей интервалов добавляются по магазинам (см. reader.queryRollup).
+func (s *SalesStorage) queryChain(res *queryResult) error {
+	return res.intervals(func(startDate, endDate time.Time, bucket *time.Time) error {
+		from, to, ok := hourCover(unixNano(startDate), unixNano(endDate))
+		if !ok {
+			return nil
+		}
+
+		return s.chain.sum(from, to, func(currency string, totals queryTotals) error {
+			if !res.matches(domain.FieldCurrency, currency) {
+				return nil
+			}
+
+			values := func(field string) string {
+				if field == domain.FieldCurrency {
+					return currency
+				}
+
+				return ""
+			}
+
+			return res.add(bucket, values, totals)
+		})
+	})
+}
+
+// query выполняет запрос по видимым продажам магазина storeID и добавляет строки в res.
+func (r *reader) query(storeID string, plan queryPlan, res *queryResult) error {
+	if !res.matches(domain.FieldStore, storeID) {
+		return nil
+	}
+
+	if plan.method == planScan {
+		return r.queryScan(storeID, res.q.StartDate, res.q.EndDate, res)
+	}
+
+	return res.intervals(func(startDate, endDate time.Time, bucket *time.Time) error {
+		if plan.method == planRollup {
+			return r.queryRollup(storeID, plan, startDate, endDate, bucket, res)
+		}
+
+		return r.queryCumulative(storeID, plan.dimension, startDate, endDate, bucket, res)
+	})
+}
+
+// queryRollup добавляет в res показатели продаж за интервал: целые интервалы сводки плана берутся
+// из сводки, остальные продажи на краях интервала считаются способом plan.edges. Для сводки сети
+// целые часы уже добавлены queryChain, и магазин добавляет только края.
+func (r *reader) queryRollup(storeID string, plan queryPlan, startDate, endDate time.Time, bucket *time.Time, res *queryResult) error {
+	start, end := unixNano(startDate), unixNano(endDate)
+
+	edge := func(from, to int64) error {
+		if plan.edges == planCumulative {
+			return r.queryCumulative(storeID, "", time.Unix(0, from), time.Unix(0, to), bucket, res)
+		}
+
+		return r.queryScan(storeID, time.Unix(0, from), time.Unix(0, to), res)
+	}
+
+	if plan.rollup == rollupChainHourly {
+		from, to, ok := hourCover(start, end)
+		if !ok {
+			return edge(start, end)
+		}
+
+		if from > start {
+			if err := edge(start, from-1); err != nil {
+				return err
+			}
+		}
+
+		if to <= end {
+			return edge(to, end)
+		}
+
+		return nil
+	}
+
+	ru := &r.store.dailyTotals
+	if plan.rollup == rollupProductDaily {
+		ru = &r.store.productTotals
+	}
+
+	return ru.cover(start, end, r.count, edge, func(b *rollupBucket) error {
+		for _, t := range b.totals {
+			if !res.matches(domain.FieldCurrency, t.currency) || !res.matches(domain.FieldProduct, t.product) {
+				continue
+			}
+
+			values := func(field string) string {
+				switch field {
+				case domain.FieldStore:
+					return storeID
+				case domain.FieldProduct:
+					return t.product
+				case domain.FieldCurrency:
+					return t.currency
+				default:
+					return ""
+				}
+			}
+
+			if err := res.add(bucket, values, t.totals); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// queryCumulative добавляет в res суммы продаж за интервал, вычисленные по кумулятивным суммам валют
+// и измерения dimension (см. totalSumByDimension).
+func (r *reader) queryCumulative(storeID, dimension string, startDate, endDate time.Time, bucket *time.Time, res *queryResult) error {
+	first, last, err := r.bounds(startDate, endDate)
+	if err != nil || first >= last {
+		return err
+	}
+
+	totals, err := r.periodTotals(first, last)
+	if err != nil {
+		return err
+	}
+
+	// суммы по значениям измерения; остаток суммы валюты - продажи без значения
+	type group struct {
+		value    string
+		currency string
+		amounts  fixedAmounts
+	}
+
+	var groups []group
+
+	if dimension != "" {
+		for k := range r.store.dimensionSums {
+			d := &r.store.dimensionSums[k]
+			if d.dimension != dimension || d.since >= r.count {
+				continue
+			}
+
+			end, err := r.dimensionPrefix(k, last-1)
+			if err != nil {
+				return err
+			}
+
+			start, err := r.dimensionPrefix(k, first-1)
+			if err != nil {
+				return err
+			}
+
+			currency := r.store.currencyCodes[d.currency]
+			amounts := end.sub(start)
+
+			groups = append(groups, group{value: d.value, currency: currency, amounts: amounts})
+			totals[currency] = totals[currency].sub(amounts)
+		}
+	}
+
+	for currency, amounts := range totals {
+		groups = append(groups, group{currency: currency, amounts: amounts})
+	}
+
+	for _, g := range groups {
+		if !res.matches(domain.FieldCurrency, g.currency) || (dimension != "" && !res.matches(dimension, g.value)) {
+			continue
+		}
+
+		values := func(field string) string {
+			switch field {
+			case domain.FieldStore:
+				return storeID
+			case domain.FieldCurrency:
+				return g.currency
+			case dimension:
+				return g.value
+			default:
+				return ""
+			}
+		}
+
+		if err := res.add(bucket, values, queryTotals{amounts: g.amounts}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// periodTotals возвращает суммы видимых продаж с индексами [first, last) по валютам.
+func (r *reader) periodTotals(first, last int) (fixedTotals, error) {
+	end, err := r.prefix(last - 1)
+	if err != nil {
+		return nil, err
+	}
+
+	start, err := r.prefix(first - 1)
+	if err != nil {
+		return nil, err
+	}
+
+	totals := make(fixedTotals, len(r.store.currencyCodes))
+	for _, currency := range r.currencies() {
+		totals[currency] = end[currency].sub(start[currency])
+	}
+
+	return totals, nil
+}
+
+// queryScan добавляет в res показатели продаж за период [startDate, endDate] внутри периода запроса,
+// перебирая продажи.
+func (r *reader) queryScan(storeID string, startDate, endDate time.Time, res *queryResult) error {
+	first, last, err := r.bounds(startDate, endDate)
+	if err != nil || first >= last {
+		return err
+	}
+
+	var (
+		addErr  error
+		current time.Time // начало интервала разбивки текущей продажи
+		next    time.Time // начало следующего интервала
+	)
+
+	err = r.scan(first, last, func(row row) {
+		if addErr != nil {
+			return
+		}
+
+		values := func(field string) string {
+			switch field {
+			case domain.FieldStore:
+				return storeID
+			case domain.FieldProduct:
+				return row.product
+			case domain.FieldCurrency:
+				return row.currency
+			default:
+				return row.dimension(field)
+			}
+		}
+
+		for field := range res.filters {
+			if !res.matches(field, values(field)) {
+				return
+			}
+		}
+
+		var bucket *time.Time
+
+		if res.q.Bucket != "" {
+			// временные метки продаж не убывают, поэтому интервал пересчитывается только при переходе в следующий
+			if t := time.Unix(0, row.timestamp); current.IsZero() || !t.Before(next) {
+				current = res.q.BucketStart(t)
+				next = res.q.NextBucket(current)
+			}
+
+			start := current
+			bucket = &start
+		}
+
+		addErr = res.add(bucket, values, queryTotals{amounts: row.amounts, quantity: row.quantity, sales: 1})
+	})
+	if err != nil {
+		return err
+	}
+
+	return addErr
+}
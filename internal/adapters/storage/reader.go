@@ -0,0 +1,639 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+	"go.dataflow.ru/service-sales/internal/app/ports"
+)
+
+// reader читает продажи снимка магазина по сквозным индексам, подгружая вытесненные гранулы с диска.
+// Видны только первые count продаж (состояние магазина в версии count). Используется в рамках одного запроса.
+type reader struct {
+	store       *storeSales
+	granularity int
+	count       int
+
+	// последняя прочитанная с диска гранула: поиск границы периода и подсчет кумулятивной суммы
+	// обычно обращаются к одной и той же грануле
+	loaded     int
+	loadedRows []row
+}
+
+// newReader возвращает reader продаж магазина в версии version (-1 - все продажи снимка).
+// Для магазина без продаж (store == nil) возвращает reader без продаж.
+func newReader(store *storeSales, granularity int, version int) *reader {
+	if store == nil {
+		store = &storeSales{}
+	}
+
+	count := store.version
+	if version >= 0 && version < count {
+		count = version
+	}
+
+	return &reader{
+		store:       store,
+		granularity: granularity,
+		count:       count,
+		loaded:      -1,
+	}
+}
+
+// evicted возвращает количество вытесненных продаж.
+func (r *reader) evicted() int {
+	return len(r.store.evicted) * r.granularity
+}
+
+// currencies возвращает валюты видимых продаж магазина.
+func (r *reader) currencies() []string {
+	currencies := make([]string, 0, len(r.store.currencyCodes))
+
+	for c, code := range r.store.currencyCodes {
+		if r.store.currencySince[c] < r.count {
+			currencies = append(currencies, code)
+		}
+	}
+
+	return currencies
+}
+
+// bounds возвращает полуинтервал индексов [first, last) продаж за период.
+func (r *reader) bounds(startDate, endDate time.Time) (int, int, error) {
+	first, err := r.lowerBound(unixNano(startDate))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	last, err := r.upperBound(unixNano(endDate))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return first, last, nil
+}
+
+// lowerBound возвращает индекс первой продажи с временной меткой не раньше ts.
+// Если таких продаж нет - возвращает количество продаж.
+func (r *reader) lowerBound(ts int64) (int, error) {
+	return r.search(func(t int64) bool { return t >= ts })
+}
+
+// upperBound возвращает индекс первой продажи с временной меткой строго позже ts.
+// Если таких продаж нет - возвращает количество продаж.
+func (r *reader) upperBound(ts int64) (int, error) {
+	return r.search(func(t int64) bool { return t > ts })
+}
+
+// search возвращает индекс первой продажи, для временной метки которой выполняется монотонный предикат found.
+// По разреженному индексу находит гранулу, после чего ищет продажу перебором внутри гранулы.
+// Поиск идет по всем продажам снимка: если найденная продажа не видна в версии reader, возвращается count.
+func (r *reader) search(found func(t int64) bool) (int, error) {
+	i, err := r.searchAll(found)
+	if err != nil {
+		return 0, err
+	}
+
+	if i > r.count {
+		return r.count, nil
+	}
+
+	return i, nil
+}
+
+func (r *reader) searchAll(found func(t int64) bool) (int, error) {
+	sparseIndex := r.store.sparseIndex
+
+	// первая гранула, "голова" которой уже удовлетворяет предикату
+	granule := sort.Search(len(sparseIndex), func(i int) bool {
+		return found(sparseIndex[i])
+	})
+
+	if granule == 0 {
+		return 0, nil
+	}
+
+	// искомая продажа лежит в предыдущей грануле, либо является "головой" найденной гранулы
+	timestamps, err := r.timestamps(granule - 1)
+	if err != nil {
+		return 0, err
+	}
+
+	head := (granule - 1) * r.granularity
+
+	for i, t := range timestamps {
+		if found(t) {
+			return head + i, nil
+		}
+	}
+
+	return head + len(timestamps), nil
+}
+
+// rows возвращает строки продаж вытесненной гранулы g.
+func (r *reader) rows(g int) ([]row, error) {
+	if r.loaded == g {
+		return r.loadedRows, nil
+	}
+
+	sales, err := readGranule(r.store.id, r.store.evicted[g])
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]row, 0, len(sales))
+	for _, sale := range sales {
+		row, err := rowOf(sale)
+		if err != nil {
+			return nil, err
+		}
+
+		rows = append(rows, row)
+	}
+
+	r.loaded, r.loadedRows = g, rows
+
+	return rows, nil
+}
+
+// timestamps возвращает временные метки продаж гранулы g.
+func (r *reader) timestamps(g int) ([]int64, error) {
+	if g >= len(r.store.evicted) {
+		head := g*r.granularity - r.evicted()
+
+		tail := head + r.granularity // "хвост" не входит в гранулу
+		if r.store.len() < tail {
+			tail = r.store.len()
+		}
+
+		return r.store.timestamps[head:tail], nil
+	}
+
+	rows, err := r.rows(g)
+	if err != nil {
+		return nil, err
+	}
+
+	timestamps := make([]int64, 0, len(rows))
+	for _, row := range rows {
+		timestamps = append(timestamps, row.timestamp)
+	}
+
+	return timestamps, nil
+}
+
+// prefix возвращает кумулятивные суммы продаж по валютам с 0-й по i-ю продажу включительно.
+func (r *reader) prefix(i int) (fixedTotals, error) {
+	totals := make(fixedTotals, len(r.store.currencyCodes))
+
+	if i < 0 {
+		return totals, nil
+	}
+
+	if evicted := r.evicted(); i >= evicted {
+		for c := range r.store.cumulativeSums {
+			totals[r.store.currencyCodes[c]] = r.store.cumulativeSums[c].at(i - evicted)
+		}
+
+		return totals, nil
+	}
+
+	// продажа вытеснена: к кумулятивной сумме до начала гранулы добавляем продажи гранулы до i-й включительно
+	g := i / r.granularity
+
+	rows, err := r.rows(g)
+	if err != nil {
+		return nil, err
+	}
+
+	for currency, amounts := range r.store.evicted[g].cumulative {
+		totals.add(currency, amounts)
+	}
+
+	for _, row := range rows[:i-g*r.granularity+1] {
+		totals.add(row.currency, row.amounts)
+	}
+
+	return totals, nil
+}
+
+// dimensionPrefix возвращает кумулятивную сумму k-й суммы измерения (см. storeSales.dimensionSums)
+// с 0-й по i-ю продажу включительно.
+func (r *reader) dimensionPrefix(k, i int) (fixedAmounts, error) {
+	if i < 0 {
+		return fixedAmounts{}, nil
+	}
+
+	d := &r.store.dimensionSums[k]
+
+	if evicted := r.evicted(); i >= evicted {
+		return d.sums.at(i - evicted), nil
+	}
+
+	// продажа вытеснена: к сумме до начала гранулы добавляем продажи гранулы со значением измерения до i-й включительно
+	g := i / r.granularity
+
+	rows, err := r.rows(g)
+	if err != nil {
+		return fixedAmounts{}, err
+	}
+
+	var sum fixedAmounts
+	if k < len(r.store.evicted[g].dimensions) {
+		sum = r.store.evicted[g].dimensions[k]
+	}
+
+	currency := r.store.currencyCodes[d.currency]
+
+	for _, row := range rows[:i-g*r.granularity+1] {
+		if row.currency == currency && row.dimension(d.dimension) == d.value {
+			sum = sum.add(row.amounts)
+		}
+	}
+
+	return sum, nil
+}
+
+// scan вызывает fn для продаж с индексами [first, last).
+func (r *reader) scan(first, last int, fn func(row row)) error {
+	evicted := r.evicted()
+
+	for i := first; i < last && i < evicted; {
+		g := i / r.granularity
+
+		rows, err := r.rows(g)
+		if err != nil {
+			return err
+		}
+
+		for ; i < last && i < (g+1)*r.granularity; i++ {
+			fn(rows[i-g*r.granularity])
+		}
+	}
+
+	if first < evicted {
+		first = evicted
+	}
+
+	for i := first; i < last; i++ {
+		fn(r.store.row(i - evicted))
+	}
+
+	return nil
+}
+
+// sales возвращает все видимые продажи магазина.
+func (r *reader) sales() ([]*domain.Sale, error) {
+	return r.salesRange(0, r.count)
+}
+
+// scanSales передает fn видимые продажи магазина по гранулам: вытесненная гранула читается с диска, только когда
+// до нее доходит очередь. Перед каждой гранулой проверяется отмена ctx.
+func (r *reader) scanSales(ctx context.Context, fn func(sale *domain.Sale) error) error {
+	for first := 0; first < r.count; first += r.granularity {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		sales, err := r.salesRange(first, first+r.granularity)
+		if err != nil {
+			return err
+		}
+
+		for _, sale := range sales {
+			if err = fn(sale); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// salesRange возвращает видимые продажи магазина с индексами [first, last).
+func (r *reader) salesRange(first, last int) ([]*domain.Sale, error) {
+	if last > r.count {
+		last = r.count
+	}
+
+	if first >= last {
+		return nil, nil
+	}
+
+	sales := make([]*domain.Sale, 0, last-first)
+	evicted := r.evicted()
+
+	for i := first; i < last && i < evicted; {
+		g := i / r.granularity
+
+		granule, err := readGranule(r.store.id, r.store.evicted[g])
+		if err != nil {
+			return nil, err
+		}
+
+		for ; i < last && i < (g+1)*r.granularity; i++ {
+			sales = append(sales, granule[i-g*r.granularity])
+		}
+	}
+
+	if first < evicted {
+		first = evicted
+	}
+
+	for i := first; i < last; i++ {
+		sales = append(sales, r.store.sale(i-evicted))
+	}
+
+	return sales, nil
+}
+
+// totalSum возвращает суммы видимых продаж за период (границы включаются) в разрезе валют.
+func (r *reader) totalSum(startDate, endDate time.Time) (domain.Totals, error) {
+	if startDate.After(endDate) {
+		return make(domain.Totals), nil
+	}
+
+	// продажи периода занимают полуинтервал индексов [first, last)
+	first, last, err := r.bounds(startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	if first >= last {
+		return make(domain.Totals), nil
+	}
+
+	// сумма за период - разность кумулятивных сумм на конец периода и перед его началом
+	end, err := r.prefix(last - 1)
+	if err != nil {
+		return nil, err
+	}
+
+	start, err := r.prefix(first - 1)
+	if err != nil {
+		return nil, err
+	}
+
+	totals := make(fixedTotals, len(r.store.currencyCodes))
+	for _, currency := range r.currencies() {
+		totals[currency] = end[currency].sub(start[currency])
+	}
+
+	return totals.totals(), nil
+}
+
+// totalSumByProduct возвращает суммы видимых продаж за период (границы включаются) в разрезе товаров и валют.
+// Продажи периода находятся по индексу, после чего суммируются перебором.
+func (r *reader) totalSumByProduct(startDate, endDate time.Time) (map[string]domain.Totals, error) {
+	res := make(map[string]domain.Totals)
+
+	if startDate.After(endDate) {
+		return res, nil
+	}
+
+	first, last, err := r.bounds(startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	byProduct := make(map[string]fixedTotals)
+
+	err = r.scan(first, last, func(row row) {
+		totals, ok := byProduct[row.product]
+		if !ok {
+			totals = make(fixedTotals)
+			byProduct[row.product] = totals
+		}
+
+		totals.add(row.currency, row.amounts)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for product, totals := range byProduct {
+		res[product] = totals.totals()
+	}
+
+	return res, nil
+}
+
+// totalSumByDimension возвращает суммы видимых продаж за период (границы включаются) в разрезе значений
+// измерения и валют. Суммы значений - разности кумулятивных сумм измерения, суммы продаж без значения
+// (domain.Unspecified) - разность суммы за период и сумм всех значений. Пустые группы не возвращаются.
+func (r *reader) totalSumByDimension(dimension string, startDate, endDate time.Time) (map[string]domain.Totals, error) {
+	res := make(map[string]domain.Totals)
+
+	if startDate.After(endDate) {
+		return res, nil
+	}
+
+	first, last, err := r.bounds(startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	if first >= last {
+		return res, nil
+	}
+
+	end, err := r.prefix(last - 1)
+	if err != nil {
+		return nil, err
+	}
+
+	start, err := r.prefix(first - 1)
+	if err != nil {
+		return nil, err
+	}
+
+	unspecified := make(fixedTotals)
+	for _, currency := range r.currencies() {
+		unspecified[currency] = end[currency].sub(start[currency])
+	}
+
+	groups := make(map[string]fixedTotals)
+
+	for k := range r.store.dimensionSums {
+		d := &r.store.dimensionSums[k]
+		if d.dimension != dimension || d.since >= r.count {
+			continue
+		}
+
+		end, err := r.dimensionPrefix(k, last-1)
+		if err != nil {
+			return nil, err
+		}
+
+		start, err := r.dimensionPrefix(k, first-1)
+		if err != nil {
+			return nil, err
+		}
+
+		amounts := end.sub(start)
+		if amounts == (fixedAmounts{}) {
+			continue
+		}
+
+		currency := r.store.currencyCodes[d.currency]
+
+		if _, ok := groups[d.value]; !ok {
+			groups[d.value] = make(fixedTotals)
+		}
+
+		groups[d.value].add(currency, amounts)
+		unspecified[currency] = unspecified[currency].sub(amounts)
+	}
+
+	for currency, amounts := range unspecified {
+		if amounts == (fixedAmounts{}) {
+			delete(unspecified, currency)
+		}
+	}
+
+	if len(unspecified) > 0 {
+		groups[domain.Unspecified] = unspecified
+	}
+
+	for value, totals := range groups {
+		res[value] = totals.totals()
+	}
+
+	return res, nil
+}
+
+// receiptTotals возвращает показатели чеков среди видимых продаж за период (границы включаются) в разрезе валют.
+// Строки чека занимают соседние индексы и имеют одну дату, поэтому чек целиком попадает в период или не попадает,
+// и смена идентификатора чека между соседними продажами означает новый чек.
+func (r *reader) receiptTotals(startDate, endDate time.Time) (domain.ReceiptTotals, error) {
+	res := make(domain.ReceiptTotals)
+
+	if startDate.After(endDate) {
+		return res, nil
+	}
+
+	first, last, err := r.bounds(startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	type receiptSums struct {
+		receipts, lines, items int64
+		amounts                fixedAmounts
+	}
+
+	var (
+		byCurrency = make(map[string]*receiptSums)
+		prev       string
+	)
+
+	err = r.scan(first, last, func(row row) {
+		if row.receipt == "" {
+			prev = ""
+			return
+		}
+
+		sums, ok := byCurrency[row.currency]
+		if !ok {
+			sums = &receiptSums{}
+			byCurrency[row.currency] = sums
+		}
+
+		if row.receipt != prev {
+			sums.receipts++
+		}
+
+		sums.lines++
+		sums.items += row.quantity
+		sums.amounts = sums.amounts.add(row.amounts)
+		prev = row.receipt
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for currency, sums := range byCurrency {
+		res[currency] = domain.ReceiptStats{
+			Receipts: sums.receipts,
+			Lines:    sums.lines,
+			Items:    sums.items,
+			Amounts:  sums.amounts.amounts(),
+		}
+	}
+
+	return res, nil
+}
+
+// sketches возвращает приближенную статистику видимых продаж за период (границы включаются). Статистика
+// дней, целиком лежащих в периоде, берется из дневной сводки, продажи на краях периода учитываются перебором.
+// Чек не пересекает границу дня и целиком попадает в период или не попадает (см. receiptTotals).
+func (r *reader) sketches(startDate, endDate time.Time) (*domain.SalesSketches, error) {
+	res := domain.NewSalesSketches()
+
+	if startDate.After(endDate) {
+		return res, nil
+	}
+
+	edge := func(from, to int64) error {
+		return r.scanSketches(time.Unix(0, from), time.Unix(0, to), res)
+	}
+
+	err := r.store.dailyTotals.cover(unixNano(startDate), unixNano(endDate), r.count, edge, func(b *rollupBucket) error {
+		res.Merge(b.sketches)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// scanSketches добавляет в res статистику видимых продаж за период (границы включаются), перебирая продажи.
+func (r *reader) scanSketches(startDate, endDate time.Time, res *domain.SalesSketches) error {
+	first, last, err := r.bounds(startDate, endDate)
+	if err != nil {
+		return err
+	}
+
+	var t ticket
+
+	err = r.scan(first, last, func(row row) {
+		res.AddProduct(row.product)
+
+		if t.open && (row.receipt == "" || row.receipt != t.receipt) {
+			res.AddTicket(t.currency, ticketSize(t.gross))
+			t = ticket{}
+		}
+
+		t.receipt, t.currency, t.open = row.receipt, row.currency, true
+		t.gross += row.amounts.gross
+	})
+	if err != nil {
+		return err
+	}
+
+	if t.open {
+		res.AddTicket(t.currency, ticketSize(t.gross))
+	}
+
+	return nil
+}
+
+// totalSums возвращает суммы продаж магазинов за периоды запросов, по одному запросу к r на магазин.
+func totalSums(r ports.SalesReader, queries []domain.StorePeriod) ([]domain.Totals, error) {
+	res := make([]domain.Totals, 0, len(queries))
+
+	for _, q := range queries {
+		totals, err := r.GetTotalSum(q.StoreID, q.StartDate, q.EndDate)
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, totals)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"fmt"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+)
+
+// Versions возвращает версии журналов магазинов - поколение и количество продаж, принятых магазином в нем.
+func (s *SalesStorage) Versions() (map[string]domain.LogVersion, error) {
+	views := s.views()
+
+	versions := make(map[string]domain.LogVersion, len(views))
+	for _, store := range views {
+		versions[store.id] = domain.LogVersion{Generation: store.generation, Version: store.version}
+	}
+
+	return versions, nil
+}
+
+func (s *SalesStorage) versions() map[string]int {
+	views := s.views()
+
+	versions := make(map[string]int, len(views))
+	for _, store := range views {
+		versions[store.id] = store.version
+	}
+
+	return versions
+}
+
+// SalesSince возвращает не более limit продаж магазина поколения generation, начиная с продажи с индексом version.
+// Продажи магазина только дописываются, поэтому их последовательность - упорядоченный журнал, который
+// ведомый экземпляр применяет к своему хранилищу (см. services.ReplicationService). Удаление продаж начинает
+// журнал заново в новом поколении (см. Purge), продажи прежнего поколения больше не читаются.
+func (s *SalesStorage) SalesSince(storeID string, generation, version, limit int) ([]*domain.Sale, error) {
+	if version < 0 || limit <= 0 {
+		return nil, fmt.Errorf("invalid log range: version %d, limit %d", version, limit)
+	}
+
+	view := s.view(storeID)
+	if view != nil && view.generation != generation {
+		return nil, fmt.Errorf("store %s: generation %d, requested %d: %w",
+			storeID, view.generation, generation, domain.ErrLogGenerationChanged)
+	}
+
+	return s.reader(view, -1).salesRange(version, version+limit)
+}
@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+	"go.dataflow.ru/service-sales/pkg/logger"
+)
+
+func TestSalesStorage_SalesSince(t *testing.T) {
+	t.Parallel()
+
+	s := New(logger.NoOpLogger(), WithIndexGranularity(3), WithRetention(5*24*time.Hour, t.TempDir()))
+
+	dt := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, s.AddSale(&domain.Sale{
+			StoreID:      "store_1",
+			ProductID:    fmt.Sprintf("product_%d", i),
+			QuantitySold: 1,
+			SalePrice:    decimal.NewFromInt(int64(i + 1)),
+			Currency:     "RUB",
+			SaleDate:     dt.AddDate(0, 0, i),
+		}))
+	}
+
+	// 3 гранулы (9 продаж) вытеснены, журнал читается и с диска, и из памяти
+	assert.NoError(t, s.Evict(dt.AddDate(0, 0, 15)))
+	assert.Len(t, s.view("store_1").evicted, 3)
+
+	versions, err := s.Versions()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]domain.LogVersion{"store_1": {Version: 10}}, versions)
+
+	testCases := []struct {
+		name     string
+		storeID  string
+		version  int
+		limit    int
+		expFirst int
+		expLen   int
+	}{
+		{name: "с начала журнала", storeID: "store_1", version: 0, limit: 4, expFirst: 0, expLen: 4},
+		{name: "внутри вытесненной гранулы", storeID: "store_1", version: 2, limit: 5, expFirst: 2, expLen: 5},
+		{name: "граница вытесненных продаж и памяти", storeID: "store_1", version: 7, limit: 100, expFirst: 7, expLen: 3},
+		{name: "конец журнала", storeID: "store_1", version: 10, limit: 5, expLen: 0},
+		{name: "магазин без продаж", storeID: "store_2", version: 0, limit: 5, expLen: 0},
+	}
+
+	for _, tt := range testCases {
+		sales, err := s.SalesSince(tt.storeID, 0, tt.version, tt.limit)
+		assert.NoError(t, err, tt.name)
+		assert.Len(t, sales, tt.expLen, tt.name)
+
+		for i, sale := range sales {
+			assert.Equal(t, fmt.Sprintf("product_%d", tt.expFirst+i), sale.ProductID, tt.name)
+		}
+	}
+
+	_, err = s.SalesSince("store_1", 0, -1, 5)
+	assert.Error(t, err)
+}
+
+func TestSalesStorage_AddSales(t *testing.T) {
+	t.Parallel()
+
+	s := New(logger.NoOpLogger(), WithIndexGranularity(3))
+
+	dt := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	sale := func(storeID string, i int) *domain.Sale {
+		return &domain.Sale{
+			StoreID:      storeID,
+			ProductID:    fmt.Sprintf("product_%d", i),
+			QuantitySold: 1,
+			SalePrice:    decimal.NewFromInt(10),
+			Currency:     "RUB",
+			SaleDate:     dt.Add(time.Duration(i) * time.Hour),
+			ReceiptID:    "receipt_1",
+		}
+	}
+
+	assert.NoError(t, s.AddSales(nil))
+	assert.NoError(t, s.AddSales([]*domain.Sale{sale("store_1", 0), sale("store_1", 1)}))
+
+	// продажа раньше последней отменяет всю пачку
+	assert.ErrorIs(t, s.AddSales([]*domain.Sale{sale("store_1", 2), sale("store_1", 0)}), domain.ErrSaleOutOfRange)
+	assert.Equal(t, 2, s.view("store_1").version)
+
+	assert.Error(t, s.AddSales([]*domain.Sale{sale("store_1", 2), sale("store_2", 3)}))
+	assert.Nil(t, s.view("store_2"))
+
+	assert.NoError(t, s.AddSales([]*domain.Sale{sale("store_1", 2), sale("store_1", 3)}))
+
+	totals, err := s.GetReceiptTotals("store_1", dt, dt.AddDate(0, 0, 1))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, totals["RUB"].Receipts)
+	assert.EqualValues(t, 4, totals["RUB"].Lines)
+}
@@ -0,0 +1,351 @@
+package storage
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+)
+
+const (
+	rollupDay  = int64(24 * time.Hour)
+	rollupHour = int64(time.Hour)
+)
+
+// rollupTotals показатели продаж группы интервала сводки: валюты, а в сводке по товарам - товара в валюте.
+type rollupTotals struct {
+	product  string // пустой в сводке без товаров
+	currency string
+	totals   queryTotals
+}
+
+// rollupBucket показатели продаж магазина за интервал сводки [start, start + step) - продаж с индексами [first, last).
+type rollupBucket struct {
+	start       int64 // в unix-наносекундах
+	first, last int
+	totals      []rollupTotals
+
+	// приближенная статистика продаж интервала; nil в сводке без статистики. Закрытый интервал хранит
+	// сжатую статистику, которая читается без изменений
+	sketches *domain.SalesSketches
+}
+
+// rollupKey группа интервала сводки.
+type rollupKey struct {
+	product  string
+	currency string
+}
+
+// rollup сводка продаж магазина по интервалам времени одинаковой длины (дням в UTC), обновляемая при добавлении
+// каждой продажи.
+//
+// Временные метки продаж магазина не убывают (это проверяет storeSales.append), поэтому продажа всегда попадает
+// в последний, открытый интервал, а продажа следующего интервала закрывает его. Закрытые интервалы больше
+// не меняются и только дописываются в closed, поэтому, как и колонки продаж, читаются в снимке storeSales
+// без блокировок. Открытый интервал изменяется на месте и доступен только при записи под блокировкой магазина:
+// чтение получает его продажи по кумулятивным суммам или перебором. Интервал, закрытый продажей с индексом last,
+// виден в версиях от last.
+//
+// Сводка со статистикой (sketched) ведет в интервалах еще и приближенную статистику продаж - различные товары
+// и размеры чеков. Строки чека занимают соседние индексы и имеют одну дату, поэтому чек копится в ticket,
+// пока идут его строки, и учитывается в интервале, когда приходит продажа другого чека или интервал закрывается.
+type rollup struct {
+	step      int64
+	byProduct bool // группы - товары в валюте, иначе - валюты
+	sketched  bool
+
+	closed []rollupBucket
+
+	open   *rollupBucket
+	groups map[rollupKey]int // индекс группы открытого интервала в open.totals
+	ticket ticket            // последний чек открытого интервала
+}
+
+// ticket накапливаемый чек: валовая сумма строк одного чека или одна продажа вне чека.
+type ticket struct {
+	receipt  string // пустой у продажи вне чека
+	currency string
+	gross    int64
+	open     bool
+}
+
+func newRollup(step int64, byProduct, sketched bool) rollup {
+	return rollup{step: step, byProduct: byProduct, sketched: sketched}
+}
+
+// add учитывает продажу r с индексом i в открытом интервале. Интервал по времени продажи не ищется: продажа раньше
+// последней продажи магазина отклоняется в storeSales.append и не может относиться к закрытому интервалу.
+func (ru *rollup) add(i int, r *row, totals queryTotals) {
+	start := floorTimestamp(r.timestamp, ru.step)
+
+	if ru.open != nil && start > ru.open.start {
+		if ru.sketched {
+			ru.flushTicket()
+			ru.open.sketches.Compress()
+		}
+
+		ru.open.last = i
+		ru.closed = append(ru.closed, *ru.open)
+		ru.open = nil
+	}
+
+	if ru.open == nil {
+		ru.open = &rollupBucket{start: start, first: i}
+		ru.groups = make(map[rollupKey]int)
+
+		if ru.sketched {
+			ru.open.sketches = domain.NewSalesSketches()
+		}
+	}
+
+	if ru.sketched {
+		ru.addSketches(r)
+	}
+
+	key := rollupKey{currency: r.currency}
+	if ru.byProduct {
+		key.product = r.product
+	}
+
+	g, ok := ru.groups[key]
+	if !ok {
+		g = len(ru.open.totals)
+		ru.groups[key] = g
+		ru.open.totals = append(ru.open.totals, rollupTotals{product: key.product, currency: r.currency})
+	}
+
+	t := &ru.open.totals[g].totals
+	t.amounts = t.amounts.add(totals.amounts)
+	t.quantity += totals.quantity
+	t.sales += totals.sales
+}
+
+// addSketches учитывает продажу r в статистике открытого интервала.
+func (ru *rollup) addSketches(r *row) {
+	ru.open.sketches.AddProduct(r.product)
+
+	if ru.ticket.open && (r.receipt == "" || r.receipt != ru.ticket.receipt) {
+		ru.flushTicket()
+	}
+
+	ru.ticket.receipt = r.receipt
+	ru.ticket.currency = r.currency
+	ru.ticket.gross += r.amounts.gross
+	ru.ticket.open = true
+}
+
+// flushTicket учитывает накопленный чек в статистике открытого интервала.
+func (ru *rollup) flushTicket() {
+	if !ru.ticket.open {
+		return
+	}
+
+	ru.open.sketches.AddTicket(ru.ticket.currency, ticketSize(ru.ticket.gross))
+	ru.ticket = ticket{}
+}
+
+// end возвращает конец интервала, начинающегося в start (не включается).
+func (ru *rollup) end(start int64) int64 {
+	return addSaturated(start, ru.step)
+}
+
+// run возвращает индексы [i, j) закрытых интервалов, которые целиком лежат в периоде [start, end]
+// и видны в версии count. Интервалы идут подряд, поэтому их продажи - продажи с индексами
+// [closed[i].first, closed[j-1].last).
+func (ru *rollup) run(start, end int64, count int) (int, int) {
+	i := sort.Search(len(ru.closed), func(k int) bool {
+		return ru.closed[k].start >= start
+	})
+
+	j := sort.Search(len(ru.closed), func(k int) bool {
+		return ru.end(ru.closed[k].start)-1 > end || ru.closed[k].last > count
+	})
+
+	if j < i {
+		j = i
+	}
+
+	return i, j
+}
+
+// cover разбивает период [start, end] на закрытые интервалы сводки, видимые в версии count и целиком лежащие
+// в периоде, и края периода: вызывает whole для каждого такого интервала и edge для частей периода до и после них,
+// в которых могут быть продажи. Если таких интервалов нет, edge вызывается для всего периода.
+func (ru *rollup) cover(start, end int64, count int, edge func(from, to int64) error, whole func(b *rollupBucket) error) error {
+	i, j := ru.run(start, end, count)
+	if i == j {
+		return edge(start, end)
+	}
+
+	// продажи до первого целого интервала есть, только если предыдущий интервал захватывает начало периода
+	if i > 0 && ru.end(ru.closed[i-1].start) > start {
+		if err := edge(start, ru.closed[i].start-1); err != nil {
+			return err
+		}
+	}
+
+	for k := i; k < j; k++ {
+		if err := whole(&ru.closed[k]); err != nil {
+			return err
+		}
+	}
+
+	// продажи после последнего целого интервала: их нет, если следующий видимый интервал начинается после периода
+	last := ru.closed[j-1]
+	if last.last >= count || (j < len(ru.closed) && ru.closed[j].last <= count && ru.closed[j].start > end) {
+		return nil
+	}
+
+	return edge(ru.end(last.start), end)
+}
+
+// ticketSize возвращает размер чека с валовой суммой gross (с фиксированной точкой) в денежных единицах.
+func ticketSize(gross int64) float64 {
+	return fromFixed(gross, amountScale).InexactFloat64()
+}
+
+// chainRollup сводка продаж всех магазинов хранилища по часам в разрезе валют. Продажи разных магазинов
+// поступают не по порядку времени, поэтому сводка изменяется на месте под блокировкой и отражает только
+// текущее состояние хранилища: чтение в снимке ее не использует.
+type chainRollup struct {
+	hours []chainHour // по возрастанию начала часа
+	mu    sync.RWMutex
+}
+
+// chainHour показатели продаж сети за час, начинающийся в start, по валютам.
+type chainHour struct {
+	start  int64
+	totals map[string]*queryTotals
+}
+
+// add учитывает продажу с временной меткой timestamp.
+func (c *chainRollup) add(timestamp int64, currency string, totals queryTotals) {
+	start := floorTimestamp(timestamp, rollupHour)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// продажи обычно приходят в текущий час, поэтому вставка в середину редка
+	k := sort.Search(len(c.hours), func(k int) bool { return c.hours[k].start >= start })
+	if k == len(c.hours) || c.hours[k].start != start {
+		c.hours = append(c.hours, chainHour{})
+		copy(c.hours[k+1:], c.hours[k:])
+		c.hours[k] = chainHour{start: start, totals: make(map[string]*queryTotals)}
+	}
+
+	t, ok := c.hours[k].totals[currency]
+	if !ok {
+		t = &queryTotals{}
+		c.hours[k].totals[currency] = t
+	}
+
+	t.amounts = t.amounts.add(totals.amounts)
+	t.quantity += totals.quantity
+	t.sales += totals.sales
+}
+
+// subtract вычитает из сводки показатели сводки other - продаж, удаленных из хранилища (см. SalesStorage.Purge).
+// Валюты и часы, в которых не осталось продаж, удаляются.
+func (c *chainRollup) subtract(other *chainRollup) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, hour := range other.hours {
+		k := sort.Search(len(c.hours), func(k int) bool { return c.hours[k].start >= hour.start })
+		if k == len(c.hours) || c.hours[k].start != hour.start {
+			continue
+		}
+
+		for currency, sub := range hour.totals {
+			t, ok := c.hours[k].totals[currency]
+			if !ok {
+				continue
+			}
+
+			t.amounts = t.amounts.sub(sub.amounts)
+			t.quantity -= sub.quantity
+			t.sales -= sub.sales
+
+			if t.sales <= 0 {
+				delete(c.hours[k].totals, currency)
+			}
+		}
+	}
+
+	hours := c.hours[:0]
+	for _, hour := range c.hours {
+		if len(hour.totals) > 0 {
+			hours = append(hours, hour)
+		}
+	}
+
+	c.hours = hours
+}
+
+// sum вызывает fn с показателями продаж по валютам за часы, начинающиеся в [from, to).
+func (c *chainRollup) sum(from, to int64, fn func(currency string, totals queryTotals) error) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	totals := make(map[string]queryTotals)
+
+	k := sort.Search(len(c.hours), func(k int) bool { return c.hours[k].start >= from })
+	for ; k < len(c.hours) && c.hours[k].start < to; k++ {
+		for currency, t := range c.hours[k].totals {
+			sum := totals[currency]
+			sum.amounts = sum.amounts.add(t.amounts)
+			sum.quantity += t.quantity
+			sum.sales += t.sales
+			totals[currency] = sum
+		}
+	}
+
+	for currency, t := range totals {
+		if err := fn(currency, t); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// hourCover возвращает целые часы [from, to), лежащие в периоде [start, end]; false - таких часов нет.
+func hourCover(start, end int64) (int64, int64, bool) {
+	from := floorTimestamp(start, rollupHour)
+	if from < start {
+		from = addSaturated(from, rollupHour)
+	}
+
+	to := floorTimestamp(end, rollupHour)
+	if addSaturated(to, rollupHour)-1 <= end {
+		to = addSaturated(to, rollupHour)
+	}
+
+	return from, to, from < to
+}
+
+// floorTimestamp возвращает начало интервала длины step, содержащего момент t (интервалы отсчитываются
+// от начала unix-времени).
+func floorTimestamp(t, step int64) int64 {
+	rem := t % step
+	if rem < 0 {
+		rem += step
+	}
+
+	if t-rem > t {
+		return math.MinInt64
+	}
+
+	return t - rem
+}
+
+// addSaturated складывает моменты, ограничивая результат максимальным int64.
+func addSaturated(t, d int64) int64 {
+	if t > math.MaxInt64-d {
+		return math.MaxInt64
+	}
+
+	return t + d
+}
@@ -1,48 +1,77 @@
 package storage
 
 import (
+	"context"
+	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/shopspring/decimal"
-
 	"go.dataflow.ru/service-sales/internal/app/domain"
 	"go.dataflow.ru/service-sales/pkg/logger"
 )
 
 const (
 	defaultIndexGranularity = 10
-	missedIndex             = -1
 )
 
-// SaleExt определяет тип для продаж, расширенный кумулятивной суммой продаж.
-type SaleExt struct {
-	*domain.Sale
-	CumulativeSum decimal.Decimal
-}
-
 // SalesStorage хранилище для работы с продажами.
+//
+// Запись в разные магазины выполняется параллельно: каждый магазин защищен собственной блокировкой.
+// Чтение не блокируется вовсе: после каждой записи магазин публикует снимок продаж (см. storeSales.snapshot),
+// и запрос читает последний опубликованный снимок.
 type SalesStorage struct {
-	salesByStore map[string][]SaleExt
+	// магазины по идентификатору; map не изменяется, при добавлении магазина публикуется новая копия
+	stores   atomic.Pointer[map[string]*store]
+	storesMu sync.Mutex
 
-	sparseIndex      map[string][]time.Time // разреженный индекс для хранения временных меток продаж
 	indexGranularity int64
 
-	mu sync.RWMutex
+	// продажи старше retention вытесняются в сегменты в каталоге segmentsDir (0 - не вытесняются)
+	retention   time.Duration
+	segmentsDir string
+	evictMu     sync.Mutex
+
+	// сводка продаж всех магазинов по часам
+	chain chainRollup
+
+	// открытые снимки по токену
+	snapshots    map[string]*Snapshot
+	snapshotTTL  time.Duration
+	maxSnapshots int
+	snapshotsMu  sync.Mutex
+
+	now func() time.Time
 
 	logger *logger.Logger
 }
 
+// store продажи магазина: состояние для записи под блокировкой и снимок для чтения.
+type store struct {
+	mu    sync.Mutex
+	sales *storeSales // доступно только под mu
+
+	published atomic.Pointer[storeSales]
+}
+
+// publish публикует снимок продаж для чтения. Вызывается под блокировкой магазина.
+func (st *store) publish() {
+	st.published.Store(st.sales.snapshot())
+}
+
 // New возвращает новый экземпляр хранилища.
 func New(logger *logger.Logger, opts ...Option) *SalesStorage {
 	s := &SalesStorage{
-		salesByStore:     make(map[string][]SaleExt),
-		sparseIndex:      make(map[string][]time.Time),
-		mu:               sync.RWMutex{},
 		logger:           logger,
 		indexGranularity: defaultIndexGranularity,
+		snapshots:        make(map[string]*Snapshot),
+		snapshotTTL:      defaultSnapshotTTL,
+		maxSnapshots:     defaultMaxSnapshots,
+		now:              time.Now,
 	}
 
+	s.stores.Store(&map[string]*store{})
+
 	for _, opt := range opts {
 		opt(s)
 	}
@@ -51,140 +80,435 @@ func New(logger *logger.Logger, opts ...Option) *SalesStorage {
 }
 
 // AddSale сохраняет информацию о продаже.
-func (s *SalesStorage) AddSale(sale *domain.Sale) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+func (s *SalesStorage) AddSale(sale *domain.Sale) error {
+	st := s.store(sale.StoreID)
 
-	sales := s.salesByStore[sale.StoreID]
+	st.mu.Lock()
+	defer st.mu.Unlock()
 
-	// сохраняем разреженный индекс, если необходимо
-	if len(sales)%defaultIndexGranularity == 0 {
-		s.sparseIndex[sale.StoreID] = append(s.sparseIndex[sale.StoreID], sale.SaleDate)
+	n := st.sales.version
+
+	if err := s.appendSale(st.sales, sale); err != nil {
+		return err
+	}
+
+	s.addRollups(st.sales, n)
+	st.publish()
+
+	return nil
+}
+
+// InsertSale сохраняет продажу, которая может быть раньше последней продажи магазина, например подтвержденную
+// продажу из карантина. Продажа не раньше последней дописывается, как в AddSale. Иначе магазин пересобирается
+// со вставленной продажей, как при удалении продаж (см. Purge): версии магазина начинаются заново в новом
+// поколении журнала, а открытые снимки закрываются. Пересборка читает все продажи магазина, в том числе
+// вытесненные, поэтому вставка предназначена для редких продаж.
+func (s *SalesStorage) InsertSale(sale *domain.Sale) error {
+	// вытеснение не выполняется одновременно с пересборкой: оно читает снимок магазина без блокировки
+	s.evictMu.Lock()
+	defer s.evictMu.Unlock()
+
+	st := s.store(sale.StoreID)
+	st.mu.Lock()
+
+	old := st.sales
+	ts := unixNano(sale.SaleDate)
+
+	if old.version == 0 || ts >= old.lastTimestamp {
+		defer st.mu.Unlock()
+
+		n := old.version
+
+		if err := s.appendSale(old, sale); err != nil {
+			return err
+		}
+
+		s.addRollups(old, n)
+		st.publish()
+
+		return nil
 	}
 
-	// считаем кумулятивную сумму продаж для текущей продажи
-	cumulativeSum := decimal.NewFromFloat(0)
-	if len(sales) > 0 {
-		cumulativeSum = sales[len(sales)-1].CumulativeSum
+	r := s.reader(old, -1)
+
+	// продажа вставляется после продаж с той же временной меткой: строки чека остаются соседними
+	i, err := r.upperBound(ts)
+	if err != nil {
+		st.mu.Unlock()
+		return err
 	}
 
-	cumulativeSum = cumulativeSum.Add(decimal.NewFromInt(sale.QuantitySold).Mul(sale.SalePrice))
+	sales, err := r.salesRange(0, r.count)
+	if err != nil {
+		st.mu.Unlock()
+		return err
+	}
 
-	// сохраняем информацию о продаже и кумулятивной сумме продаж
-	s.salesByStore[sale.StoreID] = append(s.salesByStore[sale.StoreID], SaleExt{
-		Sale:          sale,
-		CumulativeSum: cumulativeSum,
-	})
+	sales = append(sales[:i], append([]*domain.Sale{sale}, sales[i:]...)...)
+
+	rebuilt, err := s.rebuildSales(old, sales)
+	if err != nil {
+		st.mu.Unlock()
+		return err
+	}
+
+	st.sales = rebuilt
+	st.publish()
+
+	// сводки магазина построены заново, а в сводке сети учитывается только вставленная продажа
+	row := rebuilt.row(i)
+	s.chain.add(row.timestamp, row.currency, queryTotals{amounts: row.amounts, quantity: row.quantity, sales: 1})
+
+	st.mu.Unlock()
+
+	// версии снимков относятся к продажам до пересборки
+	s.closeSnapshots()
+
+	s.logger.Infof("inserted sale of %s into store %s at %d of %d", sale.SaleDate, old.id, i, len(sales))
+
+	return s.replaceSegments(st, old)
 }
 
-// GetSales возвращает данные о всех продажах.
-func (s *SalesStorage) GetSales() []*domain.Sale {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+// AddReceipt атомарно сохраняет продажи строк чека: при ошибке не сохраняется ни одна строка, а чтение видит
+// либо весь чек, либо ни одной его строки. Строки чека занимают соседние индексы продаж магазина.
+func (s *SalesStorage) AddReceipt(receipt *domain.Receipt) error {
+	st := s.store(receipt.StoreID)
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
 
-	salesCount := 0
-	for _, storeSales := range s.salesByStore {
-		salesCount += len(storeSales)
+	if st.sales.receiptIDs.contains(receipt.ID) {
+		return fmt.Errorf("receipt %q: %w", receipt.ID, domain.ErrReceiptExists)
 	}
 
-	sales := make([]*domain.Sale, 0, salesCount)
+	return s.appendSales(st, receipt.Sales())
+}
+
+// AddSales атомарно сохраняет продажи одного магазина, как AddReceipt строки чека. Ведомый экземпляр применяет
+// так пачку журнала ведущего (см. services.ReplicationService): чтение не видит чек, примененный частично.
+func (s *SalesStorage) AddSales(sales []*domain.Sale) error {
+	if len(sales) == 0 {
+		return nil
+	}
 
-	for _, storeSales := range s.salesByStore {
-		for _, s := range storeSales {
-			sales = append(sales, s.Sale)
+	storeID := sales[0].StoreID
+	for _, sale := range sales {
+		if sale.StoreID != storeID {
+			return fmt.Errorf("sales of stores %s and %s in one batch", storeID, sale.StoreID)
 		}
 	}
 
-	return sales
+	st := s.store(storeID)
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	return s.appendSales(st, sales)
 }
 
-// GetTotalSum возвращает сумму продаж магазина за период.
-func (s *SalesStorage) GetTotalSum(storeID string, startDate, endDate time.Time) decimal.Decimal {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+// appendSales добавляет продажи в магазин и публикует их одним снимком. При ошибке не добавляется ни одна продажа.
+// Вызывается под блокировкой магазина.
+func (s *SalesStorage) appendSales(st *store, sales []*domain.Sale) error {
+	// колонки только дописываются, поэтому копия продаж до добавления - точка отката
+	prev := st.sales.snapshot()
 
-	sales := s.salesByStore[storeID]
+	for _, sale := range sales {
+		if err := s.appendSale(st.sales, sale); err != nil {
+			st.sales.rollback(prev)
 
-	if startDate.After(endDate) || len(sales) == 0 {
-		return decimal.NewFromFloat(0)
+			return err
+		}
 	}
 
-	// обрабатываем корнер-кейсы, когда запрошенный диапазон не пересекается с периодом, за который есть продажи
-	if sales[0].SaleDate.After(endDate) {
-		return decimal.NewFromFloat(0)
+	// сводки обновляются, когда сохранены все продажи: при откате обновлять их не нужно
+	for i := prev.version; i < st.sales.version; i++ {
+		s.addRollups(st.sales, i)
 	}
 
-	if sales[len(sales)-1].SaleDate.Before(startDate) {
-		return decimal.NewFromFloat(0)
+	st.publish()
+
+	return nil
+}
+
+// appendSale добавляет продажу в колонки магазина и, если необходимо, в разреженный индекс.
+// Вызывается под блокировкой магазина.
+func (s *SalesStorage) appendSale(store *storeSales, sale *domain.Sale) error {
+	n := store.version
+
+	if err := store.append(sale); err != nil {
+		return err
 	}
 
-	// считаем сумму продаж за период
-	firstSaleIdx := s.findSaleIdx(storeID, startDate)
-	lastSaleIdx := s.findSaleIdx(storeID, endDate)
+	// сохраняем разреженный индекс, если необходимо
+	if int64(n)%s.indexGranularity == 0 {
+		store.sparseIndex = append(store.sparseIndex, sale.SaleDate.UnixNano())
+	}
 
-	firstSaleCumulativeSum := decimal.NewFromFloat(0)
-	if firstSaleIdx > 0 {
-		firstSaleCumulativeSum = sales[firstSaleIdx-1].CumulativeSum
+	return nil
+}
+
+// addRollups учитывает добавленную продажу магазина с индексом i в сводках магазина и сети.
+// Вызывается под блокировкой магазина.
+func (s *SalesStorage) addRollups(store *storeSales, i int) {
+	row, totals := store.addRollups(i)
+	s.chain.add(row.timestamp, row.currency, totals)
+}
+
+// store возвращает магазин, создавая его при первой продаже.
+func (s *SalesStorage) store(storeID string) *store {
+	if st, ok := (*s.stores.Load())[storeID]; ok {
+		return st
 	}
 
-	lastSaleCumulativeSum := sales[len(sales)-1].CumulativeSum
-	if lastSaleIdx < int64(len(sales)-1) && lastSaleIdx >= 0 {
-		lastSaleCumulativeSum = sales[lastSaleIdx].CumulativeSum
+	s.storesMu.Lock()
+	defer s.storesMu.Unlock()
+
+	stores := *s.stores.Load()
+	if st, ok := stores[storeID]; ok {
+		return st
+	}
+
+	st := &store{sales: newStoreSales(storeID)}
+	st.publish()
+
+	next := make(map[string]*store, len(stores)+1)
+	for id, other := range stores {
+		next[id] = other
 	}
 
-	return lastSaleCumulativeSum.Sub(firstSaleCumulativeSum)
+	next[storeID] = st
+	s.stores.Store(&next)
+
+	return st
 }
 
-// findSaleIdx возвращает индекс продажи, ближайшей справа на временной оси к заданному времени dt.
-// Если продажа не найдена - возвращает missedIndex.
-func (s *SalesStorage) findSaleIdx(storeID string, dt time.Time) int64 {
-	sales := s.salesByStore[storeID]
+// view возвращает последний опубликованный снимок продаж магазина или nil, если продаж магазина нет.
+func (s *SalesStorage) view(storeID string) *storeSales {
+	st, ok := (*s.stores.Load())[storeID]
+	if !ok {
+		return nil
+	}
+
+	return st.published.Load()
+}
 
-	granuleHeadIdx := s.findGranuleHeadIdx(storeID, dt)
+// views возвращает снимки продаж всех магазинов.
+func (s *SalesStorage) views() []*storeSales {
+	stores := *s.stores.Load()
 
-	granuleTailIdx := granuleHeadIdx + s.indexGranularity // "хвост" не входит в гранулу
-	if int64(len(sales)) < granuleTailIdx {
-		granuleTailIdx = int64(len(sales))
+	views := make([]*storeSales, 0, len(stores))
+	for _, st := range stores {
+		views = append(views, st.published.Load())
 	}
 
-	// ищем продажу перебором по всей грануле.
-	for i := granuleHeadIdx; i < granuleTailIdx; i++ {
-		if sales[i].SaleDate.After(dt) || sales[i].SaleDate == dt {
-			return i
+	return views
+}
+
+// GetSales возвращает данные о всех продажах, в том числе вытесненных на диск.
+func (s *SalesStorage) GetSales() ([]*domain.Sale, error) {
+	var sales []*domain.Sale
+
+	for _, store := range s.views() {
+		storeSales, err := s.reader(store, -1).sales()
+		if err != nil {
+			return nil, err
 		}
+
+		sales = append(sales, storeSales...)
 	}
 
-	return missedIndex
+	return sales, nil
 }
 
-// findGranuleHeadIdx возвращает стартовый индекс гранулы, внутри которой находится искомая продажа.
-func (s *SalesStorage) findGranuleHeadIdx(storeID string, dt time.Time) int64 {
-	for i, t := range s.sparseIndex[storeID] {
-		if t.After(dt) {
-			if i == 0 {
-				return 0
-			}
+// ScanSales передает fn продажи всех магазинов, в том числе вытесненные на диск, по магазинам и гранулам.
+func (s *SalesStorage) ScanSales(ctx context.Context, fn func(sale *domain.Sale) error) error {
+	for _, store := range s.views() {
+		if err := s.reader(store, -1).scanSales(ctx, fn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetTotalSum возвращает суммы продаж магазина за период (границы включаются) в разрезе валют.
+func (s *SalesStorage) GetTotalSum(storeID string, startDate, endDate time.Time) (domain.Totals, error) {
+	return s.reader(s.view(storeID), -1).totalSum(startDate, endDate)
+}
+
+// GetTotalSumByProduct возвращает суммы продаж магазина за период (границы включаются) в разрезе товаров и валют.
+func (s *SalesStorage) GetTotalSumByProduct(storeID string, startDate, endDate time.Time) (map[string]domain.Totals, error) {
+	return s.reader(s.view(storeID), -1).totalSumByProduct(startDate, endDate)
+}
+
+// GetTotalSumByDimension возвращает суммы продаж магазина за период (границы включаются) в разрезе значений
+// измерения dimension и валют. Продажи без значения измерения относятся к группе domain.Unspecified.
+func (s *SalesStorage) GetTotalSumByDimension(storeID, dimension string, startDate, endDate time.Time) (map[string]domain.Totals, error) {
+	return s.reader(s.view(storeID), -1).totalSumByDimension(dimension, startDate, endDate)
+}
+
+// GetReceiptTotals возвращает показатели чеков магазина за период (границы включаются) в разрезе валют.
+func (s *SalesStorage) GetReceiptTotals(storeID string, startDate, endDate time.Time) (domain.ReceiptTotals, error) {
+	return s.reader(s.view(storeID), -1).receiptTotals(startDate, endDate)
+}
+
+// GetSketches возвращает приближенную статистику продаж магазина за период (границы включаются).
+func (s *SalesStorage) GetSketches(storeID string, startDate, endDate time.Time) (*domain.SalesSketches, error) {
+	return s.reader(s.view(storeID), -1).sketches(startDate, endDate)
+}
+
+// Query выполняет запрос агрегатов продаж всех магазинов.
+func (s *SalesStorage) Query(q domain.Query) (*domain.QueryResult, error) {
+	plan := planQuery(&q, true)
+	res := newQueryResult(&q)
+
+	if plan.rollup == rollupChainHourly {
+		if err := s.queryChain(res); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, view := range s.views() {
+		if err := s.reader(view, -1).query(view.id, plan, res); err != nil {
+			return nil, fmt.Errorf("query sales of store %s: %w", view.id, err)
+		}
+	}
+
+	return res.result(plan), nil
+}
+
+// GetTotalSums возвращает суммы продаж нескольких магазинов, каждого за свой период, в порядке запросов.
+func (s *SalesStorage) GetTotalSums(queries []domain.StorePeriod) ([]domain.Totals, error) {
+	return totalSums(s, queries)
+}
+
+// Evict вытесняет на диск продажи старше горизонта хранения. Вытесняются только целые гранулы,
+// поэтому в памяти может остаться часть продаж старше горизонта.
+func (s *SalesStorage) Evict(now time.Time) error {
+	if s.retention <= 0 {
+		return nil
+	}
+
+	s.evictMu.Lock()
+	defer s.evictMu.Unlock()
+
+	for id, st := range *s.stores.Load() {
+		if err := s.evictStore(st, now.Add(-s.retention)); err != nil {
+			return fmt.Errorf("evict sales of store %s: %w", id, err)
+		}
+	}
+
+	return nil
+}
+
+// evictStore вытесняет на диск гранулы магазина, все продажи которых раньше cutoff.
+// Продажи только дописываются в конец, поэтому вытесняемые гранулы не меняются: они выбираются
+// по снимку, и сегмент записывается без блокировки магазина.
+func (s *SalesStorage) evictStore(st *store, cutoff time.Time) error {
+	view := st.published.Load()
+
+	sales, cumulative, dimensions, err := s.evictable(view, cutoff)
+	if err != nil || len(sales) == 0 {
+		return err
+	}
+
+	path := segmentPath(s.segmentsDir, view.id, view.generation, len(view.evicted)*int(s.indexGranularity))
+
+	refs, err := writeSegment(path, sales, int(s.indexGranularity), cumulative, dimensions)
+	if err != nil {
+		return err
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.sales.evicted = append(st.sales.evicted, refs...)
+	st.sales.trim(len(sales))
+	st.publish()
+
+	s.logger.Debugf("evicted %d sales of store %s to %s", len(sales), view.id, path)
+
+	return nil
+}
+
+// evictable возвращает продажи в памяти, которые можно вытеснить (целые гранулы до cutoff),
+// и кумулятивные суммы по валютам и измерениям до начала каждой из этих гранул.
+func (s *SalesStorage) evictable(store *storeSales, cutoff time.Time) ([]*domain.Sale, []fixedTotals, [][]fixedAmounts, error) {
+	granularity := int(s.indexGranularity)
+
+	r := s.reader(store, -1)
+
+	keep, err := r.lowerBound(unixNano(cutoff))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// вытесняются только целые гранулы
+	keep = keep / granularity * granularity
 
-			return int64(i-1) * defaultIndexGranularity
+	evicted := r.evicted()
+	if keep <= evicted {
+		return nil, nil, nil, nil
+	}
+
+	cumulative := make([]fixedTotals, 0, (keep-evicted)/granularity)
+	dimensions := make([][]fixedAmounts, 0, (keep-evicted)/granularity)
+
+	for head := evicted; head < keep; head += granularity {
+		totals, err := r.prefix(head - 1)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		sums := make([]fixedAmounts, len(store.dimensionSums))
+		for k := range sums {
+			if sums[k], err = r.dimensionPrefix(k, head-1); err != nil {
+				return nil, nil, nil, err
+			}
 		}
+
+		cumulative = append(cumulative, totals)
+		dimensions = append(dimensions, sums)
+	}
+
+	sales := make([]*domain.Sale, 0, keep-evicted)
+	for i := 0; i < keep-evicted; i++ {
+		sales = append(sales, store.sale(i))
 	}
 
-	// продажа с искомой временной меткой в последней грануле или не существует
-	return int64(len(s.sparseIndex[storeID])-1) * defaultIndexGranularity
+	return sales, cumulative, dimensions, nil
 }
 
-// GetTotalSumSimple возвращает сумму продаж магазина за период простым перебором (для сравнения).
-func (s *SalesStorage) GetTotalSumSimple(storeID string, startDate, endDate time.Time) decimal.Decimal {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+// GetTotalSumSimple возвращает суммы продаж магазина за период простым перебором (для сравнения).
+func (s *SalesStorage) GetTotalSumSimple(storeID string, startDate, endDate time.Time) (domain.Totals, error) {
+	totals := make(fixedTotals)
+
+	store := s.view(storeID)
+	if store == nil {
+		return totals.totals(), nil
+	}
+
+	start, end := unixNano(startDate), unixNano(endDate)
 
-	sum := decimal.NewFromFloat(0)
+	err := s.reader(store, -1).scan(0, store.version, func(row row) {
+		if row.timestamp >= start && row.timestamp <= end {
+			if _, ok := totals[row.currency]; !ok {
+				// как и GetTotalSum, возвращаем все валюты магазина, если в период попала хотя бы одна продажа
+				for _, currency := range store.currencyCodes {
+					totals.add(currency, fixedAmounts{})
+				}
+			}
 
-	for _, sale := range s.salesByStore[storeID] {
-		if (sale.SaleDate.After(startDate) || sale.SaleDate == startDate) && (sale.SaleDate.Before(endDate) || sale.SaleDate == endDate) {
-			sum = sum.Add(decimal.NewFromInt(sale.QuantitySold).Mul(sale.SalePrice))
+			totals.add(row.currency, row.amounts)
 		}
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return sum
+	return totals.totals(), nil
+}
+
+func (s *SalesStorage) reader(store *storeSales, version int) *reader {
+	return newReader(store, int(s.indexGranularity), version)
 }
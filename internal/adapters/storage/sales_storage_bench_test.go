@@ -0,0 +1,237 @@
+package storage
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+	"go.dataflow.ru/service-sales/pkg/logger"
+)
+
+const benchSales = 1000000
+
+// saleExt прежнее представление продажи в памяти: указатель на продажу и кумулятивная сумма (для сравнения).
+type saleExt struct {
+	sale          *domain.Sale
+	cumulativeSum decimal.Decimal
+}
+
+// saleExtStorage прежнее хранилище продаж магазина: массив saleExt с разреженным индексом.
+type saleExtStorage struct {
+	sales       []saleExt
+	sparseIndex []time.Time
+	granularity int
+}
+
+func (s *saleExtStorage) AddSale(sale *domain.Sale) {
+	if len(s.sales)%s.granularity == 0 {
+		s.sparseIndex = append(s.sparseIndex, sale.SaleDate)
+	}
+
+	sum := sale.Amounts().Gross
+	if len(s.sales) > 0 {
+		sum = sum.Add(s.sales[len(s.sales)-1].cumulativeSum)
+	}
+
+	s.sales = append(s.sales, saleExt{sale: sale, cumulativeSum: sum})
+}
+
+func (s *saleExtStorage) GetTotalSum(startDate, endDate time.Time) decimal.Decimal {
+	first := s.search(func(t time.Time) bool { return !t.Before(startDate) })
+	last := s.search(func(t time.Time) bool { return t.After(endDate) })
+
+	if first >= last {
+		return decimal.Zero
+	}
+
+	if first == 0 {
+		return s.sales[last-1].cumulativeSum
+	}
+
+	return s.sales[last-1].cumulativeSum.Sub(s.sales[first-1].cumulativeSum)
+}
+
+func (s *saleExtStorage) search(found func(t time.Time) bool) int {
+	granule := sort.Search(len(s.sparseIndex), func(i int) bool { return found(s.sparseIndex[i]) })
+	if granule == 0 {
+		return 0
+	}
+
+	head := (granule - 1) * s.granularity
+
+	tail := head + s.granularity
+	if len(s.sales) < tail {
+		tail = len(s.sales)
+	}
+
+	for i := head; i < tail; i++ {
+		if found(s.sales[i].sale.SaleDate) {
+			return i
+		}
+	}
+
+	return tail
+}
+
+func benchSale(i int) *domain.Sale {
+	return &domain.Sale{
+		StoreID:      "store_1",
+		ProductID:    fmt.Sprintf("product_%d", i%10000),
+		QuantitySold: int64(i%5 + 1),
+		SalePrice:    decimal.New(int64(i%100000+1), -2),
+		Currency:     "RUB",
+		SaleDate:     time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(i) * time.Second),
+	}
+}
+
+// heapInUse возвращает объем кучи после сборки мусора.
+func heapInUse() uint64 {
+	runtime.GC()
+
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+
+	return m.HeapAlloc
+}
+
+func BenchmarkSalesStorage_Memory(b *testing.B) {
+	b.Run("columnar", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			before := heapInUse()
+
+			s := New(logger.NoOpLogger(), WithIndexGranularity(1000))
+			for i := 0; i < benchSales; i++ {
+				_ = s.AddSale(benchSale(i))
+			}
+
+			b.ReportMetric(float64(heapInUse()-before)/benchSales, "bytes/sale")
+			runtime.KeepAlive(s)
+		}
+	})
+
+	b.Run("sale_ext", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			before := heapInUse()
+
+			s := &saleExtStorage{granularity: 1000}
+			for i := 0; i < benchSales; i++ {
+				s.AddSale(benchSale(i))
+			}
+
+			b.ReportMetric(float64(heapInUse()-before)/benchSales, "bytes/sale")
+			runtime.KeepAlive(s)
+		}
+	})
+}
+
+func BenchmarkSalesStorage_GetTotalSum(b *testing.B) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// периоды разной длины со случайными границами внутри данных
+	period := func(n int) (time.Time, time.Time) {
+		from := time.Duration(n*7919%benchSales) * time.Second
+		length := time.Duration(n*104729%benchSales) * time.Second
+
+		return start.Add(from), start.Add(from + length)
+	}
+
+	b.Run("columnar", func(b *testing.B) {
+		s := New(logger.NoOpLogger(), WithIndexGranularity(1000))
+		for i := 0; i < benchSales; i++ {
+			_ = s.AddSale(benchSale(i))
+		}
+
+		b.ReportAllocs()
+		b.ResetTimer()
+
+		for n := 0; n < b.N; n++ {
+			from, to := period(n)
+			_, _ = s.GetTotalSum("store_1", from, to)
+		}
+	})
+
+	b.Run("sale_ext", func(b *testing.B) {
+		s := &saleExtStorage{granularity: 1000}
+		for i := 0; i < benchSales; i++ {
+			s.AddSale(benchSale(i))
+		}
+
+		b.ReportAllocs()
+		b.ResetTimer()
+
+		for n := 0; n < b.N; n++ {
+			s.GetTotalSum(period(n))
+		}
+	})
+}
+
+// globalLockStorage хранилище под одной блокировкой на все магазины, как до разделения блокировок (для сравнения).
+type globalLockStorage struct {
+	s  *SalesStorage
+	mu sync.RWMutex
+}
+
+func (g *globalLockStorage) AddSale(sale *domain.Sale) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.s.AddSale(sale)
+}
+
+func (g *globalLockStorage) GetTotalSum(storeID string, startDate, endDate time.Time) (domain.Totals, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	return g.s.GetTotalSum(storeID, startDate, endDate)
+}
+
+// BenchmarkSalesStorage_MixedLoad пропускная способность при параллельной записи и чтении:
+// на каждую запись приходится 9 запросов суммы продаж, запросы распределены по 8 магазинам.
+func BenchmarkSalesStorage_MixedLoad(b *testing.B) {
+	type storage interface {
+		AddSale(sale *domain.Sale) error
+		GetTotalSum(storeID string, startDate, endDate time.Time) (domain.Totals, error)
+	}
+
+	const stores = 8
+
+	run := func(b *testing.B, s storage) {
+		start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+		var seq atomic.Int64
+
+		b.ReportAllocs()
+		b.ResetTimer()
+
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				n := seq.Add(1)
+				storeID := fmt.Sprintf("store_%d", n%stores)
+
+				if n%10 == 0 {
+					sale := benchSale(int(n))
+					sale.StoreID = storeID
+
+					_ = s.AddSale(sale)
+				} else {
+					_, _ = s.GetTotalSum(storeID, start, start.Add(time.Duration(n)*time.Second))
+				}
+			}
+		})
+	}
+
+	b.Run("per_store", func(b *testing.B) {
+		run(b, New(logger.NoOpLogger(), WithIndexGranularity(1000)))
+	})
+
+	b.Run("global_lock", func(b *testing.B) {
+		run(b, &globalLockStorage{s: New(logger.NoOpLogger(), WithIndexGranularity(1000))})
+	})
+}
@@ -1,7 +1,10 @@
 package storage
 
 import (
+	"context"
 	"fmt"
+	"runtime/debug"
+	"sort"
 	"testing"
 	"time"
 
@@ -20,98 +23,111 @@ func TestSalesStorage_GetTotal(t *testing.T) {
 	// первая продажа 2024-05-01, последняя продажа 2024-08-06, шаг 1 день
 	dt := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
 	for i := 0; i < 98; i++ {
-		s.AddSale(&domain.Sale{
+		assert.NoError(t, s.AddSale(&domain.Sale{
 			StoreID:      "store_1",
 			ProductID:    fmt.Sprintf("product_%d", i),
 			QuantitySold: 1,
 			SalePrice:    decimal.NewFromFloat(1 + float64(i)),
+			Currency:     "RUB",
 			SaleDate:     dt.AddDate(0, 0, i),
-		})
+		}))
 	}
 
 	testCases := []struct {
 		name     string
 		dateFrom time.Time
 		dateTo   time.Time
-		expSum   decimal.Decimal
+		expGross map[string]string
 	}{
 		{
 			name:     "dateFrom > dateTo",
 			dateFrom: time.Date(2024, 6, 20, 0, 0, 0, 0, time.UTC),
 			dateTo:   time.Date(2024, 6, 19, 0, 0, 0, 0, time.UTC),
-			expSum:   decimal.NewFromFloat(0),
+			expGross: map[string]string{},
 		},
 		{
 			name:     "dateFrom и dateTo левее всех продаж",
 			dateFrom: time.Date(2024, 4, 29, 0, 0, 0, 0, time.UTC),
 			dateTo:   time.Date(2024, 4, 30, 0, 0, 0, 0, time.UTC),
-			expSum:   decimal.NewFromFloat(0),
+			expGross: map[string]string{},
 		},
 		{
 			name:     "dateFrom левее всех продаж, dateTo попадает на первую продажу",
 			dateFrom: time.Date(2024, 4, 30, 0, 0, 0, 0, time.UTC),
 			dateTo:   time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
-			expSum:   decimal.NewFromFloat(1),
+			expGross: map[string]string{"RUB": "1"},
 		},
 		{
 			name:     "dateFrom левее всех продаж, dateTo попадает на первую продажу",
 			dateFrom: time.Date(2024, 4, 30, 0, 0, 0, 0, time.UTC),
 			dateTo:   time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
-			expSum:   decimal.NewFromFloat(1),
+			expGross: map[string]string{"RUB": "1"},
 		},
 		{
 			name:     "dateFrom левее всех продаж, dateTo попадает на вторую продажу",
 			dateFrom: time.Date(2024, 4, 30, 0, 0, 0, 0, time.UTC),
 			dateTo:   time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC),
-			expSum:   decimal.NewFromFloat(3),
+			expGross: map[string]string{"RUB": "3"},
 		},
 		{
 			name:     "dateFrom левее всех продаж, dateTo попадает на последнюю продажу",
 			dateFrom: time.Date(2024, 4, 30, 0, 0, 0, 0, time.UTC),
 			dateTo:   time.Date(2024, 8, 6, 0, 0, 0, 0, time.UTC),
-			expSum:   decimal.NewFromFloat(4851),
+			expGross: map[string]string{"RUB": "4851"},
 		},
 		{
 			name:     "dateFrom левее всех продаж, dateTo правее последней продажи",
 			dateFrom: time.Date(2024, 4, 30, 0, 0, 0, 0, time.UTC),
 			dateTo:   time.Date(2024, 8, 7, 0, 0, 0, 0, time.UTC),
-			expSum:   decimal.NewFromFloat(4851),
+			expGross: map[string]string{"RUB": "4851"},
 		},
 		{
 			name:     "dateFrom на первой продаже, dateTo на первой продаже",
 			dateFrom: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
 			dateTo:   time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
-			expSum:   decimal.NewFromFloat(1),
+			expGross: map[string]string{"RUB": "1"},
 		},
 		{
 			name:     "dateFrom на первой продаже, dateTo на третьей продаже",
 			dateFrom: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
 			dateTo:   time.Date(2024, 5, 3, 0, 0, 0, 0, time.UTC),
-			expSum:   decimal.NewFromFloat(6),
+			expGross: map[string]string{"RUB": "6"},
 		},
 		{
 			name:     "dateFrom на предпоследней продаже, dateTo на последней продаже",
 			dateFrom: time.Date(2024, 8, 5, 0, 0, 0, 0, time.UTC),
 			dateTo:   time.Date(2024, 8, 6, 0, 0, 0, 0, time.UTC),
-			expSum:   decimal.NewFromFloat(195),
+			expGross: map[string]string{"RUB": "195"},
 		},
 		{
 			name:     "dateFrom на последней продаже, dateTo правее всех продаж",
 			dateFrom: time.Date(2024, 8, 6, 0, 0, 0, 0, time.UTC),
 			dateTo:   time.Date(2024, 8, 7, 0, 0, 0, 0, time.UTC),
-			expSum:   decimal.NewFromFloat(98),
+			expGross: map[string]string{"RUB": "98"},
 		},
 		{
 			name:     "dateFrom и dateTo правее всех продаж",
 			dateFrom: time.Date(2024, 8, 7, 0, 0, 0, 0, time.UTC),
 			dateTo:   time.Date(2024, 8, 8, 0, 0, 0, 0, time.UTC),
-			expSum:   decimal.NewFromFloat(0),
+			expGross: map[string]string{},
+		},
+		{
+			name:     "dateFrom и dateTo между продажами",
+			dateFrom: time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC),
+			dateTo:   time.Date(2024, 5, 12, 12, 0, 0, 0, time.UTC),
+			expGross: map[string]string{"RUB": "23"},
+		},
+		{
+			name:     "dateFrom и dateTo между соседними продажами",
+			dateFrom: time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC),
+			dateTo:   time.Date(2024, 5, 10, 13, 0, 0, 0, time.UTC),
+			expGross: map[string]string{},
 		},
 		{
 			name:     "dateFrom на первой продаже, dateTo на последней продаже",
 			dateFrom: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
 			dateTo:   time.Date(2024, 8, 6, 0, 0, 0, 0, time.UTC),
-			expSum:   decimal.NewFromFloat(4851),
+			expGross: map[string]string{"RUB": "4851"},
 		},
 	}
 
@@ -119,41 +135,93 @@ func TestSalesStorage_GetTotal(t *testing.T) {
 		tt := tt
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
-			x := s.GetTotalSum("store_1", tt.dateFrom, tt.dateTo)
-			assert.Equal(t, tt.expSum, x)
+			x, err := s.GetTotalSum("store_1", tt.dateFrom, tt.dateTo)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expGross, gross(x))
 		})
 	}
 }
 
+func TestSalesStorage_GetTotal_Currencies(t *testing.T) {
+	t.Parallel()
+
+	s := New(logger.NoOpLogger(), WithIndexGranularity(2))
+
+	dt := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	currencies := []string{"RUB", "RUB", "KZT", "RUB", "BYN", "KZT", "RUB"}
+
+	for i, currency := range currencies {
+		assert.NoError(t, s.AddSale(&domain.Sale{
+			StoreID:      "store_1",
+			ProductID:    fmt.Sprintf("product_%d", i),
+			QuantitySold: 2,
+			SalePrice:    decimal.NewFromInt(int64(10 * (i + 1))),
+			Currency:     currency,
+			SaleDate:     dt.AddDate(0, 0, i),
+		}))
+	}
+
+	// все продажи
+	totals, err := s.GetTotalSum("store_1", dt, dt.AddDate(0, 0, 7))
+	assert.NoError(t, err)
+	assert.Equal(t, "280", totals["RUB"].Gross.String())
+	assert.Equal(t, "180", totals["KZT"].Gross.String())
+	assert.Equal(t, "100", totals["BYN"].Gross.String())
+
+	// продажи с 3-й по 5-ю: BYN встречается только внутри периода, RUB - и до, и после
+	totals, err = s.GetTotalSum("store_1", dt.AddDate(0, 0, 2), dt.AddDate(0, 0, 4))
+	assert.NoError(t, err)
+	assert.Equal(t, "80", totals["RUB"].Gross.String())
+	assert.Equal(t, "60", totals["KZT"].Gross.String())
+	assert.Equal(t, "100", totals["BYN"].Gross.String())
+
+	totalSimple, err := s.GetTotalSumSimple("store_1", dt.AddDate(0, 0, 1), dt.AddDate(0, 0, 5))
+	assert.NoError(t, err)
+
+	totals, err = s.GetTotalSum("store_1", dt.AddDate(0, 0, 1), dt.AddDate(0, 0, 5))
+	assert.NoError(t, err)
+	assert.Equal(t, gross(totalSimple), gross(totals))
+}
+
 func TestSalesStorage_GetTotal_Performance(t *testing.T) {
+	if testing.Short() {
+		t.Skip("10 млн продаж добавляются минуты, а под -race не укладываются в таймаут")
+	}
+
 	t.Parallel()
 
+	// 10 млн продаж занимают несколько гигабайт: ограничиваем рост кучи, чтобы тест укладывался в память CI
+	defer debug.SetMemoryLimit(debug.SetMemoryLimit(4 << 30))
+
 	s := New(logger.NoOpLogger(), WithIndexGranularity(1000))
 
 	dateFrom := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
 	dateTo := dateFrom
 	for i := 0; i < 10000000; i++ {
-		s.AddSale(&domain.Sale{
+		assert.NoError(t, s.AddSale(&domain.Sale{
 			StoreID:      "store_1",
 			ProductID:    fmt.Sprintf("product_%d", i),
 			QuantitySold: 1,
 			SalePrice:    decimal.NewFromFloat(1 + float64(i)),
+			Currency:     "RUB",
 			SaleDate:     dateFrom.Add(time.Duration(i) * time.Second),
-		})
+		}))
 
 		dateTo = dateTo.Add(time.Duration(i) * time.Second)
 	}
 
 	durationSimpleStart := time.Now()
-	totalSimple := s.GetTotalSumSimple("store_1", dateFrom, dateTo)
+	totalSimple, err := s.GetTotalSumSimple("store_1", dateFrom, dateTo)
 	durationSimple := time.Since(durationSimpleStart)
+	assert.NoError(t, err)
 
 	durationOptimizedStart := time.Now()
-	totalOptimized := s.GetTotalSum("store_1", dateFrom, dateTo)
+	totalOptimized, err := s.GetTotalSum("store_1", dateFrom, dateTo)
 	durationOptimized := time.Since(durationOptimizedStart)
+	assert.NoError(t, err)
 
 	t.Logf("performance: simple=%s, optimized=%s", durationSimple, durationOptimized)
-	assert.Equal(t, totalSimple, totalOptimized)
+	assert.Equal(t, gross(totalSimple), gross(totalOptimized))
 }
 
 func TestSalesStorage_AddGetSale(t *testing.T) {
@@ -177,8 +245,846 @@ func TestSalesStorage_AddGetSale(t *testing.T) {
 		SaleDate:     time.Date(2024, 6, 2, 10, 0, 0, 0, time.UTC),
 	}
 
-	s.AddSale(s1)
-	s.AddSale(s2)
+	assert.NoError(t, s.AddSale(s1))
+	assert.NoError(t, s.AddSale(s2))
+
+	// продажа с ценой, не представимой с фиксированной точкой, хранится целиком
+	s3 := &domain.Sale{
+		StoreID:      "store_1",
+		ProductID:    "product_1",
+		QuantitySold: 100000,
+		SalePrice:    decimal.RequireFromString("0.00001"),
+		Discount:     decimal.RequireFromString("0.5"),
+		VATRate:      decimal.NewFromInt(20),
+		Currency:     "RUB",
+		SaleDate:     time.Date(2024, 6, 3, 10, 0, 0, 0, time.UTC),
+	}
+
+	assert.NoError(t, s.AddSale(s3))
+
+	// суммы вне диапазона отклоняются
+	assert.ErrorIs(t, s.AddSale(&domain.Sale{
+		StoreID:      "store_1",
+		ProductID:    "product_1",
+		QuantitySold: 1,
+		SalePrice:    decimal.RequireFromString("1e20"),
+		SaleDate:     time.Date(2024, 6, 4, 10, 0, 0, 0, time.UTC),
+	}), domain.ErrSaleOutOfRange)
+
+	// продажи магазина дописываются по времени: продажа раньше последней отклоняется
+	assert.ErrorIs(t, s.AddSale(&domain.Sale{
+		StoreID:      "store_1",
+		ProductID:    "product_1",
+		QuantitySold: 1,
+		SalePrice:    decimal.NewFromInt(1),
+		SaleDate:     time.Date(2024, 6, 2, 10, 0, 0, 0, time.UTC),
+	}), domain.ErrSaleOutOfRange)
+
+	sales, err := s.GetSales()
+	assert.NoError(t, err)
+	assert.Len(t, sales, 3)
+
+	for i, expected := range []*domain.Sale{s1, s2, s3} {
+		assert.Equal(t, expected.StoreID, sales[i].StoreID)
+		assert.Equal(t, expected.ProductID, sales[i].ProductID)
+		assert.Equal(t, expected.QuantitySold, sales[i].QuantitySold)
+		assert.True(t, expected.SalePrice.Equal(sales[i].SalePrice))
+		assert.True(t, expected.Discount.Equal(sales[i].Discount))
+		assert.True(t, expected.VATRate.Equal(sales[i].VATRate))
+		assert.Equal(t, expected.Currency, sales[i].Currency)
+		assert.True(t, expected.SaleDate.Equal(sales[i].SaleDate))
+	}
+}
+
+func TestSalesStorage_GetTotal_Amounts(t *testing.T) {
+	t.Parallel()
+
+	s := New(logger.NoOpLogger())
+
+	dt := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	assert.NoError(t, s.AddSale(&domain.Sale{
+		StoreID:      "store_1",
+		ProductID:    "product_1",
+		QuantitySold: 3,
+		SalePrice:    decimal.RequireFromString("33.33"),
+		Discount:     decimal.RequireFromString("9.99"),
+		VATRate:      decimal.NewFromInt(20),
+		Currency:     "RUB",
+		SaleDate:     dt,
+	}))
+	assert.NoError(t, s.AddSale(&domain.Sale{
+		StoreID:      "store_1",
+		ProductID:    "product_2",
+		QuantitySold: 1,
+		SalePrice:    decimal.RequireFromString("110"),
+		VATRate:      decimal.NewFromInt(10),
+		Currency:     "RUB",
+		SaleDate:     dt.Add(time.Hour),
+	}))
+
+	totals, err := s.GetTotalSum("store_1", dt, dt.Add(time.Hour))
+	assert.NoError(t, err)
+
+	rub := totals["RUB"]
+	assert.Equal(t, "209.99", rub.Gross.String())
+	assert.Equal(t, "9.99", rub.Discount.String())
+	assert.Equal(t, "25", rub.Tax.String())
+	assert.Equal(t, "175", rub.Net.String())
+}
+
+// gross возвращает валовые суммы продаж по валютам в строковом виде для сравнения в тестах.
+func gross(totals domain.Totals) map[string]string {
+	res := make(map[string]string, len(totals))
+	for currency, amounts := range totals {
+		res[currency] = amounts.Gross.String()
+	}
+
+	return res
+}
+
+func TestSalesStorage_Evict(t *testing.T) {
+	t.Parallel()
+
+	s := New(logger.NoOpLogger(), WithIndexGranularity(3), WithRetention(10*24*time.Hour, t.TempDir()))
+
+	dt := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	currencies := []string{"RUB", "KZT", "RUB", "RUB", "BYN"}
+
+	addSales := func(from, to int) {
+		for i := from; i < to; i++ {
+			assert.NoError(t, s.AddSale(&domain.Sale{
+				StoreID:      "store_1",
+				ProductID:    fmt.Sprintf("product_%d", i%4),
+				QuantitySold: int64(i%3 + 1),
+				SalePrice:    decimal.NewFromInt(int64(10 * (i + 1))),
+				Discount:     decimal.NewFromInt(int64(i % 2)),
+				VATRate:      decimal.NewFromInt(20),
+				Currency:     currencies[i%len(currencies)],
+				SaleDate:     dt.AddDate(0, 0, i),
+			}))
+		}
+	}
+
+	// суммы за все периоды, границы которых попадают на продажи и между ними
+	totals := func() map[string]map[string]string {
+		res := make(map[string]map[string]string)
+
+		for from := -1; from < 30; from++ {
+			for to := from; to < 30; to++ {
+				startDate, endDate := dt.AddDate(0, 0, from).Add(time.Hour), dt.AddDate(0, 0, to)
+
+				x, err := s.GetTotalSum("store_1", startDate, endDate)
+				assert.NoError(t, err)
+
+				res[fmt.Sprintf("%d-%d", from, to)] = amounts(x)
+			}
+		}
+
+		return res
+	}
+
+	addSales(0, 20)
+	before := totals()
+
+	byProductBefore, err := s.GetTotalSumByProduct("store_1", dt.AddDate(0, 0, 2), dt.AddDate(0, 0, 15))
+	assert.NoError(t, err)
+
+	// горизонт - 10 дней: 12 продаж до 13 мая старше горизонта и вытесняются 4 целыми гранулами
+	assert.NoError(t, s.Evict(dt.AddDate(0, 0, 22)))
+	assert.Len(t, s.view("store_1").evicted, 4)
+	assert.Equal(t, 8, s.view("store_1").len())
+
+	assert.Equal(t, before, totals())
+
+	byProduct, err := s.GetTotalSumByProduct("store_1", dt.AddDate(0, 0, 2), dt.AddDate(0, 0, 15))
+	assert.NoError(t, err)
+	for productID, x := range byProductBefore {
+		assert.Equal(t, amounts(x), amounts(byProduct[productID]))
+	}
+
+	// повторное вытеснение дописывает новый сегмент; из 20 продаж старше горизонта вытесняются 18 (целые гранулы)
+	addSales(20, 30)
+	before = totals()
+
+	assert.NoError(t, s.Evict(dt.AddDate(0, 0, 30)))
+	assert.Len(t, s.view("store_1").evicted, 6)
+	assert.Equal(t, before, totals())
+
+	// порядок продаж проверяется и по вытесненным продажам
+	assert.ErrorIs(t, s.AddSale(&domain.Sale{StoreID: "store_1", SaleDate: dt}), domain.ErrSaleOutOfRange)
+
+	sales, err := s.GetSales()
+	assert.NoError(t, err)
+	assert.Len(t, sales, 30)
+
+	for i, sale := range sales {
+		assert.Equal(t, "store_1", sale.StoreID)
+		assert.Equal(t, fmt.Sprintf("product_%d", i%4), sale.ProductID)
+		assert.True(t, dt.AddDate(0, 0, i).Equal(sale.SaleDate))
+		assert.Equal(t, int64(10*(i+1)), sale.SalePrice.IntPart())
+		assert.Equal(t, currencies[i%len(currencies)], sale.Currency)
+	}
+
+	// чтение по гранулам возвращает те же продажи и прерывается отменой контекста
+	var scanned []*domain.Sale
+
+	ctx, cancel := context.WithCancel(context.Background())
+	assert.NoError(t, s.ScanSales(ctx, func(sale *domain.Sale) error {
+		scanned = append(scanned, sale)
+		return nil
+	}))
+	assert.Equal(t, sales, scanned)
+
+	scanned = nil
+	err = s.ScanSales(ctx, func(sale *domain.Sale) error {
+		if scanned = append(scanned, sale); len(scanned) == 4 {
+			cancel()
+		}
+
+		return nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Len(t, scanned, 6, "гранула дочитывается до конца")
+}
+
+func TestSalesStorage_AddReceipt(t *testing.T) {
+	t.Parallel()
+
+	s := New(logger.NoOpLogger(), WithIndexGranularity(3), WithRetention(10*24*time.Hour, t.TempDir()))
+
+	dt := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	sale := func(day int) *domain.Sale {
+		return &domain.Sale{
+			StoreID:      "store_1",
+			ProductID:    "bread",
+			QuantitySold: 1,
+			SalePrice:    decimal.NewFromInt(50),
+			Currency:     "RUB",
+			SaleDate:     dt.AddDate(0, 0, day),
+		}
+	}
+
+	receipt := func(id string, day int, currency string, quantities ...int64) *domain.Receipt {
+		r := &domain.Receipt{
+			ID:            id,
+			StoreID:       "store_1",
+			PaymentMethod: "card",
+			Currency:      currency,
+			SaleDate:      dt.AddDate(0, 0, day),
+		}
+
+		for i, quantity := range quantities {
+			r.Lines = append(r.Lines, domain.ReceiptLine{
+				ProductID:    fmt.Sprintf("product_%d", i),
+				QuantitySold: quantity,
+				SalePrice:    decimal.NewFromInt(10),
+			})
+		}
+
+		return r
+	}
+
+	assert.NoError(t, s.AddSale(sale(0)))
+	assert.NoError(t, s.AddReceipt(receipt("r1", 0, "RUB", 1, 2, 3)))
+	assert.NoError(t, s.AddReceipt(receipt("r2", 1, "RUB", 4)))
+	assert.NoError(t, s.AddSale(sale(1)))
+	assert.NoError(t, s.AddReceipt(receipt("r3", 2, "KZT", 1, 1)))
+
+	assert.ErrorIs(t, s.AddReceipt(receipt("r1", 3, "RUB", 1)), domain.ErrReceiptExists)
+
+	// строка с непредставимой суммой отменяет весь чек, и чек можно сохранить повторно
+	invalid := receipt("r4", 25, "RUB", 1, 1)
+	invalid.Lines[1].SalePrice = decimal.New(1, 30)
+
+	assert.ErrorIs(t, s.AddReceipt(invalid), domain.ErrSaleOutOfRange)
+	assert.Equal(t, 8, s.view("store_1").version)
+
+	assert.NoError(t, s.AddReceipt(receipt("r4", 25, "RUB", 1, 1)))
+
+	stats := func() map[string]string {
+		totals, err := s.GetReceiptTotals("store_1", dt, dt.AddDate(0, 0, 2))
+		assert.NoError(t, err)
+
+		res := make(map[string]string, len(totals))
+		for currency, x := range totals {
+			res[currency] = fmt.Sprintf("%d/%d/%d/%s", x.Receipts, x.Lines, x.Items, x.Amounts.Gross)
+		}
+
+		return res
+	}
+
+	before := stats()
+	assert.Equal(t, map[string]string{"RUB": "2/4/10/100", "KZT": "1/2/2/20"}, before)
+
+	// вытесненные на диск строки сохраняют чек и способ оплаты
+	assert.NoError(t, s.Evict(dt.AddDate(0, 0, 20)))
+	assert.Len(t, s.view("store_1").evicted, 2)
+	assert.Equal(t, before, stats())
+
+	sales, err := s.GetSales()
+	assert.NoError(t, err)
+	assert.Len(t, sales, 10)
+
+	receipts := make([]string, 0, len(sales))
+	for _, sale := range sales {
+		receipts = append(receipts, sale.ReceiptID)
+
+		if sale.ReceiptID != "" {
+			assert.Equal(t, "card", sale.PaymentMethod)
+		}
+	}
+
+	assert.Equal(t, []string{"", "r1", "r1", "r1", "r2", "", "r3", "r3", "r4", "r4"}, receipts)
+
+	// строки отмененного чека не попадают в сводку дня, закрытого следующей продажей
+	assert.NoError(t, s.AddSale(sale(26)))
+
+	queryRes, err := s.Query(domain.Query{
+		StartDate: dt,
+		EndDate:   dt.AddDate(0, 0, 26).Add(-time.Nanosecond),
+		Metrics:   []string{domain.MetricGross, domain.MetricQuantity, domain.MetricSales},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"map[currency:KZT] map[gross:20 quantity:2 sales:2]",
+		"map[currency:RUB] map[gross:220 quantity:14 sales:8]",
+	}, queryRows(queryRes.Rows))
+}
+
+func TestSalesStorage_GetSketches(t *testing.T) {
+	t.Parallel()
+
+	s := New(logger.NoOpLogger(), WithIndexGranularity(3), WithRetention(10*24*time.Hour, t.TempDir()))
+
+	dt := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	// каждый день - чек из двух строк и продажа вне чека; валюта чередуется по дням
+	for day := 0; day < 14; day++ {
+		currency := []string{"RUB", "KZT"}[day%2]
+
+		assert.NoError(t, s.AddReceipt(&domain.Receipt{
+			ID:       fmt.Sprintf("r%d", day),
+			StoreID:  "store_1",
+			Currency: currency,
+			SaleDate: dt.AddDate(0, 0, day).Add(9 * time.Hour),
+			Lines: []domain.ReceiptLine{
+				{ProductID: fmt.Sprintf("product_%d", day%5), QuantitySold: 1, SalePrice: decimal.NewFromInt(int64(day + 1))},
+				{ProductID: fmt.Sprintf("product_%d", (day+1)%5), QuantitySold: 2, SalePrice: decimal.NewFromInt(10)},
+			},
+		}))
+
+		assert.NoError(t, s.AddSale(&domain.Sale{
+			StoreID:      "store_1",
+			ProductID:    fmt.Sprintf("product_%d", 5+day%3),
+			QuantitySold: 1,
+			SalePrice:    decimal.NewFromInt(int64(100 * (day + 1))),
+			Currency:     currency,
+			SaleDate:     dt.AddDate(0, 0, day).Add(18 * time.Hour),
+		}))
+	}
+
+	assert.Len(t, s.view("store_1").dailyTotals.closed, 13)
+
+	// статистика по всем продажам периода: соседние строки одного чека - один чек
+	bruteForce := func(startDate, endDate time.Time) (uint64, map[string][]float64) {
+		sales, err := s.GetSales()
+		assert.NoError(t, err)
+
+		products := make(map[string]bool)
+		tickets := make(map[string][]float64)
+		prev := ""
+
+		for _, sale := range sales {
+			if sale.SaleDate.Before(startDate) || sale.SaleDate.After(endDate) {
+				continue
+			}
+
+			products[sale.ProductID] = true
+
+			gross, _ := sale.Amounts().Gross.Float64()
+			if sale.ReceiptID != "" && sale.ReceiptID == prev {
+				sizes := tickets[sale.Currency]
+				sizes[len(sizes)-1] += gross
+			} else {
+				tickets[sale.Currency] = append(tickets[sale.Currency], gross)
+			}
+
+			prev = sale.ReceiptID
+		}
+
+		return uint64(len(products)), tickets
+	}
+
+	check := func() {
+		for from := -1; from < 30; from++ {
+			for to := from; to < 30; to++ {
+				startDate, endDate := dt.Add(time.Duration(from)*12*time.Hour), dt.Add(time.Duration(to)*12*time.Hour)
+
+				sketches, err := s.GetSketches("store_1", startDate, endDate)
+				assert.NoError(t, err)
+
+				products, tickets := bruteForce(startDate, endDate)
+				period := fmt.Sprintf("%d-%d", from, to)
+
+				assert.Equal(t, products, sketches.Products.Estimate(), period)
+				assert.Len(t, sketches.Tickets, len(tickets), period)
+
+				for currency, sizes := range tickets {
+					assert.Equal(t, int64(len(sizes)), sketches.Tickets[currency].Count(), period)
+					assert.Equal(t, median(sizes), sketches.Tickets[currency].Quantile(0.5), period)
+				}
+			}
+		}
+	}
+
+	check()
+
+	// вытесненные продажи учитываются в краях периодов перебором сегментов
+	assert.NoError(t, s.Evict(dt.AddDate(0, 0, 16)))
+	assert.NotEmpty(t, s.view("store_1").evicted)
+
+	check()
+
+	// статистика снимка не видит продаж, добавленных после него
+	snapshot, err := s.OpenSnapshot()
+	assert.NoError(t, err)
+
+	sn, err := s.Snapshot(snapshot.Token)
+	assert.NoError(t, err)
+
+	assert.NoError(t, s.AddSale(&domain.Sale{
+		StoreID:      "store_1",
+		ProductID:    "product_new",
+		QuantitySold: 1,
+		SalePrice:    decimal.NewFromInt(1),
+		Currency:     "RUB",
+		SaleDate:     dt.AddDate(0, 0, 20),
+	}))
+
+	current, err := s.GetSketches("store_1", dt, dt.AddDate(0, 0, 21))
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(9), current.Products.Estimate())
+
+	snapshotted, err := sn.GetSketches("store_1", dt, dt.AddDate(0, 0, 21))
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(8), snapshotted.Products.Estimate())
+	assert.Equal(t, current.Tickets["RUB"].Count()-1, snapshotted.Tickets["RUB"].Count())
+}
+
+// median возвращает медиану значений так же, как t-digest с небольшим количеством значений:
+// интерполяцией между соседними значениями.
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	if len(sorted)%2 == 1 {
+		return sorted[len(sorted)/2]
+	}
+
+	return (sorted[len(sorted)/2-1] + sorted[len(sorted)/2]) / 2
+}
+
+func TestSalesStorage_GetTotalSumByDimension(t *testing.T) {
+	t.Parallel()
+
+	s := New(logger.NoOpLogger(), WithIndexGranularity(3), WithRetention(10*24*time.Hour, t.TempDir()))
+
+	dt := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	currencies := []string{"RUB", "KZT", "RUB"}
+	payments := []string{domain.PaymentCash, "", domain.PaymentCard, domain.PaymentCard, domain.PaymentVoucher}
+	channels := []string{domain.ChannelInStore, domain.ChannelOnline, ""}
+
+	addSales := func(from, to int) {
+		for i := from; i < to; i++ {
+			sale := &domain.Sale{
+				StoreID:      "store_1",
+				ProductID:    fmt.Sprintf("product_%d", i%4),
+				QuantitySold: int64(i%3 + 1),
+				SalePrice:    decimal.NewFromInt(int64(10 * (i + 1))),
+				Discount:     decimal.NewFromInt(int64(i % 2)),
+				VATRate:      decimal.NewFromInt(20),
+				Currency:     currencies[i%len(currencies)],
+				SaleDate:     dt.AddDate(0, 0, i),
+			}
+
+			// способы оплаты появляются не сразу: суммы новых значений создаются по ходу записи
+			if i >= 5 {
+				sale.PaymentMethod = payments[i%len(payments)]
+			}
+
+			sale.Channel = channels[i%len(channels)]
+
+			assert.NoError(t, s.AddSale(sale))
+		}
+	}
+
+	// суммы по измерению за все периоды, границы которых попадают на продажи и между ними, и суммы перебором
+	breakdowns := func(dimension string) (map[string]map[string]map[string]string, map[string]map[string]map[string]string) {
+		sales, err := s.GetSales()
+		assert.NoError(t, err)
+
+		res := make(map[string]map[string]map[string]string)
+		exp := make(map[string]map[string]map[string]string)
+
+		for from := -1; from < 30; from++ {
+			for to := from; to < 30; to++ {
+				startDate, endDate := dt.AddDate(0, 0, from).Add(time.Hour), dt.AddDate(0, 0, to)
+				key := fmt.Sprintf("%d-%d", from, to)
+
+				groups, err := s.GetTotalSumByDimension("store_1", dimension, startDate, endDate)
+				assert.NoError(t, err)
+
+				res[key] = make(map[string]map[string]string)
+				for value, x := range groups {
+					res[key][value] = amounts(x)
+				}
+
+				simple := make(map[string]domain.Totals)
+				for _, sale := range sales {
+					if sale.SaleDate.Before(startDate) || sale.SaleDate.After(endDate) {
+						continue
+					}
+
+					value := sale.Dimension(dimension)
+					if value == "" {
+						value = domain.Unspecified
+					}
+
+					if _, ok := simple[value]; !ok {
+						simple[value] = make(domain.Totals)
+					}
+
+					simple[value].Add(domain.Totals{sale.Currency: sale.Amounts()})
+				}
+
+				exp[key] = make(map[string]map[string]string)
+				for value, x := range simple {
+					exp[key][value] = amounts(x)
+				}
+			}
+		}
+
+		return res, exp
+	}
+
+	addSales(0, 20)
+
+	for _, dimension := range domain.Dimensions() {
+		res, exp := breakdowns(dimension)
+		assert.Equal(t, exp, res, dimension)
+	}
+
+	before, _ := breakdowns(domain.DimensionPaymentMethod)
+
+	// суммы измерений вытесненных гранул хранятся вместе с гранулами
+	assert.NoError(t, s.Evict(dt.AddDate(0, 0, 22)))
+	assert.Len(t, s.view("store_1").evicted, 4)
+
+	after, _ := breakdowns(domain.DimensionPaymentMethod)
+	assert.Equal(t, before, after)
+
+	addSales(20, 30)
+	assert.NoError(t, s.Evict(dt.AddDate(0, 0, 30)))
+
+	for _, dimension := range domain.Dimensions() {
+		res, exp := breakdowns(dimension)
+		assert.Equal(t, exp, res, dimension)
+	}
+}
+
+// amounts возвращает суммы продаж по валютам в строковом виде для сравнения в тестах.
+func amounts(totals domain.Totals) map[string]string {
+	res := make(map[string]string, len(totals))
+	for currency, a := range totals {
+		res[currency] = fmt.Sprintf("%s/%s/%s/%s", a.Gross, a.Discount, a.Tax, a.Net)
+	}
+
+	return res
+}
+
+func TestSalesStorage_Query(t *testing.T) {
+	t.Parallel()
+
+	s := New(logger.NoOpLogger(), WithIndexGranularity(4), WithRetention(10*24*time.Hour, t.TempDir()))
+
+	dt := time.Date(2024, 5, 20, 0, 0, 0, 0, time.UTC)
+	currencies := []string{"RUB", "KZT", "RUB"}
+	payments := []string{domain.PaymentCash, "", domain.PaymentCard, domain.PaymentCard, domain.PaymentVoucher}
+	channels := []string{domain.ChannelInStore, domain.ChannelOnline, ""}
+
+	for i := 0; i < 60; i++ {
+		assert.NoError(t, s.AddSale(&domain.Sale{
+			StoreID:       fmt.Sprintf("store_%d", i%2),
+			ProductID:     fmt.Sprintf("product_%d", i%4),
+			QuantitySold:  int64(i%3 + 1),
+			SalePrice:     decimal.NewFromInt(int64(10 * (i + 1))),
+			Discount:      decimal.NewFromInt(int64(i % 2)),
+			VATRate:       decimal.NewFromInt(20),
+			Currency:      currencies[i%len(currencies)],
+			SaleDate:      dt.Add(time.Duration(i) * 17 * time.Hour),
+			PaymentMethod: payments[i%len(payments)],
+			Channel:       channels[i%len(channels)],
+		}))
+	}
+
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		name   string
+		query  domain.Query
+		plan   string
+		rollup string
+	}{
+		{
+			name:   "по магазинам",
+			query:  domain.Query{GroupBy: []string{domain.FieldStore}, Metrics: []string{domain.MetricGross, domain.MetricNet}},
+			plan:   planRollup,
+			rollup: rollupStoreDaily,
+		},
+		{
+			name: "по способам оплаты магазина с разбивкой по неделям",
+			query: domain.Query{
+				Filters: map[string][]string{domain.FieldStore: {"store_1"}},
+				GroupBy: []string{domain.FieldPaymentMethod},
+				Metrics: []string{domain.MetricGross},
+				Bucket:  domain.BucketWeek,
+			},
+			plan: planCumulative,
+		},
+		{
+			name: "фильтр по способу оплаты с разбивкой по дням в поясе магазина",
+			query: domain.Query{
+				Filters:  map[string][]string{domain.FieldPaymentMethod: {domain.PaymentCard, domain.Unspecified}},
+				Metrics:  []string{domain.MetricGross, domain.MetricTax},
+				Bucket:   domain.BucketDay,
+				Location: moscow,
+			},
+			plan: planCumulative,
+		},
+		{
+			name:   "количество по товарам",
+			query:  domain.Query{GroupBy: []string{domain.FieldProduct}, Metrics: []string{domain.MetricQuantity, domain.MetricSales, domain.MetricGross}},
+			plan:   planRollup,
+			rollup: rollupProductDaily,
+		},
+		{
+			name: "по двум измерениям с разбивкой по месяцам",
+			query: domain.Query{
+				GroupBy: []string{domain.FieldPaymentMethod, domain.FieldChannel},
+				Metrics: []string{domain.MetricNet},
+				Bucket:  domain.BucketMonth,
+			},
+			plan: planScan,
+		},
+		{
+			name: "фильтр по каналу и группировка по способу оплаты",
+			query: domain.Query{
+				Filters: map[string][]string{domain.FieldChannel: {domain.ChannelOnline}},
+				GroupBy: []string{domain.FieldPaymentMethod, domain.FieldCurrency},
+				Metrics: []string{domain.MetricDiscount},
+				Bucket:  domain.BucketHour,
+			},
+			plan: planScan,
+		},
+		{
+			name: "сеть по валютам с разбивкой по дням в поясе магазина",
+			query: domain.Query{
+				Filters:  map[string][]string{domain.FieldCurrency: {"RUB"}},
+				Metrics:  []string{domain.MetricGross, domain.MetricSales},
+				Bucket:   domain.BucketDay,
+				Location: moscow,
+			},
+			plan:   planRollup,
+			rollup: rollupChainHourly,
+		},
+		{
+			name: "товары магазина по валютам с разбивкой по неделям",
+			query: domain.Query{
+				Filters: map[string][]string{domain.FieldStore: {"store_0"}, domain.FieldProduct: {"product_0", "product_2"}},
+				GroupBy: []string{domain.FieldCurrency},
+				Metrics: []string{domain.MetricNet, domain.MetricQuantity},
+				Bucket:  domain.BucketWeek,
+			},
+			plan:   planRollup,
+			rollup: rollupProductDaily,
+		},
+		{
+			name: "по магазинам с разбивкой по часам",
+			query: domain.Query{
+				GroupBy: []string{domain.FieldStore},
+				Metrics: []string{domain.MetricTax, domain.MetricSales},
+				Bucket:  domain.BucketHour,
+			},
+			plan:   planRollup,
+			rollup: rollupStoreDaily,
+		},
+	}
+
+	// периоды, границы которых попадают на продажи и между ними, и периоды из целых дней и часов
+	type period struct {
+		start, end time.Time
+	}
+
+	var periods []period
+
+	for _, from := range []int{-1, 0, 5, 13} {
+		for _, to := range []int{13, 30, 59} {
+			periods = append(periods, period{
+				start: dt.Add(time.Duration(from)*17*time.Hour + time.Hour),
+				end:   dt.Add(time.Duration(to) * 17 * time.Hour),
+			})
+		}
+	}
+
+	periods = append(periods,
+		period{start: dt, end: dt.AddDate(0, 0, 50).Add(-time.Nanosecond)},
+		period{start: dt.AddDate(0, 0, 3), end: dt.AddDate(0, 0, 30).Add(-time.Nanosecond)},
+		period{start: dt.Add(36 * time.Hour), end: dt.AddDate(0, 0, 40).Add(5*time.Hour + 30*time.Minute)},
+		period{start: dt.Add(-time.Hour), end: dt.Add(17*time.Hour - time.Nanosecond)},
+	)
+
+	// результаты запросов за периоды и результаты, посчитанные перебором продаж
+	results := func(q domain.Query) ([]string, []string) {
+		sales, err := s.GetSales()
+		assert.NoError(t, err)
+
+		var res, exp []string
+
+		for _, p := range periods {
+			q.StartDate, q.EndDate = p.start, p.end
+
+			queryRes, err := s.Query(q)
+			assert.NoError(t, err)
+
+			res = append(res, queryRows(queryRes.Rows)...)
+			exp = append(exp, queryRows(bruteForceQuery(&q, sales))...)
+		}
+
+		return res, exp
+	}
+
+	before := make([][]string, len(testCases))
+
+	for i, tt := range testCases {
+		res, exp := results(tt.query)
+		assert.NotEmpty(t, exp, tt.name)
+		assert.Equal(t, exp, res, tt.name)
+
+		queryRes, err := s.Query(tt.query)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.plan, queryRes.Plan, tt.name)
+		assert.Equal(t, tt.rollup, planQuery(&tt.query, true).rollup, tt.name)
+
+		before[i] = res
+	}
+
+	// кумулятивные суммы вытесненных гранул хранятся вместе с гранулами
+	assert.NoError(t, s.Evict(dt.AddDate(0, 0, 40)))
+	assert.NotEmpty(t, s.view("store_0").evicted)
+
+	for i, tt := range testCases {
+		res, _ := results(tt.query)
+		assert.Equal(t, before[i], res, tt.name)
+	}
+}
+
+// bruteForceQuery выполняет запрос перебором продаж.
+func bruteForceQuery(q *domain.Query, sales []*domain.Sale) []domain.QueryRow {
+	var rows []domain.QueryRow
+
+	for _, sale := range sales {
+		if sale.SaleDate.Before(q.StartDate) || sale.SaleDate.After(q.EndDate) {
+			continue
+		}
+
+		values := map[string]string{
+			domain.FieldStore:         sale.StoreID,
+			domain.FieldProduct:       sale.ProductID,
+			domain.FieldCurrency:      sale.Currency,
+			domain.FieldPaymentMethod: sale.PaymentMethod,
+			domain.FieldChannel:       sale.Channel,
+		}
+
+		for field, value := range values {
+			if value == "" {
+				values[field] = domain.Unspecified
+			}
+		}
+
+		matches := true
+
+		for field, allowed := range q.Filters {
+			found := false
+			for _, value := range allowed {
+				found = found || values[field] == value
+			}
+
+			matches = matches && found
+		}
+
+		if !matches {
+			continue
+		}
+
+		row := domain.QueryRow{
+			Group:   map[string]string{domain.FieldCurrency: sale.Currency},
+			Metrics: make(map[string]decimal.Decimal),
+		}
+
+		if q.Bucket != "" {
+			bucket := q.BucketStart(sale.SaleDate)
+			row.Bucket = &bucket
+		}
+
+		for _, field := range q.GroupBy {
+			row.Group[field] = values[field]
+		}
+
+		amounts := sale.Amounts()
+
+		for _, metric := range q.Metrics {
+			switch metric {
+			case domain.MetricGross:
+				row.Metrics[metric] = amounts.Gross
+			case domain.MetricDiscount:
+				row.Metrics[metric] = amounts.Discount
+			case domain.MetricTax:
+				row.Metrics[metric] = amounts.Tax
+			case domain.MetricNet:
+				row.Metrics[metric] = amounts.Net
+			case domain.MetricQuantity:
+				row.Metrics[metric] = decimal.NewFromInt(sale.QuantitySold)
+			case domain.MetricSales:
+				row.Metrics[metric] = decimal.NewFromInt(1)
+			}
+		}
+
+		rows = append(rows, row)
+	}
+
+	return domain.MergeQueryRows(rows)
+}
+
+// queryRows возвращает строки результата запроса в строковом виде для сравнения в тестах.
+func queryRows(rows []domain.QueryRow) []string {
+	res := make([]string, 0, len(rows))
+
+	for _, row := range rows {
+		s := fmt.Sprint(row.Group)
+		if row.Bucket != nil {
+			s = row.Bucket.UTC().Format(time.RFC3339) + " " + s
+		}
+
+		metrics := make(map[string]string, len(row.Metrics))
+		for metric, v := range row.Metrics {
+			metrics[metric] = v.String()
+		}
+
+		res = append(res, s+" "+fmt.Sprint(metrics))
+	}
 
-	assert.Equal(t, []*domain.Sale{s1, s2}, s.GetSales())
+	return res
 }
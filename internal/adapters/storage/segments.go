@@ -0,0 +1,203 @@
+package storage
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+)
+
+var errCorruptedSegment = errors.New("corrupted segment")
+
+// granuleRef гранула продаж, вытесненная на диск. В памяти остаются только ее расположение в файле сегмента
+// и кумулятивные суммы до начала гранулы, поэтому для подсчета суммы продаж за период с диска читаются
+// не более двух гранул на границах периода.
+type granuleRef struct {
+	path   string
+	offset int64
+	size   int64
+
+	cumulative fixedTotals // кумулятивные суммы продаж магазина по валютам до начала гранулы
+
+	// кумулятивные суммы измерений storeSales.dimensionSums до начала гранулы по индексу; суммы,
+	// появившиеся после вытеснения гранулы, до ее начала нулевые
+	dimensions []fixedAmounts
+}
+
+// writeSegment записывает продажи в файл сегмента гранулами по granularity продаж.
+// cumulative[i] и dimensions[i] - кумулятивные суммы по валютам и измерениям до начала i-й гранулы.
+func writeSegment(path string, sales []*domain.Sale, granularity int, cumulative []fixedTotals, dimensions [][]fixedAmounts) ([]granuleRef, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("create segments dir: %w", err)
+	}
+
+	var (
+		data []byte
+		refs = make([]granuleRef, 0, len(cumulative))
+	)
+
+	for g := range cumulative {
+		offset := len(data)
+
+		for _, sale := range sales[g*granularity : (g+1)*granularity] {
+			data = encodeSale(data, sale)
+		}
+
+		refs = append(refs, granuleRef{
+			path:       path,
+			offset:     int64(offset),
+			size:       int64(len(data) - offset),
+			cumulative: cumulative[g],
+			dimensions: dimensions[g],
+		})
+	}
+
+	// сегмент записывается целиком во временный файл, чтобы на диске не оставались частично записанные сегменты
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return nil, fmt.Errorf("write segment: %w", err)
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		return nil, fmt.Errorf("write segment: %w", err)
+	}
+
+	return refs, nil
+}
+
+// readGranule читает продажи гранулы магазина storeID с диска.
+func readGranule(storeID string, ref granuleRef) ([]*domain.Sale, error) {
+	f, err := os.Open(ref.path)
+	if err != nil {
+		return nil, fmt.Errorf("open segment: %w", err)
+	}
+	defer f.Close()
+
+	data := make([]byte, ref.size)
+	if _, err = f.ReadAt(data, ref.offset); err != nil {
+		return nil, fmt.Errorf("read segment %s: %w", ref.path, err)
+	}
+
+	var sales []*domain.Sale
+
+	for len(data) > 0 {
+		var sale *domain.Sale
+
+		sale, data, err = decodeSale(data)
+		if err != nil {
+			return nil, fmt.Errorf("read segment %s: %w", ref.path, err)
+		}
+
+		sale.StoreID = storeID
+		sales = append(sales, sale)
+	}
+
+	return sales, nil
+}
+
+// segmentPath возвращает путь к файлу сегмента поколения generation магазина, начинающегося с продажи
+// с индексом first. Индексы продаж пересобранного магазина начинаются заново, поэтому сегменты разных
+// поколений хранятся в разных файлах: чтение, начатое до пересборки, не попадает в сегмент нового поколения.
+func segmentPath(dir, storeID string, generation, first int) string {
+	return filepath.Join(dir, url.PathEscape(storeID), fmt.Sprintf("%d-%020d.seg", generation, first))
+}
+
+// encodeSale дописывает в buf компактное бинарное представление продажи (без магазина, он известен из сегмента).
+func encodeSale(buf []byte, sale *domain.Sale) []byte {
+	buf = binary.AppendVarint(buf, sale.SaleDate.UnixNano())
+	buf = appendString(buf, sale.ProductID)
+	buf = binary.AppendVarint(buf, sale.QuantitySold)
+	buf = appendString(buf, sale.SalePrice.String())
+	buf = appendString(buf, sale.Discount.String())
+	buf = appendString(buf, sale.VATRate.String())
+	buf = appendString(buf, sale.Currency)
+	buf = appendString(buf, sale.ReceiptID)
+	buf = appendString(buf, sale.PaymentMethod)
+	buf = appendString(buf, sale.Channel)
+
+	return buf
+}
+
+// decodeSale читает продажу, записанную encodeSale, и возвращает оставшиеся данные.
+func decodeSale(data []byte) (*domain.Sale, []byte, error) {
+	var (
+		sale = &domain.Sale{}
+		err  error
+		nano int64
+	)
+
+	if nano, data, err = readVarint(data); err != nil {
+		return nil, nil, err
+	}
+
+	sale.SaleDate = time.Unix(0, nano).UTC()
+
+	if sale.ProductID, data, err = readString(data); err != nil {
+		return nil, nil, err
+	}
+
+	if sale.QuantitySold, data, err = readVarint(data); err != nil {
+		return nil, nil, err
+	}
+
+	for _, d := range []*decimal.Decimal{&sale.SalePrice, &sale.Discount, &sale.VATRate} {
+		var s string
+
+		if s, data, err = readString(data); err != nil {
+			return nil, nil, err
+		}
+
+		if *d, err = decimal.NewFromString(s); err != nil {
+			return nil, nil, errCorruptedSegment
+		}
+	}
+
+	if sale.Currency, data, err = readString(data); err != nil {
+		return nil, nil, err
+	}
+
+	if sale.ReceiptID, data, err = readString(data); err != nil {
+		return nil, nil, err
+	}
+
+	if sale.PaymentMethod, data, err = readString(data); err != nil {
+		return nil, nil, err
+	}
+
+	if sale.Channel, data, err = readString(data); err != nil {
+		return nil, nil, err
+	}
+
+	return sale, data, nil
+}
+
+func appendString(buf []byte, s string) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(s)))
+
+	return append(buf, s...)
+}
+
+func readString(data []byte) (string, []byte, error) {
+	n, k := binary.Uvarint(data)
+	if k <= 0 || uint64(len(data)-k) < n {
+		return "", nil, errCorruptedSegment
+	}
+
+	return string(data[k : k+int(n)]), data[k+int(n):], nil
+}
+
+func readVarint(data []byte) (int64, []byte, error) {
+	v, k := binary.Varint(data)
+	if k <= 0 {
+		return 0, nil, errCorruptedSegment
+	}
+
+	return v, data[k:], nil
+}
@@ -0,0 +1,185 @@
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+	"go.dataflow.ru/service-sales/internal/app/ports"
+)
+
+const (
+	defaultSnapshotTTL  = 5 * time.Minute
+	defaultMaxSnapshots = 1000
+)
+
+// Snapshot чтение продаж в снимке. Продажи магазинов только дописываются, поэтому снимок хранит не копию продаж,
+// а лишь версию (количество продаж) каждого магазина на момент открытия: чтение в снимке видит первые
+// version продаж магазина. Снимок не удерживает память: продажи, вытесненные на диск после его открытия,
+// читаются из сегментов.
+type Snapshot struct {
+	s        *SalesStorage
+	versions map[string]int
+
+	expiresAt time.Time
+}
+
+// GetSales возвращает продажи, принятые до открытия снимка.
+func (sn *Snapshot) GetSales() ([]*domain.Sale, error) {
+	var sales []*domain.Sale
+
+	for storeID, version := range sn.versions {
+		storeSales, err := sn.reader(storeID, version).sales()
+		if err != nil {
+			return nil, err
+		}
+
+		sales = append(sales, storeSales...)
+	}
+
+	return sales, nil
+}
+
+// ScanSales передает fn продажи, принятые до открытия снимка, по магазинам и гранулам.
+func (sn *Snapshot) ScanSales(ctx context.Context, fn func(sale *domain.Sale) error) error {
+	for storeID, version := range sn.versions {
+		if err := sn.reader(storeID, version).scanSales(ctx, fn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetTotalSum возвращает суммы продаж магазина за период в разрезе валют в состоянии снимка.
+func (sn *Snapshot) GetTotalSum(storeID string, startDate, endDate time.Time) (domain.Totals, error) {
+	return sn.reader(storeID, sn.versions[storeID]).totalSum(startDate, endDate)
+}
+
+// GetTotalSumByProduct возвращает суммы продаж магазина за период в разрезе товаров и валют в состоянии снимка.
+func (sn *Snapshot) GetTotalSumByProduct(storeID string, startDate, endDate time.Time) (map[string]domain.Totals, error) {
+	return sn.reader(storeID, sn.versions[storeID]).totalSumByProduct(startDate, endDate)
+}
+
+// GetTotalSumByDimension возвращает суммы продаж магазина за период в разрезе значений измерения и валют
+// в состоянии снимка.
+func (sn *Snapshot) GetTotalSumByDimension(storeID, dimension string, startDate, endDate time.Time) (map[string]domain.Totals, error) {
+	return sn.reader(storeID, sn.versions[storeID]).totalSumByDimension(dimension, startDate, endDate)
+}
+
+// GetReceiptTotals возвращает показатели чеков магазина за период в разрезе валют в состоянии снимка.
+func (sn *Snapshot) GetReceiptTotals(storeID string, startDate, endDate time.Time) (domain.ReceiptTotals, error) {
+	return sn.reader(storeID, sn.versions[storeID]).receiptTotals(startDate, endDate)
+}
+
+// GetSketches возвращает приближенную статистику продаж магазина за период в состоянии снимка.
+func (sn *Snapshot) GetSketches(storeID string, startDate, endDate time.Time) (*domain.SalesSketches, error) {
+	return sn.reader(storeID, sn.versions[storeID]).sketches(startDate, endDate)
+}
+
+// Query выполняет запрос агрегатов продаж всех магазинов в состоянии снимка.
+func (sn *Snapshot) Query(q domain.Query) (*domain.QueryResult, error) {
+	plan := planQuery(&q, false)
+	res := newQueryResult(&q)
+
+	for storeID, version := range sn.versions {
+		if err := sn.reader(storeID, version).query(storeID, plan, res); err != nil {
+			return nil, fmt.Errorf("query sales of store %s: %w", storeID, err)
+		}
+	}
+
+	return res.result(plan), nil
+}
+
+// GetTotalSums возвращает суммы продаж нескольких магазинов в состоянии снимка, каждого за свой период.
+func (sn *Snapshot) GetTotalSums(queries []domain.StorePeriod) ([]domain.Totals, error) {
+	return totalSums(sn, queries)
+}
+
+func (sn *Snapshot) reader(storeID string, version int) *reader {
+	return sn.s.reader(sn.s.view(storeID), version)
+}
+
+// OpenSnapshot открывает снимок: все продажи, добавленные до вызова (AddSale вернул управление), видны в снимке,
+// добавленные после - не видны.
+func (s *SalesStorage) OpenSnapshot() (domain.Snapshot, error) {
+	token, err := newSnapshotToken()
+	if err != nil {
+		return domain.Snapshot{}, err
+	}
+
+	versions := s.versions()
+
+	s.snapshotsMu.Lock()
+	defer s.snapshotsMu.Unlock()
+
+	now := s.now()
+	s.sweepSnapshots(now)
+
+	if len(s.snapshots) >= s.maxSnapshots {
+		return domain.Snapshot{}, domain.ErrTooManySnapshots
+	}
+
+	sn := &Snapshot{s: s, versions: versions, expiresAt: now.Add(s.snapshotTTL)}
+	s.snapshots[token] = sn
+
+	res := domain.Snapshot{
+		Token:     token,
+		ExpiresAt: sn.expiresAt,
+		Versions:  make(map[string]int, len(versions)),
+	}
+
+	for storeID, version := range versions {
+		res.Versions[storeID] = version
+	}
+
+	return res, nil
+}
+
+// Snapshot возвращает чтение продаж в открытом снимке.
+func (s *SalesStorage) Snapshot(token string) (ports.SalesReader, error) {
+	s.snapshotsMu.Lock()
+	defer s.snapshotsMu.Unlock()
+
+	sn, ok := s.snapshots[token]
+	if !ok || !s.now().Before(sn.expiresAt) {
+		return nil, domain.ErrSnapshotNotFound
+	}
+
+	return sn, nil
+}
+
+// CloseSnapshot закрывает снимок.
+func (s *SalesStorage) CloseSnapshot(token string) error {
+	s.snapshotsMu.Lock()
+	defer s.snapshotsMu.Unlock()
+
+	if _, ok := s.snapshots[token]; !ok {
+		return domain.ErrSnapshotNotFound
+	}
+
+	delete(s.snapshots, token)
+
+	return nil
+}
+
+// sweepSnapshots удаляет истекшие снимки. Вызывается под блокировкой снимков.
+func (s *SalesStorage) sweepSnapshots(now time.Time) {
+	for token, sn := range s.snapshots {
+		if !now.Before(sn.expiresAt) {
+			delete(s.snapshots, token)
+		}
+	}
+}
+
+func newSnapshotToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate snapshot token: %w", err)
+	}
+
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,139 @@
+package storage
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+	"go.dataflow.ru/service-sales/pkg/logger"
+)
+
+func TestSalesStorage_Snapshot(t *testing.T) {
+	t.Parallel()
+
+	s := New(logger.NoOpLogger(), WithIndexGranularity(3), WithRetention(10*24*time.Hour, t.TempDir()))
+
+	dt := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	addSales := func(storeID, currency string, from, to int) {
+		for i := from; i < to; i++ {
+			assert.NoError(t, s.AddSale(&domain.Sale{
+				StoreID:      storeID,
+				ProductID:    fmt.Sprintf("product_%d", i%3),
+				QuantitySold: 1,
+				SalePrice:    decimal.NewFromInt(int64(i + 1)),
+				Currency:     currency,
+				SaleDate:     dt.AddDate(0, 0, i),
+			}))
+		}
+	}
+
+	addSales("store_1", "RUB", 0, 10)
+
+	snapshot, err := s.OpenSnapshot()
+	assert.NoError(t, err)
+	assert.Len(t, snapshot.Token, 32)
+	assert.Equal(t, map[string]int{"store_1": 10}, snapshot.Versions)
+
+	r, err := s.Snapshot(snapshot.Token)
+	assert.NoError(t, err)
+
+	startDate, endDate := dt, dt.AddDate(0, 0, 30)
+
+	// после открытия снимка: продажи в той же валюте, в новой валюте и в новом магазине, вытеснение на диск
+	addSales("store_1", "RUB", 10, 15)
+	addSales("store_1", "KZT", 15, 20)
+	addSales("store_2", "RUB", 0, 5)
+	assert.NoError(t, s.Evict(dt.AddDate(0, 0, 25)))
+	assert.NotEmpty(t, s.view("store_1").evicted)
+
+	live, err := s.GetTotalSum("store_1", startDate, endDate)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"RUB": "120", "KZT": "90"}, gross(live))
+
+	// снимок видит только первые 10 продаж: sum(1..10) = 55
+	totals, err := r.GetTotalSum("store_1", startDate, endDate)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"RUB": "55"}, gross(totals))
+
+	totals, err = r.GetTotalSum("store_1", dt.AddDate(0, 0, 8), endDate)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"RUB": "19"}, gross(totals))
+
+	totals, err = r.GetTotalSum("store_2", startDate, endDate)
+	assert.NoError(t, err)
+	assert.Empty(t, totals)
+
+	byProduct, err := r.GetTotalSumByProduct("store_1", startDate, endDate)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"RUB": "22"}, gross(byProduct["product_0"])) // 1 + 4 + 7 + 10
+	assert.Equal(t, map[string]string{"RUB": "15"}, gross(byProduct["product_1"])) // 2 + 5 + 8
+	assert.Equal(t, map[string]string{"RUB": "18"}, gross(byProduct["product_2"])) // 3 + 6 + 9
+
+	// дневные сводки, закрытые продажами после открытия снимка, в снимке не используются
+	queryRes, err := r.Query(domain.Query{
+		StartDate: startDate,
+		EndDate:   endDate.Add(-time.Nanosecond),
+		GroupBy:   []string{domain.FieldProduct},
+		Metrics:   []string{domain.MetricGross, domain.MetricSales},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, planRollup, queryRes.Plan)
+	assert.Equal(t, []string{
+		"map[currency:RUB product:product_0] map[gross:22 sales:4]",
+		"map[currency:RUB product:product_1] map[gross:15 sales:3]",
+		"map[currency:RUB product:product_2] map[gross:18 sales:3]",
+	}, queryRows(queryRes.Rows))
+
+	sales, err := r.GetSales()
+	assert.NoError(t, err)
+	assert.Len(t, sales, 10)
+
+	for i, sale := range sales {
+		assert.Equal(t, "store_1", sale.StoreID)
+		assert.Equal(t, int64(i+1), sale.SalePrice.IntPart())
+	}
+
+	liveSales, err := s.GetSales()
+	assert.NoError(t, err)
+	assert.Len(t, liveSales, 25)
+
+	// закрытый снимок недоступен
+	assert.NoError(t, s.CloseSnapshot(snapshot.Token))
+	assert.ErrorIs(t, s.CloseSnapshot(snapshot.Token), domain.ErrSnapshotNotFound)
+
+	_, err = s.Snapshot(snapshot.Token)
+	assert.ErrorIs(t, err, domain.ErrSnapshotNotFound)
+}
+
+func TestSalesStorage_Snapshot_Expiration(t *testing.T) {
+	t.Parallel()
+
+	s := New(logger.NoOpLogger(), WithSnapshots(time.Minute, 2))
+
+	now := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	first, err := s.OpenSnapshot()
+	assert.NoError(t, err)
+	assert.Equal(t, now.Add(time.Minute), first.ExpiresAt)
+
+	_, err = s.OpenSnapshot()
+	assert.NoError(t, err)
+
+	_, err = s.OpenSnapshot()
+	assert.ErrorIs(t, err, domain.ErrTooManySnapshots)
+
+	// истекшие снимки недоступны и не учитываются в лимите
+	now = now.Add(time.Minute)
+
+	_, err = s.Snapshot(first.Token)
+	assert.ErrorIs(t, err, domain.ErrSnapshotNotFound)
+
+	_, err = s.OpenSnapshot()
+	assert.NoError(t, err)
+}
@@ -0,0 +1,188 @@
+package targets
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+)
+
+// Storage планы выручки магазинов. Планов немного (по одному на магазин и месяц), поэтому они хранятся в памяти,
+// а при каждом изменении целиком сохраняются в JSON-файл (если путь к файлу задан).
+type Storage struct {
+	targets map[string][]*domain.Target // по магазину, упорядочены по дате начала
+
+	path string
+
+	mu sync.RWMutex
+}
+
+// New возвращает планы, загруженные из файла path. Если путь пустой, планы не сохраняются на диск.
+func New(path string) (*Storage, error) {
+	s := &Storage{
+		targets: make(map[string][]*domain.Target),
+		path:    path,
+	}
+
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("read targets: %w", err)
+	}
+
+	var targets []*domain.Target
+	if err = json.Unmarshal(data, &targets); err != nil {
+		return nil, fmt.Errorf("decode targets: %w", err)
+	}
+
+	for _, target := range targets {
+		s.put(target)
+	}
+
+	return s, nil
+}
+
+// SaveTargets создает или заменяет планы. Изменения применяются, только если их удалось сохранить.
+func (s *Storage) SaveTargets(targets ...*domain.Target) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prev := s.copy()
+
+	for _, target := range targets {
+		s.put(target)
+	}
+
+	if err := s.persist(); err != nil {
+		s.targets = prev
+
+		return err
+	}
+
+	return nil
+}
+
+// DeleteTarget удаляет план магазина с датой начала start.
+func (s *Storage) DeleteTarget(storeID string, start domain.Date) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.index(storeID, start)
+	if i < 0 {
+		return domain.ErrTargetNotFound
+	}
+
+	prev := s.copy()
+
+	targets := s.targets[storeID]
+	s.targets[storeID] = append(targets[:i:i], targets[i+1:]...)
+
+	if len(s.targets[storeID]) == 0 {
+		delete(s.targets, storeID)
+	}
+
+	if err := s.persist(); err != nil {
+		s.targets = prev
+
+		return err
+	}
+
+	return nil
+}
+
+// GetTargets возвращает планы магазина, упорядоченные по дате начала, или всех магазинов, если storeID пустой.
+func (s *Storage) GetTargets(storeID string) []*domain.Target {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if storeID != "" {
+		return append([]*domain.Target(nil), s.targets[storeID]...)
+	}
+
+	return s.all()
+}
+
+// put добавляет план или заменяет план магазина с той же датой начала. Вызывается под блокировкой.
+func (s *Storage) put(target *domain.Target) {
+	if i := s.index(target.StoreID, target.Start); i >= 0 {
+		s.targets[target.StoreID][i] = target
+		return
+	}
+
+	targets := append(s.targets[target.StoreID], target)
+	sort.Slice(targets, func(i, j int) bool { return targets[i].Start.Before(targets[j].Start) })
+
+	s.targets[target.StoreID] = targets
+}
+
+// index возвращает индекс плана магазина с датой начала start или -1.
+func (s *Storage) index(storeID string, start domain.Date) int {
+	for i, target := range s.targets[storeID] {
+		if target.Start == start {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// copy возвращает копию планов для отката изменений.
+func (s *Storage) copy() map[string][]*domain.Target {
+	c := make(map[string][]*domain.Target, len(s.targets))
+	for storeID, targets := range s.targets {
+		c[storeID] = append([]*domain.Target(nil), targets...)
+	}
+
+	return c
+}
+
+// all возвращает планы всех магазинов, упорядоченные по магазину и дате начала.
+func (s *Storage) all() []*domain.Target {
+	storeIDs := make([]string, 0, len(s.targets))
+	for storeID := range s.targets {
+		storeIDs = append(storeIDs, storeID)
+	}
+
+	sort.Strings(storeIDs)
+
+	var targets []*domain.Target
+	for _, storeID := range storeIDs {
+		targets = append(targets, s.targets[storeID]...)
+	}
+
+	return targets
+}
+
+// persist сохраняет планы в файл через временный файл, как и справочник. Вызывается под блокировкой.
+func (s *Storage) persist() error {
+	if s.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(s.all(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode targets: %w", err)
+	}
+
+	tmp := s.path + ".tmp"
+	if err = os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("write targets: %w", err)
+	}
+
+	if err = os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("write targets: %w", err)
+	}
+
+	return nil
+}
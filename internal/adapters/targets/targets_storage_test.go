@@ -0,0 +1,69 @@
+package targets
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+)
+
+func TestStorage_Persistence(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "targets.json")
+
+	s, err := New(path)
+	require.NoError(t, err)
+
+	month := func(storeID string, m time.Month, amount int64) *domain.Target {
+		start := domain.Date{Year: 2024, Month: m, Day: 1}
+
+		return &domain.Target{
+			StoreID:  storeID,
+			Start:    start,
+			End:      domain.DateOf(time.Date(2024, m+1, 0, 0, 0, 0, 0, time.UTC)),
+			Amount:   decimal.NewFromInt(amount),
+			Currency: "RUB",
+			Metric:   domain.MetricGross,
+		}
+	}
+
+	require.NoError(t, s.SaveTargets(month("store_1", time.April, 100), month("store_1", time.March, 90), month("store_2", time.March, 50)))
+
+	// план с той же датой начала заменяется
+	require.NoError(t, s.SaveTargets(month("store_1", time.April, 120)))
+
+	require.NoError(t, s.DeleteTarget("store_2", domain.Date{Year: 2024, Month: time.March, Day: 1}))
+	assert.ErrorIs(t, s.DeleteTarget("store_2", domain.Date{Year: 2024, Month: time.March, Day: 1}), domain.ErrTargetNotFound)
+
+	// планы восстанавливаются из файла
+	restored, err := New(path)
+	require.NoError(t, err)
+
+	targets := restored.GetTargets("")
+	require.Len(t, targets, 2)
+	assert.Equal(t, time.March, targets[0].Start.Month)
+	assert.Equal(t, time.April, targets[1].Start.Month)
+	assert.Equal(t, "120", targets[1].Amount.String())
+	assert.Equal(t, domain.Date{Year: 2024, Month: time.April, Day: 30}, targets[1].End)
+
+	assert.Empty(t, restored.GetTargets("store_2"))
+}
+
+func TestStorage_PersistFailure(t *testing.T) {
+	t.Parallel()
+
+	// каталог для файла планов не существует, поэтому сохранение невозможно
+	s, err := New(filepath.Join(t.TempDir(), "missing", "targets.json"))
+	require.NoError(t, err)
+
+	assert.Error(t, s.SaveTargets(&domain.Target{StoreID: "store_1"}))
+
+	// неудачное изменение не применяется
+	assert.Empty(t, s.GetTargets("store_1"))
+}
@@ -0,0 +1,103 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+)
+
+// Заголовки запросов оповещений и отчетов.
+const (
+	HeaderEvent     = "X-Sales-Event"     // идентификатор события, одинаковый при повторных попытках
+	HeaderTimestamp = "X-Sales-Timestamp" // время отправки, Unix-секунды
+	HeaderSignature = "X-Sales-Signature" // sha256=<hex HMAC-SHA256 строки "<timestamp>.<тело>">
+)
+
+// Notifier отправляет POST-запросом события оповещений (JSON-описание события) и содержимое отчетов
+// по расписанию. Запрос подписывается секретом оповещения или отчета, чтобы получатель мог проверить
+// отправителя и время отправки.
+type Notifier struct {
+	http *http.Client
+	now  func() time.Time
+}
+
+// New возвращает отправителя с ограничением времени одного запроса timeout.
+func New(timeout time.Duration) *Notifier {
+	return &Notifier{
+		http: &http.Client{Timeout: timeout},
+		now:  time.Now,
+	}
+}
+
+// Notify выполняет одну попытку доставки события оповещения (см. post).
+func (n *Notifier) Notify(ctx context.Context, alert *domain.Alert, event *domain.AlertEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("encode alert event: %w", err)
+	}
+
+	return n.post(ctx, alert.WebhookURL, alert.Secret, event.ID, "application/json", body)
+}
+
+// DeliverReport выполняет одну попытку доставки содержимого запуска отчета (см. post). Идентификатор
+// события - идентификатор запуска.
+func (n *Notifier) DeliverReport(ctx context.Context, report *domain.Report, run *domain.ReportRun, content []byte) error {
+	return n.post(ctx, report.WebhookURL, report.Secret, run.ID, domain.ReportContentType(run.Format), content)
+}
+
+// post отправляет тело body на адрес url. Ответ 2xx - тело доставлено; ответы 4xx, кроме 408 и 429, -
+// ошибка domain.ErrWebhookRejected, остальные ошибки временные.
+func (n *Notifier) post(ctx context.Context, url, secret, eventID, contentType string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("webhook %s: %v: %w", url, err, domain.ErrWebhookRejected)
+	}
+
+	timestamp := n.now().Unix()
+
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set(HeaderEvent, eventID)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+
+	if secret != "" {
+		req.Header.Set(HeaderSignature, Sign(secret, timestamp, body))
+	}
+
+	resp, err := n.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	// тело ответа дочитывается, чтобы соединение вернулось в пул
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode >= 400 && resp.StatusCode < 500 &&
+		resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests:
+		return fmt.Errorf("webhook %s: status %d: %w", url, resp.StatusCode, domain.ErrWebhookRejected)
+	default:
+		return fmt.Errorf("webhook %s: status %d", url, resp.StatusCode)
+	}
+}
+
+// Sign возвращает подпись тела запроса body, отправленного в момент timestamp, для заголовка HeaderSignature.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
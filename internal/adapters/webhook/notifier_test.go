@@ -0,0 +1,157 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+)
+
+func TestNotifier_Notify(t *testing.T) {
+	t.Parallel()
+
+	type request struct {
+		header http.Header
+		body   []byte
+	}
+
+	requests := make(chan request, 1)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- request{header: r.Header, body: body}
+	}))
+	defer srv.Close()
+
+	sentAt := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	n := New(time.Second)
+	n.now = func() time.Time { return sentAt }
+
+	revenue := decimal.NewFromInt(1500)
+	event := &domain.AlertEvent{
+		ID:       "e1",
+		AlertID:  "a1",
+		Kind:     domain.AlertDailyRevenue,
+		StoreID:  "store_1",
+		Time:     sentAt,
+		Revenue:  &revenue,
+		Currency: "RUB",
+	}
+
+	alert := &domain.Alert{ID: "a1", WebhookURL: srv.URL + "/hook", Secret: "secret"}
+	require.NoError(t, n.Notify(context.Background(), alert, event))
+
+	req := <-requests
+
+	assert.Equal(t, "application/json", req.header.Get("Content-Type"))
+	assert.Equal(t, "e1", req.header.Get(HeaderEvent))
+	assert.Equal(t, "1717243200", req.header.Get(HeaderTimestamp))
+	assert.Equal(t, Sign("secret", sentAt.Unix(), req.body), req.header.Get(HeaderSignature))
+	assert.NotEqual(t, Sign("other", sentAt.Unix(), req.body), req.header.Get(HeaderSignature))
+
+	var received domain.AlertEvent
+	require.NoError(t, json.Unmarshal(req.body, &received))
+	assert.Equal(t, "store_1", received.StoreID)
+	assert.Equal(t, "1500", received.Revenue.String())
+
+	// без секрета запрос не подписывается
+	require.NoError(t, n.Notify(context.Background(), &domain.Alert{WebhookURL: srv.URL}, event))
+
+	req = <-requests
+	assert.Empty(t, req.header.Get(HeaderSignature))
+}
+
+func TestNotifier_DeliverReport(t *testing.T) {
+	t.Parallel()
+
+	type request struct {
+		header http.Header
+		body   []byte
+	}
+
+	requests := make(chan request, 1)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- request{header: r.Header, body: body}
+	}))
+	defer srv.Close()
+
+	sentAt := time.Date(2024, 6, 3, 9, 0, 0, 0, time.UTC)
+
+	n := New(time.Second)
+	n.now = func() time.Time { return sentAt }
+
+	report := &domain.Report{ID: "r1", WebhookURL: srv.URL, Secret: "secret"}
+	run := &domain.ReportRun{ID: "run1", ReportID: "r1", Format: domain.ReportCSV}
+	content := []byte("currency,gross\nRUB,1000\n")
+
+	require.NoError(t, n.DeliverReport(context.Background(), report, run, content))
+
+	req := <-requests
+
+	// содержимое отчета отправляется как есть, с типом формата отчета
+	assert.Equal(t, content, req.body)
+	assert.Equal(t, "text/csv; charset=utf-8", req.header.Get("Content-Type"))
+	assert.Equal(t, "run1", req.header.Get(HeaderEvent))
+	assert.Equal(t, Sign("secret", sentAt.Unix(), content), req.header.Get(HeaderSignature))
+}
+
+func TestNotifier_Errors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		status   int
+		wantErr  bool
+		rejected bool
+	}{
+		{name: "доставлено", status: http.StatusNoContent},
+		{name: "ошибка получателя повторяется", status: http.StatusServiceUnavailable, wantErr: true},
+		{name: "превышение лимита повторяется", status: http.StatusTooManyRequests, wantErr: true},
+		{name: "отказ получателя не повторяется", status: http.StatusNotFound, wantErr: true, rejected: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			err := New(time.Second).Notify(context.Background(), &domain.Alert{WebhookURL: srv.URL}, &domain.AlertEvent{ID: "e1"})
+			if !tt.wantErr {
+				assert.NoError(t, err)
+				return
+			}
+
+			require.Error(t, err)
+			assert.Equal(t, tt.rejected, errors.Is(err, domain.ErrWebhookRejected))
+		})
+	}
+
+	t.Run("получатель недоступен", func(t *testing.T) {
+		t.Parallel()
+
+		srv := httptest.NewServer(http.NotFoundHandler())
+		srv.Close()
+
+		err := New(time.Second).Notify(context.Background(), &domain.Alert{WebhookURL: srv.URL}, &domain.AlertEvent{ID: "e1"})
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, domain.ErrWebhookRejected)
+	})
+}
@@ -0,0 +1,100 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrAlertNotFound = errors.New("alert not found")
+	ErrInvalidAlert  = errors.New("invalid alert")
+	// ErrWebhookRejected получатель оповещения отклонил запрос, повторная отправка не поможет.
+	ErrWebhookRejected = errors.New("webhook rejected")
+)
+
+// Виды оповещений.
+const (
+	AlertDailyRevenue = "daily_revenue" // выручка магазина за день достигла порога
+	AlertNoSales      = "no_sales"      // у магазина нет продаж дольше Window
+)
+
+// minAlertWindow минимальное окно оповещения no_sales: отсутствие продаж проверяется периодически.
+const minAlertWindow = time.Minute
+
+// Alert оповещение: при наступлении события по магазину StoreID (пустой - по любому магазину справочника)
+// на адрес WebhookURL отправляется POST-запрос с описанием события (AlertEvent).
+//
+// daily_revenue срабатывает один раз в день магазина, когда выручка за день в валюте Currency достигает Threshold.
+// Без Threshold порог - дневная доля плана магазина (сумма плана, деленная на количество дней его периода)
+// в валюте и по метрике плана. no_sales срабатывает, когда у магазина нет продаж за последние Window,
+// и снова - только после появления продаж.
+type Alert struct {
+	ID      string `json:"id"`
+	Kind    string `json:"kind"`
+	StoreID string `json:"store_id,omitempty"`
+
+	Threshold decimal.Decimal `json:"threshold"`
+	Currency  string          `json:"currency,omitempty"`
+	Metric    string          `json:"metric,omitempty"` // MetricGross (по умолчанию) или MetricNet
+
+	Window Duration `json:"window,omitempty"`
+
+	WebhookURL string `json:"webhook_url"`
+	// Secret ключ подписи запросов HMAC-SHA256, пустой - запросы не подписываются
+	Secret string `json:"secret,omitempty"`
+}
+
+// Validate проверяет описание оповещения.
+func (a *Alert) Validate() error {
+	switch a.Kind {
+	case AlertDailyRevenue:
+		if a.Threshold.IsNegative() {
+			return fmt.Errorf("threshold must not be negative")
+		}
+
+		if !a.Threshold.IsZero() && !IsCurrencyCode(a.Currency) {
+			return fmt.Errorf("invalid currency of threshold")
+		}
+
+		if a.Metric != MetricGross && a.Metric != MetricNet {
+			return fmt.Errorf("unknown metric %q", a.Metric)
+		}
+	case AlertNoSales:
+		if time.Duration(a.Window) < minAlertWindow {
+			return fmt.Errorf("window must be at least %s", minAlertWindow)
+		}
+	default:
+		return fmt.Errorf("unknown alert kind %q, expected %s or %s", a.Kind, AlertDailyRevenue, AlertNoSales)
+	}
+
+	u, err := url.Parse(a.WebhookURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid webhook url %q", a.WebhookURL)
+	}
+
+	return nil
+}
+
+// AlertEvent событие оповещения - тело запроса на адрес оповещения.
+type AlertEvent struct {
+	ID      string    `json:"id"`
+	AlertID string    `json:"alert_id"`
+	Kind    string    `json:"kind"`
+	StoreID string    `json:"store_id"`
+	Time    time.Time `json:"time"`
+	Message string    `json:"message"`
+	Test    bool      `json:"test,omitempty"` // пробное событие (см. ports.AlertService.TestAlert)
+
+	// daily_revenue: день магазина, выручка и порог
+	Day       *Date            `json:"day,omitempty"`
+	Revenue   *decimal.Decimal `json:"revenue,omitempty"`
+	Threshold *decimal.Decimal `json:"threshold,omitempty"`
+	Currency  string           `json:"currency,omitempty"`
+
+	// no_sales: окно без продаж
+	Window Duration `json:"window,omitempty"`
+}
@@ -0,0 +1,114 @@
+package domain
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+// AmountPrecision количество знаков после запятой в денежных суммах строки продажи.
+const AmountPrecision = 2
+
+var hundred = decimal.NewFromInt(100)
+
+// Amounts денежные составляющие продажи или агрегата продаж.
+//
+// Цена продажи включает НДС, поэтому:
+//
+//	Gross    = round(QuantitySold * SalePrice)
+//	Discount = round(Discount)
+//	Tax      = round((Gross - Discount) * VATRate / (100 + VATRate))
+//	Net      = Gross - Discount - Tax
+//
+// Округление до AmountPrecision знаков выполняется для каждой строки отдельно, половина округляется от нуля.
+// Net вычисляется вычитанием, поэтому Gross = Discount + Tax + Net выполняется точно как для строки, так и для любой суммы строк.
+type Amounts struct {
+	Gross    decimal.Decimal `json:"gross"`    // выручка до скидки, с НДС
+	Discount decimal.Decimal `json:"discount"` // скидка
+	Tax      decimal.Decimal `json:"tax"`      // НДС
+	Net      decimal.Decimal `json:"net"`      // выручка за вычетом скидки и НДС
+}
+
+// Amounts вычисляет денежные составляющие продажи.
+func (s *Sale) Amounts() Amounts {
+	gross := decimal.NewFromInt(s.QuantitySold).Mul(s.SalePrice).Round(AmountPrecision)
+	discount := s.Discount.Round(AmountPrecision)
+
+	tax := decimal.Zero
+	if s.VATRate.IsPositive() {
+		tax = gross.Sub(discount).Mul(s.VATRate).Div(hundred.Add(s.VATRate)).Round(AmountPrecision)
+	}
+
+	return Amounts{
+		Gross:    gross,
+		Discount: discount,
+		Tax:      tax,
+		Net:      sub(sub(gross, discount), tax),
+	}
+}
+
+// Add возвращает покомпонентную сумму.
+func (a Amounts) Add(b Amounts) Amounts {
+	return Amounts{
+		Gross:    add(a.Gross, b.Gross),
+		Discount: add(a.Discount, b.Discount),
+		Tax:      add(a.Tax, b.Tax),
+		Net:      add(a.Net, b.Net),
+	}
+}
+
+// Sub возвращает покомпонентную разность.
+func (a Amounts) Sub(b Amounts) Amounts {
+	return Amounts{
+		Gross:    sub(a.Gross, b.Gross),
+		Discount: sub(a.Discount, b.Discount),
+		Tax:      sub(a.Tax, b.Tax),
+		Net:      sub(a.Net, b.Net),
+	}
+}
+
+// add складывает суммы, не выделяя память, если второе слагаемое нулевое.
+// Скидки и НДС у большинства продаж нулевые, а кумулятивные суммы хранятся для каждой продажи.
+func add(a, b decimal.Decimal) decimal.Decimal {
+	if b.IsZero() {
+		return a
+	}
+
+	return a.Add(b)
+}
+
+// sub вычитает суммы, не выделяя память, если вычитаемое нулевое.
+func sub(a, b decimal.Decimal) decimal.Decimal {
+	if b.IsZero() {
+		return a
+	}
+
+	return a.Sub(b)
+}
+
+// Convert пересчитывает суммы по курсу rate без округления.
+func (a Amounts) Convert(rate decimal.Decimal) Amounts {
+	return Amounts{
+		Gross:    a.Gross.Mul(rate),
+		Discount: a.Discount.Mul(rate),
+		Tax:      a.Tax.Mul(rate),
+		Net:      a.Net.Mul(rate),
+	}
+}
+
+// Round округляет суммы до places знаков, сохраняя равенство Gross = Discount + Tax + Net.
+func (a Amounts) Round(places int32) Amounts {
+	gross := a.Gross.Round(places)
+	discount := a.Discount.Round(places)
+	tax := a.Tax.Round(places)
+
+	return Amounts{
+		Gross:    gross,
+		Discount: discount,
+		Tax:      tax,
+		Net:      gross.Sub(discount).Sub(tax),
+	}
+}
+
+// IsZero сообщает, что все составляющие нулевые.
+func (a Amounts) IsZero() bool {
+	return a.Gross.IsZero() && a.Discount.IsZero() && a.Tax.IsZero() && a.Net.IsZero()
+}
@@ -0,0 +1,101 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSale_Amounts(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name        string
+		sale        Sale
+		expGross    string
+		expDiscount string
+		expTax      string
+		expNet      string
+	}{
+		{
+			name: "без скидки и НДС",
+			sale: Sale{
+				QuantitySold: 3,
+				SalePrice:    decimal.RequireFromString("9.99"),
+			},
+			expGross:    "29.97",
+			expDiscount: "0",
+			expTax:      "0",
+			expNet:      "29.97",
+		},
+		{
+			name: "НДС 20%",
+			sale: Sale{
+				QuantitySold: 1,
+				SalePrice:    decimal.RequireFromString("100"),
+				VATRate:      decimal.NewFromInt(20),
+			},
+			expGross:    "100",
+			expDiscount: "0",
+			expTax:      "16.67",
+			expNet:      "83.33",
+		},
+		{
+			name: "скидка и НДС 10%",
+			sale: Sale{
+				QuantitySold: 2,
+				SalePrice:    decimal.RequireFromString("55.5"),
+				Discount:     decimal.RequireFromString("11"),
+				VATRate:      decimal.NewFromInt(10),
+			},
+			expGross:    "111",
+			expDiscount: "11",
+			expTax:      "9.09",
+			expNet:      "90.91",
+		},
+		{
+			name: "половина округляется от нуля",
+			sale: Sale{
+				QuantitySold: 1,
+				SalePrice:    decimal.RequireFromString("0.125"),
+				Discount:     decimal.RequireFromString("0.005"),
+			},
+			expGross:    "0.13",
+			expDiscount: "0.01",
+			expTax:      "0",
+			expNet:      "0.12",
+		},
+	}
+
+	for _, tt := range testCases {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			amounts := tt.sale.Amounts()
+
+			assert.Equal(t, tt.expGross, amounts.Gross.String())
+			assert.Equal(t, tt.expDiscount, amounts.Discount.String())
+			assert.Equal(t, tt.expTax, amounts.Tax.String())
+			assert.Equal(t, tt.expNet, amounts.Net.String())
+			assert.True(t, amounts.Gross.Equal(amounts.Discount.Add(amounts.Tax).Add(amounts.Net)))
+		})
+	}
+}
+
+func TestAmounts_Round(t *testing.T) {
+	t.Parallel()
+
+	a := Amounts{
+		Gross:    decimal.RequireFromString("10.005"),
+		Discount: decimal.RequireFromString("1.004"),
+		Tax:      decimal.RequireFromString("1.504"),
+		Net:      decimal.RequireFromString("7.497"),
+	}.Round(AmountPrecision)
+
+	assert.Equal(t, "10.01", a.Gross.String())
+	assert.Equal(t, "1", a.Discount.String())
+	assert.Equal(t, "1.5", a.Tax.String())
+	assert.Equal(t, "7.51", a.Net.String())
+}
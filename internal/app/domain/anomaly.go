@@ -0,0 +1,42 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrSaleQuarantined = errors.New("sale quarantined for review")
+	ErrAnomalyNotFound = errors.New("anomaly not found")
+	ErrAnomalyResolved = errors.New("anomaly already resolved")
+)
+
+// Действия с продажей, признанной аномальной.
+const (
+	AnomalyActionFlag       = "flag"       // продажа сохраняется и попадает в очередь проверки
+	AnomalyActionQuarantine = "quarantine" // продажа сохраняется только после подтверждения в очереди проверки
+)
+
+// Статусы аномалии в очереди проверки.
+const (
+	AnomalyPending  = "pending"
+	AnomalyAccepted = "accepted"
+	AnomalyRejected = "rejected"
+)
+
+// AnomalyScore оценка отклонения продажи от обычных продаж: чем больше Score, тем необычнее продажа.
+type AnomalyScore struct {
+	Score   float64  `json:"score"`
+	Reasons []string `json:"reasons"`
+}
+
+// Anomaly продажа, признанная аномальной, в очереди проверки.
+type Anomaly struct {
+	ID         string     `json:"id"`
+	Sale       *Sale      `json:"sale"`
+	Action     string     `json:"action"` // AnomalyActionFlag или AnomalyActionQuarantine
+	Status     string     `json:"status"`
+	DetectedAt time.Time  `json:"detected_at"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+	AnomalyScore
+}
@@ -0,0 +1,134 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Правила сдвига периода для сравнения с предыдущим периодом.
+const (
+	OffsetPreviousPeriod = "previous_period" // период той же длины, непосредственно предшествующий
+	OffsetPreviousWeek   = "previous_week"   // те же дни неделей раньше
+	OffsetPreviousMonth  = "previous_month"  // те же дни месяцем раньше
+	OffsetPreviousYear   = "previous_year"   // те же дни годом раньше
+)
+
+// Shift возвращает период для сравнения с p по правилу offset.
+//
+// Даты магазина сдвигаются по календарю: при сдвиге на месяц или год день, которого нет в месяце, заменяется
+// последним днем месяца, а конец месяца остается концом месяца (апрель сравнивается с мартом целиком).
+// Моменты времени сдвигаются с сохранением времени суток. Для previous_period границы периода должны быть
+// одного вида: длина периода из дат - число дней, из моментов - длительность.
+func (p Period) Shift(offset string) (Period, error) {
+	switch offset {
+	case OffsetPreviousWeek:
+		return Period{Start: p.Start.addDays(-7), End: p.End.addDays(-7)}, nil
+	case OffsetPreviousMonth:
+		return Period{Start: p.Start.addMonths(-1, false), End: p.End.addMonths(-1, true)}, nil
+	case OffsetPreviousYear:
+		return Period{Start: p.Start.addMonths(-12, false), End: p.End.addMonths(-12, true)}, nil
+	case OffsetPreviousPeriod:
+		switch {
+		case p.Start.isDay && p.End.isDay:
+			days := p.Start.day.DaysUntil(p.End.day) + 1
+
+			return Period{Start: p.Start.addDays(-days), End: p.End.addDays(-days)}, nil
+		case !p.Start.isDay && !p.End.isDay:
+			d := p.End.instant.Sub(p.Start.instant) + time.Nanosecond
+
+			return Period{Start: InstantBound(p.Start.instant.Add(-d)), End: InstantBound(p.End.instant.Add(-d))}, nil
+		default:
+			return Period{}, fmt.Errorf("previous_period requires both period bounds to be dates or both to be instants")
+		}
+	default:
+		return Period{}, fmt.Errorf("unknown offset %q", offset)
+	}
+}
+
+// addDays сдвигает границу на n календарных дней.
+func (b PeriodBound) addDays(n int) PeriodBound {
+	if b.isDay {
+		return DayBound(b.day.AddDays(n))
+	}
+
+	return InstantBound(b.instant.AddDate(0, 0, n))
+}
+
+// addMonths сдвигает границу на n месяцев (см. Date.AddMonths). Если keepMonthEnd, последний день месяца
+// переходит в последний день месяца.
+func (b PeriodBound) addMonths(n int, keepMonthEnd bool) PeriodBound {
+	if b.isDay {
+		day := b.day.AddMonths(n)
+		if keepMonthEnd && b.day.IsMonthEnd() {
+			day = Date{Year: day.Year, Month: day.Month, Day: 1}.AddMonths(1).AddDays(-1)
+		}
+
+		return DayBound(day)
+	}
+
+	t := b.instant
+	day := DateOf(t).AddMonths(n)
+
+	return InstantBound(time.Date(day.Year, day.Month, day.Day, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location()))
+}
+
+// TimeRange интервал времени, границы включаются.
+type TimeRange struct {
+	Start time.Time `json:"start_date"`
+	End   time.Time `json:"end_date"`
+}
+
+// Change изменение сумм продаж в валюте между предыдущим и текущим периодами.
+type Change struct {
+	Current  Amounts `json:"current"`
+	Previous Amounts `json:"previous"`
+	Delta    Amounts `json:"delta"` // Current - Previous
+
+	// изменение валовой и чистой выручки в процентах; nil, если в предыдущем периоде выручки не было
+	GrossPercent *decimal.Decimal `json:"gross_percent"`
+	NetPercent   *decimal.Decimal `json:"net_percent"`
+}
+
+// PeriodComparison сравнение продаж магазина за период с предыдущим периодом.
+type PeriodComparison struct {
+	StoreID    string            `json:"store_id"`
+	Current    TimeRange         `json:"current_period"`
+	Previous   TimeRange         `json:"previous_period"`
+	Currencies map[string]Change `json:"currencies"`
+}
+
+// Compare возвращает изменение сумм продаж по каждой валюте, встречающейся в одном из периодов.
+func Compare(current, previous Totals) map[string]Change {
+	res := make(map[string]Change, len(current))
+
+	for _, totals := range []Totals{current, previous} {
+		for currency := range totals {
+			if _, ok := res[currency]; ok {
+				continue
+			}
+
+			c := Change{Current: current[currency], Previous: previous[currency]}
+
+			c.Delta = c.Current.Sub(c.Previous)
+			c.GrossPercent = percentChange(c.Current.Gross, c.Previous.Gross)
+			c.NetPercent = percentChange(c.Current.Net, c.Previous.Net)
+
+			res[currency] = c
+		}
+	}
+
+	return res
+}
+
+// percentChange возвращает изменение от previous до current в процентах от |previous| с точностью до сотых.
+func percentChange(current, previous decimal.Decimal) *decimal.Decimal {
+	if previous.IsZero() {
+		return nil
+	}
+
+	p := current.Sub(previous).Mul(hundred).Div(previous.Abs()).Round(2)
+
+	return &p
+}
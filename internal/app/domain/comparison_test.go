@@ -0,0 +1,159 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPeriod_Shift(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		start    string
+		end      string
+		offset   string
+		expStart string
+		expEnd   string
+		wantErr  bool
+	}{
+		{
+			name:     "неделя к предыдущей неделе",
+			start:    "2024-03-11",
+			end:      "2024-03-17",
+			offset:   OffsetPreviousWeek,
+			expStart: "2024-03-04",
+			expEnd:   "2024-03-10",
+		},
+		{
+			name:     "предыдущий период той же длины",
+			start:    "2024-03-01",
+			end:      "2024-03-10",
+			offset:   OffsetPreviousPeriod,
+			expStart: "2024-02-20",
+			expEnd:   "2024-02-29",
+		},
+		{
+			name:     "предыдущий период из моментов времени",
+			start:    "2024-03-01T10:00:00Z",
+			end:      "2024-03-01T11:59:59.999999999Z",
+			offset:   OffsetPreviousPeriod,
+			expStart: "2024-03-01T08:00:00Z",
+			expEnd:   "2024-03-01T09:59:59.999999999Z",
+		},
+		{
+			name:     "апрель к марту целиком",
+			start:    "2024-04-01",
+			end:      "2024-04-30",
+			offset:   OffsetPreviousMonth,
+			expStart: "2024-03-01",
+			expEnd:   "2024-03-31",
+		},
+		{
+			name:     "март к февралю високосного года",
+			start:    "2024-03-01",
+			end:      "2024-03-31",
+			offset:   OffsetPreviousMonth,
+			expStart: "2024-02-01",
+			expEnd:   "2024-02-29",
+		},
+		{
+			name:     "часть месяца к тем же дням прошлого месяца",
+			start:    "2024-03-15",
+			end:      "2024-03-30",
+			offset:   OffsetPreviousMonth,
+			expStart: "2024-02-15",
+			expEnd:   "2024-02-29",
+		},
+		{
+			name:     "февраль к февралю прошлого года",
+			start:    "2024-02-01",
+			end:      "2024-02-29",
+			offset:   OffsetPreviousYear,
+			expStart: "2023-02-01",
+			expEnd:   "2023-02-28",
+		},
+		{
+			name:     "момент времени годом раньше",
+			start:    "2024-06-01T10:00:00+03:00",
+			end:      "2024-06-30",
+			offset:   OffsetPreviousYear,
+			expStart: "2023-06-01T10:00:00+03:00",
+			expEnd:   "2023-06-30",
+		},
+		{
+			name:    "previous_period с границами разного вида",
+			start:   "2024-03-01T10:00:00Z",
+			end:     "2024-03-10",
+			offset:  OffsetPreviousPeriod,
+			wantErr: true,
+		},
+		{
+			name:    "неизвестное правило",
+			start:   "2024-03-01",
+			end:     "2024-03-10",
+			offset:  "previous_decade",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range testCases {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			period, err := ParsePeriod(tt.start, tt.end)
+			require.NoError(t, err)
+
+			shifted, err := period.Shift(tt.offset)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+
+			expected, err := ParsePeriod(tt.expStart, tt.expEnd)
+			require.NoError(t, err)
+
+			start, end := shifted.Resolve(time.UTC)
+			expStart, expEnd := expected.Resolve(time.UTC)
+			assert.True(t, expStart.Equal(start), "start %s, expected %s", start, expStart)
+			assert.True(t, expEnd.Equal(end), "end %s, expected %s", end, expEnd)
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	t.Parallel()
+
+	amounts := func(gross, net int64) Amounts {
+		return Amounts{Gross: decimal.NewFromInt(gross), Tax: decimal.NewFromInt(gross - net), Net: decimal.NewFromInt(net)}
+	}
+
+	changes := Compare(
+		Totals{"RUB": amounts(150, 120), "KZT": amounts(10, 10)},
+		Totals{"RUB": amounts(100, 90), "USD": amounts(5, 5)},
+	)
+
+	require.Len(t, changes, 3)
+
+	rub := changes["RUB"]
+	assert.Equal(t, "50", rub.Delta.Gross.String())
+	assert.Equal(t, "30", rub.Delta.Net.String())
+	assert.Equal(t, "50", rub.GrossPercent.String())
+	assert.Equal(t, "33.33", rub.NetPercent.String())
+
+	// в предыдущем периоде продаж в валюте не было
+	assert.Equal(t, "10", changes["KZT"].Delta.Gross.String())
+	assert.Nil(t, changes["KZT"].GrossPercent)
+
+	// в текущем периоде продаж в валюте нет
+	assert.Equal(t, "-5", changes["USD"].Delta.Gross.String())
+	assert.Equal(t, "-100", changes["USD"].GrossPercent.String())
+}
@@ -0,0 +1,16 @@
+package domain
+
+// IsCurrencyCode проверяет, что code похож на буквенный код валюты ISO 4217 (три заглавные латинские буквы).
+func IsCurrencyCode(code string) bool {
+	if len(code) != 3 {
+		return false
+	}
+
+	for _, r := range code {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,118 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// DateLayout формат календарной даты.
+const DateLayout = "2006-01-02"
+
+// Date календарная дата без привязки к часовому поясу.
+type Date struct {
+	Year  int
+	Month time.Month
+	Day   int
+}
+
+// ParseDate разбирает дату в формате DateLayout.
+func ParseDate(s string) (Date, error) {
+	t, err := time.Parse(DateLayout, s)
+	if err != nil {
+		return Date{}, err
+	}
+
+	return DateOf(t), nil
+}
+
+// DateOf возвращает календарную дату момента t в его часовом поясе.
+func DateOf(t time.Time) Date {
+	y, m, d := t.Date()
+
+	return Date{Year: y, Month: m, Day: d}
+}
+
+// AddDays возвращает дату через n дней.
+func (d Date) AddDays(n int) Date {
+	return DateOf(time.Date(d.Year, d.Month, d.Day+n, 0, 0, 0, 0, time.UTC))
+}
+
+// AddMonths возвращает дату через n месяцев. Если в месяце нет такого дня, возвращается последний день месяца
+// (31 марта минус месяц - 29 февраля).
+func (d Date) AddMonths(n int) Date {
+	first := time.Date(d.Year, d.Month+time.Month(n), 1, 0, 0, 0, 0, time.UTC)
+
+	day := d.Day
+	if last := first.AddDate(0, 1, -1).Day(); day > last {
+		day = last
+	}
+
+	return Date{Year: first.Year(), Month: first.Month(), Day: day}
+}
+
+// IsMonthEnd сообщает, что дата - последний день месяца.
+func (d Date) IsMonthEnd() bool {
+	return d.AddDays(1).Day == 1
+}
+
+// Before сообщает, что дата d раньше other.
+func (d Date) Before(other Date) bool {
+	return d.utc().Before(other.utc())
+}
+
+// After сообщает, что дата d позже other.
+func (d Date) After(other Date) bool {
+	return d.utc().After(other.utc())
+}
+
+// DaysUntil возвращает число дней от d до other.
+func (d Date) DaysUntil(other Date) int {
+	return int(other.utc().Sub(d.utc()).Hours() / 24)
+}
+
+// Start возвращает первый момент дня в часовом поясе loc.
+// Если из-за перехода на летнее время полночь в поясе не существует, день начинается в момент перехода.
+func (d Date) Start(loc *time.Location) time.Time {
+	t := time.Date(d.Year, d.Month, d.Day, 0, 0, 0, 0, loc)
+
+	// для несуществующего локального времени time.Date может вернуть момент предыдущего дня
+	if DateOf(t).Before(d) {
+		_, t = t.ZoneBounds()
+	}
+
+	return t
+}
+
+// End возвращает последний момент дня в часовом поясе loc. День может длиться 23 или 25 часов.
+func (d Date) End(loc *time.Location) time.Time {
+	return d.AddDays(1).Start(loc).Add(-time.Nanosecond)
+}
+
+func (d Date) String() string {
+	return fmt.Sprintf("%04d-%02d-%02d", d.Year, d.Month, d.Day)
+}
+
+func (d Date) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Date) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	parsed, err := ParseDate(s)
+	if err != nil {
+		return err
+	}
+
+	*d = parsed
+
+	return nil
+}
+
+func (d Date) utc() time.Time {
+	return time.Date(d.Year, d.Month, d.Day, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,98 @@
+package domain
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDate_StartEnd(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		timeZone string
+		date     string
+		expStart string
+		expEnd   string
+		expHours float64
+	}{
+		{
+			name:     "обычный день",
+			timeZone: "Europe/Moscow",
+			date:     "2024-03-05",
+			expStart: "2024-03-04T21:00:00Z",
+			expEnd:   "2024-03-05T20:59:59.999999999Z",
+			expHours: 24,
+		},
+		{
+			name:     "переход на летнее время, 23 часа",
+			timeZone: "Europe/Berlin",
+			date:     "2024-03-31",
+			expStart: "2024-03-30T23:00:00Z",
+			expEnd:   "2024-03-31T21:59:59.999999999Z",
+			expHours: 23,
+		},
+		{
+			name:     "переход на зимнее время, 25 часов",
+			timeZone: "Europe/Berlin",
+			date:     "2024-10-27",
+			expStart: "2024-10-26T22:00:00Z",
+			expEnd:   "2024-10-27T22:59:59.999999999Z",
+			expHours: 25,
+		},
+		{
+			name:     "полночь пропущена при переходе на летнее время",
+			timeZone: "America/Sao_Paulo",
+			date:     "2018-11-04",
+			expStart: "2018-11-04T03:00:00Z",
+			expEnd:   "2018-11-05T01:59:59.999999999Z",
+			expHours: 23,
+		},
+		{
+			name:     "день перед пропущенной полуночью",
+			timeZone: "America/Sao_Paulo",
+			date:     "2018-11-03",
+			expStart: "2018-11-03T03:00:00Z",
+			expEnd:   "2018-11-04T02:59:59.999999999Z",
+			expHours: 24,
+		},
+	}
+
+	for _, tt := range testCases {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			loc, err := time.LoadLocation(tt.timeZone)
+			require.NoError(t, err)
+
+			d, err := ParseDate(tt.date)
+			require.NoError(t, err)
+
+			start := d.Start(loc)
+			end := d.End(loc)
+
+			assert.Equal(t, tt.expStart, start.UTC().Format(time.RFC3339Nano))
+			assert.Equal(t, tt.expEnd, end.UTC().Format(time.RFC3339Nano))
+			assert.Equal(t, tt.expHours, end.Add(time.Nanosecond).Sub(start).Hours())
+			assert.Equal(t, d, DateOf(start.In(loc)))
+			assert.Equal(t, d, DateOf(end.In(loc)))
+		})
+	}
+}
+
+func TestDate_AddDays(t *testing.T) {
+	t.Parallel()
+
+	d := Date{Year: 2024, Month: time.February, Day: 28}
+
+	assert.Equal(t, "2024-02-29", d.AddDays(1).String())
+	assert.Equal(t, "2024-03-01", d.AddDays(2).String())
+	assert.Equal(t, "2023-12-31", d.AddDays(-59).String())
+	assert.Equal(t, 2, d.DaysUntil(d.AddDays(2)))
+	assert.True(t, d.Before(d.AddDays(1)))
+}
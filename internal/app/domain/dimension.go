@@ -0,0 +1,68 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Измерения продаж, по которым хранилище ведет суммы для разбивки выручки.
+const (
+	DimensionPaymentMethod = "payment_method"
+	DimensionChannel       = "channel"
+)
+
+// Способы оплаты.
+const (
+	PaymentCash    = "cash"
+	PaymentCard    = "card"
+	PaymentVoucher = "voucher"
+)
+
+// Каналы продаж.
+const (
+	ChannelInStore         = "in_store"
+	ChannelOnline          = "online"
+	ChannelClickAndCollect = "click_and_collect"
+)
+
+// Unspecified группа разбивки для продаж, у которых значение измерения не задано.
+const Unspecified = "unspecified"
+
+// dimensionValues допустимые значения измерений.
+var dimensionValues = map[string][]string{
+	DimensionPaymentMethod: {PaymentCash, PaymentCard, PaymentVoucher},
+	DimensionChannel:       {ChannelInStore, ChannelOnline, ChannelClickAndCollect},
+}
+
+// Dimensions возвращает измерения продаж.
+func Dimensions() []string {
+	return []string{DimensionPaymentMethod, DimensionChannel}
+}
+
+// IsDimension сообщает, что dimension - измерение продаж.
+func IsDimension(dimension string) bool {
+	_, ok := dimensionValues[dimension]
+
+	return ok
+}
+
+// ValidateDimension проверяет значение измерения продажи. Пустое значение допустимо: измерение не задано.
+func ValidateDimension(dimension, value string) error {
+	if value == "" || contains(dimensionValues[dimension], value) {
+		return nil
+	}
+
+	return fmt.Errorf("%s must be one of %s", dimension, strings.Join(dimensionValues[dimension], ", "))
+}
+
+// Dimension возвращает значение измерения продажи (пустое, если не задано).
+func (s *Sale) Dimension(dimension string) string {
+	switch dimension {
+	case DimensionPaymentMethod:
+		return s.PaymentMethod
+	case DimensionChannel:
+		return s.Channel
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,87 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+var ErrInsufficientHistory = errors.New("not enough sales history for forecast")
+
+// Модели прогноза дневной выручки.
+const (
+	ModelSeasonalNaive = "seasonal_naive" // значение того же дня недели прошлой недели
+	ModelHoltWinters   = "holt_winters"   // экспоненциальное сглаживание с трендом и недельной сезонностью
+)
+
+// ForecastParams параметры прогноза дневной выручки магазина. Нулевые значения заменяются значениями по умолчанию.
+type ForecastParams struct {
+	Horizon  int      // количество дней прогноза после последнего полного дня
+	History  int      // количество дней истории, по которым строится прогноз
+	Currency string   // валюта прогноза, продажи в других валютах пересчитываются по курсам
+	Metric   string   // MetricGross или MetricNet
+	Models   []string // модели прогноза
+	Level    float64  // вероятность интервала прогноза, 0 < Level < 1
+}
+
+// Validate проверяет параметры прогноза.
+func (p *ForecastParams) Validate() error {
+	if p.Horizon < 0 || p.History < 0 {
+		return fmt.Errorf("horizon and history must be positive")
+	}
+
+	if p.Currency != "" && !IsCurrencyCode(p.Currency) {
+		return fmt.Errorf("currency must be ISO 4217 code")
+	}
+
+	if p.Metric != "" && p.Metric != MetricGross && p.Metric != MetricNet {
+		return fmt.Errorf("unknown metric %q", p.Metric)
+	}
+
+	for _, model := range p.Models {
+		if model != ModelSeasonalNaive && model != ModelHoltWinters {
+			return fmt.Errorf("unknown forecast model %q", model)
+		}
+	}
+
+	if p.Level < 0 || p.Level >= 1 {
+		return fmt.Errorf("level must be between 0 and 1")
+	}
+
+	return nil
+}
+
+// ForecastPoint прогноз выручки на день магазина с границами интервала прогноза.
+type ForecastPoint struct {
+	Date  Date            `json:"date"`
+	Value decimal.Decimal `json:"value"`
+	Lower decimal.Decimal `json:"lower"`
+	Upper decimal.Decimal `json:"upper"`
+}
+
+// ForecastAccuracy ошибки прогноза модели на последних Days днях истории, не использованных при построении прогноза.
+type ForecastAccuracy struct {
+	Days int              `json:"days"`
+	MAE  decimal.Decimal  `json:"mae"`
+	RMSE decimal.Decimal  `json:"rmse"`
+	MAPE *decimal.Decimal `json:"mape"` // в процентах, nil - если во всех днях проверки продаж не было
+}
+
+// ModelForecast прогноз выручки магазина одной моделью.
+type ModelForecast struct {
+	Model    string            `json:"model"`
+	Points   []ForecastPoint   `json:"points"`
+	Backtest *ForecastAccuracy `json:"backtest"` // nil - если истории недостаточно для проверки
+}
+
+// SalesForecast прогноз дневной выручки магазина, построенный по истории продаж с HistoryStart по HistoryEnd.
+type SalesForecast struct {
+	StoreID      string          `json:"store_id"`
+	Currency     string          `json:"currency"`
+	Metric       string          `json:"metric"`
+	Level        float64         `json:"level"`
+	HistoryStart Date            `json:"history_start"`
+	HistoryEnd   Date            `json:"history_end"`
+	Models       []ModelForecast `json:"models"`
+}
@@ -0,0 +1,98 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// PeriodBound граница периода запроса: момент времени или дата магазина.
+// Дата переводится в момент времени только с учетом часового пояса конкретного магазина.
+type PeriodBound struct {
+	instant time.Time
+	day     Date
+	isDay   bool
+}
+
+// ParsePeriodBound разбирает границу периода: момент в формате RFC3339 или дату в формате YYYY-MM-DD.
+func ParsePeriodBound(s string) (PeriodBound, error) {
+	if day, err := ParseDate(s); err == nil {
+		return PeriodBound{day: day, isDay: true}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return PeriodBound{}, fmt.Errorf("date must be in RFC3339 or YYYY-MM-DD format")
+	}
+
+	return PeriodBound{instant: t}, nil
+}
+
+// InstantBound возвращает границу периода, заданную моментом времени.
+func InstantBound(t time.Time) PeriodBound {
+	return PeriodBound{instant: t}
+}
+
+// DayBound возвращает границу периода, заданную датой магазина.
+func DayBound(d Date) PeriodBound {
+	return PeriodBound{day: d, isDay: true}
+}
+
+// AsStart возвращает момент начала периода: для даты - начало дня в поясе loc.
+func (b PeriodBound) AsStart(loc *time.Location) time.Time {
+	if b.isDay {
+		return b.day.Start(loc)
+	}
+
+	return b.instant
+}
+
+// AsEnd возвращает момент конца периода: для даты - конец дня в поясе loc.
+func (b PeriodBound) AsEnd(loc *time.Location) time.Time {
+	if b.isDay {
+		return b.day.End(loc)
+	}
+
+	return b.instant
+}
+
+// Day возвращает дату границы: для момента времени - дату в поясе loc.
+func (b PeriodBound) Day(loc *time.Location) Date {
+	if b.isDay {
+		return b.day
+	}
+
+	return DateOf(b.instant.In(loc))
+}
+
+// Period период запроса, границы включаются.
+type Period struct {
+	Start PeriodBound
+	End   PeriodBound
+}
+
+// ParsePeriod разбирает период запроса.
+func ParsePeriod(start, end string) (Period, error) {
+	startBound, err := ParsePeriodBound(start)
+	if err != nil {
+		return Period{}, err
+	}
+
+	endBound, err := ParsePeriodBound(end)
+	if err != nil {
+		return Period{}, err
+	}
+
+	return Period{Start: startBound, End: endBound}, nil
+}
+
+// Resolve возвращает моменты начала и конца периода в часовом поясе loc.
+func (p Period) Resolve(loc *time.Location) (time.Time, time.Time) {
+	return p.Start.AsStart(loc), p.End.AsEnd(loc)
+}
+
+// StorePeriod период продаж магазина в запросе сумм по нескольким магазинам.
+type StorePeriod struct {
+	StoreID   string    `json:"store_id"`
+	StartDate time.Time `json:"start_date"`
+	EndDate   time.Time `json:"end_date"`
+}
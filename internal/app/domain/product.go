@@ -0,0 +1,17 @@
+package domain
+
+import (
+	"errors"
+)
+
+// UnknownCategory категория продаж товаров, отсутствующих в справочнике.
+const UnknownCategory = "unknown"
+
+var ErrProductNotFound = errors.New("product not found")
+
+// Product товар.
+type Product struct {
+	ID       string `json:"product_id"`
+	Name     string `json:"name"`
+	Category string `json:"category"`
+}
@@ -0,0 +1,46 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrInvalidPurge = errors.New("invalid purge")
+
+// Действия журнала аудита.
+const (
+	AuditPurge = "purge"
+)
+
+// AuditRetention инициатор удаления продаж старше срока хранения.
+const AuditRetention = "retention"
+
+// Purge удаление продаж магазина StoreID (пустой - всех магазинов), совершенных раньше Before (nil - всех
+// продаж магазина).
+type Purge struct {
+	StoreID string     `json:"store_id,omitempty"`
+	Before  *time.Time `json:"before,omitempty"`
+	Reason  string     `json:"reason,omitempty"` // основание удаления для журнала аудита
+}
+
+// Validate проверяет удаление: продажи всех магазинов за все время одним удалением не удаляются.
+func (p *Purge) Validate() error {
+	if p.StoreID == "" && p.Before == nil {
+		return fmt.Errorf("%w: store_id or before required", ErrInvalidPurge)
+	}
+
+	return nil
+}
+
+// AuditEntry запись журнала аудита административных операций.
+type AuditEntry struct {
+	ID     string    `json:"id"`
+	Time   time.Time `json:"time"`
+	Action string    `json:"action"`
+	Actor  string    `json:"actor"` // адрес клиента API или AuditRetention
+
+	Purge   *Purge `json:"purge,omitempty"`
+	Deleted int    `json:"deleted"`         // количество удаленных продаж
+	Error   string `json:"error,omitempty"` // ошибка операции; удаление могло выполниться частично
+}
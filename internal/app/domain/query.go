@@ -0,0 +1,272 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrInvalidQuery  = errors.New("invalid query")
+	ErrQueryTooLarge = errors.New("query result too large") // результат запроса содержит слишком много строк
+)
+
+// Поля продаж для фильтров и группировки запросов. Валюта всегда входит в группу: суммы в разных валютах
+// не складываются.
+const (
+	FieldStore         = "store"
+	FieldProduct       = "product"
+	FieldCurrency      = "currency"
+	FieldPaymentMethod = DimensionPaymentMethod
+	FieldChannel       = DimensionChannel
+)
+
+// Показатели запросов. Все показатели аддитивны: показатели группы - сумма показателей ее частей.
+// С планом сравниваются показатели выручки MetricGross и MetricNet.
+const (
+	MetricGross    = "gross"
+	MetricNet      = "net"
+	MetricDiscount = "discount"
+	MetricTax      = "tax"
+	MetricQuantity = "quantity" // количество проданных единиц товаров
+	MetricSales    = "sales"    // количество продаж (строк)
+)
+
+// Шаги временной разбивки запроса.
+const (
+	BucketHour  = "hour"
+	BucketDay   = "day"
+	BucketWeek  = "week" // недели начинаются с понедельника
+	BucketMonth = "month"
+)
+
+// MaxQueryBuckets максимальное количество интервалов временной разбивки запроса.
+const MaxQueryBuckets = 10000
+
+var (
+	queryFields  = []string{FieldStore, FieldProduct, FieldCurrency, FieldPaymentMethod, FieldChannel}
+	queryMetrics = []string{MetricGross, MetricDiscount, MetricTax, MetricNet, MetricQuantity, MetricSales}
+	queryBuckets = []string{BucketHour, BucketDay, BucketWeek, BucketMonth}
+)
+
+// Query запрос агрегатов продаж за период [StartDate, EndDate]: продажи, значения полей которых входят
+// в Filters, группируются по валюте и полям GroupBy, а при заданном Bucket - еще и по интервалам времени
+// в поясе Location.
+type Query struct {
+	StartDate time.Time
+	EndDate   time.Time
+	Filters   map[string][]string // поле -> допустимые значения; domain.Unspecified - значение измерения не задано
+	GroupBy   []string
+	Metrics   []string
+	Bucket    string
+	Location  *time.Location
+}
+
+// Validate проверяет запрос.
+func (q *Query) Validate() error {
+	if q.StartDate.After(q.EndDate) {
+		return fmt.Errorf("start date after end date")
+	}
+
+	for field, values := range q.Filters {
+		if !contains(queryFields, field) {
+			return fmt.Errorf("unknown filter field %q, expected one of %s", field, strings.Join(queryFields, ", "))
+		}
+
+		if len(values) == 0 {
+			return fmt.Errorf("filter %q has no values", field)
+		}
+	}
+
+	seen := make(map[string]bool, len(q.GroupBy))
+
+	for _, field := range q.GroupBy {
+		if !contains(queryFields, field) {
+			return fmt.Errorf("unknown group by field %q, expected one of %s", field, strings.Join(queryFields, ", "))
+		}
+
+		if seen[field] {
+			return fmt.Errorf("duplicate group by field %q", field)
+		}
+
+		seen[field] = true
+	}
+
+	if len(q.Metrics) == 0 {
+		return fmt.Errorf("metrics not defined")
+	}
+
+	for _, metric := range q.Metrics {
+		if !contains(queryMetrics, metric) {
+			return fmt.Errorf("unknown metric %q, expected one of %s", metric, strings.Join(queryMetrics, ", "))
+		}
+	}
+
+	if q.Bucket == "" {
+		return nil
+	}
+
+	if !contains(queryBuckets, q.Bucket) {
+		return fmt.Errorf("unknown bucket %q, expected one of %s", q.Bucket, strings.Join(queryBuckets, ", "))
+	}
+
+	n := 0
+	for start := q.BucketStart(q.StartDate); !start.After(q.EndDate); start = q.NextBucket(start) {
+		if n++; n > MaxQueryBuckets {
+			return fmt.Errorf("too many buckets, maximum %d", MaxQueryBuckets)
+		}
+	}
+
+	return nil
+}
+
+// Buckets возвращает интервалы разбивки периода запроса (границы включаются), а без разбивки - весь период.
+func (q *Query) Buckets() []StorePeriod {
+	if q.Bucket == "" {
+		return []StorePeriod{{StartDate: q.StartDate, EndDate: q.EndDate}}
+	}
+
+	var buckets []StorePeriod
+
+	for start := q.BucketStart(q.StartDate); !start.After(q.EndDate); start = q.NextBucket(start) {
+		b := StorePeriod{StartDate: start, EndDate: q.NextBucket(start).Add(-time.Nanosecond)}
+
+		if b.StartDate.Before(q.StartDate) {
+			b.StartDate = q.StartDate
+		}
+
+		if b.EndDate.After(q.EndDate) {
+			b.EndDate = q.EndDate
+		}
+
+		buckets = append(buckets, b)
+	}
+
+	return buckets
+}
+
+// BucketStart возвращает начало интервала разбивки, содержащего момент t.
+func (q *Query) BucketStart(t time.Time) time.Time {
+	loc := q.location()
+	t = t.In(loc)
+
+	switch q.Bucket {
+	case BucketHour:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, loc)
+	case BucketDay:
+		return DateOf(t).Start(loc)
+	case BucketWeek:
+		return DateOf(t).AddDays(-(int(t.Weekday()) + 6) % 7).Start(loc)
+	case BucketMonth:
+		return DateOf(time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)).Start(loc)
+	default:
+		return t
+	}
+}
+
+// NextBucket возвращает начало интервала разбивки, следующего за интервалом, начинающимся в start.
+func (q *Query) NextBucket(start time.Time) time.Time {
+	loc := q.location()
+	day := DateOf(start.In(loc))
+
+	switch q.Bucket {
+	case BucketHour:
+		return start.Add(time.Hour)
+	case BucketDay:
+		return day.AddDays(1).Start(loc)
+	case BucketWeek:
+		return day.AddDays(7).Start(loc)
+	default:
+		return day.AddMonths(1).Start(loc)
+	}
+}
+
+func (q *Query) location() *time.Location {
+	if q.Location == nil {
+		return time.UTC
+	}
+
+	return q.Location
+}
+
+// QueryResult результат запроса агрегатов продаж.
+type QueryResult struct {
+	Plan string     `json:"plan"` // способ выполнения запроса хранилищем
+	Rows []QueryRow `json:"rows"`
+}
+
+// QueryRow строка результата запроса: интервал разбивки, значения полей группировки (всегда с валютой)
+// и показатели.
+type QueryRow struct {
+	Bucket  *time.Time                 `json:"bucket,omitempty"`
+	Group   map[string]string          `json:"group"`
+	Metrics map[string]decimal.Decimal `json:"metrics"`
+}
+
+// key возвращает ключ строки для объединения результатов.
+func (r *QueryRow) key() string {
+	fields := make([]string, 0, len(r.Group))
+	for field := range r.Group {
+		fields = append(fields, field)
+	}
+
+	sort.Strings(fields)
+
+	var b strings.Builder
+
+	if r.Bucket != nil {
+		b.WriteString(r.Bucket.UTC().Format(time.RFC3339Nano))
+	}
+
+	for _, field := range fields {
+		b.WriteString("\x00" + field + "=" + r.Group[field])
+	}
+
+	return b.String()
+}
+
+// MergeQueryRows объединяет строки результатов: показатели строк с одинаковыми интервалом и группой
+// складываются. Строки упорядочиваются по интервалу и группе.
+func MergeQueryRows(rows ...[]QueryRow) []QueryRow {
+	var (
+		res   []QueryRow
+		index = make(map[string]int)
+		keys  []string
+	)
+
+	for _, part := range rows {
+		for _, row := range part {
+			key := row.key()
+
+			i, ok := index[key]
+			if !ok {
+				index[key] = len(res)
+				keys = append(keys, key)
+				res = append(res, QueryRow{Bucket: row.Bucket, Group: row.Group, Metrics: make(map[string]decimal.Decimal)})
+				i = len(res) - 1
+			}
+
+			for metric, v := range row.Metrics {
+				res[i].Metrics[metric] = res[i].Metrics[metric].Add(v)
+			}
+		}
+	}
+
+	order := make([]int, len(res))
+	for i := range order {
+		order[i] = i
+	}
+
+	sort.Slice(order, func(a, b int) bool { return keys[order[a]] < keys[order[b]] })
+
+	sorted := make([]QueryRow, 0, len(res))
+	for _, i := range order {
+		sorted = append(sorted, res[i])
+	}
+
+	return sorted
+}
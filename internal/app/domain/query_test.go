@@ -0,0 +1,159 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuery_Validate(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	query := func(modify func(q *Query)) Query {
+		q := Query{
+			StartDate: start,
+			EndDate:   start.AddDate(0, 1, 0),
+			Filters:   map[string][]string{FieldStore: {"store_1"}},
+			GroupBy:   []string{FieldProduct, FieldChannel},
+			Metrics:   []string{MetricGross, MetricQuantity},
+			Bucket:    BucketDay,
+		}
+
+		modify(&q)
+
+		return q
+	}
+
+	testCases := []struct {
+		name  string
+		query Query
+		err   string
+	}{
+		{
+			name:  "корректный запрос",
+			query: query(func(q *Query) {}),
+		},
+		{
+			name:  "без разбивки",
+			query: query(func(q *Query) { q.Bucket = "" }),
+		},
+		{
+			name:  "начало после конца",
+			query: query(func(q *Query) { q.EndDate = start.Add(-time.Hour) }),
+			err:   "start date after end date",
+		},
+		{
+			name:  "неизвестное поле фильтра",
+			query: query(func(q *Query) { q.Filters["category"] = []string{"dairy"} }),
+			err:   `unknown filter field "category", expected one of store, product, currency, payment_method, channel`,
+		},
+		{
+			name:  "фильтр без значений",
+			query: query(func(q *Query) { q.Filters[FieldStore] = nil }),
+			err:   `filter "store" has no values`,
+		},
+		{
+			name:  "повтор поля группировки",
+			query: query(func(q *Query) { q.GroupBy = append(q.GroupBy, FieldProduct) }),
+			err:   `duplicate group by field "product"`,
+		},
+		{
+			name:  "без показателей",
+			query: query(func(q *Query) { q.Metrics = nil }),
+			err:   "metrics not defined",
+		},
+		{
+			name:  "неизвестный показатель",
+			query: query(func(q *Query) { q.Metrics = []string{"margin"} }),
+			err:   `unknown metric "margin", expected one of gross, discount, tax, net, quantity, sales`,
+		},
+		{
+			name:  "неизвестная разбивка",
+			query: query(func(q *Query) { q.Bucket = "year" }),
+			err:   `unknown bucket "year", expected one of hour, day, week, month`,
+		},
+		{
+			name:  "слишком много интервалов",
+			query: query(func(q *Query) { q.Bucket, q.EndDate = BucketHour, start.AddDate(2, 0, 0) }),
+			err:   "too many buckets, maximum 10000",
+		},
+	}
+
+	for _, tt := range testCases {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tt.query.Validate()
+			if tt.err == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func TestQuery_Buckets(t *testing.T) {
+	t.Parallel()
+
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	require.NoError(t, err)
+
+	q := Query{
+		StartDate: time.Date(2024, 6, 5, 10, 0, 0, 0, moscow),
+		EndDate:   time.Date(2024, 6, 19, 12, 0, 0, 0, moscow),
+		Bucket:    BucketWeek,
+		Location:  moscow,
+	}
+
+	// недели начинаются с понедельника: 3, 10 и 17 июня; крайние интервалы обрезаются периодом запроса
+	assert.Equal(t, []StorePeriod{
+		{StartDate: q.StartDate, EndDate: time.Date(2024, 6, 10, 0, 0, 0, 0, moscow).Add(-time.Nanosecond)},
+		{StartDate: time.Date(2024, 6, 10, 0, 0, 0, 0, moscow), EndDate: time.Date(2024, 6, 17, 0, 0, 0, 0, moscow).Add(-time.Nanosecond)},
+		{StartDate: time.Date(2024, 6, 17, 0, 0, 0, 0, moscow), EndDate: q.EndDate},
+	}, q.Buckets())
+
+	assert.Equal(t, time.Date(2024, 6, 3, 0, 0, 0, 0, moscow), q.BucketStart(q.StartDate))
+
+	// 2024-05-31 22:30 UTC - уже 1 июня в Москве
+	q.Bucket = BucketMonth
+	assert.Equal(t, time.Date(2024, 6, 1, 0, 0, 0, 0, moscow), q.BucketStart(time.Date(2024, 5, 31, 22, 30, 0, 0, time.UTC)))
+	assert.Equal(t, time.Date(2024, 7, 1, 0, 0, 0, 0, moscow), q.NextBucket(time.Date(2024, 6, 1, 0, 0, 0, 0, moscow)))
+
+	q.Bucket = ""
+	assert.Equal(t, []StorePeriod{{StartDate: q.StartDate, EndDate: q.EndDate}}, q.Buckets())
+}
+
+func TestMergeQueryRows(t *testing.T) {
+	t.Parallel()
+
+	june := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	may := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	row := func(bucket time.Time, store string, gross int64) QueryRow {
+		return QueryRow{
+			Bucket:  &bucket,
+			Group:   map[string]string{FieldCurrency: "RUB", FieldStore: store},
+			Metrics: map[string]decimal.Decimal{MetricGross: decimal.NewFromInt(gross)},
+		}
+	}
+
+	rows := MergeQueryRows(
+		[]QueryRow{row(june, "store_1", 10), row(may, "store_2", 5)},
+		[]QueryRow{row(june, "store_1", 7), row(may, "store_1", 3)},
+	)
+
+	require.Len(t, rows, 3)
+	assert.Equal(t, row(may, "store_1", 3), rows[0])
+	assert.Equal(t, row(may, "store_2", 5), rows[1])
+	assert.True(t, decimal.NewFromInt(17).Equal(rows[2].Metrics[MetricGross]))
+	assert.Equal(t, june, *rows[2].Bucket)
+}
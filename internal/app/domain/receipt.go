@@ -0,0 +1,112 @@
+package domain
+
+import (
+	"errors"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// ErrReceiptExists чек с таким идентификатором уже сохранен в магазине.
+var ErrReceiptExists = errors.New("receipt already exists")
+
+// Receipt чек: покупка нескольких товаров. Строки чека сохраняются продажами с общими магазином, датой,
+// валютой, идентификатором чека, способом оплаты и каналом, причем атомарно - сохраняются все строки или ни одной.
+type Receipt struct {
+	ID            string
+	StoreID       string
+	PaymentMethod string
+	Channel       string
+	Currency      string
+	SaleDate      time.Time
+	Lines         []ReceiptLine
+}
+
+// ReceiptLine строка чека.
+type ReceiptLine struct {
+	ProductID    string
+	QuantitySold int64
+	SalePrice    decimal.Decimal // цена единицы товара с НДС
+	Discount     decimal.Decimal // скидка на всю строку
+	VATRate      decimal.Decimal // ставка НДС в процентах
+}
+
+// Sales возвращает продажи строк чека.
+func (r *Receipt) Sales() []*Sale {
+	sales := make([]*Sale, 0, len(r.Lines))
+
+	for _, line := range r.Lines {
+		sales = append(sales, &Sale{
+			ProductID:     line.ProductID,
+			StoreID:       r.StoreID,
+			QuantitySold:  line.QuantitySold,
+			SalePrice:     line.SalePrice,
+			Discount:      line.Discount,
+			VATRate:       line.VATRate,
+			Currency:      r.Currency,
+			SaleDate:      r.SaleDate,
+			ReceiptID:     r.ID,
+			PaymentMethod: r.PaymentMethod,
+			Channel:       r.Channel,
+		})
+	}
+
+	return sales
+}
+
+// Amounts возвращает суммы чека - сумму сумм его строк.
+func (r *Receipt) Amounts() Amounts {
+	var total Amounts
+
+	for _, sale := range r.Sales() {
+		total = total.Add(sale.Amounts())
+	}
+
+	return total
+}
+
+// ReceiptStats показатели чеков в одной валюте. Продажи вне чеков не учитываются.
+type ReceiptStats struct {
+	Receipts int64   `json:"receipts"`
+	Lines    int64   `json:"lines"`
+	Items    int64   `json:"items"` // количество проданных единиц товаров
+	Amounts  Amounts `json:"amounts"`
+}
+
+// Add возвращает сумму показателей.
+func (s ReceiptStats) Add(other ReceiptStats) ReceiptStats {
+	return ReceiptStats{
+		Receipts: s.Receipts + other.Receipts,
+		Lines:    s.Lines + other.Lines,
+		Items:    s.Items + other.Items,
+		Amounts:  s.Amounts.Add(other.Amounts),
+	}
+}
+
+// AverageBasket возвращает средний чек - валовую выручку на чек, округленную до AmountPrecision знаков.
+func (s ReceiptStats) AverageBasket() decimal.Decimal {
+	if s.Receipts == 0 {
+		return decimal.Zero
+	}
+
+	return s.Amounts.Gross.Div(decimal.NewFromInt(s.Receipts)).Round(AmountPrecision)
+}
+
+// ItemsPerBasket возвращает среднее количество единиц товаров в чеке.
+func (s ReceiptStats) ItemsPerBasket() decimal.Decimal {
+	if s.Receipts == 0 {
+		return decimal.Zero
+	}
+
+	return decimal.NewFromInt(s.Items).Div(decimal.NewFromInt(s.Receipts)).Round(AmountPrecision)
+}
+
+// ReceiptTotals показатели чеков в разрезе валют (код валюты ISO 4217 -> показатели).
+type ReceiptTotals map[string]ReceiptStats
+
+// Add прибавляет к показателям other.
+func (t ReceiptTotals) Add(other ReceiptTotals) {
+	for currency, stats := range other {
+		t[currency] = t[currency].Add(stats)
+	}
+}
@@ -0,0 +1,62 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrReadOnlyReplica запись продаж на ведомом экземпляре: продажи принимает только ведущий.
+	ErrReadOnlyReplica = errors.New("read-only replica, write to the leader")
+	// ErrLeaderOnly чтение на ведомом экземпляре данных, которые есть только на ведущем.
+	ErrLeaderOnly = errors.New("not replicated, read from the leader")
+	// ErrReferenceCopy изменение справочных данных на узле кластера, который поддерживает их копию: справочные
+	// данные изменяются на первом узле кластера.
+	ErrReferenceCopy = errors.New("reference data is a copy, write to the first cluster node")
+	// ErrLogGenerationChanged журнал магазина начат заново в новом поколении или эпохе ведущего после чтения
+	// его версии.
+	ErrLogGenerationChanged = errors.New("store log generation changed")
+)
+
+const (
+	RoleLeader   = "leader"
+	RoleFollower = "follower"
+)
+
+// LogVersion версия журнала продаж магазина.
+type LogVersion struct {
+	// поколение журнала: увеличивается, когда продажи магазина удаляются и журнал начинается заново
+	Generation int `json:"generation"`
+	Version    int `json:"version"` // количество продаж в журнале поколения
+}
+
+// LogVersions версии журналов продаж магазинов ведущего экземпляра.
+type LogVersions struct {
+	// эпоха ведущего: меняется при каждом запуске ведущего, продажи которого хранятся в памяти, поэтому
+	// после перезапуска журналы всех магазинов начинаются заново
+	Epoch  string                `json:"epoch"`
+	Stores map[string]LogVersion `json:"stores"`
+}
+
+// ReferenceData справочные данные экземпляра: справочник магазинов и товаров и планы. Ведомый экземпляр
+// поддерживает копию справочных данных ведущего, а узлы кластера - копию справочных данных первого узла.
+type ReferenceData struct {
+	Stores   []*Store   `json:"stores"`
+	Products []*Product `json:"products"`
+	Targets  []*Target  `json:"targets"`
+}
+
+// ReplicationStatus состояние репликации экземпляра.
+type ReplicationStatus struct {
+	Role   string `json:"role"`
+	Leader string `json:"leader,omitempty"` // адрес ведущего экземпляра (для ведомого)
+
+	// версии магазинов (количество принятых продаж) на экземпляре
+	Versions map[string]int `json:"versions"`
+
+	// отставание ведомого - количество продаж, принятых ведущим, но еще не примененных, на момент последней
+	// синхронизации, и время последней успешной синхронизации
+	Lag      int       `json:"lag"`
+	LastSync time.Time `json:"last_sync"`
+	Error    string    `json:"error,omitempty"`
+}
@@ -0,0 +1,141 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var (
+	ErrReportNotFound    = errors.New("report not found")
+	ErrReportRunNotFound = errors.New("report run not found")
+	ErrInvalidReport     = errors.New("invalid report")
+)
+
+// Форматы отчетов.
+const (
+	ReportCSV  = "csv"
+	ReportJSON = "json"
+	ReportHTML = "html"
+)
+
+// Периоды отчетов: последний полный день, неделя (с понедельника) или месяц перед запуском в часовом поясе отчета.
+const (
+	ReportPreviousDay   = "previous_day"
+	ReportPreviousWeek  = "previous_week"
+	ReportPreviousMonth = "previous_month"
+)
+
+var (
+	reportFormats = map[string]string{
+		ReportCSV:  "text/csv; charset=utf-8",
+		ReportJSON: "application/json",
+		ReportHTML: "text/html; charset=utf-8",
+	}
+	reportPeriods = []string{ReportPreviousDay, ReportPreviousWeek, ReportPreviousMonth}
+)
+
+// Report отчет по расписанию: запрос агрегатов продаж за период Period, выполняемый по расписанию Schedule
+// (выражение cron, например "0 9 * * mon") в часовом поясе TimeZone. Результат сохраняется в формате Format
+// и, если задан WebhookURL, отправляется на этот адрес.
+type Report struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Schedule string `json:"schedule"`
+	TimeZone string `json:"time_zone,omitempty"` // часовой пояс IANA, пустой - UTC
+	Period   string `json:"period"`
+	Format   string `json:"format"`
+
+	// запрос агрегатов, как в POST /query
+	Filters map[string][]string `json:"filters,omitempty"`
+	GroupBy []string            `json:"group_by,omitempty"`
+	Metrics []string            `json:"metrics"`
+	Bucket  string              `json:"bucket,omitempty"`
+
+	WebhookURL string `json:"webhook_url,omitempty"`
+	// Secret ключ подписи запросов HMAC-SHA256, пустой - запросы не подписываются
+	Secret string `json:"secret,omitempty"`
+}
+
+// Validate проверяет описание отчета, кроме расписания, часового пояса и запроса.
+func (r *Report) Validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("report name not defined")
+	}
+
+	if _, ok := reportFormats[r.Format]; !ok {
+		return fmt.Errorf("unknown format %q, expected %s, %s or %s", r.Format, ReportCSV, ReportJSON, ReportHTML)
+	}
+
+	if !contains(reportPeriods, r.Period) {
+		return fmt.Errorf("unknown period %q, expected one of %s", r.Period, strings.Join(reportPeriods, ", "))
+	}
+
+	if r.WebhookURL != "" {
+		u, err := url.Parse(r.WebhookURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid webhook url %q", r.WebhookURL)
+		}
+	}
+
+	return nil
+}
+
+// Resolve возвращает период отчета, запущенного в момент at, в часовом поясе момента: последний полный
+// календарный день, неделю или месяц перед at.
+func (r *Report) Resolve(at time.Time) (time.Time, time.Time) {
+	loc := at.Location()
+	today := DateOf(at)
+
+	var start, end Date
+
+	switch r.Period {
+	case ReportPreviousWeek:
+		monday := today.AddDays(-(int(at.Weekday()) + 6) % 7)
+		start, end = monday.AddDays(-7), monday.AddDays(-1)
+	case ReportPreviousMonth:
+		first := Date{Year: today.Year, Month: today.Month, Day: 1}
+		start, end = first.AddMonths(-1), first.AddDays(-1)
+	default:
+		start, end = today.AddDays(-1), today.AddDays(-1)
+	}
+
+	return start.Start(loc), end.End(loc)
+}
+
+// Query возвращает запрос агрегатов отчета за период [startDate, endDate] в часовом поясе loc.
+func (r *Report) Query(startDate, endDate time.Time, loc *time.Location) Query {
+	return Query{
+		StartDate: startDate,
+		EndDate:   endDate,
+		Filters:   r.Filters,
+		GroupBy:   r.GroupBy,
+		Metrics:   r.Metrics,
+		Bucket:    r.Bucket,
+		Location:  loc,
+	}
+}
+
+// ReportContentType возвращает MIME-тип отчета в формате format.
+func ReportContentType(format string) string {
+	return reportFormats[format]
+}
+
+// ReportRun запуск отчета: период запроса, результат и доставка. Содержимое отчета хранится отдельно.
+type ReportRun struct {
+	ID        string    `json:"id"`
+	ReportID  string    `json:"report_id"`
+	Format    string    `json:"format"`
+	StartedAt time.Time `json:"started_at"`
+	StartDate time.Time `json:"start_date"`
+	EndDate   time.Time `json:"end_date"`
+
+	Rows  int    `json:"rows"`
+	Size  int    `json:"size"`            // размер содержимого в байтах
+	Error string `json:"error,omitempty"` // ошибка запроса, содержимого нет
+
+	Delivered     bool   `json:"delivered,omitempty"`
+	DeliveryError string `json:"delivery_error,omitempty"`
+}
@@ -0,0 +1,67 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReport_Resolve(t *testing.T) {
+	t.Parallel()
+
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name     string
+		period   string
+		at       time.Time
+		expStart time.Time
+		expEnd   time.Time
+	}{
+		{
+			name:     "предыдущий день",
+			period:   ReportPreviousDay,
+			at:       time.Date(2024, 3, 1, 0, 30, 0, 0, moscow),
+			expStart: time.Date(2024, 2, 29, 0, 0, 0, 0, moscow),
+			expEnd:   time.Date(2024, 2, 29, 23, 59, 59, 999999999, moscow),
+		},
+		{
+			name:     "предыдущая неделя в понедельник",
+			period:   ReportPreviousWeek,
+			at:       time.Date(2024, 4, 15, 9, 0, 0, 0, moscow),
+			expStart: time.Date(2024, 4, 8, 0, 0, 0, 0, moscow),
+			expEnd:   time.Date(2024, 4, 14, 23, 59, 59, 999999999, moscow),
+		},
+		{
+			name:     "предыдущая неделя в воскресенье",
+			period:   ReportPreviousWeek,
+			at:       time.Date(2024, 4, 14, 23, 0, 0, 0, moscow),
+			expStart: time.Date(2024, 4, 1, 0, 0, 0, 0, moscow),
+			expEnd:   time.Date(2024, 4, 7, 23, 59, 59, 999999999, moscow),
+		},
+		{
+			name:     "предыдущий месяц на границе года",
+			period:   ReportPreviousMonth,
+			at:       time.Date(2024, 1, 10, 9, 0, 0, 0, moscow),
+			expStart: time.Date(2023, 12, 1, 0, 0, 0, 0, moscow),
+			expEnd:   time.Date(2023, 12, 31, 23, 59, 59, 999999999, moscow),
+		},
+	}
+
+	for _, tt := range testCases {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := &Report{Period: tt.period}
+
+			start, end := r.Resolve(tt.at)
+			assert.True(t, tt.expStart.Equal(start), "start %s", start)
+			assert.True(t, tt.expEnd.Equal(end), "end %s", end)
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrInvalidSale     = errors.New("invalid sale")
+	ErrRuleViolation   = errors.New("sale violates business rule")
+	errRuleNotDeclared = errors.New("rule parameters not defined")
+)
+
+// Типы бизнес-правил проверки продаж.
+const (
+	RuleMaxQuantity   = "max_quantity"   // количество товара в продаже не больше MaxQuantity
+	RulePriceRange    = "price_range"    // цена единицы товара в пределах [MinPrice, MaxPrice]
+	RuleAllowedStores = "allowed_stores" // продажи принимаются только от магазинов Allowed
+	RuleSaleDate      = "sale_date"      // дата продажи не позже MaxFuture от текущего момента и не старше MaxAgeDays дней
+)
+
+// Rule бизнес-правило проверки продаж. Правила max_quantity, price_range и sale_date применяются к продажам
+// магазинов Stores и товаров Products (пустой список - всех магазинов или товаров).
+type Rule struct {
+	Name     string   `json:"name"`
+	Type     string   `json:"type"`
+	Stores   []string `json:"stores,omitempty"`
+	Products []string `json:"products,omitempty"`
+
+	MaxQuantity int64 `json:"max_quantity,omitempty"`
+
+	MinPrice *decimal.Decimal `json:"min_price,omitempty"`
+	MaxPrice *decimal.Decimal `json:"max_price,omitempty"`
+	Currency string           `json:"currency,omitempty"` // валюта цен правила, пустая - правило применяется к любой валюте
+
+	Allowed []string `json:"allowed,omitempty"`
+
+	MaxFuture  Duration `json:"max_future,omitempty"` // допустимое опережение часов кассы
+	MaxAgeDays int      `json:"max_age_days,omitempty"`
+}
+
+// Validate проверяет описание правила.
+func (r *Rule) Validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("rule name not defined")
+	}
+
+	var err error
+
+	switch r.Type {
+	case RuleMaxQuantity:
+		if r.MaxQuantity <= 0 {
+			err = errRuleNotDeclared
+		}
+	case RulePriceRange:
+		if r.MinPrice == nil && r.MaxPrice == nil {
+			err = errRuleNotDeclared
+		}
+
+		if r.Currency != "" && !IsCurrencyCode(r.Currency) {
+			err = fmt.Errorf("currency must be ISO 4217 code")
+		}
+	case RuleAllowedStores:
+		if len(r.Allowed) == 0 {
+			err = errRuleNotDeclared
+		}
+	case RuleSaleDate:
+		if r.MaxFuture < 0 || r.MaxAgeDays < 0 {
+			err = fmt.Errorf("max_future and max_age_days must not be negative")
+		}
+	default:
+		err = fmt.Errorf("unknown rule type %q", r.Type)
+	}
+
+	if err != nil {
+		return fmt.Errorf("rule %q: %w", r.Name, err)
+	}
+
+	return nil
+}
+
+// Applies сообщает, что правило применяется к продаже.
+func (r *Rule) Applies(sale *Sale) bool {
+	if r.Type == RuleAllowedStores {
+		return true
+	}
+
+	if r.Type == RulePriceRange && r.Currency != "" && r.Currency != sale.Currency {
+		return false
+	}
+
+	return (len(r.Stores) == 0 || contains(r.Stores, sale.StoreID)) &&
+		(len(r.Products) == 0 || contains(r.Products, sale.ProductID))
+}
+
+// Check проверяет продажу, к которой применяется правило, на момент now и возвращает описание нарушения.
+func (r *Rule) Check(sale *Sale, now time.Time) error {
+	switch r.Type {
+	case RuleMaxQuantity:
+		if sale.QuantitySold > r.MaxQuantity {
+			return fmt.Errorf("quantity %d exceeds %d", sale.QuantitySold, r.MaxQuantity)
+		}
+	case RulePriceRange:
+		if r.MinPrice != nil && sale.SalePrice.LessThan(*r.MinPrice) {
+			return fmt.Errorf("price %s below %s", sale.SalePrice, r.MinPrice)
+		}
+
+		if r.MaxPrice != nil && sale.SalePrice.GreaterThan(*r.MaxPrice) {
+			return fmt.Errorf("price %s above %s", sale.SalePrice, r.MaxPrice)
+		}
+	case RuleAllowedStores:
+		if !contains(r.Allowed, sale.StoreID) {
+			return fmt.Errorf("store %q not allowed", sale.StoreID)
+		}
+	case RuleSaleDate:
+		if sale.SaleDate.After(now.Add(time.Duration(r.MaxFuture))) {
+			return fmt.Errorf("sale date %s in the future", sale.SaleDate.Format(time.RFC3339))
+		}
+
+		if r.MaxAgeDays > 0 && sale.SaleDate.Before(now.AddDate(0, 0, -r.MaxAgeDays)) {
+			return fmt.Errorf("sale date %s older than %d days", sale.SaleDate.Format(time.RFC3339), r.MaxAgeDays)
+		}
+	}
+
+	return nil
+}
+
+// RuleStats статистика применения правила: сколько продаж проверено и сколько из них нарушили правило.
+type RuleStats struct {
+	Rule    Rule  `json:"rule"`
+	Checked int64 `json:"checked"`
+	Hits    int64 `json:"hits"`
+}
+
+// Duration длительность, в JSON задается строкой в формате time.ParseDuration, например "15m".
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(v)
+
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
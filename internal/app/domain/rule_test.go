@@ -0,0 +1,162 @@
+package domain
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRule_Check(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 6, 20, 12, 0, 0, 0, time.UTC)
+
+	sale := func(modify func(s *Sale)) *Sale {
+		s := &Sale{
+			StoreID:      "store_1",
+			ProductID:    "milk",
+			QuantitySold: 10,
+			SalePrice:    decimal.NewFromInt(90),
+			Currency:     "RUB",
+			SaleDate:     now.Add(-time.Hour),
+		}
+
+		if modify != nil {
+			modify(s)
+		}
+
+		return s
+	}
+
+	price := func(v int64) *decimal.Decimal {
+		d := decimal.NewFromInt(v)
+		return &d
+	}
+
+	testCases := []struct {
+		name      string
+		rule      Rule
+		sale      *Sale
+		applies   bool
+		violation string
+	}{
+		{
+			name:    "количество в пределах",
+			rule:    Rule{Type: RuleMaxQuantity, Products: []string{"milk"}, MaxQuantity: 10},
+			sale:    sale(nil),
+			applies: true,
+		},
+		{
+			name:      "количество превышено",
+			rule:      Rule{Type: RuleMaxQuantity, Products: []string{"milk"}, MaxQuantity: 5},
+			sale:      sale(nil),
+			applies:   true,
+			violation: "quantity 10 exceeds 5",
+		},
+		{
+			name: "правило другого товара",
+			rule: Rule{Type: RuleMaxQuantity, Products: []string{"bread"}, MaxQuantity: 5},
+			sale: sale(nil),
+		},
+		{
+			name:      "цена ниже минимальной",
+			rule:      Rule{Type: RulePriceRange, MinPrice: price(100), Currency: "RUB"},
+			sale:      sale(nil),
+			applies:   true,
+			violation: "price 90 below 100",
+		},
+		{
+			name:      "цена выше максимальной",
+			rule:      Rule{Type: RulePriceRange, Stores: []string{"store_1"}, MaxPrice: price(50)},
+			sale:      sale(nil),
+			applies:   true,
+			violation: "price 90 above 50",
+		},
+		{
+			name: "цены правила в другой валюте",
+			rule: Rule{Type: RulePriceRange, MinPrice: price(100), Currency: "KZT"},
+			sale: sale(nil),
+		},
+		{
+			name:      "магазин не разрешен",
+			rule:      Rule{Type: RuleAllowedStores, Allowed: []string{"store_2"}},
+			sale:      sale(nil),
+			applies:   true,
+			violation: `store "store_1" not allowed`,
+		},
+		{
+			name:    "опережение часов кассы в пределах допуска",
+			rule:    Rule{Type: RuleSaleDate, MaxFuture: Duration(5 * time.Minute)},
+			sale:    sale(func(s *Sale) { s.SaleDate = now.Add(time.Minute) }),
+			applies: true,
+		},
+		{
+			name:      "продажа в будущем",
+			rule:      Rule{Type: RuleSaleDate, MaxFuture: Duration(5 * time.Minute)},
+			sale:      sale(func(s *Sale) { s.SaleDate = now.Add(time.Hour) }),
+			applies:   true,
+			violation: "sale date 2024-06-20T13:00:00Z in the future",
+		},
+		{
+			name:      "слишком старая продажа",
+			rule:      Rule{Type: RuleSaleDate, MaxAgeDays: 30},
+			sale:      sale(func(s *Sale) { s.SaleDate = now.AddDate(0, -2, 0) }),
+			applies:   true,
+			violation: "sale date 2024-04-20T12:00:00Z older than 30 days",
+		},
+	}
+
+	for _, tt := range testCases {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.applies, tt.rule.Applies(tt.sale))
+			if !tt.applies {
+				return
+			}
+
+			err := tt.rule.Check(tt.sale, now)
+			if tt.violation == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.EqualError(t, err, tt.violation)
+		})
+	}
+}
+
+func TestRule_Validate(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, (&Rule{Name: "stores", Type: RuleAllowedStores, Allowed: []string{"store_1"}}).Validate())
+	assert.NoError(t, (&Rule{Name: "no future", Type: RuleSaleDate}).Validate())
+
+	assert.Error(t, (&Rule{Type: RuleAllowedStores, Allowed: []string{"store_1"}}).Validate())
+	assert.Error(t, (&Rule{Name: "quantity", Type: RuleMaxQuantity}).Validate())
+	assert.Error(t, (&Rule{Name: "price", Type: RulePriceRange}).Validate())
+	assert.Error(t, (&Rule{Name: "stores", Type: RuleAllowedStores}).Validate())
+	assert.Error(t, (&Rule{Name: "unknown", Type: "max_discount"}).Validate())
+}
+
+func TestRule_JSON(t *testing.T) {
+	t.Parallel()
+
+	var rule Rule
+
+	require.NoError(t, json.Unmarshal([]byte(`{"name":"date","type":"sale_date","max_future":"5m","max_age_days":30}`), &rule))
+	assert.Equal(t, Duration(5*time.Minute), rule.MaxFuture)
+	assert.Equal(t, 30, rule.MaxAgeDays)
+
+	data, err := json.Marshal(rule)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"name":"date","type":"sale_date","max_future":"5m0s","max_age_days":30}`, string(data))
+
+	assert.Error(t, json.Unmarshal([]byte(`{"max_future":"5 minutes"}`), &rule))
+}
@@ -1,15 +1,65 @@
 package domain
 
 import (
+	"errors"
+	"sort"
 	"time"
 
 	"github.com/shopspring/decimal"
 )
 
+// ErrSaleOutOfRange суммы или дата продажи выходят за пределы, поддерживаемые хранилищем.
+var ErrSaleOutOfRange = errors.New("sale out of supported range")
+
 type Sale struct {
 	ProductID    string
 	StoreID      string
 	QuantitySold int64
-	SalePrice    decimal.Decimal
+	SalePrice    decimal.Decimal // цена единицы товара с НДС
+	Discount     decimal.Decimal // скидка на всю строку продажи
+	VATRate      decimal.Decimal // ставка НДС в процентах
+	Currency     string
 	SaleDate     time.Time
+
+	// чек, в который входит продажа; пустой у продаж, принятых вне чека
+	ReceiptID string `json:",omitempty"`
+
+	// измерения продажи (см. Dimension): способ оплаты (Payment*) и канал продаж (Channel*), пустые - не заданы
+	PaymentMethod string `json:",omitempty"`
+	Channel       string `json:",omitempty"`
+}
+
+// Totals суммы продаж в разрезе валют (код валюты ISO 4217 -> суммы).
+type Totals map[string]Amounts
+
+// Add прибавляет к суммам other.
+func (t Totals) Add(other Totals) {
+	for currency, amounts := range other {
+		t[currency] = t[currency].Add(amounts)
+	}
+}
+
+// IsZero сообщает, что продаж нет ни в одной валюте.
+func (t Totals) IsZero() bool {
+	for _, amounts := range t {
+		if !amounts.IsZero() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Currencies возвращает валюты, в которых есть продажи, по возрастанию кода.
+func (t Totals) Currencies() []string {
+	currencies := make([]string, 0, len(t))
+	for currency, amounts := range t {
+		if !amounts.IsZero() {
+			currencies = append(currencies, currency)
+		}
+	}
+
+	sort.Strings(currencies)
+
+	return currencies
 }
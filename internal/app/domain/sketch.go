@@ -0,0 +1,105 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+
+	"go.dataflow.ru/service-sales/pkg/sketch"
+)
+
+// Интервалы разбивки приближенной статистики продаж.
+var sketchBuckets = []string{BucketDay, BucketWeek, BucketMonth}
+
+// DefaultQuantiles квантили размера чека по умолчанию: медиана, 90-й и 99-й процентили.
+var DefaultQuantiles = []float64{0.5, 0.9, 0.99}
+
+// SalesSketches приближенная статистика продаж: оценка количества различных проданных товаров и распределение
+// размеров чеков по валютам. Статистики разных интервалов и магазинов объединяются (Merge).
+//
+// Размер чека - валовая сумма всех его строк; продажа вне чека считается отдельным чеком.
+type SalesSketches struct {
+	Products *sketch.HLL                `json:"products"`
+	Tickets  map[string]*sketch.TDigest `json:"tickets"` // код валюты ISO 4217 -> размеры чеков
+}
+
+// NewSalesSketches возвращает пустую статистику.
+func NewSalesSketches() *SalesSketches {
+	return &SalesSketches{
+		Products: sketch.NewHLL(),
+		Tickets:  make(map[string]*sketch.TDigest),
+	}
+}
+
+// AddProduct учитывает проданный товар.
+func (s *SalesSketches) AddProduct(productID string) {
+	s.Products.Add(productID)
+}
+
+// AddTicket учитывает чек с валовой суммой gross в валюте currency.
+func (s *SalesSketches) AddTicket(currency string, gross float64) {
+	s.tickets(currency).Add(gross)
+}
+
+// Merge добавляет к статистике other. other не изменяется.
+func (s *SalesSketches) Merge(other *SalesSketches) {
+	if other == nil {
+		return
+	}
+
+	s.Products.Merge(other.Products)
+
+	for currency, tickets := range other.Tickets {
+		s.tickets(currency).Merge(tickets)
+	}
+}
+
+// Compress сжимает распределения размеров чеков: после сжатия статистика читается без изменений
+// (см. sketch.TDigest.Compress).
+func (s *SalesSketches) Compress() {
+	for _, tickets := range s.Tickets {
+		tickets.Compress()
+	}
+}
+
+func (s *SalesSketches) tickets(currency string) *sketch.TDigest {
+	tickets, ok := s.Tickets[currency]
+	if !ok {
+		tickets = sketch.NewTDigest(sketch.DefaultCompression)
+		s.Tickets[currency] = tickets
+	}
+
+	return tickets
+}
+
+// SketchBucket приближенная статистика продаж за интервал разбивки, начинающийся в день Start
+// (в часовом поясе магазина).
+type SketchBucket struct {
+	Start    Date
+	Sketches *SalesSketches
+}
+
+// SketchSeries приближенная статистика продаж магазинов за период и по интервалам разбивки.
+type SketchSeries struct {
+	Total   *SalesSketches
+	Buckets []SketchBucket // по возрастанию Start; пустой без разбивки
+}
+
+// ValidateSketchBucket проверяет интервал разбивки приближенной статистики: day, week, month или пустой.
+func ValidateSketchBucket(bucket string) error {
+	if bucket != "" && !contains(sketchBuckets, bucket) {
+		return fmt.Errorf("unknown bucket %q, expected one of %s", bucket, strings.Join(sketchBuckets, ", "))
+	}
+
+	return nil
+}
+
+// ValidateQuantiles проверяет, что квантили лежат в отрезке [0, 1].
+func ValidateQuantiles(quantiles []float64) error {
+	for _, q := range quantiles {
+		if q < 0 || q > 1 {
+			return fmt.Errorf("quantile %v out of range [0, 1]", q)
+		}
+	}
+
+	return nil
+}
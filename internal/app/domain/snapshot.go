@@ -0,0 +1,21 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrSnapshotNotFound = errors.New("snapshot not found or expired")
+	ErrTooManySnapshots = errors.New("too many open snapshots")
+)
+
+// Snapshot снимок продаж для согласованного чтения: все запросы с одним снимком видят одно и то же состояние
+// продаж - продажи, принятые до открытия снимка.
+type Snapshot struct {
+	Token     string    `json:"snapshot"`
+	ExpiresAt time.Time `json:"expires_at"`
+
+	// версия каждого магазина в снимке - количество продаж магазина, принятых до открытия снимка
+	Versions map[string]int `json:"versions"`
+}
@@ -0,0 +1,17 @@
+package domain
+
+import (
+	"errors"
+)
+
+// ErrQueryTimeout запрос не выполнен за отведенное время.
+var ErrQueryTimeout = errors.New("query timeout")
+
+// SQLResult результат SQL-запроса к продажам. Значения строк - в порядке Columns: числа (decimal.Decimal),
+// строки, моменты времени или nil (NULL). Truncated - строк больше максимального количества, и лишние
+// строки отброшены.
+type SQLResult struct {
+	Columns   []string        `json:"columns"`
+	Rows      [][]interface{} `json:"rows"`
+	Truncated bool            `json:"truncated"`
+}
@@ -0,0 +1,32 @@
+package domain
+
+import (
+	"errors"
+)
+
+var ErrStoreNotFound = errors.New("store not found")
+
+// Store магазин сети.
+type Store struct {
+	ID         string            `json:"store_id"`
+	Name       string            `json:"name"`
+	TimeZone   string            `json:"time_zone"`  // часовой пояс IANA, например Europe/Moscow
+	Attributes map[string]string `json:"attributes"` // произвольные атрибуты: регион, формат, город и т.п.
+}
+
+// Matches сообщает, что у магазина есть все атрибуты filter с теми же значениями.
+func (s *Store) Matches(filter map[string]string) bool {
+	for k, v := range filter {
+		if s.Attributes[k] != v {
+			return false
+		}
+	}
+
+	return true
+}
+
+// DailyTotals суммы продаж магазина за календарный день.
+type DailyTotals struct {
+	Date   Date   `json:"date"`
+	Totals Totals `json:"totals"`
+}
@@ -0,0 +1,86 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrTargetNotFound = errors.New("target not found")
+	ErrTargetOverlap  = errors.New("target period overlaps another target of the store")
+)
+
+// Target план выручки магазина на период - даты магазина с Start по End включительно.
+// Периоды планов одного магазина не пересекаются, план идентифицируется магазином и датой начала.
+type Target struct {
+	StoreID  string          `json:"store_id"`
+	Start    Date            `json:"start_date"`
+	End      Date            `json:"end_date"`
+	Amount   decimal.Decimal `json:"amount"`
+	Currency string          `json:"currency"`
+	Metric   string          `json:"metric"` // MetricGross (по умолчанию) или MetricNet
+}
+
+// Validate проверяет план.
+func (t *Target) Validate() error {
+	if t.StoreID == "" {
+		return fmt.Errorf("store_id not defined")
+	}
+
+	if t.End.Before(t.Start) {
+		return fmt.Errorf("target of store %q: end date before start date", t.StoreID)
+	}
+
+	if t.Amount.IsNegative() {
+		return fmt.Errorf("target of store %q: amount must not be negative", t.StoreID)
+	}
+
+	if !IsCurrencyCode(t.Currency) {
+		return fmt.Errorf("target of store %q: invalid currency", t.StoreID)
+	}
+
+	if t.Metric != MetricGross && t.Metric != MetricNet {
+		return fmt.Errorf("target of store %q: unknown metric %q", t.StoreID, t.Metric)
+	}
+
+	return nil
+}
+
+// Contains сообщает, что дата входит в период плана.
+func (t *Target) Contains(d Date) bool {
+	return !d.Before(t.Start) && !d.After(t.End)
+}
+
+// Overlaps сообщает, что периоды планов пересекаются.
+func (t *Target) Overlaps(other *Target) bool {
+	return !t.End.Before(other.Start) && !other.End.Before(t.Start)
+}
+
+// Value возвращает значение метрики плана из сумм продаж.
+func (t *Target) Value(amounts Amounts) decimal.Decimal {
+	if t.Metric == MetricNet {
+		return amounts.Net
+	}
+
+	return amounts.Gross
+}
+
+// TargetAttainment выполнение плана магазина на момент AsOf.
+type TargetAttainment struct {
+	Target *Target `json:"target"`
+	AsOf   Date    `json:"as_of"`
+
+	// фактическая выручка с начала периода плана по AsOf включительно в валюте плана
+	Actual decimal.Decimal `json:"actual"`
+	// процент выполнения плана
+	Attainment decimal.Decimal `json:"attainment"`
+
+	// прогноз выручки за весь период при сохранении текущего темпа продаж (run rate)
+	// и прогнозируемый процент выполнения плана
+	Projection          decimal.Decimal `json:"projection"`
+	ProjectedAttainment decimal.Decimal `json:"projected_attainment"`
+	ElapsedDays         int             `json:"elapsed_days"`
+	TotalDays           int             `json:"total_days"`
+}
@@ -0,0 +1,44 @@
+package ports
+
+import (
+	"context"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+)
+
+// AlertStorage хранилище описаний оповещений.
+type AlertStorage interface {
+	// SaveAlert создает оповещение или заменяет оповещение с тем же идентификатором.
+	SaveAlert(alert *domain.Alert) error
+	DeleteAlert(id string) error
+	GetAlert(id string) (*domain.Alert, error)
+	// GetAlerts возвращает оповещения в порядке создания.
+	GetAlerts() []*domain.Alert
+}
+
+// AlertNotifier доставляет события оповещений.
+type AlertNotifier interface {
+	// Notify выполняет одну попытку доставки события на адрес оповещения. Ошибка domain.ErrWebhookRejected
+	// означает, что повторять доставку бессмысленно.
+	Notify(ctx context.Context, alert *domain.Alert, event *domain.AlertEvent) error
+}
+
+// SalesObserver получает продажи после их сохранения (см. services.WithSalesObserver).
+type SalesObserver interface {
+	// SalesAdded вызывается после сохранения продажи или чека (все строки чека одним вызовом).
+	// Не должен блокировать прием продаж.
+	SalesAdded(sales []*domain.Sale)
+}
+
+type AlertService interface {
+	// CreateAlert проверяет и сохраняет новое оповещение, назначая ему идентификатор.
+	CreateAlert(alert *domain.Alert) (*domain.Alert, error)
+	// UpdateAlert заменяет оповещение с идентификатором alert.ID.
+	UpdateAlert(alert *domain.Alert) (*domain.Alert, error)
+	DeleteAlert(id string) error
+	GetAlert(id string) (*domain.Alert, error)
+	GetAlerts() []*domain.Alert
+
+	// TestAlert отправляет пробное событие оповещения одной попыткой и возвращает ошибку доставки.
+	TestAlert(ctx context.Context, id string) (*domain.AlertEvent, error)
+}
@@ -0,0 +1,30 @@
+package ports
+
+import (
+	"go.dataflow.ru/service-sales/internal/app/domain"
+)
+
+// AnomalyDetector оценивает продажи по статистике ранее поступивших продаж.
+type AnomalyDetector interface {
+	// Score оценивает отклонение продажи от обычных продаж магазина и товара.
+	Score(sale *domain.Sale) domain.AnomalyScore
+	// Observe учитывает продажу в статистике. Аномальные продажи учитываются только после подтверждения.
+	Observe(sale *domain.Sale)
+}
+
+// AnomalyQueue очередь проверки аномальных продаж.
+type AnomalyQueue interface {
+	// SaveAnomaly добавляет аномалию в очередь или обновляет ее.
+	SaveAnomaly(anomaly *domain.Anomaly) error
+	GetAnomaly(id string) (*domain.Anomaly, error)
+	// GetAnomalies возвращает аномалии со статусом status (все, если status пустой) в порядке обнаружения.
+	GetAnomalies(status string) []*domain.Anomaly
+}
+
+type AnomalyService interface {
+	GetAnomalies(status string) []*domain.Anomaly
+	// AcceptAnomaly подтверждает продажу: продажа из карантина сохраняется, продажа учитывается в статистике.
+	AcceptAnomaly(id string) (*domain.Anomaly, error)
+	// RejectAnomaly отклоняет продажу: продажа из карантина не сохраняется.
+	RejectAnomaly(id string) (*domain.Anomaly, error)
+}
@@ -0,0 +1,19 @@
+package ports
+
+import (
+	"go.dataflow.ru/service-sales/internal/app/domain"
+)
+
+// AuditLog журнал аудита административных операций: записи только добавляются.
+type AuditLog interface {
+	Append(entry *domain.AuditEntry) error
+	// GetEntries возвращает записи журнала, последняя - первой.
+	GetEntries() []*domain.AuditEntry
+}
+
+type RetentionService interface {
+	// Purge удаляет продажи по запросу инициатора actor и возвращает запись журнала аудита об удалении.
+	Purge(purge *domain.Purge, actor string) (*domain.AuditEntry, error)
+	// GetAuditLog возвращает записи журнала аудита, последняя - первой.
+	GetAuditLog() []*domain.AuditEntry
+}
@@ -0,0 +1,20 @@
+package ports
+
+import (
+	"time"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+)
+
+// Catalog справочник магазинов и товаров, используемый при обработке продаж.
+type Catalog interface {
+	GetStore(storeID string) (*domain.Store, error)
+	GetStores() []*domain.Store
+	GetProduct(productID string) (*domain.Product, error)
+}
+
+// StoreCalendar часовые пояса магазинов справочника.
+type StoreCalendar interface {
+	// Location возвращает часовой пояс магазина.
+	Location(storeID string) (*time.Location, error)
+}
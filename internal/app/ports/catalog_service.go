@@ -0,0 +1,19 @@
+package ports
+
+import (
+	"go.dataflow.ru/service-sales/internal/app/domain"
+)
+
+type CatalogService interface {
+	Catalog
+	StoreCalendar
+
+	SaveStore(store *domain.Store) error
+	ImportStores(stores []*domain.Store) error
+	DeleteStore(storeID string) error
+
+	SaveProduct(product *domain.Product) error
+	ImportProducts(products []*domain.Product) error
+	DeleteProduct(productID string) error
+	GetProducts() []*domain.Product
+}
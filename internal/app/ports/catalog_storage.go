@@ -0,0 +1,17 @@
+package ports
+
+import (
+	"go.dataflow.ru/service-sales/internal/app/domain"
+)
+
+type CatalogStorage interface {
+	SaveStores(stores ...*domain.Store) error
+	DeleteStore(storeID string) error
+	GetStore(storeID string) (*domain.Store, bool)
+	GetStores() []*domain.Store
+
+	SaveProducts(products ...*domain.Product) error
+	DeleteProduct(productID string) error
+	GetProduct(productID string) (*domain.Product, bool)
+	GetProducts() []*domain.Product
+}
@@ -0,0 +1,14 @@
+package ports
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+type ExchangeRates interface {
+	// Rate возвращает курс пересчета валюты from в валюту to, действующий на момент at.
+	Rate(from, to string, at time.Time) (decimal.Decimal, error)
+	// Changes возвращает упорядоченные моменты вступления в силу новых курсов внутри интервала (startDate, endDate].
+	Changes(startDate, endDate time.Time) []time.Time
+}
@@ -0,0 +1,10 @@
+package ports
+
+import (
+	"go.dataflow.ru/service-sales/internal/app/domain"
+)
+
+type ForecastService interface {
+	// Forecast возвращает прогноз дневной выручки магазина по истории его продаж.
+	Forecast(storeID string, params domain.ForecastParams) (domain.SalesForecast, error)
+}
@@ -0,0 +1,15 @@
+package ports
+
+import (
+	"go.dataflow.ru/service-sales/internal/app/domain"
+)
+
+// ReferenceSource справочные данные другого экземпляра, копию которых поддерживает текущий экземпляр.
+type ReferenceSource interface {
+	// Reference возвращает справочник магазинов и товаров и планы экземпляра.
+	Reference() (domain.ReferenceData, error)
+}
+
+type ReferenceService interface {
+	ReferenceSource
+}
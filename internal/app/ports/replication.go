@@ -0,0 +1,37 @@
+package ports
+
+import (
+	"go.dataflow.ru/service-sales/internal/app/domain"
+)
+
+// ReplicationLog журнал продаж ведущего экземпляра для репликации: продажи каждого магазина в порядке приема.
+type ReplicationLog interface {
+	// Versions возвращает эпоху ведущего и версии журналов магазинов: поколение и количество продаж в нем.
+	Versions() (domain.LogVersions, error)
+	// SalesSince возвращает не более limit продаж магазина из журнала эпохи epoch и поколения generation,
+	// начиная с продажи с индексом version. Если ведущий перезапущен или журнал магазина уже в другом поколении,
+	// возвращает domain.ErrLogGenerationChanged.
+	SalesSince(epoch, storeID string, generation, version, limit int) ([]*domain.Sale, error)
+}
+
+// ReplicaStorage хранилище продаж экземпляра: ведущий отдает из него журнал, ведомый применяет к нему
+// журнал ведущего.
+type ReplicaStorage interface {
+	// Versions возвращает версии журналов магазинов хранилища.
+	Versions() (map[string]domain.LogVersion, error)
+	// SalesSince возвращает не более limit продаж магазина из журнала поколения generation, начиная с продажи
+	// с индексом version. Если журнал магазина уже в другом поколении, возвращает domain.ErrLogGenerationChanged.
+	SalesSince(storeID string, generation, version, limit int) ([]*domain.Sale, error)
+
+	// AddSales атомарно сохраняет продажи одного магазина: чтение видит либо все продажи, либо ни одной.
+	AddSales(sales []*domain.Sale) error
+	// Purge удаляет продажи, ведомый удаляет так копию магазина, журнал которого ведущий начал заново.
+	Purge(purge domain.Purge) (int, error)
+}
+
+type ReplicationService interface {
+	ReplicationLog
+
+	// Status возвращает состояние репликации экземпляра.
+	Status() (domain.ReplicationStatus, error)
+}
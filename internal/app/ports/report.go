@@ -0,0 +1,52 @@
+package ports
+
+import (
+	"context"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+)
+
+// ReportStorage хранилище отчетов по расписанию и результатов их запусков.
+type ReportStorage interface {
+	// SaveReport создает отчет или заменяет отчет с тем же идентификатором.
+	SaveReport(report *domain.Report) error
+	// DeleteReport удаляет отчет вместе с результатами запусков.
+	DeleteReport(id string) error
+	GetReport(id string) (*domain.Report, error)
+	// GetReports возвращает отчеты в порядке создания.
+	GetReports() []*domain.Report
+
+	// SaveRun сохраняет запуск отчета и его содержимое (nil для неудачного запуска).
+	SaveRun(run *domain.ReportRun, content []byte) error
+	// GetRuns возвращает запуски отчета, последний - первым.
+	GetRuns(reportID string) []*domain.ReportRun
+	// GetRunContent возвращает запуск отчета и его содержимое.
+	GetRunContent(reportID, runID string) (*domain.ReportRun, []byte, error)
+}
+
+// ReportRenderer представляет результат запроса отчета в формате отчета.
+type ReportRenderer interface {
+	Render(report *domain.Report, run *domain.ReportRun, result *domain.QueryResult) ([]byte, error)
+}
+
+// ReportDelivery доставляет содержимое отчетов.
+type ReportDelivery interface {
+	// DeliverReport выполняет одну попытку доставки содержимого запуска на адрес отчета. Ошибка
+	// domain.ErrWebhookRejected означает, что повторять доставку бессмысленно.
+	DeliverReport(ctx context.Context, report *domain.Report, run *domain.ReportRun, content []byte) error
+}
+
+type ReportService interface {
+	// CreateReport проверяет и сохраняет новый отчет, назначая ему идентификатор.
+	CreateReport(report *domain.Report) (*domain.Report, error)
+	// UpdateReport заменяет отчет с идентификатором report.ID.
+	UpdateReport(report *domain.Report) (*domain.Report, error)
+	DeleteReport(id string) error
+	GetReport(id string) (*domain.Report, error)
+	GetReports() []*domain.Report
+
+	// RunReport выполняет отчет вне расписания и возвращает запуск.
+	RunReport(ctx context.Context, id string) (*domain.ReportRun, error)
+	GetRuns(reportID string) ([]*domain.ReportRun, error)
+	GetRunContent(reportID, runID string) (*domain.ReportRun, []byte, error)
+}
@@ -0,0 +1,13 @@
+package ports
+
+import (
+	"go.dataflow.ru/service-sales/internal/app/domain"
+)
+
+// RuleEngine проверяет продажи бизнес-правилами.
+type RuleEngine interface {
+	// Check возвращает ошибку domain.ErrRuleViolation с описанием всех нарушенных правил.
+	Check(sale *domain.Sale) error
+	// Stats возвращает статистику применения правил в порядке их описания.
+	Stats() []domain.RuleStats
+}
@@ -1,15 +1,39 @@
 package ports
 
 import (
+	"context"
 	"time"
 
-	"github.com/shopspring/decimal"
-
 	"go.dataflow.ru/service-sales/internal/app/domain"
 )
 
 type SalesService interface {
 	AddSale(sale *domain.Sale) error
-	GetSales() []*domain.Sale
-	GetTotalSum(storeID string, startDate, endDate time.Time) decimal.Decimal
+	// AddAcceptedSale сохраняет продажу из карантина, подтвержденную после проверки (см. AnomalyService).
+	AddAcceptedSale(sale *domain.Sale) error
+	AddReceipt(receipt *domain.Receipt) error
+	GetSales() ([]*domain.Sale, error)
+	// ScanSales передает fn продажи всех магазинов по частям, проверяя отмену ctx во время чтения.
+	ScanSales(ctx context.Context, fn func(sale *domain.Sale) error) error
+	GetTotalSum(storeID string, startDate, endDate time.Time) (domain.Totals, error)
+	GetConvertedTotalSum(storeID string, startDate, endDate time.Time, currency string) (domain.Amounts, error)
+	GetDailyTotals(storeID string, startDay, endDay domain.Date) ([]domain.DailyTotals, error)
+	GetCategoryTotals(storeID string, period domain.Period) (map[string]domain.Totals, error)
+	GetStoreGroupTotals(filter map[string]string, groupBy string, period domain.Period) (map[string]domain.Totals, error)
+	// ComparePeriods сравнивает продажи магазинов storeIDs (или магазинов справочника с атрибутами filter)
+	// за период с предыдущим периодом, полученным по правилу offset.
+	ComparePeriods(storeIDs []string, filter map[string]string, period domain.Period, offset string) ([]domain.PeriodComparison, error)
+	GetDimensionTotals(storeID, dimension string, period domain.Period) (map[string]domain.Totals, error)
+	GetReceiptTotals(storeID string, period domain.Period) (domain.ReceiptTotals, error)
+	// GetSalesSketches возвращает приближенную статистику продаж магазинов storeIDs (или магазинов справочника
+	// с атрибутами filter) за период и по интервалам разбивки bucket.
+	GetSalesSketches(storeIDs []string, filter map[string]string, period domain.Period, bucket string) (*domain.SketchSeries, error)
+	// Query выполняет запрос агрегатов продаж с фильтрами, группировкой и временной разбивкой.
+	Query(q domain.Query) (*domain.QueryResult, error)
+	StoreLocation(storeID string) (*time.Location, error)
+
+	OpenSnapshot() (domain.Snapshot, error)
+	CloseSnapshot(token string) error
+	// WithSnapshot возвращает сервис, все запросы чтения которого видят продажи в снимке token.
+	WithSnapshot(token string) (SalesService, error)
 }
@@ -1,15 +1,51 @@
 package ports
 
 import (
+	"context"
 	"time"
 
-	"github.com/shopspring/decimal"
-
 	"go.dataflow.ru/service-sales/internal/app/domain"
 )
 
+// SalesReader чтение продаж.
+type SalesReader interface {
+	GetSales() ([]*domain.Sale, error)
+	// ScanSales передает fn продажи всех магазинов по частям, не загружая их целиком, и прекращает чтение
+	// с ошибкой ctx.Err(), когда контекст отменен.
+	ScanSales(ctx context.Context, fn func(sale *domain.Sale) error) error
+	GetTotalSum(storeID string, startDate, endDate time.Time) (domain.Totals, error)
+	GetTotalSumByProduct(storeID string, startDate, endDate time.Time) (map[string]domain.Totals, error)
+	// GetTotalSumByDimension возвращает суммы продаж магазина в разрезе значений измерения dimension
+	// (domain.Dimension*), продажи без значения - в группе domain.Unspecified.
+	GetTotalSumByDimension(storeID, dimension string, startDate, endDate time.Time) (map[string]domain.Totals, error)
+	// GetTotalSums возвращает суммы продаж нескольких магазинов, каждого за свой период, в порядке запросов.
+	GetTotalSums(queries []domain.StorePeriod) ([]domain.Totals, error)
+	// GetReceiptTotals возвращает показатели чеков магазина за период в разрезе валют.
+	GetReceiptTotals(storeID string, startDate, endDate time.Time) (domain.ReceiptTotals, error)
+	// GetSketches возвращает приближенную статистику продаж магазина за период: различные товары и размеры чеков.
+	GetSketches(storeID string, startDate, endDate time.Time) (*domain.SalesSketches, error)
+	// Query выполняет запрос агрегатов продаж всех магазинов.
+	Query(q domain.Query) (*domain.QueryResult, error)
+}
+
 type SalesStorage interface {
-	AddSale(sale *domain.Sale)
-	GetSales() []*domain.Sale
-	GetTotalSum(storeID string, startDate, endDate time.Time) decimal.Decimal
+	SalesReader
+
+	AddSale(sale *domain.Sale) error
+	// InsertSale сохраняет продажу, которая может быть раньше последней продажи магазина: магазин пересобирается
+	// со вставленной продажей в новом поколении журнала, открытые снимки закрываются.
+	InsertSale(sale *domain.Sale) error
+	// AddReceipt атомарно сохраняет продажи строк чека; domain.ErrReceiptExists, если чек уже сохранен.
+	AddReceipt(receipt *domain.Receipt) error
+
+	// OpenSnapshot открывает снимок текущего состояния продаж.
+	OpenSnapshot() (domain.Snapshot, error)
+	// Snapshot возвращает чтение продаж в снимке token.
+	Snapshot(token string) (SalesReader, error)
+	// CloseSnapshot закрывает снимок до истечения срока действия.
+	CloseSnapshot(token string) error
+
+	// Purge удаляет продажи магазина (всех магазинов) раньше заданного времени (за все время) и возвращает
+	// количество удаленных продаж. Версии магазинов начинаются заново, открытые снимки закрываются.
+	Purge(purge domain.Purge) (int, error)
 }
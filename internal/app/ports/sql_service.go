@@ -0,0 +1,13 @@
+package ports
+
+import (
+	"context"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+)
+
+type SQLService interface {
+	// Query выполняет запрос SELECT к таблице продаж sales в снимке snapshot (в текущем состоянии, если снимок
+	// не задан).
+	Query(ctx context.Context, query, snapshot string) (*domain.SQLResult, error)
+}
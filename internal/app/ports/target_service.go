@@ -0,0 +1,15 @@
+package ports
+
+import (
+	"go.dataflow.ru/service-sales/internal/app/domain"
+)
+
+type TargetService interface {
+	ImportTargets(targets []*domain.Target) error
+	DeleteTarget(storeID string, start domain.Date) error
+	GetTargets(storeID string) []*domain.Target
+
+	// GetAttainment возвращает выполнение плана магазина, период которого содержит дату day магазина
+	// (nil - текущую дату в часовом поясе магазина).
+	GetAttainment(storeID string, day *domain.Date) (domain.TargetAttainment, error)
+}
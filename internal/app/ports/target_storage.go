@@ -0,0 +1,13 @@
+package ports
+
+import (
+	"go.dataflow.ru/service-sales/internal/app/domain"
+)
+
+type TargetStorage interface {
+	// SaveTargets создает или заменяет планы (план идентифицируется магазином и датой начала).
+	SaveTargets(targets ...*domain.Target) error
+	DeleteTarget(storeID string, start domain.Date) error
+	// GetTargets возвращает планы магазина, упорядоченные по дате начала, или всех магазинов, если storeID пустой.
+	GetTargets(storeID string) []*domain.Target
+}
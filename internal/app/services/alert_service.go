@@ -0,0 +1,570 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+	"go.dataflow.ru/service-sales/internal/app/ports"
+	"go.dataflow.ru/service-sales/pkg/logger"
+)
+
+const (
+	// alertQueueSize количество событий, ожидающих доставки; при переполнении новые события отбрасываются
+	alertQueueSize = 1000
+	// alertWorkers количество одновременных доставок: медленный получатель не задерживает остальные оповещения
+	alertWorkers = 4
+)
+
+// AlertService оповещения о событиях продаж магазинов. Выручка за день проверяется в фоне после сохранения продаж
+// (см. WithSalesObserver) и периодически (Run), отсутствие продаж - периодически. События доставляются в фоне
+// с повторными попытками.
+//
+// Состояние срабатываний хранится в памяти: после перезапуска оповещение о выручке может сработать повторно
+// в тот же день.
+type AlertService struct {
+	storage  ports.AlertStorage
+	sales    ports.SalesReader
+	notifier ports.AlertNotifier
+	logger   *logger.Logger
+
+	calendar ports.StoreCalendar
+	catalog  ports.Catalog
+	targets  ports.TargetStorage
+
+	retries retryPolicy
+
+	queue chan alertDelivery
+
+	mu      sync.Mutex
+	state   map[alertKey]*alertState
+	stores  map[string]struct{} // магазины, продажи которых поступали после запуска
+	pending map[string]struct{} // магазины с новыми продажами, выручка которых еще не проверена
+
+	added chan struct{} // сигнал о новых продажах для фоновой проверки выручки
+
+	now func() time.Time
+}
+
+// alertKey срабатывание оповещения по магазину.
+type alertKey struct {
+	alertID string
+	storeID string
+}
+
+type alertState struct {
+	firedDay domain.Date // день магазина, в который сработало оповещение о выручке
+	silent   bool        // оповещение об отсутствии продаж сработало и ждет продаж
+}
+
+type alertDelivery struct {
+	alert *domain.Alert
+	event *domain.AlertEvent
+}
+
+type AlertOption func(s *AlertService)
+
+// WithAlertCalendar задает источник часовых поясов магазинов для границ дня. Без него магазины в UTC.
+func WithAlertCalendar(calendar ports.StoreCalendar) AlertOption {
+	return func(s *AlertService) {
+		s.calendar = calendar
+	}
+}
+
+// WithAlertCatalog задает справочник магазинов, по которым проверяются оповещения без магазина.
+// Без него проверяются только магазины, продажи которых поступали после запуска.
+func WithAlertCatalog(catalog ports.Catalog) AlertOption {
+	return func(s *AlertService) {
+		s.catalog = catalog
+	}
+}
+
+// WithAlertTargets задает планы выручки, дневная доля которых - порог оповещений о выручке без порога.
+func WithAlertTargets(targets ports.TargetStorage) AlertOption {
+	return func(s *AlertService) {
+		s.targets = targets
+	}
+}
+
+// WithAlertRetries задает количество попыток доставки события и паузу перед повторной попыткой:
+// пауза удваивается после каждой неудачной попытки, но не превышает maxBackoff.
+func WithAlertRetries(attempts int, backoff, maxBackoff time.Duration) AlertOption {
+	return func(s *AlertService) {
+		s.retries = s.retries.with(attempts, backoff, maxBackoff)
+	}
+}
+
+func NewAlertService(storage ports.AlertStorage, sales ports.SalesReader, notifier ports.AlertNotifier, logger *logger.Logger, opts ...AlertOption) *AlertService {
+	s := &AlertService{
+		storage:  storage,
+		sales:    sales,
+		notifier: notifier,
+		logger:   logger,
+		retries:  defaultRetryPolicy,
+		queue:    make(chan alertDelivery, alertQueueSize),
+		state:    make(map[alertKey]*alertState),
+		stores:   make(map[string]struct{}),
+		pending:  make(map[string]struct{}),
+		added:    make(chan struct{}, 1),
+		now:      time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// CreateAlert проверяет и сохраняет новое оповещение, назначая ему идентификатор.
+func (s *AlertService) CreateAlert(alert *domain.Alert) (*domain.Alert, error) {
+	id, err := newAlertID()
+	if err != nil {
+		return nil, err
+	}
+
+	alert.ID = id
+
+	return s.saveAlert(alert)
+}
+
+// UpdateAlert заменяет оповещение с идентификатором alert.ID. Без секрета сохраняется прежний секрет оповещения.
+// Состояние срабатываний оповещения сбрасывается.
+func (s *AlertService) UpdateAlert(alert *domain.Alert) (*domain.Alert, error) {
+	prev, err := s.storage.GetAlert(alert.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if alert.Secret == "" {
+		alert.Secret = prev.Secret
+	}
+
+	return s.saveAlert(alert)
+}
+
+func (s *AlertService) saveAlert(alert *domain.Alert) (*domain.Alert, error) {
+	if alert.Kind == domain.AlertDailyRevenue && alert.Metric == "" {
+		alert.Metric = domain.MetricGross
+	}
+
+	if err := alert.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrInvalidAlert, err)
+	}
+
+	if err := s.storage.SaveAlert(alert); err != nil {
+		return nil, err
+	}
+
+	s.reset(alert.ID)
+
+	return alert, nil
+}
+
+func (s *AlertService) DeleteAlert(id string) error {
+	if err := s.storage.DeleteAlert(id); err != nil {
+		return err
+	}
+
+	s.reset(id)
+
+	return nil
+}
+
+func (s *AlertService) GetAlert(id string) (*domain.Alert, error) {
+	return s.storage.GetAlert(id)
+}
+
+func (s *AlertService) GetAlerts() []*domain.Alert {
+	return s.storage.GetAlerts()
+}
+
+// TestAlert отправляет пробное событие оповещения одной попыткой, без повторов, чтобы проверить адрес и подпись.
+func (s *AlertService) TestAlert(ctx context.Context, id string) (*domain.AlertEvent, error) {
+	alert, err := s.storage.GetAlert(id)
+	if err != nil {
+		return nil, err
+	}
+
+	event, err := s.event(alert, alert.StoreID, fmt.Sprintf("test event of %s alert %s", alert.Kind, alert.ID))
+	if err != nil {
+		return nil, err
+	}
+
+	event.Test = true
+
+	if err = s.notifier.Notify(ctx, alert, event); err != nil {
+		return nil, err
+	}
+
+	return event, nil
+}
+
+// SalesAdded отмечает магазины сохраненных продаж для проверки оповещений о выручке. Выручка читается в фоне
+// (см. Run), поэтому сохранение продажи не ждет запросов сумм, в том числе к другим узлам кластера.
+func (s *AlertService) SalesAdded(sales []*domain.Sale) {
+	s.mu.Lock()
+
+	for _, sale := range sales {
+		s.stores[sale.StoreID] = struct{}{}
+		s.pending[sale.StoreID] = struct{}{}
+	}
+
+	s.mu.Unlock()
+
+	select {
+	case s.added <- struct{}{}:
+	default:
+	}
+}
+
+// checkPending проверяет оповещения о выручке по магазинам, продажи которых поступили после предыдущей проверки.
+// Проверяется только текущий день магазина: продажи задним числом не вызывают оповещений за прошедшие дни.
+func (s *AlertService) checkPending() {
+	s.mu.Lock()
+	stores := s.pending
+	s.pending = make(map[string]struct{})
+	s.mu.Unlock()
+
+	if len(stores) == 0 {
+		return
+	}
+
+	now := s.now()
+
+	for _, alert := range s.storage.GetAlerts() {
+		if alert.Kind != domain.AlertDailyRevenue {
+			continue
+		}
+
+		for storeID := range stores {
+			if alert.StoreID == "" || alert.StoreID == storeID {
+				s.checkRevenue(alert, storeID, now)
+			}
+		}
+	}
+}
+
+// watchSales проверяет выручку после поступления продаж, пока не отменен ctx. Продажи, поступившие во время
+// проверки, проверяются следующей проверкой.
+func (s *AlertService) watchSales(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.added:
+			s.checkPending()
+		}
+	}
+}
+
+// Run запускает доставку событий и проверку выручки после поступления продаж и проверяет все оповещения
+// каждые interval, пока не отменен ctx.
+func (s *AlertService) Run(ctx context.Context, interval time.Duration) {
+	for i := 0; i < alertWorkers; i++ {
+		go s.deliver(ctx)
+	}
+
+	go s.watchSales(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.Check()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check проверяет все оповещения на текущий момент. Выручка проверяется здесь же, чтобы учесть продажи,
+// принятые другими узлами кластера или полученные репликацией.
+func (s *AlertService) Check() {
+	now := s.now()
+
+	for _, alert := range s.storage.GetAlerts() {
+		switch alert.Kind {
+		case domain.AlertDailyRevenue:
+			for _, storeID := range s.alertStores(alert) {
+				s.checkRevenue(alert, storeID, now)
+			}
+		case domain.AlertNoSales:
+			s.checkNoSales(alert, now)
+		}
+	}
+}
+
+// checkRevenue отправляет оповещение, если выручка магазина за текущий день достигла порога и оповещение
+// в этот день еще не срабатывало.
+func (s *AlertService) checkRevenue(alert *domain.Alert, storeID string, now time.Time) {
+	loc, err := s.location(storeID)
+	if err != nil {
+		s.logger.Errorf("alert %s: cant get time zone of store %q: %v", alert.ID, storeID, err)
+		return
+	}
+
+	day := domain.DateOf(now.In(loc))
+	key := alertKey{alertID: alert.ID, storeID: storeID}
+
+	s.mu.Lock()
+	fired := s.state[key] != nil && s.state[key].firedDay == day
+	s.mu.Unlock()
+
+	if fired {
+		return
+	}
+
+	threshold, currency, metric, ok := s.threshold(alert, storeID, day)
+	if !ok {
+		return
+	}
+
+	totals, err := s.sales.GetTotalSum(storeID, day.Start(loc), day.End(loc))
+	if err != nil {
+		s.logger.Errorf("alert %s: cant get revenue of store %q: %v", alert.ID, storeID, err)
+		return
+	}
+
+	revenue := metricValue(metric, totals[currency])
+	if revenue.LessThan(threshold) {
+		return
+	}
+
+	s.mu.Lock()
+
+	state := s.stateOf(key)
+	if state.firedDay == day {
+		s.mu.Unlock()
+		return
+	}
+
+	state.firedDay = day
+
+	s.mu.Unlock()
+
+	event, err := s.event(alert, storeID, fmt.Sprintf("store %s %s revenue %s %s on %s reached %s",
+		storeID, metric, revenue, currency, day, threshold))
+	if err != nil {
+		s.logger.Errorf("alert %s: %v", alert.ID, err)
+		return
+	}
+
+	event.Day, event.Revenue, event.Threshold, event.Currency = &day, &revenue, &threshold, currency
+
+	s.enqueue(alert, event)
+}
+
+// threshold возвращает порог выручки оповещения в день магазина: порог оповещения или дневную долю плана,
+// период которого содержит день. false - порога нет.
+func (s *AlertService) threshold(alert *domain.Alert, storeID string, day domain.Date) (decimal.Decimal, string, string, bool) {
+	if !alert.Threshold.IsZero() {
+		return alert.Threshold, alert.Currency, alert.Metric, true
+	}
+
+	if s.targets == nil {
+		return decimal.Decimal{}, "", "", false
+	}
+
+	for _, target := range s.targets.GetTargets(storeID) {
+		if target.Contains(day) {
+			days := decimal.NewFromInt(int64(target.Start.DaysUntil(target.End) + 1))
+
+			return target.Amount.Div(days).Round(domain.AmountPrecision), target.Currency, target.Metric, true
+		}
+	}
+
+	return decimal.Decimal{}, "", "", false
+}
+
+// checkNoSales отправляет оповещения по магазинам без продаж за окно оповещения. Оповещение по магазину
+// срабатывает повторно только после того, как у магазина снова появились продажи.
+func (s *AlertService) checkNoSales(alert *domain.Alert, now time.Time) {
+	stores := s.alertStores(alert)
+	if len(stores) == 0 {
+		return
+	}
+
+	res, err := s.sales.Query(domain.Query{
+		StartDate: now.Add(-time.Duration(alert.Window)),
+		EndDate:   now,
+		Filters:   map[string][]string{domain.FieldStore: stores},
+		GroupBy:   []string{domain.FieldStore},
+		Metrics:   []string{domain.MetricSales},
+	})
+	if err != nil {
+		s.logger.Errorf("alert %s: cant query sales: %v", alert.ID, err)
+		return
+	}
+
+	selling := make(map[string]bool, len(res.Rows))
+	for _, row := range res.Rows {
+		if row.Metrics[domain.MetricSales].IsPositive() {
+			selling[row.Group[domain.FieldStore]] = true
+		}
+	}
+
+	for _, storeID := range stores {
+		s.mu.Lock()
+
+		state := s.stateOf(alertKey{alertID: alert.ID, storeID: storeID})
+		fire := !selling[storeID] && !state.silent
+		state.silent = !selling[storeID]
+
+		s.mu.Unlock()
+
+		if !fire {
+			continue
+		}
+
+		event, err := s.event(alert, storeID, fmt.Sprintf("store %s has no sales for %s", storeID, time.Duration(alert.Window)))
+		if err != nil {
+			s.logger.Errorf("alert %s: %v", alert.ID, err)
+			continue
+		}
+
+		event.Window = alert.Window
+
+		s.enqueue(alert, event)
+	}
+}
+
+// alertStores возвращает магазины оповещения: магазин оповещения или все магазины справочника и магазины,
+// продажи которых поступали после запуска, по возрастанию идентификатора.
+func (s *AlertService) alertStores(alert *domain.Alert) []string {
+	if alert.StoreID != "" {
+		return []string{alert.StoreID}
+	}
+
+	s.mu.Lock()
+
+	stores := make(map[string]struct{}, len(s.stores))
+	for storeID := range s.stores {
+		stores[storeID] = struct{}{}
+	}
+
+	s.mu.Unlock()
+
+	if s.catalog != nil {
+		for _, store := range s.catalog.GetStores() {
+			stores[store.ID] = struct{}{}
+		}
+	}
+
+	res := make([]string, 0, len(stores))
+	for storeID := range stores {
+		res = append(res, storeID)
+	}
+
+	sort.Strings(res)
+
+	return res
+}
+
+// stateOf возвращает состояние срабатываний оповещения по магазину. Вызывается под блокировкой.
+func (s *AlertService) stateOf(key alertKey) *alertState {
+	state, ok := s.state[key]
+	if !ok {
+		state = &alertState{}
+		s.state[key] = state
+	}
+
+	return state
+}
+
+// reset сбрасывает состояние срабатываний оповещения.
+func (s *AlertService) reset(alertID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key := range s.state {
+		if key.alertID == alertID {
+			delete(s.state, key)
+		}
+	}
+}
+
+func (s *AlertService) location(storeID string) (*time.Location, error) {
+	if s.calendar == nil {
+		return time.UTC, nil
+	}
+
+	return s.calendar.Location(storeID)
+}
+
+func (s *AlertService) event(alert *domain.Alert, storeID, message string) (*domain.AlertEvent, error) {
+	id, err := newAlertID()
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.AlertEvent{
+		ID:      id,
+		AlertID: alert.ID,
+		Kind:    alert.Kind,
+		StoreID: storeID,
+		Time:    s.now(),
+		Message: message,
+	}, nil
+}
+
+// enqueue ставит событие в очередь доставки, не блокируя проверку оповещений.
+func (s *AlertService) enqueue(alert *domain.Alert, event *domain.AlertEvent) {
+	select {
+	case s.queue <- alertDelivery{alert: alert, event: event}:
+	default:
+		s.logger.Errorf("alert %s: delivery queue is full, event %s dropped: %s", alert.ID, event.ID, event.Message)
+	}
+}
+
+// deliver доставляет события из очереди, пока не отменен ctx.
+func (s *AlertService) deliver(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case d := <-s.queue:
+			if err := s.notify(ctx, d.alert, d.event); err != nil {
+				s.logger.Errorf("alert %s: cant deliver event %s: %v", d.alert.ID, d.event.ID, err)
+			}
+		}
+	}
+}
+
+// notify доставляет событие, повторяя попытки при временных ошибках.
+func (s *AlertService) notify(ctx context.Context, alert *domain.Alert, event *domain.AlertEvent) error {
+	return s.retries.do(ctx,
+		func() error { return s.notifier.Notify(ctx, alert, event) },
+		func(attempt int, err error) {
+			s.logger.Warnf("alert %s: attempt %d to deliver event %s failed: %v", alert.ID, attempt, event.ID, err)
+		},
+	)
+}
+
+// metricValue возвращает значение метрики выручки (domain.MetricGross или domain.MetricNet) из сумм продаж.
+func metricValue(metric string, amounts domain.Amounts) decimal.Decimal {
+	if metric == domain.MetricNet {
+		return amounts.Net
+	}
+
+	return amounts.Gross
+}
+
+func newAlertID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate alert id: %w", err)
+	}
+
+	return hex.EncodeToString(b), nil
+}
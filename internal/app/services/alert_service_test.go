@@ -0,0 +1,277 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.dataflow.ru/service-sales/pkg/logger"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+)
+
+// queuedEvents возвращает события, ожидающие доставки.
+func queuedEvents(s *AlertService) []*domain.AlertEvent {
+	var events []*domain.AlertEvent
+
+	for {
+		select {
+		case d := <-s.queue:
+			events = append(events, d.event)
+		default:
+			return events
+		}
+	}
+}
+
+func TestAlertService_DailyRevenue(t *testing.T) {
+	t.Parallel()
+
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	require.NoError(t, err)
+
+	now := time.Date(2024, 4, 10, 12, 0, 0, 0, moscow)
+	today := domain.DateOf(now)
+
+	april := &domain.Target{
+		StoreID:  "store_1",
+		Start:    domain.Date{Year: 2024, Month: time.April, Day: 1},
+		End:      domain.Date{Year: 2024, Month: time.April, Day: 30},
+		Amount:   decimal.NewFromInt(30000),
+		Currency: "RUB",
+		Metric:   domain.MetricGross,
+	}
+
+	testCases := []struct {
+		name      string
+		alert     *domain.Alert
+		revenue   int64
+		targets   []*domain.Target
+		threshold string // порог события, пустой - события нет
+	}{
+		{
+			name:      "выручка достигла порога",
+			alert:     &domain.Alert{ID: "a1", Kind: domain.AlertDailyRevenue, Threshold: decimal.NewFromInt(1000), Currency: "RUB", Metric: domain.MetricGross},
+			revenue:   1200,
+			threshold: "1000",
+		},
+		{
+			name:    "выручка ниже порога",
+			alert:   &domain.Alert{ID: "a1", Kind: domain.AlertDailyRevenue, Threshold: decimal.NewFromInt(1000), Currency: "RUB", Metric: domain.MetricGross},
+			revenue: 900,
+		},
+		{
+			name:      "без порога - дневная доля плана",
+			alert:     &domain.Alert{ID: "a1", Kind: domain.AlertDailyRevenue, Metric: domain.MetricGross},
+			targets:   []*domain.Target{april},
+			revenue:   1000,
+			threshold: "1000",
+		},
+		{
+			name:  "без порога и плана оповещение не проверяется",
+			alert: &domain.Alert{ID: "a1", Kind: domain.AlertDailyRevenue, Metric: domain.MetricGross},
+		},
+	}
+
+	for _, tt := range testCases {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			storage := NewMockAlertStorage(ctrl)
+			sales := NewMockSalesReader(ctrl)
+			calendar := NewMockStoreCalendar(ctrl)
+			targets := NewMockTargetStorage(ctrl)
+
+			storage.EXPECT().GetAlerts().Return([]*domain.Alert{tt.alert}).AnyTimes()
+			calendar.EXPECT().Location("store_1").Return(moscow, nil).AnyTimes()
+			targets.EXPECT().GetTargets("store_1").Return(tt.targets).AnyTimes()
+
+			if tt.alert.Threshold.IsPositive() || tt.targets != nil {
+				sales.EXPECT().GetTotalSum("store_1", today.Start(moscow), today.End(moscow)).
+					Return(domain.Totals{"RUB": grossAmounts(tt.revenue)}, nil).MinTimes(1)
+			}
+
+			s := NewAlertService(storage, sales, nil, logger.NoOpLogger(),
+				WithAlertCalendar(calendar),
+				WithAlertTargets(targets),
+			)
+			s.now = func() time.Time { return now }
+
+			sale := &domain.Sale{StoreID: "store_1", SaleDate: now}
+
+			// сохранение продажи только отмечает магазин, выручка проверяется в фоне
+			s.SalesAdded([]*domain.Sale{sale})
+			assert.Empty(t, queuedEvents(s))
+
+			s.checkPending()
+
+			events := queuedEvents(s)
+			if tt.threshold == "" {
+				assert.Empty(t, events)
+				return
+			}
+
+			require.Len(t, events, 1)
+			assert.Equal(t, "a1", events[0].AlertID)
+			assert.Equal(t, "store_1", events[0].StoreID)
+			assert.Equal(t, today, *events[0].Day)
+			assert.Equal(t, tt.threshold, events[0].Threshold.String())
+			assert.Equal(t, decimal.NewFromInt(tt.revenue).String(), events[0].Revenue.String())
+
+			// в тот же день оповещение больше не срабатывает
+			s.SalesAdded([]*domain.Sale{sale})
+			s.checkPending()
+			s.Check()
+			assert.Empty(t, queuedEvents(s))
+		})
+	}
+}
+
+func TestAlertService_NoSales(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	storage := NewMockAlertStorage(ctrl)
+	sales := NewMockSalesReader(ctrl)
+	catalog := NewMockCatalog(ctrl)
+
+	alert := &domain.Alert{ID: "a1", Kind: domain.AlertNoSales, Window: domain.Duration(time.Hour)}
+	now := time.Date(2024, 4, 10, 12, 0, 0, 0, time.UTC)
+
+	storage.EXPECT().GetAlerts().Return([]*domain.Alert{alert}).AnyTimes()
+	catalog.EXPECT().GetStores().Return([]*domain.Store{{ID: "store_1"}, {ID: "store_2"}}).AnyTimes()
+
+	// продажи магазинов за последний час при каждой проверке
+	selling := [][]string{{"store_1"}, {"store_1"}, {"store_1", "store_2"}, {"store_1"}}
+
+	for _, stores := range selling {
+		rows := make([]domain.QueryRow, 0, len(stores))
+		for _, storeID := range stores {
+			rows = append(rows, domain.QueryRow{
+				Group:   map[string]string{domain.FieldStore: storeID, domain.FieldCurrency: "RUB"},
+				Metrics: map[string]decimal.Decimal{domain.MetricSales: decimal.NewFromInt(3)},
+			})
+		}
+
+		sales.EXPECT().Query(domain.Query{
+			StartDate: now.Add(-time.Hour),
+			EndDate:   now,
+			Filters:   map[string][]string{domain.FieldStore: {"store_1", "store_2"}},
+			GroupBy:   []string{domain.FieldStore},
+			Metrics:   []string{domain.MetricSales},
+		}).Return(&domain.QueryResult{Rows: rows}, nil)
+	}
+
+	s := NewAlertService(storage, sales, nil, logger.NoOpLogger(), WithAlertCatalog(catalog))
+	s.now = func() time.Time { return now }
+
+	s.Check()
+
+	events := queuedEvents(s)
+	require.Len(t, events, 1)
+	assert.Equal(t, "store_2", events[0].StoreID)
+	assert.Equal(t, domain.Duration(time.Hour), events[0].Window)
+
+	// пока у магазина нет продаж, оповещение не повторяется
+	s.Check()
+	assert.Empty(t, queuedEvents(s))
+
+	// после появления продаж оповещение срабатывает снова
+	s.Check()
+	assert.Empty(t, queuedEvents(s))
+
+	s.Check()
+	events = queuedEvents(s)
+	require.Len(t, events, 1)
+	assert.Equal(t, "store_2", events[0].StoreID)
+}
+
+func TestAlertService_Notify(t *testing.T) {
+	t.Parallel()
+
+	errUnavailable := errors.New("status 503")
+
+	testCases := []struct {
+		name     string
+		results  []error // результаты попыток доставки
+		expError error
+	}{
+		{
+			name:    "доставка после временных ошибок",
+			results: []error{errUnavailable, errUnavailable, nil},
+		},
+		{
+			name:     "отказ получателя не повторяется",
+			results:  []error{domain.ErrWebhookRejected},
+			expError: domain.ErrWebhookRejected,
+		},
+		{
+			name:     "попытки исчерпаны",
+			results:  []error{errUnavailable, errUnavailable, errUnavailable},
+			expError: errUnavailable,
+		},
+	}
+
+	for _, tt := range testCases {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			notifier := NewMockAlertNotifier(ctrl)
+
+			alert := &domain.Alert{ID: "a1"}
+			event := &domain.AlertEvent{ID: "e1"}
+
+			calls := make([]*gomock.Call, 0, len(tt.results))
+			for _, err := range tt.results {
+				calls = append(calls, notifier.EXPECT().Notify(gomock.Any(), alert, event).Return(err))
+			}
+
+			gomock.InOrder(calls...)
+
+			s := NewAlertService(nil, nil, notifier, logger.NoOpLogger(),
+				WithAlertRetries(3, time.Millisecond, 2*time.Millisecond),
+			)
+
+			err := s.notify(context.Background(), alert, event)
+			if tt.expError != nil {
+				assert.ErrorIs(t, err, tt.expError)
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestAlertService_CreateAlert(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	storage := NewMockAlertStorage(ctrl)
+
+	s := NewAlertService(storage, nil, nil, logger.NoOpLogger())
+
+	_, err := s.CreateAlert(&domain.Alert{Kind: domain.AlertNoSales, Window: domain.Duration(time.Second), WebhookURL: "http://localhost/hook"})
+	assert.ErrorIs(t, err, domain.ErrInvalidAlert)
+
+	_, err = s.CreateAlert(&domain.Alert{Kind: domain.AlertDailyRevenue, Threshold: decimal.NewFromInt(1000), Currency: "RUB", WebhookURL: "localhost"})
+	assert.ErrorIs(t, err, domain.ErrInvalidAlert)
+
+	storage.EXPECT().SaveAlert(gomock.Any()).Return(nil)
+
+	alert, err := s.CreateAlert(&domain.Alert{Kind: domain.AlertDailyRevenue, Threshold: decimal.NewFromInt(1000), Currency: "RUB", WebhookURL: "https://example.com/hook"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, alert.ID)
+	assert.Equal(t, domain.MetricGross, alert.Metric)
+}
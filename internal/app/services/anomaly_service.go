@@ -0,0 +1,96 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+	"go.dataflow.ru/service-sales/internal/app/ports"
+	"go.dataflow.ru/service-sales/pkg/logger"
+)
+
+type AnomalyService struct {
+	queue    ports.AnomalyQueue
+	detector ports.AnomalyDetector
+	sales    ports.SalesService
+	logger   *logger.Logger
+
+	now func() time.Time
+
+	// решения по аномалиям принимаются по одному, чтобы продажа из карантина не была сохранена дважды
+	mu sync.Mutex
+}
+
+func NewAnomalyService(queue ports.AnomalyQueue, detector ports.AnomalyDetector, sales ports.SalesService, logger *logger.Logger) *AnomalyService {
+	return &AnomalyService{
+		queue:    queue,
+		detector: detector,
+		sales:    sales,
+		logger:   logger,
+		now:      time.Now,
+	}
+}
+
+// GetAnomalies возвращает аномалии со статусом status (все, если status пустой) в порядке обнаружения.
+func (s *AnomalyService) GetAnomalies(status string) []*domain.Anomaly {
+	return s.queue.GetAnomalies(status)
+}
+
+// AcceptAnomaly подтверждает продажу: продажа из карантина сохраняется, продажа учитывается в статистике детектора.
+func (s *AnomalyService) AcceptAnomaly(id string) (*domain.Anomaly, error) {
+	return s.resolve(id, domain.AnomalyAccepted)
+}
+
+// RejectAnomaly отклоняет продажу: продажа из карантина не сохраняется. Отклоненная продажа, сохраненная
+// без карантина, остается в хранилище: хранилище продаж не поддерживает удаление.
+func (s *AnomalyService) RejectAnomaly(id string) (*domain.Anomaly, error) {
+	return s.resolve(id, domain.AnomalyRejected)
+}
+
+func (s *AnomalyService) resolve(id, status string) (*domain.Anomaly, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	anomaly, err := s.queue.GetAnomaly(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if anomaly.Status != domain.AnomalyPending {
+		return nil, fmt.Errorf("anomaly %s %s: %w", id, anomaly.Status, domain.ErrAnomalyResolved)
+	}
+
+	if status == domain.AnomalyAccepted && anomaly.Action == domain.AnomalyActionQuarantine {
+		if err = s.sales.AddAcceptedSale(anomaly.Sale); err != nil {
+			return nil, fmt.Errorf("add quarantined sale: %w", err)
+		}
+	}
+
+	resolvedAt := s.now()
+
+	resolved := *anomaly
+	resolved.Status = status
+	resolved.ResolvedAt = &resolvedAt
+
+	if err = s.queue.SaveAnomaly(&resolved); err != nil {
+		return nil, err
+	}
+
+	if status == domain.AnomalyAccepted {
+		s.detector.Observe(anomaly.Sale)
+	}
+
+	return &resolved, nil
+}
+
+func newAnomalyID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate anomaly id: %w", err)
+	}
+
+	return hex.EncodeToString(b), nil
+}
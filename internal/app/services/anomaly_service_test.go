@@ -0,0 +1,277 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.dataflow.ru/service-sales/pkg/logger"
+
+	"go.dataflow.ru/service-sales/internal/adapters/anomaly"
+	"go.dataflow.ru/service-sales/internal/adapters/storage"
+	"go.dataflow.ru/service-sales/internal/app/domain"
+)
+
+func TestService_AddSale_AnomalyDetection(t *testing.T) {
+	t.Parallel()
+
+	newSale := func() *domain.Sale {
+		return &domain.Sale{
+			StoreID:      "store_1",
+			ProductID:    "product_1",
+			QuantitySold: 1,
+			SalePrice:    decimal.NewFromInt(199000),
+			Currency:     "RUB",
+			SaleDate:     time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		}
+	}
+
+	anomalous := domain.AnomalyScore{Score: 46, Reasons: []string{"unit price 199000 differs from typical 1990"}}
+
+	testCases := []struct {
+		name   string
+		action string
+		mock   func(storage *MockSalesStorage, detector *MockAnomalyDetector, queue *MockAnomalyQueue, sale *domain.Sale)
+		error  error
+	}{
+		{
+			name:   "обычная продажа сохраняется и учитывается в статистике",
+			action: domain.AnomalyActionQuarantine,
+			mock: func(storage *MockSalesStorage, detector *MockAnomalyDetector, queue *MockAnomalyQueue, sale *domain.Sale) {
+				detector.EXPECT().Score(sale).Return(domain.AnomalyScore{Score: 1.5})
+				storage.EXPECT().AddSale(sale).Return(nil)
+				detector.EXPECT().Observe(sale)
+			},
+		},
+		{
+			name:   "аномальная продажа сохраняется и попадает в очередь проверки",
+			action: domain.AnomalyActionFlag,
+			mock: func(storage *MockSalesStorage, detector *MockAnomalyDetector, queue *MockAnomalyQueue, sale *domain.Sale) {
+				detector.EXPECT().Score(sale).Return(anomalous)
+				storage.EXPECT().AddSale(sale).Return(nil)
+				queue.EXPECT().SaveAnomaly(gomock.Any()).DoAndReturn(func(a *domain.Anomaly) error {
+					assert.Equal(t, sale, a.Sale)
+					assert.Equal(t, domain.AnomalyActionFlag, a.Action)
+					assert.Equal(t, domain.AnomalyPending, a.Status)
+					assert.Equal(t, anomalous, a.AnomalyScore)
+					assert.Len(t, a.ID, 16)
+
+					return nil
+				})
+			},
+		},
+		{
+			name:   "аномальная продажа в карантине не сохраняется",
+			action: domain.AnomalyActionQuarantine,
+			mock: func(storage *MockSalesStorage, detector *MockAnomalyDetector, queue *MockAnomalyQueue, sale *domain.Sale) {
+				detector.EXPECT().Score(sale).Return(anomalous)
+				queue.EXPECT().SaveAnomaly(gomock.Any()).DoAndReturn(func(a *domain.Anomaly) error {
+					assert.Equal(t, domain.AnomalyActionQuarantine, a.Action)
+					return nil
+				})
+			},
+			error: domain.ErrSaleQuarantined,
+		},
+	}
+
+	for _, tt := range testCases {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			storage := NewMockSalesStorage(ctrl)
+			detector := NewMockAnomalyDetector(ctrl)
+			queue := NewMockAnomalyQueue(ctrl)
+
+			sale := newSale()
+			tt.mock(storage, detector, queue, sale)
+
+			s := NewSaleService(storage, logger.NoOpLogger(), WithAnomalyDetection(detector, queue, 6, tt.action))
+
+			err := s.AddSale(sale)
+			if tt.error != nil {
+				assert.ErrorIs(t, err, tt.error)
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestAnomalyService_Resolve(t *testing.T) {
+	t.Parallel()
+
+	sale := &domain.Sale{StoreID: "store_1", ProductID: "product_1", QuantitySold: 1, SalePrice: decimal.NewFromInt(199000)}
+	now := time.Date(2024, 5, 2, 9, 0, 0, 0, time.UTC)
+
+	anomaly := func(action, status string) *domain.Anomaly {
+		return &domain.Anomaly{ID: "a1", Sale: sale, Action: action, Status: status}
+	}
+
+	testCases := []struct {
+		name    string
+		anomaly *domain.Anomaly
+		accept  bool
+		mock    func(sales *MockSalesService, detector *MockAnomalyDetector)
+		status  string
+		error   error
+	}{
+		{
+			name:    "подтвержденная продажа из карантина сохраняется",
+			anomaly: anomaly(domain.AnomalyActionQuarantine, domain.AnomalyPending),
+			accept:  true,
+			mock: func(sales *MockSalesService, detector *MockAnomalyDetector) {
+				sales.EXPECT().AddAcceptedSale(sale).Return(nil)
+				detector.EXPECT().Observe(sale)
+			},
+			status: domain.AnomalyAccepted,
+		},
+		{
+			name:    "подтвержденная сохраненная продажа учитывается в статистике",
+			anomaly: anomaly(domain.AnomalyActionFlag, domain.AnomalyPending),
+			accept:  true,
+			mock: func(sales *MockSalesService, detector *MockAnomalyDetector) {
+				detector.EXPECT().Observe(sale)
+			},
+			status: domain.AnomalyAccepted,
+		},
+		{
+			name:    "отклоненная продажа из карантина не сохраняется",
+			anomaly: anomaly(domain.AnomalyActionQuarantine, domain.AnomalyPending),
+			mock:    func(sales *MockSalesService, detector *MockAnomalyDetector) {},
+			status:  domain.AnomalyRejected,
+		},
+		{
+			name:    "решение по аномалии уже принято",
+			anomaly: anomaly(domain.AnomalyActionQuarantine, domain.AnomalyRejected),
+			accept:  true,
+			mock:    func(sales *MockSalesService, detector *MockAnomalyDetector) {},
+			error:   domain.ErrAnomalyResolved,
+		},
+	}
+
+	for _, tt := range testCases {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			sales := NewMockSalesService(ctrl)
+			detector := NewMockAnomalyDetector(ctrl)
+			queue := NewMockAnomalyQueue(ctrl)
+
+			queue.EXPECT().GetAnomaly("a1").Return(tt.anomaly, nil)
+			tt.mock(sales, detector)
+
+			if tt.error == nil {
+				queue.EXPECT().SaveAnomaly(gomock.Any()).Return(nil)
+			}
+
+			s := NewAnomalyService(queue, detector, sales, logger.NoOpLogger())
+			s.now = func() time.Time { return now }
+
+			resolve := s.RejectAnomaly
+			if tt.accept {
+				resolve = s.AcceptAnomaly
+			}
+
+			res, err := resolve("a1")
+			if tt.error != nil {
+				assert.ErrorIs(t, err, tt.error)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.status, res.Status)
+			assert.Equal(t, &now, res.ResolvedAt)
+			assert.Equal(t, domain.AnomalyPending, tt.anomaly.Status, "аномалия в очереди не изменяется до сохранения")
+		})
+	}
+}
+
+func TestAnomalyService_AcceptBackdatedSale(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	detector := NewMockAnomalyDetector(ctrl)
+	detector.EXPECT().Observe(gomock.Any()).AnyTimes()
+
+	queue, err := anomaly.NewQueue("")
+	require.NoError(t, err)
+
+	salesStorage := storage.New(logger.NoOpLogger(), storage.WithIndexGranularity(2))
+	sales := NewSaleService(salesStorage, logger.NoOpLogger(),
+		WithAnomalyDetection(detector, queue, 6, domain.AnomalyActionQuarantine))
+
+	dt := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	sale := func(price int64, day int) *domain.Sale {
+		return &domain.Sale{
+			StoreID:      "store_1",
+			ProductID:    "product_1",
+			QuantitySold: 1,
+			SalePrice:    decimal.NewFromInt(price),
+			Currency:     "RUB",
+			SaleDate:     dt.AddDate(0, 0, day),
+		}
+	}
+
+	// продажа 2 мая попадает в карантин, пока магазин принимает более поздние продажи
+	detector.EXPECT().Score(gomock.Any()).DoAndReturn(func(sale *domain.Sale) domain.AnomalyScore {
+		if sale.SalePrice.IntPart() == 1000 {
+			return domain.AnomalyScore{Score: 10}
+		}
+
+		return domain.AnomalyScore{}
+	}).AnyTimes()
+
+	require.NoError(t, sales.AddSale(sale(10, 0)))
+	assert.ErrorIs(t, sales.AddSale(sale(1000, 1)), domain.ErrSaleQuarantined)
+
+	for day := 2; day < 10; day++ {
+		require.NoError(t, sales.AddSale(sale(10, day)))
+	}
+
+	anomalies := queue.GetAnomalies(domain.AnomalyPending)
+	require.Len(t, anomalies, 1)
+
+	s := NewAnomalyService(queue, detector, sales, logger.NoOpLogger())
+
+	res, err := s.AcceptAnomaly(anomalies[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.AnomalyAccepted, res.Status)
+
+	// продажа вставлена по времени: суммы за периоды до, после и вокруг нее учитывают ее один раз
+	periods := []struct {
+		from, to int
+		gross    string
+	}{
+		{from: 0, to: 9, gross: "1090"},
+		{from: 1, to: 1, gross: "1000"},
+		{from: 0, to: 1, gross: "1010"},
+		{from: 2, to: 9, gross: "80"},
+	}
+
+	for _, p := range periods {
+		totals, err := sales.GetTotalSum("store_1", dt.AddDate(0, 0, p.from), dt.AddDate(0, 0, p.to+1).Add(-time.Nanosecond))
+		require.NoError(t, err)
+		assert.Equal(t, p.gross, totals["RUB"].Gross.String(), "days %d - %d", p.from, p.to)
+	}
+
+	queried, err := sales.Query(domain.Query{
+		StartDate: dt,
+		EndDate:   dt.AddDate(0, 0, 10).Add(-time.Nanosecond),
+		Filters:   map[string][]string{domain.FieldStore: {"store_1"}},
+		Metrics:   []string{domain.MetricGross, domain.MetricSales},
+	})
+	require.NoError(t, err)
+	require.Len(t, queried.Rows, 1)
+	assert.Equal(t, "1090", queried.Rows[0].Metrics[domain.MetricGross].String())
+	assert.Equal(t, "10", queried.Rows[0].Metrics[domain.MetricSales].String())
+}
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+	"go.dataflow.ru/service-sales/internal/app/ports"
+	"go.dataflow.ru/service-sales/pkg/logger"
+)
+
+type CatalogService struct {
+	storage ports.CatalogStorage
+	logger  *logger.Logger
+
+	// часовой пояс магазинов, отсутствующих в справочнике или без указанного пояса
+	defaultLocation *time.Location
+
+	locations map[string]*time.Location // загруженные часовые пояса по имени
+	mu        sync.RWMutex
+}
+
+func NewCatalogService(storage ports.CatalogStorage, defaultLocation *time.Location, logger *logger.Logger) *CatalogService {
+	return &CatalogService{
+		storage:         storage,
+		logger:          logger,
+		defaultLocation: defaultLocation,
+		locations:       make(map[string]*time.Location),
+	}
+}
+
+// SaveStore создает или обновляет магазин.
+func (s *CatalogService) SaveStore(store *domain.Store) error {
+	return s.ImportStores([]*domain.Store{store})
+}
+
+// ImportStores создает или обновляет магазины. Если хотя бы один магазин некорректен, не сохраняется ни один.
+func (s *CatalogService) ImportStores(stores []*domain.Store) error {
+	for _, store := range stores {
+		if err := s.validateStore(store); err != nil {
+			return fmt.Errorf("store %q: %w", store.ID, err)
+		}
+	}
+
+	return s.storage.SaveStores(stores...)
+}
+
+// DeleteStore удаляет магазин.
+func (s *CatalogService) DeleteStore(storeID string) error {
+	return s.storage.DeleteStore(storeID)
+}
+
+// GetStore возвращает магазин по идентификатору.
+func (s *CatalogService) GetStore(storeID string) (*domain.Store, error) {
+	store, ok := s.storage.GetStore(storeID)
+	if !ok {
+		return nil, domain.ErrStoreNotFound
+	}
+
+	return store, nil
+}
+
+// GetStores возвращает все магазины.
+func (s *CatalogService) GetStores() []*domain.Store {
+	return s.storage.GetStores()
+}
+
+// SaveProduct создает или обновляет товар.
+func (s *CatalogService) SaveProduct(product *domain.Product) error {
+	return s.ImportProducts([]*domain.Product{product})
+}
+
+// ImportProducts создает или обновляет товары. Если хотя бы один товар некорректен, не сохраняется ни один.
+func (s *CatalogService) ImportProducts(products []*domain.Product) error {
+	for _, product := range products {
+		if product.ID == "" {
+			return fmt.Errorf("invalid product id")
+		}
+	}
+
+	return s.storage.SaveProducts(products...)
+}
+
+// DeleteProduct удаляет товар.
+func (s *CatalogService) DeleteProduct(productID string) error {
+	return s.storage.DeleteProduct(productID)
+}
+
+// GetProduct возвращает товар по идентификатору.
+func (s *CatalogService) GetProduct(productID string) (*domain.Product, error) {
+	product, ok := s.storage.GetProduct(productID)
+	if !ok {
+		return nil, domain.ErrProductNotFound
+	}
+
+	return product, nil
+}
+
+// GetProducts возвращает все товары.
+func (s *CatalogService) GetProducts() []*domain.Product {
+	return s.storage.GetProducts()
+}
+
+// Location возвращает часовой пояс магазина.
+func (s *CatalogService) Location(storeID string) (*time.Location, error) {
+	store, ok := s.storage.GetStore(storeID)
+	if !ok || store.TimeZone == "" {
+		return s.defaultLocation, nil
+	}
+
+	return s.loadLocation(store.TimeZone)
+}
+
+func (s *CatalogService) validateStore(store *domain.Store) error {
+	if store.ID == "" {
+		return fmt.Errorf("invalid store id")
+	}
+
+	if store.TimeZone != "" {
+		if _, err := s.loadLocation(store.TimeZone); err != nil {
+			return fmt.Errorf("invalid time zone: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// loadLocation загружает часовой пояс по имени IANA. Загруженные пояса кешируются,
+// так как time.LoadLocation каждый раз читает базу часовых поясов.
+func (s *CatalogService) loadLocation(name string) (*time.Location, error) {
+	s.mu.RLock()
+	loc, ok := s.locations[name]
+	s.mu.RUnlock()
+
+	if ok {
+		return loc, nil
+	}
+
+	// "Local" зависит от окружения сервиса, а не от магазина
+	if name == "Local" {
+		return nil, fmt.Errorf("unknown time zone %s", name)
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.locations[name] = loc
+	s.mu.Unlock()
+
+	return loc, nil
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"go.dataflow.ru/service-sales/pkg/logger"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+)
+
+func TestCatalogService_ImportStores(t *testing.T) {
+	t.Parallel()
+
+	valid := &domain.Store{ID: "store_1", TimeZone: "Europe/Moscow"}
+
+	testCases := []struct {
+		name   string
+		stores []*domain.Store
+		mock   func(storage *MockCatalogStorage)
+		error  string
+	}{
+		{
+			name:   "все магазины корректны",
+			stores: []*domain.Store{valid, {ID: "store_2"}},
+			mock: func(storage *MockCatalogStorage) {
+				storage.EXPECT().SaveStores(valid, &domain.Store{ID: "store_2"}).Return(nil)
+			},
+		},
+		{
+			name:   "пустой идентификатор: не сохраняется ни один магазин",
+			stores: []*domain.Store{valid, {ID: ""}},
+			error:  `store "": invalid store id`,
+		},
+		{
+			name:   "неизвестный часовой пояс: не сохраняется ни один магазин",
+			stores: []*domain.Store{valid, {ID: "store_2", TimeZone: "Europe/Unknown"}},
+			error:  `store "store_2": invalid time zone`,
+		},
+		{
+			name:   "часовой пояс Local зависит от окружения и не принимается",
+			stores: []*domain.Store{{ID: "store_2", TimeZone: "Local"}, valid},
+			error:  `store "store_2": invalid time zone: unknown time zone Local`,
+		},
+	}
+
+	for _, tt := range testCases {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			storage := NewMockCatalogStorage(ctrl)
+
+			if tt.mock != nil {
+				tt.mock(storage)
+			}
+
+			s := NewCatalogService(storage, time.UTC, logger.NoOpLogger())
+
+			err := s.ImportStores(tt.stores)
+			if tt.error == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.error)
+			}
+		})
+	}
+}
+
+func TestCatalogService_ImportProducts(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	storage := NewMockCatalogStorage(ctrl)
+
+	s := NewCatalogService(storage, time.UTC, logger.NoOpLogger())
+
+	// товар без идентификатора отклоняет весь импорт
+	assert.EqualError(t, s.ImportProducts([]*domain.Product{{ID: "product_1"}, {Name: "молоко"}}), "invalid product id")
+
+	storage.EXPECT().SaveProducts(&domain.Product{ID: "product_1"}).Return(nil)
+	assert.NoError(t, s.ImportProducts([]*domain.Product{{ID: "product_1"}}))
+}
+
+func TestCatalogService_Location(t *testing.T) {
+	t.Parallel()
+
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		name  string
+		store *domain.Store
+		exp   *time.Location
+	}{
+		{
+			name:  "магазина нет в справочнике",
+			store: nil,
+			exp:   time.UTC,
+		},
+		{
+			name:  "часовой пояс не указан",
+			store: &domain.Store{ID: "store_1"},
+			exp:   time.UTC,
+		},
+		{
+			name:  "часовой пояс магазина",
+			store: &domain.Store{ID: "store_1", TimeZone: "Europe/Moscow"},
+			exp:   moscow,
+		},
+	}
+
+	for _, tt := range testCases {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			storage := NewMockCatalogStorage(ctrl)
+			storage.EXPECT().GetStore("store_1").Return(tt.store, tt.store != nil)
+
+			s := NewCatalogService(storage, time.UTC, logger.NoOpLogger())
+
+			loc, err := s.Location("store_1")
+			assert.NoError(t, err)
+			assert.Equal(t, tt.exp.String(), loc.String())
+		})
+	}
+}
+
+func TestCatalogService_NotFound(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	storage := NewMockCatalogStorage(ctrl)
+	storage.EXPECT().GetStore("store_1").Return(nil, false)
+	storage.EXPECT().GetProduct("product_1").Return(nil, false)
+
+	s := NewCatalogService(storage, time.UTC, logger.NoOpLogger())
+
+	_, err := s.GetStore("store_1")
+	assert.ErrorIs(t, err, domain.ErrStoreNotFound)
+
+	_, err = s.GetProduct("product_1")
+	assert.ErrorIs(t, err, domain.ErrProductNotFound)
+}
@@ -0,0 +1,249 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+	"go.dataflow.ru/service-sales/internal/app/ports"
+	"go.dataflow.ru/service-sales/pkg/forecast"
+	"go.dataflow.ru/service-sales/pkg/logger"
+)
+
+const (
+	defaultForecastHorizon = 14
+	defaultForecastHistory = 365
+	defaultForecastLevel   = 0.95
+
+	maxForecastHorizon = 366
+
+	// weekDays сезонность дневной выручки
+	weekDays = 7
+)
+
+var forecastMethods = map[string]forecast.Method{
+	domain.ModelSeasonalNaive: forecast.SeasonalNaive,
+	domain.ModelHoltWinters:   forecast.HoltWinters,
+}
+
+type ForecastService struct {
+	sales  ports.SalesService
+	logger *logger.Logger
+
+	now func() time.Time
+}
+
+func NewForecastService(sales ports.SalesService, logger *logger.Logger) *ForecastService {
+	return &ForecastService{
+		sales:  sales,
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// Forecast возвращает прогноз дневной выручки магазина на params.Horizon дней после последнего полного дня магазина.
+// История - ряд дневной выручки за params.History дней до текущего дня, начиная с первого дня с продажами.
+// Точность каждой модели проверяется прогнозом последних дней истории по предшествующим дням.
+// Модели, для которых истории недостаточно, не включаются в прогноз.
+func (s *ForecastService) Forecast(storeID string, params domain.ForecastParams) (domain.SalesForecast, error) {
+	if err := params.Validate(); err != nil {
+		return domain.SalesForecast{}, err
+	}
+
+	withDefaults(&params)
+
+	if params.Horizon > maxForecastHorizon {
+		return domain.SalesForecast{}, fmt.Errorf("horizon must not exceed %d days", maxForecastHorizon)
+	}
+
+	loc, err := s.sales.StoreLocation(storeID)
+	if err != nil {
+		return domain.SalesForecast{}, fmt.Errorf("get store time zone: %w", err)
+	}
+
+	end := domain.DateOf(s.now().In(loc)).AddDays(-1)
+
+	days, err := s.sales.GetDailyTotals(storeID, end.AddDays(1-params.History), end)
+	if err != nil {
+		return domain.SalesForecast{}, err
+	}
+
+	// дни до первой продажи не относятся к истории магазина
+	for len(days) > 0 && days[0].Totals.IsZero() {
+		days = days[1:]
+	}
+
+	if len(days) == 0 {
+		return domain.SalesForecast{}, fmt.Errorf("store %q: %w", storeID, domain.ErrInsufficientHistory)
+	}
+
+	if params.Currency == "" {
+		if params.Currency, err = seriesCurrency(days); err != nil {
+			return domain.SalesForecast{}, fmt.Errorf("store %q: %w", storeID, err)
+		}
+	}
+
+	series, err := s.series(storeID, days, params, loc)
+	if err != nil {
+		return domain.SalesForecast{}, err
+	}
+
+	res := domain.SalesForecast{
+		StoreID:      storeID,
+		Currency:     params.Currency,
+		Metric:       params.Metric,
+		Level:        params.Level,
+		HistoryStart: days[0].Date,
+		HistoryEnd:   end,
+	}
+
+	z := forecast.Quantile(params.Level)
+
+	for _, model := range params.Models {
+		method := forecastMethods[model]
+
+		f, err := method(series, weekDays, params.Horizon)
+		if errors.Is(err, forecast.ErrShortSeries) {
+			continue
+		}
+
+		if err != nil {
+			return domain.SalesForecast{}, fmt.Errorf("forecast %s: %w", model, err)
+		}
+
+		mf := domain.ModelForecast{Model: model, Points: make([]domain.ForecastPoint, params.Horizon)}
+
+		for i := range f.Values {
+			lower, upper := f.Interval(i, z)
+
+			mf.Points[i] = domain.ForecastPoint{
+				Date:  end.AddDays(i + 1),
+				Value: revenue(f.Values[i]),
+				Lower: revenue(lower),
+				Upper: revenue(upper),
+			}
+		}
+
+		if acc, err := forecast.Backtest(method, series, weekDays, params.Horizon); err == nil {
+			mf.Backtest = accuracy(acc)
+		}
+
+		res.Models = append(res.Models, mf)
+	}
+
+	if len(res.Models) == 0 {
+		return domain.SalesForecast{}, fmt.Errorf("store %q: %d days of sales: %w", storeID, len(series), domain.ErrInsufficientHistory)
+	}
+
+	return res, nil
+}
+
+// series возвращает ряд дневной выручки магазина в валюте прогноза. Выручка дней с продажами в других валютах
+// пересчитывается по курсам.
+func (s *ForecastService) series(storeID string, days []domain.DailyTotals, params domain.ForecastParams, loc *time.Location) ([]float64, error) {
+	series := make([]float64, len(days))
+
+	for i, day := range days {
+		amounts := day.Totals[params.Currency]
+
+		for currency, other := range day.Totals {
+			if currency != params.Currency && !other.IsZero() {
+				converted, err := s.sales.GetConvertedTotalSum(storeID, day.Date.Start(loc), day.Date.End(loc), params.Currency)
+				if err != nil {
+					return nil, err
+				}
+
+				amounts = converted
+
+				break
+			}
+		}
+
+		value := amounts.Gross
+		if params.Metric == domain.MetricNet {
+			value = amounts.Net
+		}
+
+		series[i] = value.InexactFloat64()
+	}
+
+	return series, nil
+}
+
+// withDefaults заполняет незаданные параметры прогноза значениями по умолчанию.
+func withDefaults(params *domain.ForecastParams) {
+	if params.Horizon == 0 {
+		params.Horizon = defaultForecastHorizon
+	}
+
+	if params.History == 0 {
+		params.History = defaultForecastHistory
+	}
+
+	if params.Metric == "" {
+		params.Metric = domain.MetricGross
+	}
+
+	if len(params.Models) == 0 {
+		params.Models = []string{domain.ModelSeasonalNaive, domain.ModelHoltWinters}
+	}
+
+	if params.Level == 0 {
+		params.Level = defaultForecastLevel
+	}
+}
+
+// seriesCurrency возвращает валюту продаж ряда, если продажи были в единственной валюте.
+func seriesCurrency(days []domain.DailyTotals) (string, error) {
+	currencies := make(map[string]struct{})
+
+	for _, day := range days {
+		for currency, amounts := range day.Totals {
+			if !amounts.IsZero() {
+				currencies[currency] = struct{}{}
+			}
+		}
+	}
+
+	if len(currencies) != 1 {
+		list := make([]string, 0, len(currencies))
+		for currency := range currencies {
+			list = append(list, currency)
+		}
+
+		sort.Strings(list)
+
+		return "", fmt.Errorf("sales in currencies %v, currency must be defined", list)
+	}
+
+	for currency := range currencies {
+		return currency, nil
+	}
+
+	return "", nil
+}
+
+// revenue возвращает прогнозную выручку: выручка не бывает отрицательной.
+func revenue(v float64) decimal.Decimal {
+	return decimal.NewFromFloat(math.Max(v, 0)).Round(domain.AmountPrecision)
+}
+
+func accuracy(acc forecast.Accuracy) *domain.ForecastAccuracy {
+	res := &domain.ForecastAccuracy{
+		Days: acc.Points,
+		MAE:  decimal.NewFromFloat(acc.MAE).Round(domain.AmountPrecision),
+		RMSE: decimal.NewFromFloat(acc.RMSE).Round(domain.AmountPrecision),
+	}
+
+	if !math.IsNaN(acc.MAPE) {
+		mape := decimal.NewFromFloat(acc.MAPE).Round(2)
+		res.MAPE = &mape
+	}
+
+	return res
+}
@@ -0,0 +1,162 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.dataflow.ru/service-sales/pkg/logger"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+)
+
+func TestForecastService_Forecast(t *testing.T) {
+	t.Parallel()
+
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	require.NoError(t, err)
+
+	now := time.Date(2024, 3, 29, 12, 0, 0, 0, moscow)
+	start, end := domain.Date{Year: 2024, Month: time.March, Day: 1}, domain.Date{Year: 2024, Month: time.March, Day: 28}
+
+	pattern := []int64{100, 120, 110, 130, 180, 250, 90}
+
+	// история с 1 марта: продажи с недельной сезонностью в последние days дней, до них продаж нет
+	history := func(days int, currencies ...string) []domain.DailyTotals {
+		res := make([]domain.DailyTotals, 0, 28)
+
+		for day := start; !day.After(end); day = day.AddDays(1) {
+			totals := domain.Totals{}
+
+			if i := start.DaysUntil(day); i >= 28-days {
+				for _, currency := range currencies {
+					totals[currency] = grossAmounts(pattern[i%7])
+				}
+			}
+
+			res = append(res, domain.DailyTotals{Date: day, Totals: totals})
+		}
+
+		return res
+	}
+
+	testCases := []struct {
+		name   string
+		params domain.ForecastParams
+		mock   func(sales *MockSalesService)
+		days   int // дней истории с продажами
+		models []string
+		first  string // прогноз на 29 марта
+		error  error
+	}{
+		{
+			name:   "обе модели по трем неделям истории",
+			params: domain.ForecastParams{Horizon: 7, History: 28},
+			mock: func(sales *MockSalesService) {
+				sales.EXPECT().GetDailyTotals("store_1", start, end).Return(history(21, "RUB"), nil)
+			},
+			days:   21,
+			models: []string{domain.ModelSeasonalNaive, domain.ModelHoltWinters},
+			first:  "100",
+		},
+		{
+			name:   "истории недостаточно для Хольта-Винтерса",
+			params: domain.ForecastParams{Horizon: 7, History: 28},
+			mock: func(sales *MockSalesService) {
+				sales.EXPECT().GetDailyTotals("store_1", start, end).Return(history(10, "RUB"), nil)
+			},
+			days:   10,
+			models: []string{domain.ModelSeasonalNaive},
+			first:  "100",
+		},
+		{
+			name:   "продажи в нескольких валютах пересчитываются в валюту прогноза",
+			params: domain.ForecastParams{Horizon: 7, History: 28, Currency: "RUB", Models: []string{domain.ModelSeasonalNaive}},
+			mock: func(sales *MockSalesService) {
+				sales.EXPECT().GetDailyTotals("store_1", start, end).Return(history(21, "RUB", "USD"), nil)
+				sales.EXPECT().GetConvertedTotalSum("store_1", gomock.Any(), gomock.Any(), "RUB").
+					Return(grossAmounts(500), nil).Times(21)
+			},
+			days:   21,
+			models: []string{domain.ModelSeasonalNaive},
+			first:  "500",
+		},
+		{
+			name:   "валюта не задана при продажах в нескольких валютах",
+			params: domain.ForecastParams{Horizon: 7, History: 28},
+			mock: func(sales *MockSalesService) {
+				sales.EXPECT().GetDailyTotals("store_1", start, end).Return(history(21, "RUB", "USD"), nil)
+			},
+			error: assert.AnError,
+		},
+		{
+			name:   "нет продаж",
+			params: domain.ForecastParams{Horizon: 7, History: 28},
+			mock: func(sales *MockSalesService) {
+				sales.EXPECT().GetDailyTotals("store_1", start, end).Return(history(0, "RUB"), nil)
+			},
+			error: domain.ErrInsufficientHistory,
+		},
+	}
+
+	for _, tt := range testCases {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			sales := NewMockSalesService(ctrl)
+
+			sales.EXPECT().StoreLocation("store_1").Return(moscow, nil)
+			tt.mock(sales)
+
+			s := NewForecastService(sales, logger.NoOpLogger())
+			s.now = func() time.Time { return now }
+
+			res, err := s.Forecast("store_1", tt.params)
+
+			switch {
+			case tt.error == assert.AnError:
+				assert.Error(t, err)
+				return
+			case tt.error != nil:
+				assert.ErrorIs(t, err, tt.error)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, "RUB", res.Currency)
+			assert.Equal(t, domain.MetricGross, res.Metric)
+			assert.Equal(t, 0.95, res.Level)
+			assert.Equal(t, end.AddDays(1-tt.days), res.HistoryStart)
+			assert.Equal(t, end, res.HistoryEnd)
+			require.Len(t, res.Models, len(tt.models))
+
+			for i, model := range res.Models {
+				assert.Equal(t, tt.models[i], model.Model)
+				require.Len(t, model.Points, 7)
+
+				first := model.Points[0]
+				assert.Equal(t, domain.Date{Year: 2024, Month: time.April, Day: 4}, model.Points[6].Date)
+				assert.Equal(t, domain.Date{Year: 2024, Month: time.March, Day: 29}, first.Date)
+				assert.Equal(t, tt.first, first.Value.String())
+				assert.True(t, first.Lower.LessThanOrEqual(first.Value))
+				assert.True(t, first.Upper.GreaterThanOrEqual(first.Value))
+
+				// истории короче двух недель недостаточно для проверки прогноза на неделю
+				if tt.days < 14 {
+					assert.Nil(t, model.Backtest)
+					continue
+				}
+
+				// в истории без шума прогноз последней недели точен
+				require.NotNil(t, model.Backtest)
+				assert.Equal(t, 7, model.Backtest.Days)
+				assert.Equal(t, "0", model.Backtest.MAE.String())
+			}
+		})
+	}
+}
@@ -5,14 +5,172 @@
 package services
 
 import (
+	context "context"
 	reflect "reflect"
 	time "time"
 
 	gomock "github.com/golang/mock/gomock"
-	decimal "github.com/shopspring/decimal"
 	domain "go.dataflow.ru/service-sales/internal/app/domain"
+	ports "go.dataflow.ru/service-sales/internal/app/ports"
 )
 
+// MockSalesReader is a mock of SalesReader interface.
+type MockSalesReader struct {
+	ctrl     *gomock.Controller
+	recorder *MockSalesReaderMockRecorder
+}
+
+// MockSalesReaderMockRecorder is the mock recorder for MockSalesReader.
+type MockSalesReaderMockRecorder struct {
+	mock *MockSalesReader
+}
+
+// NewMockSalesReader creates a new mock instance.
+func NewMockSalesReader(ctrl *gomock.Controller) *MockSalesReader {
+	mock := &MockSalesReader{ctrl: ctrl}
+	mock.recorder = &MockSalesReaderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSalesReader) EXPECT() *MockSalesReaderMockRecorder {
+	return m.recorder
+}
+
+// GetReceiptTotals mocks base method.
+func (m *MockSalesReader) GetReceiptTotals(storeID string, startDate, endDate time.Time) (domain.ReceiptTotals, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetReceiptTotals", storeID, startDate, endDate)
+	ret0, _ := ret[0].(domain.ReceiptTotals)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetReceiptTotals indicates an expected call of GetReceiptTotals.
+func (mr *MockSalesReaderMockRecorder) GetReceiptTotals(storeID, startDate, endDate interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReceiptTotals", reflect.TypeOf((*MockSalesReader)(nil).GetReceiptTotals), storeID, startDate, endDate)
+}
+
+// GetSales mocks base method.
+func (m *MockSalesReader) GetSales() ([]*domain.Sale, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSales")
+	ret0, _ := ret[0].([]*domain.Sale)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSales indicates an expected call of GetSales.
+func (mr *MockSalesReaderMockRecorder) GetSales() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSales", reflect.TypeOf((*MockSalesReader)(nil).GetSales))
+}
+
+// GetSketches mocks base method.
+func (m *MockSalesReader) GetSketches(storeID string, startDate, endDate time.Time) (*domain.SalesSketches, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSketches", storeID, startDate, endDate)
+	ret0, _ := ret[0].(*domain.SalesSketches)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSketches indicates an expected call of GetSketches.
+func (mr *MockSalesReaderMockRecorder) GetSketches(storeID, startDate, endDate interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSketches", reflect.TypeOf((*MockSalesReader)(nil).GetSketches), storeID, startDate, endDate)
+}
+
+// GetTotalSum mocks base method.
+func (m *MockSalesReader) GetTotalSum(storeID string, startDate, endDate time.Time) (domain.Totals, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTotalSum", storeID, startDate, endDate)
+	ret0, _ := ret[0].(domain.Totals)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTotalSum indicates an expected call of GetTotalSum.
+func (mr *MockSalesReaderMockRecorder) GetTotalSum(storeID, startDate, endDate interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTotalSum", reflect.TypeOf((*MockSalesReader)(nil).GetTotalSum), storeID, startDate, endDate)
+}
+
+// GetTotalSumByDimension mocks base method.
+func (m *MockSalesReader) GetTotalSumByDimension(storeID, dimension string, startDate, endDate time.Time) (map[string]domain.Totals, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTotalSumByDimension", storeID, dimension, startDate, endDate)
+	ret0, _ := ret[0].(map[string]domain.Totals)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTotalSumByDimension indicates an expected call of GetTotalSumByDimension.
+func (mr *MockSalesReaderMockRecorder) GetTotalSumByDimension(storeID, dimension, startDate, endDate interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTotalSumByDimension", reflect.TypeOf((*MockSalesReader)(nil).GetTotalSumByDimension), storeID, dimension, startDate, endDate)
+}
+
+// GetTotalSumByProduct mocks base method.
+func (m *MockSalesReader) GetTotalSumByProduct(storeID string, startDate, endDate time.Time) (map[string]domain.Totals, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTotalSumByProduct", storeID, startDate, endDate)
+	ret0, _ := ret[0].(map[string]domain.Totals)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTotalSumByProduct indicates an expected call of GetTotalSumByProduct.
+func (mr *MockSalesReaderMockRecorder) GetTotalSumByProduct(storeID, startDate, endDate interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTotalSumByProduct", reflect.TypeOf((*MockSalesReader)(nil).GetTotalSumByProduct), storeID, startDate, endDate)
+}
+
+// GetTotalSums mocks base method.
+func (m *MockSalesReader) GetTotalSums(queries []domain.StorePeriod) ([]domain.Totals, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTotalSums", queries)
+	ret0, _ := ret[0].([]domain.Totals)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTotalSums indicates an expected call of GetTotalSums.
+func (mr *MockSalesReaderMockRecorder) GetTotalSums(queries interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTotalSums", reflect.TypeOf((*MockSalesReader)(nil).GetTotalSums), queries)
+}
+
+// Query mocks base method.
+func (m *MockSalesReader) Query(q domain.Query) (*domain.QueryResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Query", q)
+	ret0, _ := ret[0].(*domain.QueryResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Query indicates an expected call of Query.
+func (mr *MockSalesReaderMockRecorder) Query(q interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Query", reflect.TypeOf((*MockSalesReader)(nil).Query), q)
+}
+
+// ScanSales mocks base method.
+func (m *MockSalesReader) ScanSales(ctx context.Context, fn func(*domain.Sale) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ScanSales", ctx, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ScanSales indicates an expected call of ScanSales.
+func (mr *MockSalesReaderMockRecorder) ScanSales(ctx, fn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ScanSales", reflect.TypeOf((*MockSalesReader)(nil).ScanSales), ctx, fn)
+}
+
 // MockSalesStorage is a mock of SalesStorage interface.
 type MockSalesStorage struct {
 	ctrl     *gomock.Controller
@@ -36,10 +194,26 @@ func (m *MockSalesStorage) EXPECT() *MockSalesStorageMockRecorder {
 	return m.recorder
 }
 
+// AddReceipt mocks base method.
+func (m *MockSalesStorage) AddReceipt(receipt *domain.Receipt) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddReceipt", receipt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddReceipt indicates an expected call of AddReceipt.
+func (mr *MockSalesStorageMockRecorder) AddReceipt(receipt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddReceipt", reflect.TypeOf((*MockSalesStorage)(nil).AddReceipt), receipt)
+}
+
 // AddSale mocks base method.
-func (m *MockSalesStorage) AddSale(sale *domain.Sale) {
+func (m *MockSalesStorage) AddSale(sale *domain.Sale) error {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "AddSale", sale)
+	ret := m.ctrl.Call(m, "AddSale", sale)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
 // AddSale indicates an expected call of AddSale.
@@ -48,12 +222,42 @@ func (mr *MockSalesStorageMockRecorder) AddSale(sale interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddSale", reflect.TypeOf((*MockSalesStorage)(nil).AddSale), sale)
 }
 
+// CloseSnapshot mocks base method.
+func (m *MockSalesStorage) CloseSnapshot(token string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CloseSnapshot", token)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CloseSnapshot indicates an expected call of CloseSnapshot.
+func (mr *MockSalesStorageMockRecorder) CloseSnapshot(token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CloseSnapshot", reflect.TypeOf((*MockSalesStorage)(nil).CloseSnapshot), token)
+}
+
+// GetReceiptTotals mocks base method.
+func (m *MockSalesStorage) GetReceiptTotals(storeID string, startDate, endDate time.Time) (domain.ReceiptTotals, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetReceiptTotals", storeID, startDate, endDate)
+	ret0, _ := ret[0].(domain.ReceiptTotals)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetReceiptTotals indicates an expected call of GetReceiptTotals.
+func (mr *MockSalesStorageMockRecorder) GetReceiptTotals(storeID, startDate, endDate interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReceiptTotals", reflect.TypeOf((*MockSalesStorage)(nil).GetReceiptTotals), storeID, startDate, endDate)
+}
+
 // GetSales mocks base method.
-func (m *MockSalesStorage) GetSales() []*domain.Sale {
+func (m *MockSalesStorage) GetSales() ([]*domain.Sale, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetSales")
 	ret0, _ := ret[0].([]*domain.Sale)
-	return ret0
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // GetSales indicates an expected call of GetSales.
@@ -62,12 +266,28 @@ func (mr *MockSalesStorageMockRecorder) GetSales() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSales", reflect.TypeOf((*MockSalesStorage)(nil).GetSales))
 }
 
+// GetSketches mocks base method.
+func (m *MockSalesStorage) GetSketches(storeID string, startDate, endDate time.Time) (*domain.SalesSketches, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSketches", storeID, startDate, endDate)
+	ret0, _ := ret[0].(*domain.SalesSketches)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSketches indicates an expected call of GetSketches.
+func (mr *MockSalesStorageMockRecorder) GetSketches(storeID, startDate, endDate interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSketches", reflect.TypeOf((*MockSalesStorage)(nil).GetSketches), storeID, startDate, endDate)
+}
+
 // GetTotalSum mocks base method.
-func (m *MockSalesStorage) GetTotalSum(storeID string, startDate, endDate time.Time) decimal.Decimal {
+func (m *MockSalesStorage) GetTotalSum(storeID string, startDate, endDate time.Time) (domain.Totals, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetTotalSum", storeID, startDate, endDate)
-	ret0, _ := ret[0].(decimal.Decimal)
-	return ret0
+	ret0, _ := ret[0].(domain.Totals)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // GetTotalSum indicates an expected call of GetTotalSum.
@@ -75,3 +295,136 @@ func (mr *MockSalesStorageMockRecorder) GetTotalSum(storeID, startDate, endDate
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTotalSum", reflect.TypeOf((*MockSalesStorage)(nil).GetTotalSum), storeID, startDate, endDate)
 }
+
+// GetTotalSumByDimension mocks base method.
+func (m *MockSalesStorage) GetTotalSumByDimension(storeID, dimension string, startDate, endDate time.Time) (map[string]domain.Totals, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTotalSumByDimension", storeID, dimension, startDate, endDate)
+	ret0, _ := ret[0].(map[string]domain.Totals)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTotalSumByDimension indicates an expected call of GetTotalSumByDimension.
+func (mr *MockSalesStorageMockRecorder) GetTotalSumByDimension(storeID, dimension, startDate, endDate interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTotalSumByDimension", reflect.TypeOf((*MockSalesStorage)(nil).GetTotalSumByDimension), storeID, dimension, startDate, endDate)
+}
+
+// GetTotalSumByProduct mocks base method.
+func (m *MockSalesStorage) GetTotalSumByProduct(storeID string, startDate, endDate time.Time) (map[string]domain.Totals, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTotalSumByProduct", storeID, startDate, endDate)
+	ret0, _ := ret[0].(map[string]domain.Totals)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTotalSumByProduct indicates an expected call of GetTotalSumByProduct.
+func (mr *MockSalesStorageMockRecorder) GetTotalSumByProduct(storeID, startDate, endDate interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTotalSumByProduct", reflect.TypeOf((*MockSalesStorage)(nil).GetTotalSumByProduct), storeID, startDate, endDate)
+}
+
+// GetTotalSums mocks base method.
+func (m *MockSalesStorage) GetTotalSums(queries []domain.StorePeriod) ([]domain.Totals, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTotalSums", queries)
+	ret0, _ := ret[0].([]domain.Totals)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTotalSums indicates an expected call of GetTotalSums.
+func (mr *MockSalesStorageMockRecorder) GetTotalSums(queries interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTotalSums", reflect.TypeOf((*MockSalesStorage)(nil).GetTotalSums), queries)
+}
+
+// InsertSale mocks base method.
+func (m *MockSalesStorage) InsertSale(sale *domain.Sale) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertSale", sale)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InsertSale indicates an expected call of InsertSale.
+func (mr *MockSalesStorageMockRecorder) InsertSale(sale interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertSale", reflect.TypeOf((*MockSalesStorage)(nil).InsertSale), sale)
+}
+
+// OpenSnapshot mocks base method.
+func (m *MockSalesStorage) OpenSnapshot() (domain.Snapshot, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "OpenSnapshot")
+	ret0, _ := ret[0].(domain.Snapshot)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// OpenSnapshot indicates an expected call of OpenSnapshot.
+func (mr *MockSalesStorageMockRecorder) OpenSnapshot() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenSnapshot", reflect.TypeOf((*MockSalesStorage)(nil).OpenSnapshot))
+}
+
+// Purge mocks base method.
+func (m *MockSalesStorage) Purge(purge domain.Purge) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Purge", purge)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Purge indicates an expected call of Purge.
+func (mr *MockSalesStorageMockRecorder) Purge(purge interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Purge", reflect.TypeOf((*MockSalesStorage)(nil).Purge), purge)
+}
+
+// Query mocks base method.
+func (m *MockSalesStorage) Query(q domain.Query) (*domain.QueryResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Query", q)
+	ret0, _ := ret[0].(*domain.QueryResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Query indicates an expected call of Query.
+func (mr *MockSalesStorageMockRecorder) Query(q interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Query", reflect.TypeOf((*MockSalesStorage)(nil).Query), q)
+}
+
+// ScanSales mocks base method.
+func (m *MockSalesStorage) ScanSales(ctx context.Context, fn func(*domain.Sale) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ScanSales", ctx, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ScanSales indicates an expected call of ScanSales.
+func (mr *MockSalesStorageMockRecorder) ScanSales(ctx, fn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ScanSales", reflect.TypeOf((*MockSalesStorage)(nil).ScanSales), ctx, fn)
+}
+
+// Snapshot mocks base method.
+func (m *MockSalesStorage) Snapshot(token string) (ports.SalesReader, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Snapshot", token)
+	ret0, _ := ret[0].(ports.SalesReader)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Snapshot indicates an expected call of Snapshot.
+func (mr *MockSalesStorageMockRecorder) Snapshot(token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Snapshot", reflect.TypeOf((*MockSalesStorage)(nil).Snapshot), token)
+}
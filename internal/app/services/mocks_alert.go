@@ -0,0 +1,276 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ../ports/alert.go
+
+// Package services is a generated GoMock package.
+package services
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	domain "go.dataflow.ru/service-sales/internal/app/domain"
+)
+
+// MockAlertStorage is a mock of AlertStorage interface.
+type MockAlertStorage struct {
+	ctrl     *gomock.Controller
+	recorder *MockAlertStorageMockRecorder
+}
+
+// MockAlertStorageMockRecorder is the mock recorder for MockAlertStorage.
+type MockAlertStorageMockRecorder struct {
+	mock *MockAlertStorage
+}
+
+// NewMockAlertStorage creates a new mock instance.
+func NewMockAlertStorage(ctrl *gomock.Controller) *MockAlertStorage {
+	mock := &MockAlertStorage{ctrl: ctrl}
+	mock.recorder = &MockAlertStorageMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAlertStorage) EXPECT() *MockAlertStorageMockRecorder {
+	return m.recorder
+}
+
+// DeleteAlert mocks base method.
+func (m *MockAlertStorage) DeleteAlert(id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAlert", id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAlert indicates an expected call of DeleteAlert.
+func (mr *MockAlertStorageMockRecorder) DeleteAlert(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAlert", reflect.TypeOf((*MockAlertStorage)(nil).DeleteAlert), id)
+}
+
+// GetAlert mocks base method.
+func (m *MockAlertStorage) GetAlert(id string) (*domain.Alert, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAlert", id)
+	ret0, _ := ret[0].(*domain.Alert)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAlert indicates an expected call of GetAlert.
+func (mr *MockAlertStorageMockRecorder) GetAlert(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAlert", reflect.TypeOf((*MockAlertStorage)(nil).GetAlert), id)
+}
+
+// GetAlerts mocks base method.
+func (m *MockAlertStorage) GetAlerts() []*domain.Alert {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAlerts")
+	ret0, _ := ret[0].([]*domain.Alert)
+	return ret0
+}
+
+// GetAlerts indicates an expected call of GetAlerts.
+func (mr *MockAlertStorageMockRecorder) GetAlerts() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAlerts", reflect.TypeOf((*MockAlertStorage)(nil).GetAlerts))
+}
+
+// SaveAlert mocks base method.
+func (m *MockAlertStorage) SaveAlert(alert *domain.Alert) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveAlert", alert)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveAlert indicates an expected call of SaveAlert.
+func (mr *MockAlertStorageMockRecorder) SaveAlert(alert interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveAlert", reflect.TypeOf((*MockAlertStorage)(nil).SaveAlert), alert)
+}
+
+// MockAlertNotifier is a mock of AlertNotifier interface.
+type MockAlertNotifier struct {
+	ctrl     *gomock.Controller
+	recorder *MockAlertNotifierMockRecorder
+}
+
+// MockAlertNotifierMockRecorder is the mock recorder for MockAlertNotifier.
+type MockAlertNotifierMockRecorder struct {
+	mock *MockAlertNotifier
+}
+
+// NewMockAlertNotifier creates a new mock instance.
+func NewMockAlertNotifier(ctrl *gomock.Controller) *MockAlertNotifier {
+	mock := &MockAlertNotifier{ctrl: ctrl}
+	mock.recorder = &MockAlertNotifierMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAlertNotifier) EXPECT() *MockAlertNotifierMockRecorder {
+	return m.recorder
+}
+
+// Notify mocks base method.
+func (m *MockAlertNotifier) Notify(ctx context.Context, alert *domain.Alert, event *domain.AlertEvent) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Notify", ctx, alert, event)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Notify indicates an expected call of Notify.
+func (mr *MockAlertNotifierMockRecorder) Notify(ctx, alert, event interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Notify", reflect.TypeOf((*MockAlertNotifier)(nil).Notify), ctx, alert, event)
+}
+
+// MockSalesObserver is a mock of SalesObserver interface.
+type MockSalesObserver struct {
+	ctrl     *gomock.Controller
+	recorder *MockSalesObserverMockRecorder
+}
+
+// MockSalesObserverMockRecorder is the mock recorder for MockSalesObserver.
+type MockSalesObserverMockRecorder struct {
+	mock *MockSalesObserver
+}
+
+// NewMockSalesObserver creates a new mock instance.
+func NewMockSalesObserver(ctrl *gomock.Controller) *MockSalesObserver {
+	mock := &MockSalesObserver{ctrl: ctrl}
+	mock.recorder = &MockSalesObserverMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSalesObserver) EXPECT() *MockSalesObserverMockRecorder {
+	return m.recorder
+}
+
+// SalesAdded mocks base method.
+func (m *MockSalesObserver) SalesAdded(sales []*domain.Sale) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SalesAdded", sales)
+}
+
+// SalesAdded indicates an expected call of SalesAdded.
+func (mr *MockSalesObserverMockRecorder) SalesAdded(sales interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SalesAdded", reflect.TypeOf((*MockSalesObserver)(nil).SalesAdded), sales)
+}
+
+// MockAlertService is a mock of AlertService interface.
+type MockAlertService struct {
+	ctrl     *gomock.Controller
+	recorder *MockAlertServiceMockRecorder
+}
+
+// MockAlertServiceMockRecorder is the mock recorder for MockAlertService.
+type MockAlertServiceMockRecorder struct {
+	mock *MockAlertService
+}
+
+// NewMockAlertService creates a new mock instance.
+func NewMockAlertService(ctrl *gomock.Controller) *MockAlertService {
+	mock := &MockAlertService{ctrl: ctrl}
+	mock.recorder = &MockAlertServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAlertService) EXPECT() *MockAlertServiceMockRecorder {
+	return m.recorder
+}
+
+// CreateAlert mocks base method.
+func (m *MockAlertService) CreateAlert(alert *domain.Alert) (*domain.Alert, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAlert", alert)
+	ret0, _ := ret[0].(*domain.Alert)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateAlert indicates an expected call of CreateAlert.
+func (mr *MockAlertServiceMockRecorder) CreateAlert(alert interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAlert", reflect.TypeOf((*MockAlertService)(nil).CreateAlert), alert)
+}
+
+// DeleteAlert mocks base method.
+func (m *MockAlertService) DeleteAlert(id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAlert", id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAlert indicates an expected call of DeleteAlert.
+func (mr *MockAlertServiceMockRecorder) DeleteAlert(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAlert", reflect.TypeOf((*MockAlertService)(nil).DeleteAlert), id)
+}
+
+// GetAlert mocks base method.
+func (m *MockAlertService) GetAlert(id string) (*domain.Alert, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAlert", id)
+	ret0, _ := ret[0].(*domain.Alert)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAlert indicates an expected call of GetAlert.
+func (mr *MockAlertServiceMockRecorder) GetAlert(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAlert", reflect.TypeOf((*MockAlertService)(nil).GetAlert), id)
+}
+
+// GetAlerts mocks base method.
+func (m *MockAlertService) GetAlerts() []*domain.Alert {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAlerts")
+	ret0, _ := ret[0].([]*domain.Alert)
+	return ret0
+}
+
+// GetAlerts indicates an expected call of GetAlerts.
+func (mr *MockAlertServiceMockRecorder) GetAlerts() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAlerts", reflect.TypeOf((*MockAlertService)(nil).GetAlerts))
+}
+
+// TestAlert mocks base method.
+func (m *MockAlertService) TestAlert(ctx context.Context, id string) (*domain.AlertEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TestAlert", ctx, id)
+	ret0, _ := ret[0].(*domain.AlertEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TestAlert indicates an expected call of TestAlert.
+func (mr *MockAlertServiceMockRecorder) TestAlert(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TestAlert", reflect.TypeOf((*MockAlertService)(nil).TestAlert), ctx, id)
+}
+
+// UpdateAlert mocks base method.
+func (m *MockAlertService) UpdateAlert(alert *domain.Alert) (*domain.Alert, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateAlert", alert)
+	ret0, _ := ret[0].(*domain.Alert)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateAlert indicates an expected call of UpdateAlert.
+func (mr *MockAlertServiceMockRecorder) UpdateAlert(alert interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAlert", reflect.TypeOf((*MockAlertService)(nil).UpdateAlert), alert)
+}
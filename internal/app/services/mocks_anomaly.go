@@ -0,0 +1,194 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ../ports/anomaly.go
+
+// Package services is a generated GoMock package.
+package services
+
+import (
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	domain "go.dataflow.ru/service-sales/internal/app/domain"
+)
+
+// MockAnomalyDetector is a mock of AnomalyDetector interface.
+type MockAnomalyDetector struct {
+	ctrl     *gomock.Controller
+	recorder *MockAnomalyDetectorMockRecorder
+}
+
+// MockAnomalyDetectorMockRecorder is the mock recorder for MockAnomalyDetector.
+type MockAnomalyDetectorMockRecorder struct {
+	mock *MockAnomalyDetector
+}
+
+// NewMockAnomalyDetector creates a new mock instance.
+func NewMockAnomalyDetector(ctrl *gomock.Controller) *MockAnomalyDetector {
+	mock := &MockAnomalyDetector{ctrl: ctrl}
+	mock.recorder = &MockAnomalyDetectorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAnomalyDetector) EXPECT() *MockAnomalyDetectorMockRecorder {
+	return m.recorder
+}
+
+// Observe mocks base method.
+func (m *MockAnomalyDetector) Observe(sale *domain.Sale) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Observe", sale)
+}
+
+// Observe indicates an expected call of Observe.
+func (mr *MockAnomalyDetectorMockRecorder) Observe(sale interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Observe", reflect.TypeOf((*MockAnomalyDetector)(nil).Observe), sale)
+}
+
+// Score mocks base method.
+func (m *MockAnomalyDetector) Score(sale *domain.Sale) domain.AnomalyScore {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Score", sale)
+	ret0, _ := ret[0].(domain.AnomalyScore)
+	return ret0
+}
+
+// Score indicates an expected call of Score.
+func (mr *MockAnomalyDetectorMockRecorder) Score(sale interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Score", reflect.TypeOf((*MockAnomalyDetector)(nil).Score), sale)
+}
+
+// MockAnomalyQueue is a mock of AnomalyQueue interface.
+type MockAnomalyQueue struct {
+	ctrl     *gomock.Controller
+	recorder *MockAnomalyQueueMockRecorder
+}
+
+// MockAnomalyQueueMockRecorder is the mock recorder for MockAnomalyQueue.
+type MockAnomalyQueueMockRecorder struct {
+	mock *MockAnomalyQueue
+}
+
+// NewMockAnomalyQueue creates a new mock instance.
+func NewMockAnomalyQueue(ctrl *gomock.Controller) *MockAnomalyQueue {
+	mock := &MockAnomalyQueue{ctrl: ctrl}
+	mock.recorder = &MockAnomalyQueueMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAnomalyQueue) EXPECT() *MockAnomalyQueueMockRecorder {
+	return m.recorder
+}
+
+// GetAnomalies mocks base method.
+func (m *MockAnomalyQueue) GetAnomalies(status string) []*domain.Anomaly {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAnomalies", status)
+	ret0, _ := ret[0].([]*domain.Anomaly)
+	return ret0
+}
+
+// GetAnomalies indicates an expected call of GetAnomalies.
+func (mr *MockAnomalyQueueMockRecorder) GetAnomalies(status interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAnomalies", reflect.TypeOf((*MockAnomalyQueue)(nil).GetAnomalies), status)
+}
+
+// GetAnomaly mocks base method.
+func (m *MockAnomalyQueue) GetAnomaly(id string) (*domain.Anomaly, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAnomaly", id)
+	ret0, _ := ret[0].(*domain.Anomaly)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAnomaly indicates an expected call of GetAnomaly.
+func (mr *MockAnomalyQueueMockRecorder) GetAnomaly(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAnomaly", reflect.TypeOf((*MockAnomalyQueue)(nil).GetAnomaly), id)
+}
+
+// SaveAnomaly mocks base method.
+func (m *MockAnomalyQueue) SaveAnomaly(anomaly *domain.Anomaly) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveAnomaly", anomaly)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveAnomaly indicates an expected call of SaveAnomaly.
+func (mr *MockAnomalyQueueMockRecorder) SaveAnomaly(anomaly interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveAnomaly", reflect.TypeOf((*MockAnomalyQueue)(nil).SaveAnomaly), anomaly)
+}
+
+// MockAnomalyService is a mock of AnomalyService interface.
+type MockAnomalyService struct {
+	ctrl     *gomock.Controller
+	recorder *MockAnomalyServiceMockRecorder
+}
+
+// MockAnomalyServiceMockRecorder is the mock recorder for MockAnomalyService.
+type MockAnomalyServiceMockRecorder struct {
+	mock *MockAnomalyService
+}
+
+// NewMockAnomalyService creates a new mock instance.
+func NewMockAnomalyService(ctrl *gomock.Controller) *MockAnomalyService {
+	mock := &MockAnomalyService{ctrl: ctrl}
+	mock.recorder = &MockAnomalyServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAnomalyService) EXPECT() *MockAnomalyServiceMockRecorder {
+	return m.recorder
+}
+
+// AcceptAnomaly mocks base method.
+func (m *MockAnomalyService) AcceptAnomaly(id string) (*domain.Anomaly, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AcceptAnomaly", id)
+	ret0, _ := ret[0].(*domain.Anomaly)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AcceptAnomaly indicates an expected call of AcceptAnomaly.
+func (mr *MockAnomalyServiceMockRecorder) AcceptAnomaly(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AcceptAnomaly", reflect.TypeOf((*MockAnomalyService)(nil).AcceptAnomaly), id)
+}
+
+// GetAnomalies mocks base method.
+func (m *MockAnomalyService) GetAnomalies(status string) []*domain.Anomaly {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAnomalies", status)
+	ret0, _ := ret[0].([]*domain.Anomaly)
+	return ret0
+}
+
+// GetAnomalies indicates an expected call of GetAnomalies.
+func (mr *MockAnomalyServiceMockRecorder) GetAnomalies(status interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAnomalies", reflect.TypeOf((*MockAnomalyService)(nil).GetAnomalies), status)
+}
+
+// RejectAnomaly mocks base method.
+func (m *MockAnomalyService) RejectAnomaly(id string) (*domain.Anomaly, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RejectAnomaly", id)
+	ret0, _ := ret[0].(*domain.Anomaly)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RejectAnomaly indicates an expected call of RejectAnomaly.
+func (mr *MockAnomalyServiceMockRecorder) RejectAnomaly(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RejectAnomaly", reflect.TypeOf((*MockAnomalyService)(nil).RejectAnomaly), id)
+}
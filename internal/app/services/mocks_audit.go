@@ -0,0 +1,115 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ../ports/audit.go
+
+// Package services is a generated GoMock package.
+package services
+
+import (
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	domain "go.dataflow.ru/service-sales/internal/app/domain"
+)
+
+// MockAuditLog is a mock of AuditLog interface.
+type MockAuditLog struct {
+	ctrl     *gomock.Controller
+	recorder *MockAuditLogMockRecorder
+}
+
+// MockAuditLogMockRecorder is the mock recorder for MockAuditLog.
+type MockAuditLogMockRecorder struct {
+	mock *MockAuditLog
+}
+
+// NewMockAuditLog creates a new mock instance.
+func NewMockAuditLog(ctrl *gomock.Controller) *MockAuditLog {
+	mock := &MockAuditLog{ctrl: ctrl}
+	mock.recorder = &MockAuditLogMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAuditLog) EXPECT() *MockAuditLogMockRecorder {
+	return m.recorder
+}
+
+// Append mocks base method.
+func (m *MockAuditLog) Append(entry *domain.AuditEntry) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Append", entry)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Append indicates an expected call of Append.
+func (mr *MockAuditLogMockRecorder) Append(entry interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Append", reflect.TypeOf((*MockAuditLog)(nil).Append), entry)
+}
+
+// GetEntries mocks base method.
+func (m *MockAuditLog) GetEntries() []*domain.AuditEntry {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetEntries")
+	ret0, _ := ret[0].([]*domain.AuditEntry)
+	return ret0
+}
+
+// GetEntries indicates an expected call of GetEntries.
+func (mr *MockAuditLogMockRecorder) GetEntries() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEntries", reflect.TypeOf((*MockAuditLog)(nil).GetEntries))
+}
+
+// MockRetentionService is a mock of RetentionService interface.
+type MockRetentionService struct {
+	ctrl     *gomock.Controller
+	recorder *MockRetentionServiceMockRecorder
+}
+
+// MockRetentionServiceMockRecorder is the mock recorder for MockRetentionService.
+type MockRetentionServiceMockRecorder struct {
+	mock *MockRetentionService
+}
+
+// NewMockRetentionService creates a new mock instance.
+func NewMockRetentionService(ctrl *gomock.Controller) *MockRetentionService {
+	mock := &MockRetentionService{ctrl: ctrl}
+	mock.recorder = &MockRetentionServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRetentionService) EXPECT() *MockRetentionServiceMockRecorder {
+	return m.recorder
+}
+
+// GetAuditLog mocks base method.
+func (m *MockRetentionService) GetAuditLog() []*domain.AuditEntry {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAuditLog")
+	ret0, _ := ret[0].([]*domain.AuditEntry)
+	return ret0
+}
+
+// GetAuditLog indicates an expected call of GetAuditLog.
+func (mr *MockRetentionServiceMockRecorder) GetAuditLog() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAuditLog", reflect.TypeOf((*MockRetentionService)(nil).GetAuditLog))
+}
+
+// Purge mocks base method.
+func (m *MockRetentionService) Purge(purge *domain.Purge, actor string) (*domain.AuditEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Purge", purge, actor)
+	ret0, _ := ret[0].(*domain.AuditEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Purge indicates an expected call of Purge.
+func (mr *MockRetentionServiceMockRecorder) Purge(purge, actor interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Purge", reflect.TypeOf((*MockRetentionService)(nil).Purge), purge, actor)
+}
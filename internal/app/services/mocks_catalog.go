@@ -0,0 +1,118 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ../ports/catalog.go
+
+// Package services is a generated GoMock package.
+package services
+
+import (
+	reflect "reflect"
+	time "time"
+
+	gomock "github.com/golang/mock/gomock"
+	domain "go.dataflow.ru/service-sales/internal/app/domain"
+)
+
+// MockCatalog is a mock of Catalog interface.
+type MockCatalog struct {
+	ctrl     *gomock.Controller
+	recorder *MockCatalogMockRecorder
+}
+
+// MockCatalogMockRecorder is the mock recorder for MockCatalog.
+type MockCatalogMockRecorder struct {
+	mock *MockCatalog
+}
+
+// NewMockCatalog creates a new mock instance.
+func NewMockCatalog(ctrl *gomock.Controller) *MockCatalog {
+	mock := &MockCatalog{ctrl: ctrl}
+	mock.recorder = &MockCatalogMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockCatalog) EXPECT() *MockCatalogMockRecorder {
+	return m.recorder
+}
+
+// GetProduct mocks base method.
+func (m *MockCatalog) GetProduct(productID string) (*domain.Product, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetProduct", productID)
+	ret0, _ := ret[0].(*domain.Product)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetProduct indicates an expected call of GetProduct.
+func (mr *MockCatalogMockRecorder) GetProduct(productID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProduct", reflect.TypeOf((*MockCatalog)(nil).GetProduct), productID)
+}
+
+// GetStore mocks base method.
+func (m *MockCatalog) GetStore(storeID string) (*domain.Store, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetStore", storeID)
+	ret0, _ := ret[0].(*domain.Store)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetStore indicates an expected call of GetStore.
+func (mr *MockCatalogMockRecorder) GetStore(storeID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStore", reflect.TypeOf((*MockCatalog)(nil).GetStore), storeID)
+}
+
+// GetStores mocks base method.
+func (m *MockCatalog) GetStores() []*domain.Store {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetStores")
+	ret0, _ := ret[0].([]*domain.Store)
+	return ret0
+}
+
+// GetStores indicates an expected call of GetStores.
+func (mr *MockCatalogMockRecorder) GetStores() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStores", reflect.TypeOf((*MockCatalog)(nil).GetStores))
+}
+
+// MockStoreCalendar is a mock of StoreCalendar interface.
+type MockStoreCalendar struct {
+	ctrl     *gomock.Controller
+	recorder *MockStoreCalendarMockRecorder
+}
+
+// MockStoreCalendarMockRecorder is the mock recorder for MockStoreCalendar.
+type MockStoreCalendarMockRecorder struct {
+	mock *MockStoreCalendar
+}
+
+// NewMockStoreCalendar creates a new mock instance.
+func NewMockStoreCalendar(ctrl *gomock.Controller) *MockStoreCalendar {
+	mock := &MockStoreCalendar{ctrl: ctrl}
+	mock.recorder = &MockStoreCalendarMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockStoreCalendar) EXPECT() *MockStoreCalendarMockRecorder {
+	return m.recorder
+}
+
+// Location mocks base method.
+func (m *MockStoreCalendar) Location(storeID string) (*time.Location, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Location", storeID)
+	ret0, _ := ret[0].(*time.Location)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Location indicates an expected call of Location.
+func (mr *MockStoreCalendarMockRecorder) Location(storeID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Location", reflect.TypeOf((*MockStoreCalendar)(nil).Location), storeID)
+}
@@ -0,0 +1,157 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ../ports/catalog_storage.go
+
+// Package services is a generated GoMock package.
+package services
+
+import (
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	domain "go.dataflow.ru/service-sales/internal/app/domain"
+)
+
+// MockCatalogStorage is a mock of CatalogStorage interface.
+type MockCatalogStorage struct {
+	ctrl     *gomock.Controller
+	recorder *MockCatalogStorageMockRecorder
+}
+
+// MockCatalogStorageMockRecorder is the mock recorder for MockCatalogStorage.
+type MockCatalogStorageMockRecorder struct {
+	mock *MockCatalogStorage
+}
+
+// NewMockCatalogStorage creates a new mock instance.
+func NewMockCatalogStorage(ctrl *gomock.Controller) *MockCatalogStorage {
+	mock := &MockCatalogStorage{ctrl: ctrl}
+	mock.recorder = &MockCatalogStorageMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockCatalogStorage) EXPECT() *MockCatalogStorageMockRecorder {
+	return m.recorder
+}
+
+// DeleteProduct mocks base method.
+func (m *MockCatalogStorage) DeleteProduct(productID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteProduct", productID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteProduct indicates an expected call of DeleteProduct.
+func (mr *MockCatalogStorageMockRecorder) DeleteProduct(productID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteProduct", reflect.TypeOf((*MockCatalogStorage)(nil).DeleteProduct), productID)
+}
+
+// DeleteStore mocks base method.
+func (m *MockCatalogStorage) DeleteStore(storeID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteStore", storeID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteStore indicates an expected call of DeleteStore.
+func (mr *MockCatalogStorageMockRecorder) DeleteStore(storeID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteStore", reflect.TypeOf((*MockCatalogStorage)(nil).DeleteStore), storeID)
+}
+
+// GetProduct mocks base method.
+func (m *MockCatalogStorage) GetProduct(productID string) (*domain.Product, bool) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetProduct", productID)
+	ret0, _ := ret[0].(*domain.Product)
+	ret1, _ := ret[1].(bool)
+	return ret0, ret1
+}
+
+// GetProduct indicates an expected call of GetProduct.
+func (mr *MockCatalogStorageMockRecorder) GetProduct(productID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProduct", reflect.TypeOf((*MockCatalogStorage)(nil).GetProduct), productID)
+}
+
+// GetProducts mocks base method.
+func (m *MockCatalogStorage) GetProducts() []*domain.Product {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetProducts")
+	ret0, _ := ret[0].([]*domain.Product)
+	return ret0
+}
+
+// GetProducts indicates an expected call of GetProducts.
+func (mr *MockCatalogStorageMockRecorder) GetProducts() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProducts", reflect.TypeOf((*MockCatalogStorage)(nil).GetProducts))
+}
+
+// GetStore mocks base method.
+func (m *MockCatalogStorage) GetStore(storeID string) (*domain.Store, bool) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetStore", storeID)
+	ret0, _ := ret[0].(*domain.Store)
+	ret1, _ := ret[1].(bool)
+	return ret0, ret1
+}
+
+// GetStore indicates an expected call of GetStore.
+func (mr *MockCatalogStorageMockRecorder) GetStore(storeID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStore", reflect.TypeOf((*MockCatalogStorage)(nil).GetStore), storeID)
+}
+
+// GetStores mocks base method.
+func (m *MockCatalogStorage) GetStores() []*domain.Store {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetStores")
+	ret0, _ := ret[0].([]*domain.Store)
+	return ret0
+}
+
+// GetStores indicates an expected call of GetStores.
+func (mr *MockCatalogStorageMockRecorder) GetStores() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStores", reflect.TypeOf((*MockCatalogStorage)(nil).GetStores))
+}
+
+// SaveProducts mocks base method.
+func (m *MockCatalogStorage) SaveProducts(products ...*domain.Product) error {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{}
+	for _, a := range products {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "SaveProducts", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveProducts indicates an expected call of SaveProducts.
+func (mr *MockCatalogStorageMockRecorder) SaveProducts(products ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveProducts", reflect.TypeOf((*MockCatalogStorage)(nil).SaveProducts), products...)
+}
+
+// SaveStores mocks base method.
+func (m *MockCatalogStorage) SaveStores(stores ...*domain.Store) error {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{}
+	for _, a := range stores {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "SaveStores", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveStores indicates an expected call of SaveStores.
+func (mr *MockCatalogStorageMockRecorder) SaveStores(stores ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveStores", reflect.TypeOf((*MockCatalogStorage)(nil).SaveStores), stores...)
+}
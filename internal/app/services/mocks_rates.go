@@ -0,0 +1,65 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ../ports/exchange_rates.go
+
+// Package services is a generated GoMock package.
+package services
+
+import (
+	reflect "reflect"
+	time "time"
+
+	gomock "github.com/golang/mock/gomock"
+	decimal "github.com/shopspring/decimal"
+)
+
+// MockExchangeRates is a mock of ExchangeRates interface.
+type MockExchangeRates struct {
+	ctrl     *gomock.Controller
+	recorder *MockExchangeRatesMockRecorder
+}
+
+// MockExchangeRatesMockRecorder is the mock recorder for MockExchangeRates.
+type MockExchangeRatesMockRecorder struct {
+	mock *MockExchangeRates
+}
+
+// NewMockExchangeRates creates a new mock instance.
+func NewMockExchangeRates(ctrl *gomock.Controller) *MockExchangeRates {
+	mock := &MockExchangeRates{ctrl: ctrl}
+	mock.recorder = &MockExchangeRatesMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockExchangeRates) EXPECT() *MockExchangeRatesMockRecorder {
+	return m.recorder
+}
+
+// Changes mocks base method.
+func (m *MockExchangeRates) Changes(startDate, endDate time.Time) []time.Time {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Changes", startDate, endDate)
+	ret0, _ := ret[0].([]time.Time)
+	return ret0
+}
+
+// Changes indicates an expected call of Changes.
+func (mr *MockExchangeRatesMockRecorder) Changes(startDate, endDate interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Changes", reflect.TypeOf((*MockExchangeRates)(nil).Changes), startDate, endDate)
+}
+
+// Rate mocks base method.
+func (m *MockExchangeRates) Rate(from, to string, at time.Time) (decimal.Decimal, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Rate", from, to, at)
+	ret0, _ := ret[0].(decimal.Decimal)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Rate indicates an expected call of Rate.
+func (mr *MockExchangeRatesMockRecorder) Rate(from, to, at interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Rate", reflect.TypeOf((*MockExchangeRates)(nil).Rate), from, to, at)
+}
@@ -0,0 +1,354 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ../ports/report.go
+
+// Package services is a generated GoMock package.
+package services
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	domain "go.dataflow.ru/service-sales/internal/app/domain"
+)
+
+// MockReportStorage is a mock of ReportStorage interface.
+type MockReportStorage struct {
+	ctrl     *gomock.Controller
+	recorder *MockReportStorageMockRecorder
+}
+
+// MockReportStorageMockRecorder is the mock recorder for MockReportStorage.
+type MockReportStorageMockRecorder struct {
+	mock *MockReportStorage
+}
+
+// NewMockReportStorage creates a new mock instance.
+func NewMockReportStorage(ctrl *gomock.Controller) *MockReportStorage {
+	mock := &MockReportStorage{ctrl: ctrl}
+	mock.recorder = &MockReportStorageMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockReportStorage) EXPECT() *MockReportStorageMockRecorder {
+	return m.recorder
+}
+
+// DeleteReport mocks base method.
+func (m *MockReportStorage) DeleteReport(id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteReport", id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteReport indicates an expected call of DeleteReport.
+func (mr *MockReportStorageMockRecorder) DeleteReport(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteReport", reflect.TypeOf((*MockReportStorage)(nil).DeleteReport), id)
+}
+
+// GetReport mocks base method.
+func (m *MockReportStorage) GetReport(id string) (*domain.Report, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetReport", id)
+	ret0, _ := ret[0].(*domain.Report)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetReport indicates an expected call of GetReport.
+func (mr *MockReportStorageMockRecorder) GetReport(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReport", reflect.TypeOf((*MockReportStorage)(nil).GetReport), id)
+}
+
+// GetReports mocks base method.
+func (m *MockReportStorage) GetReports() []*domain.Report {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetReports")
+	ret0, _ := ret[0].([]*domain.Report)
+	return ret0
+}
+
+// GetReports indicates an expected call of GetReports.
+func (mr *MockReportStorageMockRecorder) GetReports() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReports", reflect.TypeOf((*MockReportStorage)(nil).GetReports))
+}
+
+// GetRunContent mocks base method.
+func (m *MockReportStorage) GetRunContent(reportID, runID string) (*domain.ReportRun, []byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRunContent", reportID, runID)
+	ret0, _ := ret[0].(*domain.ReportRun)
+	ret1, _ := ret[1].([]byte)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetRunContent indicates an expected call of GetRunContent.
+func (mr *MockReportStorageMockRecorder) GetRunContent(reportID, runID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRunContent", reflect.TypeOf((*MockReportStorage)(nil).GetRunContent), reportID, runID)
+}
+
+// GetRuns mocks base method.
+func (m *MockReportStorage) GetRuns(reportID string) []*domain.ReportRun {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRuns", reportID)
+	ret0, _ := ret[0].([]*domain.ReportRun)
+	return ret0
+}
+
+// GetRuns indicates an expected call of GetRuns.
+func (mr *MockReportStorageMockRecorder) GetRuns(reportID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRuns", reflect.TypeOf((*MockReportStorage)(nil).GetRuns), reportID)
+}
+
+// SaveReport mocks base method.
+func (m *MockReportStorage) SaveReport(report *domain.Report) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveReport", report)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveReport indicates an expected call of SaveReport.
+func (mr *MockReportStorageMockRecorder) SaveReport(report interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveReport", reflect.TypeOf((*MockReportStorage)(nil).SaveReport), report)
+}
+
+// SaveRun mocks base method.
+func (m *MockReportStorage) SaveRun(run *domain.ReportRun, content []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveRun", run, content)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveRun indicates an expected call of SaveRun.
+func (mr *MockReportStorageMockRecorder) SaveRun(run, content interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveRun", reflect.TypeOf((*MockReportStorage)(nil).SaveRun), run, content)
+}
+
+// MockReportRenderer is a mock of ReportRenderer interface.
+type MockReportRenderer struct {
+	ctrl     *gomock.Controller
+	recorder *MockReportRendererMockRecorder
+}
+
+// MockReportRendererMockRecorder is the mock recorder for MockReportRenderer.
+type MockReportRendererMockRecorder struct {
+	mock *MockReportRenderer
+}
+
+// NewMockReportRenderer creates a new mock instance.
+func NewMockReportRenderer(ctrl *gomock.Controller) *MockReportRenderer {
+	mock := &MockReportRenderer{ctrl: ctrl}
+	mock.recorder = &MockReportRendererMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockReportRenderer) EXPECT() *MockReportRendererMockRecorder {
+	return m.recorder
+}
+
+// Render mocks base method.
+func (m *MockReportRenderer) Render(report *domain.Report, run *domain.ReportRun, result *domain.QueryResult) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Render", report, run, result)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Render indicates an expected call of Render.
+func (mr *MockReportRendererMockRecorder) Render(report, run, result interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Render", reflect.TypeOf((*MockReportRenderer)(nil).Render), report, run, result)
+}
+
+// MockReportDelivery is a mock of ReportDelivery interface.
+type MockReportDelivery struct {
+	ctrl     *gomock.Controller
+	recorder *MockReportDeliveryMockRecorder
+}
+
+// MockReportDeliveryMockRecorder is the mock recorder for MockReportDelivery.
+type MockReportDeliveryMockRecorder struct {
+	mock *MockReportDelivery
+}
+
+// NewMockReportDelivery creates a new mock instance.
+func NewMockReportDelivery(ctrl *gomock.Controller) *MockReportDelivery {
+	mock := &MockReportDelivery{ctrl: ctrl}
+	mock.recorder = &MockReportDeliveryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockReportDelivery) EXPECT() *MockReportDeliveryMockRecorder {
+	return m.recorder
+}
+
+// DeliverReport mocks base method.
+func (m *MockReportDelivery) DeliverReport(ctx context.Context, report *domain.Report, run *domain.ReportRun, content []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeliverReport", ctx, report, run, content)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeliverReport indicates an expected call of DeliverReport.
+func (mr *MockReportDeliveryMockRecorder) DeliverReport(ctx, report, run, content interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeliverReport", reflect.TypeOf((*MockReportDelivery)(nil).DeliverReport), ctx, report, run, content)
+}
+
+// MockReportService is a mock of ReportService interface.
+type MockReportService struct {
+	ctrl     *gomock.Controller
+	recorder *MockReportServiceMockRecorder
+}
+
+// MockReportServiceMockRecorder is the mock recorder for MockReportService.
+type MockReportServiceMockRecorder struct {
+	mock *MockReportService
+}
+
+// NewMockReportService creates a new mock instance.
+func NewMockReportService(ctrl *gomock.Controller) *MockReportService {
+	mock := &MockReportService{ctrl: ctrl}
+	mock.recorder = &MockReportServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockReportService) EXPECT() *MockReportServiceMockRecorder {
+	return m.recorder
+}
+
+// CreateReport mocks base method.
+func (m *MockReportService) CreateReport(report *domain.Report) (*domain.Report, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateReport", report)
+	ret0, _ := ret[0].(*domain.Report)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateReport indicates an expected call of CreateReport.
+func (mr *MockReportServiceMockRecorder) CreateReport(report interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateReport", reflect.TypeOf((*MockReportService)(nil).CreateReport), report)
+}
+
+// DeleteReport mocks base method.
+func (m *MockReportService) DeleteReport(id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteReport", id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteReport indicates an expected call of DeleteReport.
+func (mr *MockReportServiceMockRecorder) DeleteReport(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteReport", reflect.TypeOf((*MockReportService)(nil).DeleteReport), id)
+}
+
+// GetReport mocks base method.
+func (m *MockReportService) GetReport(id string) (*domain.Report, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetReport", id)
+	ret0, _ := ret[0].(*domain.Report)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetReport indicates an expected call of GetReport.
+func (mr *MockReportServiceMockRecorder) GetReport(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReport", reflect.TypeOf((*MockReportService)(nil).GetReport), id)
+}
+
+// GetReports mocks base method.
+func (m *MockReportService) GetReports() []*domain.Report {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetReports")
+	ret0, _ := ret[0].([]*domain.Report)
+	return ret0
+}
+
+// GetReports indicates an expected call of GetReports.
+func (mr *MockReportServiceMockRecorder) GetReports() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReports", reflect.TypeOf((*MockReportService)(nil).GetReports))
+}
+
+// GetRunContent mocks base method.
+func (m *MockReportService) GetRunContent(reportID, runID string) (*domain.ReportRun, []byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRunContent", reportID, runID)
+	ret0, _ := ret[0].(*domain.ReportRun)
+	ret1, _ := ret[1].([]byte)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetRunContent indicates an expected call of GetRunContent.
+func (mr *MockReportServiceMockRecorder) GetRunContent(reportID, runID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRunContent", reflect.TypeOf((*MockReportService)(nil).GetRunContent), reportID, runID)
+}
+
+// GetRuns mocks base method.
+func (m *MockReportService) GetRuns(reportID string) ([]*domain.ReportRun, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRuns", reportID)
+	ret0, _ := ret[0].([]*domain.ReportRun)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRuns indicates an expected call of GetRuns.
+func (mr *MockReportServiceMockRecorder) GetRuns(reportID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRuns", reflect.TypeOf((*MockReportService)(nil).GetRuns), reportID)
+}
+
+// RunReport mocks base method.
+func (m *MockReportService) RunReport(ctx context.Context, id string) (*domain.ReportRun, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RunReport", ctx, id)
+	ret0, _ := ret[0].(*domain.ReportRun)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RunReport indicates an expected call of RunReport.
+func (mr *MockReportServiceMockRecorder) RunReport(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RunReport", reflect.TypeOf((*MockReportService)(nil).RunReport), ctx, id)
+}
+
+// UpdateReport mocks base method.
+func (m *MockReportService) UpdateReport(report *domain.Report) (*domain.Report, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateReport", report)
+	ret0, _ := ret[0].(*domain.Report)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateReport indicates an expected call of UpdateReport.
+func (mr *MockReportServiceMockRecorder) UpdateReport(report interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateReport", reflect.TypeOf((*MockReportService)(nil).UpdateReport), report)
+}
@@ -0,0 +1,318 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ../ports/sales_service.go
+
+// Package services is a generated GoMock package.
+package services
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	gomock "github.com/golang/mock/gomock"
+	domain "go.dataflow.ru/service-sales/internal/app/domain"
+	ports "go.dataflow.ru/service-sales/internal/app/ports"
+)
+
+// MockSalesService is a mock of SalesService interface.
+type MockSalesService struct {
+	ctrl     *gomock.Controller
+	recorder *MockSalesServiceMockRecorder
+}
+
+// MockSalesServiceMockRecorder is the mock recorder for MockSalesService.
+type MockSalesServiceMockRecorder struct {
+	mock *MockSalesService
+}
+
+// NewMockSalesService creates a new mock instance.
+func NewMockSalesService(ctrl *gomock.Controller) *MockSalesService {
+	mock := &MockSalesService{ctrl: ctrl}
+	mock.recorder = &MockSalesServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSalesService) EXPECT() *MockSalesServiceMockRecorder {
+	return m.recorder
+}
+
+// AddAcceptedSale mocks base method.
+func (m *MockSalesService) AddAcceptedSale(sale *domain.Sale) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddAcceptedSale", sale)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddAcceptedSale indicates an expected call of AddAcceptedSale.
+func (mr *MockSalesServiceMockRecorder) AddAcceptedSale(sale interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddAcceptedSale", reflect.TypeOf((*MockSalesService)(nil).AddAcceptedSale), sale)
+}
+
+// AddReceipt mocks base method.
+func (m *MockSalesService) AddReceipt(receipt *domain.Receipt) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddReceipt", receipt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddReceipt indicates an expected call of AddReceipt.
+func (mr *MockSalesServiceMockRecorder) AddReceipt(receipt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddReceipt", reflect.TypeOf((*MockSalesService)(nil).AddReceipt), receipt)
+}
+
+// AddSale mocks base method.
+func (m *MockSalesService) AddSale(sale *domain.Sale) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddSale", sale)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddSale indicates an expected call of AddSale.
+func (mr *MockSalesServiceMockRecorder) AddSale(sale interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddSale", reflect.TypeOf((*MockSalesService)(nil).AddSale), sale)
+}
+
+// CloseSnapshot mocks base method.
+func (m *MockSalesService) CloseSnapshot(token string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CloseSnapshot", token)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CloseSnapshot indicates an expected call of CloseSnapshot.
+func (mr *MockSalesServiceMockRecorder) CloseSnapshot(token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CloseSnapshot", reflect.TypeOf((*MockSalesService)(nil).CloseSnapshot), token)
+}
+
+// ComparePeriods mocks base method.
+func (m *MockSalesService) ComparePeriods(storeIDs []string, filter map[string]string, period domain.Period, offset string) ([]domain.PeriodComparison, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ComparePeriods", storeIDs, filter, period, offset)
+	ret0, _ := ret[0].([]domain.PeriodComparison)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ComparePeriods indicates an expected call of ComparePeriods.
+func (mr *MockSalesServiceMockRecorder) ComparePeriods(storeIDs, filter, period, offset interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ComparePeriods", reflect.TypeOf((*MockSalesService)(nil).ComparePeriods), storeIDs, filter, period, offset)
+}
+
+// GetCategoryTotals mocks base method.
+func (m *MockSalesService) GetCategoryTotals(storeID string, period domain.Period) (map[string]domain.Totals, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCategoryTotals", storeID, period)
+	ret0, _ := ret[0].(map[string]domain.Totals)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCategoryTotals indicates an expected call of GetCategoryTotals.
+func (mr *MockSalesServiceMockRecorder) GetCategoryTotals(storeID, period interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCategoryTotals", reflect.TypeOf((*MockSalesService)(nil).GetCategoryTotals), storeID, period)
+}
+
+// GetConvertedTotalSum mocks base method.
+func (m *MockSalesService) GetConvertedTotalSum(storeID string, startDate, endDate time.Time, currency string) (domain.Amounts, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetConvertedTotalSum", storeID, startDate, endDate, currency)
+	ret0, _ := ret[0].(domain.Amounts)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetConvertedTotalSum indicates an expected call of GetConvertedTotalSum.
+func (mr *MockSalesServiceMockRecorder) GetConvertedTotalSum(storeID, startDate, endDate, currency interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetConvertedTotalSum", reflect.TypeOf((*MockSalesService)(nil).GetConvertedTotalSum), storeID, startDate, endDate, currency)
+}
+
+// GetDailyTotals mocks base method.
+func (m *MockSalesService) GetDailyTotals(storeID string, startDay, endDay domain.Date) ([]domain.DailyTotals, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDailyTotals", storeID, startDay, endDay)
+	ret0, _ := ret[0].([]domain.DailyTotals)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDailyTotals indicates an expected call of GetDailyTotals.
+func (mr *MockSalesServiceMockRecorder) GetDailyTotals(storeID, startDay, endDay interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDailyTotals", reflect.TypeOf((*MockSalesService)(nil).GetDailyTotals), storeID, startDay, endDay)
+}
+
+// GetDimensionTotals mocks base method.
+func (m *MockSalesService) GetDimensionTotals(storeID, dimension string, period domain.Period) (map[string]domain.Totals, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDimensionTotals", storeID, dimension, period)
+	ret0, _ := ret[0].(map[string]domain.Totals)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDimensionTotals indicates an expected call of GetDimensionTotals.
+func (mr *MockSalesServiceMockRecorder) GetDimensionTotals(storeID, dimension, period interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDimensionTotals", reflect.TypeOf((*MockSalesService)(nil).GetDimensionTotals), storeID, dimension, period)
+}
+
+// GetReceiptTotals mocks base method.
+func (m *MockSalesService) GetReceiptTotals(storeID string, period domain.Period) (domain.ReceiptTotals, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetReceiptTotals", storeID, period)
+	ret0, _ := ret[0].(domain.ReceiptTotals)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetReceiptTotals indicates an expected call of GetReceiptTotals.
+func (mr *MockSalesServiceMockRecorder) GetReceiptTotals(storeID, period interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReceiptTotals", reflect.TypeOf((*MockSalesService)(nil).GetReceiptTotals), storeID, period)
+}
+
+// GetSales mocks base method.
+func (m *MockSalesService) GetSales() ([]*domain.Sale, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSales")
+	ret0, _ := ret[0].([]*domain.Sale)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSales indicates an expected call of GetSales.
+func (mr *MockSalesServiceMockRecorder) GetSales() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSales", reflect.TypeOf((*MockSalesService)(nil).GetSales))
+}
+
+// GetSalesSketches mocks base method.
+func (m *MockSalesService) GetSalesSketches(storeIDs []string, filter map[string]string, period domain.Period, bucket string) (*domain.SketchSeries, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSalesSketches", storeIDs, filter, period, bucket)
+	ret0, _ := ret[0].(*domain.SketchSeries)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSalesSketches indicates an expected call of GetSalesSketches.
+func (mr *MockSalesServiceMockRecorder) GetSalesSketches(storeIDs, filter, period, bucket interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSalesSketches", reflect.TypeOf((*MockSalesService)(nil).GetSalesSketches), storeIDs, filter, period, bucket)
+}
+
+// GetStoreGroupTotals mocks base method.
+func (m *MockSalesService) GetStoreGroupTotals(filter map[string]string, groupBy string, period domain.Period) (map[string]domain.Totals, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetStoreGroupTotals", filter, groupBy, period)
+	ret0, _ := ret[0].(map[string]domain.Totals)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetStoreGroupTotals indicates an expected call of GetStoreGroupTotals.
+func (mr *MockSalesServiceMockRecorder) GetStoreGroupTotals(filter, groupBy, period interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStoreGroupTotals", reflect.TypeOf((*MockSalesService)(nil).GetStoreGroupTotals), filter, groupBy, period)
+}
+
+// GetTotalSum mocks base method.
+func (m *MockSalesService) GetTotalSum(storeID string, startDate, endDate time.Time) (domain.Totals, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTotalSum", storeID, startDate, endDate)
+	ret0, _ := ret[0].(domain.Totals)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTotalSum indicates an expected call of GetTotalSum.
+func (mr *MockSalesServiceMockRecorder) GetTotalSum(storeID, startDate, endDate interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTotalSum", reflect.TypeOf((*MockSalesService)(nil).GetTotalSum), storeID, startDate, endDate)
+}
+
+// OpenSnapshot mocks base method.
+func (m *MockSalesService) OpenSnapshot() (domain.Snapshot, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "OpenSnapshot")
+	ret0, _ := ret[0].(domain.Snapshot)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// OpenSnapshot indicates an expected call of OpenSnapshot.
+func (mr *MockSalesServiceMockRecorder) OpenSnapshot() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenSnapshot", reflect.TypeOf((*MockSalesService)(nil).OpenSnapshot))
+}
+
+// Query mocks base method.
+func (m *MockSalesService) Query(q domain.Query) (*domain.QueryResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Query", q)
+	ret0, _ := ret[0].(*domain.QueryResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Query indicates an expected call of Query.
+func (mr *MockSalesServiceMockRecorder) Query(q interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Query", reflect.TypeOf((*MockSalesService)(nil).Query), q)
+}
+
+// ScanSales mocks base method.
+func (m *MockSalesService) ScanSales(ctx context.Context, fn func(*domain.Sale) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ScanSales", ctx, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ScanSales indicates an expected call of ScanSales.
+func (mr *MockSalesServiceMockRecorder) ScanSales(ctx, fn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ScanSales", reflect.TypeOf((*MockSalesService)(nil).ScanSales), ctx, fn)
+}
+
+// StoreLocation mocks base method.
+func (m *MockSalesService) StoreLocation(storeID string) (*time.Location, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StoreLocation", storeID)
+	ret0, _ := ret[0].(*time.Location)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// StoreLocation indicates an expected call of StoreLocation.
+func (mr *MockSalesServiceMockRecorder) StoreLocation(storeID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StoreLocation", reflect.TypeOf((*MockSalesService)(nil).StoreLocation), storeID)
+}
+
+// WithSnapshot mocks base method.
+func (m *MockSalesService) WithSnapshot(token string) (ports.SalesService, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WithSnapshot", token)
+	ret0, _ := ret[0].(ports.SalesService)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WithSnapshot indicates an expected call of WithSnapshot.
+func (mr *MockSalesServiceMockRecorder) WithSnapshot(token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WithSnapshot", reflect.TypeOf((*MockSalesService)(nil).WithSnapshot), token)
+}
@@ -0,0 +1,81 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ../ports/target_storage.go
+
+// Package services is a generated GoMock package.
+package services
+
+import (
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	domain "go.dataflow.ru/service-sales/internal/app/domain"
+)
+
+// MockTargetStorage is a mock of TargetStorage interface.
+type MockTargetStorage struct {
+	ctrl     *gomock.Controller
+	recorder *MockTargetStorageMockRecorder
+}
+
+// MockTargetStorageMockRecorder is the mock recorder for MockTargetStorage.
+type MockTargetStorageMockRecorder struct {
+	mock *MockTargetStorage
+}
+
+// NewMockTargetStorage creates a new mock instance.
+func NewMockTargetStorage(ctrl *gomock.Controller) *MockTargetStorage {
+	mock := &MockTargetStorage{ctrl: ctrl}
+	mock.recorder = &MockTargetStorageMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTargetStorage) EXPECT() *MockTargetStorageMockRecorder {
+	return m.recorder
+}
+
+// DeleteTarget mocks base method.
+func (m *MockTargetStorage) DeleteTarget(storeID string, start domain.Date) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteTarget", storeID, start)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteTarget indicates an expected call of DeleteTarget.
+func (mr *MockTargetStorageMockRecorder) DeleteTarget(storeID, start interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteTarget", reflect.TypeOf((*MockTargetStorage)(nil).DeleteTarget), storeID, start)
+}
+
+// GetTargets mocks base method.
+func (m *MockTargetStorage) GetTargets(storeID string) []*domain.Target {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTargets", storeID)
+	ret0, _ := ret[0].([]*domain.Target)
+	return ret0
+}
+
+// GetTargets indicates an expected call of GetTargets.
+func (mr *MockTargetStorageMockRecorder) GetTargets(storeID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTargets", reflect.TypeOf((*MockTargetStorage)(nil).GetTargets), storeID)
+}
+
+// SaveTargets mocks base method.
+func (m *MockTargetStorage) SaveTargets(targets ...*domain.Target) error {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{}
+	for _, a := range targets {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "SaveTargets", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveTargets indicates an expected call of SaveTargets.
+func (mr *MockTargetStorageMockRecorder) SaveTargets(targets ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveTargets", reflect.TypeOf((*MockTargetStorage)(nil).SaveTargets), targets...)
+}
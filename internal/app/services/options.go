@@ -0,0 +1,69 @@
+package services
+
+import (
+	"go.dataflow.ru/service-sales/internal/app/ports"
+)
+
+type Option func(s *SalesService)
+
+// WithDefaultCurrency задает валюту для продаж, поступивших без указания валюты.
+func WithDefaultCurrency(currency string) Option {
+	return func(s *SalesService) {
+		s.defaultCurrency = currency
+	}
+}
+
+// WithExchangeRates задает таблицу курсов для пересчета сумм продаж в другую валюту.
+func WithExchangeRates(rates ports.ExchangeRates) Option {
+	return func(s *SalesService) {
+		s.rates = rates
+	}
+}
+
+// WithStoreCalendar задает источник часовых поясов магазинов. Без него все магазины считаются находящимися в UTC.
+func WithStoreCalendar(calendar ports.StoreCalendar) Option {
+	return func(s *SalesService) {
+		s.calendar = calendar
+	}
+}
+
+// WithCatalog задает справочник магазинов и товаров для агрегации продаж по категориям товаров и атрибутам магазинов.
+func WithCatalog(catalog ports.Catalog) Option {
+	return func(s *SalesService) {
+		s.catalog = catalog
+	}
+}
+
+// WithCatalogValidation включает проверку, что магазин и товар продажи есть в справочнике.
+func WithCatalogValidation() Option {
+	return func(s *SalesService) {
+		s.validateCatalog = true
+	}
+}
+
+// WithRules задает бизнес-правила, которыми проверяются продажи после проверки полей продажи.
+func WithRules(rules ports.RuleEngine) Option {
+	return func(s *SalesService) {
+		s.rules = rules
+	}
+}
+
+// WithAnomalyDetection включает проверку продаж детектором detector: продажи с оценкой не ниже threshold
+// помещаются в очередь проверки queue и сохраняются сразу (domain.AnomalyActionFlag) или только
+// после подтверждения (domain.AnomalyActionQuarantine).
+func WithAnomalyDetection(detector ports.AnomalyDetector, queue ports.AnomalyQueue, threshold float64, action string) Option {
+	return func(s *SalesService) {
+		s.detector = detector
+		s.anomalies = queue
+		s.anomalyThreshold = threshold
+		s.anomalyAction = action
+	}
+}
+
+// WithSalesObserver задает получателя сохраненных продаж, например проверку оповещений (AlertService).
+// Получатель вызывается после успешного сохранения продажи или чека.
+func WithSalesObserver(observer ports.SalesObserver) Option {
+	return func(s *SalesService) {
+		s.observer = observer
+	}
+}
@@ -0,0 +1,210 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+	"go.dataflow.ru/service-sales/internal/app/ports"
+	"go.dataflow.ru/service-sales/pkg/logger"
+)
+
+// ReferenceService справочные данные экземпляра: справочник магазинов и товаров и планы.
+//
+// Справочные данные изменяются на одном экземпляре - источнике. Остальные экземпляры периодически читают
+// справочные данные источника и заменяют ими свою копию, поэтому запросы к ним учитывают часовые пояса,
+// атрибуты магазинов, категории товаров и планы источника.
+type ReferenceService struct {
+	catalog ports.CatalogStorage
+	targets ports.TargetStorage
+	logger  *logger.Logger
+
+	source    ports.ReferenceSource // справочные данные источника, nil - экземпляр источник
+	sourceURL string
+}
+
+type ReferenceOption func(s *ReferenceService)
+
+// FollowReference делает экземпляр копией: справочные данные читаются из source экземпляра с адресом url.
+func FollowReference(source ports.ReferenceSource, url string) ReferenceOption {
+	return func(s *ReferenceService) {
+		s.source = source
+		s.sourceURL = url
+	}
+}
+
+func NewReferenceService(catalog ports.CatalogStorage, targets ports.TargetStorage, logger *logger.Logger, opts ...ReferenceOption) *ReferenceService {
+	s := &ReferenceService{
+		catalog: catalog,
+		targets: targets,
+		logger:  logger,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Reference возвращает справочник магазинов и товаров и планы экземпляра.
+func (s *ReferenceService) Reference() (domain.ReferenceData, error) {
+	return domain.ReferenceData{
+		Stores:   s.catalog.GetStores(),
+		Products: s.catalog.GetProducts(),
+		Targets:  s.targets.GetTargets(""),
+	}, nil
+}
+
+// Run синхронизирует справочные данные с источником каждые interval, пока не отменен ctx.
+func (s *ReferenceService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Sync(); err != nil {
+			s.logger.Errorf("cant sync reference data with %s: %v", s.sourceURL, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sync заменяет справочные данные экземпляра справочными данными источника. Сохраняются только изменившиеся
+// магазины, товары и планы, поэтому без изменений на источнике файлы справочных данных не перезаписываются.
+func (s *ReferenceService) Sync() error {
+	if s.source == nil {
+		return nil
+	}
+
+	ref, err := s.source.Reference()
+	if err != nil {
+		return err
+	}
+
+	if err := s.syncStores(ref.Stores); err != nil {
+		return fmt.Errorf("sync stores: %w", err)
+	}
+
+	if err := s.syncProducts(ref.Products); err != nil {
+		return fmt.Errorf("sync products: %w", err)
+	}
+
+	if err := s.syncTargets(ref.Targets); err != nil {
+		return fmt.Errorf("sync targets: %w", err)
+	}
+
+	return nil
+}
+
+func (s *ReferenceService) syncStores(stores []*domain.Store) error {
+	source := make(map[string]bool, len(stores))
+	changed := make([]*domain.Store, 0)
+
+	for _, store := range stores {
+		source[store.ID] = true
+
+		if local, ok := s.catalog.GetStore(store.ID); !ok || !sameJSON(local, store) {
+			changed = append(changed, store)
+		}
+	}
+
+	for _, store := range s.catalog.GetStores() {
+		if source[store.ID] {
+			continue
+		}
+
+		if err := s.catalog.DeleteStore(store.ID); err != nil && !errors.Is(err, domain.ErrStoreNotFound) {
+			return err
+		}
+	}
+
+	if len(changed) == 0 {
+		return nil
+	}
+
+	return s.catalog.SaveStores(changed...)
+}
+
+func (s *ReferenceService) syncProducts(products []*domain.Product) error {
+	source := make(map[string]bool, len(products))
+	changed := make([]*domain.Product, 0)
+
+	for _, product := range products {
+		source[product.ID] = true
+
+		if local, ok := s.catalog.GetProduct(product.ID); !ok || !sameJSON(local, product) {
+			changed = append(changed, product)
+		}
+	}
+
+	for _, product := range s.catalog.GetProducts() {
+		if source[product.ID] {
+			continue
+		}
+
+		if err := s.catalog.DeleteProduct(product.ID); err != nil && !errors.Is(err, domain.ErrProductNotFound) {
+			return err
+		}
+	}
+
+	if len(changed) == 0 {
+		return nil
+	}
+
+	return s.catalog.SaveProducts(changed...)
+}
+
+// targetKey идентификатор плана: магазин и дата начала.
+type targetKey struct {
+	storeID string
+	start   domain.Date
+}
+
+func (s *ReferenceService) syncTargets(targets []*domain.Target) error {
+	local := make(map[targetKey]*domain.Target)
+	for _, target := range s.targets.GetTargets("") {
+		local[targetKey{storeID: target.StoreID, start: target.Start}] = target
+	}
+
+	changed := make([]*domain.Target, 0)
+
+	for _, target := range targets {
+		key := targetKey{storeID: target.StoreID, start: target.Start}
+		if prev, ok := local[key]; !ok || !sameJSON(prev, target) {
+			changed = append(changed, target)
+		}
+
+		delete(local, key)
+	}
+
+	// планы, которых нет на источнике, удаляются до сохранения: их периоды могут пересекаться с новыми планами
+	for key := range local {
+		if err := s.targets.DeleteTarget(key.storeID, key.start); err != nil && !errors.Is(err, domain.ErrTargetNotFound) {
+			return err
+		}
+	}
+
+	if len(changed) == 0 {
+		return nil
+	}
+
+	return s.targets.SaveTargets(changed...)
+}
+
+// sameJSON сообщает, что значения одинаково представлены в JSON. Справочные данные приходят от источника
+// в JSON, поэтому, например, суммы планов сравниваются без учета представления decimal в памяти.
+func sameJSON(a, b interface{}) bool {
+	x, errX := json.Marshal(a)
+	y, errY := json.Marshal(b)
+
+	return errX == nil && errY == nil && bytes.Equal(x, y)
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.dataflow.ru/service-sales/pkg/logger"
+
+	"go.dataflow.ru/service-sales/internal/adapters/catalog"
+	"go.dataflow.ru/service-sales/internal/adapters/targets"
+	"go.dataflow.ru/service-sales/internal/app/domain"
+)
+
+func TestReferenceService_Sync(t *testing.T) {
+	t.Parallel()
+
+	catalogRepo, err := catalog.New("")
+	require.NoError(t, err)
+
+	targetRepo, err := targets.New("")
+	require.NoError(t, err)
+
+	april := domain.Date{Year: 2024, Month: 4, Day: 1}
+	may := domain.Date{Year: 2024, Month: 5, Day: 1}
+
+	// копия до синхронизации: магазин и план, которых на источнике уже нет
+	require.NoError(t, catalogRepo.SaveStores(&domain.Store{ID: "store_1"}, &domain.Store{ID: "store_2"}))
+	require.NoError(t, catalogRepo.SaveProducts(&domain.Product{ID: "product_1", Category: "bread"}))
+	require.NoError(t, targetRepo.SaveTargets(
+		&domain.Target{StoreID: "store_1", Start: april, End: april.AddDays(29), Amount: decimal.NewFromInt(100), Currency: "RUB"},
+		&domain.Target{StoreID: "store_1", Start: may, End: may.AddDays(30), Amount: decimal.NewFromInt(100), Currency: "RUB"},
+	))
+
+	ctrl := gomock.NewController(t)
+	source := NewMockReferenceSource(ctrl)
+
+	// справочные данные источника приходят в JSON: сумма 100.00 равна сохраненной сумме 100
+	var ref domain.ReferenceData
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"stores": [{"store_id": "store_1", "time_zone": "Europe/Moscow"}, {"store_id": "store_3"}],
+		"products": [{"product_id": "product_1", "category": "bread"}],
+		"targets": [{"store_id": "store_1", "start_date": "2024-05-01", "end_date": "2024-05-31", "amount": "100.00", "currency": "RUB"}]
+	}`), &ref))
+
+	gomock.InOrder(
+		source.EXPECT().Reference().Return(ref, nil).Times(2),
+		source.EXPECT().Reference().Return(domain.ReferenceData{}, errors.New("connection refused")),
+	)
+
+	s := NewReferenceService(catalogRepo, targetRepo, logger.NoOpLogger(), FollowReference(source, "http://leader:8005"))
+
+	require.NoError(t, s.Sync())
+
+	local, err := s.Reference()
+	require.NoError(t, err)
+	assert.Equal(t, ref.Stores, local.Stores)
+	assert.Equal(t, ref.Products, local.Products)
+	require.Len(t, local.Targets, 1)
+	assert.True(t, sameJSON(ref.Targets[0], local.Targets[0]))
+
+	// без изменений на источнике копия не перезаписывается
+	require.NoError(t, s.Sync())
+
+	synced, err := s.Reference()
+	require.NoError(t, err)
+	assert.Same(t, local.Targets[0], synced.Targets[0])
+
+	assert.EqualError(t, s.Sync(), "connection refused")
+
+	// источник ничего не синхронизирует
+	assert.NoError(t, NewReferenceService(catalogRepo, targetRepo, logger.NoOpLogger()).Sync())
+}
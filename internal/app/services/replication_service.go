@@ -0,0 +1,371 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+	"go.dataflow.ru/service-sales/internal/app/ports"
+	"go.dataflow.ru/service-sales/pkg/logger"
+)
+
+const defaultReplicationBatchSize = 1000
+
+// ReplicationService репликация продаж между экземплярами сервиса.
+//
+// Ведущий экземпляр принимает продажи и отдает журнал продаж своего хранилища. Ведомый не принимает продажи,
+// а периодически запрашивает у ведущего версии магазинов и дочитывает продажи, которых у него еще нет,
+// применяя их к своему хранилищу в том же порядке. Поэтому состояние каждого магазина на ведомом - это
+// состояние магазина на ведущем в одной из прошлых версий.
+//
+// Удаление продаж на ведущем начинает журнал магазина заново в новом поколении. Ведомый, увидев новое поколение,
+// удаляет свою копию магазина и перечитывает журнал с начала. Продажи ведущего хранятся в памяти, поэтому
+// после его перезапуска журналы всех магазинов начинаются заново: ведущий отдает эпоху, случайную при каждом
+// запуске, и ведомый, увидев новую эпоху, удаляет копии всех магазинов.
+type ReplicationService struct {
+	storage ports.ReplicaStorage
+	logger  *logger.Logger
+
+	epoch string // эпоха экземпляра как ведущего
+
+	leader    ports.ReplicationLog // журнал ведущего, nil - экземпляр ведущий
+	leaderURL string
+	batchSize int
+
+	syncMu      sync.Mutex     // синхронизации выполняются последовательно
+	leaderEpoch string         // эпоха ведущего, продажи которой применены к хранилищу
+	generations map[string]int // поколения журналов ведущего, продажи которых применены к магазинам хранилища
+
+	status domain.ReplicationStatus // без versions, которые читаются из хранилища
+	mu     sync.Mutex
+
+	now func() time.Time
+}
+
+type ReplicationOption func(s *ReplicationService)
+
+// FollowLeader делает экземпляр ведомым: продажи читаются из журнала leader ведущего экземпляра с адресом url.
+func FollowLeader(leader ports.ReplicationLog, url string) ReplicationOption {
+	return func(s *ReplicationService) {
+		s.leader = leader
+		s.leaderURL = url
+	}
+}
+
+// WithReplicationBatchSize задает количество продаж, запрашиваемых у ведущего за один запрос.
+func WithReplicationBatchSize(n int) ReplicationOption {
+	return func(s *ReplicationService) {
+		if n > 0 {
+			s.batchSize = n
+		}
+	}
+}
+
+func NewReplicationService(storage ports.ReplicaStorage, logger *logger.Logger, opts ...ReplicationOption) *ReplicationService {
+	s := &ReplicationService{
+		storage:     storage,
+		logger:      logger,
+		epoch:       newEpoch(),
+		batchSize:   defaultReplicationBatchSize,
+		generations: make(map[string]int),
+		now:         time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.status.Role = domain.RoleLeader
+	if s.leader != nil {
+		s.status.Role = domain.RoleFollower
+		s.status.Leader = s.leaderURL
+	}
+
+	return s
+}
+
+// IsFollower возвращает true для ведомого экземпляра.
+func (s *ReplicationService) IsFollower() bool {
+	return s.leader != nil
+}
+
+// LeaderURL возвращает адрес ведущего экземпляра (пустой для ведущего).
+func (s *ReplicationService) LeaderURL() string {
+	return s.leaderURL
+}
+
+// Versions возвращает эпоху экземпляра и версии журналов магазинов в его хранилище.
+func (s *ReplicationService) Versions() (domain.LogVersions, error) {
+	versions, err := s.storage.Versions()
+	if err != nil {
+		return domain.LogVersions{}, err
+	}
+
+	return domain.LogVersions{Epoch: s.epoch, Stores: versions}, nil
+}
+
+// SalesSince возвращает не более limit продаж магазина из журнала экземпляра эпохи epoch и поколения generation,
+// начиная с версии version.
+func (s *ReplicationService) SalesSince(epoch, storeID string, generation, version, limit int) ([]*domain.Sale, error) {
+	if epoch != s.epoch {
+		return nil, fmt.Errorf("epoch %s, requested %s: %w", s.epoch, epoch, domain.ErrLogGenerationChanged)
+	}
+
+	return s.storage.SalesSince(storeID, generation, version, limit)
+}
+
+// Status возвращает состояние репликации экземпляра.
+func (s *ReplicationService) Status() (domain.ReplicationStatus, error) {
+	versions, err := s.storage.Versions()
+	if err != nil {
+		return domain.ReplicationStatus{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := s.status
+	status.Versions = make(map[string]int, len(versions))
+
+	for storeID, version := range versions {
+		status.Versions[storeID] = version.Version
+	}
+
+	return status, nil
+}
+
+// Run синхронизирует ведомый экземпляр с ведущим каждые interval, пока не отменен ctx.
+func (s *ReplicationService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Sync(); err != nil {
+			s.logger.Errorf("cant sync with leader %s: %v", s.leaderURL, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sync применяет к хранилищу продажи, принятые ведущим с прошлой синхронизации.
+func (s *ReplicationService) Sync() error {
+	if s.leader == nil {
+		return nil
+	}
+
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+
+	err := s.sync()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err != nil {
+		s.status.Error = err.Error()
+		return err
+	}
+
+	s.status.Error = ""
+	s.status.LastSync = s.now()
+
+	return nil
+}
+
+func (s *ReplicationService) sync() error {
+	leader, err := s.leader.Versions()
+	if err != nil {
+		return err
+	}
+
+	versions, err := s.storage.Versions()
+	if err != nil {
+		return err
+	}
+
+	local := make(map[string]int, len(versions))
+	for storeID, version := range versions {
+		local[storeID] = version.Version
+	}
+
+	if leader.Epoch != s.leaderEpoch {
+		// ведущий перезапущен: все магазины строятся заново из журналов новой эпохи
+		if err = s.dropStores(local); err != nil {
+			return err
+		}
+
+		if s.leaderEpoch != "" {
+			s.logger.Infof("leader restarted in epoch %s, resyncing all stores", leader.Epoch)
+		}
+
+		s.leaderEpoch = leader.Epoch
+		s.generations = make(map[string]int)
+	}
+
+	storeIDs := make([]string, 0, len(leader.Stores))
+	for storeID := range leader.Stores {
+		storeIDs = append(storeIDs, storeID)
+	}
+
+	sort.Strings(storeIDs)
+
+	for _, storeID := range storeIDs {
+		generation := leader.Stores[storeID].Generation
+		if applied, ok := s.generations[storeID]; ok && applied != generation {
+			// продажи магазина на ведущем удалены, копия магазина строится заново из журнала нового поколения
+			if _, err := s.storage.Purge(domain.Purge{StoreID: storeID}); err != nil {
+				return fmt.Errorf("drop store %s of generation %d: %w", storeID, applied, err)
+			}
+
+			s.logger.Infof("store %s log restarted by leader in generation %d, resyncing", storeID, generation)
+
+			local[storeID] = 0
+		}
+
+		s.generations[storeID] = generation
+	}
+
+	s.setLag(leader.Stores, local)
+
+	for _, storeID := range storeIDs {
+		if err := s.syncStore(storeID, leader, local); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// dropStores удаляет копии магазинов хранилища с версиями local и обнуляет их версии.
+func (s *ReplicationService) dropStores(local map[string]int) error {
+	for storeID, version := range local {
+		if version == 0 {
+			continue
+		}
+
+		if _, err := s.storage.Purge(domain.Purge{StoreID: storeID}); err != nil {
+			return fmt.Errorf("drop store %s: %w", storeID, err)
+		}
+
+		local[storeID] = 0
+	}
+
+	return nil
+}
+
+// syncStore применяет к магазину продажи журнала ведущего до версии leader. Если журнал магазина начат заново
+// во время чтения, магазин перечитывается при следующей синхронизации.
+//
+// Пачка продаж применяется атомарно. Строки чека занимают соседние версии журнала, поэтому строки чека в конце
+// пачки, продолжение которого может быть в следующей пачке, откладываются до нее: чтение на ведомом, как и
+// на ведущем, не видит чек частично.
+func (s *ReplicationService) syncStore(storeID string, leader domain.LogVersions, local map[string]int) error {
+	generation, version := leader.Stores[storeID].Generation, leader.Stores[storeID].Version
+
+	if local[storeID] > version {
+		// продажи ведущего хранятся в памяти: после его перезапуска журнал начинается заново
+		return fmt.Errorf("store %s is ahead of leader: version %d, leader %d", storeID, local[storeID], version)
+	}
+
+	batchSize := s.batchSize
+
+	for local[storeID] < version {
+		limit := version - local[storeID]
+		if limit > batchSize {
+			limit = batchSize
+		}
+
+		sales, err := s.leader.SalesSince(leader.Epoch, storeID, generation, local[storeID], limit)
+		if errors.Is(err, domain.ErrLogGenerationChanged) {
+			return nil
+		}
+
+		if err != nil {
+			return fmt.Errorf("read log of store %s: %w", storeID, err)
+		}
+
+		if len(sales) == 0 {
+			return fmt.Errorf("log of store %s ends at version %d, expected %d", storeID, local[storeID], version)
+		}
+
+		complete := sales
+		if local[storeID]+len(sales) < version {
+			complete = completeReceipts(sales)
+		}
+
+		if len(complete) == 0 {
+			// пачка - начало одного чека: чек читается пачкой большего размера
+			if len(sales) < limit {
+				return fmt.Errorf("receipt %q of store %s at version %d exceeds leader batch of %d sales",
+					sales[0].ReceiptID, storeID, local[storeID], len(sales))
+			}
+
+			batchSize = 2 * limit
+
+			continue
+		}
+
+		if err := s.storage.AddSales(complete); err != nil {
+			return fmt.Errorf("apply sales of store %s at version %d: %w", storeID, local[storeID], err)
+		}
+
+		local[storeID] += len(complete)
+
+		s.setLag(leader.Stores, local)
+	}
+
+	return nil
+}
+
+// completeReceipts возвращает продажи без строк чека в конце пачки sales: следующие строки этого чека
+// могут быть в следующей пачке.
+func completeReceipts(sales []*domain.Sale) []*domain.Sale {
+	receiptID := sales[len(sales)-1].ReceiptID
+	if receiptID == "" {
+		return sales
+	}
+
+	n := len(sales)
+	for n > 0 && sales[n-1].ReceiptID == receiptID {
+		n--
+	}
+
+	return sales[:n]
+}
+
+// setLag обновляет отставание от ведущего.
+func (s *ReplicationService) setLag(leader map[string]domain.LogVersion, local map[string]int) {
+	lag := 0
+	for storeID, version := range leader {
+		if version.Version > local[storeID] {
+			lag += version.Version - local[storeID]
+		}
+	}
+
+	s.mu.Lock()
+	s.status.Lag = lag
+	s.mu.Unlock()
+}
+
+// newEpoch возвращает случайную эпоху ведущего.
+func newEpoch() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		// без случайных байтов эпохи различаются временем запуска
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+
+	return hex.EncodeToString(b)
+}
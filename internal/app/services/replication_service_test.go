@@ -0,0 +1,248 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"go.dataflow.ru/service-sales/pkg/logger"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+)
+
+func TestReplicationService_Sync(t *testing.T) {
+	t.Parallel()
+
+	sales := func(storeID string, from, to int) []*domain.Sale {
+		res := make([]*domain.Sale, 0, to-from)
+		for i := from; i < to; i++ {
+			res = append(res, &domain.Sale{StoreID: storeID, ProductID: fmt.Sprintf("product_%d", i)})
+		}
+
+		return res
+	}
+
+	// receipt объединяет продажи с индексами [from, to) в один чек
+	receipt := func(sales []*domain.Sale, from, to int) []*domain.Sale {
+		for _, sale := range sales[from:to] {
+			sale.ReceiptID = "receipt_1"
+		}
+
+		return sales
+	}
+
+	testCases := []struct {
+		name        string
+		epoch       string         // эпоха ведущего, примененная до синхронизации (по умолчанию e1)
+		generations map[string]int // поколения журналов ведущего, примененные до синхронизации
+		mock        func(leader *MockReplicationLog, storage *MockReplicaStorage)
+		expLag      int
+		wantErr     bool
+	}{
+		{
+			name: "продажи дочитываются пачками по batch size с текущей версии магазина",
+			mock: func(leader *MockReplicationLog, storage *MockReplicaStorage) {
+				leader.EXPECT().Versions().Return(domain.LogVersions{Epoch: "e1", Stores: map[string]domain.LogVersion{"store_1": {Version: 5}, "store_2": {Version: 1}}}, nil)
+				storage.EXPECT().Versions().Return(map[string]domain.LogVersion{"store_1": {Version: 1}}, nil)
+
+				gomock.InOrder(
+					leader.EXPECT().SalesSince("e1", "store_1", 0, 1, 2).Return(sales("store_1", 1, 3), nil),
+					storage.EXPECT().AddSales(sales("store_1", 1, 3)).Return(nil),
+					leader.EXPECT().SalesSince("e1", "store_1", 0, 3, 2).Return(sales("store_1", 3, 5), nil),
+					storage.EXPECT().AddSales(sales("store_1", 3, 5)).Return(nil),
+					leader.EXPECT().SalesSince("e1", "store_2", 0, 0, 1).Return(sales("store_2", 0, 1), nil),
+					storage.EXPECT().AddSales(sales("store_2", 0, 1)).Return(nil),
+				)
+			},
+			expLag: 0,
+		},
+		{
+			name: "ведомый синхронизирован",
+			mock: func(leader *MockReplicationLog, storage *MockReplicaStorage) {
+				leader.EXPECT().Versions().Return(domain.LogVersions{Epoch: "e1", Stores: map[string]domain.LogVersion{"store_1": {Version: 5}}}, nil)
+				storage.EXPECT().Versions().Return(map[string]domain.LogVersion{"store_1": {Version: 5}}, nil)
+			},
+			expLag: 0,
+		},
+		{
+			name: "ошибка применения пачки - не применяется ни одна продажа пачки",
+			mock: func(leader *MockReplicationLog, storage *MockReplicaStorage) {
+				leader.EXPECT().Versions().Return(domain.LogVersions{Epoch: "e1", Stores: map[string]domain.LogVersion{"store_1": {Version: 3}}}, nil)
+				storage.EXPECT().Versions().Return(map[string]domain.LogVersion{}, nil)
+				leader.EXPECT().SalesSince("e1", "store_1", 0, 0, 2).Return(sales("store_1", 0, 2), nil)
+				storage.EXPECT().AddSales(sales("store_1", 0, 2)).Return(domain.ErrSaleOutOfRange)
+			},
+			expLag:  3,
+			wantErr: true,
+		},
+		{
+			name: "журнал ведущего короче его версии",
+			mock: func(leader *MockReplicationLog, storage *MockReplicaStorage) {
+				leader.EXPECT().Versions().Return(domain.LogVersions{Epoch: "e1", Stores: map[string]domain.LogVersion{"store_1": {Version: 3}}}, nil)
+				storage.EXPECT().Versions().Return(map[string]domain.LogVersion{}, nil)
+				leader.EXPECT().SalesSince("e1", "store_1", 0, 0, 2).Return(nil, nil)
+			},
+			expLag:  3,
+			wantErr: true,
+		},
+		{
+			name:        "ведущий начал журнал магазина заново - копия магазина удаляется и читается с начала",
+			generations: map[string]int{"store_1": 0},
+			mock: func(leader *MockReplicationLog, storage *MockReplicaStorage) {
+				leader.EXPECT().Versions().Return(domain.LogVersions{Epoch: "e1", Stores: map[string]domain.LogVersion{"store_1": {Generation: 1, Version: 2}}}, nil)
+				storage.EXPECT().Versions().Return(map[string]domain.LogVersion{"store_1": {Version: 5}}, nil)
+
+				gomock.InOrder(
+					storage.EXPECT().Purge(domain.Purge{StoreID: "store_1"}).Return(5, nil),
+					leader.EXPECT().SalesSince("e1", "store_1", 1, 0, 2).Return(sales("store_1", 0, 2), nil),
+				)
+
+				storage.EXPECT().AddSales(sales("store_1", 0, 2)).Return(nil)
+			},
+			expLag: 0,
+		},
+		{
+			name:        "журнал начат заново во время чтения - магазин читается при следующей синхронизации",
+			generations: map[string]int{"store_1": 0},
+			mock: func(leader *MockReplicationLog, storage *MockReplicaStorage) {
+				leader.EXPECT().Versions().Return(domain.LogVersions{Epoch: "e1", Stores: map[string]domain.LogVersion{"store_1": {Version: 3}}}, nil)
+				storage.EXPECT().Versions().Return(map[string]domain.LogVersion{"store_1": {Version: 1}}, nil)
+				leader.EXPECT().SalesSince("e1", "store_1", 0, 1, 2).Return(nil, domain.ErrLogGenerationChanged)
+			},
+			expLag: 2,
+		},
+		{
+			name:        "ведущий перезапущен - копии всех магазинов удаляются и читаются с начала",
+			epoch:       "e0",
+			generations: map[string]int{"store_1": 0, "store_2": 0},
+			mock: func(leader *MockReplicationLog, storage *MockReplicaStorage) {
+				leader.EXPECT().Versions().Return(domain.LogVersions{Epoch: "e1", Stores: map[string]domain.LogVersion{"store_1": {Version: 2}}}, nil)
+				storage.EXPECT().Versions().Return(map[string]domain.LogVersion{"store_1": {Version: 5}, "store_2": {Version: 3}}, nil)
+				storage.EXPECT().Purge(domain.Purge{StoreID: "store_1"}).Return(5, nil)
+				storage.EXPECT().Purge(domain.Purge{StoreID: "store_2"}).Return(3, nil)
+				leader.EXPECT().SalesSince("e1", "store_1", 0, 0, 2).Return(sales("store_1", 0, 2), nil)
+				storage.EXPECT().AddSales(sales("store_1", 0, 2)).Return(nil)
+			},
+			expLag: 0,
+		},
+		{
+			name: "строки чека в конце пачки откладываются до следующей пачки",
+			mock: func(leader *MockReplicationLog, storage *MockReplicaStorage) {
+				leader.EXPECT().Versions().Return(domain.LogVersions{Epoch: "e1", Stores: map[string]domain.LogVersion{"store_1": {Version: 3}}}, nil)
+				storage.EXPECT().Versions().Return(map[string]domain.LogVersion{}, nil)
+
+				log := receipt(sales("store_1", 0, 3), 1, 3)
+
+				gomock.InOrder(
+					leader.EXPECT().SalesSince("e1", "store_1", 0, 0, 2).Return(log[0:2], nil),
+					storage.EXPECT().AddSales(log[0:1]).Return(nil),
+					// последняя пачка журнала заканчивается целым чеком
+					leader.EXPECT().SalesSince("e1", "store_1", 0, 1, 2).Return(log[1:3], nil),
+					storage.EXPECT().AddSales(log[1:3]).Return(nil),
+				)
+			},
+			expLag: 0,
+		},
+		{
+			name: "чек больше пачки читается пачкой большего размера",
+			mock: func(leader *MockReplicationLog, storage *MockReplicaStorage) {
+				leader.EXPECT().Versions().Return(domain.LogVersions{Epoch: "e1", Stores: map[string]domain.LogVersion{"store_1": {Version: 5}}}, nil)
+				storage.EXPECT().Versions().Return(map[string]domain.LogVersion{}, nil)
+
+				log := receipt(sales("store_1", 0, 5), 0, 3)
+
+				gomock.InOrder(
+					leader.EXPECT().SalesSince("e1", "store_1", 0, 0, 2).Return(log[0:2], nil),
+					leader.EXPECT().SalesSince("e1", "store_1", 0, 0, 4).Return(log[0:4], nil),
+					storage.EXPECT().AddSales(log[0:4]).Return(nil),
+					leader.EXPECT().SalesSince("e1", "store_1", 0, 4, 1).Return(log[4:5], nil),
+					storage.EXPECT().AddSales(log[4:5]).Return(nil),
+				)
+			},
+			expLag: 0,
+		},
+		{
+			name: "чек больше максимальной пачки ведущего",
+			mock: func(leader *MockReplicationLog, storage *MockReplicaStorage) {
+				leader.EXPECT().Versions().Return(domain.LogVersions{Epoch: "e1", Stores: map[string]domain.LogVersion{"store_1": {Version: 5}}}, nil)
+				storage.EXPECT().Versions().Return(map[string]domain.LogVersion{}, nil)
+
+				log := receipt(sales("store_1", 0, 5), 0, 4)
+
+				gomock.InOrder(
+					leader.EXPECT().SalesSince("e1", "store_1", 0, 0, 2).Return(log[0:2], nil),
+					// ведущий отдает не больше 3 продаж за запрос
+					leader.EXPECT().SalesSince("e1", "store_1", 0, 0, 4).Return(log[0:3], nil),
+				)
+			},
+			expLag:  5,
+			wantErr: true,
+		},
+		{
+			name: "ведомый впереди ведущего",
+			mock: func(leader *MockReplicationLog, storage *MockReplicaStorage) {
+				leader.EXPECT().Versions().Return(domain.LogVersions{Epoch: "e1", Stores: map[string]domain.LogVersion{"store_1": {Version: 3}}}, nil)
+				storage.EXPECT().Versions().Return(map[string]domain.LogVersion{"store_1": {Version: 4}}, nil)
+			},
+			expLag:  0,
+			wantErr: true,
+		},
+		{
+			name: "ведущий недоступен",
+			mock: func(leader *MockReplicationLog, storage *MockReplicaStorage) {
+				leader.EXPECT().Versions().Return(domain.LogVersions{}, errors.New("connection refused"))
+			},
+			expLag:  0,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range testCases {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			leader := NewMockReplicationLog(ctrl)
+			storage := NewMockReplicaStorage(ctrl)
+			tt.mock(leader, storage)
+
+			now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+			s := NewReplicationService(storage, logger.NoOpLogger(),
+				FollowLeader(leader, "http://leader:8005"),
+				WithReplicationBatchSize(2),
+			)
+			s.now = func() time.Time { return now }
+
+			s.leaderEpoch = "e1"
+			if tt.epoch != "" {
+				s.leaderEpoch = tt.epoch
+			}
+
+			for storeID, generation := range tt.generations {
+				s.generations[storeID] = generation
+			}
+
+			err := s.Sync()
+			assert.Equal(t, tt.wantErr, err != nil)
+
+			status := s.status
+			assert.Equal(t, domain.RoleFollower, status.Role)
+			assert.Equal(t, "http://leader:8005", status.Leader)
+			assert.Equal(t, tt.expLag, status.Lag)
+
+			if tt.wantErr {
+				assert.Equal(t, err.Error(), status.Error)
+				assert.True(t, status.LastSync.IsZero())
+			} else {
+				assert.Empty(t, status.Error)
+				assert.Equal(t, now, status.LastSync)
+			}
+		})
+	}
+}
@@ -0,0 +1,322 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+	"go.dataflow.ru/service-sales/internal/app/ports"
+	"go.dataflow.ru/service-sales/pkg/cron"
+	"go.dataflow.ru/service-sales/pkg/logger"
+)
+
+// ReportService отчеты по расписанию: сохраненные запросы агрегатов продаж выполняются по расписанию cron,
+// результат сохраняется для скачивания и, если у отчета задан адрес, отправляется на него.
+//
+// Время следующего запуска хранится в памяти и отсчитывается от запуска сервиса или изменения отчета:
+// запуски, пропущенные пока сервис не работал, не выполняются.
+type ReportService struct {
+	storage  ports.ReportStorage
+	sales    ports.SalesService
+	renderer ports.ReportRenderer
+	delivery ports.ReportDelivery
+	logger   *logger.Logger
+
+	retries retryPolicy
+
+	mu   sync.Mutex
+	next map[string]time.Time // следующий запуск по отчету
+
+	now func() time.Time
+}
+
+type ReportOption func(s *ReportService)
+
+// WithReportRetries задает количество попыток доставки отчета и паузу перед повторной попыткой:
+// пауза удваивается после каждой неудачной попытки, но не превышает maxBackoff.
+func WithReportRetries(attempts int, backoff, maxBackoff time.Duration) ReportOption {
+	return func(s *ReportService) {
+		s.retries = s.retries.with(attempts, backoff, maxBackoff)
+	}
+}
+
+func NewReportService(
+	storage ports.ReportStorage,
+	sales ports.SalesService,
+	renderer ports.ReportRenderer,
+	delivery ports.ReportDelivery,
+	logger *logger.Logger,
+	opts ...ReportOption,
+) *ReportService {
+	s := &ReportService{
+		storage:  storage,
+		sales:    sales,
+		renderer: renderer,
+		delivery: delivery,
+		logger:   logger,
+		retries:  defaultRetryPolicy,
+		next:     make(map[string]time.Time),
+		now:      time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// CreateReport проверяет и сохраняет новый отчет, назначая ему идентификатор.
+func (s *ReportService) CreateReport(report *domain.Report) (*domain.Report, error) {
+	id, err := newReportID()
+	if err != nil {
+		return nil, err
+	}
+
+	report.ID = id
+
+	return s.saveReport(report)
+}
+
+// UpdateReport заменяет отчет с идентификатором report.ID. Без секрета сохраняется прежний секрет отчета.
+// Следующий запуск отсчитывается от момента изменения.
+func (s *ReportService) UpdateReport(report *domain.Report) (*domain.Report, error) {
+	prev, err := s.storage.GetReport(report.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if report.Secret == "" {
+		report.Secret = prev.Secret
+	}
+
+	return s.saveReport(report)
+}
+
+func (s *ReportService) saveReport(report *domain.Report) (*domain.Report, error) {
+	if report.Format == "" {
+		report.Format = domain.ReportCSV
+	}
+
+	if err := s.validate(report); err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrInvalidReport, err)
+	}
+
+	if err := s.storage.SaveReport(report); err != nil {
+		return nil, err
+	}
+
+	s.reschedule(report.ID)
+
+	return report, nil
+}
+
+// validate проверяет описание отчета, расписание, часовой пояс и запрос.
+func (s *ReportService) validate(report *domain.Report) error {
+	if err := report.Validate(); err != nil {
+		return err
+	}
+
+	if _, err := cron.Parse(report.Schedule); err != nil {
+		return err
+	}
+
+	loc, err := time.LoadLocation(report.TimeZone)
+	if err != nil {
+		return fmt.Errorf("invalid time zone %q", report.TimeZone)
+	}
+
+	start, end := report.Resolve(s.now().In(loc))
+	q := report.Query(start, end, loc)
+
+	return q.Validate()
+}
+
+func (s *ReportService) DeleteReport(id string) error {
+	if err := s.storage.DeleteReport(id); err != nil {
+		return err
+	}
+
+	s.reschedule(id)
+
+	return nil
+}
+
+func (s *ReportService) GetReport(id string) (*domain.Report, error) {
+	return s.storage.GetReport(id)
+}
+
+func (s *ReportService) GetReports() []*domain.Report {
+	return s.storage.GetReports()
+}
+
+// RunReport выполняет отчет вне расписания. Ошибка запроса или доставки сохраняется в запуске.
+func (s *ReportService) RunReport(ctx context.Context, id string) (*domain.ReportRun, error) {
+	report, err := s.storage.GetReport(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.run(ctx, report, s.now())
+}
+
+// GetRuns возвращает запуски отчета, последний - первым.
+func (s *ReportService) GetRuns(reportID string) ([]*domain.ReportRun, error) {
+	if _, err := s.storage.GetReport(reportID); err != nil {
+		return nil, err
+	}
+
+	return s.storage.GetRuns(reportID), nil
+}
+
+// GetRunContent возвращает запуск отчета и его содержимое (nil для неудачного запуска).
+func (s *ReportService) GetRunContent(reportID, runID string) (*domain.ReportRun, []byte, error) {
+	return s.storage.GetRunContent(reportID, runID)
+}
+
+// Run выполняет отчеты по расписанию, проверяя расписания каждые interval, пока не отменен ctx.
+func (s *ReportService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.Check(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check выполняет отчеты, время запуска которых наступило. Отчеты выполняются по очереди.
+func (s *ReportService) Check(ctx context.Context) {
+	now := s.now()
+
+	for _, report := range s.storage.GetReports() {
+		if !s.due(report, now) {
+			continue
+		}
+
+		run, err := s.run(ctx, report, now)
+		if err != nil {
+			s.logger.Errorf("report %s: cant save run: %v", report.ID, err)
+			continue
+		}
+
+		if run.Error != "" {
+			s.logger.Errorf("report %s: run %s failed: %s", report.ID, run.ID, run.Error)
+		}
+	}
+}
+
+// due сообщает, что время запуска отчета наступило, и назначает следующий запуск. Для нового отчета
+// назначается первый запуск после now.
+func (s *ReportService) due(report *domain.Report, now time.Time) bool {
+	schedule, err := cron.Parse(report.Schedule)
+	if err != nil {
+		return false
+	}
+
+	loc, err := time.LoadLocation(report.TimeZone)
+	if err != nil {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next, ok := s.next[report.ID]
+	if ok && (next.IsZero() || now.Before(next)) {
+		return false
+	}
+
+	s.next[report.ID] = schedule.Next(now.In(loc))
+
+	return ok
+}
+
+// reschedule сбрасывает время следующего запуска отчета.
+func (s *ReportService) reschedule(reportID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.next, reportID)
+}
+
+// run выполняет запрос отчета за период перед моментом at, сохраняет результат и доставляет его.
+func (s *ReportService) run(ctx context.Context, report *domain.Report, at time.Time) (*domain.ReportRun, error) {
+	id, err := newReportID()
+	if err != nil {
+		return nil, err
+	}
+
+	loc, err := time.LoadLocation(report.TimeZone)
+	if err != nil {
+		return nil, fmt.Errorf("load time zone of report %s: %w", report.ID, err)
+	}
+
+	at = at.In(loc)
+	start, end := report.Resolve(at)
+
+	run := &domain.ReportRun{
+		ID:        id,
+		ReportID:  report.ID,
+		Format:    report.Format,
+		StartedAt: at,
+		StartDate: start,
+		EndDate:   end,
+	}
+
+	content, err := s.render(report, run, loc)
+	if err != nil {
+		run.Error = err.Error()
+
+		return run, s.storage.SaveRun(run, nil)
+	}
+
+	if report.WebhookURL != "" && s.delivery != nil {
+		err = s.retries.do(ctx,
+			func() error { return s.delivery.DeliverReport(ctx, report, run, content) },
+			func(attempt int, err error) {
+				s.logger.Warnf("report %s: attempt %d to deliver run %s failed: %v", report.ID, attempt, run.ID, err)
+			},
+		)
+
+		run.Delivered = err == nil
+		if err != nil {
+			run.DeliveryError = err.Error()
+		}
+	}
+
+	return run, s.storage.SaveRun(run, content)
+}
+
+// render выполняет запрос отчета за период запуска и возвращает содержимое отчета.
+func (s *ReportService) render(report *domain.Report, run *domain.ReportRun, loc *time.Location) ([]byte, error) {
+	res, err := s.sales.Query(report.Query(run.StartDate, run.EndDate, loc))
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := s.renderer.Render(report, run, res)
+	if err != nil {
+		return nil, err
+	}
+
+	run.Rows, run.Size = len(res.Rows), len(content)
+
+	return content, nil
+}
+
+func newReportID() (string, error) {
+	id, err := newAlertID()
+	if err != nil {
+		return "", fmt.Errorf("generate report id: %w", err)
+	}
+
+	return id, nil
+}
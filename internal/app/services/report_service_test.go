@@ -0,0 +1,251 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.dataflow.ru/service-sales/pkg/logger"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+)
+
+func TestReportService_CreateReport(t *testing.T) {
+	t.Parallel()
+
+	valid := func() *domain.Report {
+		return &domain.Report{
+			Name:     "Выручка за неделю",
+			Schedule: "0 9 * * mon",
+			TimeZone: "Europe/Moscow",
+			Period:   domain.ReportPreviousWeek,
+			GroupBy:  []string{domain.FieldStore},
+			Metrics:  []string{domain.MetricGross},
+		}
+	}
+
+	testCases := []struct {
+		name   string
+		modify func(r *domain.Report)
+		valid  bool
+	}{
+		{
+			name:   "отчет без формата сохраняется в CSV",
+			modify: func(r *domain.Report) {},
+			valid:  true,
+		},
+		{
+			name:   "неверное расписание",
+			modify: func(r *domain.Report) { r.Schedule = "0 9 * *" },
+		},
+		{
+			name:   "неизвестный часовой пояс",
+			modify: func(r *domain.Report) { r.TimeZone = "Mars/Olympus" },
+		},
+		{
+			name:   "неизвестный показатель",
+			modify: func(r *domain.Report) { r.Metrics = []string{"profit"} },
+		},
+		{
+			name:   "неизвестный период",
+			modify: func(r *domain.Report) { r.Period = "yesterday" },
+		},
+	}
+
+	for _, tt := range testCases {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			storage := NewMockReportStorage(ctrl)
+
+			s := NewReportService(storage, nil, nil, nil, logger.NoOpLogger())
+
+			report := valid()
+			tt.modify(report)
+
+			if !tt.valid {
+				_, err := s.CreateReport(report)
+				assert.ErrorIs(t, err, domain.ErrInvalidReport)
+
+				return
+			}
+
+			storage.EXPECT().SaveReport(report).Return(nil)
+
+			created, err := s.CreateReport(report)
+			require.NoError(t, err)
+			assert.NotEmpty(t, created.ID)
+			assert.Equal(t, domain.ReportCSV, created.Format)
+		})
+	}
+}
+
+func TestReportService_RunReport(t *testing.T) {
+	t.Parallel()
+
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	require.NoError(t, err)
+
+	// понедельник
+	now := time.Date(2024, 4, 15, 9, 0, 0, 0, moscow)
+
+	report := &domain.Report{
+		ID:         "r1",
+		Name:       "Выручка за неделю",
+		Schedule:   "0 9 * * mon",
+		TimeZone:   "Europe/Moscow",
+		Period:     domain.ReportPreviousWeek,
+		Format:     domain.ReportCSV,
+		Metrics:    []string{domain.MetricGross},
+		WebhookURL: "https://example.com/reports",
+	}
+
+	query := domain.Query{
+		StartDate: time.Date(2024, 4, 8, 0, 0, 0, 0, moscow),
+		EndDate:   time.Date(2024, 4, 14, 23, 59, 59, 999999999, moscow),
+		Metrics:   []string{domain.MetricGross},
+		Location:  moscow,
+	}
+
+	result := &domain.QueryResult{Rows: []domain.QueryRow{{Group: map[string]string{domain.FieldCurrency: "RUB"}}}}
+	content := []byte("currency,gross\nRUB,1000\n")
+
+	errUnavailable := errors.New("status 503")
+
+	testCases := []struct {
+		name      string
+		queryErr  error
+		delivery  []error // результаты попыток доставки
+		expRun    func(t *testing.T, run *domain.ReportRun)
+		expStored []byte
+	}{
+		{
+			name:     "отчет доставлен после временной ошибки",
+			delivery: []error{errUnavailable, nil},
+			expRun: func(t *testing.T, run *domain.ReportRun) {
+				assert.True(t, run.Delivered)
+				assert.Empty(t, run.DeliveryError)
+				assert.Equal(t, 1, run.Rows)
+				assert.Equal(t, len(content), run.Size)
+			},
+			expStored: content,
+		},
+		{
+			name:     "получатель отклонил отчет",
+			delivery: []error{domain.ErrWebhookRejected},
+			expRun: func(t *testing.T, run *domain.ReportRun) {
+				assert.False(t, run.Delivered)
+				assert.Contains(t, run.DeliveryError, domain.ErrWebhookRejected.Error())
+			},
+			expStored: content,
+		},
+		{
+			name:     "ошибка запроса сохраняется в запуске",
+			queryErr: domain.ErrQueryTooLarge,
+			expRun: func(t *testing.T, run *domain.ReportRun) {
+				assert.Equal(t, domain.ErrQueryTooLarge.Error(), run.Error)
+				assert.False(t, run.Delivered)
+			},
+		},
+	}
+
+	for _, tt := range testCases {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			storage := NewMockReportStorage(ctrl)
+			sales := NewMockSalesService(ctrl)
+			renderer := NewMockReportRenderer(ctrl)
+			delivery := NewMockReportDelivery(ctrl)
+
+			storage.EXPECT().GetReport("r1").Return(report, nil)
+
+			if tt.queryErr != nil {
+				sales.EXPECT().Query(query).Return(nil, tt.queryErr)
+			} else {
+				sales.EXPECT().Query(query).Return(result, nil)
+				renderer.EXPECT().Render(report, gomock.Any(), result).Return(content, nil)
+			}
+
+			calls := make([]*gomock.Call, 0, len(tt.delivery))
+			for _, err := range tt.delivery {
+				calls = append(calls, delivery.EXPECT().DeliverReport(gomock.Any(), report, gomock.Any(), content).Return(err))
+			}
+
+			gomock.InOrder(calls...)
+
+			storage.EXPECT().SaveRun(gomock.Any(), tt.expStored).Return(nil)
+
+			s := NewReportService(storage, sales, renderer, delivery, logger.NoOpLogger(),
+				WithReportRetries(3, time.Millisecond, time.Millisecond),
+			)
+			s.now = func() time.Time { return now.UTC() }
+
+			run, err := s.RunReport(context.Background(), "r1")
+			require.NoError(t, err)
+
+			assert.NotEmpty(t, run.ID)
+			assert.Equal(t, "r1", run.ReportID)
+			assert.Equal(t, query.StartDate, run.StartDate)
+			assert.Equal(t, query.EndDate, run.EndDate)
+			tt.expRun(t, run)
+		})
+	}
+}
+
+func TestReportService_Check(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	storage := NewMockReportStorage(ctrl)
+	sales := NewMockSalesService(ctrl)
+	renderer := NewMockReportRenderer(ctrl)
+
+	report := &domain.Report{
+		ID:       "r1",
+		Name:     "Выручка за день",
+		Schedule: "0 9 * * *",
+		Period:   domain.ReportPreviousDay,
+		Format:   domain.ReportJSON,
+		Metrics:  []string{domain.MetricGross},
+	}
+
+	storage.EXPECT().GetReports().Return([]*domain.Report{report}).AnyTimes()
+
+	now := time.Date(2024, 4, 15, 8, 30, 0, 0, time.UTC)
+
+	s := NewReportService(storage, sales, renderer, nil, logger.NoOpLogger())
+	s.now = func() time.Time { return now }
+
+	// первая проверка назначает запуск, запуски до старта сервиса не выполняются
+	s.Check(context.Background())
+
+	now = time.Date(2024, 4, 15, 8, 59, 0, 0, time.UTC)
+	s.Check(context.Background())
+
+	// наступило время запуска: отчет за 14 апреля
+	sales.EXPECT().Query(domain.Query{
+		StartDate: time.Date(2024, 4, 14, 0, 0, 0, 0, time.UTC),
+		EndDate:   time.Date(2024, 4, 14, 23, 59, 59, 999999999, time.UTC),
+		Metrics:   []string{domain.MetricGross},
+		Location:  time.UTC,
+	}).Return(&domain.QueryResult{}, nil)
+	renderer.EXPECT().Render(report, gomock.Any(), gomock.Any()).Return([]byte("{}"), nil)
+	storage.EXPECT().SaveRun(gomock.Any(), []byte("{}")).Return(nil)
+
+	now = time.Date(2024, 4, 15, 9, 0, 30, 0, time.UTC)
+	s.Check(context.Background())
+
+	// следующий запуск - завтра
+	s.Check(context.Background())
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+	"go.dataflow.ru/service-sales/internal/app/ports"
+	"go.dataflow.ru/service-sales/pkg/logger"
+)
+
+// RetentionService удаление продаж: по запросу администратора (продажи закрытого магазина или продажи раньше
+// заданного времени) и периодически - продажи старше срока хранения. Каждое удаление записывается в журнал
+// аудита, в том числе неудачное.
+type RetentionService struct {
+	storage ports.SalesStorage
+	audit   ports.AuditLog
+	logger  *logger.Logger
+
+	horizon time.Duration // срок хранения продаж, 0 - продажи не удаляются по сроку
+
+	mu sync.Mutex // удаления выполняются последовательно
+
+	now func() time.Time
+}
+
+type RetentionOption func(s *RetentionService)
+
+// WithRetentionHorizon включает удаление продаж старше horizon (см. RetentionService.Run).
+func WithRetentionHorizon(horizon time.Duration) RetentionOption {
+	return func(s *RetentionService) {
+		s.horizon = horizon
+	}
+}
+
+func NewRetentionService(storage ports.SalesStorage, audit ports.AuditLog, logger *logger.Logger, opts ...RetentionOption) *RetentionService {
+	s := &RetentionService{
+		storage: storage,
+		audit:   audit,
+		logger:  logger,
+		now:     time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Purge удаляет продажи по запросу инициатора actor и возвращает запись журнала аудита об удалении.
+// Ошибка удаления возвращается вместе с записью: удаление могло выполниться частично.
+func (s *RetentionService) Purge(purge *domain.Purge, actor string) (*domain.AuditEntry, error) {
+	if err := purge.Validate(); err != nil {
+		return nil, err
+	}
+
+	return s.purge(purge, actor, true)
+}
+
+// GetAuditLog возвращает записи журнала аудита, последняя - первой.
+func (s *RetentionService) GetAuditLog() []*domain.AuditEntry {
+	return s.audit.GetEntries()
+}
+
+// Run удаляет продажи старше срока хранения каждые interval, пока не отменен ctx. Без срока хранения
+// сразу возвращает управление.
+func (s *RetentionService) Run(ctx context.Context, interval time.Duration) {
+	if s.horizon <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.PurgeExpired()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PurgeExpired удаляет продажи всех магазинов старше срока хранения. В журнал аудита записываются только
+// удаления, которые удалили продажи или завершились ошибкой.
+func (s *RetentionService) PurgeExpired() {
+	before := s.now().Add(-s.horizon)
+
+	purge := &domain.Purge{
+		Before: &before,
+		Reason: fmt.Sprintf("retention horizon %s", s.horizon),
+	}
+
+	entry, err := s.purge(purge, domain.AuditRetention, false)
+	if err != nil {
+		s.logger.Errorf("cant purge sales before %s: %v", before.Format(time.RFC3339), err)
+		return
+	}
+
+	if entry.Deleted > 0 {
+		s.logger.Infof("purged %d sales before %s", entry.Deleted, before.Format(time.RFC3339))
+	}
+}
+
+// purge удаляет продажи и записывает удаление в журнал аудита; если always не задан, удаление без продаж
+// не записывается.
+func (s *RetentionService) purge(purge *domain.Purge, actor string, always bool) (*domain.AuditEntry, error) {
+	id, err := newAuditID()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deleted, purgeErr := s.storage.Purge(*purge)
+
+	entry := &domain.AuditEntry{
+		ID:      id,
+		Time:    s.now(),
+		Action:  domain.AuditPurge,
+		Actor:   actor,
+		Purge:   purge,
+		Deleted: deleted,
+	}
+
+	if purgeErr != nil {
+		entry.Error = purgeErr.Error()
+	}
+
+	if !always && deleted == 0 && purgeErr == nil {
+		return entry, nil
+	}
+
+	if err = s.audit.Append(entry); err != nil {
+		// продажи уже удалены: удаление без записи журнала видно хотя бы в логе
+		s.logger.Errorf("cant record audit entry %s: purged %d sales (%+v) by %s: %v", id, deleted, *purge, actor, err)
+
+		if purgeErr == nil {
+			return entry, fmt.Errorf("record audit entry: %w", err)
+		}
+	}
+
+	if purgeErr != nil {
+		return entry, fmt.Errorf("purge sales: %w", purgeErr)
+	}
+
+	return entry, nil
+}
+
+func newAuditID() (string, error) {
+	id, err := newAlertID()
+	if err != nil {
+		return "", fmt.Errorf("generate audit entry id: %w", err)
+	}
+
+	return id, nil
+}
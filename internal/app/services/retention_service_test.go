@@ -0,0 +1,148 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.dataflow.ru/service-sales/pkg/logger"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+)
+
+func TestRetentionService_Purge(t *testing.T) {
+	t.Parallel()
+
+	errDisk := errors.New("disk failure")
+
+	testCases := []struct {
+		name       string
+		purge      *domain.Purge
+		deleted    int
+		purgeErr   error
+		auditErr   error
+		expError   error
+		expEntry   bool
+		expDeleted int
+	}{
+		{
+			name:       "продажи закрытого магазина",
+			purge:      &domain.Purge{StoreID: "store_1", Reason: "store closed"},
+			deleted:    30,
+			expEntry:   true,
+			expDeleted: 30,
+		},
+		{
+			name:     "удаление без магазина и времени",
+			purge:    &domain.Purge{},
+			expError: domain.ErrInvalidPurge,
+		},
+		{
+			name:       "ошибка удаления записывается в журнал",
+			purge:      &domain.Purge{StoreID: "store_1"},
+			deleted:    10,
+			purgeErr:   errDisk,
+			expError:   errDisk,
+			expEntry:   true,
+			expDeleted: 10,
+		},
+		{
+			name:       "журнал аудита недоступен",
+			purge:      &domain.Purge{StoreID: "store_1"},
+			deleted:    30,
+			auditErr:   errDisk,
+			expError:   errDisk,
+			expEntry:   true,
+			expDeleted: 30,
+		},
+	}
+
+	for _, tt := range testCases {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			storage := NewMockSalesStorage(ctrl)
+			audit := NewMockAuditLog(ctrl)
+
+			now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+			if tt.expEntry {
+				storage.EXPECT().Purge(*tt.purge).Return(tt.deleted, tt.purgeErr)
+				audit.EXPECT().Append(gomock.Any()).DoAndReturn(func(entry *domain.AuditEntry) error {
+					assert.Equal(t, domain.AuditPurge, entry.Action)
+					assert.Equal(t, "10.0.0.1", entry.Actor)
+					assert.Equal(t, now, entry.Time)
+					assert.Equal(t, tt.purge, entry.Purge)
+
+					return tt.auditErr
+				})
+			}
+
+			s := NewRetentionService(storage, audit, logger.NoOpLogger())
+			s.now = func() time.Time { return now }
+
+			entry, err := s.Purge(tt.purge, "10.0.0.1")
+			if tt.expError != nil {
+				assert.ErrorIs(t, err, tt.expError)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			if !tt.expEntry {
+				assert.Nil(t, entry)
+				return
+			}
+
+			require.NotNil(t, entry)
+			assert.NotEmpty(t, entry.ID)
+			assert.Equal(t, tt.expDeleted, entry.Deleted)
+
+			if tt.purgeErr != nil {
+				assert.Equal(t, tt.purgeErr.Error(), entry.Error)
+			}
+		})
+	}
+}
+
+func TestRetentionService_PurgeExpired(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	storage := NewMockSalesStorage(ctrl)
+	audit := NewMockAuditLog(ctrl)
+
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	before := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	s := NewRetentionService(storage, audit, logger.NoOpLogger(), WithRetentionHorizon(1096*24*time.Hour))
+	s.now = func() time.Time { return now }
+
+	purge := func(p domain.Purge) bool {
+		return p.StoreID == "" && p.Before != nil && p.Before.Equal(before)
+	}
+
+	// удалены продажи - удаление записывается в журнал
+	storage.EXPECT().Purge(gomock.Any()).DoAndReturn(func(p domain.Purge) (int, error) {
+		assert.True(t, purge(p), "purge %+v", p)
+		return 100, nil
+	})
+	audit.EXPECT().Append(gomock.Any()).DoAndReturn(func(entry *domain.AuditEntry) error {
+		assert.Equal(t, domain.AuditRetention, entry.Actor)
+		assert.Equal(t, 100, entry.Deleted)
+
+		return nil
+	})
+
+	s.PurgeExpired()
+
+	// удалять нечего - журнал не пополняется
+	storage.EXPECT().Purge(gomock.Any()).Return(0, nil)
+
+	s.PurgeExpired()
+}
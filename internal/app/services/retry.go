@@ -0,0 +1,61 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+)
+
+// retryPolicy количество попыток доставки на адрес webhook и пауза перед повторной попыткой: пауза удваивается
+// после каждой неудачной попытки, но не превышает maxBackoff.
+type retryPolicy struct {
+	attempts   int
+	backoff    time.Duration
+	maxBackoff time.Duration
+}
+
+var defaultRetryPolicy = retryPolicy{attempts: 5, backoff: time.Second, maxBackoff: time.Minute}
+
+// with возвращает политику с заданными положительными значениями.
+func (p retryPolicy) with(attempts int, backoff, maxBackoff time.Duration) retryPolicy {
+	if attempts > 0 {
+		p.attempts = attempts
+	}
+
+	if backoff > 0 {
+		p.backoff = backoff
+	}
+
+	if maxBackoff > 0 {
+		p.maxBackoff = maxBackoff
+	}
+
+	return p
+}
+
+// do выполняет попытку доставки deliver, повторяя ее при временных ошибках. Ошибка domain.ErrWebhookRejected
+// не повторяется. onRetry вызывается перед паузой с номером неудачной попытки и ее ошибкой.
+func (p retryPolicy) do(ctx context.Context, deliver func() error, onRetry func(attempt int, err error)) error {
+	backoff := p.backoff
+
+	for attempt := 1; ; attempt++ {
+		err := deliver()
+		if err == nil || errors.Is(err, domain.ErrWebhookRejected) || attempt >= p.attempts {
+			return err
+		}
+
+		onRetry(attempt, err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		if backoff *= 2; backoff > p.maxBackoff {
+			backoff = p.maxBackoff
+		}
+	}
+}
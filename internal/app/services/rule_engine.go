@@ -0,0 +1,82 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+)
+
+// RuleEngine проверяет продажи бизнес-правилами и считает проверки и нарушения каждого правила.
+type RuleEngine struct {
+	rules   []domain.Rule
+	checked []atomic.Int64
+	hits    []atomic.Int64
+
+	now func() time.Time
+}
+
+// NewRuleEngine возвращает движок правил rules. Имена правил должны быть уникальными.
+func NewRuleEngine(rules []domain.Rule) (*RuleEngine, error) {
+	names := make(map[string]struct{}, len(rules))
+
+	for i := range rules {
+		if err := rules[i].Validate(); err != nil {
+			return nil, err
+		}
+
+		if _, ok := names[rules[i].Name]; ok {
+			return nil, fmt.Errorf("duplicate rule %q", rules[i].Name)
+		}
+
+		names[rules[i].Name] = struct{}{}
+	}
+
+	return &RuleEngine{
+		rules:   rules,
+		checked: make([]atomic.Int64, len(rules)),
+		hits:    make([]atomic.Int64, len(rules)),
+		now:     time.Now,
+	}, nil
+}
+
+// Check проверяет продажу всеми правилами, которые к ней применяются. Ошибка содержит все нарушенные правила.
+func (e *RuleEngine) Check(sale *domain.Sale) error {
+	now := e.now()
+
+	var violations []error
+
+	for i := range e.rules {
+		rule := &e.rules[i]
+		if !rule.Applies(sale) {
+			continue
+		}
+
+		e.checked[i].Add(1)
+
+		if err := rule.Check(sale, now); err != nil {
+			e.hits[i].Add(1)
+
+			violations = append(violations, fmt.Errorf("rule %q: %v: %w", rule.Name, err, domain.ErrRuleViolation))
+		}
+	}
+
+	return errors.Join(violations...)
+}
+
+// Stats возвращает статистику применения правил в порядке их описания.
+func (e *RuleEngine) Stats() []domain.RuleStats {
+	stats := make([]domain.RuleStats, len(e.rules))
+
+	for i, rule := range e.rules {
+		stats[i] = domain.RuleStats{
+			Rule:    rule,
+			Checked: e.checked[i].Load(),
+			Hits:    e.hits[i].Load(),
+		}
+	}
+
+	return stats
+}
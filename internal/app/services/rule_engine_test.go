@@ -0,0 +1,86 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.dataflow.ru/service-sales/pkg/logger"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+)
+
+func TestRuleEngine(t *testing.T) {
+	t.Parallel()
+
+	maxPrice := decimal.NewFromInt(10000)
+
+	engine, err := NewRuleEngine([]domain.Rule{
+		{Name: "milk quantity", Type: domain.RuleMaxQuantity, Products: []string{"milk"}, MaxQuantity: 50},
+		{Name: "price", Type: domain.RulePriceRange, MaxPrice: &maxPrice, Currency: "RUB"},
+		{Name: "no future", Type: domain.RuleSaleDate, MaxFuture: domain.Duration(time.Minute)},
+	})
+	require.NoError(t, err)
+
+	now := time.Date(2024, 6, 20, 12, 0, 0, 0, time.UTC)
+	engine.now = func() time.Time { return now }
+
+	ctrl := gomock.NewController(t)
+	storage := NewMockSalesStorage(ctrl)
+
+	s := NewSaleService(storage, logger.NoOpLogger(), WithDefaultCurrency("RUB"), WithRules(engine))
+
+	valid := &domain.Sale{
+		StoreID:      "store_1",
+		ProductID:    "milk",
+		QuantitySold: 2,
+		SalePrice:    decimal.NewFromInt(90),
+		SaleDate:     now.Add(-time.Hour),
+	}
+
+	storage.EXPECT().AddSale(valid).Return(nil)
+	assert.NoError(t, s.AddSale(valid))
+
+	// нарушены два правила, ошибка содержит оба
+	invalid := &domain.Sale{
+		StoreID:      "store_1",
+		ProductID:    "milk",
+		QuantitySold: 100,
+		SalePrice:    decimal.NewFromInt(199000),
+		SaleDate:     now.Add(-time.Hour),
+	}
+
+	err = s.AddSale(invalid)
+	assert.ErrorIs(t, err, domain.ErrRuleViolation)
+	assert.ErrorContains(t, err, `rule "milk quantity": quantity 100 exceeds 50`)
+	assert.ErrorContains(t, err, `rule "price": price 199000 above 10000`)
+
+	// правило с ценами в рублях не проверяет продажи в других валютах
+	storage.EXPECT().AddSale(gomock.Any()).Return(nil)
+	assert.NoError(t, s.AddSale(&domain.Sale{
+		StoreID:      "store_1",
+		ProductID:    "bread",
+		QuantitySold: 1,
+		SalePrice:    decimal.NewFromInt(50000),
+		Currency:     "KZT",
+		SaleDate:     now,
+	}))
+
+	stats := engine.Stats()
+	require.Len(t, stats, 3)
+	assert.Equal(t, "milk quantity", stats[0].Rule.Name)
+	assert.Equal(t, [][2]int64{{2, 1}, {2, 1}, {3, 0}}, [][2]int64{
+		{stats[0].Checked, stats[0].Hits},
+		{stats[1].Checked, stats[1].Hits},
+		{stats[2].Checked, stats[2].Hits},
+	})
+
+	_, err = NewRuleEngine([]domain.Rule{
+		{Name: "stores", Type: domain.RuleAllowedStores, Allowed: []string{"store_1"}},
+		{Name: "stores", Type: domain.RuleAllowedStores, Allowed: []string{"store_2"}},
+	})
+	assert.Error(t, err)
+}
@@ -1,9 +1,22 @@
 package services
 
 //go:generate mockgen -package $GOPACKAGE -source ../ports/sales_storage.go -destination mocks.go
+//go:generate mockgen -package $GOPACKAGE -source ../ports/exchange_rates.go -destination mocks_rates.go
+//go:generate mockgen -package $GOPACKAGE -source ../ports/catalog.go -destination mocks_catalog.go
+//go:generate mockgen -package $GOPACKAGE -source ../ports/catalog_storage.go -destination mocks_catalog_storage.go
+//go:generate mockgen -package $GOPACKAGE -source ../ports/replication.go -destination mocks_replication.go
+//go:generate mockgen -package $GOPACKAGE -source ../ports/reference.go -destination mocks_reference.go
+//go:generate mockgen -package $GOPACKAGE -source ../ports/target_storage.go -destination mocks_targets.go
+//go:generate mockgen -package $GOPACKAGE -source ../ports/sales_service.go -destination mocks_sales_service.go
+//go:generate mockgen -package $GOPACKAGE -source ../ports/anomaly.go -destination mocks_anomaly.go
+//go:generate mockgen -package $GOPACKAGE -source ../ports/alert.go -destination mocks_alert.go
+//go:generate mockgen -package $GOPACKAGE -source ../ports/report.go -destination mocks_report.go
+//go:generate mockgen -package $GOPACKAGE -source ../ports/audit.go -destination mocks_audit.go
 
 import (
+	"context"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/shopspring/decimal"
@@ -13,36 +26,636 @@ import (
 	"go.dataflow.ru/service-sales/pkg/logger"
 )
 
+// maxSeriesDays максимальная длина ряда дневных продаж.
+const maxSeriesDays = 3660
+
+var maxVATRate = decimal.NewFromInt(100)
+
 type SalesService struct {
-	storage ports.SalesStorage
-	logger  *logger.Logger
+	storage  ports.SalesStorage
+	reader   ports.SalesReader // чтение продаж: хранилище или снимок (см. WithSnapshot)
+	rates    ports.ExchangeRates
+	calendar ports.StoreCalendar
+	catalog  ports.Catalog
+	logger   *logger.Logger
+
+	defaultCurrency string
+	validateCatalog bool
+	rules           ports.RuleEngine
+
+	// проверка продаж на аномалии (см. WithAnomalyDetection)
+	detector         ports.AnomalyDetector
+	anomalies        ports.AnomalyQueue
+	anomalyThreshold float64
+	anomalyAction    string
+
+	observer ports.SalesObserver // получатель сохраненных продаж (см. WithSalesObserver)
 }
 
-func NewSaleService(storage ports.SalesStorage, logger *logger.Logger) *SalesService {
-	return &SalesService{
+func NewSaleService(storage ports.SalesStorage, logger *logger.Logger, opts ...Option) *SalesService {
+	s := &SalesService{
 		storage: storage,
+		reader:  storage,
 		logger:  logger,
 	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
 }
 
+// AddSale проверяет и сохраняет продажу. Ошибки проверки полей продажи - domain.ErrInvalidSale,
+// нарушения бизнес-правил (см. WithRules) - domain.ErrRuleViolation.
 func (s *SalesService) AddSale(sale *domain.Sale) error {
+	if err := s.addSale(sale); err != nil {
+		return err
+	}
+
+	s.observe(sale)
+
+	return nil
+}
+
+func (s *SalesService) addSale(sale *domain.Sale) error {
+	if err := s.validate(sale); err != nil {
+		return err
+	}
+
+	if s.detector == nil {
+		return s.storage.AddSale(sale)
+	}
+
+	score := s.detector.Score(sale)
+	if score.Score >= s.anomalyThreshold {
+		return s.reportAnomaly(sale, score)
+	}
+
+	if err := s.storage.AddSale(sale); err != nil {
+		return err
+	}
+
+	s.detector.Observe(sale)
+
+	return nil
+}
+
+// AddAcceptedSale сохраняет продажу из карантина, подтвержденную после проверки. Продажа уже проверена при
+// поступлении и повторно не проверяется, а за время карантина магазин мог принять более поздние продажи,
+// поэтому она вставляется по времени (см. ports.SalesStorage.InsertSale).
+func (s *SalesService) AddAcceptedSale(sale *domain.Sale) error {
+	if err := s.storage.InsertSale(sale); err != nil {
+		return err
+	}
+
+	s.observe(sale)
+
+	return nil
+}
+
+// AddReceipt проверяет и атомарно сохраняет чек: каждая строка проверяется как продажа (см. AddSale),
+// и при ошибке в любой строке чек не сохраняется. Аномальные строки не помещаются в карантин,
+// чтобы не разделять чек, а сохраняются и отмечаются для проверки независимо от режима.
+func (s *SalesService) AddReceipt(receipt *domain.Receipt) error {
+	sales, err := s.addReceipt(receipt)
+	if err != nil {
+		return err
+	}
+
+	s.observe(sales...)
+
+	return nil
+}
+
+// addReceipt проверяет и сохраняет чек и возвращает сохраненные продажи его строк.
+func (s *SalesService) addReceipt(receipt *domain.Receipt) ([]*domain.Sale, error) {
+	if receipt.ID == "" {
+		return nil, fmt.Errorf("%w: receipt id not defined", domain.ErrInvalidSale)
+	}
+
+	if len(receipt.Lines) == 0 {
+		return nil, fmt.Errorf("%w: receipt has no lines", domain.ErrInvalidSale)
+	}
+
+	if receipt.Currency == "" {
+		receipt.Currency = s.defaultCurrency
+	}
+
+	sales := receipt.Sales()
+
+	for i, sale := range sales {
+		if err := s.validate(sale); err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+	}
+
+	var scores []domain.AnomalyScore
+	if s.detector != nil {
+		scores = make([]domain.AnomalyScore, len(sales))
+		for i, sale := range sales {
+			scores[i] = s.detector.Score(sale)
+		}
+	}
+
+	if err := s.storage.AddReceipt(receipt); err != nil {
+		return nil, err
+	}
+
+	if s.detector == nil {
+		return sales, nil
+	}
+
+	for i, sale := range sales {
+		if scores[i].Score >= s.anomalyThreshold {
+			s.flagAnomaly(sale, scores[i])
+		} else {
+			s.detector.Observe(sale)
+		}
+	}
+
+	return sales, nil
+}
+
+// observe передает сохраненные продажи получателю (см. WithSalesObserver).
+func (s *SalesService) observe(sales ...*domain.Sale) {
+	if s.observer != nil {
+		s.observer.SalesAdded(sales)
+	}
+}
+
+// validate проверяет поля продажи, бизнес-правила и наличие магазина и товара в справочнике.
+// Продаже без валюты назначается валюта по умолчанию.
+func (s *SalesService) validate(sale *domain.Sale) error {
 	if sale.SalePrice.IsNegative() {
-		return fmt.Errorf("invalid sale price")
+		return fmt.Errorf("%w: negative price", domain.ErrInvalidSale)
+	}
+
+	if sale.QuantitySold <= 0 {
+		return fmt.Errorf("%w: quantity must be positive", domain.ErrInvalidSale)
+	}
+
+	if sale.Discount.IsNegative() || sale.Discount.GreaterThan(decimal.NewFromInt(sale.QuantitySold).Mul(sale.SalePrice)) {
+		return fmt.Errorf("%w: discount must be between 0 and sale amount", domain.ErrInvalidSale)
+	}
+
+	if sale.VATRate.IsNegative() || sale.VATRate.GreaterThan(maxVATRate) {
+		return fmt.Errorf("%w: vat rate must be between 0 and 100", domain.ErrInvalidSale)
+	}
+
+	if sale.Currency == "" {
+		sale.Currency = s.defaultCurrency
+	}
+
+	if !domain.IsCurrencyCode(sale.Currency) {
+		return fmt.Errorf("%w: currency must be ISO 4217 code", domain.ErrInvalidSale)
+	}
+
+	for _, dimension := range domain.Dimensions() {
+		if err := domain.ValidateDimension(dimension, sale.Dimension(dimension)); err != nil {
+			return fmt.Errorf("%w: %v", domain.ErrInvalidSale, err)
+		}
 	}
 
-	if sale.QuantitySold < 0 {
-		return fmt.Errorf("invalid quantity")
+	if s.rules != nil {
+		if err := s.rules.Check(sale); err != nil {
+			return err
+		}
 	}
 
-	s.storage.AddSale(sale)
+	if s.validateCatalog {
+		if _, err := s.catalog.GetStore(sale.StoreID); err != nil {
+			return fmt.Errorf("store %q: %w", sale.StoreID, err)
+		}
+
+		if _, err := s.catalog.GetProduct(sale.ProductID); err != nil {
+			return fmt.Errorf("product %q: %w", sale.ProductID, err)
+		}
+	}
 
 	return nil
 }
 
-func (s *SalesService) GetSales() []*domain.Sale {
-	return s.storage.GetSales()
+// reportAnomaly помещает аномальную продажу в очередь проверки. Продажа сохраняется сразу
+// или, в режиме карантина, только после подтверждения (тогда возвращается domain.ErrSaleQuarantined).
+// Аномальная продажа не учитывается в статистике до подтверждения.
+func (s *SalesService) reportAnomaly(sale *domain.Sale, score domain.AnomalyScore) error {
+	if s.anomalyAction == domain.AnomalyActionQuarantine {
+		id, err := s.queueAnomaly(sale, score, domain.AnomalyActionQuarantine)
+		if err != nil {
+			return fmt.Errorf("quarantine sale: %w", err)
+		}
+
+		s.logger.Warnf("sale of product %q in store %q quarantined as anomaly %s: %v",
+			sale.ProductID, sale.StoreID, id, score.Reasons)
+
+		return fmt.Errorf("anomaly %s: %w", id, domain.ErrSaleQuarantined)
+	}
+
+	if err := s.storage.AddSale(sale); err != nil {
+		return err
+	}
+
+	s.flagAnomaly(sale, score)
+
+	return nil
+}
+
+// flagAnomaly помещает в очередь проверки уже сохраненную аномальную продажу.
+func (s *SalesService) flagAnomaly(sale *domain.Sale, score domain.AnomalyScore) {
+	if _, err := s.queueAnomaly(sale, score, domain.AnomalyActionFlag); err != nil {
+		s.logger.Errorf("cant save anomaly of product %q in store %q: %v", sale.ProductID, sale.StoreID, err)
+	}
+}
+
+// queueAnomaly помещает продажу в очередь проверки с действием action и возвращает идентификатор аномалии.
+func (s *SalesService) queueAnomaly(sale *domain.Sale, score domain.AnomalyScore, action string) (string, error) {
+	id, err := newAnomalyID()
+	if err != nil {
+		return "", err
+	}
+
+	return id, s.anomalies.SaveAnomaly(&domain.Anomaly{
+		ID:           id,
+		Sale:         sale,
+		Action:       action,
+		Status:       domain.AnomalyPending,
+		DetectedAt:   time.Now(),
+		AnomalyScore: score,
+	})
+}
+
+// OpenSnapshot открывает снимок продаж для согласованного чтения несколькими запросами.
+func (s *SalesService) OpenSnapshot() (domain.Snapshot, error) {
+	return s.storage.OpenSnapshot()
+}
+
+// CloseSnapshot закрывает снимок продаж.
+func (s *SalesService) CloseSnapshot(token string) error {
+	return s.storage.CloseSnapshot(token)
+}
+
+// WithSnapshot возвращает сервис, читающий продажи в снимке token.
+func (s *SalesService) WithSnapshot(token string) (ports.SalesService, error) {
+	reader, err := s.storage.Snapshot(token)
+	if err != nil {
+		return nil, err
+	}
+
+	c := *s
+	c.reader = reader
+
+	return &c, nil
+}
+
+func (s *SalesService) GetSales() ([]*domain.Sale, error) {
+	return s.reader.GetSales()
+}
+
+// ScanSales передает fn все продажи по частям, проверяя отмену ctx во время чтения.
+func (s *SalesService) ScanSales(ctx context.Context, fn func(sale *domain.Sale) error) error {
+	return s.reader.ScanSales(ctx, fn)
+}
+
+func (s *SalesService) GetTotalSum(storeID string, startDate, endDate time.Time) (domain.Totals, error) {
+	return s.reader.GetTotalSum(storeID, startDate, endDate)
+}
+
+// GetConvertedTotalSum возвращает сумму продаж магазина за период, пересчитанную в валюту currency.
+// Период разбивается на интервалы действия курсов, продажи каждого интервала пересчитываются по своему курсу.
+// Пересчитанные суммы округляются до domain.AmountPrecision знаков.
+func (s *SalesService) GetConvertedTotalSum(storeID string, startDate, endDate time.Time, currency string) (domain.Amounts, error) {
+	if s.rates == nil {
+		return domain.Amounts{}, fmt.Errorf("exchange rates not configured")
+	}
+
+	var total domain.Amounts
+
+	from := startDate
+	for _, change := range append(s.rates.Changes(startDate, endDate), endDate.Add(time.Nanosecond)) {
+		// интервал [from, change) с неизменными курсами
+		totals, err := s.reader.GetTotalSum(storeID, from, change.Add(-time.Nanosecond))
+		if err != nil {
+			return domain.Amounts{}, err
+		}
+
+		for saleCurrency, sum := range totals {
+			if sum.IsZero() {
+				continue
+			}
+
+			rate, err := s.rates.Rate(saleCurrency, currency, from)
+			if err != nil {
+				return domain.Amounts{}, fmt.Errorf("convert %s to %s: %w", saleCurrency, currency, err)
+			}
+
+			total = total.Add(sum.Convert(rate))
+		}
+
+		from = change
+	}
+
+	return total.Round(domain.AmountPrecision), nil
+}
+
+// StoreLocation возвращает часовой пояс магазина.
+func (s *SalesService) StoreLocation(storeID string) (*time.Location, error) {
+	if s.calendar == nil {
+		return time.UTC, nil
+	}
+
+	return s.calendar.Location(storeID)
+}
+
+// GetDailyTotals возвращает суммы продаж магазина за каждый день периода [startDay, endDay].
+// Границы дней определяются по часовому поясу магазина с учетом перехода на летнее время.
+func (s *SalesService) GetDailyTotals(storeID string, startDay, endDay domain.Date) ([]domain.DailyTotals, error) {
+	if startDay.After(endDay) {
+		return nil, fmt.Errorf("start date after end date")
+	}
+
+	if startDay.DaysUntil(endDay) >= maxSeriesDays {
+		return nil, fmt.Errorf("period too long")
+	}
+
+	loc, err := s.StoreLocation(storeID)
+	if err != nil {
+		return nil, fmt.Errorf("get store time zone: %w", err)
+	}
+
+	series := make([]domain.DailyTotals, 0, startDay.DaysUntil(endDay)+1)
+
+	for day := startDay; !day.After(endDay); day = day.AddDays(1) {
+		totals, err := s.reader.GetTotalSum(storeID, day.Start(loc), day.End(loc))
+		if err != nil {
+			return nil, err
+		}
+
+		series = append(series, domain.DailyTotals{Date: day, Totals: totals})
+	}
+
+	return series, nil
+}
+
+// GetCategoryTotals возвращает суммы продаж магазина за период в разрезе категорий товаров.
+// Продажи товаров, отсутствующих в справочнике, относятся к категории domain.UnknownCategory.
+func (s *SalesService) GetCategoryTotals(storeID string, period domain.Period) (map[string]domain.Totals, error) {
+	if s.catalog == nil {
+		return nil, fmt.Errorf("catalog not configured")
+	}
+
+	loc, err := s.StoreLocation(storeID)
+	if err != nil {
+		return nil, fmt.Errorf("get store time zone: %w", err)
+	}
+
+	startDate, endDate := period.Resolve(loc)
+
+	byProduct, err := s.reader.GetTotalSumByProduct(storeID, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(map[string]domain.Totals)
+
+	for productID, totals := range byProduct {
+		category := domain.UnknownCategory
+		if product, err := s.catalog.GetProduct(productID); err == nil && product.Category != "" {
+			category = product.Category
+		}
+
+		if _, ok := res[category]; !ok {
+			res[category] = make(domain.Totals)
+		}
+
+		res[category].Add(totals)
+	}
+
+	return res, nil
+}
+
+// GetDimensionTotals возвращает суммы продаж магазина за период в разрезе значений измерения dimension
+// (способа оплаты или канала продаж). Даты периода разрешаются по часовому поясу магазина.
+func (s *SalesService) GetDimensionTotals(storeID, dimension string, period domain.Period) (map[string]domain.Totals, error) {
+	if !domain.IsDimension(dimension) {
+		return nil, fmt.Errorf("unknown dimension %q", dimension)
+	}
+
+	loc, err := s.StoreLocation(storeID)
+	if err != nil {
+		return nil, fmt.Errorf("get store time zone: %w", err)
+	}
+
+	startDate, endDate := period.Resolve(loc)
+
+	return s.reader.GetTotalSumByDimension(storeID, dimension, startDate, endDate)
+}
+
+// Query проверяет и выполняет запрос агрегатов продаж. Ошибки проверки запроса - domain.ErrInvalidQuery,
+// слишком большой результат - domain.ErrQueryTooLarge.
+func (s *SalesService) Query(q domain.Query) (*domain.QueryResult, error) {
+	if err := q.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrInvalidQuery, err)
+	}
+
+	return s.reader.Query(q)
+}
+
+// GetReceiptTotals возвращает показатели чеков магазина за период в разрезе валют: количество чеков,
+// строк и единиц товаров и суммы чеков. Даты периода разрешаются по часовому поясу магазина.
+func (s *SalesService) GetReceiptTotals(storeID string, period domain.Period) (domain.ReceiptTotals, error) {
+	loc, err := s.StoreLocation(storeID)
+	if err != nil {
+		return nil, fmt.Errorf("get store time zone: %w", err)
+	}
+
+	startDate, endDate := period.Resolve(loc)
+
+	return s.reader.GetReceiptTotals(storeID, startDate, endDate)
+}
+
+// GetStoreGroupTotals возвращает суммы продаж за период по магазинам справочника с атрибутами filter,
+// сгруппированные по значению атрибута groupBy (без группировки, если groupBy пустой).
+// Даты периода разрешаются по часовому поясу каждого магазина.
+func (s *SalesService) GetStoreGroupTotals(filter map[string]string, groupBy string, period domain.Period) (map[string]domain.Totals, error) {
+	if s.catalog == nil {
+		return nil, fmt.Errorf("catalog not configured")
+	}
+
+	var (
+		queries []domain.StorePeriod
+		groups  []string
+	)
+
+	for _, store := range s.catalog.GetStores() {
+		if !store.Matches(filter) {
+			continue
+		}
+
+		loc, err := s.StoreLocation(store.ID)
+		if err != nil {
+			return nil, fmt.Errorf("get store %q time zone: %w", store.ID, err)
+		}
+
+		startDate, endDate := period.Resolve(loc)
+
+		group := ""
+		if groupBy != "" {
+			group = store.Attributes[groupBy]
+		}
+
+		queries = append(queries, domain.StorePeriod{StoreID: store.ID, StartDate: startDate, EndDate: endDate})
+		groups = append(groups, group)
+	}
+
+	// суммы всех магазинов запрашиваются одним вызовом: в кластере запросы к узлам выполняются параллельно
+	totals, err := s.reader.GetTotalSums(queries)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(map[string]domain.Totals)
+
+	for i, group := range groups {
+		if _, ok := res[group]; !ok {
+			res[group] = make(domain.Totals)
+		}
+
+		res[group].Add(totals[i])
+	}
+
+	return res, nil
+}
+
+// ComparePeriods сравнивает продажи магазинов за период с предыдущим периодом, полученным по правилу offset
+// (см. domain.Period.Shift). Магазины задаются списком storeIDs, а если он пустой - атрибутами filter
+// магазинов справочника. Даты периодов разрешаются по часовому поясу каждого магазина.
+func (s *SalesService) ComparePeriods(storeIDs []string, filter map[string]string, period domain.Period, offset string) ([]domain.PeriodComparison, error) {
+	previous, err := period.Shift(offset)
+	if err != nil {
+		return nil, err
+	}
+
+	storeIDs, err = s.selectStores(storeIDs, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]domain.PeriodComparison, 0, len(storeIDs))
+
+	// суммы за оба периода по всем магазинам запрашиваются одним вызовом
+	queries := make([]domain.StorePeriod, 0, 2*len(storeIDs))
+
+	for _, storeID := range storeIDs {
+		loc, err := s.StoreLocation(storeID)
+		if err != nil {
+			return nil, fmt.Errorf("get store %q time zone: %w", storeID, err)
+		}
+
+		c := domain.PeriodComparison{StoreID: storeID}
+		c.Current.Start, c.Current.End = period.Resolve(loc)
+		c.Previous.Start, c.Previous.End = previous.Resolve(loc)
+
+		res = append(res, c)
+		queries = append(queries,
+			domain.StorePeriod{StoreID: storeID, StartDate: c.Current.Start, EndDate: c.Current.End},
+			domain.StorePeriod{StoreID: storeID, StartDate: c.Previous.Start, EndDate: c.Previous.End},
+		)
+	}
+
+	totals, err := s.reader.GetTotalSums(queries)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range res {
+		res[i].Currencies = domain.Compare(totals[2*i], totals[2*i+1])
+	}
+
+	return res, nil
 }
 
-func (s *SalesService) GetTotalSum(storeID string, startDate, endDate time.Time) decimal.Decimal {
-	return s.storage.GetTotalSum(storeID, startDate, endDate)
+// GetSalesSketches возвращает приближенную статистику продаж магазинов - различные товары и размеры чеков -
+// за период и по интервалам разбивки bucket (day, week, month; пустой - без разбивки). Магазины задаются
+// списком storeIDs, а если он пустой - атрибутами filter магазинов справочника. Даты периода и интервалы
+// разбивки разрешаются по часовому поясу каждого магазина, интервалы разных магазинов объединяются по дате начала.
+func (s *SalesService) GetSalesSketches(storeIDs []string, filter map[string]string, period domain.Period, bucket string) (*domain.SketchSeries, error) {
+	if err := domain.ValidateSketchBucket(bucket); err != nil {
+		return nil, err
+	}
+
+	storeIDs, err := s.selectStores(storeIDs, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &domain.SketchSeries{Total: domain.NewSalesSketches()}
+	buckets := make(map[domain.Date]*domain.SalesSketches)
+
+	for _, storeID := range storeIDs {
+		loc, err := s.StoreLocation(storeID)
+		if err != nil {
+			return nil, fmt.Errorf("get store %q time zone: %w", storeID, err)
+		}
+
+		q := domain.Query{Bucket: bucket, Location: loc}
+		q.StartDate, q.EndDate = period.Resolve(loc)
+
+		if q.StartDate.After(q.EndDate) {
+			continue
+		}
+
+		if bucket != "" && domain.DateOf(q.StartDate.In(loc)).DaysUntil(domain.DateOf(q.EndDate.In(loc))) >= maxSeriesDays {
+			return nil, fmt.Errorf("period too long")
+		}
+
+		for _, b := range q.Buckets() {
+			sketches, err := s.reader.GetSketches(storeID, b.StartDate, b.EndDate)
+			if err != nil {
+				return nil, err
+			}
+
+			res.Total.Merge(sketches)
+
+			if bucket == "" {
+				continue
+			}
+
+			start := domain.DateOf(q.BucketStart(b.StartDate))
+			if _, ok := buckets[start]; !ok {
+				buckets[start] = domain.NewSalesSketches()
+			}
+
+			buckets[start].Merge(sketches)
+		}
+	}
+
+	for start, sketches := range buckets {
+		res.Buckets = append(res.Buckets, domain.SketchBucket{Start: start, Sketches: sketches})
+	}
+
+	sort.Slice(res.Buckets, func(i, j int) bool {
+		return res.Buckets[i].Start.Before(res.Buckets[j].Start)
+	})
+
+	return res, nil
+}
+
+// selectStores возвращает магазины storeIDs, а если список пустой - магазины справочника с атрибутами filter.
+func (s *SalesService) selectStores(storeIDs []string, filter map[string]string) ([]string, error) {
+	if len(storeIDs) > 0 {
+		return storeIDs, nil
+	}
+
+	if s.catalog == nil {
+		return nil, fmt.Errorf("catalog not configured")
+	}
+
+	for _, store := range s.catalog.GetStores() {
+		if store.Matches(filter) {
+			storeIDs = append(storeIDs, store.ID)
+		}
+	}
+
+	return storeIDs, nil
 }
@@ -1,13 +1,16 @@
 package services
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 	"time"
+	_ "time/tzdata"
 
 	"github.com/golang/mock/gomock"
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.dataflow.ru/service-sales/pkg/logger"
 
 	"go.dataflow.ru/service-sales/internal/app/domain"
@@ -20,6 +23,15 @@ func TestService_AddSale(t *testing.T) {
 	ctrl := gomock.NewController(t)
 
 	successSale := domain.Sale{
+		ProductID:    "product_100",
+		StoreID:      "store_1",
+		QuantitySold: 10,
+		SalePrice:    decimal.NewFromFloat(199),
+		Currency:     "RUB",
+		SaleDate:     time.Date(2024, 6, 20, 10, 0, 0, 0, time.UTC),
+	}
+
+	defaultCurrencySale := domain.Sale{
 		ProductID:    "product_100",
 		StoreID:      "store_1",
 		QuantitySold: 10,
@@ -38,11 +50,36 @@ func TestService_AddSale(t *testing.T) {
 			sale: successSale,
 			storage: func() ports.SalesStorage {
 				storage := NewMockSalesStorage(ctrl)
-				storage.EXPECT().AddSale(&successSale).Return()
+				storage.EXPECT().AddSale(&successSale).Return(nil)
+
+				return storage
+			},
+		},
+		{
+			name: "default currency",
+			sale: defaultCurrencySale,
+			storage: func() ports.SalesStorage {
+				storage := NewMockSalesStorage(ctrl)
+				storage.EXPECT().AddSale(&successSale).Return(nil)
 
 				return storage
 			},
 		},
+		{
+			name: "invalid currency",
+			sale: domain.Sale{
+				ProductID:    "product_100",
+				StoreID:      "store_1",
+				QuantitySold: 10,
+				SalePrice:    decimal.NewFromFloat(199),
+				Currency:     "rub",
+				SaleDate:     time.Date(2024, 6, 20, 10, 0, 0, 0, time.UTC),
+			},
+			storage: func() ports.SalesStorage {
+				return NewMockSalesStorage(ctrl)
+			},
+			err: fmt.Errorf("invalid sale: currency must be ISO 4217 code"),
+		},
 		{
 			name: "negative quantity",
 			sale: domain.Sale{
@@ -55,7 +92,50 @@ func TestService_AddSale(t *testing.T) {
 			storage: func() ports.SalesStorage {
 				return NewMockSalesStorage(ctrl)
 			},
-			err: fmt.Errorf("invalid quantity"),
+			err: fmt.Errorf("invalid sale: quantity must be positive"),
+		},
+		{
+			name: "zero quantity",
+			sale: domain.Sale{
+				ProductID: "product_100",
+				StoreID:   "store_1",
+				SalePrice: decimal.NewFromFloat(199),
+				SaleDate:  time.Date(2024, 6, 20, 10, 0, 0, 0, time.UTC),
+			},
+			storage: func() ports.SalesStorage {
+				return NewMockSalesStorage(ctrl)
+			},
+			err: fmt.Errorf("invalid sale: quantity must be positive"),
+		},
+		{
+			name: "discount exceeds sale amount",
+			sale: domain.Sale{
+				ProductID:    "product_100",
+				StoreID:      "store_1",
+				QuantitySold: 10,
+				SalePrice:    decimal.NewFromFloat(199),
+				Discount:     decimal.NewFromFloat(1990.01),
+				SaleDate:     time.Date(2024, 6, 20, 10, 0, 0, 0, time.UTC),
+			},
+			storage: func() ports.SalesStorage {
+				return NewMockSalesStorage(ctrl)
+			},
+			err: fmt.Errorf("invalid sale: discount must be between 0 and sale amount"),
+		},
+		{
+			name: "invalid vat rate",
+			sale: domain.Sale{
+				ProductID:    "product_100",
+				StoreID:      "store_1",
+				QuantitySold: 10,
+				SalePrice:    decimal.NewFromFloat(199),
+				VATRate:      decimal.NewFromFloat(120),
+				SaleDate:     time.Date(2024, 6, 20, 10, 0, 0, 0, time.UTC),
+			},
+			storage: func() ports.SalesStorage {
+				return NewMockSalesStorage(ctrl)
+			},
+			err: fmt.Errorf("invalid sale: vat rate must be between 0 and 100"),
 		},
 		{
 			name: "negative price",
@@ -69,7 +149,37 @@ func TestService_AddSale(t *testing.T) {
 			storage: func() ports.SalesStorage {
 				return NewMockSalesStorage(ctrl)
 			},
-			err: fmt.Errorf("invalid sale price"),
+			err: fmt.Errorf("invalid sale: negative price"),
+		},
+		{
+			name: "unknown payment method",
+			sale: domain.Sale{
+				ProductID:     "product_100",
+				StoreID:       "store_1",
+				QuantitySold:  10,
+				SalePrice:     decimal.NewFromFloat(199),
+				SaleDate:      time.Date(2024, 6, 20, 10, 0, 0, 0, time.UTC),
+				PaymentMethod: "crypto",
+			},
+			storage: func() ports.SalesStorage {
+				return NewMockSalesStorage(ctrl)
+			},
+			err: fmt.Errorf("invalid sale: payment_method must be one of cash, card, voucher"),
+		},
+		{
+			name: "unknown channel",
+			sale: domain.Sale{
+				ProductID:    "product_100",
+				StoreID:      "store_1",
+				QuantitySold: 10,
+				SalePrice:    decimal.NewFromFloat(199),
+				SaleDate:     time.Date(2024, 6, 20, 10, 0, 0, 0, time.UTC),
+				Channel:      "phone",
+			},
+			storage: func() ports.SalesStorage {
+				return NewMockSalesStorage(ctrl)
+			},
+			err: fmt.Errorf("invalid sale: channel must be one of in_store, online, click_and_collect"),
 		},
 	}
 
@@ -78,7 +188,7 @@ func TestService_AddSale(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
-			saleService := NewSaleService(tt.storage(), logger.NoOpLogger())
+			saleService := NewSaleService(tt.storage(), logger.NoOpLogger(), WithDefaultCurrency("RUB"))
 			err := saleService.AddSale(&tt.sale)
 
 			if tt.err == nil {
@@ -113,10 +223,11 @@ func TestService_GetSales(t *testing.T) {
 		},
 	}
 
-	storage.EXPECT().GetSales().Return(sales)
+	storage.EXPECT().GetSales().Return(sales, nil)
 
 	saleService := NewSaleService(storage, logger.NoOpLogger())
-	actualSales := saleService.GetSales()
+	actualSales, err := saleService.GetSales()
+	assert.NoError(t, err)
 	assert.Equal(t, sales, actualSales)
 }
 
@@ -129,11 +240,493 @@ func TestService_GetTotalSum(t *testing.T) {
 	storeID := "store_1"
 	dateFrom := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
 	dateTo := time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC)
-	total := decimal.NewFromFloat(1001.9)
+	total := domain.Totals{"RUB": domain.Amounts{Gross: decimal.NewFromFloat(1001.9), Net: decimal.NewFromFloat(1001.9)}}
+
+	storage.EXPECT().GetTotalSum(storeID, dateFrom, dateTo).Return(total, nil)
+
+	saleService := NewSaleService(storage, logger.NoOpLogger())
+	actualTotal, err := saleService.GetTotalSum(storeID, dateFrom, dateTo)
+	assert.NoError(t, err)
+	assert.Equal(t, total, actualTotal)
+}
+
+func TestService_GetConvertedTotalSum(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	storage := NewMockSalesStorage(ctrl)
+	rates := NewMockExchangeRates(ctrl)
+
+	storeID := "store_1"
+	dateFrom := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	dateTo := time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC)
+	rateChange := time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC)
+
+	// курс KZT меняется 15 июня: продажи до и после пересчитываются по разным курсам
+	rates.EXPECT().Changes(dateFrom, dateTo).Return([]time.Time{rateChange})
+
+	storage.EXPECT().GetTotalSum(storeID, dateFrom, rateChange.Add(-time.Nanosecond)).
+		Return(domain.Totals{"RUB": grossAmounts(100), "KZT": grossAmounts(1000)}, nil)
+	storage.EXPECT().GetTotalSum(storeID, rateChange, dateTo).
+		Return(domain.Totals{"RUB": grossAmounts(50), "KZT": grossAmounts(1000)}, nil)
+
+	rates.EXPECT().Rate("RUB", "RUB", dateFrom).Return(decimal.NewFromInt(1), nil)
+	rates.EXPECT().Rate("KZT", "RUB", dateFrom).Return(decimal.RequireFromString("0.2"), nil)
+	rates.EXPECT().Rate("RUB", "RUB", rateChange).Return(decimal.NewFromInt(1), nil)
+	rates.EXPECT().Rate("KZT", "RUB", rateChange).Return(decimal.RequireFromString("0.25"), nil)
+
+	saleService := NewSaleService(storage, logger.NoOpLogger(), WithExchangeRates(rates))
+	actualTotal, err := saleService.GetConvertedTotalSum(storeID, dateFrom, dateTo, "RUB")
+	assert.NoError(t, err)
+	assert.Equal(t, "600", actualTotal.Gross.String())
+	assert.Equal(t, "600", actualTotal.Net.String())
+}
+
+func grossAmounts(sum int64) domain.Amounts {
+	return domain.Amounts{Gross: decimal.NewFromInt(sum), Net: decimal.NewFromInt(sum)}
+}
+
+func TestService_GetDailyTotals(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	storage := NewMockSalesStorage(ctrl)
+	calendar := NewMockStoreCalendar(ctrl)
+
+	loc, err := time.LoadLocation("Europe/Berlin")
+	assert.NoError(t, err)
+
+	storeID := "store_1"
+	calendar.EXPECT().Location(storeID).Return(loc, nil)
+
+	// 31 марта в Берлине переход на летнее время: день длится 23 часа
+	storage.EXPECT().GetTotalSum(storeID,
+		time.Date(2024, 3, 30, 0, 0, 0, 0, loc),
+		time.Date(2024, 3, 31, 0, 0, 0, 0, loc).Add(-time.Nanosecond),
+	).Return(domain.Totals{"EUR": grossAmounts(10)}, nil)
+	storage.EXPECT().GetTotalSum(storeID,
+		time.Date(2024, 3, 31, 0, 0, 0, 0, loc),
+		time.Date(2024, 4, 1, 0, 0, 0, 0, loc).Add(-time.Nanosecond),
+	).Return(domain.Totals{"EUR": grossAmounts(20)}, nil)
+
+	saleService := NewSaleService(storage, logger.NoOpLogger(), WithStoreCalendar(calendar))
+	series, err := saleService.GetDailyTotals(storeID,
+		domain.Date{Year: 2024, Month: time.March, Day: 30},
+		domain.Date{Year: 2024, Month: time.March, Day: 31},
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.DailyTotals{
+		{Date: domain.Date{Year: 2024, Month: time.March, Day: 30}, Totals: domain.Totals{"EUR": grossAmounts(10)}},
+		{Date: domain.Date{Year: 2024, Month: time.March, Day: 31}, Totals: domain.Totals{"EUR": grossAmounts(20)}},
+	}, series)
+
+	_, err = saleService.GetDailyTotals(storeID,
+		domain.Date{Year: 2024, Month: time.March, Day: 31},
+		domain.Date{Year: 2024, Month: time.March, Day: 30},
+	)
+	assert.EqualError(t, err, "start date after end date")
+}
+
+func TestService_AddSale_CatalogValidation(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	storage := NewMockSalesStorage(ctrl)
+	catalog := NewMockCatalog(ctrl)
+
+	sale := domain.Sale{
+		ProductID:    "product_100",
+		StoreID:      "store_1",
+		QuantitySold: 1,
+		SalePrice:    decimal.NewFromFloat(199),
+		Currency:     "RUB",
+		SaleDate:     time.Date(2024, 6, 20, 10, 0, 0, 0, time.UTC),
+	}
+
+	catalog.EXPECT().GetStore("store_1").Return(&domain.Store{ID: "store_1"}, nil).Times(2)
+	catalog.EXPECT().GetProduct("product_100").Return(&domain.Product{ID: "product_100"}, nil)
+	catalog.EXPECT().GetProduct("product_101").Return(nil, domain.ErrProductNotFound)
+	storage.EXPECT().AddSale(&sale).Return(nil)
+
+	saleService := NewSaleService(storage, logger.NoOpLogger(), WithCatalog(catalog), WithCatalogValidation())
+	assert.NoError(t, saleService.AddSale(&sale))
+
+	unknown := sale
+	unknown.ProductID = "product_101"
+	assert.ErrorIs(t, saleService.AddSale(&unknown), domain.ErrProductNotFound)
+}
+
+func TestService_AddReceipt(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	storage := NewMockSalesStorage(ctrl)
+	detector := NewMockAnomalyDetector(ctrl)
+	queue := NewMockAnomalyQueue(ctrl)
+
+	receipt := func() *domain.Receipt {
+		return &domain.Receipt{
+			ID:            "receipt_1",
+			StoreID:       "store_1",
+			PaymentMethod: "cash",
+			SaleDate:      time.Date(2024, 6, 20, 10, 0, 0, 0, time.UTC),
+			Lines: []domain.ReceiptLine{
+				{ProductID: "milk", QuantitySold: 2, SalePrice: decimal.NewFromInt(90)},
+				{ProductID: "bread", QuantitySold: 1, SalePrice: decimal.NewFromInt(50)},
+			},
+		}
+	}
+
+	// в режиме карантина аномальная строка чека сохраняется вместе с чеком и отмечается для проверки
+	s := NewSaleService(storage, logger.NoOpLogger(), WithDefaultCurrency("RUB"),
+		WithAnomalyDetection(detector, queue, 6, domain.AnomalyActionQuarantine))
+
+	valid := receipt()
+
+	gomock.InOrder(
+		detector.EXPECT().Score(gomock.Any()).Return(domain.AnomalyScore{Score: 1}),
+		detector.EXPECT().Score(gomock.Any()).Return(domain.AnomalyScore{Score: 8}),
+		storage.EXPECT().AddReceipt(valid).Return(nil),
+	)
+
+	detector.EXPECT().Observe(gomock.Any()).Do(func(sale *domain.Sale) {
+		assert.Equal(t, "milk", sale.ProductID)
+		assert.Equal(t, "receipt_1", sale.ReceiptID)
+		assert.Equal(t, "RUB", sale.Currency)
+	})
+
+	queue.EXPECT().SaveAnomaly(gomock.Any()).DoAndReturn(func(a *domain.Anomaly) error {
+		assert.Equal(t, "bread", a.Sale.ProductID)
+		assert.Equal(t, domain.AnomalyActionFlag, a.Action)
+
+		return nil
+	})
+
+	assert.NoError(t, s.AddReceipt(valid))
+	assert.Equal(t, "RUB", valid.Currency)
+
+	// ошибка в любой строке отклоняет весь чек
+	invalid := receipt()
+	invalid.Lines[1].QuantitySold = 0
+
+	err := s.AddReceipt(invalid)
+	assert.ErrorIs(t, err, domain.ErrInvalidSale)
+	assert.EqualError(t, err, "line 2: invalid sale: quantity must be positive")
+
+	noLines := receipt()
+	noLines.Lines = nil
+	assert.ErrorIs(t, s.AddReceipt(noLines), domain.ErrInvalidSale)
+
+	noID := receipt()
+	noID.ID = ""
+	assert.ErrorIs(t, s.AddReceipt(noID), domain.ErrInvalidSale)
+}
+
+func TestService_SalesObserver(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	storage := NewMockSalesStorage(ctrl)
+	observer := NewMockSalesObserver(ctrl)
+
+	s := NewSaleService(storage, logger.NoOpLogger(), WithDefaultCurrency("RUB"), WithSalesObserver(observer))
+
+	sale := &domain.Sale{
+		StoreID:      "store_1",
+		ProductID:    "milk",
+		QuantitySold: 1,
+		SalePrice:    decimal.NewFromInt(90),
+		SaleDate:     time.Date(2024, 6, 20, 10, 0, 0, 0, time.UTC),
+	}
+
+	gomock.InOrder(
+		storage.EXPECT().AddSale(sale).Return(nil),
+		observer.EXPECT().SalesAdded([]*domain.Sale{sale}),
+	)
+
+	assert.NoError(t, s.AddSale(sale))
+
+	// несохраненные продажи получателю не передаются
+	storage.EXPECT().AddSale(sale).Return(errors.New("storage failure"))
+	assert.Error(t, s.AddSale(sale))
+
+	invalid := *sale
+	invalid.QuantitySold = 0
+	assert.ErrorIs(t, s.AddSale(&invalid), domain.ErrInvalidSale)
+
+	// строки чека передаются одним вызовом
+	receipt := &domain.Receipt{
+		ID:       "receipt_1",
+		StoreID:  "store_1",
+		SaleDate: sale.SaleDate,
+		Lines: []domain.ReceiptLine{
+			{ProductID: "milk", QuantitySold: 2, SalePrice: decimal.NewFromInt(90)},
+			{ProductID: "bread", QuantitySold: 1, SalePrice: decimal.NewFromInt(50)},
+		},
+	}
+
+	storage.EXPECT().AddReceipt(receipt).Return(nil)
+	observer.EXPECT().SalesAdded(gomock.Any()).Do(func(sales []*domain.Sale) {
+		require.Len(t, sales, 2)
+		assert.Equal(t, "receipt_1", sales[1].ReceiptID)
+	})
+
+	assert.NoError(t, s.AddReceipt(receipt))
+}
+
+func TestService_GetCategoryTotals(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	storage := NewMockSalesStorage(ctrl)
+	catalog := NewMockCatalog(ctrl)
+
+	storeID := "store_1"
+	dateFrom := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	dateTo := time.Date(2024, 6, 30, 23, 59, 59, 0, time.UTC)
+
+	storage.EXPECT().GetTotalSumByProduct(storeID, dateFrom, dateTo).Return(map[string]domain.Totals{
+		"milk":    {"RUB": grossAmounts(100)},
+		"kefir":   {"RUB": grossAmounts(50)},
+		"phantom": {"RUB": grossAmounts(7)},
+	}, nil)
+	catalog.EXPECT().GetProduct("milk").Return(&domain.Product{ID: "milk", Category: "dairy"}, nil)
+	catalog.EXPECT().GetProduct("kefir").Return(&domain.Product{ID: "kefir", Category: "dairy"}, nil)
+	catalog.EXPECT().GetProduct("phantom").Return(nil, domain.ErrProductNotFound)
+
+	saleService := NewSaleService(storage, logger.NoOpLogger(), WithCatalog(catalog))
+	groups, err := saleService.GetCategoryTotals(storeID, domain.Period{
+		Start: domain.InstantBound(dateFrom),
+		End:   domain.InstantBound(dateTo),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "150", groups["dairy"]["RUB"].Gross.String())
+	assert.Equal(t, "7", groups[domain.UnknownCategory]["RUB"].Gross.String())
+}
+
+func TestService_GetStoreGroupTotals(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	storage := NewMockSalesStorage(ctrl)
+	catalog := NewMockCatalog(ctrl)
+	calendar := NewMockStoreCalendar(ctrl)
+
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	assert.NoError(t, err)
+
+	day := domain.Date{Year: 2024, Month: time.March, Day: 5}
+
+	catalog.EXPECT().GetStores().Return([]*domain.Store{
+		{ID: "store_1", Attributes: map[string]string{"format": "hyper", "region": "center"}},
+		{ID: "store_2", Attributes: map[string]string{"format": "hyper", "region": "south"}},
+		{ID: "store_3", Attributes: map[string]string{"format": "express", "region": "center"}},
+	})
+	calendar.EXPECT().Location("store_1").Return(moscow, nil)
+	calendar.EXPECT().Location("store_2").Return(time.UTC, nil)
+
+	// дата разрешается по часовому поясу каждого магазина
+	storage.EXPECT().GetTotalSums([]domain.StorePeriod{
+		{StoreID: "store_1", StartDate: day.Start(moscow), EndDate: day.End(moscow)},
+		{StoreID: "store_2", StartDate: day.Start(time.UTC), EndDate: day.End(time.UTC)},
+	}).Return([]domain.Totals{{"RUB": grossAmounts(10)}, {"RUB": grossAmounts(20)}}, nil)
+
+	saleService := NewSaleService(storage, logger.NoOpLogger(), WithCatalog(catalog), WithStoreCalendar(calendar))
+	groups, err := saleService.GetStoreGroupTotals(map[string]string{"format": "hyper"}, "region", domain.Period{
+		Start: domain.DayBound(day),
+		End:   domain.DayBound(day),
+	})
+	assert.NoError(t, err)
+	assert.Len(t, groups, 2)
+	assert.Equal(t, "10", groups["center"]["RUB"].Gross.String())
+	assert.Equal(t, "20", groups["south"]["RUB"].Gross.String())
+}
+
+func TestService_Query(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	storage := NewMockSalesStorage(ctrl)
+
+	q := domain.Query{
+		StartDate: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+		EndDate:   time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC),
+		GroupBy:   []string{domain.FieldProduct},
+		Metrics:   []string{domain.MetricGross},
+	}
+	res := &domain.QueryResult{Plan: "scan"}
+
+	storage.EXPECT().Query(q).Return(res, nil)
+
+	saleService := NewSaleService(storage, logger.NoOpLogger())
+
+	actual, err := saleService.Query(q)
+	assert.NoError(t, err)
+	assert.Equal(t, res, actual)
+
+	// некорректный запрос не передается хранилищу
+	q.GroupBy = []string{"category"}
+	_, err = saleService.Query(q)
+	assert.ErrorIs(t, err, domain.ErrInvalidQuery)
+}
+
+func TestService_WithSnapshot(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	storage := NewMockSalesStorage(ctrl)
+	reader := NewMockSalesReader(ctrl)
+
+	storeID := "store_1"
+	dateFrom := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	dateTo := time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC)
+	total := domain.Totals{"RUB": domain.Amounts{Gross: decimal.NewFromFloat(100), Net: decimal.NewFromFloat(100)}}
 
-	storage.EXPECT().GetTotalSum(storeID, dateFrom, dateTo).Return(total)
+	storage.EXPECT().Snapshot("token").Return(reader, nil)
+	storage.EXPECT().Snapshot("unknown").Return(nil, domain.ErrSnapshotNotFound)
+	reader.EXPECT().GetTotalSum(storeID, dateFrom, dateTo).Return(total, nil)
 
 	saleService := NewSaleService(storage, logger.NoOpLogger())
-	actualTotal := saleService.GetTotalSum(storeID, dateFrom, dateTo)
+
+	_, err := saleService.WithSnapshot("unknown")
+	assert.ErrorIs(t, err, domain.ErrSnapshotNotFound)
+
+	snapshotService, err := saleService.WithSnapshot("token")
+	assert.NoError(t, err)
+
+	// чтение в снимке идет через reader снимка, а не через хранилище
+	actualTotal, err := snapshotService.GetTotalSum(storeID, dateFrom, dateTo)
+	assert.NoError(t, err)
 	assert.Equal(t, total, actualTotal)
 }
+
+func TestService_ComparePeriods(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	storage := NewMockSalesStorage(ctrl)
+	catalog := NewMockCatalog(ctrl)
+	calendar := NewMockStoreCalendar(ctrl)
+
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	assert.NoError(t, err)
+
+	march := domain.Period{
+		Start: domain.DayBound(domain.Date{Year: 2024, Month: time.March, Day: 1}),
+		End:   domain.DayBound(domain.Date{Year: 2024, Month: time.March, Day: 31}),
+	}
+
+	feb1 := domain.Date{Year: 2024, Month: time.February, Day: 1}
+	feb29 := domain.Date{Year: 2024, Month: time.February, Day: 29}
+
+	catalog.EXPECT().GetStores().Return([]*domain.Store{
+		{ID: "store_1", Attributes: map[string]string{"format": "hyper"}},
+		{ID: "store_2", Attributes: map[string]string{"format": "express"}},
+		{ID: "store_3", Attributes: map[string]string{"format": "hyper"}},
+	})
+	calendar.EXPECT().Location("store_1").Return(moscow, nil)
+	calendar.EXPECT().Location("store_3").Return(time.UTC, nil)
+
+	// периоды разрешаются по часовому поясу каждого магазина, суммы запрашиваются одним вызовом
+	mar1, mar31 := march.Resolve(moscow)
+	mar3, mar33 := march.Resolve(time.UTC)
+
+	storage.EXPECT().GetTotalSums([]domain.StorePeriod{
+		{StoreID: "store_1", StartDate: mar1, EndDate: mar31},
+		{StoreID: "store_1", StartDate: feb1.Start(moscow), EndDate: feb29.End(moscow)},
+		{StoreID: "store_3", StartDate: mar3, EndDate: mar33},
+		{StoreID: "store_3", StartDate: feb1.Start(time.UTC), EndDate: feb29.End(time.UTC)},
+	}).Return([]domain.Totals{
+		{"RUB": grossAmounts(120)},
+		{"RUB": grossAmounts(100)},
+		{"RUB": grossAmounts(30)},
+		{},
+	}, nil)
+
+	saleService := NewSaleService(storage, logger.NoOpLogger(), WithCatalog(catalog), WithStoreCalendar(calendar))
+	comparisons, err := saleService.ComparePeriods(nil, map[string]string{"format": "hyper"}, march, domain.OffsetPreviousMonth)
+	assert.NoError(t, err)
+	assert.Len(t, comparisons, 2)
+
+	assert.Equal(t, "store_1", comparisons[0].StoreID)
+	assert.Equal(t, feb1.Start(moscow), comparisons[0].Previous.Start)
+	assert.Equal(t, "20", comparisons[0].Currencies["RUB"].Delta.Gross.String())
+	assert.Equal(t, "20", comparisons[0].Currencies["RUB"].GrossPercent.String())
+
+	assert.Equal(t, "store_3", comparisons[1].StoreID)
+	assert.Equal(t, "30", comparisons[1].Currencies["RUB"].Delta.Gross.String())
+	assert.Nil(t, comparisons[1].Currencies["RUB"].GrossPercent)
+
+	_, err = saleService.ComparePeriods([]string{"store_1"}, nil, march, "previous_decade")
+	assert.Error(t, err)
+}
+
+func TestService_GetSalesSketches(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	storage := NewMockSalesStorage(ctrl)
+	calendar := NewMockStoreCalendar(ctrl)
+
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	assert.NoError(t, err)
+
+	// 1 марта 2024 - пятница: первая неделя периода неполная
+	mar1 := domain.Date{Year: 2024, Month: time.March, Day: 1}
+	period := domain.Period{Start: domain.DayBound(mar1), End: domain.DayBound(mar1.AddDays(9))}
+
+	sketches := func(currency string, tickets []float64, products ...string) *domain.SalesSketches {
+		s := domain.NewSalesSketches()
+
+		for _, p := range products {
+			s.AddProduct(p)
+		}
+
+		for _, size := range tickets {
+			s.AddTicket(currency, size)
+		}
+
+		return s
+	}
+
+	calendar.EXPECT().Location("store_1").Return(moscow, nil).AnyTimes()
+	calendar.EXPECT().Location("store_2").Return(time.UTC, nil).AnyTimes()
+
+	// интервалы разбивки разрешаются по часовому поясу каждого магазина
+	storage.EXPECT().GetSketches("store_1", mar1.Start(moscow), mar1.AddDays(2).End(moscow)).
+		Return(sketches("RUB", []float64{100, 200}, "milk", "bread"), nil)
+	storage.EXPECT().GetSketches("store_1", mar1.AddDays(3).Start(moscow), mar1.AddDays(9).End(moscow)).
+		Return(sketches("RUB", []float64{300}, "milk"), nil)
+	storage.EXPECT().GetSketches("store_2", mar1.Start(time.UTC), mar1.AddDays(2).End(time.UTC)).
+		Return(sketches("USD", []float64{5}, "milk", "eggs"), nil)
+	storage.EXPECT().GetSketches("store_2", mar1.AddDays(3).Start(time.UTC), mar1.AddDays(9).End(time.UTC)).
+		Return(domain.NewSalesSketches(), nil)
+
+	saleService := NewSaleService(storage, logger.NoOpLogger(), WithStoreCalendar(calendar))
+
+	series, err := saleService.GetSalesSketches([]string{"store_1", "store_2"}, nil, period, domain.BucketWeek)
+	assert.NoError(t, err)
+
+	// статистика магазинов объединяется: товары не повторяются, чеки складываются по валютам
+	assert.Equal(t, uint64(3), series.Total.Products.Estimate())
+	assert.Equal(t, int64(3), series.Total.Tickets["RUB"].Count())
+	assert.Equal(t, 200.0, series.Total.Tickets["RUB"].Quantile(0.5))
+	assert.Equal(t, int64(1), series.Total.Tickets["USD"].Count())
+
+	assert.Len(t, series.Buckets, 2)
+	assert.Equal(t, domain.Date{Year: 2024, Month: time.February, Day: 26}, series.Buckets[0].Start)
+	assert.Equal(t, uint64(3), series.Buckets[0].Sketches.Products.Estimate())
+	assert.Equal(t, mar1.AddDays(3), series.Buckets[1].Start)
+	assert.Equal(t, uint64(1), series.Buckets[1].Sketches.Products.Estimate())
+	assert.NotContains(t, series.Buckets[1].Sketches.Tickets, "USD")
+
+	_, err = saleService.GetSalesSketches([]string{"store_1"}, nil, period, domain.BucketHour)
+	assert.ErrorContains(t, err, `unknown bucket "hour"`)
+
+	long := domain.Period{Start: domain.DayBound(mar1), End: domain.DayBound(mar1.AddDays(maxSeriesDays))}
+	_, err = saleService.GetSalesSketches([]string{"store_1"}, nil, long, domain.BucketMonth)
+	assert.ErrorContains(t, err, "period too long")
+
+	_, err = saleService.GetSalesSketches(nil, map[string]string{"format": "hyper"}, period, "")
+	assert.ErrorContains(t, err, "catalog not configured")
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+	"go.dataflow.ru/service-sales/internal/app/ports"
+	"go.dataflow.ru/service-sales/pkg/logger"
+	"go.dataflow.ru/service-sales/pkg/sqlengine"
+)
+
+const (
+	defaultSQLTimeout = 5 * time.Second
+	defaultSQLMaxRows = 10000
+
+	// salesTableName единственная таблица SQL-запросов
+	salesTableName = "sales"
+)
+
+// salesColumns столбцы таблицы продаж. Суммы вычисляются по правилам domain.Amounts, пустые чек, способ
+// оплаты и канал - NULL.
+var salesColumns = []string{
+	"sale_date", "store_id", "product_id", "quantity", "price", "discount", "vat_rate", "currency",
+	"receipt_id", "payment_method", "channel", "gross", "tax", "net",
+}
+
+// SQLService выполнение SQL-запросов только на чтение к виртуальной таблице продаж sales.
+type SQLService struct {
+	sales  ports.SalesService
+	logger *logger.Logger
+
+	timeout time.Duration
+	maxRows int
+}
+
+type SQLOption func(s *SQLService)
+
+// WithSQLTimeout задает максимальное время выполнения запроса.
+func WithSQLTimeout(timeout time.Duration) SQLOption {
+	return func(s *SQLService) {
+		if timeout > 0 {
+			s.timeout = timeout
+		}
+	}
+}
+
+// WithSQLMaxRows задает максимальное количество строк результата, лишние строки отбрасываются.
+func WithSQLMaxRows(n int) SQLOption {
+	return func(s *SQLService) {
+		if n > 0 {
+			s.maxRows = n
+		}
+	}
+}
+
+func NewSQLService(sales ports.SalesService, logger *logger.Logger, opts ...SQLOption) *SQLService {
+	s := &SQLService{
+		sales:   sales,
+		logger:  logger,
+		timeout: defaultSQLTimeout,
+		maxRows: defaultSQLMaxRows,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Query выполняет запрос SELECT к таблице sales. Ошибки запроса - domain.ErrInvalidQuery, превышение
+// времени выполнения - domain.ErrQueryTimeout.
+func (s *SQLService) Query(ctx context.Context, query, snapshot string) (*domain.SQLResult, error) {
+	stmt, err := sqlengine.Parse(query)
+	if err != nil {
+		return nil, sqlError{err}
+	}
+
+	if stmt.From != salesTableName {
+		return nil, fmt.Errorf("%w: unknown table %s", domain.ErrInvalidQuery, stmt.From)
+	}
+
+	sales := s.sales
+	if snapshot != "" {
+		if sales, err = sales.WithSnapshot(snapshot); err != nil {
+			return nil, err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	res, err := sqlengine.Execute(ctx, stmt, &salesTable{sales: sales}, s.maxRows)
+
+	switch {
+	case errors.Is(err, sqlengine.ErrInvalidQuery):
+		return nil, sqlError{err}
+	case errors.Is(err, context.DeadlineExceeded):
+		return nil, fmt.Errorf("%w: exceeded %s", domain.ErrQueryTimeout, s.timeout)
+	case err != nil:
+		return nil, err
+	}
+
+	return &domain.SQLResult{Columns: res.Columns, Rows: res.Rows, Truncated: res.Truncated}, nil
+}
+
+// sqlError ошибка разбора или выполнения запроса движком SQL, распознаваемая как domain.ErrInvalidQuery.
+type sqlError struct {
+	error
+}
+
+func (e sqlError) Is(target error) bool {
+	return target == domain.ErrInvalidQuery
+}
+
+// salesTable таблица продаж для движка SQL.
+type salesTable struct {
+	sales ports.SalesService
+}
+
+func (t *salesTable) Columns() []string {
+	return salesColumns
+}
+
+func (t *salesTable) Scan(ctx context.Context, fn func(row []sqlengine.Value) error) error {
+	row := make([]sqlengine.Value, len(salesColumns))
+
+	// продажи читаются по частям, поэтому запрос прерывается по таймауту и во время чтения с диска
+	return t.sales.ScanSales(ctx, func(sale *domain.Sale) error {
+		amounts := sale.Amounts()
+
+		row[0] = sale.SaleDate
+		row[1] = sale.StoreID
+		row[2] = sale.ProductID
+		row[3] = decimal.NewFromInt(sale.QuantitySold)
+		row[4] = sale.SalePrice
+		row[5] = sale.Discount
+		row[6] = sale.VATRate
+		row[7] = sale.Currency
+		row[8] = nullable(sale.ReceiptID)
+		row[9] = nullable(sale.PaymentMethod)
+		row[10] = nullable(sale.Channel)
+		row[11] = amounts.Gross
+		row[12] = amounts.Tax
+		row[13] = amounts.Net
+
+		return fn(row)
+	})
+}
+
+// nullable возвращает NULL вместо пустой строки.
+func nullable(s string) sqlengine.Value {
+	if s == "" {
+		return nil
+	}
+
+	return s
+}
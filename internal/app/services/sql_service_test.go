@@ -0,0 +1,179 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.dataflow.ru/service-sales/pkg/logger"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+)
+
+func TestSQLService_Query(t *testing.T) {
+	t.Parallel()
+
+	dt := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+
+	sales := []*domain.Sale{
+		{StoreID: "store_1", ProductID: "product_1", QuantitySold: 2, SalePrice: decimal.NewFromInt(100), VATRate: decimal.NewFromInt(20), Currency: "RUB", SaleDate: dt, PaymentMethod: domain.PaymentCard},
+		{StoreID: "store_1", ProductID: "product_2", QuantitySold: 1, SalePrice: decimal.NewFromInt(50), Currency: "RUB", SaleDate: dt.Add(time.Hour)},
+		{StoreID: "store_2", ProductID: "product_1", QuantitySold: 3, SalePrice: decimal.NewFromInt(10), Discount: decimal.NewFromInt(5), Currency: "RUB", SaleDate: dt.Add(2 * time.Hour), ReceiptID: "r1", PaymentMethod: domain.PaymentCash},
+	}
+
+	testCases := []struct {
+		name      string
+		query     string
+		snapshot  string
+		opts      []SQLOption
+		mock      func(sales *MockSalesService, snapshot *MockSalesService)
+		columns   []string
+		rows      [][]string
+		truncated bool
+		error     error
+	}{
+		{
+			name:  "группировка с суммами продаж",
+			query: "SELECT store_id, sum(gross) AS gross, sum(net), count(payment_method) FROM sales GROUP BY store_id ORDER BY gross DESC",
+			mock: func(s *MockSalesService, _ *MockSalesService) {
+				s.EXPECT().ScanSales(gomock.Any(), gomock.Any()).DoAndReturn(scanSales(sales))
+			},
+			columns: []string{"store_id", "gross", "sum(net)", "count(payment_method)"},
+			rows:    [][]string{{"store_1", "250", "216.67", "1"}, {"store_2", "30", "25", "1"}},
+		},
+		{
+			name:  "пустые поля продажи - NULL",
+			query: "SELECT product_id, quantity, discount FROM sales WHERE receipt_id IS NULL AND payment_method IS NULL",
+			mock: func(s *MockSalesService, _ *MockSalesService) {
+				s.EXPECT().ScanSales(gomock.Any(), gomock.Any()).DoAndReturn(scanSales(sales))
+			},
+			columns: []string{"product_id", "quantity", "discount"},
+			rows:    [][]string{{"product_2", "1", "0"}},
+		},
+		{
+			name:     "запрос в снимке",
+			query:    "SELECT count(*) FROM sales",
+			snapshot: "token",
+			mock: func(s *MockSalesService, snapshot *MockSalesService) {
+				s.EXPECT().WithSnapshot("token").Return(snapshot, nil)
+				snapshot.EXPECT().ScanSales(gomock.Any(), gomock.Any()).DoAndReturn(scanSales(sales[:1]))
+			},
+			columns: []string{"count(*)"},
+			rows:    [][]string{{"1"}},
+		},
+		{
+			name:  "результат усекается до максимального количества строк",
+			query: "SELECT store_id FROM sales ORDER BY sale_date DESC",
+			opts:  []SQLOption{WithSQLMaxRows(2)},
+			mock: func(s *MockSalesService, _ *MockSalesService) {
+				s.EXPECT().ScanSales(gomock.Any(), gomock.Any()).DoAndReturn(scanSales(sales))
+			},
+			columns:   []string{"store_id"},
+			rows:      [][]string{{"store_2"}, {"store_1"}},
+			truncated: true,
+		},
+		{
+			name:  "синтаксическая ошибка",
+			query: "DELETE FROM sales",
+			error: domain.ErrInvalidQuery,
+		},
+		{
+			name:  "неизвестная таблица",
+			query: "SELECT * FROM receipts",
+			error: domain.ErrInvalidQuery,
+		},
+		{
+			name:  "неизвестный столбец",
+			query: "SELECT price * quantity - total FROM sales",
+			mock: func(s *MockSalesService, _ *MockSalesService) {
+				s.EXPECT().ScanSales(gomock.Any(), gomock.Any()).DoAndReturn(scanSales(sales)).AnyTimes()
+			},
+			error: domain.ErrInvalidQuery,
+		},
+		{
+			name:     "снимок не найден",
+			query:    "SELECT * FROM sales",
+			snapshot: "unknown",
+			mock: func(s *MockSalesService, _ *MockSalesService) {
+				s.EXPECT().WithSnapshot("unknown").Return(nil, domain.ErrSnapshotNotFound)
+			},
+			error: domain.ErrSnapshotNotFound,
+		},
+		{
+			name:  "превышено время выполнения",
+			query: "SELECT * FROM sales",
+			opts:  []SQLOption{WithSQLTimeout(time.Millisecond)},
+			mock: func(s *MockSalesService, _ *MockSalesService) {
+				// чтение продаж прерывается по таймауту, не дожидаясь загрузки всех продаж
+				s.EXPECT().ScanSales(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, fn func(sale *domain.Sale) error) error {
+						<-ctx.Done()
+						return ctx.Err()
+					})
+			},
+			error: domain.ErrQueryTimeout,
+		},
+	}
+
+	for _, tt := range testCases {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			salesService := NewMockSalesService(ctrl)
+			snapshot := NewMockSalesService(ctrl)
+
+			if tt.mock != nil {
+				tt.mock(salesService, snapshot)
+			}
+
+			sqlService := NewSQLService(salesService, logger.NoOpLogger(), tt.opts...)
+
+			res, err := sqlService.Query(context.Background(), tt.query, tt.snapshot)
+			if tt.error != nil {
+				assert.ErrorIs(t, err, tt.error)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.columns, res.Columns)
+			assert.Equal(t, tt.truncated, res.Truncated)
+
+			rows := make([][]string, 0, len(res.Rows))
+			for _, row := range res.Rows {
+				values := make([]string, 0, len(row))
+				for _, v := range row {
+					if d, ok := v.(decimal.Decimal); ok {
+						v = d.Round(2)
+					}
+
+					values = append(values, fmt.Sprint(v))
+				}
+
+				rows = append(rows, values)
+			}
+
+			assert.Equal(t, tt.rows, rows)
+		})
+	}
+}
+
+// scanSales возвращает реализацию SalesService.ScanSales, передающую продажи sales.
+func scanSales(sales []*domain.Sale) func(ctx context.Context, fn func(sale *domain.Sale) error) error {
+	return func(ctx context.Context, fn func(sale *domain.Sale) error) error {
+		for _, sale := range sales {
+			if err := fn(sale); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+	"go.dataflow.ru/service-sales/internal/app/ports"
+	"go.dataflow.ru/service-sales/pkg/logger"
+)
+
+type TargetService struct {
+	storage ports.TargetStorage
+	sales   ports.SalesService
+	logger  *logger.Logger
+
+	now func() time.Time
+}
+
+func NewTargetService(storage ports.TargetStorage, sales ports.SalesService, logger *logger.Logger) *TargetService {
+	return &TargetService{
+		storage: storage,
+		sales:   sales,
+		logger:  logger,
+		now:     time.Now,
+	}
+}
+
+// ImportTargets создает или заменяет планы. Если хотя бы один план некорректен или пересекается с другим планом
+// магазина, не сохраняется ни один.
+func (s *TargetService) ImportTargets(targets []*domain.Target) error {
+	// планы, которые будут у магазинов после сохранения, по магазину и дате начала
+	byStore := make(map[string]map[domain.Date]*domain.Target)
+
+	for _, target := range targets {
+		if target.Metric == "" {
+			target.Metric = domain.MetricGross
+		}
+
+		if err := target.Validate(); err != nil {
+			return err
+		}
+
+		if _, ok := byStore[target.StoreID]; !ok {
+			byStore[target.StoreID] = make(map[domain.Date]*domain.Target)
+
+			for _, existing := range s.storage.GetTargets(target.StoreID) {
+				byStore[target.StoreID][existing.Start] = existing
+			}
+		}
+
+		byStore[target.StoreID][target.Start] = target
+	}
+
+	for storeID, targets := range byStore {
+		for _, a := range targets {
+			for _, b := range targets {
+				if a != b && a.Overlaps(b) {
+					return fmt.Errorf("store %q: %s - %s and %s - %s: %w", storeID, a.Start, a.End, b.Start, b.End, domain.ErrTargetOverlap)
+				}
+			}
+		}
+	}
+
+	return s.storage.SaveTargets(targets...)
+}
+
+// DeleteTarget удаляет план магазина с датой начала start.
+func (s *TargetService) DeleteTarget(storeID string, start domain.Date) error {
+	return s.storage.DeleteTarget(storeID, start)
+}
+
+// GetTargets возвращает планы магазина или всех магазинов, если storeID пустой.
+func (s *TargetService) GetTargets(storeID string) []*domain.Target {
+	return s.storage.GetTargets(storeID)
+}
+
+// GetAttainment возвращает выполнение плана магазина, период которого содержит дату day магазина
+// (nil - текущую дату магазина). Факт считается с начала периода плана по текущий момент (для прошедшей даты -
+// по конец дня day), прогноз - экстраполяция факта на весь период плана с тем же темпом продаж.
+func (s *TargetService) GetAttainment(storeID string, day *domain.Date) (domain.TargetAttainment, error) {
+	loc, err := s.sales.StoreLocation(storeID)
+	if err != nil {
+		return domain.TargetAttainment{}, fmt.Errorf("get store time zone: %w", err)
+	}
+
+	now := s.now().In(loc)
+
+	asOf, until := domain.DateOf(now), now
+	if day != nil && *day != asOf {
+		asOf, until = *day, day.End(loc)
+	}
+
+	var target *domain.Target
+
+	for _, t := range s.storage.GetTargets(storeID) {
+		if t.Contains(asOf) {
+			target = t
+			break
+		}
+	}
+
+	if target == nil {
+		return domain.TargetAttainment{}, fmt.Errorf("store %q on %s: %w", storeID, asOf, domain.ErrTargetNotFound)
+	}
+
+	start, end := target.Start.Start(loc), target.End.End(loc)
+	if until.After(end) {
+		until = end
+	}
+
+	actual, err := s.actual(target, start, until)
+	if err != nil {
+		return domain.TargetAttainment{}, err
+	}
+
+	res := domain.TargetAttainment{
+		Target:      target,
+		AsOf:        asOf,
+		Actual:      actual,
+		Attainment:  percent(actual, target.Amount),
+		Projection:  actual,
+		ElapsedDays: target.Start.DaysUntil(asOf) + 1,
+		TotalDays:   target.Start.DaysUntil(target.End) + 1,
+	}
+
+	// темп продаж - выручка за прошедшую часть периода плана
+	if elapsed := until.Sub(start); elapsed > 0 {
+		res.Projection = actual.Mul(decimal.NewFromInt(int64(end.Sub(start)))).
+			Div(decimal.NewFromInt(int64(elapsed))).
+			Round(domain.AmountPrecision)
+	}
+
+	res.ProjectedAttainment = percent(res.Projection, target.Amount)
+
+	return res, nil
+}
+
+// actual возвращает выручку магазина за период в валюте плана. Продажи в других валютах пересчитываются по курсам.
+func (s *TargetService) actual(target *domain.Target, startDate, endDate time.Time) (decimal.Decimal, error) {
+	totals, err := s.sales.GetTotalSum(target.StoreID, startDate, endDate)
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+
+	for currency, amounts := range totals {
+		if currency != target.Currency && !amounts.IsZero() {
+			converted, err := s.sales.GetConvertedTotalSum(target.StoreID, startDate, endDate, target.Currency)
+			if err != nil {
+				return decimal.Decimal{}, err
+			}
+
+			return target.Value(converted), nil
+		}
+	}
+
+	return target.Value(totals[target.Currency]), nil
+}
+
+// percent возвращает value в процентах от base с точностью до сотых (0 при нулевом base).
+func percent(value, base decimal.Decimal) decimal.Decimal {
+	if base.IsZero() {
+		return decimal.Zero
+	}
+
+	return value.Mul(decimal.NewFromInt(100)).Div(base).Round(2)
+}
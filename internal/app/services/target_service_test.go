@@ -0,0 +1,196 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"go.dataflow.ru/service-sales/pkg/logger"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
+)
+
+func TestTargetService_GetAttainment(t *testing.T) {
+	t.Parallel()
+
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	assert.NoError(t, err)
+
+	april := &domain.Target{
+		StoreID:  "store_1",
+		Start:    domain.Date{Year: 2024, Month: time.April, Day: 1},
+		End:      domain.Date{Year: 2024, Month: time.April, Day: 30},
+		Amount:   decimal.NewFromInt(3000),
+		Currency: "RUB",
+		Metric:   domain.MetricGross,
+	}
+
+	day := func(d int) *domain.Date {
+		return &domain.Date{Year: 2024, Month: time.April, Day: d}
+	}
+
+	testCases := []struct {
+		name  string
+		now   time.Time
+		day   *domain.Date
+		mock  func(sales *MockSalesService)
+		exp   domain.TargetAttainment
+		error error
+	}{
+		{
+			name: "середина периода: прогноз по темпу продаж",
+			now:  time.Date(2024, 4, 10, 12, 0, 0, 0, moscow),
+			mock: func(sales *MockSalesService) {
+				sales.EXPECT().GetTotalSum("store_1", april.Start.Start(moscow), time.Date(2024, 4, 10, 12, 0, 0, 0, moscow)).
+					Return(domain.Totals{"RUB": grossAmounts(1000)}, nil)
+			},
+			exp: domain.TargetAttainment{
+				AsOf:                *day(10),
+				Actual:              decimal.NewFromInt(1000),
+				Attainment:          decimal.RequireFromString("33.33"),
+				Projection:          decimal.RequireFromString("3157.89"), // 1000 * 30 / 9.5
+				ProjectedAttainment: decimal.RequireFromString("105.26"),
+				ElapsedDays:         10,
+				TotalDays:           30,
+			},
+		},
+		{
+			name: "прошедший день: факт по конец дня, прогноз по темпу до конца дня",
+			now:  time.Date(2024, 5, 5, 0, 0, 0, 0, moscow),
+			day:  day(15),
+			mock: func(sales *MockSalesService) {
+				sales.EXPECT().GetTotalSum("store_1", april.Start.Start(moscow), day(15).End(moscow)).
+					Return(domain.Totals{"RUB": grossAmounts(1500)}, nil)
+			},
+			exp: domain.TargetAttainment{
+				AsOf:                *day(15),
+				Actual:              decimal.NewFromInt(1500),
+				Attainment:          decimal.NewFromInt(50),
+				Projection:          decimal.NewFromInt(3000),
+				ProjectedAttainment: decimal.NewFromInt(100),
+				ElapsedDays:         15,
+				TotalDays:           30,
+			},
+		},
+		{
+			name: "продажи в другой валюте пересчитываются в валюту плана",
+			now:  time.Date(2024, 5, 5, 0, 0, 0, 0, moscow),
+			day:  day(30),
+			mock: func(sales *MockSalesService) {
+				sales.EXPECT().GetTotalSum("store_1", gomock.Any(), gomock.Any()).
+					Return(domain.Totals{"RUB": grossAmounts(1000), "USD": grossAmounts(10)}, nil)
+				sales.EXPECT().GetConvertedTotalSum("store_1", april.Start.Start(moscow), april.End.End(moscow), "RUB").
+					Return(grossAmounts(1900), nil)
+			},
+			exp: domain.TargetAttainment{
+				AsOf:                *day(30),
+				Actual:              decimal.NewFromInt(1900),
+				Attainment:          decimal.RequireFromString("63.33"),
+				Projection:          decimal.NewFromInt(1900),
+				ProjectedAttainment: decimal.RequireFromString("63.33"),
+				ElapsedDays:         30,
+				TotalDays:           30,
+			},
+		},
+		{
+			name:  "нет плана на дату",
+			now:   time.Date(2024, 5, 5, 0, 0, 0, 0, moscow),
+			mock:  func(sales *MockSalesService) {},
+			error: domain.ErrTargetNotFound,
+		},
+	}
+
+	for _, tt := range testCases {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			storage := NewMockTargetStorage(ctrl)
+			sales := NewMockSalesService(ctrl)
+
+			storage.EXPECT().GetTargets("store_1").Return([]*domain.Target{april})
+			sales.EXPECT().StoreLocation("store_1").Return(moscow, nil)
+			tt.mock(sales)
+
+			s := NewTargetService(storage, sales, logger.NoOpLogger())
+			s.now = func() time.Time { return tt.now }
+
+			res, err := s.GetAttainment("store_1", tt.day)
+			if tt.error != nil {
+				assert.ErrorIs(t, err, tt.error)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, april, res.Target)
+			assert.Equal(t, tt.exp.AsOf, res.AsOf)
+			assert.Equal(t, tt.exp.Actual.String(), res.Actual.String())
+			assert.Equal(t, tt.exp.Attainment.String(), res.Attainment.String())
+			assert.Equal(t, tt.exp.Projection.String(), res.Projection.String())
+			assert.Equal(t, tt.exp.ProjectedAttainment.String(), res.ProjectedAttainment.String())
+			assert.Equal(t, tt.exp.ElapsedDays, res.ElapsedDays)
+			assert.Equal(t, tt.exp.TotalDays, res.TotalDays)
+		})
+	}
+}
+
+func TestTargetService_ImportTargets(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	storage := NewMockTargetStorage(ctrl)
+
+	march := &domain.Target{
+		StoreID:  "store_1",
+		Start:    domain.Date{Year: 2024, Month: time.March, Day: 1},
+		End:      domain.Date{Year: 2024, Month: time.March, Day: 31},
+		Amount:   decimal.NewFromInt(100),
+		Currency: "RUB",
+		Metric:   domain.MetricGross,
+	}
+
+	storage.EXPECT().GetTargets("store_1").Return([]*domain.Target{march}).Times(2)
+
+	s := NewTargetService(storage, nil, logger.NoOpLogger())
+
+	// план пересекается с мартовским
+	err := s.ImportTargets([]*domain.Target{{
+		StoreID:  "store_1",
+		Start:    domain.Date{Year: 2024, Month: time.March, Day: 15},
+		End:      domain.Date{Year: 2024, Month: time.April, Day: 14},
+		Amount:   decimal.NewFromInt(100),
+		Currency: "RUB",
+	}})
+	assert.ErrorIs(t, err, domain.ErrTargetOverlap)
+
+	// некорректный план
+	err = s.ImportTargets([]*domain.Target{{
+		StoreID:  "store_1",
+		Start:    domain.Date{Year: 2024, Month: time.April, Day: 1},
+		End:      domain.Date{Year: 2024, Month: time.April, Day: 30},
+		Amount:   decimal.NewFromInt(100),
+		Currency: "rubles",
+	}})
+	assert.Error(t, err)
+
+	// замена мартовского плана и апрельский план; метрика по умолчанию - валовая выручка
+	replaced := *march
+	replaced.Amount = decimal.NewFromInt(200)
+
+	april := &domain.Target{
+		StoreID:  "store_1",
+		Start:    domain.Date{Year: 2024, Month: time.April, Day: 1},
+		End:      domain.Date{Year: 2024, Month: time.April, Day: 30},
+		Amount:   decimal.NewFromInt(100),
+		Currency: "RUB",
+	}
+
+	storage.EXPECT().SaveTargets(&replaced, april).Return(nil)
+
+	assert.NoError(t, s.ImportTargets([]*domain.Target{&replaced, april}))
+	assert.Equal(t, domain.MetricGross, april.Metric)
+}
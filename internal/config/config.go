@@ -2,18 +2,224 @@ package config
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/caarlos0/env/v6"
+
+	"go.dataflow.ru/service-sales/internal/app/domain"
 )
 
 type Config struct {
-	Server Server
+	Server      Server
+	Admin       Admin
+	Limits      Limits
+	Currency    Currency
+	Catalog     Catalog
+	Rules       Rules
+	Targets     Targets
+	Anomalies   Anomalies
+	Alerts      Alerts
+	Reports     Reports
+	Storage     Storage
+	Purge       Purge
+	Replication Replication
+	Cluster     Cluster
+	SQL         SQL
 }
 
 type Server struct {
 	Port int `env:"PORT" envDefault:"8005"`
 }
 
+// Admin настройки административного API /admin/*.
+type Admin struct {
+	// токен администратора в заголовке X-Admin-Token, без него административный API недоступен
+	Token string `env:"ADMIN_TOKEN"`
+}
+
+// Limits ограничения на входящие запросы.
+type Limits struct {
+	APIKeyHeader string `env:"API_KEY_HEADER" envDefault:"X-API-Key"`
+
+	// API-ключи клиентов с отдельным лимитом, запросы с другими ключами лимитируются по IP-адресу
+	APIKeys []string `env:"API_KEYS" envSeparator:","`
+
+	// запросов в секунду и размер корзины для клиентов с API-ключом
+	APIKeyRate  float64 `env:"RATE_LIMIT_API_KEY_RPS" envDefault:"100"`
+	APIKeyBurst int     `env:"RATE_LIMIT_API_KEY_BURST" envDefault:"200"`
+
+	// запросов в секунду и размер корзины для клиентов без API-ключа (по IP-адресу)
+	IPRate  float64 `env:"RATE_LIMIT_IP_RPS" envDefault:"20"`
+	IPBurst int     `env:"RATE_LIMIT_IP_BURST" envDefault:"40"`
+
+	MaxBodySize int `env:"MAX_BODY_SIZE" envDefault:"1048576"`
+
+	// максимальное число одновременно выполняемых тяжелых запросов (агрегаты, выгрузка продаж)
+	MaxConcurrentQueries int           `env:"MAX_CONCURRENT_QUERIES" envDefault:"16"`
+	QueryRetryAfter      time.Duration `env:"QUERY_RETRY_AFTER" envDefault:"1s"`
+}
+
+// validate проверяет лимиты: нулевой лимит отклонял бы все запросы, а отрицательный размер семафора
+// недопустим.
+func (l Limits) validate() error {
+	if l.APIKeyRate <= 0 || l.APIKeyBurst <= 0 {
+		return fmt.Errorf("api key rate limit and burst must be positive")
+	}
+
+	if l.IPRate <= 0 || l.IPBurst <= 0 {
+		return fmt.Errorf("ip rate limit and burst must be positive")
+	}
+
+	if l.MaxConcurrentQueries <= 0 {
+		return fmt.Errorf("max concurrent queries must be positive")
+	}
+
+	return nil
+}
+
+// Currency настройки валют.
+type Currency struct {
+	// валюта продаж, поступивших без указания валюты
+	Default string `env:"DEFAULT_CURRENCY" envDefault:"RUB"`
+
+	// CSV-файл с курсами валют к базовой валюте, без него пересчет между валютами недоступен
+	RatesFile string `env:"EXCHANGE_RATES_FILE"`
+	Base      string `env:"EXCHANGE_RATES_BASE" envDefault:"RUB"`
+}
+
+// Catalog настройки справочника магазинов и товаров.
+type Catalog struct {
+	// JSON-файл, в котором сохраняется справочник, без него справочник хранится только в памяти
+	File string `env:"CATALOG_FILE"`
+
+	// отклонять продажи магазинов и товаров, отсутствующих в справочнике
+	Validation bool `env:"CATALOG_VALIDATION" envDefault:"false"`
+
+	// часовой пояс магазинов, для которых пояс не задан
+	DefaultTimeZone string `env:"DEFAULT_TIME_ZONE" envDefault:"UTC"`
+
+	// период, с которым ведомый экземпляр и узлы кластера, кроме первого, заменяют справочник и планы копией
+	// справочника и планов ведущего или первого узла
+	SyncInterval time.Duration `env:"CATALOG_SYNC_INTERVAL" envDefault:"10s"`
+}
+
+// Rules настройки бизнес-правил проверки продаж.
+type Rules struct {
+	// JSON-файл с правилами, без него продажи проверяются только на корректность полей
+	File string `env:"RULES_FILE"`
+}
+
+// Targets настройки планов выручки магазинов.
+type Targets struct {
+	// JSON-файл, в котором сохраняются планы, без него планы хранятся только в памяти
+	File string `env:"TARGETS_FILE"`
+}
+
+// Anomalies настройки проверки продаж на аномалии.
+type Anomalies struct {
+	Enabled bool `env:"ANOMALY_DETECTION" envDefault:"false"`
+
+	// действие с аномальной продажей: flag - сохранить и поместить в очередь проверки,
+	// quarantine - сохранить только после подтверждения
+	Action string `env:"ANOMALY_ACTION" envDefault:"flag"`
+
+	// оценка (отклонение в стандартных отклонениях), начиная с которой продажа считается аномальной
+	Threshold float64 `env:"ANOMALY_THRESHOLD" envDefault:"6"`
+
+	// окно скользящей статистики и количество продаж товара в магазине, после которого продажи оцениваются
+	Window          int `env:"ANOMALY_WINDOW" envDefault:"100"`
+	MinObservations int `env:"ANOMALY_MIN_OBSERVATIONS" envDefault:"20"`
+
+	// JSON-файл, в котором сохраняется очередь проверки, без него очередь хранится только в памяти
+	QueueFile string `env:"ANOMALY_QUEUE_FILE"`
+}
+
+// Alerts настройки оповещений.
+type Alerts struct {
+	// JSON-файл, в котором сохраняются оповещения, без него оповещения хранятся только в памяти
+	File string `env:"ALERTS_FILE"`
+
+	// период проверки выручки и отсутствия продаж
+	CheckInterval time.Duration `env:"ALERTS_CHECK_INTERVAL" envDefault:"1m"`
+
+	// время ожидания ответа получателя, количество попыток доставки и паузы между ними (пауза удваивается
+	// после каждой неудачной попытки, но не больше максимальной)
+	Timeout    time.Duration `env:"ALERTS_WEBHOOK_TIMEOUT" envDefault:"5s"`
+	Attempts   int           `env:"ALERTS_WEBHOOK_ATTEMPTS" envDefault:"5"`
+	Backoff    time.Duration `env:"ALERTS_WEBHOOK_BACKOFF" envDefault:"1s"`
+	MaxBackoff time.Duration `env:"ALERTS_WEBHOOK_MAX_BACKOFF" envDefault:"1m"`
+}
+
+// Reports настройки отчетов по расписанию. Доставка отчетов использует настройки webhook оповещений.
+type Reports struct {
+	// каталог, в котором сохраняются отчеты и результаты запусков, без него они хранятся только в памяти
+	Dir string `env:"REPORTS_DIR"`
+
+	// период проверки расписаний и количество хранимых запусков каждого отчета
+	CheckInterval time.Duration `env:"REPORTS_CHECK_INTERVAL" envDefault:"30s"`
+	KeepRuns      int           `env:"REPORTS_KEEP_RUNS" envDefault:"20"`
+}
+
+// Storage настройки хранилища продаж.
+type Storage struct {
+	// продажи старше горизонта вытесняются из памяти в сегменты на диске (0 - хранить все продажи в памяти)
+	RetentionHorizon time.Duration `env:"STORAGE_RETENTION" envDefault:"0"`
+	SegmentsDir      string        `env:"STORAGE_SEGMENTS_DIR" envDefault:"segments"`
+	EvictionInterval time.Duration `env:"STORAGE_EVICTION_INTERVAL" envDefault:"1h"`
+
+	// срок действия снимков продаж для согласованного чтения и максимальное количество открытых снимков
+	SnapshotTTL  time.Duration `env:"STORAGE_SNAPSHOT_TTL" envDefault:"5m"`
+	MaxSnapshots int           `env:"STORAGE_MAX_SNAPSHOTS" envDefault:"1000"`
+}
+
+// Purge настройки удаления продаж.
+type Purge struct {
+	// срок хранения продаж: продажи старше него удаляются каждые Interval (0 - продажи не удаляются по сроку)
+	Horizon  time.Duration `env:"PURGE_HORIZON" envDefault:"0"`
+	Interval time.Duration `env:"PURGE_INTERVAL" envDefault:"24h"`
+
+	// файл журнала аудита удалений, без него журнал хранится только в памяти
+	AuditFile string `env:"AUDIT_FILE"`
+}
+
+// Replication настройки репликации продаж.
+type Replication struct {
+	// роль экземпляра: leader принимает продажи, follower читает их из журнала ведущего LeaderURL
+	Role      string `env:"REPLICATION_ROLE" envDefault:"leader"`
+	LeaderURL string `env:"REPLICATION_LEADER_URL"`
+
+	// общий секрет ведущего и ведомых: журнал /replication/versions и /replication/sales отдается только
+	// с ним и не ограничивается лимитами клиентов, поэтому обязателен для ведомого (без него ведущий
+	// не отдает журнал)
+	Token string `env:"REPLICATION_TOKEN"`
+
+	// период опроса ведущего и количество продаж, запрашиваемых за один запрос
+	PollInterval time.Duration `env:"REPLICATION_POLL_INTERVAL" envDefault:"1s"`
+	BatchSize    int           `env:"REPLICATION_BATCH_SIZE" envDefault:"1000"`
+}
+
+// Cluster настройки кластера: магазины распределяются между узлами по идентификатору магазина.
+type Cluster struct {
+	// адреса всех узлов кластера, одинаковые на всех узлах (пустой - кластер не используется),
+	// и адрес текущего узла среди них
+	Nodes []string `env:"CLUSTER_NODES" envSeparator:","`
+	Self  string   `env:"CLUSTER_SELF"`
+
+	// общий секрет узлов: внутренний API /cluster/* принимает только запросы с ним и не ограничивается
+	// лимитами клиентов, поэтому обязателен в режиме кластера
+	Token string `env:"CLUSTER_TOKEN"`
+
+	// количество точек каждого узла на кольце консистентного хеширования
+	VirtualNodes int `env:"CLUSTER_VIRTUAL_NODES" envDefault:"128"`
+}
+
+// SQL настройки SQL-запросов к продажам.
+type SQL struct {
+	// максимальное время выполнения запроса и количество строк результата, лишние строки отбрасываются
+	QueryTimeout time.Duration `env:"SQL_QUERY_TIMEOUT" envDefault:"5s"`
+	MaxRows      int           `env:"SQL_MAX_ROWS" envDefault:"10000"`
+}
+
 // Read reads config.
 func Read() (Config, error) {
 	var conf Config
@@ -21,5 +227,35 @@ func Read() (Config, error) {
 		return Config{}, fmt.Errorf("parse config from env: %w", err)
 	}
 
+	if err := conf.Limits.validate(); err != nil {
+		return Config{}, err
+	}
+
+	switch conf.Replication.Role {
+	case domain.RoleLeader:
+	case domain.RoleFollower:
+		if conf.Replication.LeaderURL == "" {
+			return Config{}, fmt.Errorf("leader url is required for follower")
+		}
+
+		if conf.Replication.Token == "" {
+			return Config{}, fmt.Errorf("replication token is required for follower")
+		}
+	default:
+		return Config{}, fmt.Errorf("unknown replication role %q", conf.Replication.Role)
+	}
+
+	if conf.Anomalies.Action != domain.AnomalyActionFlag && conf.Anomalies.Action != domain.AnomalyActionQuarantine {
+		return Config{}, fmt.Errorf("unknown anomaly action %q", conf.Anomalies.Action)
+	}
+
+	if len(conf.Cluster.Nodes) > 0 && conf.Cluster.Self == "" {
+		return Config{}, fmt.Errorf("self node address is required in cluster mode")
+	}
+
+	if len(conf.Cluster.Nodes) > 0 && conf.Cluster.Token == "" {
+		return Config{}, fmt.Errorf("cluster token is required in cluster mode")
+	}
+
 	return conf, nil
 }
@@ -0,0 +1,216 @@
+// Package cron разбирает расписания в формате cron и вычисляет время следующего запуска.
+package cron
+
+import (
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxYears горизонт поиска следующего запуска: расписание без запусков (например, 30 февраля)
+// не приводит к бесконечному поиску.
+const maxYears = 5
+
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 1",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var (
+	monthNames = []string{"", "jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
+	dayNames   = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+)
+
+// field поле расписания: допустимые значения и имена значений.
+type field struct {
+	name     string
+	min, max int
+	names    []string // имя -> значение по индексу
+}
+
+var fields = []field{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: monthNames},
+	{name: "day of week", min: 0, max: 7, names: dayNames}, // 7 - тоже воскресенье
+}
+
+// Schedule расписание из пяти полей: минута, час, день месяца, месяц и день недели (0 или 7 - воскресенье).
+// Поле - список через запятую из значений, диапазонов a-b и * с необязательным шагом /n; месяцы и дни недели
+// можно задавать именами (jan, mon). Поддерживаются сокращения @hourly, @daily, @weekly (понедельник),
+// @monthly и @yearly. Если ограничены и день месяца, и день недели, подходит день, совпадающий с любым из них.
+type Schedule struct {
+	minute, hour, dom, month, dow uint64 // множества допустимых значений, бит - значение
+
+	domAny, dowAny bool // поле задано как *
+}
+
+// Parse разбирает расписание.
+func Parse(spec string) (*Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if expanded, ok := macros[strings.ToLower(spec)]; ok {
+		spec = expanded
+	}
+
+	parts := strings.Fields(spec)
+	if len(parts) != len(fields) {
+		return nil, fmt.Errorf("cron spec %q: expected %d fields, got %d", spec, len(fields), len(parts))
+	}
+
+	sets := make([]uint64, len(fields))
+
+	for i, part := range parts {
+		set, err := parseField(part, fields[i])
+		if err != nil {
+			return nil, fmt.Errorf("cron spec %q: %w", spec, err)
+		}
+
+		sets[i] = set
+	}
+
+	// воскресенье - 0
+	if sets[4]&(1<<7) != 0 {
+		sets[4] = sets[4]&^(1<<7) | 1
+	}
+
+	return &Schedule{
+		minute: sets[0],
+		hour:   sets[1],
+		dom:    sets[2],
+		month:  sets[3],
+		dow:    sets[4],
+		domAny: strings.HasPrefix(parts[2], "*"),
+		dowAny: strings.HasPrefix(parts[4], "*"),
+	}, nil
+}
+
+func parseField(s string, f field) (uint64, error) {
+	var set uint64
+
+	for _, item := range strings.Split(s, ",") {
+		rng, step := item, 1
+
+		if i := strings.IndexByte(item, '/'); i >= 0 {
+			n, err := strconv.Atoi(item[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("%s: invalid step in %q", f.name, item)
+			}
+
+			rng, step = item[:i], n
+		}
+
+		lo, hi := f.min, f.max
+
+		switch {
+		case rng == "*":
+		case strings.Contains(rng, "-"):
+			a, b, _ := strings.Cut(rng, "-")
+
+			var err error
+			if lo, err = f.value(a); err != nil {
+				return 0, err
+			}
+
+			if hi, err = f.value(b); err != nil {
+				return 0, err
+			}
+
+			if lo > hi {
+				return 0, fmt.Errorf("%s: invalid range %q", f.name, rng)
+			}
+		default:
+			v, err := f.value(rng)
+			if err != nil {
+				return 0, err
+			}
+
+			lo, hi = v, v
+			if step > 1 {
+				hi = f.max
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			set |= 1 << v
+		}
+	}
+
+	return set, nil
+}
+
+// value разбирает значение поля: число или имя.
+func (f field) value(s string) (int, error) {
+	for i, name := range f.names {
+		if name != "" && strings.EqualFold(s, name) {
+			return i, nil
+		}
+	}
+
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("%s: invalid value %q, expected %d-%d", f.name, s, f.min, f.max)
+	}
+
+	return v, nil
+}
+
+// Next возвращает первое время запуска строго после t в часовом поясе t (с точностью до минуты)
+// или нулевое время, если в ближайшие годы запусков нет. Запуск во время, пропущенное при переходе на летнее
+// время, пропускается.
+func (s *Schedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	// следующая минута по абсолютному времени: при переходе на зимнее время повторный час не сдвигает поиск назад
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.Year() + maxYears
+
+	for t.Year() <= limit {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			// следующая допустимая минута часа или начало следующего часа
+			if rest := s.minute >> uint(t.Minute()); rest != 0 {
+				t = t.Add(time.Duration(bits.TrailingZeros64(rest)) * time.Minute)
+			} else {
+				t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			}
+
+			continue
+		}
+
+		return t
+	}
+
+	return time.Time{}
+}
+
+func (s *Schedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+
+	if s.domAny || s.dowAny {
+		return dom && dow
+	}
+
+	return dom || dow
+}
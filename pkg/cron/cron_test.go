@@ -0,0 +1,143 @@
+package cron
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchedule_Next(t *testing.T) {
+	t.Parallel()
+
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	require.NoError(t, err)
+
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+
+	// среда, 12:34:56
+	wednesday := time.Date(2024, 5, 15, 12, 34, 56, 0, moscow)
+
+	testCases := []struct {
+		name string
+		spec string
+		from time.Time
+		exp  time.Time
+	}{
+		{
+			name: "каждую минуту",
+			spec: "* * * * *",
+			from: wednesday,
+			exp:  time.Date(2024, 5, 15, 12, 35, 0, 0, moscow),
+		},
+		{
+			name: "строго после момента запуска",
+			spec: "35 12 * * *",
+			from: time.Date(2024, 5, 15, 12, 35, 0, 0, moscow),
+			exp:  time.Date(2024, 5, 16, 12, 35, 0, 0, moscow),
+		},
+		{
+			name: "шаг минут",
+			spec: "*/15 * * * *",
+			from: wednesday,
+			exp:  time.Date(2024, 5, 15, 12, 45, 0, 0, moscow),
+		},
+		{
+			name: "каждый понедельник в 9:00",
+			spec: "0 9 * * mon",
+			from: wednesday,
+			exp:  time.Date(2024, 5, 20, 9, 0, 0, 0, moscow),
+		},
+		{
+			name: "сокращение @weekly",
+			spec: "@weekly",
+			from: wednesday,
+			exp:  time.Date(2024, 5, 20, 0, 0, 0, 0, moscow),
+		},
+		{
+			name: "рабочие дни, список часов",
+			spec: "30 8,18 * * 1-5",
+			from: time.Date(2024, 5, 17, 19, 0, 0, 0, moscow), // пятница
+			exp:  time.Date(2024, 5, 20, 8, 30, 0, 0, moscow),
+		},
+		{
+			name: "воскресенье - 7",
+			spec: "0 0 * * 7",
+			from: wednesday,
+			exp:  time.Date(2024, 5, 19, 0, 0, 0, 0, moscow),
+		},
+		{
+			name: "первое число месяца",
+			spec: "0 6 1 * *",
+			from: wednesday,
+			exp:  time.Date(2024, 6, 1, 6, 0, 0, 0, moscow),
+		},
+		{
+			name: "день месяца или день недели",
+			spec: "0 0 1 * fri",
+			from: wednesday,
+			exp:  time.Date(2024, 5, 17, 0, 0, 0, 0, moscow),
+		},
+		{
+			name: "29 февраля",
+			spec: "0 0 29 feb *",
+			from: wednesday,
+			exp:  time.Date(2028, 2, 29, 0, 0, 0, 0, moscow),
+		},
+		{
+			name: "время, пропущенное при переходе на летнее время",
+			spec: "30 2 * * *",
+			from: time.Date(2024, 3, 30, 12, 0, 0, 0, berlin),
+			exp:  time.Date(2024, 4, 1, 2, 30, 0, 0, berlin),
+		},
+		{
+			name: "повторный час при переходе на зимнее время",
+			spec: "0 * * * *",
+			from: time.Date(2024, 10, 27, 1, 30, 0, 0, time.UTC).In(berlin), // 02:30 после перевода часов
+			exp:  time.Date(2024, 10, 27, 3, 0, 0, 0, berlin),
+		},
+		{
+			name: "запусков нет",
+			spec: "0 0 30 feb *",
+			from: wednesday,
+			exp:  time.Time{},
+		},
+	}
+
+	for _, tt := range testCases {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			s, err := Parse(tt.spec)
+			require.NoError(t, err)
+
+			next := s.Next(tt.from)
+			assert.True(t, tt.exp.Equal(next), "next %s, expected %s", next, tt.exp)
+		})
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	t.Parallel()
+
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"* * * foo *",
+		"@often",
+	} {
+		_, err := Parse(spec)
+		assert.Error(t, err, spec)
+	}
+}
@@ -0,0 +1,226 @@
+package forecast
+
+import (
+	"errors"
+	"math"
+)
+
+// ErrShortSeries ряд слишком короткий для модели.
+var ErrShortSeries = errors.New("series too short")
+
+// Forecast прогноз ряда на horizon шагов: точечные значения и стандартные отклонения ошибки прогноза на каждом шаге.
+type Forecast struct {
+	Values []float64
+	StdDev []float64
+}
+
+// Interval возвращает границы интервала прогноза шага i для квантиля z нормального распределения (см. Quantile).
+func (f Forecast) Interval(i int, z float64) (float64, float64) {
+	return f.Values[i] - z*f.StdDev[i], f.Values[i] + z*f.StdDev[i]
+}
+
+// Method строит прогноз ряда series с сезонностью period на horizon шагов.
+type Method func(series []float64, period, horizon int) (Forecast, error)
+
+// Quantile возвращает квантиль стандартного нормального распределения для двустороннего интервала
+// с вероятностью level (0 < level < 1), например 1.96 для 0.95.
+func Quantile(level float64) float64 {
+	return math.Sqrt2 * math.Erfinv(level)
+}
+
+// SeasonalNaive прогнозирует значение ряда значением того же сезона в последнем периоде.
+// Ошибка прогноза растет с числом целых периодов между прогнозом и последним наблюдением.
+func SeasonalNaive(series []float64, period, horizon int) (Forecast, error) {
+	n := len(series)
+	if period < 1 || n < period+1 {
+		return Forecast{}, ErrShortSeries
+	}
+
+	var sse float64
+	for t := period; t < n; t++ {
+		e := series[t] - series[t-period]
+		sse += e * e
+	}
+
+	sigma := math.Sqrt(sse / float64(n-period))
+
+	f := Forecast{Values: make([]float64, horizon), StdDev: make([]float64, horizon)}
+
+	for h := 0; h < horizon; h++ {
+		f.Values[h] = series[n-period+h%period]
+		f.StdDev[h] = sigma * math.Sqrt(float64(h/period+1))
+	}
+
+	return f, nil
+}
+
+// параметры сглаживания, среди которых HoltWinters выбирает параметры с наименьшей ошибкой прогноза на шаг
+var (
+	levelGrid    = []float64{0.05, 0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.9}
+	trendGrid    = []float64{0, 0.01, 0.05, 0.1, 0.2}
+	seasonalGrid = []float64{0.05, 0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.9}
+)
+
+// HoltWinters прогнозирует ряд аддитивной моделью Хольта-Винтерса (уровень, тренд, сезонность).
+// Параметры сглаживания подбираются по наименьшей сумме квадратов ошибок прогноза на шаг вперед.
+// Требуется не меньше двух периодов наблюдений.
+func HoltWinters(series []float64, period, horizon int) (Forecast, error) {
+	if period < 1 || len(series) < 2*period {
+		return Forecast{}, ErrShortSeries
+	}
+
+	var best *holtWinters
+
+	for _, alpha := range levelGrid {
+		for _, beta := range trendGrid {
+			for _, gamma := range seasonalGrid {
+				m := fitHoltWinters(series, period, alpha, beta, gamma)
+				if best == nil || m.sse < best.sse {
+					best = m
+				}
+			}
+		}
+	}
+
+	return best.forecast(horizon), nil
+}
+
+// holtWinters состояние модели Хольта-Винтерса после последнего наблюдения ряда.
+type holtWinters struct {
+	alpha, beta, gamma float64
+
+	level    float64
+	trend    float64
+	seasonal []float64 // сезонные компоненты последнего периода, seasonal[i] соответствует шагу прогноза i
+	sse      float64
+	sigma    float64
+}
+
+// fitHoltWinters сглаживает ряд с заданными параметрами. Начальный тренд - средний прирост между первым и вторым
+// периодом, уровень - уровень на конец первого периода, сезонность - отклонения первого периода от линии тренда.
+func fitHoltWinters(series []float64, period int, alpha, beta, gamma float64) *holtWinters {
+	first, second := mean(series[:period]), mean(series[period:2*period])
+
+	trend := (second - first) / float64(period)
+	middle := float64(period-1) / 2
+	level := first + trend*middle
+
+	seasonal := make([]float64, len(series))
+	for i := 0; i < period; i++ {
+		seasonal[i] = series[i] - (first + trend*(float64(i)-middle))
+	}
+
+	var sse float64
+
+	for t := period; t < len(series); t++ {
+		s := seasonal[t-period]
+
+		e := series[t] - (level + trend + s)
+		sse += e * e
+
+		prev := level
+		level = alpha*(series[t]-s) + (1-alpha)*(level+trend)
+		trend = beta*(level-prev) + (1-beta)*trend
+		seasonal[t] = gamma*(series[t]-level) + (1-gamma)*s
+	}
+
+	return &holtWinters{
+		alpha:    alpha,
+		beta:     beta,
+		gamma:    gamma,
+		level:    level,
+		trend:    trend,
+		seasonal: seasonal[len(series)-period:],
+		sse:      sse,
+		sigma:    math.Sqrt(sse / float64(len(series)-period)),
+	}
+}
+
+// forecast возвращает прогноз модели. Дисперсия ошибки на шаге h: sigma² (1 + Σ c_j², j = 1..h-1),
+// c_j = alpha (1 + j beta) + gamma [j кратно периоду].
+func (m *holtWinters) forecast(horizon int) Forecast {
+	period := len(m.seasonal)
+
+	f := Forecast{Values: make([]float64, horizon), StdDev: make([]float64, horizon)}
+
+	variance := 1.0
+
+	for h := 0; h < horizon; h++ {
+		if h > 0 {
+			c := m.alpha * (1 + float64(h)*m.beta)
+			if h%period == 0 {
+				c += m.gamma
+			}
+
+			variance += c * c
+		}
+
+		f.Values[h] = m.level + float64(h+1)*m.trend + m.seasonal[h%period]
+		f.StdDev[h] = m.sigma * math.Sqrt(variance)
+	}
+
+	return f
+}
+
+// Accuracy ошибки прогноза на отложенной части ряда.
+type Accuracy struct {
+	Points int     // длина отложенной части
+	MAE    float64 // средняя абсолютная ошибка
+	RMSE   float64 // корень из средней квадратичной ошибки
+
+	// средняя абсолютная ошибка в процентах по ненулевым фактическим значениям, NaN - если таких значений нет
+	MAPE float64
+}
+
+// Backtest строит прогноз методом method по ряду без последних horizon значений
+// и сравнивает его с отложенными значениями.
+func Backtest(method Method, series []float64, period, horizon int) (Accuracy, error) {
+	if horizon < 1 || len(series) <= horizon {
+		return Accuracy{}, ErrShortSeries
+	}
+
+	train, test := series[:len(series)-horizon], series[len(series)-horizon:]
+
+	f, err := method(train, period, horizon)
+	if err != nil {
+		return Accuracy{}, err
+	}
+
+	var absSum, sqSum, pctSum float64
+
+	var pctPoints int
+
+	for i, actual := range test {
+		e := actual - f.Values[i]
+
+		absSum += math.Abs(e)
+		sqSum += e * e
+
+		if actual != 0 {
+			pctSum += math.Abs(e / actual)
+			pctPoints++
+		}
+	}
+
+	acc := Accuracy{
+		Points: horizon,
+		MAE:    absSum / float64(horizon),
+		RMSE:   math.Sqrt(sqSum / float64(horizon)),
+		MAPE:   math.NaN(),
+	}
+
+	if pctPoints > 0 {
+		acc.MAPE = 100 * pctSum / float64(pctPoints)
+	}
+
+	return acc, nil
+}
+
+func mean(values []float64) float64 {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+
+	return sum / float64(len(values))
+}
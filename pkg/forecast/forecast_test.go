@@ -0,0 +1,128 @@
+package forecast
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// weekly ряд из days значений с недельной сезонностью и линейным трендом.
+func weekly(days int, trend float64) []float64 {
+	pattern := []float64{100, 120, 110, 130, 180, 250, 90}
+
+	series := make([]float64, days)
+	for i := range series {
+		series[i] = pattern[i%7] + trend*float64(i)
+	}
+
+	return series
+}
+
+func TestSeasonalNaive(t *testing.T) {
+	t.Parallel()
+
+	series := weekly(21, 0)
+	series[20] = 100 // последнее значение отличается от сезона на 10
+
+	f, err := SeasonalNaive(series, 7, 10)
+	require.NoError(t, err)
+
+	assert.Equal(t, []float64{100, 120, 110, 130, 180, 250, 100, 100, 120, 110}, f.Values)
+
+	// единственная ненулевая остаточная ошибка 10 из 14
+	sigma := math.Sqrt(100.0 / 14)
+	assert.InDelta(t, sigma, f.StdDev[0], 1e-9)
+	assert.InDelta(t, sigma, f.StdDev[6], 1e-9)
+	assert.InDelta(t, sigma*math.Sqrt2, f.StdDev[7], 1e-9)
+
+	_, err = SeasonalNaive(series[:7], 7, 10)
+	assert.ErrorIs(t, err, ErrShortSeries)
+}
+
+func TestHoltWinters(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		trend float64
+	}{
+		{name: "seasonal", trend: 0},
+		{name: "seasonal with trend", trend: 2},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			series := weekly(63, tt.trend)
+
+			f, err := HoltWinters(series, 7, 14)
+			require.NoError(t, err)
+
+			// ряд без шума продолжается точно
+			want := weekly(77, tt.trend)[63:]
+			assert.InDeltaSlice(t, want, f.Values, 1e-6)
+
+			for i := 1; i < len(f.StdDev); i++ {
+				assert.GreaterOrEqual(t, f.StdDev[i], f.StdDev[i-1])
+			}
+		})
+	}
+
+	_, err := HoltWinters(weekly(13, 0), 7, 14)
+	assert.ErrorIs(t, err, ErrShortSeries)
+}
+
+func TestHoltWinters_Intervals(t *testing.T) {
+	t.Parallel()
+
+	series := weekly(70, 1)
+	for i := range series {
+		// детерминированный шум
+		series[i] += 15 * math.Sin(float64(i*i))
+	}
+
+	f, err := HoltWinters(series, 7, 28)
+	require.NoError(t, err)
+
+	z := Quantile(0.95)
+	assert.InDelta(t, 1.96, z, 1e-3)
+
+	for i := range f.Values {
+		lower, upper := f.Interval(i, z)
+		assert.Less(t, lower, f.Values[i])
+		assert.Greater(t, upper, f.Values[i])
+	}
+
+	assert.Greater(t, f.StdDev[27], f.StdDev[0])
+}
+
+func TestBacktest(t *testing.T) {
+	t.Parallel()
+
+	series := weekly(35, 0)
+
+	acc, err := Backtest(SeasonalNaive, series, 7, 7)
+	require.NoError(t, err)
+	assert.Equal(t, Accuracy{Points: 7, MAE: 0, RMSE: 0, MAPE: 0}, acc)
+
+	// прогноз 100 при факте 110 и 200 при факте 0
+	series = []float64{100, 200, 100, 200, 110, 0}
+
+	acc, err = Backtest(SeasonalNaive, series, 2, 2)
+	require.NoError(t, err)
+	assert.InDelta(t, 105, acc.MAE, 1e-9)
+	assert.InDelta(t, math.Sqrt((100+40000)/2.0), acc.RMSE, 1e-9)
+	assert.InDelta(t, 100*10.0/110, acc.MAPE, 1e-9)
+
+	acc, err = Backtest(SeasonalNaive, []float64{1, 2, 1, 2, 0, 0}, 2, 2)
+	require.NoError(t, err)
+	assert.True(t, math.IsNaN(acc.MAPE))
+
+	_, err = Backtest(HoltWinters, series, 2, 3)
+	assert.ErrorIs(t, err, ErrShortSeries)
+}
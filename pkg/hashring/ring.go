@@ -0,0 +1,88 @@
+package hashring
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+)
+
+// Ring кольцо консистентного хеширования: ключ принадлежит первому по часовой стрелке узлу кольца.
+// Каждый узел размещается на кольце в нескольких точках (виртуальных узлах), поэтому ключи распределяются
+// между узлами равномерно, а при добавлении или удалении узла переходит только доля ключей этого узла.
+type Ring struct {
+	nodes  []string
+	points []point // упорядочены по hash
+}
+
+type point struct {
+	hash uint64
+	node string
+}
+
+// New возвращает кольцо из узлов nodes, каждый из которых размещается в virtualNodes точках.
+func New(nodes []string, virtualNodes int) *Ring {
+	if virtualNodes < 1 {
+		virtualNodes = 1
+	}
+
+	r := &Ring{
+		nodes:  append([]string(nil), nodes...),
+		points: make([]point, 0, len(nodes)*virtualNodes),
+	}
+
+	for _, node := range nodes {
+		for i := 0; i < virtualNodes; i++ {
+			r.points = append(r.points, point{hash: hash(node + "#" + strconv.Itoa(i)), node: node})
+		}
+	}
+
+	sort.Slice(r.points, func(i, j int) bool {
+		if r.points[i].hash != r.points[j].hash {
+			return r.points[i].hash < r.points[j].hash
+		}
+
+		return r.points[i].node < r.points[j].node
+	})
+
+	return r
+}
+
+// Nodes возвращает узлы кольца.
+func (r *Ring) Nodes() []string {
+	return r.nodes
+}
+
+// Owner возвращает узел, которому принадлежит ключ, или пустую строку для пустого кольца.
+func (r *Ring) Owner(key string) string {
+	if len(r.points) == 0 {
+		return ""
+	}
+
+	h := hash(key)
+
+	i := sort.Search(len(r.points), func(i int) bool {
+		return r.points[i].hash >= h
+	})
+
+	if i == len(r.points) {
+		i = 0
+	}
+
+	return r.points[i].node
+}
+
+// hash возвращает FNV-1a хеш строки, перемешанный финализатором splitmix64: у FNV близкие строки
+// (store_1, store_2) дают близкие хеши, и без перемешивания они попадали бы на один узел.
+func hash(s string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(s))
+
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+
+	return x
+}
@@ -0,0 +1,54 @@
+package hashring
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRing_Owner(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "", New(nil, 10).Owner("store_1"))
+	assert.Equal(t, "node_1", New([]string{"node_1"}, 10).Owner("store_1"))
+
+	nodes := []string{"node_1", "node_2", "node_3"}
+	r := New(nodes, 128)
+
+	// владелец не зависит от порядка узлов
+	reversed := New([]string{"node_3", "node_2", "node_1"}, 128)
+
+	const keys = 30000
+
+	owners := make(map[string]string, keys)
+	counts := make(map[string]int)
+
+	for i := 0; i < keys; i++ {
+		key := fmt.Sprintf("store_%d", i)
+		owner := r.Owner(key)
+
+		assert.Equal(t, owner, reversed.Owner(key))
+
+		owners[key] = owner
+		counts[owner]++
+	}
+
+	// ключи распределены между узлами примерно поровну
+	for _, node := range nodes {
+		assert.InDelta(t, keys/len(nodes), counts[node], keys*0.1, node)
+	}
+
+	// при добавлении узла ключи переходят только на новый узел, и переходит примерно четверть ключей
+	grown := New(append(nodes, "node_4"), 128)
+
+	moved := 0
+	for key, owner := range owners {
+		if newOwner := grown.Owner(key); newOwner != owner {
+			assert.Equal(t, "node_4", newOwner)
+			moved++
+		}
+	}
+
+	assert.InDelta(t, keys/4, moved, keys*0.1)
+}
@@ -0,0 +1,125 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+const defaultIdleTTL = time.Minute
+
+// TokenBucket реализует алгоритм token bucket: корзина вмещает burst токенов
+// и пополняется со скоростью rate токенов в секунду.
+type TokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucket возвращает заполненную корзину.
+func NewTokenBucket(rate float64, burst int, now time.Time) *TokenBucket {
+	return &TokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   now,
+	}
+}
+
+// Take пытается забрать один токен. Если токенов нет, возвращает false и время,
+// через которое токен появится.
+func (b *TokenBucket) Take(now time.Time) (bool, time.Duration) {
+	b.refill(now)
+
+	if b.tokens >= 1 {
+		b.tokens--
+
+		return true, 0
+	}
+
+	if b.rate <= 0 {
+		return false, time.Duration(math.MaxInt64)
+	}
+
+	wait := (1 - b.tokens) / b.rate
+
+	return false, time.Duration(wait * float64(time.Second))
+}
+
+// full сообщает, что корзина полностью пополнена и ее можно безопасно удалить.
+func (b *TokenBucket) full(now time.Time) bool {
+	b.refill(now)
+
+	return b.tokens >= b.burst
+}
+
+func (b *TokenBucket) refill(now time.Time) {
+	elapsed := now.Sub(b.last).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+
+	b.tokens = math.Min(b.burst, b.tokens+elapsed*b.rate)
+	b.last = now
+}
+
+// Limiter хранит отдельную корзину для каждого ключа (IP-адрес, API-ключ и т.п.).
+type Limiter struct {
+	rate  float64
+	burst int
+
+	buckets   map[string]*TokenBucket
+	lastSweep time.Time
+	idleTTL   time.Duration
+
+	now func() time.Time
+	mu  sync.Mutex
+}
+
+// New возвращает лимитер с заданной скоростью пополнения (токенов в секунду) и размером корзины.
+func New(rate float64, burst int) *Limiter {
+	return &Limiter{
+		rate:      rate,
+		burst:     burst,
+		buckets:   make(map[string]*TokenBucket),
+		lastSweep: time.Now(),
+		idleTTL:   defaultIdleTTL,
+		now:       time.Now,
+	}
+}
+
+// Allow забирает токен из корзины ключа. Если лимит исчерпан, возвращает false
+// и время ожидания до следующего токена.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = NewTokenBucket(l.rate, l.burst, now)
+		l.buckets[key] = b
+	}
+
+	return b.Take(now)
+}
+
+// sweep раз в idleTTL удаляет заполненные корзины: корзина клиента, не делавшего запросов дольше burst/rate,
+// ничем не отличается от новой, поэтому map содержит только корзины недавно активных клиентов.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.idleTTL {
+		return
+	}
+
+	for key, b := range l.buckets {
+		if b.full(now) {
+			delete(l.buckets, key)
+		}
+	}
+
+	l.lastSweep = now
+}
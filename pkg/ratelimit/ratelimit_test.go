@@ -0,0 +1,63 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenBucket_Take(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	b := NewTokenBucket(2, 2, now)
+
+	ok, _ := b.Take(now)
+	assert.True(t, ok)
+
+	ok, _ = b.Take(now)
+	assert.True(t, ok)
+
+	ok, wait := b.Take(now)
+	assert.False(t, ok)
+	assert.Equal(t, 500*time.Millisecond, wait)
+
+	// через полсекунды появляется один токен
+	ok, _ = b.Take(now.Add(500 * time.Millisecond))
+	assert.True(t, ok)
+
+	// корзина не переполняется больше burst
+	ok, _ = b.Take(now.Add(time.Hour))
+	assert.True(t, ok)
+	ok, _ = b.Take(now.Add(time.Hour))
+	assert.True(t, ok)
+	ok, _ = b.Take(now.Add(time.Hour))
+	assert.False(t, ok)
+}
+
+func TestLimiter_Allow(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	l := New(1, 1)
+	l.now = func() time.Time { return now }
+	l.lastSweep = now
+
+	ok, _ := l.Allow("10.0.0.1")
+	assert.True(t, ok)
+
+	ok, wait := l.Allow("10.0.0.1")
+	assert.False(t, ok)
+	assert.Equal(t, time.Second, wait)
+
+	// у другого ключа собственная корзина
+	ok, _ = l.Allow("10.0.0.2")
+	assert.True(t, ok)
+
+	// неактивные корзины удаляются
+	now = now.Add(l.idleTTL)
+	ok, _ = l.Allow("10.0.0.3")
+	assert.True(t, ok)
+	assert.Len(t, l.buckets, 1)
+}
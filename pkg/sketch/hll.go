@@ -0,0 +1,237 @@
+// Package sketch содержит вероятностные структуры для приближенной статистики: HyperLogLog для подсчета
+// различных значений и t-digest для квантилей. Структуры одного вида объединяются (Merge) без потери
+// точности, поэтому статистику можно считать по частям - интервалам времени или магазинам - и складывать.
+package sketch
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"hash/fnv"
+	"math"
+	"math/bits"
+	"sort"
+)
+
+const (
+	// hllPrecision количество бит хеша, выбирающих регистр: 2^12 регистров, стандартная ошибка около 1,6%.
+	hllPrecision = 12
+	hllRegisters = 1 << hllPrecision
+
+	// hllSparseMax количество регистров в разреженном представлении, после которого плотное занимает меньше памяти.
+	hllSparseMax = hllRegisters / 4
+
+	hllVersion = 1
+)
+
+// ErrInvalidEncoding сериализованная структура повреждена или записана несовместимой версией.
+var ErrInvalidEncoding = errors.New("invalid sketch encoding")
+
+// HLL оценка количества различных значений (HyperLogLog). Пока заполнено мало регистров, хранятся только
+// ненулевые (разреженное представление), поэтому небольшие множества занимают единицы килобайт.
+// Нулевое значение готово к использованию. Не безопасен для одновременной записи и чтения.
+type HLL struct {
+	sparse []uint32 // ненулевые регистры: номер << 8 | значение, по возрастанию номера
+	dense  []uint8  // все регистры; nil в разреженном представлении
+}
+
+// NewHLL возвращает пустую оценку.
+func NewHLL() *HLL {
+	return &HLL{}
+}
+
+// Add учитывает значение.
+func (h *HLL) Add(value string) {
+	f := fnv.New64a()
+	_, _ = f.Write([]byte(value))
+
+	x := mix(f.Sum64())
+
+	// старшие биты хеша выбирают регистр, в регистре - позиция первой единицы в остальных битах
+	idx := uint32(x >> (64 - hllPrecision))
+	rho := uint8(bits.LeadingZeros64(x<<hllPrecision|1<<(hllPrecision-1)) + 1)
+
+	h.set(idx, rho)
+}
+
+// set записывает в регистр idx максимум из его значения и rho.
+func (h *HLL) set(idx uint32, rho uint8) {
+	if h.dense != nil {
+		if rho > h.dense[idx] {
+			h.dense[idx] = rho
+		}
+
+		return
+	}
+
+	k := sort.Search(len(h.sparse), func(k int) bool { return h.sparse[k]>>8 >= idx })
+	if k < len(h.sparse) && h.sparse[k]>>8 == idx {
+		if rho > uint8(h.sparse[k]) {
+			h.sparse[k] = idx<<8 | uint32(rho)
+		}
+
+		return
+	}
+
+	h.sparse = append(h.sparse, 0)
+	copy(h.sparse[k+1:], h.sparse[k:])
+	h.sparse[k] = idx<<8 | uint32(rho)
+
+	if len(h.sparse) > hllSparseMax {
+		h.toDense()
+	}
+}
+
+func (h *HLL) toDense() {
+	h.dense = make([]uint8, hllRegisters)
+
+	for _, e := range h.sparse {
+		h.dense[e>>8] = uint8(e)
+	}
+
+	h.sparse = nil
+}
+
+// Merge добавляет к оценке значения other. other не изменяется.
+func (h *HLL) Merge(other *HLL) {
+	if other == nil {
+		return
+	}
+
+	if other.dense != nil {
+		if h.dense == nil {
+			h.toDense()
+		}
+
+		for idx, rho := range other.dense {
+			if rho > h.dense[idx] {
+				h.dense[idx] = rho
+			}
+		}
+
+		return
+	}
+
+	for _, e := range other.sparse {
+		h.set(e>>8, uint8(e))
+	}
+}
+
+// Estimate возвращает оценку количества различных значений.
+func (h *HLL) Estimate() uint64 {
+	const m = float64(hllRegisters)
+
+	var (
+		sum   float64
+		zeros int
+	)
+
+	if h.dense != nil {
+		for _, rho := range h.dense {
+			sum += math.Ldexp(1, -int(rho))
+
+			if rho == 0 {
+				zeros++
+			}
+		}
+	} else {
+		zeros = hllRegisters - len(h.sparse)
+		sum = float64(zeros)
+
+		for _, e := range h.sparse {
+			sum += math.Ldexp(1, -int(uint8(e)))
+		}
+	}
+
+	estimate := 0.7213 / (1 + 1.079/m) * m * m / sum
+
+	// на малых множествах точнее линейный подсчет по количеству пустых регистров
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+
+	return uint64(math.Round(estimate))
+}
+
+// MarshalBinary кодирует оценку: версия, представление и регистры.
+func (h *HLL) MarshalBinary() ([]byte, error) {
+	if h.dense != nil {
+		return append([]byte{hllVersion, 1}, h.dense...), nil
+	}
+
+	buf := binary.AppendUvarint([]byte{hllVersion, 0}, uint64(len(h.sparse)))
+	for _, e := range h.sparse {
+		buf = binary.LittleEndian.AppendUint32(buf, e)
+	}
+
+	return buf, nil
+}
+
+// UnmarshalBinary декодирует оценку, закодированную MarshalBinary.
+func (h *HLL) UnmarshalBinary(data []byte) error {
+	if len(data) < 2 || data[0] != hllVersion {
+		return ErrInvalidEncoding
+	}
+
+	if data[1] == 1 {
+		if len(data) != 2+hllRegisters {
+			return ErrInvalidEncoding
+		}
+
+		h.sparse, h.dense = nil, append([]uint8(nil), data[2:]...)
+
+		return nil
+	}
+
+	n, size := binary.Uvarint(data[2:])
+	if size <= 0 || n > hllSparseMax || uint64(len(data)-2-size) != 4*n {
+		return ErrInvalidEncoding
+	}
+
+	sparse := make([]uint32, 0, n)
+
+	for p := 2 + size; p < len(data); p += 4 {
+		e := binary.LittleEndian.Uint32(data[p:])
+		if e>>8 >= hllRegisters || (len(sparse) > 0 && e>>8 <= sparse[len(sparse)-1]>>8) {
+			return ErrInvalidEncoding
+		}
+
+		sparse = append(sparse, e)
+	}
+
+	h.sparse, h.dense = sparse, nil
+
+	return nil
+}
+
+// MarshalJSON кодирует оценку в JSON строкой base64 (см. MarshalBinary).
+func (h *HLL) MarshalJSON() ([]byte, error) {
+	data, err := h.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(data)
+}
+
+// UnmarshalJSON декодирует оценку, закодированную MarshalJSON.
+func (h *HLL) UnmarshalJSON(data []byte) error {
+	var raw []byte
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	return h.UnmarshalBinary(raw)
+}
+
+// mix перемешивает биты хеша (финализатор MurmurHash3): у FNV старшие биты плохо зависят от последних байтов
+// значения, а HyperLogLog требует равномерно распределенных бит.
+func mix(x uint64) uint64 {
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+
+	return x
+}
@@ -0,0 +1,93 @@
+package sketch
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHLL_Estimate(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		distinct int
+	}{
+		{name: "пустая оценка", distinct: 0},
+		{name: "разреженное представление", distinct: 300},
+		{name: "плотное представление", distinct: 5000},
+		{name: "большое множество", distinct: 200000},
+	}
+
+	for _, tt := range testCases {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			h := NewHLL()
+
+			// каждое значение добавляется дважды: повторы не меняют оценку
+			for i := 0; i < 2*tt.distinct; i++ {
+				h.Add(fmt.Sprintf("product_%d", i%tt.distinct))
+			}
+
+			assert.InEpsilon(t, float64(tt.distinct)+1, float64(h.Estimate())+1, 0.05)
+		})
+	}
+}
+
+func TestHLL_Merge(t *testing.T) {
+	t.Parallel()
+
+	// множества пересекаются: [0, 3000) и [2000, 2300)
+	a, b, all := NewHLL(), NewHLL(), NewHLL()
+
+	for i := 0; i < 3000; i++ {
+		a.Add(fmt.Sprint(i))
+		all.Add(fmt.Sprint(i))
+	}
+
+	for i := 2000; i < 2300; i++ {
+		b.Add(fmt.Sprint(i))
+	}
+
+	sparse := NewHLL()
+	sparse.Merge(b)
+	assert.Equal(t, b.Estimate(), sparse.Estimate())
+
+	// объединение не зависит от порядка и совпадает с оценкой по всем значениям
+	sparse.Merge(a)
+	a.Merge(b)
+	assert.Equal(t, all.Estimate(), a.Estimate())
+	assert.Equal(t, all.Estimate(), sparse.Estimate())
+
+	b.Merge(nil)
+	assert.InDelta(t, 300, float64(b.Estimate()), 10)
+}
+
+func TestHLL_Marshal(t *testing.T) {
+	t.Parallel()
+
+	for _, n := range []int{0, 100, 5000} {
+		h := NewHLL()
+		for i := 0; i < n; i++ {
+			h.Add(fmt.Sprint(i))
+		}
+
+		data, err := json.Marshal(h)
+		require.NoError(t, err)
+
+		var decoded HLL
+		require.NoError(t, json.Unmarshal(data, &decoded))
+		assert.Equal(t, h.Estimate(), decoded.Estimate(), n)
+	}
+
+	var h HLL
+	assert.ErrorIs(t, h.UnmarshalBinary([]byte{hllVersion, 1, 0}), ErrInvalidEncoding)
+	assert.ErrorIs(t, h.UnmarshalBinary([]byte{2, 0, 0}), ErrInvalidEncoding)
+	assert.ErrorIs(t, h.UnmarshalBinary([]byte{hllVersion, 0, 1, 1}), ErrInvalidEncoding)
+}
@@ -0,0 +1,252 @@
+package sketch
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"sort"
+)
+
+const (
+	// DefaultCompression степень сжатия t-digest по умолчанию: не больше 100 центроидов, ошибка квантилей
+	// на хвостах - доли процента, в середине распределения - около процента.
+	DefaultCompression = 100
+
+	tdigestVersion = 1
+)
+
+// TDigest оценка распределения значений (t-digest) для приближенного расчета квантилей. Значения
+// группируются в центроиды - среднее и количество, причем у краев распределения центроиды мельче,
+// поэтому крайние квантили точнее средних. Не безопасен для одновременной записи и чтения.
+type TDigest struct {
+	compression float64
+
+	centroids []centroid // сжатые, по возрастанию среднего
+	buffer    []centroid // добавленные после последнего сжатия
+
+	count    float64
+	min, max float64
+}
+
+type centroid struct {
+	mean  float64
+	count float64
+}
+
+// NewTDigest возвращает пустую оценку со степенью сжатия compression (DefaultCompression, если не больше 0).
+func NewTDigest(compression float64) *TDigest {
+	if compression <= 0 {
+		compression = DefaultCompression
+	}
+
+	return &TDigest{compression: compression, min: math.Inf(1), max: math.Inf(-1)}
+}
+
+// Add учитывает значение.
+func (t *TDigest) Add(x float64) {
+	t.add(centroid{mean: x, count: 1})
+}
+
+func (t *TDigest) add(c centroid) {
+	t.buffer = append(t.buffer, c)
+	t.count += c.count
+	t.min = math.Min(t.min, c.mean)
+	t.max = math.Max(t.max, c.mean)
+
+	if len(t.buffer) >= 5*int(t.compression) {
+		t.Compress()
+	}
+}
+
+// Merge добавляет к оценке значения other. other не изменяется.
+func (t *TDigest) Merge(other *TDigest) {
+	if other == nil || other.count == 0 {
+		return
+	}
+
+	for _, c := range other.centroids {
+		t.add(c)
+	}
+
+	for _, c := range other.buffer {
+		t.add(c)
+	}
+
+	t.min = math.Min(t.min, other.min)
+	t.max = math.Max(t.max, other.max)
+}
+
+// Count возвращает количество учтенных значений.
+func (t *TDigest) Count() int64 {
+	return int64(math.Round(t.count))
+}
+
+// Compress сжимает добавленные значения в центроиды. Вызывается автоматически при переполнении буфера
+// и перед расчетом квантилей; сжатая оценка читается (Quantile, Merge в другую оценку) без изменений.
+func (t *TDigest) Compress() {
+	if len(t.buffer) == 0 {
+		return
+	}
+
+	all := append(t.centroids, t.buffer...)
+	sort.Slice(all, func(i, j int) bool { return all[i].mean < all[j].mean })
+
+	merged := make([]centroid, 0, int(t.compression))
+	cur := all[0]
+	before := 0.0 // количество значений в центроидах левее cur
+
+	// центроид занимает не больше единицы масштаба k(q) = compression / 2π * asin(2q - 1), который растягивает
+	// края распределения: центроиды у краев мельче, а всего их не больше compression
+	limit := t.quantileLimit(0)
+
+	for _, c := range all[1:] {
+		if (before+cur.count+c.count)/t.count <= limit {
+			cur.mean += (c.mean - cur.mean) * c.count / (cur.count + c.count)
+			cur.count += c.count
+
+			continue
+		}
+
+		merged = append(merged, cur)
+		before += cur.count
+		cur = c
+		limit = t.quantileLimit(before / t.count)
+	}
+
+	t.centroids = append(merged, cur)
+	t.buffer = nil
+}
+
+// quantileLimit возвращает квантиль, до которого может дойти центроид, начинающийся в квантиле q.
+func (t *TDigest) quantileLimit(q float64) float64 {
+	k := t.compression/(2*math.Pi)*math.Asin(2*q-1) + 1
+	if k >= t.compression/4 {
+		return 1
+	}
+
+	return (math.Sin(2*math.Pi*k/t.compression) + 1) / 2
+}
+
+// Quantile возвращает оценку квантиля q (0 <= q <= 1); NaN для пустой оценки.
+func (t *TDigest) Quantile(q float64) float64 {
+	t.Compress()
+
+	if t.count == 0 {
+		return math.NaN()
+	}
+
+	if q <= 0 {
+		return t.min
+	}
+
+	if q >= 1 {
+		return t.max
+	}
+
+	cs := t.centroids
+	if len(cs) == 1 {
+		return cs[0].mean
+	}
+
+	// значения центроида считаются распределенными вокруг его среднего: квантиль интерполируется
+	// между средними соседних центроидов, а у краев - между крайним центроидом и минимумом или максимумом
+	target := q * t.count
+
+	if first := cs[0].count / 2; target < first {
+		return t.min + (cs[0].mean-t.min)*target/first
+	}
+
+	seen := cs[0].count / 2
+
+	for i := 1; i < len(cs); i++ {
+		step := (cs[i-1].count + cs[i].count) / 2
+
+		if target < seen+step {
+			return cs[i-1].mean + (cs[i].mean-cs[i-1].mean)*(target-seen)/step
+		}
+
+		seen += step
+	}
+
+	last := cs[len(cs)-1]
+
+	return last.mean + (t.max-last.mean)*math.Min(1, (target-seen)/(last.count/2))
+}
+
+// MarshalBinary кодирует оценку: версия, степень сжатия, минимум, максимум и центроиды. Оценка сжимается.
+func (t *TDigest) MarshalBinary() ([]byte, error) {
+	t.Compress()
+
+	buf := make([]byte, 0, 1+8*3+binary.MaxVarintLen64+16*len(t.centroids))
+	buf = append(buf, tdigestVersion)
+
+	for _, v := range []float64{t.compression, t.min, t.max} {
+		buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(v))
+	}
+
+	buf = binary.AppendUvarint(buf, uint64(len(t.centroids)))
+
+	for _, c := range t.centroids {
+		buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(c.mean))
+		buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(c.count))
+	}
+
+	return buf, nil
+}
+
+// UnmarshalBinary декодирует оценку, закодированную MarshalBinary.
+func (t *TDigest) UnmarshalBinary(data []byte) error {
+	if len(data) < 1+8*3 || data[0] != tdigestVersion {
+		return ErrInvalidEncoding
+	}
+
+	float := func(p int) float64 {
+		return math.Float64frombits(binary.LittleEndian.Uint64(data[p:]))
+	}
+
+	res := TDigest{compression: float(1), min: float(9), max: float(17)}
+	if !(res.compression > 0) {
+		return ErrInvalidEncoding
+	}
+
+	n, size := binary.Uvarint(data[25:])
+	if size <= 0 || uint64(len(data)-25-size) != 16*n {
+		return ErrInvalidEncoding
+	}
+
+	res.centroids = make([]centroid, 0, n)
+
+	for p := 25 + size; p < len(data); p += 16 {
+		c := centroid{mean: float(p), count: float(p + 8)}
+		if !(c.count > 0) || (len(res.centroids) > 0 && c.mean < res.centroids[len(res.centroids)-1].mean) {
+			return ErrInvalidEncoding
+		}
+
+		res.centroids = append(res.centroids, c)
+		res.count += c.count
+	}
+
+	*t = res
+
+	return nil
+}
+
+// MarshalJSON кодирует оценку в JSON строкой base64 (см. MarshalBinary).
+func (t *TDigest) MarshalJSON() ([]byte, error) {
+	data, err := t.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(data)
+}
+
+// UnmarshalJSON декодирует оценку, закодированную MarshalJSON.
+func (t *TDigest) UnmarshalJSON(data []byte) error {
+	var raw []byte
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	return t.UnmarshalBinary(raw)
+}
@@ -0,0 +1,118 @@
+package sketch
+
+import (
+	"encoding/json"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTDigest_Quantile(t *testing.T) {
+	t.Parallel()
+
+	rnd := rand.New(rand.NewSource(1))
+
+	testCases := []struct {
+		name   string
+		values func(i int) float64
+	}{
+		{name: "равномерное распределение", values: func(int) float64 { return rnd.Float64() * 1000 }},
+		{name: "логнормальное распределение", values: func(int) float64 { return math.Exp(rnd.NormFloat64()) * 500 }},
+		{name: "повторяющиеся значения", values: func(i int) float64 { return float64(i % 10) }},
+	}
+
+	for _, tt := range testCases {
+		td := NewTDigest(0)
+		values := make([]float64, 0, 20000)
+
+		for i := 0; i < 20000; i++ {
+			v := tt.values(i)
+			td.Add(v)
+			values = append(values, v)
+		}
+
+		sort.Float64s(values)
+
+		assert.Equal(t, int64(20000), td.Count(), tt.name)
+		td.Compress()
+		assert.LessOrEqual(t, len(td.centroids), DefaultCompression, tt.name)
+		assert.Equal(t, values[0], td.Quantile(0), tt.name)
+		assert.Equal(t, values[len(values)-1], td.Quantile(1), tt.name)
+
+		// ошибка оценивается по рангу: доля значений меньше оценки квантиля не больше квантиля,
+		// а доля значений не больше оценки - не меньше (с допуском)
+		for _, q := range []float64{0.01, 0.1, 0.5, 0.9, 0.99} {
+			v := td.Quantile(q)
+			below := float64(sort.SearchFloat64s(values, v)) / float64(len(values))
+			upTo := float64(sort.Search(len(values), func(i int) bool { return values[i] > v })) / float64(len(values))
+
+			assert.LessOrEqual(t, below, q+0.01, "%s: q=%v", tt.name, q)
+			assert.GreaterOrEqual(t, upTo, q-0.01, "%s: q=%v", tt.name, q)
+		}
+	}
+}
+
+func TestTDigest_Merge(t *testing.T) {
+	t.Parallel()
+
+	a, b, all := NewTDigest(0), NewTDigest(0), NewTDigest(0)
+
+	for i := 0; i < 10000; i++ {
+		a.Add(float64(i))
+		all.Add(float64(i))
+	}
+
+	for i := 10000; i < 30000; i++ {
+		b.Add(float64(i))
+		all.Add(float64(i))
+	}
+
+	merged := NewTDigest(0)
+	merged.Merge(a)
+	merged.Merge(b)
+	merged.Merge(nil)
+	merged.Merge(NewTDigest(0))
+
+	assert.Equal(t, int64(30000), merged.Count())
+
+	for _, q := range []float64{0.1, 0.5, 0.9} {
+		assert.InDelta(t, all.Quantile(q), merged.Quantile(q), 300, q)
+	}
+
+	assert.True(t, math.IsNaN(NewTDigest(0).Quantile(0.5)))
+
+	single := NewTDigest(0)
+	single.Add(42)
+	assert.Equal(t, 42.0, single.Quantile(0.5))
+}
+
+func TestTDigest_Marshal(t *testing.T) {
+	t.Parallel()
+
+	td := NewTDigest(50)
+	for i := 0; i < 1000; i++ {
+		td.Add(float64(i % 97))
+	}
+
+	data, err := json.Marshal(td)
+	require.NoError(t, err)
+
+	var decoded TDigest
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, td.Count(), decoded.Count())
+
+	for _, q := range []float64{0, 0.25, 0.5, 0.75, 1} {
+		assert.Equal(t, td.Quantile(q), decoded.Quantile(q), q)
+	}
+
+	data, err = json.Marshal(NewTDigest(0))
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, int64(0), decoded.Count())
+
+	assert.ErrorIs(t, decoded.UnmarshalBinary([]byte{tdigestVersion}), ErrInvalidEncoding)
+}
@@ -0,0 +1,590 @@
+package sqlengine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// checkEvery количество строк, после которого проверяется отмена запроса.
+const checkEvery = 1024
+
+// errStop останавливает перебор строк таблицы, когда результат уже получен.
+var errStop = errors.New("stop scan")
+
+// Table таблица запроса. Scan передает fn строки таблицы (значения в порядке Columns) и прекращает
+// перебор при ошибке fn. Строка не используется после возврата из fn, поэтому Scan может переиспользовать ее.
+type Table interface {
+	Columns() []string
+	Scan(ctx context.Context, fn func(row []Value) error) error
+}
+
+// Result результат запроса. Truncated - строк больше максимального количества, и лишние строки отброшены.
+type Result struct {
+	Columns   []string
+	Rows      [][]Value
+	Truncated bool
+}
+
+// output выражение столбца результата.
+type output struct {
+	name string
+	expr Expr
+}
+
+// resultRow строка результата и значения выражений ORDER BY для сортировки.
+type resultRow struct {
+	values []Value
+	keys   []Value
+}
+
+// group группа строк: первая строка группы (значения выражений GROUP BY в ней общие для группы) и агрегатные функции.
+type group struct {
+	row  []Value
+	aggs []aggregate
+}
+
+// Execute выполняет запрос к таблице и возвращает не больше maxRows строк (0 - без ограничения).
+// Запрос прерывается при отмене ctx.
+func Execute(ctx context.Context, stmt *Select, table Table, maxRows int) (*Result, error) {
+	q, err := bind(stmt, table.Columns())
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []resultRow
+
+	if q.aggregated() {
+		rows, err = q.aggregate(ctx, table)
+	} else {
+		rows, err = q.project(ctx, table, maxRows)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if stmt.distinct {
+		rows = distinct(rows)
+	}
+
+	if len(stmt.orderBy) > 0 {
+		sort.SliceStable(rows, func(i, j int) bool { return q.less(rows[i].keys, rows[j].keys) })
+	}
+
+	rows = window(rows, stmt.offset, stmt.limit)
+
+	res := &Result{Columns: make([]string, 0, len(q.outputs)), Rows: make([][]Value, 0, len(rows))}
+
+	for _, out := range q.outputs {
+		res.Columns = append(res.Columns, out.name)
+	}
+
+	if maxRows > 0 && len(rows) > maxRows {
+		rows, res.Truncated = rows[:maxRows], true
+	}
+
+	for _, row := range rows {
+		res.Rows = append(res.Rows, row.values)
+	}
+
+	return res, nil
+}
+
+// query запрос, выражения которого привязаны к столбцам таблицы.
+type query struct {
+	*Select
+
+	width   int // количество столбцов таблицы
+	outputs []output
+	order   []Expr
+	aggs    []*call
+}
+
+// bind раскрывает *, заменяет псевдонимы и номера столбцов результата в GROUP BY и ORDER BY их выражениями,
+// привязывает ссылки на столбцы к номерам столбцов таблицы и нумерует агрегатные функции.
+func bind(stmt *Select, columns []string) (*query, error) {
+	q := &query{Select: stmt, width: len(columns)}
+
+	indexes := make(map[string]int, len(columns))
+	for i, name := range columns {
+		indexes[name] = i
+	}
+
+	for _, column := range stmt.columns {
+		if !column.star {
+			name := column.alias
+			if name == "" {
+				name = column.expr.String()
+			}
+
+			q.outputs = append(q.outputs, output{name: name, expr: column.expr})
+
+			continue
+		}
+
+		for i, name := range columns {
+			q.outputs = append(q.outputs, output{name: name, expr: &columnRef{name: name, index: i}})
+		}
+	}
+
+	for i, expr := range stmt.groupBy {
+		resolved, err := q.resolveOutput(expr, indexes, false)
+		if err != nil {
+			return nil, err
+		}
+
+		stmt.groupBy[i] = resolved
+	}
+
+	for _, item := range stmt.orderBy {
+		resolved, err := q.resolveOutput(item.expr, indexes, true)
+		if err != nil {
+			return nil, err
+		}
+
+		q.order = append(q.order, resolved)
+	}
+
+	// ссылки на столбцы и агрегатные функции
+	bindColumns := func(e Expr) error {
+		ref, ok := e.(*columnRef)
+		if !ok {
+			return nil
+		}
+
+		i, ok := indexes[ref.name]
+		if !ok {
+			return fmt.Errorf("%w: unknown column %s", ErrInvalidQuery, ref.name)
+		}
+
+		ref.index = i
+
+		return nil
+	}
+
+	noAggregates := func(clause string) func(Expr) error {
+		return func(e Expr) error {
+			if fn, ok := e.(*call); ok && isAggregate(fn.name) {
+				return fmt.Errorf("%w: aggregate function %s not allowed in %s", ErrInvalidQuery, fn, clause)
+			}
+
+			return bindColumns(e)
+		}
+	}
+
+	// выражения ORDER BY, замененные выражениями столбцов результата, содержат те же агрегатные функции
+	collected := make(map[*call]bool)
+
+	collectAggregates := func(e Expr) error {
+		fn, ok := e.(*call)
+		if !ok || !isAggregate(fn.name) {
+			return bindColumns(e)
+		}
+
+		if collected[fn] {
+			return errSkip
+		}
+
+		collected[fn] = true
+
+		for _, arg := range fn.args {
+			if err := walk(arg, noAggregates("aggregate function")); err != nil {
+				return err
+			}
+		}
+
+		fn.slot = len(q.aggs)
+		q.aggs = append(q.aggs, fn)
+
+		return errSkip
+	}
+
+	if stmt.where != nil {
+		if err := walk(stmt.where, noAggregates("WHERE")); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, expr := range stmt.groupBy {
+		if err := walk(expr, noAggregates("GROUP BY")); err != nil {
+			return nil, err
+		}
+	}
+
+	exprs := append([]Expr(nil), q.order...)
+	for _, out := range q.outputs {
+		exprs = append(exprs, out.expr)
+	}
+
+	if stmt.having != nil {
+		exprs = append(exprs, stmt.having)
+	}
+
+	for _, expr := range exprs {
+		if err := walk(expr, collectAggregates); err != nil {
+			return nil, err
+		}
+	}
+
+	if !q.aggregated() {
+		return q, nil
+	}
+
+	// в запросе с группировкой столбцы вне агрегатных функций должны входить в GROUP BY
+	grouped := make(map[string]bool, len(stmt.groupBy))
+	for _, expr := range stmt.groupBy {
+		grouped[expr.String()] = true
+	}
+
+	for _, expr := range exprs {
+		err := walk(expr, func(e Expr) error {
+			if grouped[e.String()] {
+				return errSkip
+			}
+
+			switch e := e.(type) {
+			case *call:
+				if isAggregate(e.name) {
+					return errSkip
+				}
+			case *columnRef:
+				return fmt.Errorf("%w: column %s must appear in GROUP BY or be used in aggregate function", ErrInvalidQuery, e.name)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return q, nil
+}
+
+// resolveOutput заменяет номер столбца результата (1, 2, ...) или псевдоним его выражением. Псевдоним в ORDER BY
+// имеет приоритет перед столбцом таблицы с тем же именем, в GROUP BY - наоборот.
+func (q *query) resolveOutput(expr Expr, indexes map[string]int, preferAlias bool) (Expr, error) {
+	switch e := expr.(type) {
+	case *literal:
+		d, ok := e.value.(decimal.Decimal)
+		if !ok {
+			return expr, nil
+		}
+
+		n := d.IntPart()
+		if !d.IsInteger() || n < 1 || n > int64(len(q.outputs)) {
+			return nil, fmt.Errorf("%w: column position %s out of range", ErrInvalidQuery, d)
+		}
+
+		return q.outputs[n-1].expr, nil
+	case *columnRef:
+		if _, isColumn := indexes[e.name]; isColumn && !preferAlias {
+			return expr, nil
+		}
+
+		for _, column := range q.Select.columns {
+			if column.alias == e.name {
+				return column.expr, nil
+			}
+		}
+	}
+
+	return expr, nil
+}
+
+func (q *query) aggregated() bool {
+	return len(q.groupBy) > 0 || len(q.aggs) > 0 || q.having != nil
+}
+
+// project выполняет запрос без группировки. Без сортировки и DISTINCT перебор останавливается, как только
+// получены строки LIMIT (и строка сверх maxRows, по которой определяется усечение результата).
+func (q *query) project(ctx context.Context, table Table, maxRows int) ([]resultRow, error) {
+	need := int64(-1)
+
+	if len(q.orderBy) == 0 && !q.distinct {
+		if q.limit >= 0 {
+			need = q.offset + q.limit
+		}
+
+		if maxRows > 0 && (need < 0 || need > q.offset+int64(maxRows)+1) {
+			need = q.offset + int64(maxRows) + 1
+		}
+	}
+
+	var rows []resultRow
+
+	err := q.scan(ctx, table, func(row []Value) error {
+		if need >= 0 && int64(len(rows)) >= need {
+			return errStop
+		}
+
+		res, ok, err := q.result(&env{row: row})
+		if ok {
+			rows = append(rows, res)
+		}
+
+		return err
+	})
+
+	return rows, err
+}
+
+// aggregate выполняет запрос с группировкой.
+func (q *query) aggregate(ctx context.Context, table Table) ([]resultRow, error) {
+	var (
+		groups = make(map[string]*group)
+		order  []*group
+		key    strings.Builder
+	)
+
+	err := q.scan(ctx, table, func(row []Value) error {
+		key.Reset()
+
+		for _, expr := range q.groupBy {
+			v, err := expr.eval(&env{row: row})
+			if err != nil {
+				return err
+			}
+
+			key.WriteString(valueKey(v))
+		}
+
+		g, ok := groups[key.String()]
+		if !ok {
+			g = q.newGroup(append([]Value(nil), row...))
+			groups[key.String()] = g
+			order = append(order, g)
+		}
+
+		for i, fn := range q.aggs {
+			var v Value = true // COUNT(*) считает все строки
+
+			if !fn.star {
+				var err error
+				if v, err = fn.args[0].eval(&env{row: row}); err != nil {
+					return err
+				}
+			}
+
+			if err := g.aggs[i].add(v); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// без GROUP BY результат - одна строка, даже если строк нет
+	if len(order) == 0 && len(q.groupBy) == 0 {
+		order = append(order, q.newGroup(make([]Value, q.width)))
+	}
+
+	rows := make([]resultRow, 0, len(order))
+
+	for _, g := range order {
+		aggs := make([]Value, 0, len(g.aggs))
+		for _, agg := range g.aggs {
+			aggs = append(aggs, agg.result())
+		}
+
+		res, ok, err := q.result(&env{row: g.row, aggs: aggs})
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			rows = append(rows, res)
+		}
+	}
+
+	return rows, nil
+}
+
+func (q *query) newGroup(row []Value) *group {
+	g := &group{row: row, aggs: make([]aggregate, 0, len(q.aggs))}
+
+	for _, fn := range q.aggs {
+		agg := aggregateFunctions[fn.name]()
+		if fn.distinct {
+			agg = &distinctAgg{aggregate: agg, seen: make(map[string]bool)}
+		}
+
+		g.aggs = append(g.aggs, agg)
+	}
+
+	return g
+}
+
+// scan передает fn строки таблицы, удовлетворяющие WHERE.
+func (q *query) scan(ctx context.Context, table Table, fn func(row []Value) error) error {
+	n := 0
+
+	err := table.Scan(ctx, func(row []Value) error {
+		if n++; n%checkEvery == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+
+		if q.where != nil {
+			ok, err := isTrue(q.where, &env{row: row})
+			if err != nil || !ok {
+				return err
+			}
+		}
+
+		return fn(row)
+	})
+	if errors.Is(err, errStop) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return ctx.Err()
+}
+
+// result вычисляет строку результата в окружении строки или группы; ok = false, если группа не проходит HAVING.
+func (q *query) result(env *env) (resultRow, bool, error) {
+	if q.having != nil {
+		ok, err := isTrue(q.having, env)
+		if err != nil || !ok {
+			return resultRow{}, false, err
+		}
+	}
+
+	res := resultRow{values: make([]Value, 0, len(q.outputs)), keys: make([]Value, 0, len(q.order))}
+
+	for _, out := range q.outputs {
+		v, err := out.expr.eval(env)
+		if err != nil {
+			return resultRow{}, false, err
+		}
+
+		res.values = append(res.values, v)
+	}
+
+	for _, expr := range q.order {
+		v, err := expr.eval(env)
+		if err != nil {
+			return resultRow{}, false, err
+		}
+
+		res.keys = append(res.keys, v)
+	}
+
+	return res, true, nil
+}
+
+// less сравнивает строки по ORDER BY. NULL меньше любого значения.
+func (q *query) less(a, b []Value) bool {
+	for i, item := range q.orderBy {
+		c := sortCompare(a[i], b[i])
+		if c == 0 {
+			continue
+		}
+
+		return (c < 0) != item.desc
+	}
+
+	return false
+}
+
+// sortCompare сравнивает значения для сортировки: значения разных типов упорядочиваются по типу.
+func sortCompare(a, b Value) int {
+	if a != nil && b != nil {
+		if c, err := compare(a, b); err == nil {
+			return c
+		}
+	}
+
+	return typeOrder(a) - typeOrder(b)
+}
+
+func typeOrder(v Value) int {
+	switch v.(type) {
+	case nil:
+		return 0
+	case bool:
+		return 1
+	case decimal.Decimal:
+		return 2
+	case string:
+		return 3
+	default:
+		return 4
+	}
+}
+
+func isTrue(e Expr, env *env) (bool, error) {
+	v, err := e.eval(env)
+	if err != nil {
+		return false, err
+	}
+
+	b, ok := v.(bool)
+	if v != nil && !ok {
+		return false, fmt.Errorf("%w: condition %s is not boolean", ErrInvalidQuery, e)
+	}
+
+	return b, nil
+}
+
+func distinct(rows []resultRow) []resultRow {
+	seen := make(map[string]bool, len(rows))
+	res := rows[:0]
+
+	for _, row := range rows {
+		var key strings.Builder
+		for _, v := range row.values {
+			key.WriteString(valueKey(v))
+		}
+
+		if !seen[key.String()] {
+			seen[key.String()] = true
+			res = append(res, row)
+		}
+	}
+
+	return res
+}
+
+// window возвращает строки с offset, не больше limit (-1 - без ограничения).
+func window(rows []resultRow, offset, limit int64) []resultRow {
+	if offset >= int64(len(rows)) {
+		return nil
+	}
+
+	rows = rows[offset:]
+
+	if limit >= 0 && limit < int64(len(rows)) {
+		rows = rows[:limit]
+	}
+
+	return rows
+}
+
+// valueKey возвращает ключ значения для группировки: равные значения имеют равные ключи.
+func valueKey(v Value) string {
+	switch v := v.(type) {
+	case nil:
+		return "n\x00"
+	case decimal.Decimal:
+		return "d" + v.String() + "\x00"
+	case time.Time:
+		return fmt.Sprintf("t%d\x00", v.UnixNano())
+	default:
+		return fmt.Sprintf("%T%v\x00", v, v)
+	}
+}
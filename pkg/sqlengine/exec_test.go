@@ -0,0 +1,228 @@
+package sqlengine
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memTable таблица в памяти.
+type memTable struct {
+	columns []string
+	rows    [][]Value
+	scanned int
+}
+
+func (t *memTable) Columns() []string {
+	return t.columns
+}
+
+func (t *memTable) Scan(ctx context.Context, fn func(row []Value) error) error {
+	for _, row := range t.rows {
+		t.scanned++
+
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func salesTable() *memTable {
+	dt := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+
+	t := &memTable{columns: []string{"sale_date", "store_id", "product_id", "quantity", "gross", "payment_method"}}
+
+	payments := []Value{"card", nil, "cash"}
+
+	for i := 0; i < 12; i++ {
+		t.rows = append(t.rows, []Value{
+			dt.Add(time.Duration(i) * 12 * time.Hour),
+			fmt.Sprintf("store_%d", i%2),
+			fmt.Sprintf("product_%d", i%3),
+			decimal.NewFromInt(int64(i%4 + 1)),
+			decimal.NewFromInt(int64(10 * (i + 1))),
+			payments[i%3],
+		})
+	}
+
+	return t
+}
+
+func TestExecute(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name    string
+		query   string
+		columns []string
+		rows    [][]string
+	}{
+		{
+			name:    "фильтр, сортировка и лимит",
+			query:   "SELECT product_id, gross FROM sales WHERE store_id = 'store_1' AND gross > 30 ORDER BY gross DESC LIMIT 3",
+			columns: []string{"product_id", "gross"},
+			rows:    [][]string{{"product_2", "120"}, {"product_0", "100"}, {"product_1", "80"}},
+		},
+		{
+			name:    "группировка с агрегатами и псевдонимами",
+			query:   "select store_id, count(*) AS n, sum(gross) total, avg(quantity), min(sale_date) from sales group by store_id order by total desc",
+			columns: []string{"store_id", "n", "total", "avg(quantity)", "min(sale_date)"},
+			rows: [][]string{
+				{"store_1", "6", "420", "3", "2024-06-01T22:00:00Z"},
+				{"store_0", "6", "360", "2", "2024-06-01T10:00:00Z"},
+			},
+		},
+		{
+			name:    "группировка по выражению и номер столбца в ORDER BY",
+			query:   "SELECT date_trunc('day', sale_date) AS day, sum(gross) FROM sales GROUP BY day ORDER BY 1 LIMIT 2 OFFSET 1",
+			columns: []string{"day", "sum(gross)"},
+			rows:    [][]string{{"2024-06-02T00:00:00Z", "70"}, {"2024-06-03T00:00:00Z", "110"}},
+		},
+		{
+			name:    "NULL в группировке и HAVING",
+			query:   "SELECT coalesce(payment_method, 'unspecified') AS payment, count(*) FROM sales GROUP BY payment HAVING sum(gross) > 250 ORDER BY payment",
+			columns: []string{"payment", "count(*)"},
+			rows:    [][]string{{"cash", "4"}, {"unspecified", "4"}}, // card: 10 + 40 + 70 + 100
+		},
+		{
+			name:    "IS NULL, IN, BETWEEN и LIKE",
+			query:   "SELECT gross FROM sales WHERE payment_method IS NULL AND product_id IN ('product_1', 'product_2') AND gross BETWEEN 20 AND 80 AND store_id LIKE '%_1'",
+			columns: []string{"gross"},
+			rows:    [][]string{{"20"}, {"80"}},
+		},
+		{
+			name:    "сравнение момента со строкой и арифметика",
+			query:   "SELECT gross / quantity AS price, -gross + 5 FROM sales WHERE sale_date >= '2024-06-06' AND NOT store_id = 'store_0'",
+			columns: []string{"price", "(-gross + 5)"},
+			rows:    [][]string{{"30", "-115"}},
+		},
+		{
+			name:    "агрегат без строк",
+			query:   "SELECT count(*), sum(gross) FROM sales WHERE gross < 0",
+			columns: []string{"count(*)", "sum(gross)"},
+			rows:    [][]string{{"0", "<nil>"}},
+		},
+		{
+			name:    "DISTINCT и COUNT DISTINCT",
+			query:   "SELECT DISTINCT store_id, count(DISTINCT product_id) FROM sales GROUP BY store_id ORDER BY store_id",
+			columns: []string{"store_id", "count(DISTINCT product_id)"},
+			rows:    [][]string{{"store_0", "3"}, {"store_1", "3"}},
+		},
+		{
+			name:    "все столбцы",
+			query:   "SELECT * FROM sales WHERE gross = 10;",
+			columns: []string{"sale_date", "store_id", "product_id", "quantity", "gross", "payment_method"},
+			rows:    [][]string{{"2024-06-01T10:00:00Z", "store_0", "product_0", "1", "10", "card"}},
+		},
+	}
+
+	for _, tt := range testCases {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			stmt, err := Parse(tt.query)
+			require.NoError(t, err)
+			assert.Equal(t, "sales", stmt.From)
+
+			res, err := Execute(context.Background(), stmt, salesTable(), 0)
+			require.NoError(t, err)
+			assert.Equal(t, tt.columns, res.Columns)
+			assert.Equal(t, tt.rows, format(res.Rows))
+			assert.False(t, res.Truncated)
+		})
+	}
+}
+
+func TestExecute_Errors(t *testing.T) {
+	t.Parallel()
+
+	queries := map[string]string{
+		"SELECT price FROM sales":                                       "unknown column price",
+		"SELECT store_id, sum(gross) FROM sales":                        "column store_id must appear in GROUP BY",
+		"SELECT store_id FROM sales WHERE sum(gross) > 10":              "aggregate function sum(gross) not allowed in WHERE",
+		"SELECT sum(count(*)) FROM sales":                               "aggregate function count(*) not allowed in aggregate function",
+		"SELECT gross FROM sales WHERE store_id > 1":                    "cannot compare string with number",
+		"SELECT gross FROM sales WHERE gross":                           "condition gross is not boolean",
+		"SELECT gross / 0 FROM sales":                                   "division by zero",
+		"SELECT store_id FROM sales ORDER BY 2":                         "column position 2 out of range",
+		"SELECT date_trunc('decade', sale_date) FROM sales":             `unknown date_trunc unit "decade"`,
+		"SELECT gross FROM sales WHERE sale_date > 'yesterday'":         `invalid time "yesterday"`,
+		"SELECT sum(store_id) FROM sales":                               "invalid operand of sum: string",
+		"SELECT upper(gross) FROM sales WHERE gross > 0 AND gross < 20": "invalid operand of string function: number",
+	}
+
+	for query, msg := range queries {
+		stmt, err := Parse(query)
+		require.NoError(t, err, query)
+
+		_, err = Execute(context.Background(), stmt, salesTable(), 0)
+		assert.ErrorIs(t, err, ErrInvalidQuery, query)
+		assert.ErrorContains(t, err, msg, query)
+	}
+}
+
+func TestExecute_Limits(t *testing.T) {
+	t.Parallel()
+
+	// без сортировки перебор останавливается после строк LIMIT
+	table := salesTable()
+
+	stmt, err := Parse("SELECT gross FROM sales LIMIT 2")
+	require.NoError(t, err)
+
+	res, err := Execute(context.Background(), stmt, table, 0)
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"10"}, {"20"}}, format(res.Rows))
+	assert.Equal(t, 3, table.scanned)
+
+	// результат усекается до максимального количества строк
+	for _, query := range []string{"SELECT gross FROM sales", "SELECT gross FROM sales ORDER BY gross DESC"} {
+		stmt, err = Parse(query)
+		require.NoError(t, err)
+
+		res, err = Execute(context.Background(), stmt, salesTable(), 5)
+		require.NoError(t, err)
+		assert.Len(t, res.Rows, 5, query)
+		assert.True(t, res.Truncated, query)
+	}
+
+	// отмененный запрос прерывается
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	stmt, err = Parse("SELECT count(*) FROM sales")
+	require.NoError(t, err)
+
+	_, err = Execute(ctx, stmt, salesTable(), 0)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+// format возвращает строки результата в строковом виде.
+func format(rows [][]Value) [][]string {
+	res := make([][]string, 0, len(rows))
+
+	for _, row := range rows {
+		values := make([]string, 0, len(row))
+
+		for _, v := range row {
+			if t, ok := v.(time.Time); ok {
+				v = t.Format(time.RFC3339)
+			}
+
+			values = append(values, fmt.Sprint(v))
+		}
+
+		res = append(res, values)
+	}
+
+	return res
+}
@@ -0,0 +1,531 @@
+package sqlengine
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Value значение столбца или выражения: nil (NULL), bool, string, decimal.Decimal или time.Time.
+type Value = interface{}
+
+// Expr выражение запроса.
+type Expr interface {
+	String() string
+	eval(env *env) (Value, error)
+}
+
+// env окружение вычисления выражения: строка таблицы и результаты агрегатных функций группы.
+type env struct {
+	row  []Value
+	aggs []Value
+}
+
+type literal struct {
+	value Value
+}
+
+func (e *literal) String() string {
+	switch v := e.value.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return "'" + strings.ReplaceAll(v, "'", "''") + "'"
+	case bool:
+		return strings.ToUpper(fmt.Sprint(v))
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func (e *literal) eval(*env) (Value, error) {
+	return e.value, nil
+}
+
+// columnRef ссылка на столбец таблицы, index - номер столбца (определяется при привязке к таблице).
+type columnRef struct {
+	name  string
+	index int
+}
+
+func (e *columnRef) String() string {
+	return e.name
+}
+
+func (e *columnRef) eval(env *env) (Value, error) {
+	return env.row[e.index], nil
+}
+
+type unary struct {
+	op string // "-" или "NOT"
+	x  Expr
+}
+
+func (e *unary) String() string {
+	if e.op == "NOT" {
+		return "NOT " + e.x.String()
+	}
+
+	return e.op + e.x.String()
+}
+
+func (e *unary) eval(env *env) (Value, error) {
+	v, err := e.x.eval(env)
+	if err != nil || v == nil {
+		return nil, err
+	}
+
+	if e.op == "NOT" {
+		b, ok := v.(bool)
+		if !ok {
+			return nil, typeError("NOT", v)
+		}
+
+		return !b, nil
+	}
+
+	d, ok := v.(decimal.Decimal)
+	if !ok {
+		return nil, typeError(e.op, v)
+	}
+
+	return d.Neg(), nil
+}
+
+type binary struct {
+	op          string
+	left, right Expr
+
+	// последний шаблон LIKE и его регулярное выражение: шаблон обычно константа и компилируется один раз
+	likeSrc string
+	like    *regexp.Regexp
+}
+
+func (e *binary) String() string {
+	return "(" + e.left.String() + " " + e.op + " " + e.right.String() + ")"
+}
+
+func (e *binary) eval(env *env) (Value, error) {
+	left, err := e.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	// AND и OR вычисляются по трехзначной логике: FALSE AND NULL - FALSE, TRUE OR NULL - TRUE
+	if e.op == "AND" || e.op == "OR" {
+		return e.logical(left, env)
+	}
+
+	right, err := e.right.eval(env)
+	if err != nil || left == nil || right == nil {
+		return nil, err
+	}
+
+	switch e.op {
+	case "=", "<>", "!=", "<", "<=", ">", ">=":
+		c, err := compare(left, right)
+		if err != nil {
+			return nil, err
+		}
+
+		return compareOp(e.op, c), nil
+	case "LIKE":
+		s, ok1 := left.(string)
+		pattern, ok2 := right.(string)
+		if !ok1 || !ok2 {
+			return nil, fmt.Errorf("%w: LIKE requires strings", ErrInvalidQuery)
+		}
+
+		if e.like == nil || e.likeSrc != pattern {
+			e.likeSrc, e.like = pattern, regexp.MustCompile(likePattern(pattern))
+		}
+
+		return e.like.MatchString(s), nil
+	default:
+		return arithmetic(e.op, left, right)
+	}
+}
+
+func (e *binary) logical(left Value, env *env) (Value, error) {
+	l, err := asBool(e.op, left)
+	if err != nil {
+		return nil, err
+	}
+
+	if l != nil && *l == (e.op == "OR") {
+		return *l, nil
+	}
+
+	right, err := e.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := asBool(e.op, right)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case r != nil && *r == (e.op == "OR"):
+		return *r, nil
+	case l == nil || r == nil:
+		return nil, nil
+	default:
+		return *r, nil
+	}
+}
+
+type inExpr struct {
+	x    Expr
+	list []Expr
+	not  bool
+}
+
+func (e *inExpr) String() string {
+	items := make([]string, 0, len(e.list))
+	for _, item := range e.list {
+		items = append(items, item.String())
+	}
+
+	return "(" + e.x.String() + not(e.not) + " IN (" + strings.Join(items, ", ") + "))"
+}
+
+func (e *inExpr) eval(env *env) (Value, error) {
+	v, err := e.x.eval(env)
+	if err != nil || v == nil {
+		return nil, err
+	}
+
+	hasNull := false
+
+	for _, item := range e.list {
+		w, err := item.eval(env)
+		if err != nil {
+			return nil, err
+		}
+
+		if w == nil {
+			hasNull = true
+			continue
+		}
+
+		c, err := compare(v, w)
+		if err != nil {
+			return nil, err
+		}
+
+		if c == 0 {
+			return !e.not, nil
+		}
+	}
+
+	if hasNull {
+		return nil, nil
+	}
+
+	return e.not, nil
+}
+
+type betweenExpr struct {
+	x, low, high Expr
+	not          bool
+}
+
+func (e *betweenExpr) String() string {
+	return "(" + e.x.String() + not(e.not) + " BETWEEN " + e.low.String() + " AND " + e.high.String() + ")"
+}
+
+func (e *betweenExpr) eval(env *env) (Value, error) {
+	values := make([]Value, 0, 3)
+
+	for _, x := range []Expr{e.x, e.low, e.high} {
+		v, err := x.eval(env)
+		if err != nil || v == nil {
+			return nil, err
+		}
+
+		values = append(values, v)
+	}
+
+	low, err := compare(values[0], values[1])
+	if err != nil {
+		return nil, err
+	}
+
+	high, err := compare(values[0], values[2])
+	if err != nil {
+		return nil, err
+	}
+
+	return (low >= 0 && high <= 0) != e.not, nil
+}
+
+type isNull struct {
+	x   Expr
+	not bool
+}
+
+func (e *isNull) String() string {
+	return "(" + e.x.String() + " IS" + not(e.not) + " NULL)"
+}
+
+func (e *isNull) eval(env *env) (Value, error) {
+	v, err := e.x.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	return (v == nil) != e.not, nil
+}
+
+// call вызов функции. Для агрегатной функции slot - номер ее результата в окружении группы.
+type call struct {
+	name     string
+	args     []Expr
+	star     bool // COUNT(*)
+	distinct bool
+	slot     int
+}
+
+func (e *call) String() string {
+	if e.star {
+		return e.name + "(*)"
+	}
+
+	args := make([]string, 0, len(e.args))
+	for _, arg := range e.args {
+		args = append(args, arg.String())
+	}
+
+	distinct := ""
+	if e.distinct {
+		distinct = "DISTINCT "
+	}
+
+	return e.name + "(" + distinct + strings.Join(args, ", ") + ")"
+}
+
+func (e *call) eval(env *env) (Value, error) {
+	if isAggregate(e.name) {
+		return env.aggs[e.slot], nil
+	}
+
+	args := make([]Value, 0, len(e.args))
+
+	for _, arg := range e.args {
+		v, err := arg.eval(env)
+		if err != nil {
+			return nil, err
+		}
+
+		args = append(args, v)
+	}
+
+	return scalarFunctions[e.name].fn(args)
+}
+
+func not(negated bool) string {
+	if negated {
+		return " NOT"
+	}
+
+	return ""
+}
+
+// errSkip возвращается функцией обхода walk, чтобы не обходить подвыражения выражения.
+var errSkip = errors.New("skip children")
+
+// walk вызывает fn для выражения и всех его подвыражений.
+func walk(e Expr, fn func(Expr) error) error {
+	if err := fn(e); errors.Is(err, errSkip) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	var children []Expr
+
+	switch e := e.(type) {
+	case *unary:
+		children = []Expr{e.x}
+	case *binary:
+		children = []Expr{e.left, e.right}
+	case *inExpr:
+		children = append([]Expr{e.x}, e.list...)
+	case *betweenExpr:
+		children = []Expr{e.x, e.low, e.high}
+	case *isNull:
+		children = []Expr{e.x}
+	case *call:
+		children = e.args
+	}
+
+	for _, child := range children {
+		if err := walk(child, fn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// compare сравнивает непустые значения одного типа. Момент времени сравнивается и со строкой
+// в формате RFC3339, YYYY-MM-DD или YYYY-MM-DD HH:MM:SS (UTC).
+func compare(a, b Value) (int, error) {
+	switch x := a.(type) {
+	case decimal.Decimal:
+		if y, ok := b.(decimal.Decimal); ok {
+			return x.Cmp(y), nil
+		}
+	case string:
+		switch y := b.(type) {
+		case string:
+			return strings.Compare(x, y), nil
+		case time.Time:
+			c, err := compare(y, x)
+			return -c, err
+		}
+	case bool:
+		if y, ok := b.(bool); ok {
+			switch {
+			case x == y:
+				return 0, nil
+			case y:
+				return -1, nil
+			default:
+				return 1, nil
+			}
+		}
+	case time.Time:
+		switch y := b.(type) {
+		case time.Time:
+			return x.Compare(y), nil
+		case string:
+			t, err := parseTime(y)
+			if err != nil {
+				return 0, err
+			}
+
+			return x.Compare(t), nil
+		}
+	}
+
+	return 0, fmt.Errorf("%w: cannot compare %s with %s", ErrInvalidQuery, typeName(a), typeName(b))
+}
+
+func compareOp(op string, c int) bool {
+	switch op {
+	case "=":
+		return c == 0
+	case "<>", "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	default:
+		return c >= 0
+	}
+}
+
+var timeLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02"}
+
+func parseTime(s string) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("%w: invalid time %q", ErrInvalidQuery, s)
+}
+
+func arithmetic(op string, left, right Value) (Value, error) {
+	x, ok1 := left.(decimal.Decimal)
+	y, ok2 := right.(decimal.Decimal)
+	if !ok1 || !ok2 {
+		return nil, fmt.Errorf("%w: operator %s requires numbers, got %s and %s", ErrInvalidQuery, op, typeName(left), typeName(right))
+	}
+
+	switch op {
+	case "+":
+		return x.Add(y), nil
+	case "-":
+		return x.Sub(y), nil
+	case "*":
+		return x.Mul(y), nil
+	}
+
+	if y.IsZero() {
+		return nil, fmt.Errorf("%w: division by zero", ErrInvalidQuery)
+	}
+
+	if op == "/" {
+		return x.Div(y), nil
+	}
+
+	return x.Mod(y), nil
+}
+
+func asBool(op string, v Value) (*bool, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	b, ok := v.(bool)
+	if !ok {
+		return nil, typeError(op, v)
+	}
+
+	return &b, nil
+}
+
+// likePattern возвращает регулярное выражение шаблона LIKE: % - любая последовательность символов, _ - один символ.
+func likePattern(pattern string) string {
+	var b strings.Builder
+
+	b.WriteString("(?s)^")
+
+	for _, r := range pattern {
+		switch r {
+		case '%':
+			b.WriteString(".*")
+		case '_':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+
+	b.WriteString("$")
+
+	return b.String()
+}
+
+func typeError(op string, v Value) error {
+	return fmt.Errorf("%w: invalid operand of %s: %s", ErrInvalidQuery, op, typeName(v))
+}
+
+func typeName(v Value) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case decimal.Decimal:
+		return "number"
+	case time.Time:
+		return "timestamp"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
@@ -0,0 +1,246 @@
+package sqlengine
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// aggregate состояние агрегатной функции группы.
+type aggregate interface {
+	add(v Value) error
+	result() Value
+}
+
+var aggregateFunctions = map[string]func() aggregate{
+	"count": func() aggregate { return &countAgg{} },
+	"sum":   func() aggregate { return &sumAgg{name: "sum"} },
+	"avg":   func() aggregate { return &avgAgg{sumAgg{name: "avg"}} },
+	"min":   func() aggregate { return &extremumAgg{sign: -1} },
+	"max":   func() aggregate { return &extremumAgg{sign: 1} },
+}
+
+func isAggregate(name string) bool {
+	_, ok := aggregateFunctions[name]
+
+	return ok
+}
+
+// countAgg количество непустых значений (для COUNT(*) значение каждой строки - TRUE).
+type countAgg struct {
+	n int64
+}
+
+func (a *countAgg) add(v Value) error {
+	if v != nil {
+		a.n++
+	}
+
+	return nil
+}
+
+func (a *countAgg) result() Value {
+	return decimal.NewFromInt(a.n)
+}
+
+// sumAgg сумма непустых значений, NULL, если значений нет.
+type sumAgg struct {
+	name  string
+	sum   decimal.Decimal
+	count int64
+}
+
+func (a *sumAgg) add(v Value) error {
+	if v == nil {
+		return nil
+	}
+
+	d, ok := v.(decimal.Decimal)
+	if !ok {
+		return typeError(a.name, v)
+	}
+
+	a.sum = a.sum.Add(d)
+	a.count++
+
+	return nil
+}
+
+func (a *sumAgg) result() Value {
+	if a.count == 0 {
+		return nil
+	}
+
+	return a.sum
+}
+
+type avgAgg struct {
+	sumAgg
+}
+
+func (a *avgAgg) result() Value {
+	if a.count == 0 {
+		return nil
+	}
+
+	return a.sum.Div(decimal.NewFromInt(a.count))
+}
+
+// extremumAgg минимум (sign = -1) или максимум (sign = 1) непустых значений.
+type extremumAgg struct {
+	sign  int
+	value Value
+}
+
+func (a *extremumAgg) add(v Value) error {
+	if v == nil {
+		return nil
+	}
+
+	if a.value == nil {
+		a.value = v
+		return nil
+	}
+
+	c, err := compare(v, a.value)
+	if err != nil {
+		return err
+	}
+
+	if c*a.sign > 0 {
+		a.value = v
+	}
+
+	return nil
+}
+
+func (a *extremumAgg) result() Value {
+	return a.value
+}
+
+// distinctAgg передает агрегатной функции только первое вхождение каждого значения.
+type distinctAgg struct {
+	aggregate
+	seen map[string]bool
+}
+
+func (a *distinctAgg) add(v Value) error {
+	key := valueKey(v)
+	if a.seen[key] {
+		return nil
+	}
+
+	a.seen[key] = true
+
+	return a.aggregate.add(v)
+}
+
+// scalarFunction скалярная функция с количеством аргументов от minArgs до maxArgs (-1 - без ограничения).
+type scalarFunction struct {
+	minArgs, maxArgs int
+	fn               func(args []Value) (Value, error)
+}
+
+var scalarFunctions = map[string]scalarFunction{
+	"lower":      {1, 1, stringFunction(strings.ToLower)},
+	"upper":      {1, 1, stringFunction(strings.ToUpper)},
+	"abs":        {1, 1, abs},
+	"round":      {1, 2, round},
+	"coalesce":   {1, -1, coalesce},
+	"date_trunc": {2, 2, dateTrunc},
+}
+
+func stringFunction(fn func(string) string) func(args []Value) (Value, error) {
+	return func(args []Value) (Value, error) {
+		if args[0] == nil {
+			return nil, nil
+		}
+
+		s, ok := args[0].(string)
+		if !ok {
+			return nil, typeError("string function", args[0])
+		}
+
+		return fn(s), nil
+	}
+}
+
+func abs(args []Value) (Value, error) {
+	if args[0] == nil {
+		return nil, nil
+	}
+
+	d, ok := args[0].(decimal.Decimal)
+	if !ok {
+		return nil, typeError("abs", args[0])
+	}
+
+	return d.Abs(), nil
+}
+
+// round округляет число до places знаков после запятой (по умолчанию до целого).
+func round(args []Value) (Value, error) {
+	if args[0] == nil {
+		return nil, nil
+	}
+
+	d, ok := args[0].(decimal.Decimal)
+	if !ok {
+		return nil, typeError("round", args[0])
+	}
+
+	places := decimal.Zero
+	if len(args) == 2 {
+		if places, ok = args[1].(decimal.Decimal); !ok || !places.IsInteger() {
+			return nil, fmt.Errorf("%w: round places must be integer", ErrInvalidQuery)
+		}
+	}
+
+	return d.Round(int32(places.IntPart())), nil
+}
+
+func coalesce(args []Value) (Value, error) {
+	for _, v := range args {
+		if v != nil {
+			return v, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// dateTrunc возвращает начало часа, дня, недели (с понедельника), месяца или года момента в UTC.
+func dateTrunc(args []Value) (Value, error) {
+	unit, ok := args[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("%w: date_trunc unit must be string", ErrInvalidQuery)
+	}
+
+	if args[1] == nil {
+		return nil, nil
+	}
+
+	t, ok := args[1].(time.Time)
+	if !ok {
+		return nil, typeError("date_trunc", args[1])
+	}
+
+	t = t.UTC()
+
+	switch strings.ToLower(unit) {
+	case "hour":
+		return t.Truncate(time.Hour), nil
+	case "day":
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), nil
+	case "week":
+		return time.Date(t.Year(), t.Month(), t.Day()-(int(t.Weekday())+6)%7, 0, 0, 0, 0, time.UTC), nil
+	case "month":
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC), nil
+	case "year":
+		return time.Date(t.Year(), 1, 1, 0, 0, 0, 0, time.UTC), nil
+	default:
+		return nil, fmt.Errorf("%w: unknown date_trunc unit %q", ErrInvalidQuery, unit)
+	}
+}
@@ -0,0 +1,139 @@
+package sqlengine
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenKeyword
+	tokenNumber
+	tokenString
+	tokenOperator
+)
+
+// token лексема запроса. Ключевые слова приводятся к верхнему регистру, идентификаторы - к нижнему
+// (кроме идентификаторов в двойных кавычках).
+type token struct {
+	kind  tokenKind
+	value string
+	pos   int
+}
+
+func (t token) String() string {
+	if t.kind == tokenEOF {
+		return "end of query"
+	}
+
+	return fmt.Sprintf("%q at position %d", t.value, t.pos+1)
+}
+
+var keywords = map[string]bool{
+	"SELECT": true, "DISTINCT": true, "FROM": true, "WHERE": true, "GROUP": true, "BY": true, "HAVING": true,
+	"ORDER": true, "ASC": true, "DESC": true, "LIMIT": true, "OFFSET": true, "AS": true, "AND": true, "OR": true,
+	"NOT": true, "IN": true, "BETWEEN": true, "LIKE": true, "IS": true, "NULL": true, "TRUE": true, "FALSE": true,
+}
+
+var operators = map[string]bool{
+	"=": true, "<>": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true,
+	"+": true, "-": true, "*": true, "/": true, "%": true, "(": true, ")": true, ",": true, ";": true,
+}
+
+// lex разбивает запрос на лексемы.
+func lex(query string) ([]token, error) {
+	var (
+		tokens []token
+		runes  = []rune(query)
+	)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+
+			word := string(runes[start:i])
+			if upper := strings.ToUpper(word); keywords[upper] {
+				tokens = append(tokens, token{kind: tokenKeyword, value: upper, pos: start})
+			} else {
+				tokens = append(tokens, token{kind: tokenIdent, value: strings.ToLower(word), pos: start})
+			}
+		case unicode.IsDigit(r) || (r == '.' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+
+			tokens = append(tokens, token{kind: tokenNumber, value: string(runes[start:i]), pos: start})
+		case r == '\'' || r == '"':
+			value, next, err := lexQuoted(runes, i)
+			if err != nil {
+				return nil, err
+			}
+
+			kind := tokenString
+			if r == '"' {
+				kind = tokenIdent
+			}
+
+			tokens = append(tokens, token{kind: kind, value: value, pos: i})
+			i = next
+		default:
+			op := string(r)
+			if i+1 < len(runes) {
+				if two := string(runes[i : i+2]); two == "<=" || two == ">=" || two == "<>" || two == "!=" {
+					op = two
+				}
+			}
+
+			if !operators[op] {
+				return nil, fmt.Errorf("%w: unexpected character %q at position %d", ErrInvalidQuery, r, i+1)
+			}
+
+			tokens = append(tokens, token{kind: tokenOperator, value: op, pos: i})
+			i += len([]rune(op))
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF, pos: len(runes)}), nil
+}
+
+// lexQuoted разбирает строку в кавычках, начинающуюся в позиции start. Кавычка внутри строки удваивается.
+func lexQuoted(runes []rune, start int) (string, int, error) {
+	quote := runes[start]
+
+	var b strings.Builder
+
+	for i := start + 1; i < len(runes); i++ {
+		if runes[i] != quote {
+			b.WriteRune(runes[i])
+			continue
+		}
+
+		if i+1 < len(runes) && runes[i+1] == quote {
+			b.WriteRune(quote)
+			i++
+
+			continue
+		}
+
+		return b.String(), i + 1, nil
+	}
+
+	return "", 0, fmt.Errorf("%w: unterminated string at position %d", ErrInvalidQuery, start+1)
+}
@@ -0,0 +1,533 @@
+package sqlengine
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// ErrInvalidQuery синтаксическая или смысловая ошибка запроса.
+var ErrInvalidQuery = errors.New("invalid query")
+
+// Select разобранный запрос SELECT к таблице From.
+type Select struct {
+	From string
+
+	distinct bool
+	columns  []selectColumn
+	where    Expr
+	groupBy  []Expr
+	having   Expr
+	orderBy  []orderBy
+	limit    int64 // -1 - без ограничения
+	offset   int64
+}
+
+type selectColumn struct {
+	expr  Expr
+	alias string
+	star  bool // все столбцы таблицы
+}
+
+type orderBy struct {
+	expr Expr
+	desc bool
+}
+
+// Parse разбирает запрос: SELECT [DISTINCT] выражения FROM таблица [WHERE условие] [GROUP BY выражения]
+// [HAVING условие] [ORDER BY выражения [ASC|DESC]] [LIMIT n [OFFSET m]].
+func Parse(query string) (*Select, error) {
+	tokens, err := lex(query)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+
+	stmt, err := p.parseSelect()
+	if err != nil {
+		return nil, err
+	}
+
+	p.acceptOperator(";")
+
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, p.unexpected(tok)
+	}
+
+	return stmt, nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) parseSelect() (*Select, error) {
+	if err := p.expectKeyword("SELECT"); err != nil {
+		return nil, err
+	}
+
+	stmt := &Select{limit: -1, distinct: p.acceptKeyword("DISTINCT")}
+
+	for {
+		column, err := p.parseColumn()
+		if err != nil {
+			return nil, err
+		}
+
+		stmt.columns = append(stmt.columns, column)
+
+		if !p.acceptOperator(",") {
+			break
+		}
+	}
+
+	if err := p.expectKeyword("FROM"); err != nil {
+		return nil, err
+	}
+
+	tok := p.next()
+	if tok.kind != tokenIdent {
+		return nil, p.unexpected(tok)
+	}
+
+	stmt.From = tok.value
+
+	var err error
+
+	if p.acceptKeyword("WHERE") {
+		if stmt.where, err = p.parseExpr(); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.acceptKeyword("GROUP") {
+		if err := p.expectKeyword("BY"); err != nil {
+			return nil, err
+		}
+
+		if stmt.groupBy, err = p.parseExprList(); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.acceptKeyword("HAVING") {
+		if stmt.having, err = p.parseExpr(); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.acceptKeyword("ORDER") {
+		if err := p.expectKeyword("BY"); err != nil {
+			return nil, err
+		}
+
+		for {
+			expr, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+
+			desc := p.acceptKeyword("DESC")
+			if !desc {
+				p.acceptKeyword("ASC")
+			}
+
+			stmt.orderBy = append(stmt.orderBy, orderBy{expr: expr, desc: desc})
+
+			if !p.acceptOperator(",") {
+				break
+			}
+		}
+	}
+
+	if p.acceptKeyword("LIMIT") {
+		if stmt.limit, err = p.parseCount(); err != nil {
+			return nil, err
+		}
+
+		if p.acceptKeyword("OFFSET") {
+			if stmt.offset, err = p.parseCount(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return stmt, nil
+}
+
+func (p *parser) parseColumn() (selectColumn, error) {
+	if p.acceptOperator("*") {
+		return selectColumn{star: true}, nil
+	}
+
+	expr, err := p.parseExpr()
+	if err != nil {
+		return selectColumn{}, err
+	}
+
+	column := selectColumn{expr: expr}
+
+	if p.acceptKeyword("AS") || p.peek().kind == tokenIdent {
+		tok := p.next()
+		if tok.kind != tokenIdent {
+			return selectColumn{}, p.unexpected(tok)
+		}
+
+		column.alias = tok.value
+	}
+
+	return column, nil
+}
+
+// parseCount разбирает неотрицательное целое LIMIT или OFFSET.
+func (p *parser) parseCount() (int64, error) {
+	tok := p.next()
+	if tok.kind != tokenNumber {
+		return 0, p.unexpected(tok)
+	}
+
+	d, err := decimal.NewFromString(tok.value)
+	if err != nil || !d.IsInteger() {
+		return 0, fmt.Errorf("%w: expected integer, got %s", ErrInvalidQuery, tok)
+	}
+
+	return d.IntPart(), nil
+}
+
+func (p *parser) parseExprList() ([]Expr, error) {
+	var list []Expr
+
+	for {
+		expr, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+
+		list = append(list, expr)
+
+		if !p.acceptOperator(",") {
+			return list, nil
+		}
+	}
+}
+
+func (p *parser) parseExpr() (Expr, error) {
+	return p.parseLogical("OR", func() (Expr, error) {
+		return p.parseLogical("AND", p.parseNot)
+	})
+}
+
+// parseLogical разбирает последовательность операндов, соединенных оператором op (AND или OR).
+func (p *parser) parseLogical(op string, operand func() (Expr, error)) (Expr, error) {
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.acceptKeyword(op) {
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+
+		left = &binary{op: op, left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseNot() (Expr, error) {
+	if p.acceptKeyword("NOT") {
+		x, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+
+		return &unary{op: "NOT", x: x}, nil
+	}
+
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (Expr, error) {
+	left, err := p.parseArithmetic(additive)
+	if err != nil {
+		return nil, err
+	}
+
+	if tok := p.peek(); tok.kind == tokenOperator && isComparison(tok.value) {
+		p.next()
+
+		right, err := p.parseArithmetic(additive)
+		if err != nil {
+			return nil, err
+		}
+
+		return &binary{op: tok.value, left: left, right: right}, nil
+	}
+
+	if p.acceptKeyword("IS") {
+		negated := p.acceptKeyword("NOT")
+		if err := p.expectKeyword("NULL"); err != nil {
+			return nil, err
+		}
+
+		return &isNull{x: left, not: negated}, nil
+	}
+
+	negated := p.acceptKeyword("NOT")
+
+	switch {
+	case p.acceptKeyword("IN"):
+		if err := p.expectOperator("("); err != nil {
+			return nil, err
+		}
+
+		list, err := p.parseExprList()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := p.expectOperator(")"); err != nil {
+			return nil, err
+		}
+
+		return &inExpr{x: left, list: list, not: negated}, nil
+	case p.acceptKeyword("BETWEEN"):
+		low, err := p.parseArithmetic(additive)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := p.expectKeyword("AND"); err != nil {
+			return nil, err
+		}
+
+		high, err := p.parseArithmetic(additive)
+		if err != nil {
+			return nil, err
+		}
+
+		return &betweenExpr{x: left, low: low, high: high, not: negated}, nil
+	case p.acceptKeyword("LIKE"):
+		pattern, err := p.parseArithmetic(additive)
+		if err != nil {
+			return nil, err
+		}
+
+		var expr Expr = &binary{op: "LIKE", left: left, right: pattern}
+		if negated {
+			expr = &unary{op: "NOT", x: expr}
+		}
+
+		return expr, nil
+	case negated:
+		return nil, p.unexpected(p.peek())
+	default:
+		return left, nil
+	}
+}
+
+func isComparison(op string) bool {
+	switch op {
+	case "=", "<>", "!=", "<", "<=", ">", ">=":
+		return true
+	default:
+		return false
+	}
+}
+
+// уровни арифметических операторов
+const (
+	additive = iota
+	multiplicative
+)
+
+func (p *parser) parseArithmetic(level int) (Expr, error) {
+	operand := func() (Expr, error) {
+		if level == additive {
+			return p.parseArithmetic(multiplicative)
+		}
+
+		return p.parseUnary()
+	}
+
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+
+	ops := "+-"
+	if level == multiplicative {
+		ops = "*/%"
+	}
+
+	for {
+		tok := p.peek()
+		if tok.kind != tokenOperator || len(tok.value) != 1 || !strings.Contains(ops, tok.value) {
+			return left, nil
+		}
+
+		p.next()
+
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+
+		left = &binary{op: tok.value, left: left, right: right}
+	}
+}
+
+func (p *parser) parseUnary() (Expr, error) {
+	if p.acceptOperator("-") {
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		return &unary{op: "-", x: x}, nil
+	}
+
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (Expr, error) {
+	tok := p.next()
+
+	switch tok.kind {
+	case tokenNumber:
+		d, err := decimal.NewFromString(tok.value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid number %s", ErrInvalidQuery, tok)
+		}
+
+		return &literal{value: d}, nil
+	case tokenString:
+		return &literal{value: tok.value}, nil
+	case tokenKeyword:
+		switch tok.value {
+		case "NULL":
+			return &literal{}, nil
+		case "TRUE", "FALSE":
+			return &literal{value: tok.value == "TRUE"}, nil
+		}
+	case tokenIdent:
+		if p.acceptOperator("(") {
+			return p.parseCall(tok)
+		}
+
+		return &columnRef{name: tok.value}, nil
+	case tokenOperator:
+		if tok.value == "(" {
+			expr, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+
+			return expr, p.expectOperator(")")
+		}
+	}
+
+	return nil, p.unexpected(tok)
+}
+
+// parseCall разбирает вызов функции name после открывающей скобки.
+func (p *parser) parseCall(name token) (Expr, error) {
+	fn := &call{name: name.value}
+
+	_, scalar := scalarFunctions[fn.name]
+	if !scalar && !isAggregate(fn.name) {
+		return nil, fmt.Errorf("%w: unknown function %s", ErrInvalidQuery, name)
+	}
+
+	switch {
+	case fn.name == "count" && p.acceptOperator("*"):
+		fn.star = true
+	case p.peek().kind == tokenOperator && p.peek().value == ")":
+	default:
+		fn.distinct = isAggregate(fn.name) && p.acceptKeyword("DISTINCT")
+
+		args, err := p.parseExprList()
+		if err != nil {
+			return nil, err
+		}
+
+		fn.args = args
+	}
+
+	if err := p.expectOperator(")"); err != nil {
+		return nil, err
+	}
+
+	n := len(fn.args)
+
+	if isAggregate(fn.name) {
+		if !fn.star && n != 1 {
+			return nil, fmt.Errorf("%w: %s expects 1 argument", ErrInvalidQuery, fn.name)
+		}
+
+		return fn, nil
+	}
+
+	if f := scalarFunctions[fn.name]; n < f.minArgs || (f.maxArgs >= 0 && n > f.maxArgs) {
+		return nil, fmt.Errorf("%w: wrong number of arguments of %s", ErrInvalidQuery, fn.name)
+	}
+
+	return fn, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+
+	return tok
+}
+
+func (p *parser) acceptKeyword(keyword string) bool {
+	if tok := p.peek(); tok.kind == tokenKeyword && tok.value == keyword {
+		p.pos++
+		return true
+	}
+
+	return false
+}
+
+func (p *parser) acceptOperator(op string) bool {
+	if tok := p.peek(); tok.kind == tokenOperator && tok.value == op {
+		p.pos++
+		return true
+	}
+
+	return false
+}
+
+func (p *parser) expectKeyword(keyword string) error {
+	if !p.acceptKeyword(keyword) {
+		return fmt.Errorf("%w: expected %s, got %s", ErrInvalidQuery, keyword, p.peek())
+	}
+
+	return nil
+}
+
+func (p *parser) expectOperator(op string) error {
+	if !p.acceptOperator(op) {
+		return fmt.Errorf("%w: expected %q, got %s", ErrInvalidQuery, op, p.peek())
+	}
+
+	return nil
+}
+
+func (p *parser) unexpected(tok token) error {
+	return fmt.Errorf("%w: unexpected %s", ErrInvalidQuery, tok)
+}
@@ -0,0 +1,72 @@
+package sqlengine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	stmt, err := Parse(`
+		-- выручка по магазинам
+		SELECT store_id, SUM(gross) AS "Total", count(*)
+		FROM Sales
+		WHERE payment_method NOT IN ('cash', 'voucher') OR NOT (gross <= 10 AND quantity <> 1)
+		GROUP BY store_id
+		HAVING sum(gross) >= 100
+		ORDER BY "Total" DESC, store_id
+		LIMIT 10 OFFSET 5
+	`)
+	require.NoError(t, err)
+
+	assert.Equal(t, "sales", stmt.From)
+	assert.Len(t, stmt.columns, 3)
+	assert.Equal(t, "Total", stmt.columns[1].alias)
+	assert.Equal(t, "sum(gross)", stmt.columns[1].expr.String())
+	assert.Equal(t, "(payment_method NOT IN ('cash', 'voucher')) OR NOT ((gross <= 10) AND (quantity <> 1))",
+		trimParens(stmt.where.String()))
+	assert.Equal(t, "(sum(gross) >= 100)", stmt.having.String())
+	assert.Equal(t, []orderBy{{expr: &columnRef{name: "Total"}, desc: true}, {expr: &columnRef{name: "store_id"}}}, stmt.orderBy)
+	assert.Equal(t, int64(10), stmt.limit)
+	assert.Equal(t, int64(5), stmt.offset)
+
+	// приоритет операторов: умножение выше сложения, AND выше OR
+	stmt, err = Parse("SELECT 1 + 2 * -3, 'it''s' FROM sales WHERE a OR b AND c")
+	require.NoError(t, err)
+	assert.Equal(t, "(1 + (2 * -3))", stmt.columns[0].expr.String())
+	assert.Equal(t, "'it''s'", stmt.columns[1].expr.String())
+	assert.Equal(t, "(a OR (b AND c))", stmt.where.String())
+	assert.Equal(t, int64(-1), stmt.limit)
+}
+
+func TestParse_Errors(t *testing.T) {
+	t.Parallel()
+
+	queries := map[string]string{
+		"":                              "expected SELECT, got end of query",
+		"DELETE FROM sales":             `expected SELECT, got "delete" at position 1`,
+		"SELECT gross sales":            "expected FROM, got end of query",
+		"SELECT gross FROM sales WHERE": "unexpected end of query",
+		"SELECT gross FROM sales; DROP TABLE sales": `unexpected "drop" at position 26`,
+		"SELECT median(gross) FROM sales":           `unknown function "median" at position 8`,
+		"SELECT sum(gross, net) FROM sales":         "sum expects 1 argument",
+		"SELECT round() FROM sales":                 "wrong number of arguments of round",
+		"SELECT 'gross FROM sales":                  "unterminated string at position 8",
+		"SELECT gross FROM sales LIMIT 1.5":         `expected integer, got "1.5" at position 31`,
+		"SELECT gross FROM sales WHERE a NOT b":     `unexpected "b" at position 37`,
+		"SELECT gross FROM sales WHERE a ! b":       "unexpected character '!' at position 33",
+	}
+
+	for query, msg := range queries {
+		_, err := Parse(query)
+		assert.ErrorIs(t, err, ErrInvalidQuery, query)
+		assert.ErrorContains(t, err, msg, query)
+	}
+}
+
+func trimParens(s string) string {
+	return s[1 : len(s)-1]
+}